@@ -1,9 +1,63 @@
 package v1beta1
 
+import corev1 "k8s.io/api/core/v1"
+
 // CloudInit is passed to disk directly as raw yaml file
 // not via Proxmox API so you can configure more detailed configs
 type CloudInit struct {
 	UserData *UserData `json:"user,omitempty"`
+
+	// SnippetMode is how the snippets carrying the bootstrap data are written to the snippet storage.
+	// Defaults to restricted.
+	// +optional
+	SnippetMode SnippetMode `json:"snippetMode,omitempty"`
+
+	// CredentialsSecretRef refers to a Secret in the namespace of the machine whose "password",
+	// and optionally "user", set the password of the default user like ciuser and cipassword do,
+	// e.g. for break-glass access via the console. The password does not expire and
+	// overrides the password of the user data. Changes apply to new machines only.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// Encryption encrypts the bootstrap data in the user-data snippet with age, so that admins
+	// of the snippet storage can not read join tokens and certificates of the cluster.
+	// The image must have age installed and the identity of one of the recipients.
+	// +optional
+	Encryption *SnippetEncryption `json:"encryption,omitempty"`
+}
+
+// SnippetEncryption encrypts bootstrap data to age recipients. The guest decrypts it with its identity
+// and runs it as a shell script, so that only bootcmd, write_files, ssh_authorized_keys, user/password,
+// packages and runcmd of the bootstrap data are applied.
+type SnippetEncryption struct {
+	// Recipients are the age public keys (age1...) the bootstrap data is encrypted to.
+	// +kubebuilder:validation:MinItems:=1
+	// +kubebuilder:validation:items:Pattern:=`^age1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]{58}$`
+	Recipients []string `json:"recipients"`
+
+	// IdentityPath is the path of the age identity in the image which decrypts the bootstrap data.
+	// +kubebuilder:default:=/etc/cappx/age.key
+	// +kubebuilder:validation:Pattern:=`^/[^\s'"]+$`
+	// +optional
+	IdentityPath string `json:"identityPath,omitempty"`
+}
+
+// SnippetMode is the permission of cloud-init snippets on the snippet storage
+// +kubebuilder:validation:Enum:=restricted;shared
+type SnippetMode string
+
+const (
+	// SnippetModeRestricted snippets are owned and readable by root only, since bootstrap data
+	// contains join tokens and certificates of the cluster
+	SnippetModeRestricted = SnippetMode("restricted")
+	// SnippetModeShared snippets are written with the default umask of the node and can be
+	// read by any user of the storage. e.g. for nfs exports squashing root
+	SnippetModeShared = SnippetMode("shared")
+)
+
+// Restricted returns true unless snippets are explicitly shared
+func (m SnippetMode) Restricted() bool {
+	return m != SnippetModeShared
 }
 
 type UserData struct {
@@ -18,6 +72,7 @@ type UserData struct {
 	PackageUpgrade    bool         `yaml:"package_upgrade,omitempty" json:"package_upgrade,omitempty"`
 	Password          string       `yaml:"password,omitempty" json:"password,omitempty"`
 	RunCmd            []string     `yaml:"runcmd,omitempty" json:"runCmd,omitempty"`
+	SetHostName       string       `yaml:"set_hostname,omitempty" json:"-"`
 	SSH               SSH          `yaml:"ssh,omitempty" json:"ssh,omitempty"`
 	SSHAuthorizedKeys []string     `yaml:"ssh_authorized_keys,omitempty" json:"ssh_authorized_keys,omitempty"`
 	SSHKeys           SSHKeys      `yaml:"ssh_keys,omitempty" json:"ssh_keys,omitempty"`
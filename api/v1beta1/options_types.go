@@ -1,15 +1,47 @@
 package v1beta1
 
-import "strconv"
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // import "encoding/json"
 
 // +kubebuilder:validation:Enum:=x86_64;aarch64
 type Arch string
 
+const (
+	ArchX86_64  Arch = "x86_64"
+	ArchAarch64 Arch = "aarch64"
+)
+
 // +kubebuilder:validation:Enum:=seabios;ovmf
 type BIOS string
 
+const (
+	BIOSSeaBIOS BIOS = "seabios"
+	BIOSOVMF    BIOS = "ovmf"
+)
+
+// BootDevice is a device to boot from. e.g. scsi0, virtio0, ide2, net0
+// +kubebuilder:validation:Pattern:=`^(ide|sata|scsi|virtio|net|hostpci|usb)\d+$|^efidisk0$`
+// +kubebuilder:validation:MaxLength:=16
+type BootDevice string
+
+// +kubebuilder:validation:Enum:=network;disk;usb;memory;cpu
+type HotPlugDevice string
+
+const (
+	HotPlugNetwork HotPlugDevice = "network"
+	HotPlugDisk    HotPlugDevice = "disk"
+	HotPlugUSB     HotPlugDevice = "usb"
+	HotPlugMemory  HotPlugDevice = "memory"
+	HotPlugCPU     HotPlugDevice = "cpu"
+)
+
 // +kubebuilder:validation:Enum:=0;2;1024
 type HugePages int
 
@@ -19,9 +51,21 @@ type Lock string
 // +kubebuilder:validation:Enum:=other;wxp;w2k;w2k3;w2k8;wvista;win7;win8;win10;win11;l24;l26;solaris
 type OSType string
 
-// +kubebuilder:validation:Pattern:="[a-zA-Z0-9-_.;]+"
+// IsWindows returns true for the Microsoft Windows os types
+func (t OSType) IsWindows() bool {
+	return strings.HasPrefix(string(t), "w")
+}
+
+// Tag of the VM. Tags are case insensitive and lowercased before sending to Proxmox.
+// +kubebuilder:validation:Pattern:=`^[a-zA-Z0-9_][a-zA-Z0-9_+.-]*$`
+// +kubebuilder:validation:MaxLength:=128
 type Tag string
 
+// MaxTagLength is the length of the longest tag Proxmox accepts
+const MaxTagLength = 128
+
+var tagRegex = regexp.MustCompile(`^[a-z0-9_][a-z0-9_+.-]*$`)
+
 type Tags []Tag
 
 func (h *HugePages) String() string {
@@ -33,36 +77,118 @@ func (h *HugePages) String() string {
 	return strconv.Itoa(int(*h))
 }
 
-func (t *Tags) String() string {
-	var tags string
-	for _, tag := range *t {
-		tags += string(tag) + ";"
+// Normalize returns lowercased tags without duplicates. the order is kept.
+func (t Tags) Normalize() Tags {
+	tags := Tags{}
+	seen := map[Tag]bool{}
+	for _, tag := range t {
+		tag = Tag(strings.ToLower(strings.TrimSpace(string(tag))))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
 	}
 	return tags
 }
 
+// Validate returns an error naming the first tag Proxmox would reject.
+func (t Tags) Validate() error {
+	for i, tag := range t {
+		normalized := strings.ToLower(strings.TrimSpace(string(tag)))
+		if len(normalized) > MaxTagLength {
+			return fmt.Errorf("tags[%d]: %q is longer than %d characters", i, tag, MaxTagLength)
+		}
+		if !tagRegex.MatchString(normalized) {
+			return fmt.Errorf("tags[%d]: %q must consist of alphanumerics, '_', '-', '+' or '.'", i, tag)
+		}
+	}
+	return nil
+}
+
+func (t *Tags) String() string {
+	tags := []string{}
+	for _, tag := range t.Normalize() {
+		tags = append(tags, string(tag))
+	}
+	return strings.Join(tags, ";")
+}
+
+// NUMANode is a NUMA node of the VM
+type NUMANode struct {
+	// CPUs accessing this NUMA node. e.g. 0-3 or 0-1;4-5
+	// +kubebuilder:validation:Pattern:=`^\d+(-\d+)?(;\d+(-\d+)?)*$`
+	CPUs string `json:"cpus"`
+
+	// host NUMA nodes to use. e.g. 0 or 0-1
+	// +kubebuilder:validation:Pattern:=`^\d+(-\d+)?(;\d+(-\d+)?)*$`
+	HostNodes string `json:"hostNodes,omitempty"`
+
+	// amount of memory this NUMA node provides in MiB
+	// +kubebuilder:validation:Minimum:=0
+	Memory int `json:"memory,omitempty"`
+
+	// NUMA allocation policy
+	// +kubebuilder:validation:Enum:=preferred;bind;interleave
+	Policy string `json:"policy,omitempty"`
+}
+
+func (n *NUMANode) String() string {
+	config := []string{fmt.Sprintf("cpus=%s", n.CPUs)}
+	if n.HostNodes != "" {
+		config = append(config, fmt.Sprintf("hostnodes=%s", n.HostNodes))
+	}
+	if n.Memory != 0 {
+		config = append(config, fmt.Sprintf("memory=%d", n.Memory))
+	}
+	if n.Policy != "" {
+		config = append(config, fmt.Sprintf("policy=%s", n.Policy))
+	}
+	return strings.Join(config, ",")
+}
+
 // Options
+// +kubebuilder:validation:XValidation:rule="!has(self.numaNodes) || (has(self.numa) && self.numa)",message="numaNodes requires numa to be enabled"
+// +kubebuilder:validation:XValidation:rule="!has(self.osType) || !has(self.arch) || self.arch != 'aarch64' || !(self.osType.startsWith('w'))",message="windows osType is not supported on aarch64"
+// +kubebuilder:validation:XValidation:rule="!has(self.smbios) || !has(self.smbios.serialFromMachineUID) || !self.smbios.serialFromMachineUID || !has(self.smbios.serial)",message="smbios.serial and smbios.serialFromMachineUID are mutually exclusive"
 type Options struct {
 	// Enable/Disable ACPI. Defaults to true.
 	ACPI bool `json:"acpi,omitempty"`
 
+	// QEMU guest agent settings. The agent is enabled and installed via cloud-init by default.
+	Agent *Agent `json:"agent,omitempty"`
+
 	// Virtual processor architecture. Defaults to the host. x86_64 or aarch64.
+	// The machine is only scheduled to nodes of the same architecture.
+	// aarch64 defaults hardware.bios to ovmf, hardware.machine to virt and hardware.cpuType to host.
 	Arch Arch `json:"arch,omitempty"`
 
 	// +kubebuilder:validation:Minimum:=0
 	// Amount of target RAM for the VM in MiB. Using zero disables the ballon driver.
 	Balloon int `json:"balloon,omitempty"`
 
+	// Devices to boot from in the order of trial. e.g. [scsi0, ide2, net0].
+	// Defaults to [scsi0], the root disk.
+	// +kubebuilder:validation:MaxItems:=8
+	// +listType=set
+	BootOrder []BootDevice `json:"bootOrder,omitempty"`
+
 	// Description for the VM. Shown in the web-interface VM's summary.
 	// This is saved as comment inside the configuration file.
+	// This is rendered as go template with .ClusterName, .Namespace, .MachineName,
+	// .ProxmoxMachineName, .Owner and .ProviderVersion.
+	// Defaults to a summary of those values.
 	Description string `json:"description,omitempty"`
 
 	// Script that will be executed during various steps in the vms lifetime.
 	// HookScripts []Hookscript `json:"hookScripts,omitempty"`
 
-	// enable hotplug feature. list og devices.
+	// enable hotplug feature. list of devices.
 	// network, disk, cpu, memory, usb. Defaults to [network, disk, usb].
-	// HotPlug []HotPlugDevice `json:"hotPlug,omitempty"`
+	// memory is added automatically if hardware.memoryHotplug is enabled.
+	// +kubebuilder:validation:MaxItems:=5
+	// +listType=set
+	HotPlug []HotPlugDevice `json:"hotPlug,omitempty"`
 
 	// enable/disable hugepages memory. 0 or 2 or 1024. 0 indicated 'any'
 	HugePages *HugePages `json:"hugePages,omitempty"`
@@ -90,11 +216,26 @@ type Options struct {
 	// Enable/disable NUMA.
 	NUMA bool `json:"numa,omitempty"`
 
+	// NUMA topology of the VM (numa0 ~ numa7). requires numa to be enabled.
+	// +kubebuilder:validation:MaxItems:=8
+	NUMANodes []NUMANode `json:"numaNodes,omitempty"`
+
+	// List of host cores used to execute guest processes. e.g. 0,5,8-11
+	// +kubebuilder:validation:Pattern:=`^\d+(-\d+)?(,\d+(-\d+)?)*$`
+	Affinity string `json:"affinity,omitempty"`
+
+	// Arbitrary arguments passed to kvm. e.g. -no-reboot -smbios 'type=0,vendor=FOO'
+	// This is for experts only and requires the QEMUArgs feature gate to be enabled.
+	Args string `json:"args,omitempty"`
+
 	// Specifies whether a VM will be started during system bootup.
 	OnBoot bool `json:"onBoot,omitempty"`
 
 	// Specify guest operating system. This is used to enable special
 	// optimization/features for specific operating systems.
+	// This is set per machine so that e.g. Linux and Windows MachineDeployments
+	// can coexist in a cluster. Windows types are not available on aarch64.
+	// other, wxp, w2k, w2k3, w2k8, wvista, win7, win8, win10, win11, l24, l26 or solaris.
 	OSType OSType `json:"osType,omitempty"`
 
 	// Sets the protection flag of the VM.
@@ -113,12 +254,18 @@ type Options struct {
 	// Auto-ballooning is done by pvestatd. 0 ~ 5000. Defaults to 1000.
 	Shares int `json:"shares,omitempty"`
 
+	// SMBIOS type1 settings of the VM.
+	SMBios *SMBios `json:"smbios,omitempty"`
+
 	// Set the initial date of the real time clock.
 	// Valid format for date are:'now' or '2006-06-17T16:01:21' or '2006-06-17'.
 	// Defaults to 'now'.
 	// StartDate string `json:"startDate,omitempty"`
 
-	// StartUp string `json:"startUp,omitempty`
+	// Startup and shutdown behavior when the proxmox host boots or shuts down.
+	// e.g. give control-plane machines a lower order than workers so that they start first.
+	// This only takes effect for VMs having onBoot enabled.
+	StartUp *StartUp `json:"startUp,omitempty"`
 
 	// Enable/disable the USB tablet device. This device is usually needed to allow
 	// absolute mouse positioning with VNC. Else the mouse runs out of sync with normal VNC clients.
@@ -129,6 +276,7 @@ type Options struct {
 	Tablet bool `json:"tablet,omitempty"`
 
 	// Tags of the VM. This is only meta information.
+	// cappx and cluster.<cluster name> tags are always added.
 	Tags Tags `json:"tags,omitempty"`
 
 	// Enable/disable time drift fix. Defaults to false.
@@ -143,7 +291,8 @@ type Options struct {
 	// Number of hotplugged vcpus. Defaults to 0.
 	VCPUs int `json:"vcpus,omitempty"`
 
-	// VGA string `json:"vga,omitempty"`
+	// Display device of the VM. Defaults to serial0.
+	VGA *VGA `json:"vga,omitempty"`
 
 	// +kubebuilder:validation:Pattern:="(?:[a-fA-F0-9]{8}(?:-[a-fA-F0-9]{4}){3}-[a-fA-F0-9]{12}|[01])"
 	// The VM generation ID (vmgenid) device exposes a 128-bit integer value identifier to the guest OS.
@@ -161,5 +310,153 @@ type Options struct {
 	// Create a virtual hardware watchdog device. Once enabled (by a guest action),
 	// the watchdog must be periodically polled by an agent inside the guest or else
 	// the watchdog will reset the guest (or execute the respective action specified)
-	// WatchDog string `json:"watchDog,omitempty"`
+	WatchDog *WatchDog `json:"watchDog,omitempty"`
+}
+
+// WatchDog is a virtual hardware watchdog device
+type WatchDog struct {
+	// watchdog model. Defaults to i6300esb.
+	// +kubebuilder:validation:Enum:=i6300esb;ib700
+	// +kubebuilder:default:=i6300esb
+	Model string `json:"model,omitempty"`
+
+	// action to perform if the watchdog is enabled and triggered. Defaults to reset.
+	// +kubebuilder:validation:Enum:=reset;shutdown;poweroff;pause;debug;none
+	// +kubebuilder:default:=reset
+	Action string `json:"action,omitempty"`
+}
+
+func (w *WatchDog) String() string {
+	model, action := w.Model, w.Action
+	if model == "" {
+		model = "i6300esb"
+	}
+	if action == "" {
+		action = "reset"
+	}
+	return fmt.Sprintf("model=%s,action=%s", model, action)
+}
+
+// Agent is QEMU guest agent settings
+type Agent struct {
+	// Enable/disable communication with the QEMU guest agent.
+	// If disabled, cappx does not install qemu-guest-agent via cloud-init. Defaults to true.
+	// +kubebuilder:default:=true
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Run fstrim after moving a disk or migrating the VM.
+	FSTrimClonedDisks bool `json:"fstrimClonedDisks,omitempty"`
+
+	// Agent type. Defaults to virtio.
+	// +kubebuilder:validation:Enum:=virtio;isa
+	Type string `json:"type,omitempty"`
+}
+
+// IsEnabled returns true unless the agent is explicitly disabled
+func (a *Agent) IsEnabled() bool {
+	return a == nil || a.Enabled == nil || *a.Enabled
+}
+
+func (a *Agent) String() string {
+	config := []string{fmt.Sprintf("enabled=%d", btoi(a.IsEnabled()))}
+	if a != nil && a.FSTrimClonedDisks {
+		config = append(config, "fstrim_cloned_disks=1")
+	}
+	if a != nil && a.Type != "" {
+		config = append(config, fmt.Sprintf("type=%s", a.Type))
+	}
+	return strings.Join(config, ",")
+}
+
+// VGA is a display device of the VM
+// +kubebuilder:validation:XValidation:rule="!has(self.memory) || !(self.type == 'none' || self.type.startsWith('serial'))",message="memory can not be set for serial or none display"
+type VGA struct {
+	// type of the display device. serialN uses a serial terminal as display.
+	// +kubebuilder:validation:Enum:=cirrus;none;qxl;qxl2;qxl3;qxl4;serial0;serial1;serial2;serial3;std;virtio;virtio-gl;vmware
+	// +kubebuilder:default:=serial0
+	Type string `json:"type"`
+
+	// video memory in MiB. 4 ~ 512.
+	// +kubebuilder:validation:Minimum:=4
+	// +kubebuilder:validation:Maximum:=512
+	Memory int `json:"memory,omitempty"`
+}
+
+func (v *VGA) String() string {
+	if v.Memory == 0 {
+		return v.Type
+	}
+	return fmt.Sprintf("type=%s,memory=%d", v.Type, v.Memory)
+}
+
+// SMBios is SMBIOS type1 settings exposed to the guest
+type SMBios struct {
+	// UUID of the VM. This is used for providerID so it must be unique.
+	// Do not set this in ProxmoxMachineTemplate. Generated if empty.
+	// +kubebuilder:validation:Pattern:=`^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}$`
+	UUID string `json:"uuid,omitempty"`
+
+	// Serial number of the VM.
+	// +kubebuilder:validation:MaxLength:=128
+	Serial string `json:"serial,omitempty"`
+
+	// Use the UID of the owner Machine as serial number.
+	// This is useful when the serial must be unique but spec is shared by a template.
+	SerialFromMachineUID bool `json:"serialFromMachineUID,omitempty"`
+
+	// Manufacturer name of the VM.
+	// +kubebuilder:validation:MaxLength:=128
+	Manufacturer string `json:"manufacturer,omitempty"`
+
+	// Product ID of the VM.
+	// +kubebuilder:validation:MaxLength:=128
+	Product string `json:"product,omitempty"`
+}
+
+// returns smbios1 option. string values are base64 encoded so that they can contain any characters
+func (s *SMBios) String() string {
+	config := []string{"base64=1"}
+	if s.Manufacturer != "" {
+		config = append(config, fmt.Sprintf("manufacturer=%s", base64.StdEncoding.EncodeToString([]byte(s.Manufacturer))))
+	}
+	if s.Product != "" {
+		config = append(config, fmt.Sprintf("product=%s", base64.StdEncoding.EncodeToString([]byte(s.Product))))
+	}
+	if s.Serial != "" {
+		config = append(config, fmt.Sprintf("serial=%s", base64.StdEncoding.EncodeToString([]byte(s.Serial))))
+	}
+	if s.UUID != "" {
+		config = append(config, fmt.Sprintf("uuid=%s", s.UUID))
+	}
+	return strings.Join(config, ",")
+}
+
+// StartUp defines startup and shutdown behavior of the VM
+type StartUp struct {
+	// Order is a non-negative number defining the general startup order.
+	// Shutdown is done with reverse ordering.
+	// +kubebuilder:validation:Minimum:=0
+	Order *int `json:"order,omitempty"`
+
+	// Up is a delay in seconds before the next VM is started.
+	// +kubebuilder:validation:Minimum:=0
+	Up int `json:"up,omitempty"`
+
+	// Down is a timeout in seconds to wait for this VM to be powered off.
+	// +kubebuilder:validation:Minimum:=0
+	Down int `json:"down,omitempty"`
+}
+
+func (s *StartUp) String() string {
+	config := []string{}
+	if s.Order != nil {
+		config = append(config, fmt.Sprintf("order=%d", *s.Order))
+	}
+	if s.Up != 0 {
+		config = append(config, fmt.Sprintf("up=%d", s.Up))
+	}
+	if s.Down != 0 {
+		config = append(config, fmt.Sprintf("down=%d", s.Down))
+	}
+	return strings.Join(config, ",")
 }
@@ -0,0 +1,109 @@
+package v1beta1_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+var _ = Describe("Tags", Label("unit", "api"), func() {
+	It("should lowercase and dedup tags", func() {
+		tags := infrav1.Tags{"Foo", "bar", "foo", "BAR", "k8s.io"}
+		Expect(tags.String()).To(Equal("foo;bar;k8s.io"))
+	})
+
+	It("should return empty string for no tags", func() {
+		tags := infrav1.Tags{}
+		Expect(tags.String()).To(Equal(""))
+	})
+
+	It("should accept valid tags", func() {
+		tags := infrav1.Tags{"cappx", "Cluster_1", "v1.30+rke2", "a-b"}
+		Expect(tags.Validate()).To(Succeed())
+	})
+
+	It("should name the invalid tag", func() {
+		tags := infrav1.Tags{"ok", "-bad"}
+		err := tags.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("tags[1]"))
+
+		tags = infrav1.Tags{"with;separator"}
+		Expect(tags.Validate()).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("WatchDog", Label("unit", "api"), func() {
+	It("should default to i6300esb with reset", func() {
+		w := infrav1.WatchDog{}
+		Expect(w.String()).To(Equal("model=i6300esb,action=reset"))
+	})
+
+	It("should render action", func() {
+		w := infrav1.WatchDog{Model: "i6300esb", Action: "poweroff"}
+		Expect(w.String()).To(Equal("model=i6300esb,action=poweroff"))
+	})
+})
+
+var _ = Describe("NUMANode", Label("unit", "api"), func() {
+	It("should render numa node", func() {
+		n := infrav1.NUMANode{CPUs: "0-3", HostNodes: "0", Memory: 4096, Policy: "bind"}
+		Expect(n.String()).To(Equal("cpus=0-3,hostnodes=0,memory=4096,policy=bind"))
+	})
+})
+
+var _ = Describe("StartUp", Label("unit", "api"), func() {
+	It("should render order and delays", func() {
+		order := 0
+		s := infrav1.StartUp{Order: &order, Up: 30, Down: 60}
+		Expect(s.String()).To(Equal("order=0,up=30,down=60"))
+	})
+
+	It("should omit unset fields", func() {
+		s := infrav1.StartUp{Up: 10}
+		Expect(s.String()).To(Equal("up=10"))
+	})
+})
+
+var _ = Describe("SMBios", Label("unit", "api"), func() {
+	It("should base64 encode string values", func() {
+		s := infrav1.SMBios{UUID: "5c1e5f6e-0bd4-4d3c-9f1b-0a2f7f0e1a11", Serial: "abc", Manufacturer: "ACME, Inc."}
+		Expect(s.String()).To(Equal("base64=1,manufacturer=QUNNRSwgSW5jLg==,serial=YWJj,uuid=5c1e5f6e-0bd4-4d3c-9f1b-0a2f7f0e1a11"))
+	})
+})
+
+var _ = Describe("VGA", Label("unit", "api"), func() {
+	It("should render type only", func() {
+		v := infrav1.VGA{Type: "std"}
+		Expect(v.String()).To(Equal("std"))
+	})
+
+	It("should render memory", func() {
+		v := infrav1.VGA{Type: "qxl", Memory: 32}
+		Expect(v.String()).To(Equal("type=qxl,memory=32"))
+	})
+})
+
+var _ = Describe("Agent", Label("unit", "api"), func() {
+	It("should be enabled by default", func() {
+		var a *infrav1.Agent
+		Expect(a.IsEnabled()).To(BeTrue())
+		Expect(a.String()).To(Equal("enabled=1"))
+	})
+
+	It("should render options", func() {
+		disabled := false
+		a := &infrav1.Agent{Enabled: &disabled, FSTrimClonedDisks: true, Type: "isa"}
+		Expect(a.String()).To(Equal("enabled=0,fstrim_cloned_disks=1,type=isa"))
+	})
+})
+
+var _ = Describe("OSType", Label("unit", "api"), func() {
+	It("should detect windows", func() {
+		Expect(infrav1.OSType("win11").IsWindows()).To(BeTrue())
+		Expect(infrav1.OSType("w2k8").IsWindows()).To(BeTrue())
+		Expect(infrav1.OSType("l26").IsWindows()).To(BeFalse())
+		Expect(infrav1.OSType("").IsWindows()).To(BeFalse())
+	})
+})
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BackupPolicyFinalizer
+	BackupPolicyFinalizer = "proxmoxbackuppolicy.infrastructure.cluster.x-k8s.io"
+)
+
+// +kubebuilder:validation:Enum:=snapshot;suspend;stop
+type BackupMode string
+
+// +kubebuilder:validation:Enum:="0";gzip;lzo;zstd
+type BackupCompression string
+
+// ProxmoxBackupPolicySpec defines the desired state of ProxmoxBackupPolicy
+type ProxmoxBackupPolicySpec struct {
+	// ClusterName is the name of the Cluster in the same namespace whose machines are backed up
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterName is immutable"
+	ClusterName string `json:"clusterName"`
+
+	// Selector selects ProxmoxMachines of the cluster to back up. All machines if empty.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Schedule of the backup job in systemd calendar event format. e.g. daily, sat 02:00, *-*-* 03:30
+	// +kubebuilder:validation:MinLength:=1
+	Schedule string `json:"schedule"`
+
+	// Mode of the backup. Defaults to snapshot.
+	// +kubebuilder:default:=snapshot
+	Mode BackupMode `json:"mode,omitempty"`
+
+	// Storage to store the backups in. This can be a Proxmox Backup Server storage.
+	// +kubebuilder:validation:MinLength:=1
+	Storage string `json:"storage"`
+
+	// Compression of the backup files. Ignored by Proxmox Backup Server storages.
+	Compress BackupCompression `json:"compress,omitempty"`
+
+	// Suspend disables the backup job without deleting it.
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Retention of the backups. Backups are kept forever if empty.
+	Retention *BackupRetention `json:"retention,omitempty"`
+}
+
+// BackupRetention defines which backups of the selected VMs are kept in the storage
+type BackupRetention struct {
+	// KeepLast is the number of latest backups of each VM to keep.
+	// Older ones are pruned by Proxmox after each run of the job.
+	// +kubebuilder:validation:Minimum:=1
+	KeepLast int `json:"keepLast,omitempty"`
+
+	// MaxAge deletes backups of the selected VMs older than this from the storage. e.g. 720h.
+	// All backups of the VMs in the storage are subject to this, not only the ones taken by the job.
+	// Protected backups are kept.
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// ProxmoxBackupPolicyStatus defines the observed state of ProxmoxBackupPolicy
+type ProxmoxBackupPolicyStatus struct {
+	// Ready is true when the backup job is applied
+	// +optional
+	Ready bool `json:"ready"`
+
+	// JobID is the id of the backup job in Proxmox
+	JobID string `json:"jobID,omitempty"`
+
+	// VMIDs backed up by the job
+	VMIDs []int `json:"vmIDs,omitempty"`
+
+	// FailureMessage
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Storage",type=string,JSONPath=`.spec.storage`
+// +kubebuilder:printcolumn:name="Job",type=string,JSONPath=`.status.jobID`,priority=1
+// +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of ProxmoxBackupPolicy"
+
+// ProxmoxBackupPolicy is the Schema for the proxmoxbackuppolicies API
+type ProxmoxBackupPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProxmoxBackupPolicySpec   `json:"spec,omitempty"`
+	Status ProxmoxBackupPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProxmoxBackupPolicyList contains a list of ProxmoxBackupPolicy
+type ProxmoxBackupPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProxmoxBackupPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProxmoxBackupPolicy{}, &ProxmoxBackupPolicyList{})
+}
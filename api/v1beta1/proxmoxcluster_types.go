@@ -17,6 +17,9 @@ limitations under the License.
 package v1beta1
 
 import (
+	"regexp"
+	"slices"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -24,19 +27,650 @@ import (
 const (
 	// ClusterFinalizer
 	ClusterFinalizer = "proxmoxcluster.infrastructure.cluster.x-k8s.io"
+
+	// NodesDownAnnotation confirms that Proxmox nodes are down permanently.
+	// The value is a comma-separated list of node names.
+	NodesDownAnnotation = "infrastructure.cluster.x-k8s.io/proxmox-nodes-down"
+
+	// AllowedNamespacesAnnotation on the secret of serverRef.secretRef lists the namespaces whose ProxmoxClusters
+	// may use it, comma-separated or "*" for any. Secrets are usable from their own namespace only unless annotated.
+	AllowedNamespacesAnnotation = "infrastructure.cluster.x-k8s.io/proxmox-allowed-namespaces"
+)
+
+const (
+	// ProxmoxVersionSupportedCondition reports whether the version of Proxmox VE is supported by cappx.
+	// Machines are not created while it is false.
+	ProxmoxVersionSupportedCondition clusterv1.ConditionType = "ProxmoxVersionSupported"
+
+	// UnsupportedVersionReason is used when the version of Proxmox VE is older than the minimum supported one.
+	UnsupportedVersionReason = "UnsupportedVersion"
+
+	// QuorateCondition reports whether the Proxmox cluster is quorate and all of its nodes are in a known state.
+	// Operations creating or deleting guests are paused while it is false.
+	QuorateCondition clusterv1.ConditionType = "Quorate"
+
+	// QuorumLostReason is used when the Proxmox cluster has lost quorum.
+	QuorumLostReason = "QuorumLost"
+
+	// NodesUnknownReason is used when Proxmox nodes are in unknown state.
+	NodesUnknownReason = "NodesUnknown"
+
+	// VMsAdoptedCondition reports whether Machines have been generated for all qemus of spec.adoption.
+	VMsAdoptedCondition clusterv1.ConditionType = "VMsAdopted"
+
+	// AdoptionFailedReason is used when some qemus of spec.adoption can not be adopted.
+	AdoptionFailedReason = "AdoptionFailed"
 )
 
 // ProxmoxClusterSpec defines the desired state of ProxmoxCluster
+// +kubebuilder:validation:XValidation:rule="!has(self.externalControlPlane) || !self.externalControlPlane || !has(self.adoption) || self.adoption.all(vm, vm.role != 'ControlPlane')",message="control plane vms can not be adopted by clusters with an external control plane"
 type ProxmoxClusterSpec struct {
 	// ControlPlaneEndpoint represents the endpoint used to communicate with the control plane.
 	// +optional
 	ControlPlaneEndpoint clusterv1.APIEndpoint `json:"controlPlaneEndpoint"`
 
+	// ExternalControlPlane makes the cluster workers-only. Its control plane runs outside of Proxmox,
+	// e.g. a managed Kubernetes service or a cluster of another provider, and control-plane machines are
+	// not provisioned. Unless set, the control-plane endpoint is taken from the kubeconfig Secret of the Cluster.
+	// +optional
+	ExternalControlPlane bool `json:"externalControlPlane,omitempty"`
+
 	// ServerRef is used for configuring Proxmox client
 	ServerRef ServerRef `json:"serverRef"`
 
 	// storage is used for storing cloud init snippet
 	Storage Storage `json:"storage,omitempty"`
+
+	// Rebalance moves VMs of the cluster between Proxmox nodes periodically to even out their memory usage.
+	// Requires the ClusterRebalancer feature gate.
+	Rebalance *RebalancePolicy `json:"rebalance,omitempty"`
+
+	// NodeFailure enables recovery of machines whose Proxmox node is down permanently.
+	// Such machines are recreated on healthy nodes.
+	NodeFailure *NodeFailurePolicy `json:"nodeFailure,omitempty"`
+
+	// Pool places the VMs and the snippet storage of the cluster into a Proxmox resource pool,
+	// so that Proxmox permissions and accounting can be scoped per cluster.
+	Pool *ResourcePool `json:"pool,omitempty"`
+
+	// TagMappings propagate labels and annotations of the Machines and ProxmoxMachines
+	// to the tags of their VMs, so that VMs can be grouped by them on Proxmox.
+	// Tags are kept up to date with the labels and annotations.
+	TagMappings []TagMapping `json:"tagMappings,omitempty"`
+
+	// VMTags are added to the tags of every VM of the cluster, together with the tags of the machine,
+	// so that org-wide tagging conventions, e.g. cost center or environment, apply to all of them.
+	// Tags added later are added to existing VMs too, while removed ones are left on them.
+	VMTags Tags `json:"vmTags,omitempty"`
+
+	// Orphans configures the detection of VMs carrying the tags of the cluster
+	// but belonging to no ProxmoxMachine, e.g. leftovers of interrupted deletions.
+	// Orphaned VMs are reported every 10m unless set.
+	Orphans *OrphanPolicy `json:"orphans,omitempty"`
+
+	// Networks are named networks which ProxmoxMachines refer to by spec.network.name,
+	// so that the bridge, vlan and addressing of a network are defined in one place.
+	// +listType=map
+	// +listMapKey=name
+	Networks []ClusterNetwork `json:"networks,omitempty"`
+
+	// NodeGroups are named groups of Proxmox nodes which ProxmoxMachines are placed in by spec.nodeGroup,
+	// e.g. so that control plane VMs run only on dedicated hypervisors.
+	// +listType=map
+	// +listMapKey=name
+	NodeGroups []NodeGroup `json:"nodeGroups,omitempty"`
+
+	// DNS is the default DNS configuration of the machines of the cluster.
+	// spec.network of the machines and the ipam of their network take precedence.
+	DNS *DNS `json:"dns,omitempty"`
+
+	// Proxy is the HTTP proxy the machines of the cluster reach outside through.
+	// It is injected into the environment and the containerd service of the machines.
+	Proxy *Proxy `json:"proxy,omitempty"`
+
+	// Registries configure mirrors and tls verification of container registries for containerd of the machines.
+	// +listType=map
+	// +listMapKey=host
+	Registries []Registry `json:"registries,omitempty"`
+
+	// SSHKeyPair generates an ssh key pair for the cluster, stored in a Secret of the management cluster,
+	// and authorizes its public key on every machine of the cluster, so that nodes are always accessible.
+	SSHKeyPair *SSHKeyPair `json:"sshKeyPair,omitempty"`
+
+	// Quota limits the resources the machines of the cluster take from Proxmox.
+	// Machines beyond the quota are not scheduled until others are deleted or the quota is raised.
+	Quota *ResourceQuota `json:"quota,omitempty"`
+
+	// ReservedVMIDs are vmids and ranges of vmids never assigned to the machines of the cluster. e.g. 100,200-299
+	// Use it to keep the vmids of VMs managed by hand out of the way of the scheduler.
+	// +kubebuilder:validation:Pattern:=`^\d+(-\d+)?(,\d+(-\d+)?)*$`
+	ReservedVMIDs string `json:"reservedVMIDs,omitempty"`
+
+	// Tasks defines when Proxmox tasks creating machines are considered stuck.
+	// Stuck tasks are cancelled and their machines are created again, on another node if possible.
+	Tasks *TaskPolicy `json:"tasks,omitempty"`
+
+	// MachineTimeouts are the default timeouts of the phases of provisioning the machines of the cluster.
+	// Once a phase has timed out, the machine is marked failed.
+	MachineTimeouts *ProvisioningTimeouts `json:"machineTimeouts,omitempty"`
+
+	// MachineDefaults are the defaults of the settings of the machines of the cluster.
+	// Settings of a ProxmoxMachine take precedence over them.
+	// +optional
+	MachineDefaults *MachineDefaults `json:"machineDefaults,omitempty"`
+
+	// ProviderID is the format of the provider ids of the machines of the cluster. It must match the one
+	// the Proxmox cloud-controller-manager running in the workload cluster sets on its nodes, if any.
+	// Changes apply to new machines only.
+	// +optional
+	ProviderID *ProviderIDPolicy `json:"providerID,omitempty"`
+
+	// Adoption lists existing qemus of a cluster built without Cluster API to bring under its management.
+	// A Machine and a ProxmoxMachine are generated for each of them, which manage the qemu as it is
+	// instead of creating a new one.
+	// +listType=map
+	// +listMapKey=vmID
+	// +optional
+	Adoption []AdoptedVM `json:"adoption,omitempty"`
+
+	// Notifications are webhooks notified of provisioning problems of the cluster, so that they are
+	// heard of without watching events.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Notifications []NotificationHook `json:"notifications,omitempty"`
+
+	// Firewall opens the ports Kubernetes needs in the Proxmox firewall of the machines of the cluster,
+	// so that enabling the firewall does not break the cluster.
+	// +optional
+	Firewall *ClusterFirewall `json:"firewall,omitempty"`
+}
+
+// AdoptedVM maps an existing qemu to the role of its machine
+type AdoptedVM struct {
+	// VMID of the qemu
+	// +kubebuilder:validation:Minimum:=100
+	VMID int `json:"vmID"`
+
+	// Role of the node running on the qemu
+	Role AdoptionRole `json:"role"`
+
+	// Name of the generated Machine and ProxmoxMachine. Defaults to the name of the qemu.
+	// +kubebuilder:validation:MaxLength:=63
+	// +kubebuilder:validation:Pattern:=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Version is the Kubernetes version of the node running on the qemu, e.g. v1.30.4
+	// +optional
+	Version string `json:"version,omitempty"`
+}
+
+// AdoptionRole is the role of the node of an adopted qemu
+// +kubebuilder:validation:Enum:=ControlPlane;Worker
+type AdoptionRole string
+
+const (
+	AdoptionRoleControlPlane = AdoptionRole("ControlPlane")
+	AdoptionRoleWorker       = AdoptionRole("Worker")
+)
+
+// ClusterFirewall is a security group of the datacenter firewall opening the ports of Kubernetes, attached to
+// every machine of the cluster on top of its own security groups. Ports between the machines are only opened
+// to the addresses of the machines, which are kept in an IPSet of the datacenter firewall.
+// networkDevice.firewall of the machines must be enabled for the rules to apply.
+type ClusterFirewall struct {
+	// GroupName is the name of the security group and the IPSet. Defaults to "k8s-" followed by a hash
+	// of the namespace and the name of the cluster.
+	// +kubebuilder:validation:MaxLength:=18
+	// +kubebuilder:validation:Pattern:=`^[A-Za-z][A-Za-z0-9\-_]+$`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="groupName is immutable"
+	// +optional
+	GroupName string `json:"groupName,omitempty"`
+
+	// APIServerSources are CIDRs allowed to reach the API server. Defaults to anywhere.
+	// +kubebuilder:validation:items:Pattern:=`^[0-9a-fA-F.:]+(/[0-9]+)?$`
+	// +optional
+	APIServerSources []string `json:"apiServerSources,omitempty"`
+
+	// Sources are CIDRs allowed to reach the ports between the machines besides the addresses of the machines,
+	// e.g. the subnet of the machines so that new machines reach the others before their addresses are known.
+	// +kubebuilder:validation:items:Pattern:=`^[0-9a-fA-F.:]+(/[0-9]+)?$`
+	// +optional
+	Sources []string `json:"sources,omitempty"`
+
+	// NodePorts opens the NodePort range 30000-32767 to anywhere.
+	// +optional
+	NodePorts bool `json:"nodePorts,omitempty"`
+
+	// Overlay is the overlay network of the CNI whose ports are opened between the machines.
+	// VXLAN is used by Flannel, Cilium and Calico, Geneve by Cilium and OVN-Kubernetes,
+	// BGP by Calico with IP-in-IP, and WireGuard by Calico and Cilium with encryption.
+	// +kubebuilder:default:=VXLAN
+	// +optional
+	Overlay FirewallOverlay `json:"overlay,omitempty"`
+}
+
+// FirewallOverlay is the overlay network of a CNI
+// +kubebuilder:validation:Enum:=VXLAN;Geneve;BGP;WireGuard;None
+type FirewallOverlay string
+
+const (
+	FirewallOverlayVXLAN     = FirewallOverlay("VXLAN")
+	FirewallOverlayGeneve    = FirewallOverlay("Geneve")
+	FirewallOverlayBGP       = FirewallOverlay("BGP")
+	FirewallOverlayWireGuard = FirewallOverlay("WireGuard")
+	FirewallOverlayNone      = FirewallOverlay("None")
+)
+
+// NotificationHook is a webhook notified of provisioning problems
+type NotificationHook struct {
+	// Name of the hook
+	// +kubebuilder:validation:MaxLength:=63
+	// +kubebuilder:validation:Pattern:=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// SecretName is the Secret in the namespace of the cluster holding the url of the webhook as "url".
+	// Urls of webhooks like the ones of Slack contain credentials.
+	SecretName string `json:"secretName"`
+
+	// Format of the payload. Generic posts the notification as JSON, Slack posts a message
+	// for incoming webhooks of Slack and compatible chats like Mattermost.
+	// +kubebuilder:default:=Generic
+	// +optional
+	Format NotificationFormat `json:"format,omitempty"`
+
+	// Events the hook is notified of. Defaults to all of them.
+	// +optional
+	Events []NotificationEvent `json:"events,omitempty"`
+}
+
+// NotificationFormat is the format of the payload of a notification hook
+// +kubebuilder:validation:Enum:=Generic;Slack
+type NotificationFormat string
+
+const (
+	NotificationFormatGeneric = NotificationFormat("Generic")
+	NotificationFormatSlack   = NotificationFormat("Slack")
+)
+
+// NotificationEvent is a provisioning problem hooks are notified of
+// +kubebuilder:validation:Enum:=MachineFailed;SchedulingFailed;EndpointUnavailable
+type NotificationEvent string
+
+const (
+	// NotificationEventMachineFailed is sent once a machine has failed for good, e.g. by a provisioning timeout
+	NotificationEventMachineFailed = NotificationEvent("MachineFailed")
+	// NotificationEventSchedulingFailed is sent once no node has fit a machine several times in a row
+	NotificationEventSchedulingFailed = NotificationEvent("SchedulingFailed")
+	// NotificationEventEndpointUnavailable is sent once reconciles are paused since the Proxmox API is unavailable
+	NotificationEventEndpointUnavailable = NotificationEvent("EndpointUnavailable")
+)
+
+// ProviderIDPolicy is the format of the provider ids of machines
+// +kubebuilder:validation:XValidation:rule="self.format != 'RegionVMID' || has(self.region)",message="region is required for format RegionVMID"
+type ProviderIDPolicy struct {
+	// Format of the provider ids. UUID is proxmox://<smbios uuid>, as set by the
+	// proxmox-cloud-controller-manager with the capmox provider. RegionVMID is proxmox://<region>/<vmid>,
+	// as set by the proxmox-cloud-controller-manager by default. Defaults to UUID.
+	// +kubebuilder:default:=UUID
+	// +optional
+	Format ProviderIDFormat `json:"format,omitempty"`
+
+	// Region is the name of the Proxmox cluster in the config of the cloud-controller-manager.
+	// +kubebuilder:validation:Pattern:=`^[a-zA-Z\d]([-a-zA-Z\d_.]*[a-zA-Z\d])?$`
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// ProviderIDFormat is the format of provider ids
+// +kubebuilder:validation:Enum:=UUID;RegionVMID
+type ProviderIDFormat string
+
+const (
+	// ProviderIDFormatUUID is proxmox://<smbios uuid>
+	ProviderIDFormatUUID = ProviderIDFormat("UUID")
+	// ProviderIDFormatRegionVMID is proxmox://<region>/<vmid>
+	ProviderIDFormatRegionVMID = ProviderIDFormat("RegionVMID")
+)
+
+// MachineDefaults are the settings of ProxmoxMachines which do not set them.
+type MachineDefaults struct {
+	// Image of qemu machines which specify neither image nor restore
+	// +optional
+	Image *Image `json:"image,omitempty"`
+
+	// Tags of machines which do not specify options.tags.
+	// Tags of a machine replace the default tags instead of being added to them.
+	// +optional
+	Tags Tags `json:"tags,omitempty"`
+
+	// NameTemplate of the qemus of machines which do not specify nameTemplate.
+	// Changing it does not rename existing VMs.
+	// +optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+}
+
+// ResourceQuota limits the resources of the machines of a cluster. Unset limits are unlimited.
+type ResourceQuota struct {
+	// maximum number of VMs and containers
+	// +kubebuilder:validation:Minimum:=1
+	MaxVMs int `json:"maxVMs,omitempty"`
+
+	// maximum total vCPUs. hardware.cpu * hardware.sockets of each machine
+	// +kubebuilder:validation:Minimum:=1
+	CPU int `json:"cpu,omitempty"`
+
+	// maximum total memory in MiB
+	// +kubebuilder:validation:Minimum:=1
+	Memory int `json:"memory,omitempty"`
+
+	// maximum total size of root and extra disks in GiB
+	// +kubebuilder:validation:Minimum:=1
+	Disk int `json:"disk,omitempty"`
+}
+
+// ClusterNetwork is a network which the machines of the cluster are connected to.
+// Its values take precedence over hardware.networkDevice of the machines referring to it,
+// and its ipam fills what spec.network of the machines leaves empty.
+type ClusterNetwork struct {
+	// name referred to by spec.network.name of ProxmoxMachines
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name"`
+
+	// bridge of the network device
+	Bridge NetworkDeviceBridge `json:"bridge,omitempty"`
+
+	// VLAN tag of the network device
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=4094
+	VLAN int `json:"vlan,omitempty"`
+
+	// MTU of the network device
+	// +kubebuilder:validation:Minimum:=576
+	// +kubebuilder:validation:Maximum:=65520
+	MTU int `json:"mtu,omitempty"`
+
+	// IPAM is the addressing of the network
+	IPAM *NetworkIPAM `json:"ipam,omitempty"`
+}
+
+// NetworkIPAM is the addressing of a network. Machines use dhcp unless they have static ips,
+// and the gateways apply to static ips only.
+type NetworkIPAM struct {
+	// gateway IPv4
+	Gateway string `json:"gateway,omitempty"`
+
+	// gateway IPv6
+	Gateway6 string `json:"gateway6,omitempty"`
+
+	// DNS server
+	NameServer string `json:"nameServer,omitempty"`
+
+	// search domain
+	SearchDomain string `json:"searchDomain,omitempty"`
+}
+
+// Device returns the network device of a machine connected to the network
+func (n *ClusterNetwork) Device(device NetworkDevice) NetworkDevice {
+	if n.Bridge != "" {
+		device.Bridge = n.Bridge
+	}
+	if n.VLAN != 0 {
+		device.Tag = n.VLAN
+	}
+	if n.MTU != 0 {
+		device.MTU = n.MTU
+	}
+	return device
+}
+
+// Network returns the network configuration of a machine connected to the network
+func (n *ClusterNetwork) Network(network Network) Network {
+	if n.IPAM == nil {
+		return network
+	}
+	ipconfig := &network.IPConfig
+	if ipconfig.Gateway == "" && isStatic(ipconfig.IP) {
+		ipconfig.Gateway = n.IPAM.Gateway
+	}
+	if ipconfig.Gateway6 == "" && isStatic(ipconfig.IP6) {
+		ipconfig.Gateway6 = n.IPAM.Gateway6
+	}
+	if network.NameServer == "" {
+		network.NameServer = n.IPAM.NameServer
+	}
+	if network.SearchDomain == "" {
+		network.SearchDomain = n.IPAM.SearchDomain
+	}
+	return network
+}
+
+// DNS is the DNS configuration of machines
+type DNS struct {
+	// DNS servers separated by spaces. e.g. "10.0.0.2 10.0.0.3"
+	NameServer string `json:"nameServer,omitempty"`
+
+	// search domains separated by spaces
+	SearchDomain string `json:"searchDomain,omitempty"`
+}
+
+// Network returns the network configuration of a machine whose empty dns fields are defaulted
+func (d *DNS) Network(network Network) Network {
+	if network.NameServer == "" {
+		network.NameServer = d.NameServer
+	}
+	if network.SearchDomain == "" {
+		network.SearchDomain = d.SearchDomain
+	}
+	return network
+}
+
+// Proxy is the HTTP proxy configuration of machines
+// +kubebuilder:validation:XValidation:rule="has(self.httpProxy) || has(self.httpsProxy)",message="either httpProxy or httpsProxy is required"
+type Proxy struct {
+	// proxy for http requests. e.g. "http://proxy.example.com:3128"
+	// +kubebuilder:validation:Pattern:="^https?://.+"
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// proxy for https requests. e.g. "http://proxy.example.com:3128"
+	// +kubebuilder:validation:Pattern:="^https?://.+"
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// hosts, domains and CIDRs reached without the proxy.
+	// localhost, the control plane endpoint and the pod and service CIDRs of the cluster are always added
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// Registry configures how containerd pulls images of a container registry.
+// It is rendered into /etc/containerd/certs.d/<host>/hosts.toml
+type Registry struct {
+	// host of the registry. e.g. "docker.io", "registry.example.com:5000"
+	// +kubebuilder:validation:Pattern:="^[^/]+$"
+	Host string `json:"host"`
+
+	// endpoints tried in order before the registry itself. e.g. "https://mirror.example.com"
+	// +kubebuilder:validation:items:Pattern:="^https?://.+"
+	Mirrors []string `json:"mirrors,omitempty"`
+
+	// skip tls verification of the registry and its mirrors
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// Server returns the endpoint of the registry used when no mirror serves the image
+func (r *Registry) Server() string {
+	if r.Host == "docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + r.Host
+}
+
+// NodeGroup is a group of Proxmox nodes. A node is in the group if it is listed in nodes or matches nodeRegex.
+// +kubebuilder:validation:XValidation:rule="has(self.nodes) || has(self.nodeRegex)",message="nodes or nodeRegex is required"
+type NodeGroup struct {
+	// name referred to by spec.nodeGroup of ProxmoxMachines
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name"`
+
+	// names of the nodes of the group
+	Nodes []string `json:"nodes,omitempty"`
+
+	// regex matching the names of the nodes of the group. e.g. pve-infra-[0-9]+
+	NodeRegex string `json:"nodeRegex,omitempty"`
+}
+
+// Contains returns true if the node is in the group
+func (g *NodeGroup) Contains(node string) bool {
+	if slices.Contains(g.Nodes, node) {
+		return true
+	}
+	if g.NodeRegex == "" {
+		return false
+	}
+	reg, err := regexp.Compile(g.NodeRegex)
+	return err == nil && reg.MatchString(node)
+}
+
+func isStatic(ip string) bool {
+	return ip != "" && ip != "dhcp" && ip != "auto"
+}
+
+// TagMapping maps a label or an annotation to the tag <prefix><value>.
+// Values of the ProxmoxMachine take precedence over the ones of the Machine.
+// Tags having the prefix are owned by the mapping, other tags with the same prefix are removed.
+// +kubebuilder:validation:XValidation:rule="has(self.label) != has(self.annotation)",message="exactly one of label or annotation must be set"
+type TagMapping struct {
+	// Label key. e.g. cluster.x-k8s.io/deployment-name
+	Label string `json:"label,omitempty"`
+
+	// Annotation key
+	Annotation string `json:"annotation,omitempty"`
+
+	// Prefix of the tag. Defaults to the name of the key without its domain followed by ".".
+	// e.g. deployment-name.
+	// +kubebuilder:validation:Pattern:=`^[a-zA-Z0-9_][a-zA-Z0-9_+.-]*$`
+	// +kubebuilder:validation:MaxLength:=64
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// SSHKeyPair is the ssh key pair of a cluster.
+// The key pair is generated unless its Secret exists, and deleted with the ProxmoxCluster if cappx generated it.
+type SSHKeyPair struct {
+	// SecretName is the Secret in the namespace of the cluster holding the key pair
+	// as "ssh-privatekey" and "ssh-publickey". Defaults to <cluster name>-ssh.
+	// An existing Secret is used as it is, e.g. to bring a key pair of your own.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="secretName is immutable"
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// ResourcePool is the Proxmox resource pool of a cluster.
+// The pool is created unless it exists, and deleted with the cluster only if cappx created it and it is empty.
+type ResourcePool struct {
+	// Name of the pool. Defaults to the name of the Cluster.
+	// +kubebuilder:validation:Pattern:=`^[A-Za-z0-9._-]+$`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="pool name is immutable"
+	Name string `json:"name,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=migrate;recreate
+type RebalanceStrategy string
+
+const (
+	// RebalanceStrategyMigrate live-migrates VMs to less loaded nodes
+	RebalanceStrategyMigrate = RebalanceStrategy("migrate")
+	// RebalanceStrategyRecreate deletes Machines on overloaded nodes so that
+	// the scheduler places their replacements
+	RebalanceStrategyRecreate = RebalanceStrategy("recreate")
+)
+
+// RebalancePolicy defines when and how VMs are rebalanced.
+// VMs of the same control plane or MachineDeployment are never moved onto the same node.
+type RebalancePolicy struct {
+	// Interval between evaluations of the node load. Defaults to 10m.
+	// +kubebuilder:default:="10m"
+	Interval metav1.Duration `json:"interval,omitempty"`
+
+	// Threshold is the difference of memory usage in percent between the most and the least loaded nodes
+	// above which VMs are moved. Defaults to 20.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=100
+	// +kubebuilder:default:=20
+	Threshold int `json:"threshold,omitempty"`
+
+	// Strategy to move VMs. Defaults to migrate.
+	// +kubebuilder:default:=migrate
+	Strategy RebalanceStrategy `json:"strategy,omitempty"`
+
+	// MaxMoves is the budget of VMs moved per evaluation. Defaults to 1.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:default:=1
+	MaxMoves int `json:"maxMoves,omitempty"`
+}
+
+// NodeFailurePolicy defines when an offline Proxmox node is considered down permanently.
+type NodeFailurePolicy struct {
+	// Timeout is how long a node must be offline before it is considered down. Defaults to 10m.
+	// +kubebuilder:default:="10m"
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+
+	// SkipConfirmation considers nodes down as soon as the timeout has passed.
+	// Otherwise the node must also be listed in the
+	// infrastructure.cluster.x-k8s.io/proxmox-nodes-down annotation of the ProxmoxCluster.
+	SkipConfirmation bool `json:"skipConfirmation,omitempty"`
+}
+
+// TaskPolicy defines when a Proxmox task is considered stuck.
+type TaskPolicy struct {
+	// Timeout is how long a task may run without writing to its log before it is cancelled. Defaults to 15m.
+	// +kubebuilder:default:="15m"
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=report;delete
+type OrphanAction string
+
+const (
+	// OrphanActionReport records orphaned VMs in the status and as events
+	OrphanActionReport = OrphanAction("report")
+	// OrphanActionDelete also stops and deletes orphaned VMs
+	OrphanActionDelete = OrphanAction("delete")
+)
+
+// OrphanPolicy defines how often orphaned VMs are looked for and what is done with them.
+type OrphanPolicy struct {
+	// Interval between checks. Defaults to 10m.
+	// +kubebuilder:default:="10m"
+	Interval metav1.Duration `json:"interval,omitempty"`
+
+	// Action taken on orphaned VMs. Defaults to report.
+	// VMs are deleted only once they have been orphaned for a full interval, only if they are tagged
+	// with their ProxmoxMachine and only if their node is reachable.
+	// +kubebuilder:default:=report
+	Action OrphanAction `json:"action,omitempty"`
+}
+
+// OrphanedVM is a VM of the cluster belonging to no ProxmoxMachine
+type OrphanedVM struct {
+	// VMID of the VM
+	VMID int `json:"vmid"`
+
+	// Name of the VM
+	Name string `json:"name,omitempty"`
+
+	// Node the VM is on
+	Node string `json:"node"`
+
+	// Since is the time the VM was first observed orphaned
+	Since metav1.Time `json:"since"`
+}
+
+// OfflineNode is a Proxmox node observed offline
+type OfflineNode struct {
+	// Name of the node
+	Name string `json:"name"`
+
+	// Since is the time the node was first observed offline
+	Since metav1.Time `json:"since"`
 }
 
 // ProxmoxClusterStatus defines the observed state of ProxmoxCluster
@@ -49,6 +683,56 @@ type ProxmoxClusterStatus struct {
 
 	// Conditions
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+
+	// LastRebalanceTime is the time VMs were last evaluated for rebalancing
+	LastRebalanceTime *metav1.Time `json:"lastRebalanceTime,omitempty"`
+
+	// OfflineNodes are the Proxmox nodes currently offline. Only tracked when nodeFailure is set.
+	OfflineNodes []OfflineNode `json:"offlineNodes,omitempty"`
+
+	// DownNodes are the Proxmox nodes considered down permanently
+	DownNodes []string `json:"downNodes,omitempty"`
+
+	// Pool is the Proxmox resource pool new VMs of the cluster are placed into
+	Pool string `json:"pool,omitempty"`
+
+	// LastOrphanCheckTime is the time VMs were last checked for orphans
+	LastOrphanCheckTime *metav1.Time `json:"lastOrphanCheckTime,omitempty"`
+
+	// OrphanedVMs are the VMs of the cluster belonging to no ProxmoxMachine
+	OrphanedVMs []OrphanedVM `json:"orphanedVMs,omitempty"`
+
+	// ProxmoxVersion is the version of Proxmox VE detected at the endpoint, e.g. 8.1.4
+	ProxmoxVersion string `json:"proxmoxVersion,omitempty"`
+
+	// SSHPublicKey is the public key of the ssh key pair of the cluster authorized on its machines
+	SSHPublicKey string `json:"sshPublicKey,omitempty"`
+
+	// Initialization reports the provisioning of the cluster following the v1beta2 contract of Cluster API.
+	// +optional
+	Initialization *ProxmoxClusterInitializationStatus `json:"initialization,omitempty"`
+
+	// V1Beta2 groups the conditions of the cluster following the v1beta2 contract of Cluster API.
+	// +optional
+	V1Beta2 *ProxmoxClusterV1Beta2Status `json:"v1beta2,omitempty"`
+}
+
+// ProxmoxClusterInitializationStatus reports the provisioning of the cluster
+type ProxmoxClusterInitializationStatus struct {
+	// Provisioned is true once the infrastructure of the cluster is ready and the control-plane endpoint is set.
+	// It is never reset, like ready of the v1beta1 contract.
+	// +optional
+	Provisioned *bool `json:"provisioned,omitempty"`
+}
+
+// ProxmoxClusterV1Beta2Status groups the conditions of the v1beta2 contract of Cluster API
+type ProxmoxClusterV1Beta2Status struct {
+	// Conditions of the cluster: Ready and Paused.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:MaxItems=32
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -76,6 +760,30 @@ type ProxmoxClusterList struct {
 	Items           []ProxmoxCluster `json:"items"`
 }
 
+func (c *ProxmoxCluster) GetConditions() clusterv1.Conditions {
+	return c.Status.Conditions
+}
+
+func (c *ProxmoxCluster) SetConditions(conditions clusterv1.Conditions) {
+	c.Status.Conditions = conditions
+}
+
+// GetV1Beta2Conditions returns the conditions of the v1beta2 contract
+func (c *ProxmoxCluster) GetV1Beta2Conditions() []metav1.Condition {
+	if c.Status.V1Beta2 == nil {
+		return nil
+	}
+	return c.Status.V1Beta2.Conditions
+}
+
+// SetV1Beta2Conditions sets the conditions of the v1beta2 contract
+func (c *ProxmoxCluster) SetV1Beta2Conditions(conditions []metav1.Condition) {
+	if c.Status.V1Beta2 == nil {
+		c.Status.V1Beta2 = &ProxmoxClusterV1Beta2Status{}
+	}
+	c.Status.V1Beta2.Conditions = conditions
+}
+
 func init() {
 	SchemeBuilder.Register(&ProxmoxCluster{}, &ProxmoxClusterList{})
 }
@@ -26,34 +26,167 @@ import (
 const (
 	// MachineFinalizer
 	MachineFinalizer = "proxmoxmachine.infrastructure.cluster.x-k8s.io"
+
+	// Labels put on the Machine describing the placement of its vm.
+	// Cluster API syncs labels of the node.cluster.x-k8s.io domain to the Node of the workload cluster.
+	ProxmoxNodeLabel          = "node.cluster.x-k8s.io/proxmox-node"
+	ProxmoxStorageLabel       = "node.cluster.x-k8s.io/proxmox-storage"
+	ProxmoxFailureDomainLabel = "node.cluster.x-k8s.io/proxmox-failure-domain"
+
+	// ConfigHashAnnotation is the hash of the qemu config last applied by cappx.
+	// Remove it to accept the current config of the qemu.
+	ConfigHashAnnotation = "infrastructure.cluster.x-k8s.io/proxmox-config-hash"
+
+	// BootstrapDataHashAnnotation is the hash of the bootstrap data last written to the snippet of the machine.
+	// The snippet is rewritten when the bootstrap data changes until the machine has joined the cluster.
+	BootstrapDataHashAnnotation = "infrastructure.cluster.x-k8s.io/proxmox-bootstrap-data-hash"
+
+	// SMBiosUUIDAnnotation is the smbios uuid generated for a qemu whose options.smbios has no uuid.
+	// It is generated once so that the qemu is created with the same uuid however often it is retried.
+	SMBiosUUIDAnnotation = "infrastructure.cluster.x-k8s.io/proxmox-smbios-uuid"
+
+	// DryRunAnnotation puts the ProxmoxMachine, or all machines of the ProxmoxCluster, in dry-run mode.
+	// What cappx would do is published in status.plan instead of calling mutating Proxmox APIs.
+	DryRunAnnotation = "infrastructure.cluster.x-k8s.io/proxmox-dry-run"
+
+	// AdoptedAnnotation marks ProxmoxMachines generated to adopt an existing qemu of spec.adoption of
+	// the ProxmoxCluster. The value is the vmid of the qemu.
+	AdoptedAnnotation = "infrastructure.cluster.x-k8s.io/proxmox-adopted"
+)
+
+const (
+	// ConfigInSyncCondition reports whether the config of the qemu is the one last applied by cappx.
+	// It turns false when the qemu is edited out of band, e.g. in the Proxmox web UI.
+	ConfigInSyncCondition clusterv1.ConditionType = "ConfigInSync"
+
+	// ConfigDriftedReason is used when the config of the qemu has changed out of band.
+	ConfigDriftedReason = "ConfigDrifted"
+
+	// Conditions of the steps deleting the instance, in order. Each one turns true once its step
+	// is completed, so that a failed step is retried without running the completed ones again.
+	HAResourceDeletedCondition  clusterv1.ConditionType = "HAResourceDeleted"
+	ReplicationDeletedCondition clusterv1.ConditionType = "ReplicationDeleted"
+	InstanceStoppedCondition    clusterv1.ConditionType = "InstanceStopped"
+	DisksWipedCondition         clusterv1.ConditionType = "DisksWiped"
+	CloudInitDeletedCondition   clusterv1.ConditionType = "CloudInitDeleted"
+	InstanceDeletedCondition    clusterv1.ConditionType = "InstanceDeleted"
+	VolumesDeletedCondition     clusterv1.ConditionType = "VolumesDeleted"
+
+	// DeletionFailedReason is used when a deletion step has failed. It is retried.
+	DeletionFailedReason = "DeletionFailed"
+
+	// InstanceOwnershipVerifiedCondition reports whether the guest having the vmid of the machine belongs to it.
+	// It turns false when the guest is not tagged with the machine, e.g. the vmid has been reused after
+	// manual changes in Proxmox, and the guest is neither stopped nor deleted.
+	InstanceOwnershipVerifiedCondition clusterv1.ConditionType = "InstanceOwnershipVerified"
+
+	// OwnershipMismatchReason is used when the guest having the vmid of the machine belongs to something else.
+	OwnershipMismatchReason = "OwnershipMismatch"
+
+	// WithinQuotaCondition reports whether the machine fits in the quota of its ProxmoxCluster.
+	// Machines not within the quota are not scheduled.
+	WithinQuotaCondition clusterv1.ConditionType = "WithinQuota"
+
+	// QuotaExceededReason is used when the machine would exceed the quota of its ProxmoxCluster.
+	QuotaExceededReason = "QuotaExceeded"
+
+	// TemplateInSyncCondition reports whether the spec of the machine matches the ProxmoxMachineTemplate
+	// it was created from. It turns false when the template is edited in place, which does not roll out machines.
+	TemplateInSyncCondition clusterv1.ConditionType = "TemplateInSync"
+
+	// TemplateChangedReason is used when the ProxmoxMachineTemplate has changed since the machine was created.
+	TemplateChangedReason = "TemplateChanged"
 )
 
 // ProxmoxMachineSpec defines the desired state of ProxmoxMachine
+// +kubebuilder:validation:XValidation:rule="has(self.type) && self.type == 'lxc' ? has(self.container) && !has(self.image) && !has(self.restore) : !(has(self.image) && has(self.restore))",message="at most one of image or restore may be specified for qemu, container for lxc"
+// +kubebuilder:validation:XValidation:rule="!has(self.options) || !has(self.options.template) || !self.options.template",message="options.template can not be enabled for a machine provisioned from spec.image"
+// +kubebuilder:validation:XValidation:rule="!has(self.nodeName) || !has(self.nodeGroup)",message="at most one of nodeName or nodeGroup may be specified"
+// +kubebuilder:validation:XValidation:rule="!has(self.options) || !has(self.options.hugePages) || self.options.hugePages == 0 || !has(self.hardware) || !has(self.hardware.memory) || self.hardware.memory % self.options.hugePages == 0",message="hardware.memory must be a multiple of options.hugePages"
+// +kubebuilder:validation:XValidation:rule="!has(self.options) || !has(self.options.vcpus) || !has(self.hardware) || !has(self.hardware.cpu) || self.options.vcpus <= self.hardware.cpu * (has(self.hardware.sockets) ? self.hardware.sockets : 1)",message="options.vcpus must not exceed hardware.cpu * hardware.sockets"
+// +kubebuilder:validation:XValidation:rule="!has(self.hardware) || !has(self.hardware.memoryHotplug) || !self.hardware.memoryHotplug || (has(self.options) && has(self.options.numa) && self.options.numa)",message="hardware.memoryHotplug requires options.numa"
+// +kubebuilder:validation:XValidation:rule="!has(self.options) || !has(self.options.hotPlug) || !('memory' in self.options.hotPlug) || (has(self.options.numa) && self.options.numa)",message="memory hotplug requires options.numa"
+// +kubebuilder:validation:XValidation:rule="!has(self.options) || !has(self.options.arch) || self.options.arch != 'aarch64' || !has(self.hardware) || !has(self.hardware.bios) || self.hardware.bios == 'ovmf'",message="aarch64 requires hardware.bios ovmf"
+// +kubebuilder:validation:XValidation:rule="!has(self.options) || !has(self.options.arch) || self.options.arch != 'aarch64' || !has(self.hardware) || !has(self.hardware.machine) || self.hardware.machine.startsWith('virt')",message="aarch64 requires a virt hardware.machine"
+// +kubebuilder:validation:XValidation:rule="!has(self.options) || !has(self.options.arch) || self.options.arch != 'aarch64' || !has(self.hardware) || !has(self.hardware.extraDisks) || size(self.hardware.extraDisks) < 30",message="aarch64 supports at most 29 extra disks since scsi30 holds the cloud-init drive"
 type ProxmoxMachineSpec struct {
-	// ProviderID
+	// ProviderID is proxmox://<uuid> or proxmox://<region>/<vmid> depending on providerID.format
+	// of the ProxmoxCluster. It is set by cappx, or adopted from the node of the machine set by a
+	// Proxmox cloud-controller-manager.
+	// +kubebuilder:validation:Pattern:=`^proxmox://([a-fA-F\d]{8}-[a-fA-F\d]{4}-[a-fA-F\d]{4}-[a-fA-F\d]{4}-[a-fA-F\d]{12}|[a-zA-Z\d]([-a-zA-Z\d_.]*[a-zA-Z\d])?/\d+)$`
 	ProviderID *string `json:"providerID,omitempty"`
 
 	// Node is proxmox node hosting vm instance which used for ProxmoxMachine
 	Node string `json:"node,omitempty"`
 
+	// NodeName pins the instance to the proxmox node, e.g. for machines tied to local hardware.
+	// Filter and score plugins of the scheduler are skipped, but the node must exist and be online
+	// and storage must be available on it. Pinned instances are never rebalanced or migrated off the node.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="nodeName is immutable"
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Priority of the machine when cappx moves instances between nodes.
+	// Machines of low priority are migrated or recreated first by rebalancing and node maintenance,
+	// and machines of high priority are never moved automatically.
+	// +kubebuilder:default:=normal
+	// +optional
+	Priority MachinePriority `json:"priority,omitempty"`
+
+	// NodeGroup places the instance on the nodes of the node group of the ProxmoxCluster,
+	// e.g. so that control plane machines run only on dedicated hypervisors.
+	// +optional
+	NodeGroup string `json:"nodeGroup,omitempty"`
+
 	// Storage is name of proxmox storage used by this node.
 	// The storage must support "images(VM Disks)" type of content.
 	// cappx will use random storage if empty
 	Storage string `json:"storage,omitempty"`
 
+	// SnippetStorage overrides the snippet storage of the ProxmoxCluster for the cloud-init snippets
+	// of this machine. It must exist and support "snippets" content on the node of the machine.
+	// +kubebuilder:validation:XValidation:rule="has(self.name) && has(self.path)",message="name and path are required"
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="snippetStorage is immutable"
+	// +optional
+	SnippetStorage *Storage `json:"snippetStorage,omitempty"`
+
 	// +kubebuilder:validation:Minimum:=0
 	// VMID is proxmox qemu's id
 	VMID *int `json:"vmID,omitempty"`
 
-	// Image is the image to be provisioned
-	Image Image `json:"image"`
+	// NameTemplate is the go template of the name of the qemu on Proxmox, so that VMs follow
+	// the naming conventions of the hypervisor. e.g. "k8s-{{ .ClusterName }}-{{ .VMID }}"
+	// Available values are .ClusterName, .Namespace, .MachineName, .ProxmoxMachineName, .VMID and .FailureDomain.
+	// {{ trunc n .Value }} keeps the first n characters of a value. The name must be a dns name of at most 63 characters.
+	// Defaults to machineDefaults.nameTemplate of the ProxmoxCluster, or the name of the ProxmoxMachine.
+	// Containers are always named after the ProxmoxMachine since the name is their hostname.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="nameTemplate is immutable"
+	// +optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+
+	// Type is the type of the proxmox guest backing the machine. Defaults to qemu.
+	// +kubebuilder:default:=qemu
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="type is immutable"
+	Type InstanceType `json:"type,omitempty"`
+
+	// Image is the image to be provisioned. Defaults to machineDefaults.image of the ProxmoxCluster
+	// unless restore is specified.
+	Image *Image `json:"image,omitempty"`
+
+	// Restore provisions the machine from a backup instead of an image.
+	// hardware and options are taken from the backup.
+	Restore *Restore `json:"restore,omitempty"`
+
+	// Container defines the lxc container provisioned for the machine of type lxc.
+	// Only cpu, memory, rootDisk and networkDevice.bridge/firewall of hardware and network apply to containers.
+	Container *Container `json:"container,omitempty"`
 
 	// CloudInit defines options related to the bootstrapping systems where
 	// CloudInit is used.
 	CloudInit CloudInit `json:"cloudInit,omitempty"`
 
 	// Hardware
-	// +kubebuilder:default:={cpu:2,disk:"50G",memory:4096,networkDevice:{model:virtio,bridge:vmbr0,firewall:true}}
+	// +kubebuilder:default:={cpu:2,rootDisk:"50G",memory:4096,networkDevice:{model:virtio,bridge:vmbr0,firewall:true}}
 	Hardware Hardware `json:"hardware,omitempty"`
 
 	// Network
@@ -62,10 +195,73 @@ type ProxmoxMachineSpec struct {
 	// Options for QEMU instance
 	Options Options `json:"options,omitempty"`
 
+	// SnapshotPolicy defines snapshots taken automatically by cappx
+	SnapshotPolicy *SnapshotPolicy `json:"snapshotPolicy,omitempty"`
+
+	// HA registers the VM with the Proxmox HA manager. Typically set for control-plane machines.
+	// The VM is deregistered when the machine is deleted.
+	HA *HighAvailability `json:"ha,omitempty"`
+
+	// Replication replicates the VM disks to another node so that the VM can be recovered there after node loss.
+	Replication *Replication `json:"replication,omitempty"`
+
+	// Readiness delays the machine becoming ready until a tcp port of the guest accepts connections.
+	// Unset, the machine is ready once its guest is running. e.g. 5985 waits for WinRM of windows guests,
+	// which reboot after cloudbase-init renamed them. The port is not checked anymore once the machine is ready.
+	Readiness *Readiness `json:"readiness,omitempty"`
+
+	// Firewall attaches datacenter-level security groups to the instance
+	Firewall *Firewall `json:"firewall,omitempty"`
+
+	// AddressFilter selects the network interfaces and subnets of the guest whose addresses
+	// become the addresses of the machine and are used for the readiness check.
+	AddressFilter *AddressFilter `json:"addressFilter,omitempty"`
+
+	// Timeouts limit the phases of provisioning the machine. Unset ones default to machineTimeouts of the ProxmoxCluster.
+	Timeouts *ProvisioningTimeouts `json:"timeouts,omitempty"`
+
+	// WipeDisksOnDelete overwrites the disks of the instance with zeros before they are removed,
+	// e.g. for compliance where data must not be left behind on shared storage.
+	// Deleting the machine takes as long as writing the size of its disks.
+	// Machines are not created on storages whose volumes can not be wiped, e.g. Ceph RBD without krbd.
+	// +optional
+	WipeDisksOnDelete bool `json:"wipeDisksOnDelete,omitempty"`
+
 	// FailureDomain is the failure domain unique identifier this Machine should be attached to, as defined in Cluster API.
 	FailureDomain *string `json:"failureDomain,omitempty"`
 }
 
+// MachinePriority is how important a machine is when cappx moves instances between nodes
+// +kubebuilder:validation:Enum:=low;normal;high
+type MachinePriority string
+
+const (
+	// MachinePriorityLow machines are moved before others
+	MachinePriorityLow = MachinePriority("low")
+	// MachinePriorityNormal is the default priority
+	MachinePriorityNormal = MachinePriority("normal")
+	// MachinePriorityHigh machines are never moved automatically
+	MachinePriorityHigh = MachinePriority("high")
+)
+
+// Rank orders priorities. machines of lower rank are moved first
+func (p MachinePriority) Rank() int {
+	switch p {
+	case MachinePriorityLow:
+		return 0
+	case MachinePriorityHigh:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Movable returns true if cappx may move the instance to another node automatically,
+// i.e. it is neither pinned to its node nor of high priority
+func (s *ProxmoxMachineSpec) Movable() bool {
+	return s.NodeName == "" && s.Priority != MachinePriorityHigh
+}
+
 // ProxmoxMachineStatus defines the observed state of ProxmoxMachine
 type ProxmoxMachineStatus struct {
 	// Ready is true when the provider resource is ready.
@@ -78,9 +274,15 @@ type ProxmoxMachineStatus struct {
 	// FailureMessage
 	FailureMessage *string `json:"failureMessage,omitempty"`
 
-	// Addresses
+	// Addresses are the hostname and the addresses of the network interfaces of the instance.
+	// Addresses of the primary interface come first.
 	Addresses []clusterv1.MachineAddress `json:"addresses,omitempty"`
 
+	// NetworkInterfaces are the network interfaces of the instance reported by the qemu guest agent
+	// or by the container, except loopback and interfaces created by container runtimes and CNIs
+	// +optional
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+
 	// Conditions
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 
@@ -90,17 +292,134 @@ type ProxmoxMachineStatus struct {
 	// InstanceStatus is the status of the proxmox instance for this machine.
 	// +optional
 	InstanceStatus *InstanceStatus `json:"instanceStatus,omitempty"` // InstanceStatus
+
+	// Console describes how to reach the console of the instance
+	// +optional
+	Console *Console `json:"console,omitempty"`
+
+	// Plan is what cappx would do for the machine. Only set in dry-run mode.
+	// +optional
+	Plan *Plan `json:"plan,omitempty"`
+
+	// FailedNodes are nodes creating the instance has failed on for node-specific reasons,
+	// e.g. a full storage, the node going offline or a stuck task. The instance is created on other nodes
+	// while any fits. Cleared once the instance is created.
+	// +optional
+	FailedNodes []string `json:"failedNodes,omitempty"`
+
+	// ProvisioningPhase is the phase of provisioning the machine is in. Cleared once the node of the Machine has joined.
+	// +optional
+	ProvisioningPhase *ProvisioningPhase `json:"provisioningPhase,omitempty"`
+
+	// Initialization reports the provisioning of the machine following the v1beta2 contract of Cluster API.
+	// +optional
+	Initialization *ProxmoxMachineInitializationStatus `json:"initialization,omitempty"`
+
+	// V1Beta2 groups the conditions of the machine following the v1beta2 contract of Cluster API.
+	// +optional
+	V1Beta2 *ProxmoxMachineV1Beta2Status `json:"v1beta2,omitempty"`
+}
+
+// ProxmoxMachineInitializationStatus reports the provisioning of the machine
+type ProxmoxMachineInitializationStatus struct {
+	// Provisioned is true once the instance is running and the provider id is set.
+	// It is never reset, like ready of the v1beta1 contract.
+	// +optional
+	Provisioned *bool `json:"provisioned,omitempty"`
+}
+
+// ProxmoxMachineV1Beta2Status groups the conditions of the v1beta2 contract of Cluster API
+type ProxmoxMachineV1Beta2Status struct {
+	// Conditions of the machine: Ready and Paused.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:MaxItems=32
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=Create;Restore;Delete;None
+type PlanAction string
+
+const (
+	PlanActionCreate  = PlanAction("Create")
+	PlanActionRestore = PlanAction("Restore")
+	PlanActionDelete  = PlanAction("Delete")
+	// the instance exists and is left as it is
+	PlanActionNone = PlanAction("None")
+)
+
+// Plan describes the instance cappx would create or delete
+type Plan struct {
+	// Action cappx would take
+	Action PlanAction `json:"action"`
+
+	// Type of the instance
+	Type InstanceType `json:"type,omitempty"`
+
+	// Node the instance would be placed on
+	Node string `json:"node,omitempty"`
+
+	// VMID the instance would be assigned
+	VMID int `json:"vmid,omitempty"`
+
+	// Storage of the disks of the instance
+	Storage string `json:"storage,omitempty"`
+
+	// Source the instance would be created from. e.g. image, backup archive or container template
+	Source string `json:"source,omitempty"`
+
+	// Disks the instance would be created with. e.g. scsi0=local-lvm:0,import-from=...
+	Disks []string `json:"disks,omitempty"`
+
+	// Error preventing the action. e.g. no node fits the instance
+	Error string `json:"error,omitempty"`
+}
+
+// NetworkInterface is a network interface of the instance
+type NetworkInterface struct {
+	// name of the interface in the guest. e.g. "eth0"
+	Name string `json:"name"`
+
+	// mac address of the interface
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// Primary is true for the interface of net0, which is connected to the node network
+	Primary bool `json:"primary,omitempty"`
+
+	// ip addresses of the interface without prefix length
+	IPAddresses []string `json:"ipAddresses,omitempty"`
+}
+
+// Console of the instance. No ticket is included since the status is readable by anyone
+// having access to the ProxmoxMachine. Log in to the Proxmox web UI to open the url.
+type Console struct {
+	// URL of the console in the Proxmox web UI
+	URL string `json:"url,omitempty"`
+
+	// Node hosting the instance
+	Node string `json:"node"`
+
+	// VMID of the instance. e.g. qm terminal <vmid> or pct enter <vmid> on the node
+	VMID int `json:"vmid"`
+
+	// Viewer of the console. xtermjs for serial consoles and containers, novnc for graphical displays
+	// +kubebuilder:validation:Enum:=xtermjs;novnc
+	Viewer string `json:"viewer"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".metadata.labels.cluster\\.x-k8s\\.io/cluster-name",description="Cluster to which this VSphereMachine belongs"
 // +kubebuilder:printcolumn:name="Machine",type="string",JSONPath=".metadata.ownerReferences[?(@.kind==\"Machine\")].name",description="Machine object which owns with this ProxmoxMachine",priority=1
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`,priority=1
 // +kubebuilder:printcolumn:name="VMID",type=string,JSONPath=`.spec.vmID`,priority=1
 // +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.node`,priority=1
 // +kubebuilder:printcolumn:name="Storage",type=string,JSONPath=`.spec.storage`,priority=1
 // +kubebuilder:printcolumn:name="ProviderID",type=string,JSONPath=`.spec.providerID`
 // +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.instanceStatus`
+// +kubebuilder:printcolumn:name="Console",type=string,JSONPath=`.status.console.url`,priority=1
+// +kubebuilder:printcolumn:name="Plan",type=string,JSONPath=`.status.plan.action`,priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of Machine"
 
 // ProxmoxMachine is the Schema for the proxmoxmachines API
@@ -112,6 +431,30 @@ type ProxmoxMachine struct {
 	Status ProxmoxMachineStatus `json:"status,omitempty"`
 }
 
+func (m *ProxmoxMachine) GetConditions() clusterv1.Conditions {
+	return m.Status.Conditions
+}
+
+func (m *ProxmoxMachine) SetConditions(conditions clusterv1.Conditions) {
+	m.Status.Conditions = conditions
+}
+
+// GetV1Beta2Conditions returns the conditions of the v1beta2 contract
+func (m *ProxmoxMachine) GetV1Beta2Conditions() []metav1.Condition {
+	if m.Status.V1Beta2 == nil {
+		return nil
+	}
+	return m.Status.V1Beta2.Conditions
+}
+
+// SetV1Beta2Conditions sets the conditions of the v1beta2 contract
+func (m *ProxmoxMachine) SetV1Beta2Conditions(conditions []metav1.Condition) {
+	if m.Status.V1Beta2 == nil {
+		m.Status.V1Beta2 = &ProxmoxMachineV1Beta2Status{}
+	}
+	m.Status.V1Beta2.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // ProxmoxMachineList contains a list of ProxmoxMachine
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -37,8 +38,11 @@ type ProxmoxMachineTemplateResource struct {
 
 // ProxmoxMachineTemplateStatus defines the observed state of ProxmoxMachineTemplate
 type ProxmoxMachineTemplateStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Capacity is the resources of the machines of the template: cpu, memory, ephemeral-storage
+	// of the root disk and the resources of the pci devices. It is used by the cluster autoscaler
+	// to scale node groups from zero.
+	// +optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum:=migrate;recreate
+type MaintenanceStrategy string
+
+const (
+	// MaintenanceStrategyMigrate live-migrates the VMs to other nodes
+	MaintenanceStrategyMigrate = MaintenanceStrategy("migrate")
+	// MaintenanceStrategyRecreate deletes the Machines one by one so that
+	// their replacements are created on other nodes
+	MaintenanceStrategyRecreate = MaintenanceStrategy("recreate")
+)
+
+// ProxmoxNodeMaintenanceSpec defines the desired state of ProxmoxNodeMaintenance
+type ProxmoxNodeMaintenanceSpec struct {
+	// ClusterName is the name of the Cluster in the same namespace whose machines are moved off the node
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterName is immutable"
+	ClusterName string `json:"clusterName"`
+
+	// NodeName is the name of the Proxmox node under maintenance.
+	// No new VM of the cluster is scheduled to the node while this resource exists.
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="nodeName is immutable"
+	NodeName string `json:"nodeName"`
+
+	// Strategy to move the machines off the node. Defaults to migrate.
+	// +kubebuilder:default:=migrate
+	Strategy MaintenanceStrategy `json:"strategy,omitempty"`
+}
+
+// ProxmoxNodeMaintenanceStatus defines the observed state of ProxmoxNodeMaintenance
+type ProxmoxNodeMaintenanceStatus struct {
+	// Ready is true when no machine of the cluster is left on the node
+	// +optional
+	Ready bool `json:"ready"`
+
+	// Machines is the names of the ProxmoxMachines left on the node
+	Machines []string `json:"machines,omitempty"`
+
+	// FailureMessage
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.nodeName`
+// +kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.spec.strategy`
+// +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of ProxmoxNodeMaintenance"
+
+// ProxmoxNodeMaintenance is the Schema for the proxmoxnodemaintenances API
+type ProxmoxNodeMaintenance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProxmoxNodeMaintenanceSpec   `json:"spec,omitempty"`
+	Status ProxmoxNodeMaintenanceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProxmoxNodeMaintenanceList contains a list of ProxmoxNodeMaintenance
+type ProxmoxNodeMaintenanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProxmoxNodeMaintenance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProxmoxNodeMaintenance{}, &ProxmoxNodeMaintenanceList{})
+}
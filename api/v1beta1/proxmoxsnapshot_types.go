@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SnapshotFinalizer
+	SnapshotFinalizer = "proxmoxsnapshot.infrastructure.cluster.x-k8s.io"
+)
+
+// ProxmoxSnapshotSpec defines the desired state of ProxmoxSnapshot
+type ProxmoxSnapshotSpec struct {
+	// MachineRef is the ProxmoxMachine in the same namespace to take a snapshot of
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="machineRef is immutable"
+	MachineRef corev1.LocalObjectReference `json:"machineRef"`
+
+	// Description of the snapshot shown in Proxmox
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="description is immutable"
+	Description string `json:"description,omitempty"`
+
+	// Rollback the VM to this snapshot. The rollback is done once each time this is set to true.
+	// Set this back to false and true again to roll back again.
+	Rollback bool `json:"rollback,omitempty"`
+
+	// Retain is the number of ProxmoxSnapshots of the same machine to keep.
+	// When this snapshot is taken, the oldest ones exceeding this count are deleted.
+	// +kubebuilder:validation:Minimum:=1
+	Retain *int `json:"retain,omitempty"`
+}
+
+// ProxmoxSnapshotStatus defines the observed state of ProxmoxSnapshot
+type ProxmoxSnapshotStatus struct {
+	// Ready is true when the snapshot is taken
+	// +optional
+	Ready bool `json:"ready"`
+
+	// SnapshotName is the name of the snapshot in Proxmox
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// VMID of the VM the snapshot belongs to
+	VMID *int `json:"vmID,omitempty"`
+
+	// CreationTime is the time the snapshot is taken
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// RolledBack is true when the VM is rolled back for the current spec.rollback
+	RolledBack bool `json:"rolledBack,omitempty"`
+
+	// FailureMessage
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Machine",type=string,JSONPath=`.spec.machineRef.name`
+// +kubebuilder:printcolumn:name="Snapshot",type=string,JSONPath=`.status.snapshotName`,priority=1
+// +kubebuilder:printcolumn:name="VMID",type=string,JSONPath=`.status.vmID`,priority=1
+// +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of ProxmoxSnapshot"
+
+// ProxmoxSnapshot is the Schema for the proxmoxsnapshots API
+type ProxmoxSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProxmoxSnapshotSpec   `json:"spec,omitempty"`
+	Status ProxmoxSnapshotStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProxmoxSnapshotList contains a list of ProxmoxSnapshot
+type ProxmoxSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProxmoxSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProxmoxSnapshot{}, &ProxmoxSnapshotList{})
+}
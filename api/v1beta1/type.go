@@ -2,9 +2,12 @@ package v1beta1
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/k8s-proxmox/proxmox-go/api"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type InstanceStatus string
@@ -15,6 +18,25 @@ var (
 	InstanceStatusStopped = InstanceStatus(api.ProcessStatusStopped)
 )
 
+// Conditions of the v1beta2 contract of Cluster API, set on ProxmoxClusters and ProxmoxMachines
+// besides the conditions of the v1beta1 contract
+const (
+	// ReadyV1Beta2Condition is true when the object is ready. Its message tells what it waits for otherwise.
+	ReadyV1Beta2Condition = "Ready"
+
+	// ReadyV1Beta2Reason, NotReadyV1Beta2Reason and DeletingV1Beta2Reason are reasons of the Ready condition.
+	ReadyV1Beta2Reason    = "Ready"
+	NotReadyV1Beta2Reason = "NotReady"
+	DeletingV1Beta2Reason = "Deleting"
+
+	// PausedV1Beta2Condition is true while the object or its Cluster is paused and it is not reconciled.
+	PausedV1Beta2Condition = "Paused"
+
+	// PausedV1Beta2Reason and NotPausedV1Beta2Reason are reasons of the Paused condition.
+	PausedV1Beta2Reason    = "Paused"
+	NotPausedV1Beta2Reason = "NotPaused"
+)
+
 // ServerRef is used for configuring Proxmox client
 type ServerRef struct {
 	// endpoint is the address of the Proxmox-VE REST API endpoint.
@@ -39,16 +61,41 @@ type ObjectReference struct {
 }
 
 // Image is the image to be provisioned
+// +kubebuilder:validation:XValidation:rule="!has(self.sourceType) || self.sourceType == 'URL' ? has(self.url) && !has(self.path) && !has(self.volume) : true",message="url is required for sourceType URL"
+// +kubebuilder:validation:XValidation:rule="has(self.sourceType) && self.sourceType == 'NodeLocalPath' ? has(self.path) && !has(self.url) && !has(self.volume) : true",message="path is required for sourceType NodeLocalPath"
+// +kubebuilder:validation:XValidation:rule="has(self.sourceType) && self.sourceType == 'Storage' ? has(self.volume) && !has(self.url) && !has(self.path) && !has(self.checksum) : true",message="volume is required and checksum is not supported for sourceType Storage"
 type Image struct {
+	// SourceType is where the image is taken from. Defaults to URL.
+	// URL downloads the image to the node, NodeLocalPath imports a file already present on the node,
+	// e.g. on an NFS export mounted on every node, and Storage imports a volume of a Proxmox storage.
+	// +optional
+	SourceType ImageSourceType `json:"sourceType,omitempty"`
+
 	// +kubebuilder:validation:Pattern:=.*\.(iso|img|qcow2|qed|raw|vdi|vpc|vmdk)$
 	// URL is a location of an image to deploy.
 	// supported formats are iso/qcow2/qed/raw/vdi/vpc/vmdk.
-	URL string `json:"url"`
+	URL string `json:"url,omitempty"`
+
+	// Path is the absolute path of the image on the node for sourceType NodeLocalPath.
+	// e.g. "/var/lib/vz/import/ubuntu-24.04.qcow2". The file must exist on every node machines are scheduled to
+	// and lie under one of the directories the controller allows by --image-import-dirs.
+	// Importing from paths requires the Proxmox user to be root@pam.
+	// +kubebuilder:validation:Pattern:=`^/[A-Za-z0-9._/-]+\.(img|qcow2|qed|raw|vdi|vpc|vmdk)$`
+	// +kubebuilder:validation:MaxLength:=255
+	// +kubebuilder:validation:XValidation:rule="!self.contains('..')",message="path must not contain '..'"
+	Path string `json:"path,omitempty"`
+
+	// Volume is the volume id of the image for sourceType Storage.
+	// e.g. "nfs:import/ubuntu-24.04.qcow2" or "nfs:iso/ubuntu-24.04.img".
+	// The storage must be available on every node machines are scheduled to.
+	// +kubebuilder:validation:Pattern:=`^[^:]+:.+$`
+	Volume string `json:"volume,omitempty"`
 
 	// Checksum
 	// Always better to specify checksum otherwise cappx will download
 	// same image for every time. If checksum is specified, cappx will try
 	// to avoid downloading existing image.
+	// Files of sourceType NodeLocalPath are verified against it.
 	Checksum string `json:"checksum,omitempty"`
 
 	// +kubebuilder:validation:Enum:=sha256;sha256sum;md5;md5sum
@@ -56,21 +103,372 @@ type Image struct {
 	ChecksumType *string `json:"checksumType,omitempty"`
 }
 
+// ImageSourceType is where an image is taken from
+// +kubebuilder:validation:Enum:=URL;NodeLocalPath;Storage
+type ImageSourceType string
+
+const (
+	// ImageSourceURL downloads the image from its url to the node
+	ImageSourceURL = ImageSourceType("URL")
+	// ImageSourceNodeLocalPath imports the image from a path on the node
+	ImageSourceNodeLocalPath = ImageSourceType("NodeLocalPath")
+	// ImageSourceStorage imports the image from a volume of a proxmox storage
+	ImageSourceStorage = ImageSourceType("Storage")
+)
+
+// same as the validation of Image.Path
+var imagePathRegex = regexp.MustCompile(`^/[A-Za-z0-9._/-]+\.(img|qcow2|qed|raw|vdi|vpc|vmdk)$`)
+
+// ValidImagePath returns whether path is an absolute image path
+// without parent references or characters special to the shell
+func ValidImagePath(path string) bool {
+	return len(path) <= 255 && imagePathRegex.MatchString(path) && !strings.Contains(path, "..")
+}
+
+// Source returns where the image is taken from: its url, path or volume
+func (i Image) Source() string {
+	switch i.SourceType {
+	case ImageSourceNodeLocalPath:
+		return i.Path
+	case ImageSourceStorage:
+		return i.Volume
+	}
+	return i.URL
+}
+
+// Restore is the backup to restore a machine from
+type Restore struct {
+	// Archive is the volume id of a vzdump or proxmox backup server backup.
+	// e.g. "local:backup/vzdump-qemu-100-2024_01_01-00_00_00.vma.zst" or
+	// "pbs:backup/vm/100/2024-01-01T00:00:00Z".
+	// The backup storage must be available on the node the machine is scheduled to.
+	// +kubebuilder:validation:Pattern:=`^[^:]+:.+$`
+	// +kubebuilder:validation:MaxLength:=512
+	Archive string `json:"archive"`
+}
+
+// +kubebuilder:validation:Enum:=qemu;lxc
+type InstanceType string
+
+const (
+	// InstanceTypeQEMU provisions a qemu virtual machine
+	InstanceTypeQEMU = InstanceType("qemu")
+	// InstanceTypeLXC provisions an lxc container
+	InstanceTypeLXC = InstanceType("lxc")
+)
+
+// SCSIController is the model of the scsi controller the disks are attached to
+// +kubebuilder:validation:Enum:=lsi;lsi53c810;virtio-scsi-pci;virtio-scsi-single;megasas;pvscsi
+type SCSIController string
+
+const (
+	SCSIControllerVirtio       = SCSIController("virtio-scsi-pci")
+	SCSIControllerVirtioSingle = SCSIController("virtio-scsi-single")
+)
+
+// Readiness checks that the guest accepts tcp connections.
+// The address is the static ip of the machine, or the one reported by the qemu guest agent.
+type Readiness struct {
+	// TCP port of the guest. e.g. 5985 for WinRM, 3389 for RDP or 22 for SSH.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	Port int `json:"port"`
+}
+
+// ProvisioningTimeouts limit how long each phase of provisioning a machine may take.
+// A machine whose phase has timed out is marked failed, so that a MachineHealthCheck or its owner replaces it.
+type ProvisioningTimeouts struct {
+	// ImageImport limits downloading the node image onto the Proxmox node.
+	// +optional
+	ImageImport *metav1.Duration `json:"imageImport,omitempty"`
+
+	// Create limits the task creating the instance, which imports the image into the storage.
+	// +optional
+	Create *metav1.Duration `json:"create,omitempty"`
+
+	// GuestReady limits how long the started instance may take to get ready.
+	// When set, qemu machines with the guest agent enabled also wait for the agent to answer.
+	// +optional
+	GuestReady *metav1.Duration `json:"guestReady,omitempty"`
+
+	// Bootstrap limits how long the ready instance may take until the node of the Machine has joined the cluster.
+	// +optional
+	Bootstrap *metav1.Duration `json:"bootstrap,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=ImageImport;Create;GuestReady;Bootstrap
+type ProvisioningPhaseName string
+
+const (
+	ProvisioningPhaseImageImport = ProvisioningPhaseName("ImageImport")
+	ProvisioningPhaseCreate      = ProvisioningPhaseName("Create")
+	ProvisioningPhaseGuestReady  = ProvisioningPhaseName("GuestReady")
+	ProvisioningPhaseBootstrap   = ProvisioningPhaseName("Bootstrap")
+)
+
+// ProvisioningPhase is the phase of provisioning the machine is in
+type ProvisioningPhase struct {
+	Name ProvisioningPhaseName `json:"name"`
+
+	// StartTime is when the machine has entered the phase
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// Firewall of the instance. The firewall of the instance is enabled once security groups are attached.
+// networkDevice.firewall must be enabled for the rules to apply.
+type Firewall struct {
+	// SecurityGroups are names of security groups defined by the Proxmox admin (Datacenter > Firewall > Security Group).
+	// They are attached in order on top of the other firewall rules of the instance.
+	// +kubebuilder:validation:items:Pattern:=`^[A-Za-z][A-Za-z0-9\-_]+$`
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+}
+
+// AddressFilter selects addresses of the guest by the names of their interfaces and by subnets.
+// Without include filters, all interfaces except the ones of container runtimes and CNIs are used.
+type AddressFilter struct {
+	// regular expressions of interface names to use. e.g. "^ens[0-9]+$"
+	// Interfaces of container runtimes and CNIs are used too if they match.
+	IncludeInterfaces []string `json:"includeInterfaces,omitempty"`
+
+	// regular expressions of interface names not to use, even the primary one. e.g. "^docker"
+	ExcludeInterfaces []string `json:"excludeInterfaces,omitempty"`
+
+	// CIDRs to use addresses from. e.g. "192.168.0.0/24"
+	// +kubebuilder:validation:items:Pattern:=`^[0-9a-fA-F.:]+/[0-9]+$`
+	IncludeSubnets []string `json:"includeSubnets,omitempty"`
+
+	// CIDRs not to use addresses from. e.g. "172.17.0.0/16"
+	// +kubebuilder:validation:items:Pattern:=`^[0-9a-fA-F.:]+/[0-9]+$`
+	ExcludeSubnets []string `json:"excludeSubnets,omitempty"`
+}
+
+// Container defines the lxc container of a machine of type lxc
+type Container struct {
+	// OSTemplate is the volume id of the container template.
+	// e.g. "local:vztmpl/ubuntu-22.04-standard_22.04-1_amd64.tar.zst".
+	// The template does not need cloud-init, since the bootstrap data is rendered as a shell script run with pct exec.
+	// Only bootcmd, write_files, ssh_authorized_keys, user/password, packages and runcmd are applied.
+	// +kubebuilder:validation:Pattern:=`^[^:]+:.+$`
+	OSTemplate string `json:"osTemplate"`
+
+	// Privileged runs the container as privileged container
+	Privileged bool `json:"privileged,omitempty"`
+
+	// Features of the container. Defaults to "nesting=1,keyctl=1" which kubelet and containerd require.
+	// +kubebuilder:default:="nesting=1,keyctl=1"
+	Features string `json:"features,omitempty"`
+}
+
+// MaxExtraDisks is the maximum number of extra disks.
+// scsi0 is reserved for the root disk so scsi1 ~ scsi30 are available.
+const MaxExtraDisks = 30
+
 // ExtraDisk represents an additional virtual disk
 type ExtraDisk struct {
-	Size    string `json:"size,omitempty"`    // e.g., "100Gi"
-	Storage string `json:"storage,omitempty"` // e.g., "local-lvm"
-	Type    string `json:"type,omitempty"`    // e.g., "scsi", "virtio"
-	Format  string `json:"format,omitempty"`  //
+	// Size of the disk (e.g., 100Gi, 50G). Like rootDisk, K, M, G and T are binary units,
+	// so 50G is 50GiB. Rounded up to GiB.
+	// +kubebuilder:validation:Pattern:=`^\+?(0*[1-9][0-9]*(\.[0-9]*)?|0*\.[0-9]*[1-9][0-9]*)([KMGT]i?|k)?$`
+	// +kubebuilder:validation:Minimum:=1
+	Size resource.Quantity `json:"size"`
+
+	// Storage backend to use (e.g., local-lvm, ceph, etc.)
+	// Defaults to the storage of the root disk.
+	Storage string `json:"storage,omitempty"`
+
+	// Disk bus type (e.g., scsi, virtio)
+	Type string `json:"type,omitempty"`
+
+	// Disk format (qcow2, raw, etc.)
+	// +kubebuilder:validation:Enum:=raw;qcow2
+	Format string `json:"format,omitempty"`
+}
+
+// PCIDevice is a host PCI device passed through to the VM (hostpciN).
+// raw device IDs can only be used by root@pam, use mapping otherwise.
+// +kubebuilder:validation:XValidation:rule="has(self.id) != has(self.mapping)",message="exactly one of id or mapping must be set"
+type PCIDevice struct {
+	// host PCI device ID. e.g. 0000:01:00.0 or 01:00 (all functions)
+	// +kubebuilder:validation:Pattern:=`^([a-fA-F0-9]{4}:)?[a-fA-F0-9]{2}:[a-fA-F0-9]{2}(\.[0-7])?$`
+	ID string `json:"id,omitempty"`
+
+	// name of the cluster-wide PCI resource mapping
+	Mapping string `json:"mapping,omitempty"`
+
+	// present the device as PCIe device. requires q35 machine type.
+	PCIe bool `json:"pcie,omitempty"`
+
+	// mark the device as the primary GPU of the VM (x-vga)
+	XVGA bool `json:"xVGA,omitempty"`
+
+	// mediated device type to create on the device. e.g. nvidia-63 for vGPU.
+	// only nodes having available instances of the type are scheduled.
+	MDev string `json:"mdev,omitempty"`
+
+	// extended resource the device provides on the node. e.g. nvidia.com/gpu.
+	// devices are counted in the capacity of ProxmoxMachineTemplates by their resource.
+	// +kubebuilder:validation:Pattern:=`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[a-zA-Z0-9]([-a-zA-Z0-9_.]*[a-zA-Z0-9])?$`
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+func (d *PCIDevice) String() string {
+	config := []string{}
+	if d.Mapping != "" {
+		config = append(config, fmt.Sprintf("mapping=%s", d.Mapping))
+	} else {
+		config = append(config, d.ID)
+	}
+	if d.PCIe {
+		config = append(config, fmt.Sprintf("pcie=%d", btoi(d.PCIe)))
+	}
+	if d.XVGA {
+		config = append(config, fmt.Sprintf("x-vga=%d", btoi(d.XVGA)))
+	}
+	if d.MDev != "" {
+		config = append(config, fmt.Sprintf("mdev=%s", d.MDev))
+	}
+	return strings.Join(config, ",")
+}
+
+// SRIOVNIC is an additional NIC of the VM backed by an SR-IOV virtual function (hostpciN).
+// Proxmox picks a free VF of the mapping on the node when the VM starts.
+type SRIOVNIC struct {
+	// name of the cluster-wide PCI resource mapping listing the VFs of each node.
+	// only nodes having a free VF of the mapping are scheduled.
+	// +kubebuilder:validation:MinLength:=1
+	Mapping string `json:"mapping"`
+
+	// present the VF as PCIe device. requires q35 machine type.
+	PCIe bool `json:"pcie,omitempty"`
+}
+
+func (n *SRIOVNIC) String() string {
+	config := []string{fmt.Sprintf("mapping=%s", n.Mapping)}
+	if n.PCIe {
+		config = append(config, fmt.Sprintf("pcie=%d", btoi(n.PCIe)))
+	}
+	return strings.Join(config, ",")
+}
+
+// RNGDevice is a virtio-rng device (rng0)
+type RNGDevice struct {
+	// entropy source on the host. Defaults to /dev/urandom.
+	// +kubebuilder:validation:Enum:=/dev/urandom;/dev/random;/dev/hwrng
+	// +kubebuilder:default:=/dev/urandom
+	Source string `json:"source,omitempty"`
+
+	// maximum bytes of entropy allowed to get injected into the guest every period.
+	// 0 means no limit.
+	// +kubebuilder:validation:Minimum:=0
+	MaxBytes int `json:"maxBytes,omitempty"`
+
+	// period in milliseconds for maxBytes. Defaults to 1000.
+	// +kubebuilder:validation:Minimum:=0
+	Period int `json:"period,omitempty"`
+}
+
+func (r *RNGDevice) String() string {
+	source := r.Source
+	if source == "" {
+		source = "/dev/urandom"
+	}
+	config := []string{fmt.Sprintf("source=%s", source)}
+	if r.MaxBytes != 0 {
+		config = append(config, fmt.Sprintf("max_bytes=%d", r.MaxBytes))
+	}
+	if r.Period != 0 {
+		config = append(config, fmt.Sprintf("period=%d", r.Period))
+	}
+	return strings.Join(config, ",")
+}
+
+// Replication configures a Proxmox storage replication job keeping a copy of the VM disks on another node.
+// All disks of the VM must be on ZFS storages available on both nodes.
+type Replication struct {
+	// Target is the node the disks are replicated to. The VM is never scheduled to this node.
+	// Proxmox can not move a replication job to another target, so the target is immutable.
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=128
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="replication target is immutable"
+	Target string `json:"target"`
+
+	// Schedule of the replication in systemd calendar event format. Defaults to every 15 minutes.
+	// +kubebuilder:default:="*/15"
+	Schedule string `json:"schedule,omitempty"`
+
+	// Rate limit of the replication in MB/s. Unlimited if empty.
+	// +kubebuilder:validation:Minimum:=1
+	Rate *int `json:"rate,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=started;stopped;disabled;ignored
+type HAState string
+
+// HighAvailability registers the VM as a resource of the Proxmox HA manager
+// so that the VM is restarted on another node when its node fails.
+type HighAvailability struct {
+	// Group is the HA group restricting the nodes the VM can be recovered on.
+	// Note that the HA manager migrates the VM if it is scheduled to a node out of the group.
+	// +kubebuilder:validation:MaxLength:=128
+	Group string `json:"group,omitempty"`
+
+	// State requested to the HA manager. Defaults to started.
+	// +kubebuilder:default:=started
+	State HAState `json:"state,omitempty"`
+
+	// MaxRestart is the number of restart tries on the same node before relocating. Proxmox defaults to 1.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=10
+	MaxRestart *int `json:"maxRestart,omitempty"`
+
+	// MaxRelocate is the number of relocation tries to another node. Proxmox defaults to 1.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=10
+	MaxRelocate *int `json:"maxRelocate,omitempty"`
+}
+
+// SnapshotPolicy defines when cappx takes snapshots of the VM
+type SnapshotPolicy struct {
+	// take a disk snapshot before applying in-place config changes (e.g. memory hotplug)
+	// so that the VM can be rolled back quickly. the storage must support snapshots.
+	// one snapshot is taken per change however often applying it is retried.
+	// note that snapshots are removed together with the VM, so machine deletion
+	// (e.g. replacement during rollout) is not covered.
+	BeforeUpdate bool `json:"beforeUpdate,omitempty"`
+
+	// number of snapshots taken by cappx to keep. older ones are deleted.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:default:=3
+	Retain int `json:"retain,omitempty"`
+
+	// snapshots taken by cappx older than this are deleted even within retain. e.g. 168h.
+	// the age is checked whenever the machine is reconciled.
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
 }
 
 // Hardware
+// +kubebuilder:validation:XValidation:rule="!has(self.maxMemory) || (has(self.memoryHotplug) && self.memoryHotplug)",message="maxMemory requires memoryHotplug"
+// +kubebuilder:validation:XValidation:rule="!has(self.maxMemory) || !has(self.memory) || self.memory <= self.maxMemory",message="memory must not exceed maxMemory"
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.maxMemory) || (has(self.maxMemory) && self.maxMemory == oldSelf.maxMemory)",message="maxMemory can not be changed once set"
+// +kubebuilder:validation:XValidation:rule="!has(self.nestedVirtualization) || !self.nestedVirtualization || !has(self.cpuType) || self.cpuType == 'host'",message="nestedVirtualization requires cpuType to be host"
+// +kubebuilder:validation:XValidation:rule="!has(self.pciDevices) || !self.pciDevices.exists(d, has(d.pcie) && d.pcie) || (has(self.machine) && self.machine.contains('q35'))",message="pcie passthrough requires q35 machine type"
+// +kubebuilder:validation:XValidation:rule="!has(self.sriovNICs) || !self.sriovNICs.exists(n, has(n.pcie) && n.pcie) || (has(self.machine) && self.machine.contains('q35'))",message="pcie passthrough requires q35 machine type"
+// +kubebuilder:validation:XValidation:rule="(has(self.pciDevices) ? size(self.pciDevices) : 0) + (has(self.sriovNICs) ? size(self.sriovNICs) : 0) <= 4",message="pciDevices and sriovNICs share hostpci0 ~ hostpci3"
 type Hardware struct {
 	// amount of RAM for the VM in MiB : 16 ~
 	// +kubebuilder:validation:Minimum:=16
 	// +kubebuilder:default:=4096
 	Memory int `json:"memory,omitempty"`
 
+	// enable memory hotplug so that memory can be increased
+	// without recreating the VM. requires options.numa.
+	MemoryHotplug bool `json:"memoryHotplug,omitempty"`
+
+	// upper limit in MiB which memory can be hot-plugged up to. requires memoryHotplug.
+	// memory can never exceed it, and it can not be changed once set.
+	// this is a limit of cappx, it is not passed to proxmox.
+	// +kubebuilder:validation:Minimum:=16
+	MaxMemory int `json:"maxMemory,omitempty"`
+
 	// number of CPU cores : 1 ~
 	// +kubebuilder:validation:Minimum:=1
 	// +kubebuilder:default:=2
@@ -79,6 +477,20 @@ type Hardware struct {
 	// Emulated CPU Type. Defaults to kvm64
 	CPUType string `json:"cpuType,omitempty"`
 
+	// CPU flags appended to cpuType. e.g. +aes, +pdpe1gb, -md-clear.
+	// the same flag can not be specified twice.
+	// +kubebuilder:validation:MaxItems:=12
+	// +kubebuilder:validation:XValidation:rule="self.all(f, self.filter(g, g.substring(1) == f.substring(1)).size() == 1)",message="each cpu flag can only be specified once"
+	CPUFlags []CPUFlag `json:"cpuFlags,omitempty"`
+
+	// hide KVM from the guest (hidden=1). needed by some guest drivers.
+	HideKVM bool `json:"hideKVM,omitempty"`
+
+	// expose hardware virtualization extensions (vmx/svm) to the guest so that
+	// it can run VMs itself. e.g. kubevirt or kind. this sets cpuType to host.
+	// the proxmox host must have nested virtualization enabled on the kvm module.
+	NestedVirtualization bool `json:"nestedVirtualization,omitempty"`
+
 	// +kubebuilder:validation:Minimum:=1
 	// The number of CPU sockets. Defaults to 1.
 	Sockets int `json:"sockets,omitempty"`
@@ -88,22 +500,45 @@ type Hardware struct {
 	// Value '0' indicates no CPU limit. Defaults to 0.
 	CPULimit int `json:"cpuLimit,omitempty"`
 
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=262144
+	// CPU weight of the VM. The larger the number is, the more CPU time this VM gets.
+	// Number is relative to weights of all other running VMs.
+	// Defaults to 100 on cgroup v2 hosts, 1024 on cgroup v1 hosts.
+	CPUUnits int `json:"cpuUnits,omitempty"`
+
 	// Select BIOS implementation. Defaults to seabios. seabios or ovmf.
 	// Defaults to seabios.
 	BIOS BIOS `json:"bios,omitempty"`
 
-	// Specifies the QEMU machine type.
-	// regex: (pc|pc(-i440fx)?-\d+(\.\d+)+(\+pve\d+)?(\.pxe)?|q35|pc-q35-\d+(\.\d+)+(\+pve\d+)?(\.pxe)?|virt(?:-\d+(\.\d+)+)?(\+pve\d+)?)
-	// Machine string `json:"machine,omitempty"`
+	// Specifies the QEMU machine type. q35 is required for PCIe passthrough.
+	// +kubebuilder:validation:Pattern:=`^(pc|pc(-i440fx)?-\d+(\.\d+)+(\+pve\d+)?(\.pxe)?|q35|pc-q35-\d+(\.\d+)+(\+pve\d+)?(\.pxe)?|virt(?:-\d+(\.\d+)+)?(\+pve\d+)?)$`
+	Machine string `json:"machine,omitempty"`
 
-	// SCSI controller model
-	// SCSIHardWare SCSIHardWare `json:"scsiHardWare,omitempty"`
+	// SCSI controller model. Defaults to virtio-scsi-single for windows osType and virtio-scsi-pci otherwise.
+	// Both are served by the vioscsi driver of virtio-win.
+	SCSIController SCSIController `json:"scsiController,omitempty"`
 
 	// hard disk size
-	// +kubebuilder:validation:Pattern:=\+?\d+(\.\d+)?[KMGT]?
+	// +kubebuilder:validation:Pattern:=`^\+?\d+(\.\d+)?[KMGT]?$`
 	// +kubebuilder:default:="50G"
-	RootDisk   string      `json:"rootDisk,omitempty"`
-	ExtraDisks []ExtraDisk `json:"extraDisks,omitempty"` // Add support for multiple disks
+	RootDisk string `json:"rootDisk,omitempty"`
+
+	// List of additional disks attached to the VM as scsi1 ~ scsi30
+	// +kubebuilder:validation:MaxItems:=30
+	ExtraDisks []ExtraDisk `json:"extraDisks,omitempty"`
+
+	// host PCI devices passed through to the VM as hostpci0 ~ hostpci3
+	// +kubebuilder:validation:MaxItems:=4
+	PCIDevices []PCIDevice `json:"pciDevices,omitempty"`
+
+	// SR-IOV virtual functions passed through to the VM as additional NICs.
+	// they take the hostpciN left by pciDevices.
+	// +kubebuilder:validation:MaxItems:=4
+	SRIOVNICs []SRIOVNIC `json:"sriovNICs,omitempty"`
+
+	// virtio-rng device feeding host entropy to the guest
+	RNG *RNGDevice `json:"rng,omitempty"`
 
 	// network devices
 	// to do: multiple devices
@@ -140,6 +575,33 @@ type NetworkDevice struct {
 	Trunks []int `json:"trunks,omitempty"`
 }
 
+// CPU flag supported by Proxmox for VMs, prefixed with '+' to enable or '-' to disable
+// +kubebuilder:validation:MaxLength:=12
+// +kubebuilder:validation:Pattern:=`^[+-](md-clear|pcid|spec-ctrl|ssbd|ibpb|virt-ssbd|amd-ssbd|amd-no-ssb|pdpe1gb|hv-tlbflush|hv-evmcs|aes)$`
+type CPUFlag string
+
+// CPUOption returns cpu option combining cpu type and its flags
+func (h *Hardware) CPUOption() string {
+	config := []string{}
+	if h.NestedVirtualization {
+		// only host cpu type passes vmx/svm through. proxmox does not allow them in flags
+		config = append(config, "host")
+	} else if h.CPUType != "" {
+		config = append(config, h.CPUType)
+	}
+	if len(h.CPUFlags) != 0 {
+		flags := []string{}
+		for _, f := range h.CPUFlags {
+			flags = append(flags, string(f))
+		}
+		config = append(config, fmt.Sprintf("flags=%s", strings.Join(flags, ";")))
+	}
+	if h.HideKVM {
+		config = append(config, fmt.Sprintf("hidden=%d", btoi(h.HideKVM)))
+	}
+	return strings.Join(config, ",")
+}
+
 type (
 	// +kubebuilder:validation:Enum:=e1000;virtio;rtl8139;vmxnet3
 	NetworkDeviceModel string
@@ -185,30 +647,64 @@ func (n *NetworkDevice) String() string {
 // cloud-init network configuration is configured through Proxmox API
 // it may be migrated to raw yaml way from Proxmox API way in the future
 type Network struct {
+	// Name of a network of the ProxmoxCluster to connect to.
+	// It overrides the bridge, vlan and mtu of hardware.networkDevice.
+	Name string `json:"name,omitempty"`
+
 	// to do : should accept multiple IPConfig
 	IPConfig IPConfig `json:"ipConfig,omitempty"`
 
-	// DNS server
+	// DNS servers separated by spaces.
+	// Defaults to the ones of the network or the ProxmoxCluster.
 	NameServer string `json:"nameServer,omitempty"`
 
-	// search domain
+	// search domains separated by spaces.
+	// Defaults to the ones of the network or the ProxmoxCluster.
 	SearchDomain string `json:"searchDomain,omitempty"`
 }
 
 // IPConfig defines IP addresses and gateways for corresponding interface.
 // it defaults to using dhcp on IPv4 if neither IP nor IP6 is specified.
+// +kubebuilder:validation:XValidation:rule="!has(self.gateway) || (has(self.ip) && self.ip != 'dhcp')",message="gateway requires a static ip"
+// +kubebuilder:validation:XValidation:rule="!has(self.gateway6) || (has(self.ip6) && self.ip6 != 'dhcp')",message="gateway6 requires a static ip6"
 type IPConfig struct {
-	// IPv4 with CIDR
+	// IPv4 with CIDR or "dhcp"
+	// +kubebuilder:validation:MaxLength:=18
+	// +kubebuilder:validation:XValidation:rule="self == 'dhcp' || self.matches('^([0-9]{1,3}[.]){3}[0-9]{1,3}/[0-9]{1,2}$')",message="ip must be 'dhcp' or an IPv4 address with CIDR"
 	IP string `json:"ip,omitempty"`
 
 	// gateway IPv4
 	Gateway string `json:"gateway,omitempty"`
 
-	// IPv6 with CIDR
+	// IPv6 with CIDR, "dhcp" or "auto". "auto" configures the address by SLAAC
+	// from router advertisements, which also provide the gateway.
+	// +kubebuilder:validation:MaxLength:=43
+	// +kubebuilder:validation:XValidation:rule="self == 'dhcp' || self == 'auto' || self.matches('^[0-9a-fA-F:]+/[0-9]{1,3}$')",message="ip6 must be 'dhcp', 'auto' or an IPv6 address with CIDR"
 	IP6 string `json:"ip6,omitempty"`
 
 	// gateway IPv6
 	Gateway6 string `json:"gateway6,omitempty"`
+
+	// static routes of the interface, e.g. to reach storage or registry networks via
+	// another gateway than the default one. only supported by qemus, whose network
+	// configuration is then rendered into a cloud-init network-config snippet.
+	// +kubebuilder:validation:MaxItems:=32
+	Routes []Route `json:"routes,omitempty"`
+}
+
+// Route is a static route
+type Route struct {
+	// destination network with CIDR. e.g. 10.1.0.0/16
+	// +kubebuilder:validation:Pattern:=`^([0-9]{1,3}[.]){3}[0-9]{1,3}/[0-9]{1,2}$|^[0-9a-fA-F:]+/[0-9]{1,3}$`
+	To string `json:"to"`
+
+	// gateway of the route
+	// +kubebuilder:validation:Pattern:=`^([0-9]{1,3}[.]){3}[0-9]{1,3}$|^[0-9a-fA-F:]+$`
+	Via string `json:"via"`
+
+	// metric of the route
+	// +kubebuilder:validation:Minimum:=0
+	Metric int `json:"metric,omitempty"`
 }
 
 func (c *IPConfig) String() string {
@@ -238,6 +734,8 @@ func (c *IPConfig) String() string {
 // Storage for image and snippets
 type Storage struct {
 	Name string `json:"name,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == '' || self.startsWith('/')",message="path must be absolute"
 	Path string `json:"path,omitempty"`
 }
 
@@ -0,0 +1,151 @@
+package v1beta1_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+var _ = Describe("PCIDevice", Label("unit", "api"), func() {
+	It("should render device id", func() {
+		device := infrav1.PCIDevice{ID: "0000:01:00", PCIe: true, XVGA: true}
+		Expect(device.String()).To(Equal("0000:01:00,pcie=1,x-vga=1"))
+	})
+
+	It("should render mapping", func() {
+		device := infrav1.PCIDevice{Mapping: "gpu"}
+		Expect(device.String()).To(Equal("mapping=gpu"))
+	})
+})
+
+var _ = Describe("SRIOVNIC", Label("unit", "api"), func() {
+	It("should render mapping", func() {
+		nic := infrav1.SRIOVNIC{Mapping: "vf-pool", PCIe: true}
+		Expect(nic.String()).To(Equal("mapping=vf-pool,pcie=1"))
+	})
+})
+
+var _ = Describe("ValidImagePath", Label("unit", "api"), func() {
+	It("should accept absolute image paths", func() {
+		Expect(infrav1.ValidImagePath("/var/lib/vz/import/ubuntu-24.04.qcow2")).To(BeTrue())
+		Expect(infrav1.ValidImagePath("/mnt/pve/nfs/import/k8s_v1.30.img")).To(BeTrue())
+	})
+
+	It("should reject relative paths, parent references and other formats", func() {
+		Expect(infrav1.ValidImagePath("var/lib/vz/import/ubuntu.qcow2")).To(BeFalse())
+		Expect(infrav1.ValidImagePath("/var/lib/vz/import/../images/100/vm-100-disk-0.qcow2")).To(BeFalse())
+		Expect(infrav1.ValidImagePath("/var/lib/vz/import/ubuntu.iso")).To(BeFalse())
+	})
+
+	It("should reject characters special to the shell", func() {
+		for _, path := range []string{
+			"/var/lib/vz/import/$(reboot).qcow2",
+			"/var/lib/vz/import/a;reboot;.qcow2",
+			"/var/lib/vz/import/a'b.qcow2",
+			"/var/lib/vz/import/a b.qcow2",
+			"/var/lib/vz/import/a\nb.qcow2",
+		} {
+			Expect(infrav1.ValidImagePath(path)).To(BeFalse(), path)
+		}
+	})
+})
+
+var _ = Describe("NodeGroup", Label("unit", "api"), func() {
+	It("should contain listed nodes and nodes matching the regex", func() {
+		g := infrav1.NodeGroup{Name: "infra", Nodes: []string{"pve1"}, NodeRegex: "^infra[0-9]+$"}
+		Expect(g.Contains("pve1")).To(BeTrue())
+		Expect(g.Contains("infra2")).To(BeTrue())
+		Expect(g.Contains("pve2")).To(BeFalse())
+	})
+
+	It("should contain no node by invalid regex", func() {
+		g := infrav1.NodeGroup{Name: "infra", NodeRegex: "("}
+		Expect(g.Contains("pve1")).To(BeFalse())
+	})
+})
+
+var _ = Describe("ProxmoxMachineSpec", Label("unit", "api"), func() {
+	It("should not move pinned or high priority machines", func() {
+		Expect((&infrav1.ProxmoxMachineSpec{}).Movable()).To(BeTrue())
+		Expect((&infrav1.ProxmoxMachineSpec{Priority: infrav1.MachinePriorityLow}).Movable()).To(BeTrue())
+		Expect((&infrav1.ProxmoxMachineSpec{Priority: infrav1.MachinePriorityHigh}).Movable()).To(BeFalse())
+		Expect((&infrav1.ProxmoxMachineSpec{NodeName: "pve1"}).Movable()).To(BeFalse())
+	})
+
+	It("should rank low priority first", func() {
+		Expect(infrav1.MachinePriorityLow.Rank()).To(BeNumerically("<", infrav1.MachinePriority("").Rank()))
+		Expect(infrav1.MachinePriorityNormal.Rank()).To(BeNumerically("<", infrav1.MachinePriorityHigh.Rank()))
+	})
+})
+
+var _ = Describe("ClusterNetwork", Label("unit", "api"), func() {
+	network := infrav1.ClusterNetwork{
+		Name: "prod", Bridge: "vmbr1", VLAN: 100, MTU: 9000,
+		IPAM: &infrav1.NetworkIPAM{Gateway: "10.0.0.1", Gateway6: "fd00::1", NameServer: "10.0.0.2", SearchDomain: "example.com"},
+	}
+
+	It("should override the bridge, vlan and mtu of the device", func() {
+		device := network.Device(infrav1.NetworkDevice{Model: "virtio", Bridge: "vmbr0", Firewall: true, Tag: 10})
+		Expect(device).To(Equal(infrav1.NetworkDevice{Model: "virtio", Bridge: "vmbr1", Firewall: true, Tag: 100, MTU: 9000}))
+	})
+
+	It("should fill the gateways of static ips", func() {
+		n := network.Network(infrav1.Network{IPConfig: infrav1.IPConfig{IP: "10.0.0.10/24", IP6: "dhcp"}, NameServer: "1.1.1.1"})
+		Expect(n).To(Equal(infrav1.Network{
+			IPConfig:     infrav1.IPConfig{IP: "10.0.0.10/24", Gateway: "10.0.0.1", IP6: "dhcp"},
+			NameServer:   "1.1.1.1",
+			SearchDomain: "example.com",
+		}))
+	})
+
+	It("should keep dhcp without gateways", func() {
+		n := network.Network(infrav1.Network{})
+		Expect(n.IPConfig).To(Equal(infrav1.IPConfig{}))
+		Expect(n.NameServer).To(Equal("10.0.0.2"))
+	})
+
+	It("should not fill the gateway of slaac", func() {
+		n := network.Network(infrav1.Network{IPConfig: infrav1.IPConfig{IP6: "auto"}})
+		Expect(n.IPConfig).To(Equal(infrav1.IPConfig{IP6: "auto"}))
+	})
+})
+
+var _ = Describe("DNS", Label("unit", "api"), func() {
+	It("should default the empty fields of the machine", func() {
+		dns := infrav1.DNS{NameServer: "10.0.0.2 10.0.0.3", SearchDomain: "example.com"}
+		Expect(dns.Network(infrav1.Network{SearchDomain: "dmz.example.com"})).To(Equal(infrav1.Network{
+			NameServer:   "10.0.0.2 10.0.0.3",
+			SearchDomain: "dmz.example.com",
+		}))
+	})
+})
+
+var _ = Describe("RNGDevice", Label("unit", "api"), func() {
+	It("should default source to /dev/urandom", func() {
+		rng := infrav1.RNGDevice{}
+		Expect(rng.String()).To(Equal("source=/dev/urandom"))
+	})
+
+	It("should render rate limit", func() {
+		rng := infrav1.RNGDevice{Source: "/dev/hwrng", MaxBytes: 1024, Period: 1000}
+		Expect(rng.String()).To(Equal("source=/dev/hwrng,max_bytes=1024,period=1000"))
+	})
+})
+
+var _ = Describe("Hardware.CPUOption", Label("unit", "api"), func() {
+	It("should render cpu type only", func() {
+		h := infrav1.Hardware{CPUType: "host"}
+		Expect(h.CPUOption()).To(Equal("host"))
+	})
+
+	It("should append flags and hidden", func() {
+		h := infrav1.Hardware{CPUType: "host", CPUFlags: []infrav1.CPUFlag{"+aes", "+pdpe1gb"}, HideKVM: true}
+		Expect(h.CPUOption()).To(Equal("host,flags=+aes;+pdpe1gb,hidden=1"))
+	})
+
+	It("should use host cpu type for nested virtualization", func() {
+		h := infrav1.Hardware{NestedVirtualization: true, CPUFlags: []infrav1.CPUFlag{"+pcid"}}
+		Expect(h.CPUOption()).To(Equal("host,flags=+pcid"))
+	})
+})
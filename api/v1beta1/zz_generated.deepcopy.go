@@ -21,11 +21,103 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	apiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/errors"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddressFilter) DeepCopyInto(out *AddressFilter) {
+	*out = *in
+	if in.IncludeInterfaces != nil {
+		in, out := &in.IncludeInterfaces, &out.IncludeInterfaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeInterfaces != nil {
+		in, out := &in.ExcludeInterfaces, &out.ExcludeInterfaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeSubnets != nil {
+		in, out := &in.IncludeSubnets, &out.IncludeSubnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeSubnets != nil {
+		in, out := &in.ExcludeSubnets, &out.ExcludeSubnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddressFilter.
+func (in *AddressFilter) DeepCopy() *AddressFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(AddressFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptedVM) DeepCopyInto(out *AdoptedVM) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptedVM.
+func (in *AdoptedVM) DeepCopy() *AdoptedVM {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptedVM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Agent) DeepCopyInto(out *Agent) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Agent.
+func (in *Agent) DeepCopy() *Agent {
+	if in == nil {
+		return nil
+	}
+	out := new(Agent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupRetention) DeepCopyInto(out *BackupRetention) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRetention.
+func (in *BackupRetention) DeepCopy() *BackupRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CACert) DeepCopyInto(out *CACert) {
 	*out = *in
@@ -69,6 +161,16 @@ func (in *CloudInit) DeepCopyInto(out *CloudInit) {
 		*out = new(UserData)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(SnippetEncryption)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudInit.
@@ -81,9 +183,162 @@ func (in *CloudInit) DeepCopy() *CloudInit {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterFirewall) DeepCopyInto(out *ClusterFirewall) {
+	*out = *in
+	if in.APIServerSources != nil {
+		in, out := &in.APIServerSources, &out.APIServerSources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterFirewall.
+func (in *ClusterFirewall) DeepCopy() *ClusterFirewall {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterFirewall)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterNetwork) DeepCopyInto(out *ClusterNetwork) {
+	*out = *in
+	if in.IPAM != nil {
+		in, out := &in.IPAM, &out.IPAM
+		*out = new(NetworkIPAM)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNetwork.
+func (in *ClusterNetwork) DeepCopy() *ClusterNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Console) DeepCopyInto(out *Console) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Console.
+func (in *Console) DeepCopy() *Console {
+	if in == nil {
+		return nil
+	}
+	out := new(Console)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Container) DeepCopyInto(out *Container) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Container.
+func (in *Container) DeepCopy() *Container {
+	if in == nil {
+		return nil
+	}
+	out := new(Container)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNS) DeepCopyInto(out *DNS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNS.
+func (in *DNS) DeepCopy() *DNS {
+	if in == nil {
+		return nil
+	}
+	out := new(DNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtraDisk) DeepCopyInto(out *ExtraDisk) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtraDisk.
+func (in *ExtraDisk) DeepCopy() *ExtraDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtraDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Firewall) DeepCopyInto(out *Firewall) {
+	*out = *in
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Firewall.
+func (in *Firewall) DeepCopy() *Firewall {
+	if in == nil {
+		return nil
+	}
+	out := new(Firewall)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Hardware) DeepCopyInto(out *Hardware) {
 	*out = *in
+	if in.CPUFlags != nil {
+		in, out := &in.CPUFlags, &out.CPUFlags
+		*out = make([]CPUFlag, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraDisks != nil {
+		in, out := &in.ExtraDisks, &out.ExtraDisks
+		*out = make([]ExtraDisk, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PCIDevices != nil {
+		in, out := &in.PCIDevices, &out.PCIDevices
+		*out = make([]PCIDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.SRIOVNICs != nil {
+		in, out := &in.SRIOVNICs, &out.SRIOVNICs
+		*out = make([]SRIOVNIC, len(*in))
+		copy(*out, *in)
+	}
+	if in.RNG != nil {
+		in, out := &in.RNG, &out.RNG
+		*out = new(RNGDevice)
+		**out = **in
+	}
 	in.NetworkDevice.DeepCopyInto(&out.NetworkDevice)
 }
 
@@ -97,9 +352,39 @@ func (in *Hardware) DeepCopy() *Hardware {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HighAvailability) DeepCopyInto(out *HighAvailability) {
+	*out = *in
+	if in.MaxRestart != nil {
+		in, out := &in.MaxRestart, &out.MaxRestart
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxRelocate != nil {
+		in, out := &in.MaxRelocate, &out.MaxRelocate
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HighAvailability.
+func (in *HighAvailability) DeepCopy() *HighAvailability {
+	if in == nil {
+		return nil
+	}
+	out := new(HighAvailability)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPConfig) DeepCopyInto(out *IPConfig) {
 	*out = *in
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]Route, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPConfig.
@@ -132,10 +417,50 @@ func (in *Image) DeepCopy() *Image {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineDefaults) DeepCopyInto(out *MachineDefaults) {
+	*out = *in
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(Image)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(Tags, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineDefaults.
+func (in *MachineDefaults) DeepCopy() *MachineDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NUMANode) DeepCopyInto(out *NUMANode) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NUMANode.
+func (in *NUMANode) DeepCopy() *NUMANode {
+	if in == nil {
+		return nil
+	}
+	out := new(NUMANode)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Network) DeepCopyInto(out *Network) {
 	*out = *in
-	out.IPConfig = in.IPConfig
+	in.IPConfig.DeepCopyInto(&out.IPConfig)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Network.
@@ -169,98 +494,1164 @@ func (in *NetworkDevice) DeepCopy() *NetworkDevice {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
+func (in *NetworkIPAM) DeepCopyInto(out *NetworkIPAM) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectReference.
-func (in *ObjectReference) DeepCopy() *ObjectReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkIPAM.
+func (in *NetworkIPAM) DeepCopy() *NetworkIPAM {
 	if in == nil {
 		return nil
 	}
-	out := new(ObjectReference)
+	out := new(NetworkIPAM)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Options) DeepCopyInto(out *Options) {
+func (in *NetworkInterface) DeepCopyInto(out *NetworkInterface) {
 	*out = *in
-	if in.HugePages != nil {
-		in, out := &in.HugePages, &out.HugePages
-		*out = new(HugePages)
-		**out = **in
-	}
-	if in.Tags != nil {
-		in, out := &in.Tags, &out.Tags
-		*out = make(Tags, len(*in))
+	if in.IPAddresses != nil {
+		in, out := &in.IPAddresses, &out.IPAddresses
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Options.
-func (in *Options) DeepCopy() *Options {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterface.
+func (in *NetworkInterface) DeepCopy() *NetworkInterface {
 	if in == nil {
 		return nil
 	}
-	out := new(Options)
+	out := new(NetworkInterface)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxCluster) DeepCopyInto(out *ProxmoxCluster) {
+func (in *NodeFailurePolicy) DeepCopyInto(out *NodeFailurePolicy) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	out.Timeout = in.Timeout
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxCluster.
-func (in *ProxmoxCluster) DeepCopy() *ProxmoxCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFailurePolicy.
+func (in *NodeFailurePolicy) DeepCopy() *NodeFailurePolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxCluster)
+	out := new(NodeFailurePolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProxmoxCluster) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroup) DeepCopyInto(out *NodeGroup) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroup.
+func (in *NodeGroup) DeepCopy() *NodeGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroup)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxClusterList) DeepCopyInto(out *ProxmoxClusterList) {
+func (in *NotificationHook) DeepCopyInto(out *NotificationHook) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]NotificationEvent, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationHook.
+func (in *NotificationHook) DeepCopy() *NotificationHook {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectReference.
+func (in *ObjectReference) DeepCopy() *ObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OfflineNode) DeepCopyInto(out *OfflineNode) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OfflineNode.
+func (in *OfflineNode) DeepCopy() *OfflineNode {
+	if in == nil {
+		return nil
+	}
+	out := new(OfflineNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Options) DeepCopyInto(out *Options) {
+	*out = *in
+	if in.Agent != nil {
+		in, out := &in.Agent, &out.Agent
+		*out = new(Agent)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BootOrder != nil {
+		in, out := &in.BootOrder, &out.BootOrder
+		*out = make([]BootDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.HotPlug != nil {
+		in, out := &in.HotPlug, &out.HotPlug
+		*out = make([]HotPlugDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.HugePages != nil {
+		in, out := &in.HugePages, &out.HugePages
+		*out = new(HugePages)
+		**out = **in
+	}
+	if in.NUMANodes != nil {
+		in, out := &in.NUMANodes, &out.NUMANodes
+		*out = make([]NUMANode, len(*in))
+		copy(*out, *in)
+	}
+	if in.SMBios != nil {
+		in, out := &in.SMBios, &out.SMBios
+		*out = new(SMBios)
+		**out = **in
+	}
+	if in.StartUp != nil {
+		in, out := &in.StartUp, &out.StartUp
+		*out = new(StartUp)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(Tags, len(*in))
+		copy(*out, *in)
+	}
+	if in.VGA != nil {
+		in, out := &in.VGA, &out.VGA
+		*out = new(VGA)
+		**out = **in
+	}
+	if in.WatchDog != nil {
+		in, out := &in.WatchDog, &out.WatchDog
+		*out = new(WatchDog)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Options.
+func (in *Options) DeepCopy() *Options {
+	if in == nil {
+		return nil
+	}
+	out := new(Options)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanPolicy) DeepCopyInto(out *OrphanPolicy) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanPolicy.
+func (in *OrphanPolicy) DeepCopy() *OrphanPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanedVM) DeepCopyInto(out *OrphanedVM) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanedVM.
+func (in *OrphanedVM) DeepCopy() *OrphanedVM {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanedVM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PCIDevice) DeepCopyInto(out *PCIDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PCIDevice.
+func (in *PCIDevice) DeepCopy() *PCIDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(PCIDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Plan) DeepCopyInto(out *Plan) {
+	*out = *in
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Plan.
+func (in *Plan) DeepCopy() *Plan {
+	if in == nil {
+		return nil
+	}
+	out := new(Plan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderIDPolicy) DeepCopyInto(out *ProviderIDPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderIDPolicy.
+func (in *ProviderIDPolicy) DeepCopy() *ProviderIDPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderIDPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningPhase) DeepCopyInto(out *ProvisioningPhase) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningPhase.
+func (in *ProvisioningPhase) DeepCopy() *ProvisioningPhase {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningPhase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningTimeouts) DeepCopyInto(out *ProvisioningTimeouts) {
+	*out = *in
+	if in.ImageImport != nil {
+		in, out := &in.ImageImport, &out.ImageImport
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.GuestReady != nil {
+		in, out := &in.GuestReady, &out.GuestReady
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningTimeouts.
+func (in *ProvisioningTimeouts) DeepCopy() *ProvisioningTimeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningTimeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxBackupPolicy) DeepCopyInto(out *ProxmoxBackupPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxBackupPolicy.
+func (in *ProxmoxBackupPolicy) DeepCopy() *ProxmoxBackupPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxBackupPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxBackupPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxBackupPolicyList) DeepCopyInto(out *ProxmoxBackupPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProxmoxBackupPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxBackupPolicyList.
+func (in *ProxmoxBackupPolicyList) DeepCopy() *ProxmoxBackupPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxBackupPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxBackupPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxBackupPolicySpec) DeepCopyInto(out *ProxmoxBackupPolicySpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(BackupRetention)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxBackupPolicySpec.
+func (in *ProxmoxBackupPolicySpec) DeepCopy() *ProxmoxBackupPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxBackupPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxBackupPolicyStatus) DeepCopyInto(out *ProxmoxBackupPolicyStatus) {
+	*out = *in
+	if in.VMIDs != nil {
+		in, out := &in.VMIDs, &out.VMIDs
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxBackupPolicyStatus.
+func (in *ProxmoxBackupPolicyStatus) DeepCopy() *ProxmoxBackupPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxBackupPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxCluster) DeepCopyInto(out *ProxmoxCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxCluster.
+func (in *ProxmoxCluster) DeepCopy() *ProxmoxCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxClusterInitializationStatus) DeepCopyInto(out *ProxmoxClusterInitializationStatus) {
+	*out = *in
+	if in.Provisioned != nil {
+		in, out := &in.Provisioned, &out.Provisioned
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterInitializationStatus.
+func (in *ProxmoxClusterInitializationStatus) DeepCopy() *ProxmoxClusterInitializationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxClusterInitializationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxClusterList) DeepCopyInto(out *ProxmoxClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProxmoxCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterList.
+func (in *ProxmoxClusterList) DeepCopy() *ProxmoxClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxClusterSpec) DeepCopyInto(out *ProxmoxClusterSpec) {
+	*out = *in
+	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	in.ServerRef.DeepCopyInto(&out.ServerRef)
+	out.Storage = in.Storage
+	if in.Rebalance != nil {
+		in, out := &in.Rebalance, &out.Rebalance
+		*out = new(RebalancePolicy)
+		**out = **in
+	}
+	if in.NodeFailure != nil {
+		in, out := &in.NodeFailure, &out.NodeFailure
+		*out = new(NodeFailurePolicy)
+		**out = **in
+	}
+	if in.Pool != nil {
+		in, out := &in.Pool, &out.Pool
+		*out = new(ResourcePool)
+		**out = **in
+	}
+	if in.TagMappings != nil {
+		in, out := &in.TagMappings, &out.TagMappings
+		*out = make([]TagMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.VMTags != nil {
+		in, out := &in.VMTags, &out.VMTags
+		*out = make(Tags, len(*in))
+		copy(*out, *in)
+	}
+	if in.Orphans != nil {
+		in, out := &in.Orphans, &out.Orphans
+		*out = new(OrphanPolicy)
+		**out = **in
+	}
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]ClusterNetwork, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeGroups != nil {
+		in, out := &in.NodeGroups, &out.NodeGroups
+		*out = make([]NodeGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DNS)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]Registry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SSHKeyPair != nil {
+		in, out := &in.SSHKeyPair, &out.SSHKeyPair
+		*out = new(SSHKeyPair)
+		**out = **in
+	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(ResourceQuota)
+		**out = **in
+	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = new(TaskPolicy)
+		**out = **in
+	}
+	if in.MachineTimeouts != nil {
+		in, out := &in.MachineTimeouts, &out.MachineTimeouts
+		*out = new(ProvisioningTimeouts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MachineDefaults != nil {
+		in, out := &in.MachineDefaults, &out.MachineDefaults
+		*out = new(MachineDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProviderID != nil {
+		in, out := &in.ProviderID, &out.ProviderID
+		*out = new(ProviderIDPolicy)
+		**out = **in
+	}
+	if in.Adoption != nil {
+		in, out := &in.Adoption, &out.Adoption
+		*out = make([]AdoptedVM, len(*in))
+		copy(*out, *in)
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]NotificationHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Firewall != nil {
+		in, out := &in.Firewall, &out.Firewall
+		*out = new(ClusterFirewall)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterSpec.
+func (in *ProxmoxClusterSpec) DeepCopy() *ProxmoxClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxClusterStatus) DeepCopyInto(out *ProxmoxClusterStatus) {
+	*out = *in
+	if in.FailureDomains != nil {
+		in, out := &in.FailureDomains, &out.FailureDomains
+		*out = make(apiv1beta1.FailureDomains, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastRebalanceTime != nil {
+		in, out := &in.LastRebalanceTime, &out.LastRebalanceTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OfflineNodes != nil {
+		in, out := &in.OfflineNodes, &out.OfflineNodes
+		*out = make([]OfflineNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DownNodes != nil {
+		in, out := &in.DownNodes, &out.DownNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastOrphanCheckTime != nil {
+		in, out := &in.LastOrphanCheckTime, &out.LastOrphanCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OrphanedVMs != nil {
+		in, out := &in.OrphanedVMs, &out.OrphanedVMs
+		*out = make([]OrphanedVM, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Initialization != nil {
+		in, out := &in.Initialization, &out.Initialization
+		*out = new(ProxmoxClusterInitializationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V1Beta2 != nil {
+		in, out := &in.V1Beta2, &out.V1Beta2
+		*out = new(ProxmoxClusterV1Beta2Status)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterStatus.
+func (in *ProxmoxClusterStatus) DeepCopy() *ProxmoxClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxClusterV1Beta2Status) DeepCopyInto(out *ProxmoxClusterV1Beta2Status) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterV1Beta2Status.
+func (in *ProxmoxClusterV1Beta2Status) DeepCopy() *ProxmoxClusterV1Beta2Status {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxClusterV1Beta2Status)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachine) DeepCopyInto(out *ProxmoxMachine) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachine.
+func (in *ProxmoxMachine) DeepCopy() *ProxmoxMachine {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxMachine) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineInitializationStatus) DeepCopyInto(out *ProxmoxMachineInitializationStatus) {
+	*out = *in
+	if in.Provisioned != nil {
+		in, out := &in.Provisioned, &out.Provisioned
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineInitializationStatus.
+func (in *ProxmoxMachineInitializationStatus) DeepCopy() *ProxmoxMachineInitializationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineInitializationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineList) DeepCopyInto(out *ProxmoxMachineList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProxmoxMachine, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineList.
+func (in *ProxmoxMachineList) DeepCopy() *ProxmoxMachineList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxMachineList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineSpec) DeepCopyInto(out *ProxmoxMachineSpec) {
+	*out = *in
+	if in.ProviderID != nil {
+		in, out := &in.ProviderID, &out.ProviderID
+		*out = new(string)
+		**out = **in
+	}
+	if in.SnippetStorage != nil {
+		in, out := &in.SnippetStorage, &out.SnippetStorage
+		*out = new(Storage)
+		**out = **in
+	}
+	if in.VMID != nil {
+		in, out := &in.VMID, &out.VMID
+		*out = new(int)
+		**out = **in
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(Image)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Restore != nil {
+		in, out := &in.Restore, &out.Restore
+		*out = new(Restore)
+		**out = **in
+	}
+	if in.Container != nil {
+		in, out := &in.Container, &out.Container
+		*out = new(Container)
+		**out = **in
+	}
+	in.CloudInit.DeepCopyInto(&out.CloudInit)
+	in.Hardware.DeepCopyInto(&out.Hardware)
+	in.Network.DeepCopyInto(&out.Network)
+	in.Options.DeepCopyInto(&out.Options)
+	if in.SnapshotPolicy != nil {
+		in, out := &in.SnapshotPolicy, &out.SnapshotPolicy
+		*out = new(SnapshotPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HA != nil {
+		in, out := &in.HA, &out.HA
+		*out = new(HighAvailability)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		*out = new(Replication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Readiness != nil {
+		in, out := &in.Readiness, &out.Readiness
+		*out = new(Readiness)
+		**out = **in
+	}
+	if in.Firewall != nil {
+		in, out := &in.Firewall, &out.Firewall
+		*out = new(Firewall)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AddressFilter != nil {
+		in, out := &in.AddressFilter, &out.AddressFilter
+		*out = new(AddressFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeouts != nil {
+		in, out := &in.Timeouts, &out.Timeouts
+		*out = new(ProvisioningTimeouts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FailureDomain != nil {
+		in, out := &in.FailureDomain, &out.FailureDomain
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineSpec.
+func (in *ProxmoxMachineSpec) DeepCopy() *ProxmoxMachineSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineStatus) DeepCopyInto(out *ProxmoxMachineStatus) {
+	*out = *in
+	if in.FailureReason != nil {
+		in, out := &in.FailureReason, &out.FailureReason
+		*out = new(errors.MachineStatusError)
+		**out = **in
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]apiv1beta1.MachineAddress, len(*in))
+		copy(*out, *in)
+	}
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]NetworkInterface, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.Config = in.Config
+	if in.InstanceStatus != nil {
+		in, out := &in.InstanceStatus, &out.InstanceStatus
+		*out = new(InstanceStatus)
+		**out = **in
+	}
+	if in.Console != nil {
+		in, out := &in.Console, &out.Console
+		*out = new(Console)
+		**out = **in
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(Plan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FailedNodes != nil {
+		in, out := &in.FailedNodes, &out.FailedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProvisioningPhase != nil {
+		in, out := &in.ProvisioningPhase, &out.ProvisioningPhase
+		*out = new(ProvisioningPhase)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Initialization != nil {
+		in, out := &in.Initialization, &out.Initialization
+		*out = new(ProxmoxMachineInitializationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.V1Beta2 != nil {
+		in, out := &in.V1Beta2, &out.V1Beta2
+		*out = new(ProxmoxMachineV1Beta2Status)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineStatus.
+func (in *ProxmoxMachineStatus) DeepCopy() *ProxmoxMachineStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineTemplate) DeepCopyInto(out *ProxmoxMachineTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplate.
+func (in *ProxmoxMachineTemplate) DeepCopy() *ProxmoxMachineTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxMachineTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineTemplateList) DeepCopyInto(out *ProxmoxMachineTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProxmoxMachineTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateList.
+func (in *ProxmoxMachineTemplateList) DeepCopy() *ProxmoxMachineTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxMachineTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineTemplateResource) DeepCopyInto(out *ProxmoxMachineTemplateResource) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateResource.
+func (in *ProxmoxMachineTemplateResource) DeepCopy() *ProxmoxMachineTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineTemplateSpec) DeepCopyInto(out *ProxmoxMachineTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateSpec.
+func (in *ProxmoxMachineTemplateSpec) DeepCopy() *ProxmoxMachineTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineTemplateStatus) DeepCopyInto(out *ProxmoxMachineTemplateStatus) {
+	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateStatus.
+func (in *ProxmoxMachineTemplateStatus) DeepCopy() *ProxmoxMachineTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxMachineV1Beta2Status) DeepCopyInto(out *ProxmoxMachineV1Beta2Status) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineV1Beta2Status.
+func (in *ProxmoxMachineV1Beta2Status) DeepCopy() *ProxmoxMachineV1Beta2Status {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxMachineV1Beta2Status)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxNodeMaintenance) DeepCopyInto(out *ProxmoxNodeMaintenance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxNodeMaintenance.
+func (in *ProxmoxNodeMaintenance) DeepCopy() *ProxmoxNodeMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxNodeMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxNodeMaintenance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxNodeMaintenanceList) DeepCopyInto(out *ProxmoxNodeMaintenanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ProxmoxCluster, len(*in))
+		*out = make([]ProxmoxNodeMaintenance, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterList.
-func (in *ProxmoxClusterList) DeepCopy() *ProxmoxClusterList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxNodeMaintenanceList.
+func (in *ProxmoxNodeMaintenanceList) DeepCopy() *ProxmoxNodeMaintenanceList {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxClusterList)
+	out := new(ProxmoxNodeMaintenanceList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProxmoxClusterList) DeepCopyObject() runtime.Object {
+func (in *ProxmoxNodeMaintenanceList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -268,292 +1659,346 @@ func (in *ProxmoxClusterList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxClusterSpec) DeepCopyInto(out *ProxmoxClusterSpec) {
+func (in *ProxmoxNodeMaintenanceSpec) DeepCopyInto(out *ProxmoxNodeMaintenanceSpec) {
 	*out = *in
-	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
-	in.ServerRef.DeepCopyInto(&out.ServerRef)
-	out.Storage = in.Storage
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterSpec.
-func (in *ProxmoxClusterSpec) DeepCopy() *ProxmoxClusterSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxNodeMaintenanceSpec.
+func (in *ProxmoxNodeMaintenanceSpec) DeepCopy() *ProxmoxNodeMaintenanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxNodeMaintenanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxNodeMaintenanceStatus) DeepCopyInto(out *ProxmoxNodeMaintenanceStatus) {
+	*out = *in
+	if in.Machines != nil {
+		in, out := &in.Machines, &out.Machines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxNodeMaintenanceStatus.
+func (in *ProxmoxNodeMaintenanceStatus) DeepCopy() *ProxmoxNodeMaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxNodeMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxSnapshot) DeepCopyInto(out *ProxmoxSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxSnapshot.
+func (in *ProxmoxSnapshot) DeepCopy() *ProxmoxSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxSnapshotList) DeepCopyInto(out *ProxmoxSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProxmoxSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxSnapshotList.
+func (in *ProxmoxSnapshotList) DeepCopy() *ProxmoxSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProxmoxSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxSnapshotSpec) DeepCopyInto(out *ProxmoxSnapshotSpec) {
+	*out = *in
+	out.MachineRef = in.MachineRef
+	if in.Retain != nil {
+		in, out := &in.Retain, &out.Retain
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxSnapshotSpec.
+func (in *ProxmoxSnapshotSpec) DeepCopy() *ProxmoxSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxmoxSnapshotStatus) DeepCopyInto(out *ProxmoxSnapshotStatus) {
+	*out = *in
+	if in.VMID != nil {
+		in, out := &in.VMID, &out.VMID
+		*out = new(int)
+		**out = **in
+	}
+	if in.CreationTime != nil {
+		in, out := &in.CreationTime, &out.CreationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxSnapshotStatus.
+func (in *ProxmoxSnapshotStatus) DeepCopy() *ProxmoxSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxmoxSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proxy) DeepCopyInto(out *Proxy) {
+	*out = *in
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
+	if in == nil {
+		return nil
+	}
+	out := new(Proxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RNGDevice) DeepCopyInto(out *RNGDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RNGDevice.
+func (in *RNGDevice) DeepCopy() *RNGDevice {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxClusterSpec)
+	out := new(RNGDevice)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxClusterStatus) DeepCopyInto(out *ProxmoxClusterStatus) {
+func (in *Readiness) DeepCopyInto(out *Readiness) {
 	*out = *in
-	if in.FailureDomains != nil {
-		in, out := &in.FailureDomains, &out.FailureDomains
-		*out = make(apiv1beta1.FailureDomains, len(*in))
-		for key, val := range *in {
-			(*out)[key] = *val.DeepCopy()
-		}
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make(apiv1beta1.Conditions, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxClusterStatus.
-func (in *ProxmoxClusterStatus) DeepCopy() *ProxmoxClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Readiness.
+func (in *Readiness) DeepCopy() *Readiness {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxClusterStatus)
+	out := new(Readiness)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachine) DeepCopyInto(out *ProxmoxMachine) {
+func (in *RebalancePolicy) DeepCopyInto(out *RebalancePolicy) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	out.Interval = in.Interval
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachine.
-func (in *ProxmoxMachine) DeepCopy() *ProxmoxMachine {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalancePolicy.
+func (in *RebalancePolicy) DeepCopy() *RebalancePolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachine)
+	out := new(RebalancePolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProxmoxMachine) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineList) DeepCopyInto(out *ProxmoxMachineList) {
+func (in *Registry) DeepCopyInto(out *Registry) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ProxmoxMachine, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineList.
-func (in *ProxmoxMachineList) DeepCopy() *ProxmoxMachineList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Registry.
+func (in *Registry) DeepCopy() *Registry {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineList)
+	out := new(Registry)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProxmoxMachineList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineSpec) DeepCopyInto(out *ProxmoxMachineSpec) {
+func (in *Replication) DeepCopyInto(out *Replication) {
 	*out = *in
-	if in.ProviderID != nil {
-		in, out := &in.ProviderID, &out.ProviderID
-		*out = new(string)
-		**out = **in
-	}
-	if in.VMID != nil {
-		in, out := &in.VMID, &out.VMID
+	if in.Rate != nil {
+		in, out := &in.Rate, &out.Rate
 		*out = new(int)
 		**out = **in
 	}
-	in.Image.DeepCopyInto(&out.Image)
-	in.CloudInit.DeepCopyInto(&out.CloudInit)
-	in.Hardware.DeepCopyInto(&out.Hardware)
-	out.Network = in.Network
-	in.Options.DeepCopyInto(&out.Options)
-	if in.FailureDomain != nil {
-		in, out := &in.FailureDomain, &out.FailureDomain
-		*out = new(string)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineSpec.
-func (in *ProxmoxMachineSpec) DeepCopy() *ProxmoxMachineSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Replication.
+func (in *Replication) DeepCopy() *Replication {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineSpec)
+	out := new(Replication)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineStatus) DeepCopyInto(out *ProxmoxMachineStatus) {
+func (in *ResourcePool) DeepCopyInto(out *ResourcePool) {
 	*out = *in
-	if in.FailureReason != nil {
-		in, out := &in.FailureReason, &out.FailureReason
-		*out = new(errors.MachineStatusError)
-		**out = **in
-	}
-	if in.FailureMessage != nil {
-		in, out := &in.FailureMessage, &out.FailureMessage
-		*out = new(string)
-		**out = **in
-	}
-	if in.Addresses != nil {
-		in, out := &in.Addresses, &out.Addresses
-		*out = make([]apiv1beta1.MachineAddress, len(*in))
-		copy(*out, *in)
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make(apiv1beta1.Conditions, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	out.Config = in.Config
-	if in.InstanceStatus != nil {
-		in, out := &in.InstanceStatus, &out.InstanceStatus
-		*out = new(InstanceStatus)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineStatus.
-func (in *ProxmoxMachineStatus) DeepCopy() *ProxmoxMachineStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcePool.
+func (in *ResourcePool) DeepCopy() *ResourcePool {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineStatus)
+	out := new(ResourcePool)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineTemplate) DeepCopyInto(out *ProxmoxMachineTemplate) {
+func (in *ResourceQuota) DeepCopyInto(out *ResourceQuota) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplate.
-func (in *ProxmoxMachineTemplate) DeepCopy() *ProxmoxMachineTemplate {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuota.
+func (in *ResourceQuota) DeepCopy() *ResourceQuota {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineTemplate)
+	out := new(ResourceQuota)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProxmoxMachineTemplate) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineTemplateList) DeepCopyInto(out *ProxmoxMachineTemplateList) {
+func (in *Restore) DeepCopyInto(out *Restore) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ProxmoxMachineTemplate, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateList.
-func (in *ProxmoxMachineTemplateList) DeepCopy() *ProxmoxMachineTemplateList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Restore.
+func (in *Restore) DeepCopy() *Restore {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineTemplateList)
+	out := new(Restore)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProxmoxMachineTemplateList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineTemplateResource) DeepCopyInto(out *ProxmoxMachineTemplateResource) {
+func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateResource.
-func (in *ProxmoxMachineTemplateResource) DeepCopy() *ProxmoxMachineTemplateResource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route.
+func (in *Route) DeepCopy() *Route {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineTemplateResource)
+	out := new(Route)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineTemplateSpec) DeepCopyInto(out *ProxmoxMachineTemplateSpec) {
+func (in *SMBios) DeepCopyInto(out *SMBios) {
 	*out = *in
-	in.Template.DeepCopyInto(&out.Template)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateSpec.
-func (in *ProxmoxMachineTemplateSpec) DeepCopy() *ProxmoxMachineTemplateSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SMBios.
+func (in *SMBios) DeepCopy() *SMBios {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineTemplateSpec)
+	out := new(SMBios)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxmoxMachineTemplateStatus) DeepCopyInto(out *ProxmoxMachineTemplateStatus) {
+func (in *SRIOVNIC) DeepCopyInto(out *SRIOVNIC) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxmoxMachineTemplateStatus.
-func (in *ProxmoxMachineTemplateStatus) DeepCopy() *ProxmoxMachineTemplateStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SRIOVNIC.
+func (in *SRIOVNIC) DeepCopy() *SRIOVNIC {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxmoxMachineTemplateStatus)
+	out := new(SRIOVNIC)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -573,6 +2018,21 @@ func (in *SSH) DeepCopy() *SSH {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHKeyPair) DeepCopyInto(out *SSHKeyPair) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHKeyPair.
+func (in *SSHKeyPair) DeepCopy() *SSHKeyPair {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHKeyPair)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSHKeys) DeepCopyInto(out *SSHKeys) {
 	*out = *in
@@ -608,6 +2068,66 @@ func (in *ServerRef) DeepCopy() *ServerRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotPolicy) DeepCopyInto(out *SnapshotPolicy) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotPolicy.
+func (in *SnapshotPolicy) DeepCopy() *SnapshotPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnippetEncryption) DeepCopyInto(out *SnippetEncryption) {
+	*out = *in
+	if in.Recipients != nil {
+		in, out := &in.Recipients, &out.Recipients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnippetEncryption.
+func (in *SnippetEncryption) DeepCopy() *SnippetEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(SnippetEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StartUp) DeepCopyInto(out *StartUp) {
+	*out = *in
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StartUp.
+func (in *StartUp) DeepCopy() *StartUp {
+	if in == nil {
+		return nil
+	}
+	out := new(StartUp)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Storage) DeepCopyInto(out *Storage) {
 	*out = *in
@@ -623,6 +2143,21 @@ func (in *Storage) DeepCopy() *Storage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TagMapping) DeepCopyInto(out *TagMapping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TagMapping.
+func (in *TagMapping) DeepCopy() *TagMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(TagMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in Tags) DeepCopyInto(out *Tags) {
 	{
@@ -642,6 +2177,22 @@ func (in Tags) DeepCopy() Tags {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskPolicy) DeepCopyInto(out *TaskPolicy) {
+	*out = *in
+	out.Timeout = in.Timeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskPolicy.
+func (in *TaskPolicy) DeepCopy() *TaskPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *User) DeepCopyInto(out *User) {
 	*out = *in
@@ -733,6 +2284,36 @@ func (in *UserData) DeepCopy() *UserData {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VGA) DeepCopyInto(out *VGA) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VGA.
+func (in *VGA) DeepCopy() *VGA {
+	if in == nil {
+		return nil
+	}
+	out := new(VGA)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatchDog) DeepCopyInto(out *WatchDog) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatchDog.
+func (in *WatchDog) DeepCopy() *WatchDog {
+	if in == nil {
+		return nil
+	}
+	out := new(WatchDog)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WriteFiles) DeepCopyInto(out *WriteFiles) {
 	*out = *in
@@ -0,0 +1,108 @@
+package adoption
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/providerid"
+)
+
+// suffix of the name of the bootstrap data secret of adopted machines
+const bootstrapSecretSuffix = "-adopted"
+
+// MachineName returns the name of the Machine and the ProxmoxMachine adopting the guest.
+// the name of the guest is used unless set, or the vmid if it is no valid name
+func MachineName(vm infrav1.AdoptedVM, g guest.Guest, clusterName string) string {
+	if vm.Name != "" {
+		return vm.Name
+	}
+	name := strings.ToLower(g.Name)
+	if len(validation.IsDNS1123Label(name)) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", clusterName, vm.VMID)
+}
+
+// Validate returns an error if the guest can not be adopted by the ProxmoxMachine of the name.
+// guests managed by another machine are never adopted
+func Validate(g guest.Guest, machines []infrav1.ProxmoxMachine, namespace, name string) error {
+	if g.Type != guest.TypeQEMU {
+		return fmt.Errorf("vm %d is a %s, only qemus can be adopted", g.VMID, g.Type)
+	}
+	if _, tagged := g.MachineNamespace(); tagged && !g.HasTag(guest.MachineTag(namespace, name)) {
+		return fmt.Errorf("vm %d is managed by another ProxmoxMachine", g.VMID)
+	}
+	for _, m := range machines {
+		if m.Spec.VMID == nil || *m.Spec.VMID != g.VMID || m.Name == name {
+			continue
+		}
+		return fmt.Errorf("vm %d is managed by ProxmoxMachine %s", g.VMID, m.Name)
+	}
+	return nil
+}
+
+// Objects returns the ProxmoxMachine, the Machine and the bootstrap data secret adopting the guest.
+// the provider id pins the smbios uuid of the qemu, so that another vm reusing the vmid is never taken for it.
+// the bootstrap data is empty since the node has already joined
+func Objects(cluster *clusterv1.Cluster, vm infrav1.AdoptedVM, g guest.Guest, name, uuid string) (*infrav1.ProxmoxMachine, *clusterv1.Machine, *corev1.Secret, error) {
+	id, err := providerid.Parse(providerid.Prefix + uuid)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	labels := map[string]string{clusterv1.ClusterNameLabel: cluster.Name}
+	if vm.Role == infrav1.AdoptionRoleControlPlane {
+		labels[clusterv1.MachineControlPlaneLabel] = ""
+	}
+	meta := func() metav1.ObjectMeta {
+		l := map[string]string{}
+		for k, v := range labels {
+			l[k] = v
+		}
+		return metav1.ObjectMeta{Name: name, Namespace: cluster.Namespace, Labels: l}
+	}
+
+	proxmoxMachine := &infrav1.ProxmoxMachine{ObjectMeta: meta()}
+	proxmoxMachine.Annotations = map[string]string{infrav1.AdoptedAnnotation: strconv.Itoa(g.VMID)}
+	proxmoxMachine.Spec = infrav1.ProxmoxMachineSpec{
+		ProviderID: ptr.To(id.String()),
+		Node:       g.Node,
+		VMID:       ptr.To(g.VMID),
+		Type:       infrav1.InstanceTypeQEMU,
+		Hardware: infrav1.Hardware{
+			CPU:    int(g.MaxCPU),
+			Memory: g.MaxMem / 1024 / 1024,
+		},
+	}
+
+	secret := &corev1.Secret{ObjectMeta: meta()}
+	secret.Name = name + bootstrapSecretSuffix
+	secret.Type = clusterv1.ClusterSecretType
+	secret.Data = map[string][]byte{"value": {}, "format": []byte(cloudinit.FormatCloudConfig)}
+
+	machine := &clusterv1.Machine{ObjectMeta: meta()}
+	machine.Spec = clusterv1.MachineSpec{
+		ClusterName: cluster.Name,
+		Bootstrap:   clusterv1.Bootstrap{DataSecretName: ptr.To(secret.Name)},
+		InfrastructureRef: corev1.ObjectReference{
+			APIVersion: infrav1.GroupVersion.String(),
+			Kind:       "ProxmoxMachine",
+			Namespace:  cluster.Namespace,
+			Name:       name,
+		},
+		ProviderID: proxmoxMachine.Spec.ProviderID,
+	}
+	if vm.Version != "" {
+		machine.Spec.Version = ptr.To(vm.Version)
+	}
+	return proxmoxMachine, machine, secret, nil
+}
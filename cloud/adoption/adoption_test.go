@@ -0,0 +1,109 @@
+package adoption_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/adoption"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+func TestAdoption(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Adoption Suite")
+}
+
+var _ = Describe("MachineName", Label("unit", "adoption"), func() {
+	It("should prefer the name of the adopted vm", func() {
+		Expect(adoption.MachineName(infrav1.AdoptedVM{VMID: 100, Name: "cp-0"}, guest.Guest{Name: "k8s-master"}, "prod")).To(Equal("cp-0"))
+	})
+
+	It("should default to the name of the guest", func() {
+		Expect(adoption.MachineName(infrav1.AdoptedVM{VMID: 100}, guest.Guest{Name: "K8s-Master"}, "prod")).To(Equal("k8s-master"))
+	})
+
+	It("should fall back to the vmid for invalid names", func() {
+		Expect(adoption.MachineName(infrav1.AdoptedVM{VMID: 100}, guest.Guest{Name: "k8s.master"}, "prod")).To(Equal("prod-100"))
+		Expect(adoption.MachineName(infrav1.AdoptedVM{VMID: 101}, guest.Guest{}, "prod")).To(Equal("prod-101"))
+	})
+})
+
+var _ = Describe("Validate", Label("unit", "adoption"), func() {
+	machine := func(name string, vmid int) infrav1.ProxmoxMachine {
+		m := infrav1.ProxmoxMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		m.Spec.VMID = ptr.To(vmid)
+		return m
+	}
+
+	It("should accept qemus managed by no machine", func() {
+		g := guest.Guest{Type: guest.TypeQEMU, VMID: 100, Tags: "kubernetes"}
+		Expect(adoption.Validate(g, []infrav1.ProxmoxMachine{machine("md-0", 101)}, "default", "cp-0")).To(Succeed())
+	})
+
+	It("should accept qemus already adopted by the machine", func() {
+		g := guest.Guest{Type: guest.TypeQEMU, VMID: 100, Tags: "cappx;" + guest.MachineTag("default", "cp-0")}
+		Expect(adoption.Validate(g, []infrav1.ProxmoxMachine{machine("cp-0", 100)}, "default", "cp-0")).To(Succeed())
+	})
+
+	It("should reject containers", func() {
+		g := guest.Guest{Type: guest.TypeLXC, VMID: 100}
+		Expect(adoption.Validate(g, nil, "default", "cp-0")).To(MatchError(ContainSubstring("only qemus can be adopted")))
+	})
+
+	It("should reject qemus of other machines", func() {
+		g := guest.Guest{Type: guest.TypeQEMU, VMID: 100, Tags: "cappx;" + guest.MachineTag("default", "md-0")}
+		Expect(adoption.Validate(g, nil, "default", "cp-0")).To(MatchError(ContainSubstring("managed by another ProxmoxMachine")))
+
+		g = guest.Guest{Type: guest.TypeQEMU, VMID: 100}
+		Expect(adoption.Validate(g, []infrav1.ProxmoxMachine{machine("md-0", 100)}, "default", "cp-0")).To(MatchError(ContainSubstring("managed by ProxmoxMachine md-0")))
+	})
+})
+
+var _ = Describe("Objects", Label("unit", "adoption"), func() {
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "prod", Namespace: "default"}}
+	g := guest.Guest{Type: guest.TypeQEMU, Node: "pve1", VMID: 100, Name: "k8s-master", MaxCPU: 4, MaxMem: 8 * 1024 * 1024 * 1024}
+
+	It("should generate a control plane machine adopting the qemu", func() {
+		vm := infrav1.AdoptedVM{VMID: 100, Role: infrav1.AdoptionRoleControlPlane, Version: "v1.30.4"}
+		proxmoxMachine, machine, secret, err := adoption.Objects(cluster, vm, g, "k8s-master", "0D9E6A86-2C1A-4B8E-9F57-6B1C0A3B2F11")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(proxmoxMachine.Labels).To(HaveKeyWithValue(clusterv1.MachineControlPlaneLabel, ""))
+		Expect(proxmoxMachine.Annotations).To(HaveKeyWithValue(infrav1.AdoptedAnnotation, "100"))
+		Expect(proxmoxMachine.Spec.ProviderID).To(Equal(ptr.To("proxmox://0d9e6a86-2c1a-4b8e-9f57-6b1c0a3b2f11")))
+		Expect(proxmoxMachine.Spec.Node).To(Equal("pve1"))
+		Expect(proxmoxMachine.Spec.VMID).To(Equal(ptr.To(100)))
+		Expect(proxmoxMachine.Spec.Hardware.CPU).To(Equal(4))
+		Expect(proxmoxMachine.Spec.Hardware.Memory).To(Equal(8192))
+
+		Expect(machine.Labels).To(HaveKeyWithValue(clusterv1.ClusterNameLabel, "prod"))
+		Expect(machine.Labels).To(HaveKey(clusterv1.MachineControlPlaneLabel))
+		Expect(machine.Spec.ClusterName).To(Equal("prod"))
+		Expect(machine.Spec.Version).To(Equal(ptr.To("v1.30.4")))
+		Expect(machine.Spec.InfrastructureRef.Kind).To(Equal("ProxmoxMachine"))
+		Expect(machine.Spec.InfrastructureRef.Name).To(Equal("k8s-master"))
+		Expect(machine.Spec.Bootstrap.DataSecretName).To(Equal(ptr.To(secret.Name)))
+
+		Expect(secret.Type).To(Equal(clusterv1.ClusterSecretType))
+		Expect(secret.Data).To(HaveKeyWithValue("value", BeEmpty()))
+	})
+
+	It("should not label workers with the control plane", func() {
+		proxmoxMachine, machine, _, err := adoption.Objects(cluster, infrav1.AdoptedVM{VMID: 100, Role: infrav1.AdoptionRoleWorker}, g, "k8s-worker", "0d9e6a86-2c1a-4b8e-9f57-6b1c0a3b2f11")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxmoxMachine.Labels).NotTo(HaveKey(clusterv1.MachineControlPlaneLabel))
+		Expect(machine.Labels).NotTo(HaveKey(clusterv1.MachineControlPlaneLabel))
+		Expect(machine.Spec.Version).To(BeNil())
+	})
+
+	It("should fail for invalid smbios uuids", func() {
+		_, _, _, err := adoption.Objects(cluster, infrav1.AdoptedVM{VMID: 100, Role: infrav1.AdoptionRoleWorker}, g, "k8s-worker", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,142 @@
+// Package audit records the calls cappx makes to change the state of Proxmox.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	loggerName = "proxmox-audit"
+	message    = "proxmox api call"
+
+	// operation of commands run on a node through its vnc shell
+	OperationExec = "EXEC"
+	// operation of files written on a node through its vnc shell
+	OperationWrite = "WRITE"
+)
+
+// values identifying the credentials of each proxmox client, keyed by its rest client
+// since proxmox.Service is passed around by value
+var identities sync.Map
+
+// Register sets the values identifying the client in its records, e.g. the ProxmoxCluster,
+// the endpoint and the user
+func Register(client *proxmox.Service, keysAndValues ...interface{}) {
+	identities.Store(client.RESTClient(), keysAndValues)
+}
+
+// Record writes a call changing the state of proxmox to the audit log at level 0. the logger of ctx
+// carries the object being reconciled and the reconcile id. request bodies are never recorded
+func Record(ctx context.Context, client *proxmox.Service, operation, target string, err error, keysAndValues ...interface{}) {
+	logger := log.FromContext(ctx).WithName(loggerName)
+	if client != nil {
+		if identity, ok := identities.Load(client.RESTClient()); ok {
+			logger = logger.WithValues(identity.([]interface{})...)
+		}
+	}
+	keysAndValues = append([]interface{}{"operation", operation, "target", target}, keysAndValues...)
+	if err != nil {
+		logger.Info(message, append(keysAndValues, "result", "failed", "error", err.Error())...)
+		return
+	}
+	logger.Info(message, append(keysAndValues, "result", "succeeded")...)
+}
+
+// Do runs the call and records it
+func Do(ctx context.Context, client *proxmox.Service, operation, target string, call func() error) error {
+	err := call()
+	Record(ctx, client, operation, target, err)
+	return err
+}
+
+// RESTClient is the rest client of a proxmox client recording its mutating calls,
+// including the ones failing in the transport
+type RESTClient struct {
+	client *proxmox.Service
+}
+
+// REST returns the recording rest client of the client
+func REST(client *proxmox.Service) *RESTClient {
+	return &RESTClient{client: client}
+}
+
+func (c *RESTClient) Post(ctx context.Context, path string, req, res interface{}) error {
+	return c.do(ctx, http.MethodPost, path, req, res)
+}
+
+func (c *RESTClient) Put(ctx context.Context, path string, req, res interface{}) error {
+	return c.do(ctx, http.MethodPut, path, req, res)
+}
+
+func (c *RESTClient) Delete(ctx context.Context, path string, req, res interface{}) error {
+	return c.do(ctx, http.MethodDelete, path, req, res)
+}
+
+func (c *RESTClient) CreateVirtualMachine(ctx context.Context, node string, vmid int, option api.VirtualMachineCreateOptions) (*string, error) {
+	upid, err := c.client.RESTClient().CreateVirtualMachine(ctx, node, vmid, option)
+	keysAndValues := []interface{}{}
+	if upid != nil {
+		keysAndValues = append(keysAndValues, "upid", *upid)
+	}
+	Record(ctx, c.client, http.MethodPost, fmt.Sprintf("/nodes/%s/qemu", node), err, keysAndValues...)
+	return upid, err
+}
+
+func (c *RESTClient) do(ctx context.Context, method, path string, req, res interface{}) error {
+	err := c.client.RESTClient().Do(ctx, method, path, req, res)
+	Record(ctx, c.client, method, path, err, upidOf(res)...)
+	return err
+}
+
+// returns the upid of the task started by the call, if any
+func upidOf(res interface{}) []interface{} {
+	if upid, ok := res.(*string); ok && upid != nil && strings.HasPrefix(*upid, "UPID:") {
+		return []interface{}{"upid", *upid}
+	}
+	return nil
+}
+
+// VNCClient is the vnc shell of a node recording the commands run and the files written through it.
+// neither the commands nor the content of the files are recorded since they may contain credentials
+type VNCClient struct {
+	*proxmox.VNCWebSocketClient
+	client *proxmox.Service
+	node   string
+}
+
+// NewVNCClient opens the vnc shell of the node
+func NewVNCClient(ctx context.Context, client *proxmox.Service, node string) (*VNCClient, error) {
+	vnc, err := client.NewNodeVNCWebSocketConnection(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	return &VNCClient{VNCWebSocketClient: vnc, client: client, node: node}, nil
+}
+
+func (c *VNCClient) Exec(ctx context.Context, cmd string) (string, int, error) {
+	out, code, err := c.VNCWebSocketClient.Exec(ctx, cmd)
+	result := err
+	if result == nil && code != 0 {
+		result = fmt.Errorf("exit code %d", code)
+	}
+	Record(ctx, c.client, OperationExec, c.target(), result, "exitCode", code)
+	return out, code, err
+}
+
+func (c *VNCClient) WriteFile(ctx context.Context, content, path string) error {
+	err := c.VNCWebSocketClient.WriteFile(ctx, content, path)
+	Record(ctx, c.client, OperationWrite, c.target()+":"+path, err)
+	return err
+}
+
+func (c *VNCClient) target() string {
+	return fmt.Sprintf("/nodes/%s/vncshell", c.node)
+}
@@ -0,0 +1,78 @@
+package audit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+func TestAudit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Audit Suite")
+}
+
+var _ = Describe("REST", Label("unit", "audit"), func() {
+	var (
+		server *httptest.Server
+		client *proxmox.Service
+		ctx    context.Context
+		lines  []string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api2/json/nodes/pve1/qemu/100/snapshot":
+				_, _ = w.Write([]byte(`{"data":"UPID:pve1:0001:snapshot"}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		DeferCleanup(server.Close)
+		params := proxmox.NewParams(server.URL+"/api2/json", proxmox.AuthConfig{TokenID: "cappx@pve!token", Secret: "secret"}, proxmox.ClientConfig{})
+		var err error
+		client, err = proxmox.NewService(params)
+		Expect(err).NotTo(HaveOccurred())
+		audit.Register(client, "proxmoxCluster", "default/cluster")
+
+		lines = nil
+		logger := funcr.New(func(prefix, args string) {
+			lines = append(lines, prefix+" "+args)
+		}, funcr.Options{})
+		ctx = logr.NewContext(context.Background(), logger.WithValues("ProxmoxMachine", "default/machine"))
+	})
+
+	It("should record the call with the reconciled object, the client and the upid", func() {
+		var upid string
+		Expect(audit.REST(client).Post(ctx, "/nodes/pve1/qemu/100/snapshot", map[string]string{"snapname": "secret"}, &upid)).To(Succeed())
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(HavePrefix("proxmox-audit "))
+		Expect(lines[0]).To(ContainSubstring(`"ProxmoxMachine"="default/machine"`))
+		Expect(lines[0]).To(ContainSubstring(`"proxmoxCluster"="default/cluster"`))
+		Expect(lines[0]).To(ContainSubstring(`"operation"="POST" "target"="/nodes/pve1/qemu/100/snapshot"`))
+		Expect(lines[0]).To(ContainSubstring(`"upid"="UPID:pve1:0001:snapshot" "result"="succeeded"`))
+		Expect(lines[0]).NotTo(ContainSubstring("snapname"))
+	})
+
+	It("should record failed calls", func() {
+		Expect(audit.REST(client).Delete(ctx, "/nodes/pve1/qemu/101", nil, nil)).NotTo(Succeed())
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"operation"="DELETE" "target"="/nodes/pve1/qemu/101" "result"="failed"`))
+	})
+
+	It("should record calls failing in the transport", func() {
+		server.Close()
+		Expect(audit.REST(client).Put(ctx, "/nodes/pve1/qemu/100/config", nil, nil)).NotTo(Succeed())
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"operation"="PUT" "target"="/nodes/pve1/qemu/100/config" "result"="failed"`))
+	})
+})
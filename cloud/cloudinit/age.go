@@ -0,0 +1,36 @@
+package cloudinit
+
+import (
+	"bytes"
+	"errors"
+
+	"filippo.io/age"
+)
+
+// EncryptAge encrypts content to X25519 recipients (age1...) in the binary format of age,
+// so that it can be decrypted with `age -d -i <identity>` in the guest
+func EncryptAge(content []byte, recipients []string) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no age recipients")
+	}
+	parsed := make([]age.Recipient, 0, len(recipients))
+	for _, recipient := range recipients {
+		r, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, parsed...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
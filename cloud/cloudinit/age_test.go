@@ -0,0 +1,62 @@
+package cloudinit_test
+
+import (
+	"bytes"
+	"io"
+
+	"filippo.io/age"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+)
+
+var _ = Describe("EncryptAge", Label("unit", "cloudinit"), func() {
+	recipient := "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+
+	It("should write a stanza per recipient", func() {
+		encrypted, err := cloudinit.EncryptAge([]byte("#!/bin/sh\necho secret\n"), []string{recipient, recipient})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encrypted).To(HavePrefix("age-encryption.org/v1\n-> X25519 "))
+		Expect(bytes.Count(encrypted, []byte("\n-> X25519 "))).To(Equal(2))
+		Expect(encrypted).To(ContainSubstring("\n--- "))
+		Expect(encrypted).NotTo(ContainSubstring("secret"))
+	})
+
+	It("should be decrypted by the identity of any recipient", func() {
+		// larger than a chunk of the payload
+		content := bytes.Repeat([]byte("#!/bin/sh\necho secret\n"), 5000)
+		a, err := age.GenerateX25519Identity()
+		Expect(err).NotTo(HaveOccurred())
+		b, err := age.GenerateX25519Identity()
+		Expect(err).NotTo(HaveOccurred())
+		encrypted, err := cloudinit.EncryptAge(content, []string{a.Recipient().String(), b.Recipient().String()})
+		Expect(err).NotTo(HaveOccurred())
+		for _, identity := range []age.Identity{a, b} {
+			r, err := age.Decrypt(bytes.NewReader(encrypted), identity)
+			Expect(err).NotTo(HaveOccurred())
+			decrypted, err := io.ReadAll(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decrypted).To(Equal(content))
+		}
+	})
+
+	It("should not encrypt the same way twice", func() {
+		a, err := cloudinit.EncryptAge([]byte("secret"), []string{recipient})
+		Expect(err).NotTo(HaveOccurred())
+		b, err := cloudinit.EncryptAge([]byte("secret"), []string{recipient})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).NotTo(Equal(b))
+	})
+
+	It("should reject missing and malformed recipients", func() {
+		_, err := cloudinit.EncryptAge([]byte("secret"), nil)
+		Expect(err).To(HaveOccurred())
+		_, err = cloudinit.EncryptAge([]byte("secret"), []string{"ssh-ed25519 AAAA"})
+		Expect(err).To(HaveOccurred())
+		// broken checksum
+		_, err = cloudinit.EncryptAge([]byte("secret"), []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8q"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,167 @@
+package cloudinit
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// Format is the format of bootstrap data
+type Format string
+
+const (
+	// cloud-config of kubeadm, k3s and rke2 bootstrap providers
+	FormatCloudConfig Format = "cloud-config"
+	// shell script run by cloud-init
+	FormatShellScript Format = "shell-script"
+	// ignition is consumed by ignition instead of cloud-init
+	FormatIgnition Format = "ignition"
+
+	cloudConfigHeader = "#cloud-config"
+	// k3s and rke2 bootstrap data refer to instance data like
+	// {{ ds.meta_data.local_hostname }}, which is rendered only with this header
+	jinjaHeader = "## template: jinja"
+
+	// shell script bootstrap data is written here and run by runcmd
+	BootstrapScriptPath = "/etc/cappx/bootstrap.sh"
+)
+
+// DetectFormat returns the format of bootstrap data
+func DetectFormat(content string) Format {
+	_, body := splitJinjaHeader(content)
+	switch {
+	case strings.HasPrefix(body, "#!"):
+		return FormatShellScript
+	case strings.HasPrefix(body, "{"):
+		return FormatIgnition
+	default:
+		return FormatCloudConfig
+	}
+}
+
+// ResolveFormat returns the format of bootstrap data, checking it against the format declared by the
+// "format" key of the bootstrap secret. bootstrap data of a declared cloud-config may be a shell script,
+// since cloud-init runs both. the content decides if nothing is declared
+func ResolveFormat(declared, content string) (Format, error) {
+	detected := DetectFormat(content)
+	switch Format(declared) {
+	case "":
+		return detected, nil
+	case FormatCloudConfig:
+		if detected == FormatIgnition {
+			return "", fmt.Errorf("bootstrap data declared as %s looks like %s", declared, detected)
+		}
+		return detected, nil
+	case FormatIgnition:
+		if detected != FormatIgnition {
+			return "", fmt.Errorf("bootstrap data declared as %s looks like %s", declared, detected)
+		}
+		return detected, nil
+	default:
+		return "", fmt.Errorf("unsupported bootstrap data format %q", declared)
+	}
+}
+
+// MergeBootstrapData merges user data into bootstrap data and returns cloud-config.
+// unlike ParseUserData, keys of the bootstrap data unknown to UserData are kept as they are.
+// scalars of the user data take precedence and lists of the bootstrap data are appended to the ones of user data
+func MergeBootstrapData(bootstrap string, userData infrav1.UserData) (string, error) {
+	jinja, body := splitJinjaHeader(bootstrap)
+	config := map[string]interface{}{}
+	switch DetectFormat(bootstrap) {
+	case FormatIgnition:
+		return "", errors.New("ignition bootstrap data is not supported")
+	case FormatShellScript:
+		config = shellScriptConfig(body)
+	default:
+		if err := yaml.Unmarshal([]byte(body), &config); err != nil {
+			return "", fmt.Errorf("failed to parse bootstrap data: %w", err)
+		}
+		if config == nil {
+			config = map[string]interface{}{}
+		}
+	}
+
+	b, err := yaml.Marshal(&userData)
+	if err != nil {
+		return "", err
+	}
+	user := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &user); err != nil {
+		return "", err
+	}
+	b, err = yaml.Marshal(mergeConfig(user, config))
+	if err != nil {
+		return "", err
+	}
+	header := cloudConfigHeader
+	if jinja {
+		header = jinjaHeader + "\n" + header
+	}
+	return fmt.Sprintf("%s\n%s", header, string(b)), nil
+}
+
+// StripJinjaHeader removes the jinja header. cloudbase-init does not render
+// templates and only recognizes cloud-config starting with its header
+func StripJinjaHeader(content string) string {
+	_, body := splitJinjaHeader(content)
+	return body
+}
+
+// returns whether the content has the jinja header and the content without it
+func splitJinjaHeader(content string) (bool, string) {
+	content = strings.TrimLeft(content, " \t\r\n")
+	if !strings.HasPrefix(content, jinjaHeader) {
+		return false, content
+	}
+	_, body, _ := strings.Cut(content, "\n")
+	return true, strings.TrimLeft(body, " \t\r\n")
+}
+
+func shellScriptConfig(script string) map[string]interface{} {
+	return map[string]interface{}{
+		"write_files": []interface{}{
+			map[string]interface{}{
+				"path":        BootstrapScriptPath,
+				"permissions": "0700",
+				"encoding":    "b64",
+				"content":     base64.StdEncoding.EncodeToString([]byte(script)),
+			},
+		},
+		"runcmd": []interface{}{BootstrapScriptPath},
+	}
+}
+
+// merges over into under. scalars of over win, lists are concatenated and maps are merged recursively
+func mergeConfig(over, under map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range under {
+		merged[k] = v
+	}
+	for k, v := range over {
+		current, ok := merged[k]
+		if !ok {
+			merged[k] = v
+			continue
+		}
+		switch v := v.(type) {
+		case []interface{}:
+			if list, ok := current.([]interface{}); ok {
+				merged[k] = append(append([]interface{}{}, v...), list...)
+				continue
+			}
+		case map[string]interface{}:
+			if m, ok := current.(map[string]interface{}); ok {
+				merged[k] = mergeConfig(v, m)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
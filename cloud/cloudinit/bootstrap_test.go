@@ -0,0 +1,131 @@
+package cloudinit_test
+
+import (
+	"encoding/base64"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+)
+
+var _ = Describe("DetectFormat", Label("unit", "cloudinit"), func() {
+	It("should detect formats", func() {
+		Expect(cloudinit.DetectFormat("## template: jinja\n#cloud-config\nruncmd: []")).To(Equal(cloudinit.FormatCloudConfig))
+		Expect(cloudinit.DetectFormat("#cloud-config\nruncmd: []")).To(Equal(cloudinit.FormatCloudConfig))
+		Expect(cloudinit.DetectFormat("#!/bin/bash\necho hello")).To(Equal(cloudinit.FormatShellScript))
+		Expect(cloudinit.DetectFormat(`{"ignition":{"version":"3.3.0"}}`)).To(Equal(cloudinit.FormatIgnition))
+	})
+})
+
+var _ = Describe("ResolveFormat", Label("unit", "cloudinit"), func() {
+	It("should detect the format unless declared", func() {
+		Expect(cloudinit.ResolveFormat("", "#!/bin/sh\necho hello")).To(Equal(cloudinit.FormatShellScript))
+		Expect(cloudinit.ResolveFormat("", `{"ignition":{"version":"3.3.0"}}`)).To(Equal(cloudinit.FormatIgnition))
+	})
+
+	It("should accept matching declared formats", func() {
+		Expect(cloudinit.ResolveFormat("cloud-config", "#cloud-config\nruncmd: []")).To(Equal(cloudinit.FormatCloudConfig))
+		Expect(cloudinit.ResolveFormat("cloud-config", "#!/bin/sh\necho hello")).To(Equal(cloudinit.FormatShellScript))
+		Expect(cloudinit.ResolveFormat("ignition", `{"ignition":{"version":"3.3.0"}}`)).To(Equal(cloudinit.FormatIgnition))
+	})
+
+	It("should reject mismatching and unknown formats", func() {
+		_, err := cloudinit.ResolveFormat("ignition", "#cloud-config\nruncmd: []")
+		Expect(err).To(MatchError(ContainSubstring("declared as ignition looks like cloud-config")))
+		_, err = cloudinit.ResolveFormat("cloud-config", `{"ignition":{"version":"3.3.0"}}`)
+		Expect(err).To(HaveOccurred())
+		_, err = cloudinit.ResolveFormat("talos", "version: v1alpha1")
+		Expect(err).To(MatchError(ContainSubstring(`unsupported bootstrap data format "talos"`)))
+	})
+})
+
+var _ = Describe("MergeBootstrapData", Label("unit", "cloudinit"), func() {
+	userData := infrav1.UserData{
+		HostName: "rke2-cp-0",
+		Packages: []string{"qemu-guest-agent"},
+		RunCmd:   []string{"systemctl start qemu-guest-agent"},
+	}
+
+	parse := func(content string) map[string]interface{} {
+		config := map[string]interface{}{}
+		Expect(yaml.Unmarshal([]byte(content), &config)).To(Succeed())
+		return config
+	}
+
+	It("should keep jinja header and keys unknown to UserData", func() {
+		bootstrap := `## template: jinja
+#cloud-config
+write_files:
+  - path: /etc/rancher/rke2/config.yaml
+    owner: root:root
+    permissions: '0640'
+    append: true
+    content: |
+      node-name: {{ ds.meta_data.local_hostname }}
+ntp:
+  enabled: true
+  servers: [0.pool.ntp.org]
+runcmd:
+  - 'curl -sfL https://get.rke2.io | sh -s - server'
+  - [sh, -c, 'mkdir -p /run/cluster-api && echo success > /run/cluster-api/bootstrap-success.complete']
+`
+		out, err := cloudinit.MergeBootstrapData(bootstrap, userData)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HavePrefix("## template: jinja\n#cloud-config\n"))
+		config := parse(out)
+		Expect(config).To(HaveKeyWithValue("hostname", "rke2-cp-0"))
+		Expect(config).To(HaveKeyWithValue("ntp", HaveKeyWithValue("enabled", true)))
+		Expect(config["write_files"]).To(ConsistOf(HaveKeyWithValue("append", true)))
+		Expect(out).To(ContainSubstring("{{ ds.meta_data.local_hostname }}"))
+		runcmd := config["runcmd"].([]interface{})
+		Expect(runcmd).To(HaveLen(3))
+		Expect(runcmd[0]).To(Equal("systemctl start qemu-guest-agent"))
+		Expect(runcmd[2]).To(HaveLen(3))
+	})
+
+	It("should let user data win over scalars of bootstrap data", func() {
+		out, err := cloudinit.MergeBootstrapData("#cloud-config\nhostname: k3s\nssh:\n  emit_keys_to_console: false\n", infrav1.UserData{HostName: "k3s-0", SSH: infrav1.SSH{EmitKeysToConsole: true}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HavePrefix("#cloud-config\n"))
+		config := parse(out)
+		Expect(config).To(HaveKeyWithValue("hostname", "k3s-0"))
+		Expect(config).To(HaveKeyWithValue("ssh", HaveKeyWithValue("emit_keys_to_console", true)))
+	})
+
+	It("should wrap shell script", func() {
+		script := "#!/bin/sh\ncurl -sfL https://get.k3s.io | sh -\n"
+		out, err := cloudinit.MergeBootstrapData(script, userData)
+		Expect(err).NotTo(HaveOccurred())
+		config := parse(out)
+		files := config["write_files"].([]interface{})
+		Expect(files).To(HaveLen(1))
+		file := files[0].(map[string]interface{})
+		Expect(file).To(HaveKeyWithValue("path", cloudinit.BootstrapScriptPath))
+		content, err := base64.StdEncoding.DecodeString(file["content"].(string))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(script))
+		Expect(config["runcmd"]).To(Equal([]interface{}{"systemctl start qemu-guest-agent", cloudinit.BootstrapScriptPath}))
+	})
+
+	It("should reject ignition", func() {
+		_, err := cloudinit.MergeBootstrapData(`{"ignition":{"version":"3.3.0"}}`, userData)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject broken cloud-config", func() {
+		_, err := cloudinit.MergeBootstrapData("#cloud-config\nruncmd:\n  - a\n b: c\n", userData)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("StripJinjaHeader", Label("unit", "cloudinit"), func() {
+	It("should strip the header", func() {
+		Expect(cloudinit.StripJinjaHeader("## template: jinja\n#cloud-config\nruncmd: []\n")).To(HavePrefix("#cloud-config"))
+		content := "#cloud-config\nruncmd: []\n"
+		Expect(strings.TrimSpace(cloudinit.StripJinjaHeader(content))).To(Equal(strings.TrimSpace(content)))
+	})
+})
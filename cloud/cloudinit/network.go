@@ -0,0 +1,77 @@
+package cloudinit
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// network-config version 2
+type networkConfig struct {
+	Version   int                 `yaml:"version"`
+	Ethernets map[string]ethernet `yaml:"ethernets"`
+}
+
+type ethernet struct {
+	Match       map[string]string `yaml:"match"`
+	SetName     string            `yaml:"set-name"`
+	DHCP4       bool              `yaml:"dhcp4,omitempty"`
+	DHCP6       bool              `yaml:"dhcp6,omitempty"`
+	AcceptRA    bool              `yaml:"accept-ra,omitempty"`
+	Addresses   []string          `yaml:"addresses,omitempty"`
+	Nameservers *nameservers      `yaml:"nameservers,omitempty"`
+	Routes      []route           `yaml:"routes,omitempty"`
+}
+
+type nameservers struct {
+	Addresses []string `yaml:"addresses,omitempty"`
+	Search    []string `yaml:"search,omitempty"`
+}
+
+type route struct {
+	To     string `yaml:"to"`
+	Via    string `yaml:"via"`
+	Metric int    `yaml:"metric,omitempty"`
+}
+
+// GenerateNetworkConfigYaml renders the network of the interface having the mac address,
+// which replaces the network configuration proxmox generates from ipconfig0.
+// like ipconfig0, it defaults to dhcp on IPv4 if neither IP nor IP6 is specified
+func GenerateNetworkConfigYaml(name, mac string, network infrav1.Network) (string, error) {
+	ipconfig := network.IPConfig
+	eth := ethernet{
+		Match:   map[string]string{"macaddress": strings.ToLower(mac)},
+		SetName: name,
+		DHCP4:   ipconfig.IP == "dhcp" || (ipconfig.IP == "" && ipconfig.IP6 == ""),
+		DHCP6:   ipconfig.IP6 == "dhcp",
+		// SLAAC
+		AcceptRA: ipconfig.IP6 == "auto",
+	}
+	for _, ip := range []string{ipconfig.IP, ipconfig.IP6} {
+		if ip != "" && ip != "dhcp" && ip != "auto" {
+			eth.Addresses = append(eth.Addresses, ip)
+		}
+	}
+	if ipconfig.Gateway != "" {
+		eth.Routes = append(eth.Routes, route{To: "0.0.0.0/0", Via: ipconfig.Gateway})
+	}
+	if ipconfig.Gateway6 != "" {
+		eth.Routes = append(eth.Routes, route{To: "::/0", Via: ipconfig.Gateway6})
+	}
+	for _, r := range ipconfig.Routes {
+		eth.Routes = append(eth.Routes, route{To: r.To, Via: r.Via, Metric: r.Metric})
+	}
+	if network.NameServer != "" || network.SearchDomain != "" {
+		eth.Nameservers = &nameservers{
+			Addresses: strings.Fields(network.NameServer),
+			Search:    strings.Fields(network.SearchDomain),
+		}
+	}
+	b, err := yaml.Marshal(networkConfig{Version: 2, Ethernets: map[string]ethernet{name: eth}})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
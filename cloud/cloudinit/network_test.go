@@ -0,0 +1,80 @@
+package cloudinit_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+)
+
+var _ = Describe("GenerateNetworkConfigYaml", Label("unit", "cloudinit"), func() {
+	It("should render static ips with routes", func() {
+		network := infrav1.Network{
+			IPConfig: infrav1.IPConfig{
+				IP: "10.0.0.10/24", Gateway: "10.0.0.1", IP6: "dhcp",
+				Routes: []infrav1.Route{{To: "10.1.0.0/16", Via: "10.0.0.254", Metric: 100}},
+			},
+			NameServer:   "10.0.0.2 10.0.0.3",
+			SearchDomain: "example.com",
+		}
+		config, err := cloudinit.GenerateNetworkConfigYaml("eth0", "BC:24:11:00:00:01", network)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchYAML(`
+version: 2
+ethernets:
+  eth0:
+    match:
+      macaddress: bc:24:11:00:00:01
+    set-name: eth0
+    dhcp6: true
+    addresses: [10.0.0.10/24]
+    nameservers:
+      addresses: [10.0.0.2, 10.0.0.3]
+      search: [example.com]
+    routes:
+      - to: 0.0.0.0/0
+        via: 10.0.0.1
+      - to: 10.1.0.0/16
+        via: 10.0.0.254
+        metric: 100
+`))
+	})
+
+	It("should default to dhcp on IPv4", func() {
+		network := infrav1.Network{IPConfig: infrav1.IPConfig{Routes: []infrav1.Route{{To: "10.1.0.0/16", Via: "10.0.0.254"}}}}
+		config, err := cloudinit.GenerateNetworkConfigYaml("eth0", "bc:24:11:00:00:01", network)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchYAML(`
+version: 2
+ethernets:
+  eth0:
+    match:
+      macaddress: bc:24:11:00:00:01
+    set-name: eth0
+    dhcp4: true
+    routes:
+      - to: 10.1.0.0/16
+        via: 10.0.0.254
+`))
+	})
+
+	It("should accept router advertisements for slaac", func() {
+		network := infrav1.Network{IPConfig: infrav1.IPConfig{IP: "10.0.0.10/24", IP6: "auto", Routes: []infrav1.Route{{To: "10.1.0.0/16", Via: "10.0.0.254"}}}}
+		config, err := cloudinit.GenerateNetworkConfigYaml("eth0", "bc:24:11:00:00:01", network)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchYAML(`
+version: 2
+ethernets:
+  eth0:
+    match:
+      macaddress: bc:24:11:00:00:01
+    set-name: eth0
+    accept-ra: true
+    addresses: [10.0.0.10/24]
+    routes:
+      - to: 10.1.0.0/16
+        via: 10.0.0.254
+`))
+	})
+})
@@ -0,0 +1,53 @@
+package cloudinit
+
+import (
+	"fmt"
+	"strings"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+const (
+	// ProxyEnvironmentPath is sourced by login shells
+	ProxyEnvironmentPath = "/etc/profile.d/proxy.sh"
+	// ProxyContainerdPath is the systemd drop-in of containerd
+	ProxyContainerdPath = "/etc/systemd/system/containerd.service.d/http-proxy.conf"
+)
+
+// ProxyUserData returns user data injecting the proxy into the environment and containerd.
+// containerd is restarted by runcmd, which runs before the commands of the bootstrap data
+func ProxyUserData(proxy infrav1.Proxy) *infrav1.UserData {
+	vars := proxyVariables(proxy)
+	var env, unit strings.Builder
+	unit.WriteString("[Service]\n")
+	for _, v := range vars {
+		fmt.Fprintf(&env, "export %s=%q\n", v[0], v[1])
+		fmt.Fprintf(&unit, "Environment=\"%s=%s\"\n", v[0], v[1])
+	}
+	return &infrav1.UserData{
+		WriteFiles: []infrav1.WriteFiles{
+			{Path: ProxyEnvironmentPath, Permissions: "0644", Content: env.String()},
+			{Path: ProxyContainerdPath, Permissions: "0644", Content: unit.String()},
+		},
+		RunCmd: []string{
+			"systemctl daemon-reload",
+			"systemctl try-restart containerd.service",
+		},
+	}
+}
+
+// returns name and value pairs of the proxy variables in both cases,
+// since tools disagree on which one they read
+func proxyVariables(proxy infrav1.Proxy) [][2]string {
+	var vars [][2]string
+	add := func(name, value string) {
+		if value == "" {
+			return
+		}
+		vars = append(vars, [2]string{strings.ToUpper(name), value}, [2]string{name, value})
+	}
+	add("http_proxy", proxy.HTTPProxy)
+	add("https_proxy", proxy.HTTPSProxy)
+	add("no_proxy", strings.Join(proxy.NoProxy, ","))
+	return vars
+}
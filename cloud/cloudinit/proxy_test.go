@@ -0,0 +1,34 @@
+package cloudinit_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+)
+
+var _ = Describe("ProxyUserData", Label("unit", "cloudinit"), func() {
+	It("should write the proxy into the environment and containerd", func() {
+		proxy := infrav1.Proxy{
+			HTTPProxy: "http://proxy.example.com:3128",
+			NoProxy:   []string{"localhost", "10.96.0.0/12"},
+		}
+		userData := cloudinit.ProxyUserData(proxy)
+		Expect(userData.WriteFiles).To(HaveLen(2))
+		Expect(userData.WriteFiles[0].Path).To(Equal(cloudinit.ProxyEnvironmentPath))
+		Expect(userData.WriteFiles[0].Content).To(Equal(`export HTTP_PROXY="http://proxy.example.com:3128"
+export http_proxy="http://proxy.example.com:3128"
+export NO_PROXY="localhost,10.96.0.0/12"
+export no_proxy="localhost,10.96.0.0/12"
+`))
+		Expect(userData.WriteFiles[1].Path).To(Equal(cloudinit.ProxyContainerdPath))
+		Expect(userData.WriteFiles[1].Content).To(Equal(`[Service]
+Environment="HTTP_PROXY=http://proxy.example.com:3128"
+Environment="http_proxy=http://proxy.example.com:3128"
+Environment="NO_PROXY=localhost,10.96.0.0/12"
+Environment="no_proxy=localhost,10.96.0.0/12"
+`))
+		Expect(userData.RunCmd).To(ContainElement("systemctl try-restart containerd.service"))
+	})
+})
@@ -0,0 +1,45 @@
+package cloudinit
+
+import (
+	"fmt"
+	"strings"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// RegistryConfigDir is the config_path of the containerd registry hosts
+const RegistryConfigDir = "/etc/containerd/certs.d"
+
+// RegistryUserData returns user data writing hosts.toml of the registries.
+// containerd reads them on every pull, so no restart is needed
+func RegistryUserData(registries []infrav1.Registry) *infrav1.UserData {
+	userData := &infrav1.UserData{}
+	for _, r := range registries {
+		userData.WriteFiles = append(userData.WriteFiles, infrav1.WriteFiles{
+			Path:        fmt.Sprintf("%s/%s/hosts.toml", RegistryConfigDir, r.Host),
+			Permissions: "0644",
+			Content:     GenerateRegistryHostsToml(r),
+		})
+	}
+	return userData
+}
+
+// GenerateRegistryHostsToml renders hosts.toml of the registry
+func GenerateRegistryHostsToml(r infrav1.Registry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "server = %q\n", r.Server())
+	for _, mirror := range r.Mirrors {
+		fmt.Fprintf(&b, "\n[host.%q]\n", mirror)
+		b.WriteString("  capabilities = [\"pull\", \"resolve\"]\n")
+		if r.Insecure {
+			b.WriteString("  skip_verify = true\n")
+		}
+	}
+	// hosts replace the server unless it is listed too
+	if r.Insecure && len(r.Mirrors) == 0 {
+		fmt.Fprintf(&b, "\n[host.%q]\n", r.Server())
+		b.WriteString("  capabilities = [\"pull\", \"resolve\", \"push\"]\n")
+		b.WriteString("  skip_verify = true\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package cloudinit_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+)
+
+var _ = Describe("GenerateRegistryHostsToml", Label("unit", "cloudinit"), func() {
+	It("should render the mirrors before the server", func() {
+		registry := infrav1.Registry{
+			Host:    "docker.io",
+			Mirrors: []string{"https://mirror.example.com"},
+		}
+		Expect(cloudinit.GenerateRegistryHostsToml(registry)).To(Equal(`server = "https://registry-1.docker.io"
+
+[host."https://mirror.example.com"]
+  capabilities = ["pull", "resolve"]
+`))
+	})
+
+	It("should skip tls verification of an insecure registry", func() {
+		registry := infrav1.Registry{
+			Host:     "registry.example.com:5000",
+			Insecure: true,
+		}
+		Expect(cloudinit.GenerateRegistryHostsToml(registry)).To(Equal(`server = "https://registry.example.com:5000"
+
+[host."https://registry.example.com:5000"]
+  capabilities = ["pull", "resolve", "push"]
+  skip_verify = true
+`))
+	})
+
+	It("should write hosts.toml per registry", func() {
+		userData := cloudinit.RegistryUserData([]infrav1.Registry{{Host: "quay.io"}, {Host: "ghcr.io"}})
+		Expect(userData.WriteFiles).To(HaveLen(2))
+		Expect(userData.WriteFiles[1].Path).To(Equal("/etc/containerd/certs.d/ghcr.io/hosts.toml"))
+	})
+})
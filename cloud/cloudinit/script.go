@@ -0,0 +1,192 @@
+package cloudinit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jinja variables of the local hostname. other instance data is not available without cloud-init
+var localHostNameRegex = regexp.MustCompile(`\{\{\s*(ds\.meta_data\.local_hostname|v1\.local_hostname)\s*\}\}`)
+
+// subset of cloud-config rendered by ShellScript
+type scriptConfig struct {
+	WriteFiles []struct {
+		Path        string `yaml:"path"`
+		Content     string `yaml:"content"`
+		Encoding    string `yaml:"encoding"`
+		Owner       string `yaml:"owner"`
+		Permissions string `yaml:"permissions"`
+		Append      bool   `yaml:"append"`
+	} `yaml:"write_files"`
+	BootCmd           []interface{} `yaml:"bootcmd"`
+	Packages          []interface{} `yaml:"packages"`
+	SSHAuthorizedKeys []string      `yaml:"ssh_authorized_keys"`
+	User              string        `yaml:"user"`
+	Password          string        `yaml:"password"`
+	RunCmd            []interface{} `yaml:"runcmd"`
+}
+
+// ShellScript renders cloud-config as a shell script for guests without cloud-init datasource, e.g. lxc containers.
+// bootcmd, write_files, ssh_authorized_keys of root, password of user (root by default), packages and runcmd
+// are run in the order of cloud-init.
+// hostname and network are left to proxmox
+func ShellScript(cloudConfig, hostname string) (string, error) {
+	jinja, body := splitJinjaHeader(cloudConfig)
+	if jinja {
+		body = localHostNameRegex.ReplaceAllLiteralString(body, hostname)
+	}
+	var config scriptConfig
+	if err := yaml.Unmarshal([]byte(body), &config); err != nil {
+		return "", fmt.Errorf("failed to parse cloud-config: %w", err)
+	}
+
+	script := []string{"#!/bin/sh"}
+	for _, c := range config.BootCmd {
+		cmd, err := command(c)
+		if err != nil {
+			return "", fmt.Errorf("bootcmd: %w", err)
+		}
+		script = append(script, cmd)
+	}
+	for _, f := range config.WriteFiles {
+		content, err := decode(f.Content, f.Encoding)
+		if err != nil {
+			return "", fmt.Errorf("write_files %s: %w", f.Path, err)
+		}
+		redirect := ">"
+		if f.Append {
+			redirect = ">>"
+		}
+		owner, permissions := f.Owner, f.Permissions
+		if owner == "" {
+			owner = "root:root"
+		}
+		if permissions == "" {
+			permissions = "0644"
+		}
+		p := ShellQuote(f.Path)
+		script = append(script,
+			fmt.Sprintf("mkdir -p %s", ShellQuote(path.Dir(f.Path))),
+			fmt.Sprintf("echo '%s' | base64 -d %s %s", base64.StdEncoding.EncodeToString(content), redirect, p),
+			fmt.Sprintf("chown %s %s", ShellQuote(owner), p),
+			fmt.Sprintf("chmod %s %s", ShellQuote(permissions), p),
+		)
+	}
+	if len(config.SSHAuthorizedKeys) > 0 {
+		script = append(script, "mkdir -p /root/.ssh && chmod 700 /root/.ssh")
+		for _, key := range config.SSHAuthorizedKeys {
+			script = append(script, fmt.Sprintf("echo %s >> /root/.ssh/authorized_keys", ShellQuote(key)))
+		}
+		script = append(script, "chmod 600 /root/.ssh/authorized_keys")
+	}
+	if config.Password != "" {
+		user := config.User
+		if user == "" {
+			user = "root"
+		}
+		// the password is passed base64 encoded so that it never needs quoting
+		credentials := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", user, config.Password)))
+		script = append(script,
+			fmt.Sprintf("id -u %[1]s >/dev/null 2>&1 || useradd -m %[1]s", ShellQuote(user)),
+			fmt.Sprintf("echo '%s' | base64 -d | chpasswd", credentials),
+		)
+	}
+	if len(config.Packages) > 0 {
+		packages := []string{}
+		for _, p := range config.Packages {
+			pkg, err := packageName(p)
+			if err != nil {
+				return "", fmt.Errorf("packages: %w", err)
+			}
+			packages = append(packages, ShellQuote(pkg))
+		}
+		script = append(script, installCommand(strings.Join(packages, " ")))
+	}
+	for _, c := range config.RunCmd {
+		cmd, err := command(c)
+		if err != nil {
+			return "", fmt.Errorf("runcmd: %w", err)
+		}
+		script = append(script, cmd)
+	}
+	return strings.Join(script, "\n") + "\n", nil
+}
+
+// commands of cloud-config are either a shell line or a list of arguments
+func command(c interface{}) (string, error) {
+	switch c := c.(type) {
+	case string:
+		return c, nil
+	case []interface{}:
+		args := []string{}
+		for _, arg := range c {
+			args = append(args, ShellQuote(fmt.Sprint(arg)))
+		}
+		return strings.Join(args, " "), nil
+	default:
+		return "", fmt.Errorf("unexpected command %v", c)
+	}
+}
+
+// packages of cloud-config are either a name or a list of name and version
+func packageName(p interface{}) (string, error) {
+	switch p := p.(type) {
+	case string:
+		return p, nil
+	case []interface{}:
+		if len(p) != 2 {
+			return "", fmt.Errorf("unexpected package %v", p)
+		}
+		return fmt.Sprintf("%v=%v", p[0], p[1]), nil
+	default:
+		return "", fmt.Errorf("unexpected package %v", p)
+	}
+}
+
+func installCommand(packages string) string {
+	return fmt.Sprintf(`if command -v apt-get >/dev/null; then apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y %[1]s; `+
+		`elif command -v dnf >/dev/null; then dnf install -y %[1]s; `+
+		`elif command -v yum >/dev/null; then yum install -y %[1]s; `+
+		`elif command -v apk >/dev/null; then apk add --no-cache %[1]s; fi`, packages)
+}
+
+func decode(content, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "text/plain":
+		return []byte(content), nil
+	case "b64", "base64":
+		return base64.StdEncoding.DecodeString(content)
+	case "gz", "gzip":
+		return gunzip([]byte(content))
+	case "gz+b64", "gz+base64", "gzip+b64", "gzip+base64":
+		b, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, err
+		}
+		return gunzip(b)
+	default:
+		return nil, fmt.Errorf("unknown encoding %s", encoding)
+	}
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// ShellQuote quotes s as a single word of a posix shell
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
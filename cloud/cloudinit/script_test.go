@@ -0,0 +1,102 @@
+package cloudinit_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+)
+
+var _ = Describe("ShellScript", Label("unit", "cloudinit"), func() {
+	It("should render cloud-config in the order of cloud-init", func() {
+		config := `## template: jinja
+#cloud-config
+runcmd:
+  - 'k3s-install.sh --node-name {{ ds.meta_data.local_hostname }}'
+  - [sh, -c, "echo it's done"]
+packages:
+  - curl
+  - [containerd, 1.7.2]
+ssh_authorized_keys:
+  - ssh-ed25519 AAAA user@host
+write_files:
+  - path: /etc/rancher/k3s/config.yaml
+    permissions: '0600'
+    content: |
+      node-name: {{v1.local_hostname}}
+bootcmd:
+  - modprobe br_netfilter
+`
+		script, err := cloudinit.ShellScript(config, "k3s-0")
+		Expect(err).NotTo(HaveOccurred())
+		lines := strings.Split(strings.TrimSpace(script), "\n")
+		Expect(lines[0]).To(Equal("#!/bin/sh"))
+		Expect(lines[1]).To(Equal("modprobe br_netfilter"))
+		Expect(lines[2]).To(Equal("mkdir -p '/etc/rancher/k3s'"))
+		Expect(lines[3]).To(Equal("echo '" + base64.StdEncoding.EncodeToString([]byte("node-name: k3s-0\n")) + "' | base64 -d > '/etc/rancher/k3s/config.yaml'"))
+		Expect(lines[4]).To(Equal("chown 'root:root' '/etc/rancher/k3s/config.yaml'"))
+		Expect(lines[5]).To(Equal("chmod '0600' '/etc/rancher/k3s/config.yaml'"))
+		Expect(lines[7]).To(Equal("echo 'ssh-ed25519 AAAA user@host' >> /root/.ssh/authorized_keys"))
+		Expect(lines[9]).To(ContainSubstring("apt-get install -y 'curl' 'containerd=1.7.2'"))
+		Expect(lines[10]).To(Equal("k3s-install.sh --node-name k3s-0"))
+		Expect(lines[11]).To(Equal(`'sh' '-c' 'echo it'\''s done'`))
+	})
+
+	It("should not render templates without jinja header", func() {
+		script, err := cloudinit.ShellScript("#cloud-config\nruncmd:\n  - echo '{{ v1.local_hostname }}'\n", "k3s-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(script).To(ContainSubstring("{{ v1.local_hostname }}"))
+	})
+
+	It("should write files that the script creates", func() {
+		if _, err := exec.LookPath("base64"); err != nil {
+			Skip("base64 is not available")
+		}
+		dir := GinkgoT().TempDir()
+		var gz bytes.Buffer
+		w := gzip.NewWriter(&gz)
+		_, err := w.Write([]byte("compressed\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		config := "#cloud-config\nwrite_files:\n" +
+			"  - path: " + dir + "/a/plain\n    owner: " + currentUser() + "\n    content: |\n      plain\n" +
+			"  - path: " + dir + "/a/plain\n    owner: " + currentUser() + "\n    append: true\n    content: appended\n" +
+			"  - path: " + dir + "/gz\n    owner: " + currentUser() + "\n    encoding: gz+b64\n    content: " + base64.StdEncoding.EncodeToString(gz.Bytes()) + "\n"
+		script, err := cloudinit.ShellScript(config, "host")
+		Expect(err).NotTo(HaveOccurred())
+		out, err := exec.Command("sh", "-c", script).CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+		Expect(os.ReadFile(filepath.Join(dir, "a", "plain"))).To(BeEquivalentTo("plain\nappended"))
+		Expect(os.ReadFile(filepath.Join(dir, "gz"))).To(BeEquivalentTo("compressed\n"))
+	})
+
+	It("should set the password of the user", func() {
+		script, err := cloudinit.ShellScript("#cloud-config\nuser: admin\npassword: it's secret\n", "host")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(script).To(ContainSubstring("id -u 'admin' >/dev/null 2>&1 || useradd -m 'admin'"))
+		Expect(script).To(ContainSubstring("echo '" + base64.StdEncoding.EncodeToString([]byte("admin:it's secret")) + "' | base64 -d | chpasswd"))
+
+		script, err = cloudinit.ShellScript("#cloud-config\npassword: secret\n", "host")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(script).To(ContainSubstring("echo '" + base64.StdEncoding.EncodeToString([]byte("root:secret")) + "' | base64 -d | chpasswd"))
+	})
+
+	It("should error for unknown encoding", func() {
+		_, err := cloudinit.ShellScript("#cloud-config\nwrite_files:\n  - path: /a\n    encoding: zstd\n    content: a\n", "host")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func currentUser() string {
+	out, err := exec.Command("id", "-un").Output()
+	Expect(err).NotTo(HaveOccurred())
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,172 @@
+package guest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/retry"
+)
+
+const (
+	TypeQEMU = "qemu"
+	TypeLXC  = "lxc"
+
+	// status of guests on unreachable nodes
+	StatusUnknown = api.ProcessStatus("unknown")
+
+	resourcesPath = "/cluster/resources?type=vm"
+
+	// ManagedTag is put on every guest managed by cappx
+	ManagedTag = "cappx"
+
+	machineTagPrefix = "machine."
+)
+
+// ErrUnreachable is returned when the guest is on a proxmox node which can not be reached
+var ErrUnreachable = errors.New("guest is on an unreachable proxmox node")
+
+// Guest is a qemu or an lxc container listed in cluster resources
+type Guest struct {
+	Type   string            `json:"type"`
+	Node   string            `json:"node"`
+	VMID   int               `json:"vmid"`
+	Name   string            `json:"name"`
+	Status api.ProcessStatus `json:"status"`
+	MaxMem int               `json:"maxmem"`
+	// size of the disks in bytes
+	MaxDisk int     `json:"maxdisk"`
+	MaxCPU  float64 `json:"maxcpu"`
+	Pool    string  `json:"pool"`
+	// semicolon separated
+	Tags string `json:"tags"`
+}
+
+// List returns qemus and containers of all nodes at once.
+// unlike listing per node, it does not fail when some nodes are unreachable
+func List(ctx context.Context, client *proxmox.Service) ([]Guest, error) {
+	var guests []Guest
+	if err := client.RESTClient().Get(ctx, resourcesPath, &guests); err != nil {
+		return nil, err
+	}
+	return guests, nil
+}
+
+// Find returns the guest having the vmid or rest.NotFoundErr
+func Find(guests []Guest, vmid int) (*Guest, error) {
+	for i := range guests {
+		if guests[i].VMID == vmid {
+			return &guests[i], nil
+		}
+	}
+	return nil, rest.NotFoundErr
+}
+
+// ClusterTag is the tag of guests belonging to the cluster
+func ClusterTag(clusterName string) string {
+	return "cluster." + clusterName
+}
+
+// MachineTag is the tag of the guest of the ProxmoxMachine.
+// namespaces can not contain ".", so the namespace and the name are told apart by the first one
+func MachineTag(namespace, name string) string {
+	return TruncateTag(machineTagPrefix + namespace + "." + name)
+}
+
+// TruncateTag shortens tags longer than Proxmox accepts. the end is replaced by a hash of the
+// whole tag, so that long tags sharing a prefix are still told apart
+func TruncateTag(tag string) string {
+	if len(tag) <= infrav1.MaxTagLength {
+		return tag
+	}
+	sum := sha256.Sum256([]byte(tag))
+	suffix := hex.EncodeToString(sum[:])[:8]
+	return tag[:infrav1.MaxTagLength-len(suffix)-1] + "-" + suffix
+}
+
+// MachineNamespace returns the namespace of the ProxmoxMachine the guest is tagged with.
+// false is returned if the guest has no machine tag, e.g. created by older versions of cappx
+func (g Guest) MachineNamespace() (string, bool) {
+	for _, t := range strings.Split(g.Tags, ";") {
+		rest, ok := strings.CutPrefix(strings.ToLower(t), machineTagPrefix)
+		if !ok {
+			continue
+		}
+		if namespace, _, ok := strings.Cut(rest, "."); ok {
+			return namespace, true
+		}
+	}
+	return "", false
+}
+
+// HasTag returns true if the guest has the tag. tags are case insensitive
+func (g Guest) HasTag(tag string) bool {
+	for _, t := range strings.Split(g.Tags, ";") {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnedBy returns true if the guest is tagged with the machine tag. guests without any machine tag,
+// e.g. created by older versions of cappx, are owned if they have one of the names
+func (g Guest) OwnedBy(machineTag string, names ...string) bool {
+	if g.HasTag(machineTag) {
+		return true
+	}
+	if _, tagged := g.MachineNamespace(); tagged {
+		return false
+	}
+	for _, name := range names {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// VirtualMachine converts the guest into the form scheduler plugins use for qemus
+func (g Guest) VirtualMachine() *api.VirtualMachine {
+	return &api.VirtualMachine{
+		Name:   g.Name,
+		VMID:   g.VMID,
+		Status: g.Status,
+		MaxMem: g.MaxMem,
+		Cpus:   int(g.MaxCPU),
+	}
+}
+
+// QEMU returns the qemu of the guest. cluster resources tell its node and whether the node can be
+// reached, since client.VirtualMachine asks every node and fails with 595 of unreachable ones.
+// rest.NotFoundErr is returned if the guest is not a qemu
+func (g Guest) QEMU(ctx context.Context, client *proxmox.Service) (*proxmox.VirtualMachine, error) {
+	if g.Type != TypeQEMU {
+		return nil, rest.NotFoundErr
+	}
+	if g.Status == StatusUnknown {
+		return nil, fmt.Errorf("%w: vmid %d on node %s", ErrUnreachable, g.VMID, g.Node)
+	}
+	vm, err := client.VirtualMachine(ctx, g.VMID)
+	if err == nil || !retry.IsServerError(err) {
+		return vm, err
+	}
+	// another node can not be reached. looking the qemu up by its uuid skips unreachable nodes
+	config, err := client.RESTClient().GetVirtualMachineConfig(ctx, g.Node, g.VMID)
+	if err != nil {
+		return nil, err
+	}
+	uuid, err := proxmox.ConvertSMBiosToUUID(config.SMBios1)
+	if err != nil {
+		return nil, err
+	}
+	return client.VirtualMachineFromUUID(ctx, uuid)
+}
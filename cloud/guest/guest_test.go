@@ -0,0 +1,90 @@
+package guest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+func TestGuest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Guest Suite")
+}
+
+var _ = Describe("Find", Label("unit", "guest"), func() {
+	guests := []guest.Guest{
+		{Type: guest.TypeQEMU, Node: "node1", VMID: 100},
+		{Type: guest.TypeLXC, Node: "node2", VMID: 101},
+	}
+
+	It("should find guest of any type", func() {
+		g, err := guest.Find(guests, 101)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(g.Type).To(Equal(guest.TypeLXC))
+		Expect(g.Node).To(Equal("node2"))
+	})
+
+	It("should return not found error", func() {
+		_, err := guest.Find(guests, 102)
+		Expect(rest.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("VirtualMachine", Label("unit", "guest"), func() {
+	It("should convert resources", func() {
+		g := guest.Guest{Type: guest.TypeLXC, VMID: 100, Name: "ct", Status: api.ProcessStatusRunning, MaxMem: 1 << 30, MaxCPU: 2}
+		Expect(g.VirtualMachine()).To(Equal(&api.VirtualMachine{VMID: 100, Name: "ct", Status: api.ProcessStatusRunning, MaxMem: 1 << 30, Cpus: 2}))
+	})
+})
+
+var _ = Describe("MachineTag", Label("unit", "guest"), func() {
+	It("should tag namespace and name", func() {
+		Expect(guest.MachineTag("default", "cappx-test-cp-abcde")).To(Equal("machine.default.cappx-test-cp-abcde"))
+	})
+
+	It("should truncate long names with a hash", func() {
+		a := guest.MachineTag("default", strings.Repeat("a", 200)+"-1")
+		b := guest.MachineTag("default", strings.Repeat("a", 200)+"-2")
+		Expect(a).To(HaveLen(128))
+		Expect(b).To(HaveLen(128))
+		Expect(a).NotTo(Equal(b))
+		Expect(a).To(HavePrefix("machine.default.aaa"))
+		Expect(guest.MachineTag("default", strings.Repeat("a", 200))).To(Equal(guest.MachineTag("default", strings.Repeat("a", 200))))
+	})
+})
+
+var _ = Describe("MachineNamespace", Label("unit", "guest"), func() {
+	It("should return namespace of the machine tag", func() {
+		ns, ok := guest.Guest{Tags: "cappx;cluster.test;machine.kube-system.cp-0"}.MachineNamespace()
+		Expect(ok).To(BeTrue())
+		Expect(ns).To(Equal("kube-system"))
+	})
+
+	It("should return false without machine tag", func() {
+		_, ok := guest.Guest{Tags: "cappx;cluster.test"}.MachineNamespace()
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("OwnedBy", Label("unit", "guest"), func() {
+	tag := guest.MachineTag("default", "cp-0")
+
+	It("should be owned by the machine tag", func() {
+		Expect(guest.Guest{Name: "renamed", Tags: "cappx;machine.default.cp-0"}.OwnedBy(tag, "cp-0")).To(BeTrue())
+	})
+
+	It("should not be owned by another machine", func() {
+		Expect(guest.Guest{Name: "cp-0", Tags: "cappx;machine.other.cp-0"}.OwnedBy(tag, "cp-0")).To(BeFalse())
+	})
+
+	It("should be owned by the name without machine tag", func() {
+		Expect(guest.Guest{Name: "cp-0", Tags: "cappx"}.OwnedBy(tag, "cp-0")).To(BeTrue())
+		Expect(guest.Guest{Name: "web-0"}.OwnedBy(tag, "cp-0")).To(BeFalse())
+	})
+})
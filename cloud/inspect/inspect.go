@@ -0,0 +1,193 @@
+// Package inspect correlates ProxmoxMachines with the live state of their guests for troubleshooting,
+// as shown by the kubectl-proxmox plugin
+package inspect
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+// MachineState is a ProxmoxMachine together with the guest found for it
+type MachineState struct {
+	Machine infrav1.ProxmoxMachine
+	// nil if no guest is found
+	Guest *guest.Guest
+	// Issues are differences between the ProxmoxMachine and the guest worth looking into
+	Issues []string
+}
+
+// Correlate finds the guest of every machine. guests are matched by the machine tag,
+// then by the vmid of the spec as long as they are not tagged for another machine
+func Correlate(machines []infrav1.ProxmoxMachine, guests []guest.Guest) []MachineState {
+	states := make([]MachineState, 0, len(machines))
+	for _, m := range machines {
+		state := MachineState{Machine: m, Guest: find(m, guests)}
+		state.Issues = issues(m, state.Guest)
+		states = append(states, state)
+	}
+	slices.SortFunc(states, func(a, b MachineState) int {
+		return strings.Compare(a.Machine.Namespace+"/"+a.Machine.Name, b.Machine.Namespace+"/"+b.Machine.Name)
+	})
+	return states
+}
+
+func find(m infrav1.ProxmoxMachine, guests []guest.Guest) *guest.Guest {
+	tag := guest.MachineTag(m.Namespace, m.Name)
+	for i := range guests {
+		if guests[i].HasTag(tag) {
+			return &guests[i]
+		}
+	}
+	if m.Spec.VMID == nil {
+		return nil
+	}
+	g, err := guest.Find(guests, *m.Spec.VMID)
+	if err != nil {
+		return nil
+	}
+	if _, tagged := g.MachineNamespace(); tagged {
+		return nil
+	}
+	return g
+}
+
+func issues(m infrav1.ProxmoxMachine, g *guest.Guest) []string {
+	var issues []string
+	if m.Status.FailureMessage != nil {
+		issues = append(issues, fmt.Sprintf("failed: %s", *m.Status.FailureMessage))
+	}
+	if g == nil {
+		if m.Spec.VMID != nil || m.Spec.ProviderID != nil {
+			issues = append(issues, "no vm is found")
+		}
+		return issues
+	}
+	if m.Spec.VMID != nil && *m.Spec.VMID != g.VMID {
+		issues = append(issues, fmt.Sprintf("spec has vmid %d, vm has %d", *m.Spec.VMID, g.VMID))
+	}
+	if m.Spec.Node != "" && m.Spec.Node != g.Node {
+		issues = append(issues, fmt.Sprintf("spec has node %s, vm is on %s", m.Spec.Node, g.Node))
+	}
+	if g.Status == guest.StatusUnknown {
+		issues = append(issues, fmt.Sprintf("node %s is unreachable", g.Node))
+	}
+	if status := m.Status.InstanceStatus; status != nil && string(*status) != string(g.Status) && g.Status != guest.StatusUnknown {
+		issues = append(issues, fmt.Sprintf("status says %s, vm is %s", *status, g.Status))
+	}
+	return issues
+}
+
+// Status returns the status of the guest, or the provisioning phase while there is no guest yet
+func (s MachineState) Status() string {
+	if s.Guest != nil {
+		return string(s.Guest.Status)
+	}
+	if phase := s.Machine.Status.ProvisioningPhase; phase != nil {
+		return string(phase.Name)
+	}
+	return "<none>"
+}
+
+// Node returns the node the guest is on, or the node of the spec while there is no guest yet
+func (s MachineState) Node() string {
+	if s.Guest != nil {
+		return s.Guest.Node
+	}
+	if s.Machine.Spec.Node != "" {
+		return s.Machine.Spec.Node
+	}
+	return "<none>"
+}
+
+// VMID returns the vmid of the guest, or of the spec while there is no guest yet. 0 if neither is known
+func (s MachineState) VMID() int {
+	if s.Guest != nil {
+		return s.Guest.VMID
+	}
+	if s.Machine.Spec.VMID != nil {
+		return *s.Machine.Spec.VMID
+	}
+	return 0
+}
+
+// ConsoleHint tells how to open the console of the guest: the url of the Proxmox web UI if known,
+// otherwise the command to run on the node
+func (s MachineState) ConsoleHint() string {
+	if c := s.Machine.Status.Console; c != nil && c.URL != "" {
+		return c.URL
+	}
+	vmid := s.VMID()
+	if vmid == 0 {
+		return "<none>"
+	}
+	command := "qm terminal"
+	if s.Machine.Spec.Type == infrav1.InstanceTypeLXC || s.Guest != nil && s.Guest.Type == guest.TypeLXC {
+		command = "pct enter"
+	}
+	return fmt.Sprintf("%s %d (on node %s)", command, vmid, s.Node())
+}
+
+// Cluster returns the name of the Cluster the machine belongs to
+func (s MachineState) Cluster() string {
+	return s.Machine.Labels[clusterv1.ClusterNameLabel]
+}
+
+// Task is a task listed by GET /nodes/{node}/tasks
+type Task struct {
+	UPID      string `json:"upid"`
+	Type      string `json:"type"`
+	User      string `json:"user"`
+	StartTime int64  `json:"starttime"`
+	EndTime   int64  `json:"endtime"`
+	// OK or the error of finished tasks, empty while running
+	Status string `json:"status"`
+}
+
+// Duration returns how long the task has run. running tasks are measured until now
+func (t Task) Duration(now time.Time) time.Duration {
+	end := now.Unix()
+	if t.EndTime != 0 {
+		end = t.EndTime
+	}
+	return time.Duration(end-t.StartTime) * time.Second
+}
+
+// Result returns the status of finished tasks or "running"
+func (t Task) Result() string {
+	if t.EndTime == 0 && t.Status == "" {
+		return "running"
+	}
+	return t.Status
+}
+
+// Tasks returns the latest tasks of the guest on the node, newest first, including failed ones
+func Tasks(ctx context.Context, client *proxmox.Service, node string, vmid, limit int) ([]Task, error) {
+	query := url.Values{}
+	query.Set("vmid", fmt.Sprint(vmid))
+	query.Set("limit", fmt.Sprint(limit))
+	query.Set("source", "all")
+	var tasks []Task
+	if err := client.RESTClient().Get(ctx, fmt.Sprintf("/nodes/%s/tasks?%s", node, query.Encode()), &tasks); err != nil {
+		return nil, err
+	}
+	SortTasks(tasks)
+	return tasks, nil
+}
+
+// SortTasks sorts the tasks newest first
+func SortTasks(tasks []Task) {
+	slices.SortStableFunc(tasks, func(a, b Task) int {
+		return cmp.Compare(b.StartTime, a.StartTime)
+	})
+}
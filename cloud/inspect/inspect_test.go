@@ -0,0 +1,122 @@
+package inspect_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/inspect"
+)
+
+func TestInspect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Inspect Suite")
+}
+
+func machine(name string, vmid *int) infrav1.ProxmoxMachine {
+	m := infrav1.ProxmoxMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	m.Spec.VMID = vmid
+	return m
+}
+
+var _ = Describe("Correlate", Label("unit", "inspect"), func() {
+	It("should find guests by the machine tag", func() {
+		guests := []guest.Guest{
+			{Type: guest.TypeQEMU, VMID: 100, Node: "pve1", Name: "cp-0", Status: "running", Tags: "cappx;" + guest.MachineTag("default", "cp-0")},
+			{Type: guest.TypeQEMU, VMID: 101, Node: "pve2", Name: "md-0", Status: "running", Tags: "cappx;" + guest.MachineTag("default", "md-0")},
+		}
+		states := inspect.Correlate([]infrav1.ProxmoxMachine{machine("md-0", nil), machine("cp-0", ptr.To(100))}, guests)
+		Expect(states).To(HaveLen(2))
+		Expect(states[0].Machine.Name).To(Equal("cp-0"))
+		Expect(states[0].Guest.VMID).To(Equal(100))
+		Expect(states[1].Guest.VMID).To(Equal(101))
+		Expect(states[1].Node()).To(Equal("pve2"))
+		Expect(states[1].Issues).To(BeEmpty())
+	})
+
+	It("should fall back to the vmid for guests without machine tag", func() {
+		guests := []guest.Guest{{Type: guest.TypeQEMU, VMID: 100, Node: "pve1", Name: "old", Tags: "cappx"}}
+		states := inspect.Correlate([]infrav1.ProxmoxMachine{machine("cp-0", ptr.To(100))}, guests)
+		Expect(states[0].Guest).NotTo(BeNil())
+	})
+
+	It("should not take guests of other machines", func() {
+		guests := []guest.Guest{{Type: guest.TypeQEMU, VMID: 100, Tags: guest.MachineTag("default", "md-0")}}
+		states := inspect.Correlate([]infrav1.ProxmoxMachine{machine("cp-0", ptr.To(100))}, guests)
+		Expect(states[0].Guest).To(BeNil())
+		Expect(states[0].Issues).To(ConsistOf("no vm is found"))
+	})
+
+	It("should report differences between the machine and the guest", func() {
+		m := machine("cp-0", ptr.To(100))
+		m.Spec.Node = "pve1"
+		m.Status.InstanceStatus = ptr.To(infrav1.InstanceStatusRunning)
+		m.Status.FailureMessage = ptr.To("boom")
+		guests := []guest.Guest{{Type: guest.TypeQEMU, VMID: 100, Node: "pve2", Status: "stopped", Tags: guest.MachineTag("default", "cp-0")}}
+		states := inspect.Correlate([]infrav1.ProxmoxMachine{m}, guests)
+		Expect(states[0].Issues).To(ConsistOf(
+			"failed: boom",
+			"spec has node pve1, vm is on pve2",
+			"status says running, vm is stopped",
+		))
+	})
+
+	It("should report unreachable nodes", func() {
+		guests := []guest.Guest{{Type: guest.TypeQEMU, VMID: 100, Node: "pve1", Status: guest.StatusUnknown, Tags: guest.MachineTag("default", "cp-0")}}
+		states := inspect.Correlate([]infrav1.ProxmoxMachine{machine("cp-0", nil)}, guests)
+		Expect(states[0].Issues).To(ConsistOf("node pve1 is unreachable"))
+	})
+})
+
+var _ = Describe("MachineState", Label("unit", "inspect"), func() {
+	It("should show the provisioning phase while there is no guest", func() {
+		m := machine("cp-0", nil)
+		m.Status.ProvisioningPhase = &infrav1.ProvisioningPhase{Name: "Scheduling"}
+		state := inspect.MachineState{Machine: m}
+		Expect(state.Status()).To(Equal("Scheduling"))
+		Expect(state.Node()).To(Equal("<none>"))
+		Expect(state.ConsoleHint()).To(Equal("<none>"))
+	})
+
+	It("should prefer the console url of the status", func() {
+		m := machine("cp-0", ptr.To(100))
+		m.Status.Console = &infrav1.Console{URL: "https://pve.example.com:8006/?console=kvm"}
+		Expect(inspect.MachineState{Machine: m}.ConsoleHint()).To(Equal("https://pve.example.com:8006/?console=kvm"))
+	})
+
+	It("should hint the command of the node otherwise", func() {
+		g := &guest.Guest{Type: guest.TypeQEMU, VMID: 100, Node: "pve1"}
+		Expect(inspect.MachineState{Machine: machine("cp-0", nil), Guest: g}.ConsoleHint()).To(Equal("qm terminal 100 (on node pve1)"))
+		g = &guest.Guest{Type: guest.TypeLXC, VMID: 101, Node: "pve2"}
+		Expect(inspect.MachineState{Machine: machine("cp-0", nil), Guest: g}.ConsoleHint()).To(Equal("pct enter 101 (on node pve2)"))
+	})
+})
+
+var _ = Describe("Task", Label("unit", "inspect"), func() {
+	now := time.Unix(1000, 0)
+
+	It("should measure running tasks until now", func() {
+		t := inspect.Task{StartTime: 900}
+		Expect(t.Result()).To(Equal("running"))
+		Expect(t.Duration(now)).To(Equal(100 * time.Second))
+	})
+
+	It("should report the status of finished tasks", func() {
+		t := inspect.Task{StartTime: 900, EndTime: 930, Status: "OK"}
+		Expect(t.Result()).To(Equal("OK"))
+		Expect(t.Duration(now)).To(Equal(30 * time.Second))
+	})
+
+	It("should sort tasks newest first", func() {
+		tasks := []inspect.Task{{UPID: "a", StartTime: 1}, {UPID: "c", StartTime: 3}, {UPID: "b", StartTime: 2}}
+		inspect.SortTasks(tasks)
+		Expect(tasks[0].UPID).To(Equal("c"))
+		Expect(tasks[2].UPID).To(Equal("a"))
+	})
+})
@@ -5,6 +5,7 @@ import (
 
 	"github.com/k8s-proxmox/proxmox-go/api"
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
@@ -43,10 +44,57 @@ type ClusterSettter interface {
 	SetStorage(storage infrav1.Storage)
 }
 
+// ResourcePool is an interface which can get and set the proxmox resource pool of a cluster.
+type ResourcePool interface {
+	ClusterGetter
+	PoolSpec() *infrav1.ResourcePool
+	Pool() string
+	SetPool(name string)
+}
+
+// NodeFailure is an interface which can get and set failures of proxmox nodes of a cluster.
+type NodeFailure interface {
+	ClusterGetter
+	NodeFailurePolicy() *infrav1.NodeFailurePolicy
+	ConfirmedDownNodes() []string
+	OfflineNodes() []infrav1.OfflineNode
+	DownNodes() []string
+	SetOfflineNodes(nodes []infrav1.OfflineNode)
+	SetDownNodes(nodes []string)
+}
+
+// VersionChecker is an interface which can get and set the detected version of proxmox.
+type VersionChecker interface {
+	ClusterGetter
+	ProxmoxVersion() string
+	SetProxmoxVersion(version string)
+	SetVersionSupported()
+	SetVersionUnsupported(message string)
+}
+
+// Quorum is an interface which can get and set whether the proxmox cluster is quorate.
+type Quorum interface {
+	ClusterGetter
+	Quorate() bool
+	SetQuorate()
+	SetQuorumLost(reason, message string)
+}
+
+// ClusterFirewall is an interface which can get the firewall of a cluster and the addresses of its machines.
+type ClusterFirewall interface {
+	ClusterGetter
+	FirewallSpec() *infrav1.ClusterFirewall
+	MachineAddresses(ctx context.Context) ([]string, error)
+}
+
 // MachineGetter is an interface which can get machine information.
 type MachineGetter interface {
 	Client
 	GetScheduler(client *proxmox.Service) *scheduler.Scheduler
+	CordonedNodes() []string
+	ReservedVMIDs() string
+	PinnedNode() string
+	NodeDown() bool
 	Name() string
 	Namespace() string
 	Annotations() map[string]string
@@ -56,31 +104,91 @@ type MachineGetter interface {
 	// ControlPlaneGroupName() string
 	NodeName() string
 	GetBiosUUID() *string
+	GetType() infrav1.InstanceType
 	GetImage() infrav1.Image
+	GetNameTemplate() string
+	FailureDomain() *string
+	GetRestore() *infrav1.Restore
+	GetContainer() *infrav1.Container
 	GetProviderID() string
-	GetBootstrapData() (string, error)
+	GetBootstrapData() (string, string, error)
+	GetCloudInitCredentials() (string, string, error)
 	GetInstanceStatus() *infrav1.InstanceStatus
-	GetClusterStorage() infrav1.Storage
+	IsReady() bool
+	GetSnippetStorage() infrav1.Storage
+	GetPool() string
+	GetLabels() map[string]string
+	GetAnnotations() map[string]string
+	GetTagMappings() []infrav1.TagMapping
+	GetVMTags() infrav1.Tags
+	GetSSHPublicKey() string
 	GetStorage() string
+	WipeDisksOnDelete() bool
 	GetCloudInit() infrav1.CloudInit
 	GetNetwork() infrav1.Network
+	GetProxy() *infrav1.Proxy
+	GetRegistries() []infrav1.Registry
+	GetTaskPolicy() *infrav1.TaskPolicy
+	GetProxmoxVersion() string
+	FailedNodes() []string
+	GetProvisioningTimeouts() infrav1.ProvisioningTimeouts
+	ProvisioningPhase() *infrav1.ProvisioningPhase
+	Bootstrapped() bool
+	ClusterNetwork() (*infrav1.ClusterNetwork, error)
+	NodeGroup() (*infrav1.NodeGroup, error)
 	GetHardware() infrav1.Hardware
 	GetVMID() *int
 	GetOptions() infrav1.Options
+	GetSnapshotPolicy() *infrav1.SnapshotPolicy
+	GetHA() *infrav1.HighAvailability
+	GetReplication() *infrav1.Replication
+	GetReadiness() *infrav1.Readiness
+	GetAddressFilter() *infrav1.AddressFilter
+	GetFirewall() *infrav1.Firewall
+	GetClusterFirewallGroup() string
+	GetServerEndpoint() string
+	GetConfigHash() string
+	GetBootstrapDataHash() string
+	GetSMBiosUUID() string
+	ConfigDrifted() bool
+	GetMachineUID() string
+	ClusterName() string
+	MachineName() string
+	MachineOwner() string
+	MachineDeploymentName() string
+	CreationTimestamp() metav1.Time
+	DeletionStepDone(step clusterv1.ConditionType) bool
 }
 
 // MachineSetter is an interface which can set machine information.
 type MachineSetter interface {
-	SetProviderID(uuid string) error
+	SetProviderID(uuid string, vmid int) error
 	SetInstanceStatus(v infrav1.InstanceStatus)
 	SetNodeName(name string)
 	SetVMID(vmid int)
 	SetConfigStatus(config api.VirtualMachineConfig)
 	SetStorage(name string)
+	SetConsole(console infrav1.Console)
+	SetAddresses(addresses []clusterv1.MachineAddress, interfaces []infrav1.NetworkInterface)
+	SetConfigHash(hash string)
+	SetBootstrapDataHash(hash string)
+	SetSMBiosUUID(uuid string)
+	SetConfigInSync()
+	SetConfigDrifted(message string)
+	SetDeletionStepDone(step clusterv1.ConditionType)
+	SetDeletionStepFailed(step clusterv1.ConditionType, err error)
+	SetOwnershipVerified()
+	SetOwnershipMismatch(message string)
+	AddFailedNode(node string)
+	ClearFailedNodes()
+	SetProvisioningPhase(phase *infrav1.ProvisioningPhase)
+	Eventf(reason, format string, args ...interface{})
+	Warnf(reason, format string, args ...interface{})
+	Notify(ctx context.Context, event infrav1.NotificationEvent, message string)
+	ResolveNotification(event infrav1.NotificationEvent)
 	// SetFailureMessage(v error)
 	// SetFailureReason(v capierrors.MachineStatusError)
 	// SetAnnotation(key, value string)
-	// SetAddresses(addressList []corev1.NodeAddress)
 	PatchObject() error
 }
 
@@ -89,3 +197,51 @@ type Machine interface {
 	MachineGetter
 	MachineSetter
 }
+
+// Snapshot is an interface which can get and set snapshot information.
+type Snapshot interface {
+	Client
+	Name() string
+	Namespace() string
+	MachineName() string
+	UID() string
+	Description() string
+	RollbackRequested() bool
+	GetVMID() *int
+	GetSnapshotName() string
+	SetSnapshotName(name string)
+	GetSnapshotVMID() *int
+	SetVMID(vmid int)
+	GetCreationTime() *metav1.Time
+	SetCreationTime(t metav1.Time)
+	IsRolledBack() bool
+	SetRolledBack(v bool)
+	SetReady(v bool)
+}
+
+// BackupPolicy is an interface which can get and set backup policy information.
+type BackupPolicy interface {
+	Client
+	Name() string
+	Namespace() string
+	UID() string
+	GetSpec() infrav1.ProxmoxBackupPolicySpec
+	VMIDs() []int
+	GetJobID() string
+	SetJobID(id string)
+	SetVMIDs(vmids []int)
+	SetReady(v bool)
+}
+
+// NodeMaintenance is an interface which can get and set node maintenance information.
+type NodeMaintenance interface {
+	Client
+	Name() string
+	Namespace() string
+	GetSpec() infrav1.ProxmoxNodeMaintenanceSpec
+	Machines() []infrav1.ProxmoxMachine
+	CordonedNodes() []string
+	NodeGroup(name string) *infrav1.NodeGroup
+	SetMachines(names []string)
+	SetReady(v bool)
+}
@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+// Migrate migrates the guest to the target node and waits for the task.
+// running qemus are migrated online together with their local disks.
+// containers can not be migrated online, so running ones are restarted on the target
+func Migrate(ctx context.Context, client *proxmox.Service, g guest.Guest, target string) error {
+	request := map[string]interface{}{
+		"target": target,
+	}
+	running := g.Status == api.ProcessStatusRunning
+	switch {
+	case g.Type == guest.TypeLXC && running:
+		request["restart"] = 1
+	case g.Type == guest.TypeQEMU:
+		request["with-local-disks"] = 1
+		if running {
+			request["online"] = 1
+		}
+	}
+	var upid string
+	if err := audit.REST(client).Post(ctx, fmt.Sprintf("/nodes/%s/%s/%d/migrate", g.Node, g.Type, g.VMID), request, &upid); err != nil {
+		return err
+	}
+	return client.EnsureTaskDone(ctx, g.Node, upid)
+}
+
+// SelectTarget returns the online node having the most free memory except the excluded ones
+func SelectTarget(nodes []*api.Node, excluded []string) (string, error) {
+	var target *api.Node
+	for _, n := range nodes {
+		if n.Status != "online" || slices.Contains(excluded, n.Node) {
+			continue
+		}
+		if target == nil || n.MaxMem-n.Mem > target.MaxMem-target.Mem {
+			target = n
+		}
+	}
+	if target == nil {
+		return "", fmt.Errorf("no node is available to migrate vms to")
+	}
+	return target.Node, nil
+}
@@ -0,0 +1,34 @@
+package migration_test
+
+import (
+	"testing"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/migration"
+)
+
+func TestMigration(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Migration Suite")
+}
+
+var _ = Describe("SelectTarget", Label("unit", "migration"), func() {
+	nodes := []*api.Node{
+		{Node: "node1", Status: "online", MaxMem: 64, Mem: 8},
+		{Node: "node2", Status: "online", MaxMem: 64, Mem: 32},
+		{Node: "node3", Status: "offline", MaxMem: 128},
+	}
+
+	It("should select online node having the most free memory", func() {
+		Expect(migration.SelectTarget(nodes, nil)).To(Equal("node1"))
+		Expect(migration.SelectTarget(nodes, []string{"node1"})).To(Equal("node2"))
+	})
+
+	It("should fail without available nodes", func() {
+		_, err := migration.SelectTarget(nodes, []string{"node1", "node2"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,14 @@
+// Package names derives names of proxmox objects from kubernetes objects.
+package names
+
+import "strings"
+
+// FromUID returns the prefix followed by the first 12 hex digits of the uid, e.g. "k8s-5c1e5f6e0bd4".
+// they are short enough for proxmox snapshot names and backup job ids, and unique in practice
+func FromUID(prefix, uid string) string {
+	id := strings.ReplaceAll(uid, "-", "")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return prefix + id
+}
@@ -0,0 +1,25 @@
+package names_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/names"
+)
+
+func TestNames(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Names Suite")
+}
+
+var _ = Describe("FromUID", Label("unit", "names"), func() {
+	It("should append the head of the uid to the prefix", func() {
+		Expect(names.FromUID("k8s-", "5c1e5f6e-0bd4-4d3c-9f1b-0a2f7f0e1a11")).To(Equal("k8s-5c1e5f6e0bd4"))
+	})
+
+	It("should keep short uids", func() {
+		Expect(names.FromUID("k8s-", "abc")).To(Equal("k8s-abc"))
+	})
+})
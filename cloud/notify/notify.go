@@ -0,0 +1,152 @@
+// Package notify posts provisioning problems to the webhooks of clusters, e.g. to chats of platform teams
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+const (
+	// problems are sent again at most once per interval while they persist
+	resendInterval = time.Hour
+	// consecutive scheduling failures of a machine before they are sent
+	schedulingFailureThreshold = 3
+	sendTimeout                = 10 * time.Second
+)
+
+// Default is the notifier of the manager
+var Default = NewNotifier(&http.Client{Timeout: sendTimeout}, time.Now)
+
+// Hook is a notification hook together with the url read from its secret
+type Hook struct {
+	infrav1.NotificationHook
+	URL string
+}
+
+// Wants returns true if the hook is notified of the event
+func (h Hook) Wants(event infrav1.NotificationEvent) bool {
+	return len(h.Events) == 0 || slices.Contains(h.Events, event)
+}
+
+// Notification is a provisioning problem. it is the payload of generic hooks
+type Notification struct {
+	Event     infrav1.NotificationEvent `json:"event"`
+	Namespace string                    `json:"namespace"`
+	Cluster   string                    `json:"cluster"`
+	// Object the problem is about. e.g. ProxmoxMachine/cp-0 or the endpoint of the proxmox api
+	Object  string    `json:"object"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// e.g. "MachineFailed: ProxmoxMachine/cp-0 of cluster default/prod: ..."
+func (n Notification) String() string {
+	return fmt.Sprintf("%s: %s of cluster %s/%s: %s", n.Event, n.Object, n.Namespace, n.Cluster, n.Message)
+}
+
+// identifies the problem for deduplication
+func (n Notification) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", n.Event, n.Namespace, n.Cluster, n.Object)
+}
+
+// Payload returns the body posted to hooks of the format
+func Payload(format infrav1.NotificationFormat, n Notification) ([]byte, error) {
+	if format == infrav1.NotificationFormatSlack {
+		return json.Marshal(map[string]string{"text": n.String()})
+	}
+	return json.Marshal(n)
+}
+
+// Post sends the notification to the hook
+func Post(ctx context.Context, client *http.Client, hook Hook, n Notification) error {
+	body, err := Payload(hook.Format, n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("hook %s responded %s", hook.Name, resp.Status)
+	}
+	return nil
+}
+
+// Notifier deduplicates problems before they are sent
+type Notifier struct {
+	mu sync.Mutex
+	// consecutive occurrences of problems
+	counts map[string]int
+	// when problems were last sent
+	sent   map[string]time.Time
+	client *http.Client
+	// time.Now. replaced in tests
+	now func() time.Time
+}
+
+func NewNotifier(client *http.Client, now func() time.Time) *Notifier {
+	return &Notifier{counts: map[string]int{}, sent: map[string]time.Time{}, client: client, now: now}
+}
+
+// Due records an occurrence of the problem and returns true if it is to be sent.
+// problems are sent once per resend interval, scheduling failures only once they have repeated
+func (n *Notifier) Due(notification Notification) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := notification.key()
+	n.counts[key]++
+	if notification.Event == infrav1.NotificationEventSchedulingFailed && n.counts[key] < schedulingFailureThreshold {
+		return false
+	}
+	if last, ok := n.sent[key]; ok && n.now().Sub(last) < resendInterval {
+		return false
+	}
+	n.sent[key] = n.now()
+	return true
+}
+
+// Resolve forgets the problem, so that its next occurrence is counted and sent anew
+func (n *Notifier) Resolve(event infrav1.NotificationEvent, namespace, cluster, object string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := Notification{Event: event, Namespace: namespace, Cluster: cluster, Object: object}.key()
+	delete(n.counts, key)
+	delete(n.sent, key)
+}
+
+// Send posts the notification to the hooks wanting its event in the background,
+// so that reconciles are not held up by slow hooks. failures are logged
+func (n *Notifier) Send(ctx context.Context, hooks []Hook, notification Notification) {
+	log := log.FromContext(ctx)
+	for _, hook := range hooks {
+		if !hook.Wants(notification.Event) {
+			continue
+		}
+		go func(hook Hook) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := Post(ctx, n.client, hook, notification); err != nil {
+				log.Error(err, "failed to notify hook", "hook", hook.Name, "event", notification.Event)
+				return
+			}
+			log.V(4).Info("notified hook", "hook", hook.Name, "event", notification.Event, "object", notification.Object)
+		}(hook)
+	}
+}
@@ -0,0 +1,115 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/notify"
+)
+
+func TestNotify(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Notify Suite")
+}
+
+var notification = notify.Notification{
+	Event:     infrav1.NotificationEventMachineFailed,
+	Namespace: "default",
+	Cluster:   "prod",
+	Object:    "ProxmoxMachine/cp-0",
+	Message:   "phase Scheduling has timed out",
+	Time:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+var _ = Describe("Payload", Label("unit", "notify"), func() {
+	It("should post the notification as json to generic hooks", func() {
+		b, err := notify.Payload(infrav1.NotificationFormatGeneric, notification)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"event":"MachineFailed","namespace":"default","cluster":"prod","object":"ProxmoxMachine/cp-0","message":"phase Scheduling has timed out","time":"2024-01-01T00:00:00Z"}`))
+	})
+
+	It("should post a message to slack hooks", func() {
+		b, err := notify.Payload(infrav1.NotificationFormatSlack, notification)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"text":"MachineFailed: ProxmoxMachine/cp-0 of cluster default/prod: phase Scheduling has timed out"}`))
+	})
+})
+
+var _ = Describe("Post", Label("unit", "notify"), func() {
+	It("should post the payload to the url of the hook", func() {
+		var body map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			Expect(json.Unmarshal(b, &body)).To(Succeed())
+		}))
+		defer server.Close()
+
+		hook := notify.Hook{NotificationHook: infrav1.NotificationHook{Name: "chat", Format: infrav1.NotificationFormatSlack}, URL: server.URL}
+		Expect(notify.Post(context.TODO(), server.Client(), hook, notification)).To(Succeed())
+		Expect(body).To(HaveKey("text"))
+	})
+
+	It("should fail for error responses", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		hook := notify.Hook{NotificationHook: infrav1.NotificationHook{Name: "chat"}, URL: server.URL}
+		Expect(notify.Post(context.TODO(), server.Client(), hook, notification)).To(MatchError(ContainSubstring("403")))
+	})
+})
+
+var _ = Describe("Hook", Label("unit", "notify"), func() {
+	It("should want all events by default", func() {
+		Expect(notify.Hook{}.Wants(infrav1.NotificationEventEndpointUnavailable)).To(BeTrue())
+		hook := notify.Hook{NotificationHook: infrav1.NotificationHook{Events: []infrav1.NotificationEvent{infrav1.NotificationEventMachineFailed}}}
+		Expect(hook.Wants(infrav1.NotificationEventMachineFailed)).To(BeTrue())
+		Expect(hook.Wants(infrav1.NotificationEventEndpointUnavailable)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Notifier", Label("unit", "notify"), func() {
+	var (
+		now      time.Time
+		notifier *notify.Notifier
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+		notifier = notify.NewNotifier(http.DefaultClient, func() time.Time { return now })
+	})
+
+	It("should send problems once per interval", func() {
+		Expect(notifier.Due(notification)).To(BeTrue())
+		Expect(notifier.Due(notification)).To(BeFalse())
+		now = now.Add(2 * time.Hour)
+		Expect(notifier.Due(notification)).To(BeTrue())
+	})
+
+	It("should send resolved problems anew", func() {
+		Expect(notifier.Due(notification)).To(BeTrue())
+		notifier.Resolve(notification.Event, notification.Namespace, notification.Cluster, notification.Object)
+		Expect(notifier.Due(notification)).To(BeTrue())
+	})
+
+	It("should send scheduling failures once they have repeated", func() {
+		n := notification
+		n.Event = infrav1.NotificationEventSchedulingFailed
+		Expect(notifier.Due(n)).To(BeFalse())
+		Expect(notifier.Due(n)).To(BeFalse())
+		Expect(notifier.Due(n)).To(BeTrue())
+
+		notifier.Resolve(n.Event, n.Namespace, n.Cluster, n.Object)
+		Expect(notifier.Due(n)).To(BeFalse())
+	})
+})
@@ -0,0 +1,141 @@
+package orphan
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+const haResourcesPath = "/cluster/ha/resources"
+
+type haResource struct {
+	SID string `json:"sid"`
+}
+
+// Find returns guests tagged with the cluster but belonging to none of its machines, sorted by vmid.
+// a guest tagged with a machine belongs to it unless the machine has another vmid.
+// guests of a cluster of the same name in another namespace are skipped.
+// guests created by older versions of cappx lack machine tags and belong to the machine having their vmid
+func Find(guests []guest.Guest, machines []infrav1.ProxmoxMachine, namespace, clusterName string) []guest.Guest {
+	owners := map[string]*int{}
+	vmids := map[int]bool{}
+	for _, m := range machines {
+		owners[guest.MachineTag(namespace, m.Name)] = m.Spec.VMID
+		if m.Spec.VMID != nil {
+			vmids[*m.Spec.VMID] = true
+		}
+	}
+	orphans := []guest.Guest{}
+	for _, g := range guests {
+		if !g.HasTag(guest.ManagedTag) || !g.HasTag(guest.ClusterTag(clusterName)) {
+			continue
+		}
+		ns, tagged := g.MachineNamespace()
+		if !tagged {
+			if !vmids[g.VMID] {
+				orphans = append(orphans, g)
+			}
+			continue
+		}
+		if ns != namespace {
+			continue
+		}
+		if !ownedBy(g, owners) {
+			orphans = append(orphans, g)
+		}
+	}
+	slices.SortFunc(orphans, func(a, b guest.Guest) int { return a.VMID - b.VMID })
+	return orphans
+}
+
+func ownedBy(g guest.Guest, owners map[string]*int) bool {
+	for tag, vmid := range owners {
+		if g.HasTag(tag) {
+			// the vmid is set once the guest is created
+			return vmid == nil || *vmid == g.VMID
+		}
+	}
+	return false
+}
+
+// Track returns the orphaned guests in the same order, keeping the time they were first observed orphaned
+func Track(current []infrav1.OrphanedVM, orphans []guest.Guest, now metav1.Time) []infrav1.OrphanedVM {
+	tracked := []infrav1.OrphanedVM{}
+	for _, g := range orphans {
+		since := now
+		for _, c := range current {
+			if c.VMID == g.VMID {
+				since = c.Since
+			}
+		}
+		tracked = append(tracked, infrav1.OrphanedVM{VMID: g.VMID, Name: g.Name, Node: g.Node, Since: since})
+	}
+	return tracked
+}
+
+// Deletable returns true if the guest has been orphaned for the grace period, is tagged with a machine
+// of the namespace and its node is reachable. guests without machine tags are never deleted since
+// they can not be told apart from the ones of a cluster of the same name in another namespace
+func Deletable(g guest.Guest, tracked infrav1.OrphanedVM, namespace string, grace time.Duration, now time.Time) bool {
+	if now.Sub(tracked.Since.Time) < grace || g.Status == guest.StatusUnknown {
+		return false
+	}
+	ns, tagged := g.MachineNamespace()
+	return tagged && ns == namespace
+}
+
+// Delete stops and deletes the guest. it is removed from ha, replication and backup jobs too
+func Delete(ctx context.Context, client *proxmox.Service, g guest.Guest) error {
+	// stop requests of ha-managed guests are handed over to the ha manager.
+	// deregister it so that the guest can be stopped right away
+	if err := deleteHA(ctx, client, g); err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/nodes/%s/%s/%d", g.Node, g.Type, g.VMID)
+	if g.Status == api.ProcessStatusRunning {
+		var upid string
+		if err := audit.REST(client).Post(ctx, path+"/status/stop", nil, &upid); err != nil {
+			return fmt.Errorf("failed to stop %s %d: %w", g.Type, g.VMID, err)
+		}
+		if err := client.EnsureTaskDone(ctx, g.Node, upid); err != nil {
+			return err
+		}
+	}
+	var upid string
+	if err := audit.REST(client).Delete(ctx, path+"?purge=1", nil, &upid); err != nil {
+		return fmt.Errorf("failed to delete %s %d: %w", g.Type, g.VMID, err)
+	}
+	return client.EnsureTaskDone(ctx, g.Node, upid)
+}
+
+func deleteHA(ctx context.Context, client *proxmox.Service, g guest.Guest) error {
+	sid := haSID(g)
+	var resources []haResource
+	if err := client.RESTClient().Get(ctx, haResourcesPath, &resources); err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(resources, func(r haResource) bool { return r.SID == sid }) {
+		return nil
+	}
+	if err := audit.REST(client).Delete(ctx, fmt.Sprintf("%s/%s", haResourcesPath, sid), nil, nil); err != nil {
+		return fmt.Errorf("failed to deregister %s from ha manager: %w", sid, err)
+	}
+	return nil
+}
+
+// e.g. vm:100, ct:101
+func haSID(g guest.Guest) string {
+	if g.Type == guest.TypeLXC {
+		return fmt.Sprintf("ct:%d", g.VMID)
+	}
+	return fmt.Sprintf("vm:%d", g.VMID)
+}
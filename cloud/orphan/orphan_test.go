@@ -0,0 +1,103 @@
+package orphan_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/orphan"
+)
+
+func TestOrphan(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Orphan Suite")
+}
+
+func machine(name string, vmid *int) infrav1.ProxmoxMachine {
+	m := infrav1.ProxmoxMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	m.Spec.VMID = vmid
+	return m
+}
+
+var _ = Describe("Find", Label("unit", "orphan"), func() {
+	machines := []infrav1.ProxmoxMachine{
+		machine("cp-0", ptr.To(100)),
+		machine("md-0", nil),
+	}
+
+	vmids := func(guests []guest.Guest) []int {
+		result := []int{}
+		for _, g := range guests {
+			result = append(result, g.VMID)
+		}
+		return result
+	}
+
+	It("should find guests of the cluster belonging to no machine", func() {
+		guests := []guest.Guest{
+			{VMID: 104, Tags: "cappx;cluster.test;machine.default.deleted"},
+			{VMID: 100, Tags: "cappx;cluster.test;machine.default.cp-0"},
+			{VMID: 101, Tags: "cappx;cluster.test;machine.default.md-0"},
+			{VMID: 102, Tags: "cappx;cluster.test;machine.default.cp-0"},
+			{VMID: 103, Tags: "cappx;cluster.other;machine.default.gone"},
+			{VMID: 105, Tags: "cluster.test;machine.default.gone"},
+		}
+		Expect(vmids(orphan.Find(guests, machines, "default", "test"))).To(Equal([]int{102, 104}))
+	})
+
+	It("should skip guests of the cluster of the same name in another namespace", func() {
+		guests := []guest.Guest{{VMID: 100, Tags: "cappx;cluster.test;machine.other.cp-0"}}
+		Expect(orphan.Find(guests, nil, "default", "test")).To(BeEmpty())
+	})
+
+	It("should match guests without machine tags by vmid", func() {
+		guests := []guest.Guest{
+			{VMID: 100, Tags: "cappx;cluster.test"},
+			{VMID: 101, Tags: "cappx;cluster.test"},
+		}
+		Expect(vmids(orphan.Find(guests, machines, "default", "test"))).To(Equal([]int{101}))
+	})
+})
+
+var _ = Describe("Track", Label("unit", "orphan"), func() {
+	It("should keep the time guests were first observed orphaned", func() {
+		since := metav1.NewTime(time.Now().Add(-time.Hour))
+		now := metav1.Now()
+		current := []infrav1.OrphanedVM{{VMID: 100, Node: "node1", Since: since}, {VMID: 101, Node: "node1", Since: since}}
+		guests := []guest.Guest{{VMID: 100, Name: "a", Node: "node2"}, {VMID: 102, Name: "b", Node: "node1"}}
+		Expect(orphan.Track(current, guests, now)).To(Equal([]infrav1.OrphanedVM{
+			{VMID: 100, Name: "a", Node: "node2", Since: since},
+			{VMID: 102, Name: "b", Node: "node1", Since: now},
+		}))
+	})
+})
+
+var _ = Describe("Deletable", Label("unit", "orphan"), func() {
+	now := time.Now()
+	tracked := infrav1.OrphanedVM{VMID: 100, Since: metav1.NewTime(now.Add(-time.Hour))}
+	g := guest.Guest{VMID: 100, Status: api.ProcessStatusRunning, Tags: "cappx;cluster.test;machine.default.gone"}
+
+	It("should delete guests orphaned for the grace period", func() {
+		Expect(orphan.Deletable(g, tracked, "default", 10*time.Minute, now)).To(BeTrue())
+		Expect(orphan.Deletable(g, tracked, "default", 2*time.Hour, now)).To(BeFalse())
+	})
+
+	It("should not delete guests on unreachable nodes", func() {
+		unknown := g
+		unknown.Status = guest.StatusUnknown
+		Expect(orphan.Deletable(unknown, tracked, "default", 10*time.Minute, now)).To(BeFalse())
+	})
+
+	It("should not delete guests without machine tags", func() {
+		legacy := g
+		legacy.Tags = "cappx;cluster.test"
+		Expect(orphan.Deletable(legacy, tracked, "default", 10*time.Minute, now)).To(BeFalse())
+	})
+})
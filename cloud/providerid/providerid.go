@@ -2,6 +2,9 @@ package providerid
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -9,15 +12,31 @@ import (
 const (
 	Prefix     = "proxmox://"
 	UUIDFormat = `[a-f\d]{8}-[a-f\d]{4}-[a-f\d]{4}-[a-f\d]{4}-[a-f\d]{12}`
+	// RegionFormat is the name of the proxmox cluster in the config of the cloud-controller-manager
+	RegionFormat = `[a-zA-Z\d]([-a-zA-Z\d_.]*[a-zA-Z\d])?`
+)
+
+var (
+	// proxmox://<uuid>, set by cappx, capmox and the proxmox-cloud-controller-manager with the capmox provider
+	uuidPattern = regexp.MustCompile(`^(?i)` + UUIDFormat + `$`)
+	// proxmox://<region>/<vmid>, set by the proxmox-cloud-controller-manager by default
+	regionVMIDPattern = regexp.MustCompile(`^(` + RegionFormat + `)/(\d+)$`)
 )
 
 type ProviderID interface {
+	// UUID is the smbios uuid of a qemu, or the uid of the Machine of a container.
+	// empty for ids of the region/vmid format
 	UUID() string
+	// Region and VMID are set for ids of the region/vmid format only
+	Region() string
+	VMID() int
 	fmt.Stringer
 }
 
 type providerID struct {
-	uuid string
+	uuid   string
+	region string
+	vmid   int
 }
 
 func New(uuid string) (ProviderID, error) {
@@ -32,11 +51,68 @@ func New(uuid string) (ProviderID, error) {
 	}, nil
 }
 
+// NewRegionVMID returns the provider id proxmox://<region>/<vmid> expected by the
+// proxmox-cloud-controller-manager, whose config names the proxmox cluster region
+func NewRegionVMID(region string, vmid int) (ProviderID, error) {
+	id := &providerID{region: region, vmid: vmid}
+	if _, err := Parse(id.String()); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// Parse parses the provider id of a proxmox guest set by cappx or by a proxmox cloud-controller-manager.
+// either proxmox://<uuid> or proxmox://<region>/<vmid>
+func Parse(id string) (ProviderID, error) {
+	rest, ok := strings.CutPrefix(id, Prefix)
+	if !ok {
+		return nil, errors.Errorf("provider id %q does not start with %s", id, Prefix)
+	}
+	if uuidPattern.MatchString(rest) {
+		return &providerID{uuid: strings.ToLower(rest)}, nil
+	}
+	if match := regionVMIDPattern.FindStringSubmatch(rest); match != nil {
+		vmid, err := strconv.Atoi(match[3])
+		if err != nil || vmid < 100 {
+			return nil, errors.Errorf("provider id %q has an invalid vmid", id)
+		}
+		return &providerID{region: match[1], vmid: vmid}, nil
+	}
+	return nil, errors.Errorf("provider id %q is neither %s<uuid> nor %s<region>/<vmid>", id, Prefix, Prefix)
+}
+
 func (p *providerID) UUID() string {
 	return p.uuid
 }
 
+func (p *providerID) Region() string {
+	return p.region
+}
+
+func (p *providerID) VMID() int {
+	return p.vmid
+}
+
 func (p *providerID) String() string {
+	if p.uuid == "" {
+		// provider ID : proxmox://<region>/<vmid>
+		return fmt.Sprintf("%s%s/%d", Prefix, p.region, p.vmid)
+	}
 	// provider ID : proxmox://<bios-uuid>
 	return Prefix + p.uuid
 }
+
+// Matches returns true if both ids refer to the same guest. ids of different formats can
+// not be compared, so they match if vmid of the region/vmid id is the one of the guest
+func Matches(a, b ProviderID, vmid int) bool {
+	switch {
+	case a.UUID() != "" && b.UUID() != "":
+		return strings.EqualFold(a.UUID(), b.UUID())
+	case a.UUID() == "" && b.UUID() == "":
+		return a.Region() == b.Region() && a.VMID() == b.VMID()
+	case a.UUID() == "":
+		return a.VMID() == vmid
+	default:
+		return b.VMID() == vmid
+	}
+}
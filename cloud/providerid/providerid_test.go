@@ -66,3 +66,57 @@ var _ = Describe("String method", Label("unit", "providerid"), func() {
 		})
 	})
 })
+
+var _ = Describe("Parse", Label("unit", "providerid"), func() {
+	It("should parse uuid provider ids", func() {
+		pid, err := providerid.Parse("proxmox://0AA2B8C8-1111-2222-3333-444455556666")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pid.UUID()).To(Equal("0aa2b8c8-1111-2222-3333-444455556666"))
+		Expect(pid.VMID()).To(BeZero())
+	})
+
+	It("should parse region/vmid provider ids", func() {
+		pid, err := providerid.Parse("proxmox://cluster-1/105")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pid.UUID()).To(BeEmpty())
+		Expect(pid.Region()).To(Equal("cluster-1"))
+		Expect(pid.VMID()).To(Equal(105))
+		Expect(pid.String()).To(Equal("proxmox://cluster-1/105"))
+	})
+
+	It("should reject provider ids proxmox cloud-controller-managers do not set", func() {
+		for _, id := range []string{"", "asdf", "proxmox://", "proxmox://asdf", "proxmox:///0aa2b8c8-1111-2222-3333-444455556666", "proxmox://cluster-1/99", "proxmox://cluster-1/node/105", "aws://0aa2b8c8-1111-2222-3333-444455556666"} {
+			_, err := providerid.Parse(id)
+			Expect(err).To(HaveOccurred(), id)
+		}
+	})
+})
+
+var _ = Describe("NewRegionVMID", Label("unit", "providerid"), func() {
+	It("should validate the region", func() {
+		pid, err := providerid.NewRegionVMID("cluster-1", 105)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pid.String()).To(Equal("proxmox://cluster-1/105"))
+
+		_, err = providerid.NewRegionVMID("cluster/1", 105)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Matches", Label("unit", "providerid"), func() {
+	uuid, _ := providerid.Parse("proxmox://0aa2b8c8-1111-2222-3333-444455556666")
+	other, _ := providerid.Parse("proxmox://0aa2b8c8-1111-2222-3333-777777777777")
+	regionVMID, _ := providerid.Parse("proxmox://cluster-1/105")
+
+	It("should compare ids of the same format", func() {
+		Expect(providerid.Matches(uuid, uuid, 105)).To(BeTrue())
+		Expect(providerid.Matches(uuid, other, 105)).To(BeFalse())
+		Expect(providerid.Matches(regionVMID, regionVMID, 0)).To(BeTrue())
+	})
+
+	It("should compare ids of different formats by vmid", func() {
+		Expect(providerid.Matches(uuid, regionVMID, 105)).To(BeTrue())
+		Expect(providerid.Matches(regionVMID, uuid, 105)).To(BeTrue())
+		Expect(providerid.Matches(uuid, regionVMID, 106)).To(BeFalse())
+	})
+})
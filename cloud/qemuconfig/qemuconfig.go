@@ -0,0 +1,228 @@
+// Package qemuconfig renders qemu configs the way Proxmox reports them, so that configs differing
+// only in the order of values, values left at their defaults or the units of sizes compare equal.
+package qemuconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+)
+
+// devices proxmox enables hotplug of unless the hotplug option is set
+var defaultHotPlug = []string{"disk", "network", "usb"}
+
+var (
+	diskKey  = regexp.MustCompile(`^(ide|sata|scsi|virtio|efidisk|tpmstate|unused)\d+$`)
+	netKey   = regexp.MustCompile(`^net\d+$`)
+	tagSep   = regexp.MustCompile(`[;, ]+`)
+	sizeExpr = regexp.MustCompile(`^(\d+)([KMGT]?)$`)
+)
+
+// keys of property strings whose value may be given without the key, e.g. cpu: host
+var defaultKeys = map[string]string{
+	"agent": "enabled",
+	"cpu":   "cputype",
+	"rng0":  "source",
+	"vga":   "type",
+}
+
+// values of properties which proxmox treats the same as leaving them unset
+var (
+	diskDefaults = map[string]string{
+		"backup":    "1",
+		"discard":   "ignore",
+		"iothread":  "0",
+		"replicate": "1",
+		"ro":        "0",
+		"shared":    "0",
+		"ssd":       "0",
+	}
+	netDefaults = map[string]string{
+		"firewall":  "0",
+		"link_down": "0",
+	}
+	agentDefaults = map[string]string{
+		"freeze-fs-on-backup": "1",
+		"fstrim_cloned_disks": "0",
+		"type":                "virtio",
+	}
+)
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// Normalize returns the config with its values rendered the way Proxmox reports them
+func Normalize(config api.VirtualMachineConfig) (api.VirtualMachineConfig, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return config, err
+	}
+	values := map[string]any{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return config, err
+	}
+	for key, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if normalized := Value(key, s); normalized != "" {
+			values[key] = normalized
+		} else {
+			delete(values, key)
+		}
+	}
+	b, err = json.Marshal(values)
+	if err != nil {
+		return config, err
+	}
+	result := api.VirtualMachineConfig{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return config, err
+	}
+	result.Node = config.Node
+	return result, nil
+}
+
+// Value returns the value of the config option rendered the way Proxmox reports it.
+// values of options without known format are returned as they are
+func Value(key, value string) string {
+	switch {
+	case key == "tags":
+		return Tags(value)
+	case key == "hotplug":
+		return HotPlug(value)
+	case diskKey.MatchString(key):
+		return propertyString(value, "file", false, diskDefaults)
+	case netKey.MatchString(key):
+		// the model is the key of the mac address, e.g. virtio=BC:24:11:00:00:01
+		return propertyString(value, "", true, netDefaults)
+	case key == "agent":
+		return propertyString(value, defaultKeys[key], false, agentDefaults)
+	case defaultKeys[key] != "":
+		return propertyString(value, defaultKeys[key], false, nil)
+	case key == "smbios1":
+		return propertyString(value, "", false, nil)
+	}
+	return value
+}
+
+// Tags returns the tags sorted and deduplicated, separated by ";"
+func Tags(value string) string {
+	tags := []string{}
+	for _, tag := range tagSep.Split(value, -1) {
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+	return strings.Join(tags, ";")
+}
+
+// HotPlug returns the hotplug devices sorted, or empty for the devices proxmox enables by default
+func HotPlug(value string) string {
+	switch value {
+	case "", "1":
+		return ""
+	case "0":
+		return "0"
+	}
+	devices := []string{}
+	for _, d := range strings.Split(value, ",") {
+		if d != "" && !slices.Contains(devices, d) {
+			devices = append(devices, d)
+		}
+	}
+	slices.Sort(devices)
+	if slices.Equal(devices, defaultHotPlug) {
+		return ""
+	}
+	return strings.Join(devices, ",")
+}
+
+// Size returns the size in the largest unit it is a whole multiple of, e.g. 10G for 10240M.
+// sizes without unit are bytes. values which are no sizes are returned as they are
+func Size(value string) string {
+	match := sizeExpr.FindStringSubmatch(value)
+	if match == nil {
+		return value
+	}
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return value
+	}
+	for _, unit := range sizeUnits {
+		if unit.suffix == match[2] {
+			n *= unit.bytes
+		}
+	}
+	if n == 0 {
+		return "0"
+	}
+	for _, unit := range sizeUnits {
+		if n%unit.bytes == 0 {
+			return fmt.Sprintf("%d%s", n/unit.bytes, unit.suffix)
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// proxmox prints the value of the default key first and the other properties sorted by key.
+// keepFirst keeps the first property first, like the model of network devices
+func propertyString(value, defaultKey string, keepFirst bool, defaults map[string]string) string {
+	if value == "" {
+		return ""
+	}
+	head := ""
+	props := map[string]string{}
+	for i, item := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(item, "=")
+		switch {
+		case i == 0 && (keepFirst || !ok):
+			head = item
+			continue
+		case !ok:
+			// not a property, kept as it is
+			k, v = item, ""
+		case defaultKey != "" && k == defaultKey:
+			head = v
+			continue
+		}
+		if k == "size" {
+			v = Size(v)
+		}
+		if d, ok := defaults[k]; ok && d == v {
+			continue
+		}
+		props[k] = v
+	}
+	keys := []string{}
+	for k := range props {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	items := []string{}
+	if head != "" {
+		items = append(items, head)
+	}
+	for _, k := range keys {
+		if props[k] == "" {
+			items = append(items, k)
+			continue
+		}
+		items = append(items, k+"="+props[k])
+	}
+	return strings.Join(items, ",")
+}
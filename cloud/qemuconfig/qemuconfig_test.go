@@ -0,0 +1,77 @@
+package qemuconfig_test
+
+import (
+	"testing"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/qemuconfig"
+)
+
+func TestQEMUConfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "QEMU Config Suite")
+}
+
+var _ = Describe("Value", Label("unit", "qemuconfig"), func() {
+	DescribeTable("should render values the way proxmox reports them",
+		func(key, value, expected string) {
+			Expect(qemuconfig.Value(key, value)).To(Equal(expected))
+		},
+		Entry("tags", "tags", "k8s,cappx;cappx cluster.prod", "cappx;cluster.prod;k8s"),
+		Entry("default hotplug", "hotplug", "usb,network,disk", ""),
+		Entry("hotplug", "hotplug", "network,memory,disk,cpu,usb", "cpu,disk,memory,network,usb"),
+		Entry("disabled hotplug", "hotplug", "0", "0"),
+		Entry("disk", "scsi0", "file=local-lvm:vm-100-disk-0,ssd=1,size=10240M,iothread=0,discard=on", "local-lvm:vm-100-disk-0,discard=on,size=10G,ssd=1"),
+		Entry("disk without key", "virtio1", "local-lvm:vm-100-disk-1,backup=1,size=512M", "local-lvm:vm-100-disk-1,size=512M"),
+		Entry("network", "net0", "virtio=BC:24:11:00:00:01,firewall=0,tag=10,bridge=vmbr0", "virtio=BC:24:11:00:00:01,bridge=vmbr0,tag=10"),
+		Entry("cpu", "cpu", "flags=+aes,cputype=host", "host,flags=+aes"),
+		Entry("agent", "agent", "enabled=1,fstrim_cloned_disks=0,type=virtio", "1"),
+		Entry("smbios", "smbios1", "uuid=0d9e6a86-2c1a-4b8e-9f57-6b1c0a3b2f11,base64=1,serial=abc", "base64=1,serial=abc,uuid=0d9e6a86-2c1a-4b8e-9f57-6b1c0a3b2f11"),
+		Entry("unknown", "description", "b,a", "b,a"),
+	)
+})
+
+var _ = Describe("Size", Label("unit", "qemuconfig"), func() {
+	It("should use the largest whole unit", func() {
+		Expect(qemuconfig.Size("10240M")).To(Equal("10G"))
+		Expect(qemuconfig.Size("1536M")).To(Equal("1536M"))
+		Expect(qemuconfig.Size("1099511627776")).To(Equal("1T"))
+		Expect(qemuconfig.Size("100")).To(Equal("100"))
+		Expect(qemuconfig.Size("0G")).To(Equal("0"))
+	})
+
+	It("should keep values which are no sizes", func() {
+		Expect(qemuconfig.Size("4.5G")).To(Equal("4.5G"))
+	})
+})
+
+var _ = Describe("Normalize", Label("unit", "qemuconfig"), func() {
+	It("should make equivalent configs equal", func() {
+		applied := api.VirtualMachineConfig{Name: "cappx-test", Cores: 2, Memory: 4096, Tags: "k8s;cappx", HotPlug: "network,disk,usb"}
+		applied.Scsi.Scsi0 = "local-lvm:vm-100-disk-0,size=32768M,iothread=0"
+		applied.Net.Net0 = "virtio=BC:24:11:00:00:01,firewall=0,bridge=vmbr0"
+
+		reported := api.VirtualMachineConfig{Name: "cappx-test", Cores: 2, Memory: 4096, Tags: "cappx;k8s"}
+		reported.Scsi.Scsi0 = "local-lvm:vm-100-disk-0,size=32G"
+		reported.Net.Net0 = "virtio=BC:24:11:00:00:01,bridge=vmbr0"
+
+		a, err := qemuconfig.Normalize(applied)
+		Expect(err).NotTo(HaveOccurred())
+		b, err := qemuconfig.Normalize(reported)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).To(Equal(b))
+		Expect(a.HotPlug).To(BeEmpty())
+		Expect(a.Scsi.Scsi0).To(Equal("local-lvm:vm-100-disk-0,size=32G"))
+	})
+
+	It("should keep real differences", func() {
+		a, err := qemuconfig.Normalize(api.VirtualMachineConfig{Cores: 2, HotPlug: "network,disk,usb,memory"})
+		Expect(err).NotTo(HaveOccurred())
+		b, err := qemuconfig.Normalize(api.VirtualMachineConfig{Cores: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).NotTo(Equal(b))
+	})
+})
@@ -0,0 +1,131 @@
+package rebalance
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// Node is a proxmox node vms can be moved between
+type Node struct {
+	Name string
+	// memory in bytes
+	Used  int64
+	Total int64
+	// vms are neither moved from nor to a cordoned node. e.g. under maintenance
+	Cordoned bool
+}
+
+// VM is a vm managed by cappx
+type VM struct {
+	Name   string
+	VMID   int
+	Node   string
+	Memory int64
+	// vms of the same group are never moved onto the same node. e.g. control plane
+	Group string
+	// pinned vms are never moved, but still count for the group
+	Pinned bool
+	// nodes the vm may be moved to, e.g. the nodes of its node group. any node if nil
+	Nodes []string
+	// vms of lower priority are moved first
+	Priority int
+}
+
+// Move is a planned move of the vm to the target node
+type Move struct {
+	VM     VM
+	Target string
+}
+
+func usage(n *Node) float64 {
+	if n.Total == 0 {
+		return 0
+	}
+	return float64(n.Used) * 100 / float64(n.Total)
+}
+
+// Skew returns the difference of memory usage in percent between the most and the least loaded nodes
+func Skew(nodes []Node) float64 {
+	schedulable := []*Node{}
+	for i := range nodes {
+		if !nodes[i].Cordoned {
+			schedulable = append(schedulable, &nodes[i])
+		}
+	}
+	if len(schedulable) < 2 {
+		return 0
+	}
+	sortByUsage(schedulable)
+	return usage(schedulable[len(schedulable)-1]) - usage(schedulable[0])
+}
+
+// Plan returns at most budget moves evening out memory usage of the nodes
+// while the skew exceeds threshold (percent).
+// each move is the one reducing the gap between its source and target nodes the most
+func Plan(nodes []Node, vms []VM, threshold float64, budget int) []Move {
+	state := map[string]*Node{}
+	schedulable := []*Node{}
+	for _, n := range nodes {
+		n := n
+		state[n.Name] = &n
+		if !n.Cordoned {
+			schedulable = append(schedulable, &n)
+		}
+	}
+	vms = append([]VM{}, vms...)
+
+	moves := []Move{}
+	for len(moves) < budget && len(schedulable) > 1 {
+		sortByUsage(schedulable)
+		source := schedulable[len(schedulable)-1]
+		if usage(source)-usage(schedulable[0]) <= threshold {
+			break
+		}
+		best, target, gain := -1, "", 0.0
+		for i, vm := range vms {
+			if vm.Node != source.Name || vm.Pinned {
+				continue
+			}
+			for _, dst := range schedulable[:len(schedulable)-1] {
+				if conflicts(vms, vm, dst.Name) || (vm.Nodes != nil && !slices.Contains(vm.Nodes, dst.Name)) {
+					continue
+				}
+				before := usage(source) - usage(dst)
+				after := math.Abs(float64(source.Used-vm.Memory)*100/float64(source.Total) - float64(dst.Used+vm.Memory)*100/float64(dst.Total))
+				if before-after <= 0 {
+					continue
+				}
+				if best < 0 || vm.Priority < vms[best].Priority || (vm.Priority == vms[best].Priority && before-after > gain) {
+					best, target, gain = i, dst.Name, before-after
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+		vm := vms[best]
+		moves = append(moves, Move{VM: vm, Target: target})
+		source.Used -= vm.Memory
+		state[target].Used += vm.Memory
+		vms[best].Node = target
+	}
+	return moves
+}
+
+// returns true if another vm of the same group is on the node
+func conflicts(vms []VM, vm VM, node string) bool {
+	if vm.Group == "" {
+		return false
+	}
+	for _, v := range vms {
+		if v.VMID != vm.VMID && v.Group == vm.Group && v.Node == node {
+			return true
+		}
+	}
+	return false
+}
+
+func sortByUsage(nodes []*Node) {
+	sort.SliceStable(nodes, func(i, j int) bool { return usage(nodes[i]) < usage(nodes[j]) })
+}
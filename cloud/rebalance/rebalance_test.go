@@ -0,0 +1,84 @@
+package rebalance_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/rebalance"
+)
+
+func TestRebalance(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rebalance Suite")
+}
+
+var _ = Describe("Plan", Label("unit", "rebalance"), func() {
+	var nodes []rebalance.Node
+	var vms []rebalance.VM
+
+	BeforeEach(func() {
+		nodes = []rebalance.Node{
+			{Name: "node1", Used: 80, Total: 100},
+			{Name: "node2", Used: 20, Total: 100},
+		}
+		vms = []rebalance.VM{
+			{Name: "small", VMID: 100, Node: "node1", Memory: 10},
+			{Name: "large", VMID: 101, Node: "node1", Memory: 30},
+		}
+	})
+
+	It("should move the vm evening out the nodes", func() {
+		Expect(rebalance.Skew(nodes)).To(BeNumerically("==", 60))
+		moves := rebalance.Plan(nodes, vms, 20, 2)
+		Expect(moves).To(HaveLen(1))
+		Expect(moves[0].VM.Name).To(Equal("large"))
+		Expect(moves[0].Target).To(Equal("node2"))
+	})
+
+	It("should do nothing within threshold", func() {
+		Expect(rebalance.Plan(nodes, vms, 60, 1)).To(BeEmpty())
+	})
+
+	It("should respect the budget", func() {
+		Expect(rebalance.Plan(nodes, vms, 0, 0)).To(BeEmpty())
+	})
+
+	It("should not move a vm onto a node having a vm of the same group", func() {
+		vms[1].Group = "controlplane"
+		vms = append(vms, rebalance.VM{Name: "cp", VMID: 102, Node: "node2", Memory: 10, Group: "controlplane"})
+		moves := rebalance.Plan(nodes, vms, 20, 1)
+		Expect(moves).To(HaveLen(1))
+		Expect(moves[0].VM.Name).To(Equal("small"))
+	})
+
+	It("should not move pinned vms", func() {
+		vms[1].Pinned = true
+		moves := rebalance.Plan(nodes, vms, 20, 1)
+		Expect(moves).To(HaveLen(1))
+		Expect(moves[0].VM.Name).To(Equal("small"))
+	})
+
+	It("should move vms only to the nodes allowed for them", func() {
+		nodes = append(nodes, rebalance.Node{Name: "node3", Used: 30, Total: 100})
+		vms[1].Nodes = []string{"node1", "node3"}
+		moves := rebalance.Plan(nodes, vms, 20, 1)
+		Expect(moves).To(HaveLen(1))
+		Expect(moves[0].VM.Name).To(Equal("large"))
+		Expect(moves[0].Target).To(Equal("node3"))
+	})
+
+	It("should move vms of lower priority first", func() {
+		vms[0].Priority = 0
+		vms[1].Priority = 1
+		moves := rebalance.Plan(nodes, vms, 20, 1)
+		Expect(moves).To(HaveLen(1))
+		Expect(moves[0].VM.Name).To(Equal("small"))
+	})
+
+	It("should not move vms to cordoned nodes", func() {
+		nodes[1].Cordoned = true
+		Expect(rebalance.Plan(nodes, vms, 20, 1)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// consecutive failures tripping the breaker
+	breakerThreshold = 5
+	// how long reconciles are paused once the breaker has tripped. doubled on every trip up to the max
+	breakerOpenDuration    = 30 * time.Second
+	breakerMaxOpenDuration = 5 * time.Minute
+)
+
+// breakers per endpoint
+var breakers sync.Map
+
+// Breaker pauses reconciles against an endpoint which has been unavailable for calls in a row.
+// After the pause one reconcile is let through. its success closes the breaker, its failure
+// trips it again for longer.
+type Breaker struct {
+	mu       sync.Mutex
+	failures int
+	trips    int
+	openedAt time.Time
+	// time.Now. replaced in tests
+	now func() time.Time
+}
+
+// BreakerFor returns the breaker of the endpoint
+func BreakerFor(endpoint string) *Breaker {
+	b, _ := breakers.LoadOrStore(endpoint, NewBreaker(time.Now))
+	return b.(*Breaker)
+}
+
+func NewBreaker(now func() time.Time) *Breaker {
+	return &Breaker{now: now}
+}
+
+// Allow returns 0 if calls may be made, otherwise how long the endpoint is still paused
+func (b *Breaker) Allow() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.trips == 0 {
+		return 0
+	}
+	if remaining := b.openedAt.Add(b.openDuration()).Sub(b.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Done records the result of calls to the endpoint. only errors of the endpoint being unavailable
+// count as failures, since other errors prove that the endpoint is up
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !IsUnavailable(err) {
+		b.failures = 0
+		b.trips = 0
+		return
+	}
+	b.failures++
+	// half-open breakers trip again on the first failure
+	if b.failures >= breakerThreshold || b.trips > 0 {
+		b.trips++
+		b.failures = 0
+		b.openedAt = b.now()
+	}
+}
+
+func (b *Breaker) openDuration() time.Duration {
+	d := breakerOpenDuration
+	for i := 1; i < b.trips && d < breakerMaxOpenDuration; i++ {
+		d *= 2
+	}
+	return min(d, breakerMaxOpenDuration)
+}
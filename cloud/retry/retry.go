@@ -0,0 +1,97 @@
+// Package retry tells client errors of the Proxmox API from server errors, retries the latter
+// with jittered exponential backoff and trips circuit breakers of endpoints which are clearly down.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// backoff of calls retried within a reconcile. var for testing
+var DefaultBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.5,
+	Steps:    4,
+	Cap:      10 * time.Second,
+}
+
+// StatusCode returns the http status code of the error of the Proxmox API
+func StatusCode(err error) (int, bool) {
+	var restErr *rest.Error
+	if !errors.As(err, &restErr) {
+		return 0, false
+	}
+	// the code is only exposed in the message. e.g. "500 - Internal Server Error - ..."
+	code, _, _ := strings.Cut(restErr.Error(), " ")
+	c, err := strconv.Atoi(code)
+	return c, err == nil
+}
+
+// IsClientError returns true if the API has rejected the request. retrying it as it is would fail again
+func IsClientError(err error) bool {
+	code, ok := StatusCode(err)
+	return ok && code >= 400 && code < 500
+}
+
+// IsServerError returns true if the API or the connection to it has failed, including
+// 595/596 of the API failing to proxy the request to another node. it may succeed later
+func IsServerError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code, ok := StatusCode(err); ok {
+		return code >= 500
+	}
+	return isConnectionError(err)
+}
+
+// IsUnavailable returns true if the endpoint itself can not serve requests. unlike the other
+// server errors, e.g. 500 of a locked vm, they are not specific to the request
+func IsUnavailable(err error) bool {
+	if code, ok := StatusCode(err); ok {
+		return code == 502 || code == 503 || code == 504
+	}
+	return err != nil && isConnectionError(err)
+}
+
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// OnServerError calls f until it succeeds, fails with an error other than a server error
+// or the backoff is exhausted. the last error is returned
+func OnServerError(ctx context.Context, f func() error) error {
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, DefaultBackoff, func(context.Context) (bool, error) {
+		lastErr = f()
+		if lastErr == nil {
+			return true, nil
+		}
+		if !IsServerError(lastErr) {
+			return false, lastErr
+		}
+		return false, nil
+	})
+	if lastErr != nil {
+		return lastErr
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/retry"
+)
+
+func TestRetry(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Retry Suite")
+}
+
+var _ = Describe("errors", Label("unit", "retry"), func() {
+	It("should tell client errors from server errors", func() {
+		badRequest := fmt.Errorf("failed to create qemu: %w", rest.NewError(http.StatusBadRequest, "Parameter verification failed.", nil))
+		Expect(retry.IsClientError(badRequest)).To(BeTrue())
+		Expect(retry.IsServerError(badRequest)).To(BeFalse())
+
+		locked := rest.NewError(http.StatusInternalServerError, "VM 100 is locked (create)", nil)
+		Expect(retry.IsServerError(locked)).To(BeTrue())
+		Expect(retry.IsUnavailable(locked)).To(BeFalse())
+
+		proxy := rest.NewError(595, "Connection refused", nil)
+		Expect(retry.IsServerError(proxy)).To(BeTrue())
+		Expect(retry.IsUnavailable(proxy)).To(BeFalse())
+
+		unavailable := rest.NewError(http.StatusServiceUnavailable, "Service Unavailable", nil)
+		Expect(retry.IsUnavailable(unavailable)).To(BeTrue())
+	})
+
+	It("should treat connection failures as unavailability", func() {
+		refused := fmt.Errorf("dial tcp 10.0.0.1:8006: %w", syscall.ECONNREFUSED)
+		Expect(retry.IsClientError(refused)).To(BeFalse())
+		Expect(retry.IsServerError(refused)).To(BeTrue())
+		Expect(retry.IsUnavailable(refused)).To(BeTrue())
+		Expect(retry.IsServerError(errors.New("qemu already exists"))).To(BeFalse())
+		Expect(retry.IsUnavailable(nil)).To(BeFalse())
+	})
+})
+
+var _ = Describe("OnServerError", Label("unit", "retry"), func() {
+	BeforeEach(func() {
+		backoff := retry.DefaultBackoff
+		retry.DefaultBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+		DeferCleanup(func() { retry.DefaultBackoff = backoff })
+	})
+
+	It("should retry server errors until success", func() {
+		calls := 0
+		err := retry.OnServerError(context.Background(), func() error {
+			calls++
+			if calls < 3 {
+				return rest.NewError(http.StatusBadGateway, "Bad Gateway", nil)
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(3))
+	})
+
+	It("should not retry client errors", func() {
+		calls := 0
+		err := retry.OnServerError(context.Background(), func() error {
+			calls++
+			return rest.NotFoundErr
+		})
+		Expect(rest.IsNotFound(err)).To(BeTrue())
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should return the last error once the backoff is exhausted", func() {
+		calls := 0
+		err := retry.OnServerError(context.Background(), func() error {
+			calls++
+			return rest.NewError(http.StatusBadGateway, "Bad Gateway", nil)
+		})
+		Expect(retry.IsServerError(err)).To(BeTrue())
+		Expect(calls).To(Equal(3))
+	})
+})
+
+var _ = Describe("Breaker", Label("unit", "retry"), func() {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	unavailable := rest.NewError(http.StatusServiceUnavailable, "Service Unavailable", nil)
+
+	It("should trip after consecutive failures and close on success", func() {
+		breaker := retry.NewBreaker(clock)
+		for i := 0; i < 4; i++ {
+			breaker.Done(unavailable)
+		}
+		Expect(breaker.Allow()).To(BeZero())
+		breaker.Done(unavailable)
+		Expect(breaker.Allow()).To(Equal(30 * time.Second))
+
+		// half-open after the pause. a failure trips it again for longer
+		now = now.Add(30 * time.Second)
+		Expect(breaker.Allow()).To(BeZero())
+		breaker.Done(unavailable)
+		Expect(breaker.Allow()).To(Equal(time.Minute))
+
+		now = now.Add(time.Minute)
+		breaker.Done(nil)
+		Expect(breaker.Allow()).To(BeZero())
+		breaker.Done(unavailable)
+		Expect(breaker.Allow()).To(BeZero())
+	})
+
+	It("should not count errors proving the endpoint is up", func() {
+		breaker := retry.NewBreaker(clock)
+		for i := 0; i < 10; i++ {
+			breaker.Done(rest.NewError(http.StatusInternalServerError, "VM 100 is locked (create)", nil))
+		}
+		Expect(breaker.Allow()).To(BeZero())
+	})
+})
@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+)
+
+func SortedScores(scoreList map[string]framework.NodeScore, selected string) []framework.NodeScore {
+	return sortedScores(scoreList, selected)
+}
+
+func SelectPinnedNode(nodes []*api.Node, pinned string) (string, error) {
+	return selectPinnedNode(nodes, pinned)
+}
+
+func RecordScores(schedulerID string, scoresMap map[string]map[string]framework.NodeScore) {
+	recordScores(schedulerID, scoresMap)
+}
+
+func NodeScore() *prometheus.GaugeVec {
+	return nodeScore
+}
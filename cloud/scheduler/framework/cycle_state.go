@@ -4,17 +4,21 @@ type CycleState struct {
 	completed bool
 	err       error
 	messages  map[string]string
-	result    SchedulerResult
+	// number of nodes rejected per filter plugin
+	rejections map[string]int
+	result     SchedulerResult
 }
 
 type SchedulerResult struct {
 	vmid    int
 	node    string
 	storage string
+	// scores of the feasible nodes in descending order
+	scores []NodeScore
 }
 
 func NewCycleState() CycleState {
-	return CycleState{completed: false, err: nil, messages: map[string]string{}}
+	return CycleState{completed: false, err: nil, messages: map[string]string{}, rejections: map[string]int{}}
 }
 
 func (c *CycleState) SetComplete() {
@@ -41,6 +45,18 @@ func (c *CycleState) Messages() map[string]string {
 	return c.messages
 }
 
+// Reject counts a node rejected by the filter plugin
+func (c *CycleState) Reject(pluginName string) {
+	if c.rejections == nil {
+		c.rejections = map[string]int{}
+	}
+	c.rejections[pluginName]++
+}
+
+func (c *CycleState) Rejections() map[string]int {
+	return c.rejections
+}
+
 func (c *CycleState) UpdateState(completed bool, err error, result SchedulerResult) {
 	c.completed = completed
 	c.err = err
@@ -66,3 +82,11 @@ func (r *SchedulerResult) VMID() int {
 func (r *SchedulerResult) Storage() string {
 	return r.storage
 }
+
+func (r *SchedulerResult) Scores() []NodeScore {
+	return r.scores
+}
+
+func (r *SchedulerResult) SetScores(scores []NodeScore) {
+	r.scores = scores
+}
@@ -0,0 +1,19 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+)
+
+// name of the node the qemu must be placed on. filter and score plugins are skipped.
+// cappx sets this from ProxmoxMachine.spec.nodeName
+const PinnedNodeKey = "node.qemu-scheduler/pinned"
+
+// PinnedNode returns the node bound to the context, or empty if the qemu is not pinned
+func PinnedNode(ctx context.Context) string {
+	value := ctx.Value(CtxKey(PinnedNodeKey))
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s", value)
+}
@@ -0,0 +1,21 @@
+package framework_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+)
+
+var _ = Describe("PinnedNode", Label("unit", "framework"), func() {
+	It("should return nothing without the key", func() {
+		Expect(framework.PinnedNode(context.Background())).To(BeEmpty())
+	})
+
+	It("should find the pinned node", func() {
+		ctx := framework.ContextWithMap(context.Background(), map[string]string{framework.PinnedNodeKey: "pve1"})
+		Expect(framework.PinnedNode(ctx)).To(Equal("pve1"))
+	})
+})
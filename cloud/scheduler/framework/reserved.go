@@ -0,0 +1,73 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// comma separated vmids and ranges of vmids never selected. e.g. "100,200-299".
+	// cappx sets this from ProxmoxCluster.spec.reservedVMIDs
+	ReservedVMIDsKey = "vmid.qemu-scheduler/reserved"
+
+	// limit of the number of reserved vmids, since they are marked used one by one
+	maxReservedVMIDs = 100000
+)
+
+// VMIDRange is the range of vmids from start to end, both included
+type VMIDRange struct {
+	Start int
+	End   int
+}
+
+// ParseVMIDRanges parses comma separated vmids and ranges of vmids. e.g. "100,200-299"
+func ParseVMIDRanges(value string) ([]VMIDRange, error) {
+	ranges := []VMIDRange{}
+	total := 0
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		startStr, endStr, isRange := strings.Cut(item, "-")
+		start, err := strconv.Atoi(startStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vmid %q: %w", item, err)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(endStr); err != nil {
+				return nil, fmt.Errorf("invalid vmid range %q: %w", item, err)
+			}
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid vmid range %q: end is lower than start", item)
+		}
+		if total += end - start + 1; total > maxReservedVMIDs {
+			return nil, fmt.Errorf("at most %d vmids can be reserved", maxReservedVMIDs)
+		}
+		ranges = append(ranges, VMIDRange{Start: start, End: end})
+	}
+	return ranges, nil
+}
+
+// ReservedVMIDs returns the reserved vmids bound to the context
+func ReservedVMIDs(ctx context.Context) ([]VMIDRange, error) {
+	value := ctx.Value(CtxKey(ReservedVMIDsKey))
+	if value == nil {
+		return nil, nil
+	}
+	return ParseVMIDRanges(fmt.Sprintf("%s", value))
+}
+
+// VMIDReserved returns true if the vmid is in any of the ranges
+func VMIDReserved(ranges []VMIDRange, vmid int) bool {
+	for _, r := range ranges {
+		if vmid >= r.Start && vmid <= r.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package framework_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+)
+
+var _ = Describe("ParseVMIDRanges", Label("unit", "framework"), func() {
+	It("should parse vmids and ranges", func() {
+		Expect(framework.ParseVMIDRanges("100, 200-299,")).To(Equal([]framework.VMIDRange{{Start: 100, End: 100}, {Start: 200, End: 299}}))
+	})
+
+	It("should reject invalid ranges", func() {
+		for _, value := range []string{"a", "100-b", "200-100", "100-200000"} {
+			_, err := framework.ParseVMIDRanges(value)
+			Expect(err).To(HaveOccurred(), value)
+		}
+	})
+})
+
+var _ = Describe("ReservedVMIDs", Label("unit", "framework"), func() {
+	It("should return nothing without the key", func() {
+		Expect(framework.ReservedVMIDs(context.Background())).To(BeEmpty())
+	})
+
+	It("should find reserved vmids", func() {
+		ctx := framework.ContextWithMap(context.Background(), map[string]string{framework.ReservedVMIDsKey: "100,200-299"})
+		ranges, err := framework.ReservedVMIDs(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(framework.VMIDReserved(ranges, 100)).To(BeTrue())
+		Expect(framework.VMIDReserved(ranges, 250)).To(BeTrue())
+		Expect(framework.VMIDReserved(ranges, 101)).To(BeFalse())
+		Expect(framework.VMIDReserved(ranges, 300)).To(BeFalse())
+	})
+})
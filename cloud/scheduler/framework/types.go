@@ -5,6 +5,8 @@ import (
 
 	"github.com/k8s-proxmox/proxmox-go/api"
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
 )
 
 type Status struct {
@@ -55,6 +57,13 @@ type NodeInfo struct {
 
 	// qemus assigned to the node
 	qemus []*api.VirtualMachine
+
+	// lxc containers assigned to the node
+	containers []*api.VirtualMachine
+
+	// client is used by plugins requiring node level information
+	// which is not included in node status. e.g. pci devices
+	client *proxmox.Service
 }
 
 func GetNodeInfoList(ctx context.Context, client *proxmox.Service) ([]*NodeInfo, error) {
@@ -62,13 +71,27 @@ func GetNodeInfoList(ctx context.Context, client *proxmox.Service) ([]*NodeInfo,
 	if err != nil {
 		return nil, err
 	}
+	guests, err := guest.List(ctx, client)
+	if err != nil {
+		return nil, err
+	}
 	nodeInfos := []*NodeInfo{}
 	for _, node := range nodes {
+		// offline nodes can neither be queried nor host new qemus
+		if node.Status != "online" {
+			continue
+		}
 		qemus, err := client.RESTClient().GetVirtualMachines(ctx, node.Node)
 		if err != nil {
 			return nil, err
 		}
-		nodeInfos = append(nodeInfos, &NodeInfo{node: node, qemus: qemus})
+		containers := []*api.VirtualMachine{}
+		for _, g := range guests {
+			if g.Type == guest.TypeLXC && g.Node == node.Node {
+				containers = append(containers, g.VirtualMachine())
+			}
+		}
+		nodeInfos = append(nodeInfos, &NodeInfo{node: node, qemus: qemus, containers: containers, client: client})
 	}
 	return nodeInfos, nil
 }
@@ -81,6 +104,19 @@ func (n NodeInfo) QEMUs() []*api.VirtualMachine {
 	return n.qemus
 }
 
+func (n NodeInfo) Containers() []*api.VirtualMachine {
+	return n.containers
+}
+
+// Guests returns both qemus and containers consuming resources of the node
+func (n NodeInfo) Guests() []*api.VirtualMachine {
+	return append(append([]*api.VirtualMachine{}, n.qemus...), n.containers...)
+}
+
+func (n NodeInfo) Client() *proxmox.Service {
+	return n.client
+}
+
 // NodeScoreList declares a list of nodes and their scores.
 type NodeScoreList []NodeScore
 
@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+)
+
+// nodeScore is the latest score of each proxmox node computed by each score plugin.
+// the scheduler label is the address of the node having id=1 of the proxmox cluster
+var nodeScore = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "cappx",
+		Subsystem: "scheduler",
+		Name:      "node_score",
+		Help:      "Latest score of a Proxmox node computed by a score plugin",
+	},
+	[]string{"scheduler", "node", "plugin"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(nodeScore)
+}
+
+// replaces the scores of the scheduler so that removed nodes and plugins disappear.
+// scoresMap is map[plugin name]map[node name]score
+func recordScores(schedulerID string, scoresMap map[string]map[string]framework.NodeScore) {
+	nodeScore.DeletePartialMatch(prometheus.Labels{"scheduler": schedulerID})
+	for plugin, scores := range scoresMap {
+		for node, score := range scores {
+			nodeScore.WithLabelValues(schedulerID, node, plugin).Set(float64(score.Score))
+		}
+	}
+}
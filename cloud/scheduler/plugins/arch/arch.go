@@ -0,0 +1,61 @@
+package arch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/names"
+)
+
+type Arch struct{}
+
+var _ framework.NodeFilterPlugin = &Arch{}
+
+const Name = names.Arch
+
+// subset of GET /nodes/{node}/status
+type nodeStatus struct {
+	CurrentKernel struct {
+		Machine string `json:"machine"`
+	} `json:"current-kernel"`
+}
+
+func (pl *Arch) Name() string {
+	return Name
+}
+
+// filter nodes whose cpu architecture differs from the requested one.
+// guests of foreign architectures would be emulated without kvm
+func (pl *Arch) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
+	if config.Arch == "" {
+		return &framework.Status{}
+	}
+	node := nodeInfo.Node().Node
+	var status nodeStatus
+	if err := nodeInfo.Client().RESTClient().Get(ctx, fmt.Sprintf("/nodes/%s/status", node), &status); err != nil {
+		state.SetMessage(pl.Name(), fmt.Sprintf("node %s: failed to get status: %v", node, err))
+		return unschedulable()
+	}
+	if host := hostArch(status); host != config.Arch {
+		state.SetMessage(pl.Name(), fmt.Sprintf("node %s is %s, %s requested", node, host, config.Arch))
+		return unschedulable()
+	}
+	return &framework.Status{}
+}
+
+func unschedulable() *framework.Status {
+	status := framework.NewStatus()
+	status.SetCode(1)
+	return status
+}
+
+// proxmox versions before 7.3 do not report the kernel and run on x86_64 only
+func hostArch(status nodeStatus) api.Arch {
+	if status.CurrentKernel.Machine == "" {
+		return api.X86_64
+	}
+	return api.Arch(status.CurrentKernel.Machine)
+}
@@ -0,0 +1,29 @@
+package arch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/arch"
+)
+
+func TestArch(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "arch plugin")
+}
+
+var _ = Describe("hostArch", Label("unit", "plugins"), func() {
+	It("should read the machine of the running kernel", func() {
+		var status arch.NodeStatus
+		Expect(json.Unmarshal([]byte(`{"current-kernel":{"machine":"aarch64","release":"6.8.12-1-pve"}}`), &status)).To(Succeed())
+		Expect(arch.HostArch(status)).To(Equal(api.Aarch64))
+	})
+
+	It("should default to x86_64", func() {
+		Expect(arch.HostArch(arch.NodeStatus{})).To(Equal(api.X86_64))
+	})
+})
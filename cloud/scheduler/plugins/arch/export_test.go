@@ -0,0 +1,9 @@
+package arch
+
+import "github.com/k8s-proxmox/proxmox-go/api"
+
+type NodeStatus = nodeStatus
+
+func HostArch(status NodeStatus) api.Arch {
+	return hostArch(status)
+}
@@ -0,0 +1,48 @@
+package cordon
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/names"
+)
+
+type Cordon struct{}
+
+var _ framework.NodeFilterPlugin = &Cordon{}
+
+const (
+	Name = names.Cordon
+	// comma separated names of nodes under maintenance.
+	// cappx sets this from ProxmoxNodeMaintenance
+	CordonedNodesKey = "node.qemu-scheduler/cordoned"
+)
+
+func (pl *Cordon) Name() string {
+	return Name
+}
+
+// filter nodes under maintenance
+func (pl *Cordon) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
+	node := nodeInfo.Node().Node
+	if slices.Contains(findCordonedNodes(ctx), node) {
+		state.SetMessage(pl.Name(), fmt.Sprintf("node %s is under maintenance", node))
+		status := framework.NewStatus()
+		status.SetCode(1)
+		return status
+	}
+	return &framework.Status{}
+}
+
+func findCordonedNodes(ctx context.Context) []string {
+	value := ctx.Value(framework.CtxKey(CordonedNodesKey))
+	if value == nil {
+		return nil
+	}
+	return strings.Split(fmt.Sprintf("%s", value), ",")
+}
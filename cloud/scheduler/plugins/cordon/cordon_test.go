@@ -0,0 +1,28 @@
+package cordon_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/cordon"
+)
+
+func TestCordon(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "cordon plugin")
+}
+
+var _ = Describe("findCordonedNodes", Label("unit", "plugins"), func() {
+	It("should split node names", func() {
+		ctx := framework.ContextWithMap(context.Background(), map[string]string{cordon.CordonedNodesKey: "node1,node2"})
+		Expect(cordon.FindCordonedNodes(ctx)).To(Equal([]string{"node1", "node2"}))
+	})
+
+	It("should return nothing without the key", func() {
+		Expect(cordon.FindCordonedNodes(context.Background())).To(BeEmpty())
+	})
+})
@@ -0,0 +1,7 @@
+package cordon
+
+import "context"
+
+func FindCordonedNodes(ctx context.Context) []string {
+	return findCordonedNodes(ctx)
+}
@@ -0,0 +1,9 @@
+package hugepages
+
+func ParseFreeHugePages(out string) (map[int]int, error) {
+	return parseFreeHugePages(out)
+}
+
+func CheckHugePages(size string, memory int, free map[int]int) error {
+	return checkHugePages(size, memory, free)
+}
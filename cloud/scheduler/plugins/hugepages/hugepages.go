@@ -0,0 +1,107 @@
+package hugepages
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/names"
+)
+
+type HugePages struct{}
+
+var _ framework.NodeFilterPlugin = &HugePages{}
+
+const (
+	Name = names.HugePages
+
+	// prints free hugepages of 2MiB and 1GiB
+	freeHugePagesCommand = `echo "cappx-hugepages:$(cat /sys/kernel/mm/hugepages/hugepages-2048kB/free_hugepages 2>/dev/null || echo 0):$(cat /sys/kernel/mm/hugepages/hugepages-1048576kB/free_hugepages 2>/dev/null || echo 0)"`
+)
+
+var freeHugePagesRegex = regexp.MustCompile(`cappx-hugepages:(\d+):(\d+)`)
+
+func (pl *HugePages) Name() string {
+	return Name
+}
+
+// filter nodes not having enough free hugepages of requested size
+func (pl *HugePages) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
+	if config.HugePages == "" {
+		return &framework.Status{}
+	}
+	node := nodeInfo.Node().Node
+	free, err := getFreeHugePages(ctx, nodeInfo, node)
+	if err != nil {
+		state.SetMessage(pl.Name(), fmt.Sprintf("node %s: failed to get free hugepages: %v", node, err))
+		return unschedulable()
+	}
+	if err := checkHugePages(config.HugePages, config.Memory, free); err != nil {
+		state.SetMessage(pl.Name(), fmt.Sprintf("node %s: %v", node, err))
+		return unschedulable()
+	}
+	return &framework.Status{}
+}
+
+func unschedulable() *framework.Status {
+	status := framework.NewStatus()
+	status.SetCode(1)
+	return status
+}
+
+// return map[page size in MiB]free pages
+func getFreeHugePages(ctx context.Context, nodeInfo *framework.NodeInfo, node string) (map[int]int, error) {
+	vnc, err := nodeInfo.Client().NewNodeVNCWebSocketConnection(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	defer vnc.Close()
+	out, _, err := vnc.Exec(ctx, freeHugePagesCommand)
+	if err != nil {
+		return nil, err
+	}
+	return parseFreeHugePages(out)
+}
+
+func parseFreeHugePages(out string) (map[int]int, error) {
+	match := freeHugePagesRegex.FindStringSubmatch(out)
+	if match == nil {
+		return nil, fmt.Errorf("unexpected output: %s", out)
+	}
+	free2M, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil, err
+	}
+	free1G, err := strconv.Atoi(match[2])
+	if err != nil {
+		return nil, err
+	}
+	return map[int]int{2: free2M, 1024: free1G}, nil
+}
+
+// check if memory (MiB) fits into free hugepages of the size.
+// size "any" is satisfied by either of page sizes.
+func checkHugePages(size string, memory int, free map[int]int) error {
+	sizes := []int{2, 1024}
+	if size != "any" {
+		s, err := strconv.Atoi(size)
+		if err != nil {
+			return fmt.Errorf("invalid hugepages size %s", size)
+		}
+		sizes = []int{s}
+	}
+	for _, s := range sizes {
+		required := (memory + s - 1) / s
+		if free[s] >= required {
+			return nil
+		}
+		if len(sizes) == 1 {
+			return fmt.Errorf("%d free %dMiB hugepages, %d required", free[s], s, required)
+		}
+	}
+	return fmt.Errorf("not enough free hugepages for %dMiB memory (2MiB: %d, 1024MiB: %d)", memory, free[2], free[1024])
+}
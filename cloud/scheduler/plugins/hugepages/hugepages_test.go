@@ -0,0 +1,48 @@
+package hugepages_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/hugepages"
+)
+
+func TestHugePages(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "hugepages plugin")
+}
+
+var _ = Describe("parseFreeHugePages", Label("unit", "plugins"), func() {
+	It("should parse free hugepages from noisy output", func() {
+		out := "root@pve:~# echo \"cappx-hugepages:$(cat ...)\"\r\ncappx-hugepages:512:4\r\n"
+		free, err := hugepages.ParseFreeHugePages(out)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(free).To(Equal(map[int]int{2: 512, 1024: 4}))
+	})
+
+	It("should error for unexpected output", func() {
+		_, err := hugepages.ParseFreeHugePages("command not found")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("checkHugePages", Label("unit", "plugins"), func() {
+	free := map[int]int{2: 1024, 1024: 1}
+
+	It("should pass with enough pages", func() {
+		Expect(hugepages.CheckHugePages("2", 2048, free)).To(Succeed())
+		Expect(hugepages.CheckHugePages("1024", 1024, free)).To(Succeed())
+	})
+
+	It("should fail with clear message", func() {
+		err := hugepages.CheckHugePages("1024", 4096, free)
+		Expect(err).To(MatchError("1 free 1024MiB hugepages, 4 required"))
+	})
+
+	It("should accept any size", func() {
+		Expect(hugepages.CheckHugePages("any", 2048, free)).To(Succeed())
+		Expect(hugepages.CheckHugePages("any", 4096, free)).NotTo(Succeed())
+	})
+})
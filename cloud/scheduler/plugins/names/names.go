@@ -11,6 +11,18 @@ const (
 	CPUOvercommit = "CPUOvercommit"
 	// filter by memory overcommit ratio
 	MemoryOvercommit = "MemoryOvercommit"
+	// filter by available vGPU (mediated device) instances
+	VGPU = "VGPU"
+	// filter by free devices of pci resource mappings. e.g. sr-iov virtual functions
+	PCIMapping = "PCIMapping"
+	// filter by free hugepages
+	HugePages = "HugePages"
+	// filter nodes under maintenance
+	Cordon = "Cordon"
+	// filter by cpu architecture
+	Arch = "Arch"
+	// filter nodes out of the node group
+	NodeGroup = "NodeGroup"
 
 	// score plugins
 	// random score
@@ -0,0 +1,10 @@
+package nodegroup
+
+import (
+	"context"
+	"regexp"
+)
+
+func FindNodeGroup(ctx context.Context) ([]string, *regexp.Regexp, error) {
+	return findNodeGroup(ctx)
+}
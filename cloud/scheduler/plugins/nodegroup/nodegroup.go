@@ -0,0 +1,69 @@
+package nodegroup
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/names"
+)
+
+type NodeGroup struct{}
+
+var _ framework.NodeFilterPlugin = &NodeGroup{}
+
+const (
+	Name = names.NodeGroup
+	// comma separated names of the nodes of the node group.
+	// cappx sets this and NodeGroupRegexKey from ProxmoxCluster.spec.nodeGroups
+	NodeGroupNodesKey = "node.qemu-scheduler/group-nodes"
+	// regex matching the names of the nodes of the node group
+	NodeGroupRegexKey = "node.qemu-scheduler/group-regex"
+)
+
+func (pl *NodeGroup) Name() string {
+	return Name
+}
+
+// filter nodes out of the node group. a node is in the group if it is listed or matches the regex
+func (pl *NodeGroup) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
+	nodes, reg, err := findNodeGroup(ctx)
+	if err != nil {
+		state.SetMessage(pl.Name(), err.Error())
+		status := framework.NewStatus()
+		status.SetCode(1)
+		return status
+	}
+	if nodes == nil && reg == nil {
+		return &framework.Status{}
+	}
+	node := nodeInfo.Node().Node
+	if slices.Contains(nodes, node) || (reg != nil && reg.MatchString(node)) {
+		return &framework.Status{}
+	}
+	state.SetMessage(pl.Name(), fmt.Sprintf("node %s is not in the node group", node))
+	status := framework.NewStatus()
+	status.SetCode(1)
+	return status
+}
+
+func findNodeGroup(ctx context.Context) ([]string, *regexp.Regexp, error) {
+	var nodes []string
+	if value := ctx.Value(framework.CtxKey(NodeGroupNodesKey)); value != nil {
+		nodes = strings.Split(fmt.Sprintf("%s", value), ",")
+	}
+	value := ctx.Value(framework.CtxKey(NodeGroupRegexKey))
+	if value == nil {
+		return nodes, nil, nil
+	}
+	reg, err := regexp.Compile(fmt.Sprintf("%s", value))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid node group regex: %w", err)
+	}
+	return nodes, reg, nil
+}
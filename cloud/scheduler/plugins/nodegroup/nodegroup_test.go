@@ -0,0 +1,43 @@
+package nodegroup_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/nodegroup"
+)
+
+func TestNodeGroup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "nodegroup plugin")
+}
+
+var _ = Describe("findNodeGroup", Label("unit", "plugins"), func() {
+	It("should find nodes and regex", func() {
+		ctx := framework.ContextWithMap(context.Background(), map[string]string{
+			nodegroup.NodeGroupNodesKey: "node1,node2",
+			nodegroup.NodeGroupRegexKey: "infra[0-9]+",
+		})
+		nodes, reg, err := nodegroup.FindNodeGroup(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(Equal([]string{"node1", "node2"}))
+		Expect(reg.MatchString("infra1")).To(BeTrue())
+	})
+
+	It("should return nothing without the keys", func() {
+		nodes, reg, err := nodegroup.FindNodeGroup(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(BeNil())
+		Expect(reg).To(BeNil())
+	})
+
+	It("should fail with invalid regex", func() {
+		ctx := framework.ContextWithMap(context.Background(), map[string]string{nodegroup.NodeGroupRegexKey: "("})
+		_, _, err := nodegroup.FindNodeGroup(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -24,7 +24,7 @@ func (pl *CPUOvercommit) Name() string {
 
 // filter by cpu overcommit ratio
 func (pl *CPUOvercommit) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
-	cpu := sumCPUs(nodeInfo.QEMUs())
+	cpu := sumCPUs(nodeInfo.Guests())
 	maxCPU := nodeInfo.Node().MaxCpu
 	sockets := config.Sockets
 	if sockets == 0 {
@@ -40,7 +40,7 @@ func (pl *CPUOvercommit) Filter(ctx context.Context, state *framework.CycleState
 	return &framework.Status{}
 }
 
-// sum cpus of all 'running' qemu
+// sum cpus of all 'running' qemus and containers
 func sumCPUs(qemus []*api.VirtualMachine) int {
 	var result int
 	for _, q := range qemus {
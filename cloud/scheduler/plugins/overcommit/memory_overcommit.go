@@ -24,7 +24,7 @@ func (pl *MemoryOvercommit) Name() string {
 
 // filter by memory overcommit ratio
 func (pl *MemoryOvercommit) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
-	mem := sumMems(nodeInfo.QEMUs())
+	mem := sumMems(nodeInfo.Guests())
 	maxMem := nodeInfo.Node().MaxMem
 	ratio := float32(mem+1024*1024*config.Memory) / float32(maxMem)
 	if ratio >= defaultMemoryOvercommitRatio {
@@ -36,7 +36,7 @@ func (pl *MemoryOvercommit) Filter(ctx context.Context, state *framework.CycleSt
 	return &framework.Status{}
 }
 
-// sum maxmem of all 'running' qemu
+// sum maxmem of all 'running' qemus and containers
 func sumMems(qemus []*api.VirtualMachine) int {
 	var result int
 	for _, q := range qemus {
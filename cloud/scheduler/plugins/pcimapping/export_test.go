@@ -0,0 +1,16 @@
+package pcimapping
+
+import "github.com/k8s-proxmox/proxmox-go/api"
+
+func FindMappingRequests(hostpci api.HostPci) map[string]int {
+	return findMappingRequests(hostpci)
+}
+
+// FreeDevices returns the free devices of the mapping on the node used by the vm configs
+func FreeDevices(name string, devices []string, node string, vmconfigs ...map[string]interface{}) int {
+	used := usage{mappings: map[string]int{}, ids: map[string]bool{}}
+	for _, c := range vmconfigs {
+		used.add(c)
+	}
+	return freeDevices(name, devices, node, used)
+}
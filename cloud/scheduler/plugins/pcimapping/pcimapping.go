@@ -0,0 +1,173 @@
+package pcimapping
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/names"
+)
+
+type PCIMapping struct{}
+
+var _ framework.NodeFilterPlugin = &PCIMapping{}
+
+const (
+	Name = names.PCIMapping
+)
+
+func (pl *PCIMapping) Name() string {
+	return Name
+}
+
+// pci resource mapping
+type pciMapping struct {
+	Map []string `json:"map"`
+}
+
+// devices of pci resource mappings and raw device ids used by the guests of a node
+type usage struct {
+	// map[mapping name]number of devices
+	mappings map[string]int
+	// normalized device ids
+	ids map[string]bool
+}
+
+// filter nodes not having enough free devices of the requested pci resource mappings. e.g. sr-iov virtual functions.
+// proxmox picks a free device of the mapping when a vm starts, so only running qemus use devices.
+// requests having mdev are left to the VGPU plugin since a device hosts multiple mediated devices
+func (pl *PCIMapping) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
+	requests := findMappingRequests(config.HostPci)
+	if len(requests) == 0 {
+		return &framework.Status{}
+	}
+	node := nodeInfo.Node().Node
+
+	used := usage{mappings: map[string]int{}, ids: map[string]bool{}}
+	for _, qemu := range nodeInfo.QEMUs() {
+		if qemu.Status != api.ProcessStatusRunning {
+			continue
+		}
+		vmconfig := map[string]interface{}{}
+		if err := nodeInfo.Client().RESTClient().Get(ctx, fmt.Sprintf("/nodes/%s/qemu/%d/config", node, qemu.VMID), &vmconfig); err != nil {
+			state.SetMessage(pl.Name(), fmt.Sprintf("node %s: failed to get config of qemu %d: %v", node, qemu.VMID, err))
+			return unschedulable()
+		}
+		used.add(vmconfig)
+	}
+
+	for name, n := range requests {
+		var mapping pciMapping
+		if err := nodeInfo.Client().RESTClient().Get(ctx, fmt.Sprintf("/cluster/mapping/pci/%s", url.PathEscape(name)), &mapping); err != nil {
+			state.SetMessage(pl.Name(), fmt.Sprintf("node %s: failed to get pci mapping %s: %v", node, name, err))
+			return unschedulable()
+		}
+		if free := freeDevices(name, mapping.Map, node, used); free < n {
+			state.SetMessage(pl.Name(), fmt.Sprintf("node %s: %d free devices of pci mapping %s, %d requested", node, free, name, n))
+			return unschedulable()
+		}
+	}
+	return &framework.Status{}
+}
+
+func unschedulable() *framework.Status {
+	status := framework.NewStatus()
+	status.SetCode(1)
+	return status
+}
+
+// returns map[mapping name]number of requested devices of hostpciN entries having mapping but no mdev
+func findMappingRequests(hostpci api.HostPci) map[string]int {
+	requests := map[string]int{}
+	v := reflect.ValueOf(hostpci)
+	for i := 0; i < v.NumField(); i++ {
+		mapping, _, mdev := parseHostPci(v.Field(i).String())
+		if mapping != "" && mdev == "" {
+			requests[mapping]++
+		}
+	}
+	return requests
+}
+
+// parse hostpci option. e.g. "mapping=vf-pool,pcie=1" or "0000:01:00.2;0000:01:00.3,mdev=nvidia-63"
+func parseHostPci(option string) (mapping string, ids []string, mdev string) {
+	for _, kv := range strings.Split(option, ",") {
+		key, value, found := strings.Cut(kv, "=")
+		switch {
+		case !found:
+			ids = strings.Split(key, ";")
+		case key == "host":
+			ids = strings.Split(value, ";")
+		case key == "mapping":
+			mapping = value
+		case key == "mdev":
+			mdev = value
+		}
+	}
+	return mapping, ids, mdev
+}
+
+// adds the devices used by hostpciN entries of the vm config
+func (u *usage) add(vmconfig map[string]interface{}) {
+	for key, value := range vmconfig {
+		option, ok := value.(string)
+		if !ok || !strings.HasPrefix(key, "hostpci") {
+			continue
+		}
+		mapping, ids, _ := parseHostPci(option)
+		if mapping != "" {
+			u.mappings[mapping]++
+			continue
+		}
+		for _, id := range ids {
+			u.ids[normalize(id)] = true
+		}
+	}
+}
+
+// returns the number of devices of the mapping on the node used by no running qemu.
+// each entry of the map is a device. e.g. "node=pve1,path=0000:01:00.2,id=8086:154c"
+func freeDevices(name string, devices []string, node string, used usage) int {
+	free := 0
+	for _, m := range devices {
+		var mappedNode string
+		var paths []string
+		for _, kv := range strings.Split(m, ",") {
+			key, value, _ := strings.Cut(kv, "=")
+			switch key {
+			case "node":
+				mappedNode = value
+			case "path":
+				paths = strings.Split(value, ";")
+			}
+		}
+		if mappedNode != node || len(paths) == 0 || usedByID(paths, used.ids) {
+			continue
+		}
+		free++
+	}
+	return free - used.mappings[name]
+}
+
+func usedByID(paths []string, ids map[string]bool) bool {
+	for _, p := range paths {
+		if ids[normalize(p)] {
+			return true
+		}
+	}
+	return false
+}
+
+// the pci domain can be omitted. e.g. 01:00.2 is 0000:01:00.2
+func normalize(id string) string {
+	id = strings.ToLower(id)
+	if strings.Count(id, ":") == 1 {
+		return "0000:" + id
+	}
+	return id
+}
@@ -0,0 +1,50 @@
+package pcimapping_test
+
+import (
+	"testing"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/pcimapping"
+)
+
+func TestPCIMapping(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pcimapping plugin")
+}
+
+var _ = Describe("findMappingRequests", Label("unit", "plugins"), func() {
+	It("should count hostpci entries having mapping but no mdev", func() {
+		hostpci := api.HostPci{
+			HostPci0: "mapping=gpu,mdev=nvidia-64",
+			HostPci1: "mapping=vf-pool,pcie=1",
+			HostPci2: "mapping=vf-pool",
+			HostPci3: "0000:02:00.0,pcie=1",
+		}
+		Expect(pcimapping.FindMappingRequests(hostpci)).To(Equal(map[string]int{"vf-pool": 2}))
+	})
+})
+
+var _ = Describe("freeDevices", Label("unit", "plugins"), func() {
+	devices := []string{
+		"node=pve1,path=0000:01:00.2,id=8086:154c",
+		"node=pve1,path=0000:01:00.3,id=8086:154c",
+		"node=pve1,path=0000:01:00.4,id=8086:154c",
+		"node=pve2,path=0000:01:00.2,id=8086:154c",
+	}
+
+	It("should count the devices of the node", func() {
+		Expect(pcimapping.FreeDevices("vf-pool", devices, "pve1")).To(Equal(3))
+		Expect(pcimapping.FreeDevices("vf-pool", devices, "pve3")).To(Equal(0))
+	})
+
+	It("should subtract devices used by mapping or device id", func() {
+		vmconfigs := []map[string]interface{}{
+			{"hostpci0": "mapping=vf-pool,pcie=1", "hostpci1": "mapping=gpu"},
+			{"hostpci0": "01:00.3", "net0": "virtio=BC:24:11:00:00:00,bridge=vmbr0"},
+		}
+		Expect(pcimapping.FreeDevices("vf-pool", devices, "pve1", vmconfigs...)).To(Equal(1))
+	})
+})
@@ -6,11 +6,17 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/arch"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/cordon"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/hugepages"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/idrange"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/nodegroup"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/nodename"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/noderesource"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/overcommit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/pcimapping"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/regex"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/vgpu"
 )
 
 type PluginConfigs struct {
@@ -57,6 +63,12 @@ func NewNodeFilterPlugins(config map[string]PluginConfig) []framework.NodeFilter
 		&overcommit.CPUOvercommit{},
 		&overcommit.MemoryOvercommit{},
 		&regex.NodeRegex{},
+		&vgpu.VGPU{},
+		&pcimapping.PCIMapping{},
+		&hugepages.HugePages{},
+		&cordon.Cordon{},
+		&arch.Arch{},
+		&nodegroup.NodeGroup{},
 	}
 	plugins := []framework.NodeFilterPlugin{}
 	for _, pl := range pls {
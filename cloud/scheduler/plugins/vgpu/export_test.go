@@ -0,0 +1,13 @@
+package vgpu
+
+import "github.com/k8s-proxmox/proxmox-go/api"
+
+type MDevRequest = mdevRequest
+
+func NewMDevRequest(id, mapping, mdevType string) MDevRequest {
+	return mdevRequest{id: id, mapping: mapping, mdevType: mdevType}
+}
+
+func FindMDevRequests(hostpci api.HostPci) []MDevRequest {
+	return findMDevRequests(hostpci)
+}
@@ -0,0 +1,166 @@
+package vgpu
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/names"
+)
+
+type VGPU struct{}
+
+var _ framework.NodeFilterPlugin = &VGPU{}
+
+const (
+	Name = names.VGPU
+)
+
+func (pl *VGPU) Name() string {
+	return Name
+}
+
+// mediated device requested by hostpciN
+type mdevRequest struct {
+	// host pci device id. empty if mapping is used
+	id string
+	// name of pci resource mapping
+	mapping string
+	// mdev type
+	mdevType string
+}
+
+// mediated device type available on the pci device
+type mdevType struct {
+	Type      string `json:"type"`
+	Available int    `json:"available"`
+}
+
+// pci resource mapping
+type pciMapping struct {
+	ID  string   `json:"id"`
+	Map []string `json:"map"`
+}
+
+// filter nodes not having enough available instances of requested mdev types
+func (pl *VGPU) Filter(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nodeInfo *framework.NodeInfo) *framework.Status {
+	requests := findMDevRequests(config.HostPci)
+	if len(requests) == 0 {
+		return &framework.Status{}
+	}
+	node := nodeInfo.Node().Node
+
+	// number of requested instances for each device and type
+	required := map[string]map[string]int{}
+	for _, r := range requests {
+		id := r.id
+		if r.mapping != "" {
+			var err error
+			id, err = resolveMapping(ctx, nodeInfo.Client(), r.mapping, node)
+			if err != nil {
+				state.SetMessage(pl.Name(), fmt.Sprintf("node %s: %v", node, err))
+				return unschedulable()
+			}
+		}
+		if required[id] == nil {
+			required[id] = map[string]int{}
+		}
+		required[id][r.mdevType]++
+	}
+
+	for id, types := range required {
+		available, err := getAvailableMDevs(ctx, nodeInfo.Client(), node, id)
+		if err != nil {
+			state.SetMessage(pl.Name(), fmt.Sprintf("node %s: failed to get mdev types of %s: %v", node, id, err))
+			return unschedulable()
+		}
+		for t, n := range types {
+			if available[t] < n {
+				state.SetMessage(pl.Name(), fmt.Sprintf("node %s: not enough %s instances on %s", node, t, id))
+				return unschedulable()
+			}
+		}
+	}
+	return &framework.Status{}
+}
+
+func unschedulable() *framework.Status {
+	status := framework.NewStatus()
+	status.SetCode(1)
+	return status
+}
+
+// find hostpciN entries having mdev option
+func findMDevRequests(hostpci api.HostPci) []mdevRequest {
+	requests := []mdevRequest{}
+	v := reflect.ValueOf(hostpci)
+	for i := 0; i < v.NumField(); i++ {
+		if r, ok := parseHostPci(v.Field(i).String()); ok {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// parse hostpci option. e.g. "0000:01:00.0,mdev=nvidia-63" or "mapping=gpu,mdev=nvidia-63"
+func parseHostPci(option string) (mdevRequest, bool) {
+	r := mdevRequest{}
+	for _, kv := range strings.Split(option, ",") {
+		key, value, found := strings.Cut(kv, "=")
+		switch {
+		case !found:
+			r.id = key
+		case key == "host":
+			r.id = value
+		case key == "mapping":
+			r.mapping = value
+		case key == "mdev":
+			r.mdevType = value
+		}
+	}
+	return r, r.mdevType != "" && (r.id != "" || r.mapping != "")
+}
+
+// resolve host pci device id of the mapping on the node
+func resolveMapping(ctx context.Context, client *proxmox.Service, name, node string) (string, error) {
+	var mapping pciMapping
+	if err := client.RESTClient().Get(ctx, fmt.Sprintf("/cluster/mapping/pci/%s", url.PathEscape(name)), &mapping); err != nil {
+		return "", err
+	}
+	for _, m := range mapping.Map {
+		var mappedNode, path string
+		for _, kv := range strings.Split(m, ",") {
+			key, value, _ := strings.Cut(kv, "=")
+			switch key {
+			case "node":
+				mappedNode = value
+			case "path":
+				path = value
+			}
+		}
+		if mappedNode == node && path != "" {
+			// multiple paths can be mapped. use the first one
+			return strings.Split(path, ";")[0], nil
+		}
+	}
+	return "", fmt.Errorf("pci mapping %s has no device on this node", name)
+}
+
+// return map[mdev type]available instances
+func getAvailableMDevs(ctx context.Context, client *proxmox.Service, node, id string) (map[string]int, error) {
+	var types []mdevType
+	if err := client.RESTClient().Get(ctx, fmt.Sprintf("/nodes/%s/hardware/pci/%s/mdev", node, url.PathEscape(id)), &types); err != nil {
+		return nil, err
+	}
+	available := map[string]int{}
+	for _, t := range types {
+		available[t.Type] = t.Available
+	}
+	return available, nil
+}
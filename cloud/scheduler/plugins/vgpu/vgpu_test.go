@@ -0,0 +1,34 @@
+package vgpu_test
+
+import (
+	"testing"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/vgpu"
+)
+
+func TestVGPU(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "vgpu plugin")
+}
+
+var _ = Describe("findMDevRequests", Label("unit", "plugins"), func() {
+	It("should find hostpci entries having mdev", func() {
+		hostpci := api.HostPci{
+			HostPci0: "0000:01:00.0,pcie=1,mdev=nvidia-63",
+			HostPci1: "mapping=gpu,mdev=nvidia-64",
+			HostPci2: "0000:02:00.0,pcie=1",
+		}
+		Expect(vgpu.FindMDevRequests(hostpci)).To(Equal([]vgpu.MDevRequest{
+			vgpu.NewMDevRequest("0000:01:00.0", "", "nvidia-63"),
+			vgpu.NewMDevRequest("", "gpu", "nvidia-64"),
+		}))
+	})
+
+	It("should return empty for no mdev", func() {
+		Expect(vgpu.FindMDevRequests(api.HostPci{})).To(BeEmpty())
+	})
+})
@@ -40,6 +40,22 @@ func (s *SchedulingQueue) Add(ctx context.Context, config *api.VirtualMachineCre
 	s.lock.Signal()
 }
 
+// removes the qemuSpecs of the name not taken by the scheduler yet
+// and returns how many were removed
+func (s *SchedulingQueue) Remove(name string) int {
+	s.lock.L.Lock()
+	defer s.lock.L.Unlock()
+	kept := []*qemuSpec{}
+	for _, spec := range s.activeQ {
+		if spec.config.Name != name {
+			kept = append(kept, spec)
+		}
+	}
+	removed := len(s.activeQ) - len(kept)
+	s.activeQ = kept
+	return removed
+}
+
 // return length of active queue
 // func (s *SchedulingQueue) Len() int {
 // 	s.lock.L.Lock()
@@ -60,3 +60,17 @@ var _ = Describe("Get", Label("unit", "queue"), func() {
 		})
 	})
 })
+
+var _ = Describe("Remove", Label("unit", "queue"), func() {
+	It("should remove pending qemus of the name only", func() {
+		q := queue.New()
+		q.Add(context.Background(), &api.VirtualMachineCreateOptions{Name: "foo"})
+		q.Add(context.Background(), &api.VirtualMachineCreateOptions{Name: "bar"})
+		q.Add(context.Background(), &api.VirtualMachineCreateOptions{Name: "foo"})
+		Expect(q.Remove("foo")).To(Equal(2))
+		Expect(q.Remove("foo")).To(Equal(0))
+		qemu, shutdown := q.Get()
+		Expect(shutdown).To(BeFalse())
+		Expect(qemu.Config().Name).To(Equal("bar"))
+	})
+})
@@ -3,7 +3,9 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -11,6 +13,7 @@ import (
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
 	"k8s.io/apimachinery/pkg/util/wait"
 
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/queue"
@@ -24,6 +27,40 @@ var (
 	ErrNoVMIDAvailable = fmt.Errorf("no vmid available to schedule qemus")
 )
 
+// FitError is returned when no node passes the filter plugins. like kube-scheduler,
+// it counts the nodes rejected by each filter plugin
+type FitError struct {
+	NumAllNodes int
+	// number of nodes rejected per filter plugin
+	Rejections map[string]int
+	// messages of the filter plugins
+	Messages map[string]string
+}
+
+func (e *FitError) Error() string {
+	plugins := make([]string, 0, len(e.Rejections))
+	for plugin := range e.Rejections {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	reasons := make([]string, 0, len(plugins))
+	for _, plugin := range plugins {
+		reasons = append(reasons, fmt.Sprintf("%d rejected by %s", e.Rejections[plugin], plugin))
+	}
+	msg := fmt.Sprintf("%s: 0/%d nodes are available", ErrNoNodesAvailable, e.NumAllNodes)
+	if len(reasons) != 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(reasons, ", "))
+	}
+	if len(e.Messages) != 0 {
+		msg = fmt.Sprintf("%s %v", msg, e.Messages)
+	}
+	return msg
+}
+
+func (e *FitError) Unwrap() error {
+	return ErrNoNodesAvailable
+}
+
 // manager manages schedulers
 type Manager struct {
 	ctx context.Context
@@ -62,6 +99,7 @@ func (m *Manager) GetOrCreateScheduler(client *proxmox.Service) *Scheduler {
 		m.params.Logger.V(4).Info("registering new scheduler")
 		sched := m.NewScheduler(client)
 		sched.logger = sched.logger.WithValues("schedulerID", &schedID)
+		sched.id = schedID.IPAddress
 		m.table[*schedID] = sched
 		return sched
 	}
@@ -124,6 +162,9 @@ func (m *Manager) getSchedulerID(client *proxmox.Service) (*schedulerID, error)
 }
 
 type Scheduler struct {
+	// address of the node having id=1. empty for unregistered schedulers
+	id string
+
 	client          *proxmox.Service
 	schedulingQueue *queue.SchedulingQueue
 
@@ -132,8 +173,9 @@ type Scheduler struct {
 	// to do : cache
 
 	// map[qemu name]chan *framework.CycleState
-	resultMap map[string]chan *framework.CycleState
-	logger    logr.Logger
+	resultMap  map[string]chan *framework.CycleState
+	resultLock sync.Mutex
+	logger     logr.Logger
 
 	// scheduler status
 	running bool
@@ -204,34 +246,42 @@ func (s *Scheduler) ScheduleOne(ctx context.Context) {
 	s.logger.Info("scheduling qemu")
 
 	state := framework.NewCycleState()
-	s.resultMap[config.Name] = make(chan *framework.CycleState, 1)
-	defer func() { s.resultMap[config.Name] <- &state }()
+	// buffered so that the result of a released qemu is dropped without blocking
+	done := make(chan *framework.CycleState, 1)
+	s.resultLock.Lock()
+	s.resultMap[config.Name] = done
+	s.resultLock.Unlock()
+	defer func() { done <- &state }()
+
+	result, err := s.Plan(qemuCtx, *config)
+	state.UpdateState(true, err, result)
+}
 
+// Plan selects the node, vmid and storage of the qemu without going through the scheduling queue
+func (s *Scheduler) Plan(ctx context.Context, config api.VirtualMachineCreateOptions) (framework.SchedulerResult, error) {
 	// select node to run qemu
-	node, err := s.SelectNode(qemuCtx, *config)
+	node, scores, err := s.SelectNode(ctx, config)
 	if err != nil {
-		state.UpdateState(true, err, framework.SchedulerResult{})
-		return
+		return framework.SchedulerResult{}, err
 	}
 
 	// select vmid to be assigned to qemu
 	// to do: do this in parallel with SelectNode
-	vmid, err := s.SelectVMID(qemuCtx, *config)
+	vmid, err := s.SelectVMID(ctx, config)
 	if err != nil {
-		state.UpdateState(true, err, framework.SchedulerResult{})
-		return
+		return framework.SchedulerResult{}, err
 	}
 
 	// select vm storage to be used for vm image
 	// must be done after node selection as some storages may not be available on some nodes
-	storage, err := s.SelectStorage(qemuCtx, *config, node)
+	storage, err := s.SelectStorage(ctx, config, node)
 	if err != nil {
-		state.UpdateState(true, err, framework.SchedulerResult{})
-		return
+		return framework.SchedulerResult{}, err
 	}
 
 	result := framework.NewSchedulerResult(vmid, node, storage)
-	state.UpdateState(true, nil, result)
+	result.SetScores(scores)
+	return result, nil
 }
 
 // wait until CycleState is put into channel and then return it
@@ -241,14 +291,18 @@ func (s *Scheduler) WaitStatus(ctx context.Context, config *api.VirtualMachineCr
 	var done chan *framework.CycleState
 	ok := false
 	for !ok {
+		s.resultLock.Lock()
 		done, ok = s.resultMap[config.Name]
+		s.resultLock.Unlock()
 		if !ok {
 			time.Sleep(100 * time.Millisecond)
 		}
 	}
 	select {
 	case state := <-done:
+		s.resultLock.Lock()
 		delete(s.resultMap, config.Name)
+		s.resultLock.Unlock()
 		return *state, nil
 	case <-ctx.Done():
 		err := fmt.Errorf("exceed timeout deadline. schedulingQueue might be shutdowned")
@@ -257,6 +311,17 @@ func (s *Scheduler) WaitStatus(ctx context.Context, config *api.VirtualMachineCr
 	}
 }
 
+// Release drops the pending scheduling request and the result of the qemu, e.g. when its machine
+// is deleted, so that a qemu created later with the same name does not receive a stale result
+func (s *Scheduler) Release(name string) {
+	if n := s.schedulingQueue.Remove(name); n > 0 {
+		s.logger.Info("removed qemu from scheduler queue", "qemu", name)
+	}
+	s.resultLock.Lock()
+	defer s.resultLock.Unlock()
+	delete(s.resultMap, name)
+}
+
 // create new qemu with given spec and context
 func (s *Scheduler) CreateQEMU(ctx context.Context, config *api.VirtualMachineCreateOptions) (framework.SchedulerResult, error) {
 	log := s.logger.WithValues("qemu", config.Name)
@@ -278,22 +343,31 @@ func (s *Scheduler) CreateQEMU(ctx context.Context, config *api.VirtualMachineCr
 	return status.Result(), nil
 }
 
-func (s *Scheduler) SelectNode(ctx context.Context, config api.VirtualMachineCreateOptions) (string, error) {
+// returns the selected node and the scores of the feasible nodes in descending order
+func (s *Scheduler) SelectNode(ctx context.Context, config api.VirtualMachineCreateOptions) (string, []framework.NodeScore, error) {
 	s.logger.Info("finding proxmox node matching qemu")
 	nodes, err := s.client.GetNodes(ctx)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	if pinned := framework.PinnedNode(ctx); pinned != "" {
+		node, err := selectPinnedNode(nodes, pinned)
+		return node, nil, err
 	}
 
 	state := framework.NewCycleState()
 
 	// filter
-	nodelist, _ := s.RunFilterPlugins(ctx, &state, config, nodes)
+	nodelist, err := s.RunFilterPlugins(ctx, &state, config, nodes)
+	if err != nil {
+		return "", nil, err
+	}
 	if len(nodelist) == 0 {
-		return "", ErrNoNodesAvailable
+		return "", nil, &FitError{NumAllNodes: len(nodes), Rejections: state.Rejections(), Messages: state.Messages()}
 	}
 	if len(nodelist) == 1 {
-		return nodelist[0].Node, nil
+		return nodelist[0].Node, nil, nil
 	}
 
 	// score
@@ -303,15 +377,36 @@ func (s *Scheduler) SelectNode(ctx context.Context, config api.VirtualMachineCre
 	}
 	selectedNode, err := selectHighestScoreNode(scorelist)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	s.logger.Info(fmt.Sprintf("proxmox node %s was selected for vm %s", selectedNode, config.Name))
-	return selectedNode, nil
+	return selectedNode, sortedScores(scorelist, selectedNode), nil
+}
+
+// returns the pinned node if it exists and is online
+func selectPinnedNode(nodes []*api.Node, pinned string) (string, error) {
+	for _, node := range nodes {
+		if node.Node != pinned {
+			continue
+		}
+		if node.Status != "online" {
+			return "", fmt.Errorf("pinned node %s is %s", pinned, node.Status)
+		}
+		return pinned, nil
+	}
+	return "", fmt.Errorf("pinned node %s does not exist", pinned)
 }
 
 func (s *Scheduler) SelectVMID(ctx context.Context, config api.VirtualMachineCreateOptions) (int, error) {
 	s.logger.Info("finding proxmox vmid to be assigned to qemu")
+	reserved, err := framework.ReservedVMIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
 	if config.VMID != nil {
+		if framework.VMIDReserved(reserved, *config.VMID) {
+			return 0, fmt.Errorf("vmid %d is reserved", *config.VMID)
+		}
 		return *config.VMID, nil
 	}
 	nextid, err := s.client.NextID(ctx)
@@ -322,13 +417,28 @@ func (s *Scheduler) SelectVMID(ctx context.Context, config api.VirtualMachineCre
 	if err != nil {
 		return 0, err
 	}
+	// reserved vmids are never selected by any plugin
+	markReserved(*usedID, reserved)
+	for (*usedID)[nextid] {
+		nextid++
+	}
 	return s.RunVMIDPlugins(ctx, nil, config, nextid, *usedID)
 }
 
+// marks the reserved vmids used
+func markReserved(usedID map[int]bool, reserved []framework.VMIDRange) {
+	for _, r := range reserved {
+		for id := r.Start; id <= r.End; id++ {
+			usedID[id] = true
+		}
+	}
+}
+
 func (s *Scheduler) SelectStorage(ctx context.Context, config api.VirtualMachineCreateOptions, nodeName string) (string, error) {
 	log := s.logger.WithValues("qemu", config.Name).WithValues("node", nodeName)
 	log.Info("finding proxmox storage to be used for qemu")
-	if config.Storage != "" {
+	pinned := framework.PinnedNode(ctx) != ""
+	if config.Storage != "" && !pinned {
 		// to do: raise error if storage is not available on the node
 		return config.Storage, nil
 	}
@@ -347,9 +457,20 @@ func (s *Scheduler) SelectStorage(ctx context.Context, config api.VirtualMachine
 	// current logic is just selecting the first storage
 	// that is active and supports "images" type of content
 	for _, storage := range storages {
-		if strings.Contains(storage.Content, "images") && storage.Active == 1 {
+		available := strings.Contains(storage.Content, "images") && storage.Active == 1
+		if config.Storage == "" && available {
 			return storage.Storage, nil
 		}
+		// the storage of a pinned qemu is checked since the node is not filtered
+		if config.Storage == storage.Storage {
+			if !available {
+				return "", fmt.Errorf("storage %s is not available for VM image on node %s", config.Storage, nodeName)
+			}
+			return config.Storage, nil
+		}
+	}
+	if config.Storage != "" {
+		return "", fmt.Errorf("storage %s does not exist on node %s", config.Storage, nodeName)
 	}
 
 	return "", fmt.Errorf("no storage available for VM image on node %s", nodeName)
@@ -368,6 +489,7 @@ func (s *Scheduler) RunFilterPlugins(ctx context.Context, state *framework.Cycle
 			status = pl.Filter(ctx, state, config, nodeInfo)
 			if !status.IsSuccess() {
 				status.SetFailedPlugin(pl.Name())
+				state.Reject(pl.Name())
 				break
 			}
 		}
@@ -405,12 +527,14 @@ func (s *Scheduler) RunScorePlugins(ctx context.Context, state *framework.CycleS
 			}
 		}
 	}
+	recordScores(s.id, scoresMap)
 	result := make(map[string]framework.NodeScore)
 	for _, node := range nodes {
 		result[node.Node] = framework.NodeScore{Name: node.Node, Score: 0}
 		for plugin := range scoresMap {
 			r := result[node.Node]
 			r.Score += scoresMap[plugin][node.Node].Score
+			result[node.Node] = r
 		}
 	}
 	return result, status
@@ -429,6 +553,24 @@ func selectHighestScoreNode(scoreList map[string]framework.NodeScore) (string, e
 	return selectedScore.Name, nil
 }
 
+// returns scores in descending order. the selected node comes first among ties
+func sortedScores(scoreList map[string]framework.NodeScore, selected string) []framework.NodeScore {
+	scores := make([]framework.NodeScore, 0, len(scoreList))
+	for _, score := range scoreList {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		if scores[i].Name == selected || scores[j].Name == selected {
+			return scores[i].Name == selected
+		}
+		return scores[i].Name < scores[j].Name
+	})
+	return scores
+}
+
 func (s *Scheduler) RunVMIDPlugins(ctx context.Context, state *framework.CycleState, config api.VirtualMachineCreateOptions, nextid int, usedID map[int]bool) (int, error) {
 	for _, pl := range s.registry.VMIDPlugins() {
 		key := pl.PluginKey()
@@ -442,15 +584,15 @@ func (s *Scheduler) RunVMIDPlugins(ctx context.Context, state *framework.CycleSt
 	return nextid, nil
 }
 
-// return map[vmid]bool
+// return map[vmid]bool. vmids are shared by qemus and containers
 func usedIDMap(ctx context.Context, client *proxmox.Service) (*map[int]bool, error) {
-	vms, err := client.VirtualMachines(ctx)
+	guests, err := guest.List(ctx, client)
 	if err != nil {
 		return nil, err
 	}
 	result := make(map[int]bool)
-	for _, vm := range vms {
-		result[vm.VMID] = true
+	for _, g := range guests {
+		result[g.VMID] = true
 	}
 	return &result, nil
 }
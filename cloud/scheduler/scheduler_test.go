@@ -2,11 +2,13 @@ package scheduler_test
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/k8s-proxmox/proxmox-go/api"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler"
@@ -120,3 +122,62 @@ var _ = Describe("CreateQEMU", Label("integration"), func() {
 		})
 	})
 })
+
+var _ = Describe("FitError", Label("unit", "scheduler"), func() {
+	It("should count rejections per plugin", func() {
+		err := &scheduler.FitError{NumAllNodes: 3, Rejections: map[string]int{"NodeName": 1, "CPU": 2}}
+		Expect(err.Error()).To(Equal("no nodes available to schedule qemus: 0/3 nodes are available: 2 rejected by CPU, 1 rejected by NodeName"))
+		Expect(errors.Is(err, scheduler.ErrNoNodesAvailable)).To(BeTrue())
+	})
+})
+
+var _ = Describe("selectPinnedNode", Label("unit", "scheduler"), func() {
+	nodes := []*api.Node{{Node: "pve1", Status: "online"}, {Node: "pve2", Status: "offline"}}
+
+	It("should select the pinned node", func() {
+		Expect(scheduler.SelectPinnedNode(nodes, "pve1")).To(Equal("pve1"))
+	})
+
+	It("should fail if the pinned node is offline or missing", func() {
+		_, err := scheduler.SelectPinnedNode(nodes, "pve2")
+		Expect(err).To(MatchError("pinned node pve2 is offline"))
+		_, err = scheduler.SelectPinnedNode(nodes, "pve3")
+		Expect(err).To(MatchError("pinned node pve3 does not exist"))
+	})
+})
+
+var _ = Describe("sortedScores", Label("unit", "scheduler"), func() {
+	It("should sort scores in descending order with the selected node first among ties", func() {
+		scores := map[string]framework.NodeScore{
+			"pve1": {Name: "pve1", Score: 50},
+			"pve2": {Name: "pve2", Score: 80},
+			"pve3": {Name: "pve3", Score: 80},
+			"pve4": {Name: "pve4", Score: 10},
+		}
+		Expect(scheduler.SortedScores(scores, "pve3")).To(Equal([]framework.NodeScore{
+			{Name: "pve3", Score: 80},
+			{Name: "pve2", Score: 80},
+			{Name: "pve1", Score: 50},
+			{Name: "pve4", Score: 10},
+		}))
+	})
+})
+
+var _ = Describe("recordScores", Label("unit", "scheduler"), func() {
+	It("should replace the scores of the scheduler", func() {
+		scheduler.RecordScores("192.168.0.1", map[string]map[string]framework.NodeScore{
+			"NodeResource": {"pve1": {Name: "pve1", Score: 50}, "pve2": {Name: "pve2", Score: 80}},
+		})
+		scheduler.RecordScores("192.168.1.1", map[string]map[string]framework.NodeScore{
+			"NodeResource": {"pve1": {Name: "pve1", Score: 10}},
+		})
+		scheduler.RecordScores("192.168.0.1", map[string]map[string]framework.NodeScore{
+			"NodeResource": {"pve1": {Name: "pve1", Score: 60}},
+			"Random":       {"pve1": {Name: "pve1", Score: 5}},
+		})
+		Expect(testutil.ToFloat64(scheduler.NodeScore().WithLabelValues("192.168.0.1", "pve1", "NodeResource"))).To(Equal(60.0))
+		Expect(testutil.ToFloat64(scheduler.NodeScore().WithLabelValues("192.168.0.1", "pve1", "Random"))).To(Equal(5.0))
+		Expect(testutil.ToFloat64(scheduler.NodeScore().WithLabelValues("192.168.1.1", "pve1", "NodeResource"))).To(Equal(10.0))
+		Expect(testutil.CollectAndCount(scheduler.NodeScore())).To(Equal(3))
+	})
+})
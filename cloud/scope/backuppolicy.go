@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"context"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/pkg/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+type BackupPolicyScopeParams struct {
+	Client              client.Client
+	ProxmoxBackupPolicy *infrav1.ProxmoxBackupPolicy
+	ClusterGetter       *ClusterScope
+	// vmids of the machines selected by the policy
+	VMIDs []int
+}
+
+func NewBackupPolicyScope(params BackupPolicyScopeParams) (*BackupPolicyScope, error) {
+	if params.Client == nil {
+		return nil, errors.New("client is required when creating a BackupPolicyScope")
+	}
+	if params.ProxmoxBackupPolicy == nil {
+		return nil, errors.New("failed to generate new scope from nil ProxmoxBackupPolicy")
+	}
+	if params.ClusterGetter == nil {
+		return nil, errors.New("failed to generate new scope form nil ClusterScope")
+	}
+
+	helper, err := patch.NewHelper(params.ProxmoxBackupPolicy, params.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init patch helper")
+	}
+
+	return &BackupPolicyScope{
+		client:              params.Client,
+		patchHelper:         helper,
+		ProxmoxBackupPolicy: params.ProxmoxBackupPolicy,
+		ClusterGetter:       params.ClusterGetter,
+		vmids:               params.VMIDs,
+	}, nil
+}
+
+type BackupPolicyScope struct {
+	client              client.Client
+	patchHelper         *patch.Helper
+	ProxmoxBackupPolicy *infrav1.ProxmoxBackupPolicy
+	ClusterGetter       *ClusterScope
+	vmids               []int
+}
+
+func (s *BackupPolicyScope) CloudClient() *proxmox.Service {
+	return s.ClusterGetter.CloudClient()
+}
+
+func (s *BackupPolicyScope) Name() string {
+	return s.ProxmoxBackupPolicy.Name
+}
+
+func (s *BackupPolicyScope) Namespace() string {
+	return s.ProxmoxBackupPolicy.Namespace
+}
+
+func (s *BackupPolicyScope) UID() string {
+	return string(s.ProxmoxBackupPolicy.UID)
+}
+
+func (s *BackupPolicyScope) GetSpec() infrav1.ProxmoxBackupPolicySpec {
+	return s.ProxmoxBackupPolicy.Spec
+}
+
+// VMIDs returns vmids of the machines selected by the policy
+func (s *BackupPolicyScope) VMIDs() []int {
+	return s.vmids
+}
+
+func (s *BackupPolicyScope) GetJobID() string {
+	return s.ProxmoxBackupPolicy.Status.JobID
+}
+
+func (s *BackupPolicyScope) SetJobID(id string) {
+	s.ProxmoxBackupPolicy.Status.JobID = id
+}
+
+func (s *BackupPolicyScope) SetVMIDs(vmids []int) {
+	s.ProxmoxBackupPolicy.Status.VMIDs = vmids
+}
+
+func (s *BackupPolicyScope) SetReady(v bool) {
+	s.ProxmoxBackupPolicy.Status.Ready = v
+}
+
+func (s *BackupPolicyScope) SetFailureMessage(v error) {
+	s.ProxmoxBackupPolicy.Status.FailureMessage = ptr.To(v.Error())
+}
+
+func (s *BackupPolicyScope) Close() error {
+	return s.PatchObject()
+}
+
+// PatchObject persists the backup policy configuration and status.
+func (s *BackupPolicyScope) PatchObject() error {
+	return s.patchHelper.Patch(context.TODO(), s.ProxmoxBackupPolicy)
+}
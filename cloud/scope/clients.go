@@ -18,45 +18,102 @@ package scope
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/cluster-api/util"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/logging"
 )
 
+// proxmox services per ProxmoxCluster, endpoint and credentials, so that
+// the audit log tells which cluster's credentials have made a call
+var services sync.Map
+
 type ProxmoxServices struct {
 	Compute *proxmox.Service
 }
 
 func newComputeService(ctx context.Context, cluster *infrav1.ProxmoxCluster, crClient client.Client) (*proxmox.Service, error) {
-	serverRef := cluster.Spec.ServerRef
-	secretRef := serverRef.SecretRef
-	if secretRef == nil {
-		return nil, errors.New("failed to get proxmox client from nil secretRef")
+	secret, err := serverSecret(ctx, cluster, crClient)
+	if err != nil {
+		return nil, err
 	}
 
-	var secret corev1.Secret
-	key := client.ObjectKey{Namespace: secretRef.Namespace, Name: secretRef.Name}
-	if err := crClient.Get(ctx, key, &secret); err != nil {
-		return nil, fmt.Errorf("failed to get secret from secretRef: %w", err)
+	// owner references can not cross namespaces. shared secrets are left to their owner
+	if secret.Namespace != cluster.Namespace {
+		return computeService(cluster, secret)
 	}
-
 	secret.SetOwnerReferences(util.EnsureOwnerRef(secret.OwnerReferences, metav1.OwnerReference{
 		APIVersion: infrav1.GroupVersion.String(),
 		Kind:       "ProxmoxCluster",
 		Name:       cluster.Name,
 		UID:        cluster.UID,
 	}))
-	if err := crClient.Update(ctx, &secret); err != nil {
+	if err := crClient.Update(ctx, secret); err != nil {
 		return nil, fmt.Errorf("failed to set ownerReference to secret: %w", err)
 	}
+	return computeService(cluster, secret)
+}
+
+// NewComputeService returns the proxmox client of the ProxmoxCluster without setting the ownerReference
+// of the credentials secret, for tools not reconciling the cluster, e.g. the kubectl plugin
+func NewComputeService(ctx context.Context, cluster *infrav1.ProxmoxCluster, reader client.Reader) (*proxmox.Service, error) {
+	populateNamespace(cluster)
+	secret, err := serverSecret(ctx, cluster, reader)
+	if err != nil {
+		return nil, err
+	}
+	return computeService(cluster, secret)
+}
+
+// returns the secret holding the credentials of the proxmox api
+func serverSecret(ctx context.Context, cluster *infrav1.ProxmoxCluster, reader client.Reader) (*corev1.Secret, error) {
+	secretRef := cluster.Spec.ServerRef.SecretRef
+	if secretRef == nil {
+		return nil, errors.New("failed to get proxmox client from nil secretRef")
+	}
+
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: secretRef.Namespace, Name: secretRef.Name}
+	if err := reader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get secret from secretRef: %w", err)
+	}
+	if !secretAllowedFor(&secret, cluster.Namespace) {
+		return nil, fmt.Errorf("secret %s/%s is not allowed for namespace %s: add it to the %s annotation of the secret",
+			secret.Namespace, secret.Name, cluster.Namespace, infrav1.AllowedNamespacesAnnotation)
+	}
+	return &secret, nil
+}
+
+// secrets of other namespaces must allow the namespace explicitly,
+// so that tenants can not borrow the proxmox credentials of other teams
+func secretAllowedFor(secret *corev1.Secret, namespace string) bool {
+	if secret.Namespace == namespace {
+		return true
+	}
+	for _, allowed := range strings.Split(secret.Annotations[infrav1.AllowedNamespacesAnnotation], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
 
+func computeService(cluster *infrav1.ProxmoxCluster, secret *corev1.Secret) (*proxmox.Service, error) {
+	endpoint := cluster.Spec.ServerRef.Endpoint
 	authConfig := proxmox.AuthConfig{
 		Username: string(secret.Data["PROXMOX_USER"]),
 		Password: string(secret.Data["PROXMOX_PASSWORD"]),
@@ -66,6 +123,26 @@ func newComputeService(ctx context.Context, cluster *infrav1.ProxmoxCluster, crC
 	clientConfig := proxmox.ClientConfig{
 		InsecureSkipVerify: true,
 	}
-	param := proxmox.NewParams(serverRef.Endpoint, authConfig, clientConfig)
-	return proxmox.GetOrCreateService(param)
+	user := authConfig.TokenID
+	if user == "" {
+		user = authConfig.Username
+	}
+	key := serviceKey(cluster, endpoint, user, authConfig.Password+authConfig.Secret)
+	if svc, ok := services.Load(key); ok {
+		return svc.(*proxmox.Service), nil
+	}
+	svc, err := proxmox.NewService(proxmox.NewParams(endpoint, authConfig, clientConfig))
+	if err != nil {
+		return nil, err
+	}
+	svc.RESTClient().SetLogger(logging.Logger(ctrl.Log.WithName("proxmox-client"), logging.Client))
+	actual, loaded := services.LoadOrStore(key, svc)
+	if !loaded {
+		audit.Register(svc, "proxmoxCluster", klog.KObj(cluster), "endpoint", endpoint, "user", user)
+	}
+	return actual.(*proxmox.Service), nil
+}
+
+func serviceKey(cluster *infrav1.ProxmoxCluster, endpoint, user, secret string) string {
+	return fmt.Sprintf("%s/%s#%s#%s#%x", cluster.Namespace, cluster.Name, endpoint, user, sha256.Sum256([]byte(secret)))
 }
@@ -49,6 +49,7 @@ var _ = Describe("newComputeService", Label("unit", "scope"), func() {
 				Namespace: "default",
 				Name:      "foo",
 			}
+			cluster.SetNamespace("default")
 			cluster.SetName("foo")
 			cluster.SetUID("bar")
 			secret := &corev1.Secret{}
@@ -64,4 +65,74 @@ var _ = Describe("newComputeService", Label("unit", "scope"), func() {
 			Expect(svc).To(BeNil())
 		})
 	})
+
+	Context("When Secret is in another namespace", func() {
+		BeforeEach(func() {
+			cluster = &infrav1.ProxmoxCluster{}
+			cluster.SetNamespace("team-a")
+			cluster.SetName("foo")
+			cluster.Spec.ServerRef.SecretRef = &infrav1.ObjectReference{
+				Namespace: "default",
+				Name:      "shared",
+			}
+		})
+
+		It("should refuse a secret not allowing the namespace", func() {
+			secret := &corev1.Secret{}
+			secret.SetNamespace("default")
+			secret.SetName("shared")
+			secret.SetAnnotations(map[string]string{infrav1.AllowedNamespacesAnnotation: "team-b"})
+			Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+
+			_, err := serverSecret(context.TODO(), cluster, k8sClient)
+			Expect(err).To(MatchError(ContainSubstring("secret default/shared is not allowed for namespace team-a")))
+		})
+
+		// the annotation is enforced on every reconciliation, not on admission
+		It("should cut off a cluster already using the secret once the namespace is no longer allowed", func() {
+			cluster.Spec.ServerRef.Endpoint = "https://pve.example.com:8006/api2/json"
+			cluster.Spec.ServerRef.SecretRef.Name = "revoked"
+			secret := &corev1.Secret{}
+			secret.SetNamespace("default")
+			secret.SetName("revoked")
+			secret.SetAnnotations(map[string]string{infrav1.AllowedNamespacesAnnotation: "team-a"})
+			secret.Data = map[string][]byte{"PROXMOX_TOKENID": []byte("cappx@pve!token"), "PROXMOX_SECRET": []byte("secret")}
+			Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+
+			svc, err := newComputeService(context.TODO(), cluster, k8sClient)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(svc).NotTo(BeNil())
+
+			secret.SetAnnotations(nil)
+			Expect(k8sClient.Update(context.TODO(), secret)).To(Succeed())
+			svc, err = newComputeService(context.TODO(), cluster, k8sClient)
+			Expect(err).To(MatchError(ContainSubstring("secret default/revoked is not allowed for namespace team-a")))
+			Expect(svc).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("secretAllowedFor", Label("unit", "scope"), func() {
+	secret := func(annotation string) *corev1.Secret {
+		s := &corev1.Secret{}
+		s.SetNamespace("default")
+		if annotation != "" {
+			s.SetAnnotations(map[string]string{infrav1.AllowedNamespacesAnnotation: annotation})
+		}
+		return s
+	}
+
+	It("should allow its own namespace", func() {
+		Expect(secretAllowedFor(secret(""), "default")).To(BeTrue())
+	})
+
+	It("should allow listed namespaces only", func() {
+		Expect(secretAllowedFor(secret(""), "team-a")).To(BeFalse())
+		Expect(secretAllowedFor(secret("team-a, team-b"), "team-b")).To(BeTrue())
+		Expect(secretAllowedFor(secret("team-a"), "team-c")).To(BeFalse())
+	})
+
+	It("should allow any namespace with *", func() {
+		Expect(secretAllowedFor(secret("*"), "team-c")).To(BeTrue())
+	})
 })
@@ -19,10 +19,14 @@ package scope
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -69,8 +73,8 @@ func NewClusterScope(ctx context.Context, params ClusterScopeParams) (*ClusterSc
 }
 
 func populateNamespace(proxmoxCluster *infrav1.ProxmoxCluster) {
-	if proxmoxCluster.Spec.ServerRef.SecretRef.Namespace == "" {
-		proxmoxCluster.Spec.ServerRef.SecretRef.Namespace = proxmoxCluster.Namespace
+	if ref := proxmoxCluster.Spec.ServerRef.SecretRef; ref != nil && ref.Namespace == "" {
+		ref.Namespace = proxmoxCluster.Namespace
 	}
 }
 
@@ -95,6 +99,11 @@ func (s *ClusterScope) ControlPlaneEndpoint() clusterv1.APIEndpoint {
 }
 
 // return default values if they are not specified
+// ServerEndpoint returns the url of the proxmox api
+func (s *ClusterScope) ServerEndpoint() string {
+	return s.ProxmoxCluster.Spec.ServerRef.Endpoint
+}
+
 func (s *ClusterScope) Storage() infrav1.Storage {
 	if s.ProxmoxCluster.Spec.Storage.Name == "" {
 		s.ProxmoxCluster.Spec.Storage.Name = fmt.Sprintf("local-dir-%s", s.Name())
@@ -105,11 +114,148 @@ func (s *ClusterScope) Storage() infrav1.Storage {
 	return s.ProxmoxCluster.Spec.Storage
 }
 
+func (s *ClusterScope) PoolSpec() *infrav1.ResourcePool {
+	return s.ProxmoxCluster.Spec.Pool
+}
+
+// Pool returns the resource pool once it has been reconciled
+func (s *ClusterScope) Pool() string {
+	return s.ProxmoxCluster.Status.Pool
+}
+
+func (s *ClusterScope) TagMappings() []infrav1.TagMapping {
+	return s.ProxmoxCluster.Spec.TagMappings
+}
+
+// DNS returns the default dns configuration of the machines, or nil if the cluster has none
+func (s *ClusterScope) DNS() *infrav1.DNS {
+	return s.ProxmoxCluster.Spec.DNS
+}
+
+// Proxy returns the proxy configuration of the machines, or nil if the cluster has none.
+// NoProxy is completed by localhost, the control plane endpoint and the pod and service CIDRs of the cluster
+func (s *ClusterScope) Proxy() *infrav1.Proxy {
+	if s.ProxmoxCluster.Spec.Proxy == nil {
+		return nil
+	}
+	proxy := *s.ProxmoxCluster.Spec.Proxy
+	noProxy := []string{"localhost", "127.0.0.1"}
+	if host := s.ProxmoxCluster.Spec.ControlPlaneEndpoint.Host; host != "" {
+		noProxy = append(noProxy, host)
+	}
+	if network := s.Cluster.Spec.ClusterNetwork; network != nil {
+		if network.Pods != nil {
+			noProxy = append(noProxy, network.Pods.CIDRBlocks...)
+		}
+		if network.Services != nil {
+			noProxy = append(noProxy, network.Services.CIDRBlocks...)
+		}
+		if network.ServiceDomain != "" {
+			noProxy = append(noProxy, "."+network.ServiceDomain)
+		}
+	}
+	for _, host := range noProxy {
+		if !slices.Contains(proxy.NoProxy, host) {
+			proxy.NoProxy = append(proxy.NoProxy, host)
+		}
+	}
+	return &proxy
+}
+
+// Network returns the network of the name, or nil if the cluster has none
+func (s *ClusterScope) Network(name string) *infrav1.ClusterNetwork {
+	for i, n := range s.ProxmoxCluster.Spec.Networks {
+		if n.Name == name {
+			return &s.ProxmoxCluster.Spec.Networks[i]
+		}
+	}
+	return nil
+}
+
+func (s *ClusterScope) NodeGroup(name string) *infrav1.NodeGroup {
+	for i, g := range s.ProxmoxCluster.Spec.NodeGroups {
+		if g.Name == name {
+			return &s.ProxmoxCluster.Spec.NodeGroups[i]
+		}
+	}
+	return nil
+}
+
+func (s *ClusterScope) MachineTimeouts() *infrav1.ProvisioningTimeouts {
+	return s.ProxmoxCluster.Spec.MachineTimeouts
+}
+
+func (s *ClusterScope) MachineDefaults() *infrav1.MachineDefaults {
+	return s.ProxmoxCluster.Spec.MachineDefaults
+}
+
+func (s *ClusterScope) ReservedVMIDs() string {
+	return s.ProxmoxCluster.Spec.ReservedVMIDs
+}
+
+// ProviderIDPolicy returns the format of the provider ids of the machines. defaults to UUID
+func (s *ClusterScope) ProviderIDPolicy() infrav1.ProviderIDPolicy {
+	if s.ProxmoxCluster.Spec.ProviderID == nil {
+		return infrav1.ProviderIDPolicy{Format: infrav1.ProviderIDFormatUUID}
+	}
+	return *s.ProxmoxCluster.Spec.ProviderID
+}
+
+func (s *ClusterScope) VMTags() infrav1.Tags {
+	return s.ProxmoxCluster.Spec.VMTags
+}
+
+func (s *ClusterScope) TaskPolicy() *infrav1.TaskPolicy {
+	return s.ProxmoxCluster.Spec.Tasks
+}
+
+func (s *ClusterScope) NodeFailurePolicy() *infrav1.NodeFailurePolicy {
+	return s.ProxmoxCluster.Spec.NodeFailure
+}
+
+// ConfirmedDownNodes returns nodes confirmed down by the operator
+func (s *ClusterScope) ConfirmedDownNodes() []string {
+	nodes := []string{}
+	for _, node := range strings.Split(s.ProxmoxCluster.Annotations[infrav1.NodesDownAnnotation], ",") {
+		if node = strings.TrimSpace(node); node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (s *ClusterScope) OfflineNodes() []infrav1.OfflineNode {
+	return s.ProxmoxCluster.Status.OfflineNodes
+}
+
+func (s *ClusterScope) DownNodes() []string {
+	return s.ProxmoxCluster.Status.DownNodes
+}
+
+func (s *ClusterScope) OrphanedVMs() []infrav1.OrphanedVM {
+	return s.ProxmoxCluster.Status.OrphanedVMs
+}
+
+func (s *ClusterScope) ProxmoxVersion() string {
+	return s.ProxmoxCluster.Status.ProxmoxVersion
+}
+
+// Quorate returns false while the proxmox cluster has lost quorum or has nodes in unknown state
+func (s *ClusterScope) Quorate() bool {
+	return !conditions.IsFalse(s.ProxmoxCluster, infrav1.QuorateCondition)
+}
+
+// QuorumMessage returns why the proxmox cluster is not quorate
+func (s *ClusterScope) QuorumMessage() string {
+	return conditions.GetMessage(s.ProxmoxCluster, infrav1.QuorateCondition)
+}
+
 func (s *ClusterScope) CloudClient() *proxmox.Service {
 	return s.ProxmoxServices.Compute
 }
 
 func (s *ClusterScope) Close() error {
+	s.setV1Beta2Status()
 	return s.PatchObject()
 }
 
@@ -121,6 +267,50 @@ func (s *ClusterScope) SetControlPlaneEndpoint(endpoint clusterv1.APIEndpoint) {
 	s.ProxmoxCluster.Spec.ControlPlaneEndpoint = endpoint
 }
 
+func (s *ClusterScope) SetLastRebalanceTime(t metav1.Time) {
+	s.ProxmoxCluster.Status.LastRebalanceTime = &t
+}
+
+func (s *ClusterScope) SetLastOrphanCheckTime(t metav1.Time) {
+	s.ProxmoxCluster.Status.LastOrphanCheckTime = &t
+}
+
+func (s *ClusterScope) SetOrphanedVMs(vms []infrav1.OrphanedVM) {
+	s.ProxmoxCluster.Status.OrphanedVMs = vms
+}
+
+func (s *ClusterScope) SetOfflineNodes(nodes []infrav1.OfflineNode) {
+	s.ProxmoxCluster.Status.OfflineNodes = nodes
+}
+
+func (s *ClusterScope) SetDownNodes(nodes []string) {
+	s.ProxmoxCluster.Status.DownNodes = nodes
+}
+
+func (s *ClusterScope) SetPool(name string) {
+	s.ProxmoxCluster.Status.Pool = name
+}
+
+func (s *ClusterScope) SetProxmoxVersion(version string) {
+	s.ProxmoxCluster.Status.ProxmoxVersion = version
+}
+
+func (s *ClusterScope) SetVersionSupported() {
+	conditions.MarkTrue(s.ProxmoxCluster, infrav1.ProxmoxVersionSupportedCondition)
+}
+
+func (s *ClusterScope) SetVersionUnsupported(message string) {
+	conditions.MarkFalse(s.ProxmoxCluster, infrav1.ProxmoxVersionSupportedCondition, infrav1.UnsupportedVersionReason, clusterv1.ConditionSeverityError, "%s", message)
+}
+
+func (s *ClusterScope) SetQuorate() {
+	conditions.MarkTrue(s.ProxmoxCluster, infrav1.QuorateCondition)
+}
+
+func (s *ClusterScope) SetQuorumLost(reason, message string) {
+	conditions.MarkFalse(s.ProxmoxCluster, infrav1.QuorateCondition, reason, clusterv1.ConditionSeverityError, "%s", message)
+}
+
 func (s *ClusterScope) SetStorage(storage infrav1.Storage) {
 	s.ProxmoxCluster.Spec.Storage = storage
 }
@@ -0,0 +1,119 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// V1Beta2Object is an object having the conditions of the v1beta2 contract of Cluster API
+type V1Beta2Object interface {
+	client.Object
+	GetV1Beta2Conditions() []metav1.Condition
+	SetV1Beta2Conditions([]metav1.Condition)
+}
+
+// EnsurePausedCondition sets the Paused condition of the object and patches it if the condition has changed,
+// so that paused objects report it although they are not reconciled. kind names the object in the message since
+// typed objects read from the api server have no kind. returns true if the object or its Cluster is paused
+func EnsurePausedCondition(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, obj V1Beta2Object, kind string) (bool, error) {
+	paused := annotations.IsPaused(cluster, obj)
+	condition := pausedCondition(cluster, obj, kind, paused)
+	if current := meta.FindStatusCondition(obj.GetV1Beta2Conditions(), condition.Type); current != nil &&
+		current.Status == condition.Status && current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return paused, nil
+	}
+	helper, err := patch.NewHelper(obj, c)
+	if err != nil {
+		return paused, err
+	}
+	setV1Beta2Condition(obj, condition)
+	return paused, helper.Patch(ctx, obj)
+}
+
+func pausedCondition(cluster *clusterv1.Cluster, obj V1Beta2Object, kind string, paused bool) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               infrav1.PausedV1Beta2Condition,
+		Status:             metav1.ConditionFalse,
+		Reason:             infrav1.NotPausedV1Beta2Reason,
+		ObservedGeneration: obj.GetGeneration(),
+	}
+	if !paused {
+		return condition
+	}
+	condition.Status, condition.Reason = metav1.ConditionTrue, infrav1.PausedV1Beta2Reason
+	if cluster.Spec.Paused {
+		condition.Message = "Cluster spec.paused is set to true"
+	} else {
+		condition.Message = fmt.Sprintf("%s has the %s annotation", kind, clusterv1.PausedAnnotation)
+	}
+	return condition
+}
+
+func setV1Beta2Condition(obj V1Beta2Object, condition metav1.Condition) {
+	conditions := obj.GetV1Beta2Conditions()
+	meta.SetStatusCondition(&conditions, condition)
+	obj.SetV1Beta2Conditions(conditions)
+}
+
+// readyCondition returns the Ready condition following status.ready, so that tools reading
+// the v1beta2 contract see the same state as the ones reading the v1beta1 contract
+func readyCondition(obj client.Object, ready bool, notReadyMessage string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               infrav1.ReadyV1Beta2Condition,
+		Status:             metav1.ConditionFalse,
+		Reason:             infrav1.NotReadyV1Beta2Reason,
+		Message:            notReadyMessage,
+		ObservedGeneration: obj.GetGeneration(),
+	}
+	switch {
+	case !obj.GetDeletionTimestamp().IsZero():
+		condition.Reason, condition.Message = infrav1.DeletingV1Beta2Reason, "deletion is in progress"
+	case ready:
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionTrue, infrav1.ReadyV1Beta2Reason, ""
+	}
+	return condition
+}
+
+// sets the status of the v1beta2 contract from the one of the v1beta1 contract
+func (s *ClusterScope) setV1Beta2Status() {
+	c := s.ProxmoxCluster
+	if c.Status.Ready {
+		c.Status.Initialization = &infrav1.ProxmoxClusterInitializationStatus{Provisioned: ptr.To(true)}
+	}
+	message := "waiting for the infrastructure of the cluster to be reconciled"
+	switch {
+	case !s.Quorate():
+		message = s.QuorumMessage()
+	case c.Spec.ControlPlaneEndpoint.Host == "" && s.ExternalControlPlane():
+		message = "waiting for the kubeconfig Secret of the external control plane"
+	case c.Spec.ControlPlaneEndpoint.Host == "":
+		message = "waiting for control-plane endpoint"
+	}
+	setV1Beta2Condition(c, readyCondition(c, c.Status.Ready, message))
+}
+
+// sets the status of the v1beta2 contract from the one of the v1beta1 contract
+func (m *MachineScope) setV1Beta2Status() {
+	pm := m.ProxmoxMachine
+	if pm.Status.Ready {
+		pm.Status.Initialization = &infrav1.ProxmoxMachineInitializationStatus{Provisioned: ptr.To(true)}
+	}
+	message := "waiting for the instance to be created"
+	switch {
+	case pm.Status.FailureMessage != nil:
+		message = *pm.Status.FailureMessage
+	case pm.Status.InstanceStatus != nil:
+		message = fmt.Sprintf("instance is %s", *pm.Status.InstanceStatus)
+	}
+	setV1Beta2Condition(pm, readyCondition(pm, pm.Status.Ready, message))
+}
@@ -0,0 +1,81 @@
+package scope
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+var _ = Describe("V1Beta2Status", Label("unit", "scope"), func() {
+	It("should set the Ready condition of a cluster from status.ready", func() {
+		cluster := &infrav1.ProxmoxCluster{}
+		cluster.Generation = 2
+		s := &ClusterScope{ProxmoxCluster: cluster}
+
+		s.setV1Beta2Status()
+		ready := meta.FindStatusCondition(cluster.GetV1Beta2Conditions(), infrav1.ReadyV1Beta2Condition)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(infrav1.NotReadyV1Beta2Reason))
+		Expect(ready.Message).To(Equal("waiting for control-plane endpoint"))
+		Expect(ready.ObservedGeneration).To(Equal(int64(2)))
+		Expect(cluster.Status.Initialization).To(BeNil())
+
+		conditions.MarkFalse(cluster, infrav1.QuorateCondition, infrav1.QuorumLostReason, clusterv1.ConditionSeverityError, "node pve2 is offline")
+		s.setV1Beta2Status()
+		ready = meta.FindStatusCondition(cluster.GetV1Beta2Conditions(), infrav1.ReadyV1Beta2Condition)
+		Expect(ready.Message).To(Equal("node pve2 is offline"))
+
+		conditions.MarkTrue(cluster, infrav1.QuorateCondition)
+		cluster.Status.Ready = true
+		s.setV1Beta2Status()
+		ready = meta.FindStatusCondition(cluster.GetV1Beta2Conditions(), infrav1.ReadyV1Beta2Condition)
+		Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+		Expect(ready.Reason).To(Equal(infrav1.ReadyV1Beta2Reason))
+		Expect(cluster.Status.Initialization.Provisioned).To(Equal(ptr.To(true)))
+	})
+
+	It("should set the Ready condition of a machine from its instance", func() {
+		machine := &infrav1.ProxmoxMachine{}
+		m := &MachineScope{ProxmoxMachine: machine}
+
+		m.setV1Beta2Status()
+		ready := meta.FindStatusCondition(machine.GetV1Beta2Conditions(), infrav1.ReadyV1Beta2Condition)
+		Expect(ready.Message).To(Equal("waiting for the instance to be created"))
+
+		m.SetInstanceStatus(infrav1.InstanceStatusStopped)
+		m.setV1Beta2Status()
+		ready = meta.FindStatusCondition(machine.GetV1Beta2Conditions(), infrav1.ReadyV1Beta2Condition)
+		Expect(ready.Message).To(Equal("instance is stopped"))
+
+		machine.Status.Ready = true
+		now := metav1.Now()
+		machine.DeletionTimestamp = &now
+		m.setV1Beta2Status()
+		ready = meta.FindStatusCondition(machine.GetV1Beta2Conditions(), infrav1.ReadyV1Beta2Condition)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(infrav1.DeletingV1Beta2Reason))
+		Expect(machine.Status.Initialization.Provisioned).To(Equal(ptr.To(true)))
+	})
+
+	It("should report why a machine is paused", func() {
+		cluster := &clusterv1.Cluster{}
+		machine := &infrav1.ProxmoxMachine{}
+
+		Expect(pausedCondition(cluster, machine, "ProxmoxMachine", false).Status).To(Equal(metav1.ConditionFalse))
+
+		machine.Annotations = map[string]string{clusterv1.PausedAnnotation: ""}
+		paused := pausedCondition(cluster, machine, "ProxmoxMachine", true)
+		Expect(paused.Status).To(Equal(metav1.ConditionTrue))
+		Expect(paused.Reason).To(Equal(infrav1.PausedV1Beta2Reason))
+		Expect(paused.Message).To(Equal("ProxmoxMachine has the cluster.x-k8s.io/paused annotation"))
+
+		cluster.Spec.Paused = true
+		Expect(pausedCondition(cluster, machine, "ProxmoxMachine", true).Message).To(Equal("Cluster spec.paused is set to true"))
+	})
+})
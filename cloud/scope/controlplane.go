@@ -0,0 +1,76 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ExternalControlPlane returns true if the control plane of the cluster runs outside of Proxmox
+func (s *ClusterScope) ExternalControlPlane() bool {
+	return s.ProxmoxCluster.Spec.ExternalControlPlane
+}
+
+// ReconcileExternalControlPlaneEndpoint sets the control-plane endpoint of a cluster with an external
+// control plane from the server of its kubeconfig Secret. the endpoint is kept once set, and left empty
+// while the Secret does not exist
+func (s *ClusterScope) ReconcileExternalControlPlaneEndpoint(ctx context.Context) error {
+	if !s.ExternalControlPlane() || s.ControlPlaneEndpoint().Host != "" {
+		return nil
+	}
+	data, err := kubeconfig.FromSecret(ctx, s.client, util.ObjectKey(s.Cluster))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get kubeconfig of the external control plane: %w", err)
+	}
+	endpoint, err := kubeconfigEndpoint(data)
+	if err != nil {
+		return fmt.Errorf("invalid kubeconfig of the external control plane: %w", err)
+	}
+	log.FromContext(ctx).Info("got control-plane endpoint from kubeconfig", "host", endpoint.Host, "port", endpoint.Port)
+	s.SetControlPlaneEndpoint(endpoint)
+	return nil
+}
+
+// returns the endpoint of the server of the current context of the kubeconfig
+func kubeconfigEndpoint(data []byte) (clusterv1.APIEndpoint, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return clusterv1.APIEndpoint{}, err
+	}
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return clusterv1.APIEndpoint{}, fmt.Errorf("current context %q is not found", config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok {
+		return clusterv1.APIEndpoint{}, fmt.Errorf("cluster %q is not found", kubeContext.Cluster)
+	}
+	u, err := url.Parse(cluster.Server)
+	if err != nil {
+		return clusterv1.APIEndpoint{}, err
+	}
+	if u.Hostname() == "" {
+		return clusterv1.APIEndpoint{}, fmt.Errorf("server %q has no host", cluster.Server)
+	}
+	port := 443
+	if p := u.Port(); p != "" {
+		if port, err = strconv.Atoi(p); err != nil {
+			return clusterv1.APIEndpoint{}, err
+		}
+	} else if u.Scheme == "http" {
+		port = 80
+	}
+	// brackets of ipv6 addresses are stripped by Hostname
+	return clusterv1.APIEndpoint{Host: u.Hostname(), Port: int32(port)}, nil
+}
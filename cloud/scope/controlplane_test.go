@@ -0,0 +1,86 @@
+package scope
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+func kubeconfigData(server string) []byte {
+	return []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: external
+  cluster:
+    server: ` + server + `
+contexts:
+- name: admin@external
+  context:
+    cluster: external
+    user: admin
+current-context: admin@external
+users:
+- name: admin
+  user:
+    token: foo
+`)
+}
+
+var _ = Describe("kubeconfigEndpoint", Label("unit", "scope"), func() {
+	It("should return the host and port of the server", func() {
+		Expect(kubeconfigEndpoint(kubeconfigData("https://10.0.0.10:6443"))).To(Equal(clusterv1.APIEndpoint{Host: "10.0.0.10", Port: 6443}))
+		Expect(kubeconfigEndpoint(kubeconfigData("https://[fd00::10]:6443"))).To(Equal(clusterv1.APIEndpoint{Host: "fd00::10", Port: 6443}))
+	})
+
+	It("should default the port to the one of the scheme", func() {
+		Expect(kubeconfigEndpoint(kubeconfigData("https://k8s.example.com"))).To(Equal(clusterv1.APIEndpoint{Host: "k8s.example.com", Port: 443}))
+	})
+
+	It("should fail for servers without host", func() {
+		_, err := kubeconfigEndpoint(kubeconfigData("/api"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ReconcileExternalControlPlaneEndpoint", Label("unit", "scope"), func() {
+	newScope := func(name string, external bool) *ClusterScope {
+		return &ClusterScope{
+			client:  k8sClient,
+			Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}},
+			ProxmoxCluster: &infrav1.ProxmoxCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+				Spec:       infrav1.ProxmoxClusterSpec{ExternalControlPlane: external},
+			},
+		}
+	}
+
+	It("should wait for the kubeconfig secret", func() {
+		s := newScope("external-waiting", true)
+		Expect(s.ReconcileExternalControlPlaneEndpoint(context.TODO())).To(Succeed())
+		Expect(s.ControlPlaneEndpoint().Host).To(BeEmpty())
+	})
+
+	It("should take the endpoint from the kubeconfig secret", func() {
+		s := newScope("external", true)
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "external-kubeconfig"},
+			Data:       map[string][]byte{"value": kubeconfigData("https://10.0.0.10:6443")},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		Expect(s.ReconcileExternalControlPlaneEndpoint(context.TODO())).To(Succeed())
+		Expect(s.ControlPlaneEndpoint()).To(Equal(clusterv1.APIEndpoint{Host: "10.0.0.10", Port: 6443}))
+	})
+
+	It("should leave the endpoint of other clusters alone", func() {
+		s := newScope("internal", false)
+		Expect(s.ReconcileExternalControlPlaneEndpoint(context.TODO())).To(Succeed())
+		Expect(s.ControlPlaneEndpoint().Host).To(BeEmpty())
+	})
+})
@@ -0,0 +1,52 @@
+package scope
+
+import (
+	"context"
+	"net/netip"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/firewall"
+)
+
+func (s *ClusterScope) FirewallSpec() *infrav1.ClusterFirewall {
+	return s.ProxmoxCluster.Spec.Firewall
+}
+
+// FirewallGroup returns the security group opening the ports of kubernetes, or empty unless the firewall is managed
+func (s *ClusterScope) FirewallGroup() string {
+	if s.FirewallSpec() == nil {
+		return ""
+	}
+	return firewall.GroupName(*s.FirewallSpec(), s.Namespace(), s.Name())
+}
+
+// MachineAddresses returns the ip addresses reported by the ProxmoxMachines of the cluster
+func (s *ClusterScope) MachineAddresses(ctx context.Context) ([]string, error) {
+	machines := &infrav1.ProxmoxMachineList{}
+	if err := s.client.List(ctx, machines,
+		client.InNamespace(s.Namespace()),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: s.Name()},
+	); err != nil {
+		return nil, err
+	}
+	addresses := []string{}
+	for _, m := range machines.Items {
+		for _, a := range m.Status.Addresses {
+			if a.Type != clusterv1.MachineInternalIP && a.Type != clusterv1.MachineExternalIP {
+				continue
+			}
+			if _, err := netip.ParseAddr(a.Address); err == nil {
+				addresses = append(addresses, a.Address)
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// GetClusterFirewallGroup returns the security group of the cluster attached on top of the ones of the machine
+func (m *MachineScope) GetClusterFirewallGroup() string {
+	return m.ClusterGetter.FirewallGroup()
+}
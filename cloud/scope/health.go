@@ -0,0 +1,128 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+const (
+	// interval between connectivity checks. probes only read the last result
+	// since they time out long before unreachable endpoints do
+	proxmoxCheckInterval = 30 * time.Second
+	proxmoxCheckTimeout  = 10 * time.Second
+)
+
+var errNotChecked = errors.New("proxmox connectivity has not been checked yet")
+
+// ProxmoxChecker checks periodically that the Proxmox API of at least one ProxmoxCluster
+// can be authenticated against. It is healthy while no ProxmoxCluster exists.
+type ProxmoxChecker struct {
+	reader client.Reader
+
+	mu  sync.RWMutex
+	err error
+}
+
+func NewProxmoxChecker(reader client.Reader) *ProxmoxChecker {
+	return &ProxmoxChecker{reader: reader, err: errNotChecked}
+}
+
+// Check is a healthz.Checker returning the result of the last connectivity check
+func (c *ProxmoxChecker) Check(_ *http.Request) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
+
+// Start runs the checks until the context is done
+func (c *ProxmoxChecker) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := c.check(ctx)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Proxmox connectivity check failed")
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.err = err
+	}, proxmoxCheckInterval)
+	return nil
+}
+
+// NeedLeaderElection returns false so that standby replicas report their connectivity too
+func (c *ProxmoxChecker) NeedLeaderElection() bool {
+	return false
+}
+
+// returns nil once an endpoint succeeds. endpoints shared by clusters are checked once
+func (c *ProxmoxChecker) check(ctx context.Context) error {
+	clusters := &infrav1.ProxmoxClusterList{}
+	if err := c.reader.List(ctx, clusters); err != nil {
+		return fmt.Errorf("failed to list ProxmoxClusters: %w", err)
+	}
+	if len(clusters.Items) == 0 {
+		return nil
+	}
+	checked := map[string]bool{}
+	errs := []error{}
+	for i := range clusters.Items {
+		cluster := &clusters.Items[i]
+		populateNamespace(cluster)
+		serverRef := cluster.Spec.ServerRef
+		key := serverRef.Endpoint
+		if serverRef.SecretRef != nil {
+			key = fmt.Sprintf("%s %s/%s", key, serverRef.SecretRef.Namespace, serverRef.SecretRef.Name)
+		}
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+		err := c.checkEndpoint(ctx, cluster)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", serverRef.Endpoint, err))
+	}
+	return fmt.Errorf("no proxmox endpoint is reachable: %w", errors.Join(errs...))
+}
+
+func (c *ProxmoxChecker) checkEndpoint(ctx context.Context, cluster *infrav1.ProxmoxCluster) error {
+	ctx, cancel := context.WithTimeout(ctx, proxmoxCheckTimeout)
+	defer cancel()
+	secret, err := serverSecret(ctx, cluster, c.reader)
+	if err != nil {
+		return err
+	}
+	svc, err := computeService(cluster, secret)
+	if err != nil {
+		return err
+	}
+	// the version is readable by any authenticated user
+	_, err = svc.RESTClient().GetVersion(ctx)
+	return err
+}
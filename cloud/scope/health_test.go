@@ -0,0 +1,77 @@
+package scope
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+var _ = Describe("ProxmoxChecker", Label("unit", "scope"), func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "PVEAPIToken=cappx@pve!token=secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"version":"8.2.4"}}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	cluster := func(name, endpoint string) *infrav1.ProxmoxCluster {
+		c := &infrav1.ProxmoxCluster{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		c.Spec.ServerRef.Endpoint = endpoint
+		c.Spec.ServerRef.SecretRef = &infrav1.ObjectReference{Name: name}
+		return c
+	}
+	secret := func(name, tokenSecret string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data: map[string][]byte{
+				"PROXMOX_TOKENID": []byte("cappx@pve!token"),
+				"PROXMOX_SECRET":  []byte(tokenSecret),
+			},
+		}
+	}
+	checker := func(objects ...client.Object) *ProxmoxChecker {
+		return NewProxmoxChecker(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build())
+	}
+
+	It("should not be healthy before the first check", func() {
+		Expect(checker().Check(nil)).To(MatchError(errNotChecked))
+	})
+
+	It("should be healthy without ProxmoxClusters", func() {
+		Expect(checker().check(context.TODO())).To(Succeed())
+	})
+
+	It("should be healthy if any endpoint can be authenticated against", func() {
+		c := checker(
+			cluster("unauthorized", server.URL), secret("unauthorized", "wrong"),
+			cluster("authorized", server.URL), secret("authorized", "secret"),
+		)
+		Expect(c.check(context.TODO())).To(Succeed())
+	})
+
+	It("should fail if no endpoint can be authenticated against", func() {
+		c := checker(cluster("unauthorized", server.URL), secret("unauthorized", "wrong"), cluster("nosecret", server.URL))
+		err := c.check(context.TODO())
+		Expect(err).To(MatchError(ContainSubstring("no proxmox endpoint is reachable")))
+		Expect(err).To(MatchError(ContainSubstring("failed to get secret from secretRef")))
+	})
+})
@@ -18,16 +18,22 @@ package scope
 
 import (
 	"context"
+	"maps"
+	"slices"
 
 	"github.com/k8s-proxmox/proxmox-go/api"
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
@@ -42,6 +48,8 @@ type MachineScopeParams struct {
 	ProxmoxMachine   *infrav1.ProxmoxMachine
 	ClusterGetter    *ClusterScope
 	SchedulerManager *scheduler.Manager
+	// nodes under maintenance for the cluster
+	CordonedNodes []string
 }
 
 func NewMachineScope(params MachineScopeParams) (*MachineScope, error) {
@@ -73,6 +81,7 @@ func NewMachineScope(params MachineScopeParams) (*MachineScope, error) {
 		patchHelper:      helper,
 		ClusterGetter:    params.ClusterGetter,
 		SchedulerManager: params.SchedulerManager,
+		cordonedNodes:    params.CordonedNodes,
 	}, err
 }
 
@@ -83,6 +92,7 @@ type MachineScope struct {
 	ProxmoxMachine   *infrav1.ProxmoxMachine
 	ClusterGetter    *ClusterScope
 	SchedulerManager *scheduler.Manager
+	cordonedNodes    []string
 }
 
 func (m *MachineScope) CloudClient() *proxmox.Service {
@@ -95,14 +105,51 @@ func (m *MachineScope) GetScheduler(client *proxmox.Service) *scheduler.Schedule
 	return sched
 }
 
-func (m *MachineScope) GetClusterStorage() infrav1.Storage {
+// CordonedNodes returns nodes under maintenance which new vms must not be scheduled to
+func (m *MachineScope) CordonedNodes() []string {
+	return m.cordonedNodes
+}
+
+// PinnedNode returns the node the instance is pinned to, or empty if it is placed by the scheduler
+func (m *MachineScope) PinnedNode() string {
+	return m.ProxmoxMachine.Spec.NodeName
+}
+
+// ReservedVMIDs returns vmids and ranges of vmids which must not be assigned to new vms. e.g. 100,200-299
+func (m *MachineScope) ReservedVMIDs() string {
+	return m.ClusterGetter.ReservedVMIDs()
+}
+
+// NodeDown returns true if the node hosting the vm is down permanently
+func (m *MachineScope) NodeDown() bool {
+	return m.NodeName() != "" && slices.Contains(m.ClusterGetter.DownNodes(), m.NodeName())
+}
+
+// GetSnippetStorage returns the snippet storage of the machine, or the one of the cluster if the machine has none
+func (m *MachineScope) GetSnippetStorage() infrav1.Storage {
+	if storage := m.ProxmoxMachine.Spec.SnippetStorage; storage != nil {
+		return *storage
+	}
 	return m.ClusterGetter.Storage()
 }
 
+// GetPool returns the resource pool of the cluster, or empty if the cluster has none
+func (m *MachineScope) GetPool() string {
+	return m.ClusterGetter.Pool()
+}
+
+func (m *MachineScope) GetServerEndpoint() string {
+	return m.ClusterGetter.ServerEndpoint()
+}
+
 func (m *MachineScope) GetStorage() string {
 	return m.ProxmoxMachine.Spec.Storage
 }
 
+func (m *MachineScope) WipeDisksOnDelete() bool {
+	return m.ProxmoxMachine.Spec.WipeDisksOnDelete
+}
+
 func (m *MachineScope) Name() string {
 	return m.ProxmoxMachine.Name
 }
@@ -115,6 +162,36 @@ func (m *MachineScope) Annotations() map[string]string {
 	return m.ProxmoxMachine.Annotations
 }
 
+// GetLabels returns labels of the Machine overridden by the ones of the ProxmoxMachine
+func (m *MachineScope) GetLabels() map[string]string {
+	values := map[string]string{}
+	maps.Copy(values, m.Machine.Labels)
+	maps.Copy(values, m.ProxmoxMachine.Labels)
+	return values
+}
+
+// GetAnnotations returns annotations of the Machine overridden by the ones of the ProxmoxMachine
+func (m *MachineScope) GetAnnotations() map[string]string {
+	values := map[string]string{}
+	maps.Copy(values, m.Machine.Annotations)
+	maps.Copy(values, m.ProxmoxMachine.Annotations)
+	return values
+}
+
+func (m *MachineScope) GetTagMappings() []infrav1.TagMapping {
+	return m.ClusterGetter.TagMappings()
+}
+
+// GetVMTags returns the tags the cluster puts on all of its vms
+func (m *MachineScope) GetVMTags() infrav1.Tags {
+	return m.ClusterGetter.VMTags()
+}
+
+// GetSSHPublicKey returns the public key of the ssh key pair of the cluster, empty unless it has one
+func (m *MachineScope) GetSSHPublicKey() string {
+	return m.ClusterGetter.SSHPublicKey()
+}
+
 func (m *MachineScope) NodeName() string {
 	return m.ProxmoxMachine.Spec.Node
 }
@@ -123,6 +200,21 @@ func (m *MachineScope) SetNodeName(name string) {
 	m.ProxmoxMachine.Spec.Node = name
 }
 
+// SetAddresses sets the addresses and the network interfaces of the instance
+func (m *MachineScope) SetAddresses(addresses []clusterv1.MachineAddress, interfaces []infrav1.NetworkInterface) {
+	m.ProxmoxMachine.Status.Addresses = addresses
+	m.ProxmoxMachine.Status.NetworkInterfaces = interfaces
+}
+
+func (m *MachineScope) SetConsole(console infrav1.Console) {
+	m.ProxmoxMachine.Status.Console = &console
+}
+
+// SetPlan sets what cappx would do in dry-run mode. nil clears it
+func (m *MachineScope) SetPlan(plan *infrav1.Plan) {
+	m.ProxmoxMachine.Status.Plan = plan
+}
+
 func (m *MachineScope) SetStorage(name string) {
 	m.ProxmoxMachine.Spec.Storage = name
 }
@@ -131,26 +223,51 @@ func (m *MachineScope) SetStorage(name string) {
 // 	return m.ClusterGetter.Client()
 // }
 
-func (m *MachineScope) GetBootstrapData() (string, error) {
+// GetBootstrapData returns the bootstrap data and its format declared by the bootstrap provider,
+// e.g. cloud-config or ignition. the format is empty if the provider does not declare it
+func (m *MachineScope) GetBootstrapData() (string, string, error) {
 	if m.Machine.Spec.Bootstrap.DataSecretName == nil {
-		return "", errors.New("error retrieving bootstrap data: linked Machine's bootstrap.dataSecretName is nil")
+		return "", "", errors.New("error retrieving bootstrap data: linked Machine's bootstrap.dataSecretName is nil")
 	}
 
 	secret := &corev1.Secret{}
 	key := types.NamespacedName{Namespace: m.Namespace(), Name: *m.Machine.Spec.Bootstrap.DataSecretName}
 	if err := m.client.Get(context.TODO(), key, secret); err != nil {
-		return "", errors.Wrapf(err, "failed to retrieve bootstrap data secret for ProxmoxMachine %s/%s", m.Namespace(), m.Name())
+		return "", "", errors.Wrapf(err, "failed to retrieve bootstrap data secret for ProxmoxMachine %s/%s", m.Namespace(), m.Name())
 	}
 
 	value, ok := secret.Data["value"]
 	if !ok {
-		return "", errors.New("error retrieving bootstrap data: secret value key is missing")
+		return "", "", errors.New("error retrieving bootstrap data: secret value key is missing")
+	}
+
+	return string(value), string(secret.Data["format"]), nil
+}
+
+// GetCloudInitCredentials returns the user and password of cloudInit.credentialsSecretRef.
+// both are empty without the reference, and the user is empty unless set by the secret
+func (m *MachineScope) GetCloudInitCredentials() (string, string, error) {
+	ref := m.ProxmoxMachine.Spec.CloudInit.CredentialsSecretRef
+	if ref == nil {
+		return "", "", nil
+	}
+
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: m.Namespace(), Name: ref.Name}
+	if err := m.client.Get(context.TODO(), key, secret); err != nil {
+		return "", "", errors.Wrapf(err, "failed to retrieve cloud-init credentials secret for ProxmoxMachine %s/%s", m.Namespace(), m.Name())
 	}
 
-	return string(value), nil
+	password, ok := secret.Data["password"]
+	if !ok || len(password) == 0 {
+		return "", "", errors.Errorf("error retrieving cloud-init credentials: secret %s has no password key", ref.Name)
+	}
+
+	return string(secret.Data["user"]), string(password), nil
 }
 
 func (m *MachineScope) Close() error {
+	m.setV1Beta2Status()
 	return m.PatchObject()
 }
 
@@ -163,12 +280,14 @@ func (m *MachineScope) SetInstanceStatus(v infrav1.InstanceStatus) {
 	m.ProxmoxMachine.Status.InstanceStatus = &v
 }
 
+// GetBiosUUID returns the uuid of the provider id. nil if the machine has no provider id yet
+// or its provider id is of the region/vmid format
 func (m *MachineScope) GetBiosUUID() *string {
-	parsed, err := NewProviderID(m.GetProviderID()) //nolint: staticcheck
-	if err != nil {
+	parsed, err := providerid.Parse(m.GetProviderID())
+	if err != nil || parsed.UUID() == "" {
 		return nil
 	}
-	return ptr.To(parsed.ID()) //nolint: staticcheck
+	return ptr.To(parsed.UUID())
 }
 
 func (m *MachineScope) GetProviderID() string {
@@ -182,36 +301,281 @@ func (m *MachineScope) GetVMID() *int {
 	return m.ProxmoxMachine.Spec.VMID
 }
 
+// GetImage returns the image of the machine, or the default image of the cluster if the machine
+// is provisioned from neither an image nor a backup
 func (m *MachineScope) GetImage() infrav1.Image {
-	return m.ProxmoxMachine.Spec.Image
+	if m.ProxmoxMachine.Spec.Image != nil {
+		return *m.ProxmoxMachine.Spec.Image
+	}
+	if defaults := m.ClusterGetter.MachineDefaults(); defaults != nil && defaults.Image != nil &&
+		m.GetType() == infrav1.InstanceTypeQEMU && m.ProxmoxMachine.Spec.Restore == nil {
+		return *defaults.Image
+	}
+	return infrav1.Image{}
+}
+
+// GetNameTemplate returns the template of the name of the vm, or the default template of the cluster if the machine has none
+func (m *MachineScope) GetNameTemplate() string {
+	if m.ProxmoxMachine.Spec.NameTemplate != "" {
+		return m.ProxmoxMachine.Spec.NameTemplate
+	}
+	if defaults := m.ClusterGetter.MachineDefaults(); defaults != nil {
+		return defaults.NameTemplate
+	}
+	return ""
+}
+
+func (m *MachineScope) GetRestore() *infrav1.Restore {
+	return m.ProxmoxMachine.Spec.Restore
 }
 
 func (m *MachineScope) GetCloudInit() infrav1.CloudInit {
 	return m.ProxmoxMachine.Spec.CloudInit
 }
 
+// GetNetwork returns the network configuration completed by the ipam of the cluster network
+// and the dns configuration of the cluster
 func (m *MachineScope) GetNetwork() infrav1.Network {
-	return m.ProxmoxMachine.Spec.Network
+	network := m.ProxmoxMachine.Spec.Network
+	if n, err := m.ClusterNetwork(); err == nil && n != nil {
+		network = n.Network(network)
+	}
+	if dns := m.ClusterGetter.DNS(); dns != nil {
+		network = dns.Network(network)
+	}
+	return network
+}
+
+// GetProxy returns the proxy configuration of the cluster, or nil if the cluster has none
+func (m *MachineScope) GetProxy() *infrav1.Proxy {
+	return m.ClusterGetter.Proxy()
+}
+
+// GetTaskPolicy returns when tasks creating the instance are considered stuck
+func (m *MachineScope) GetTaskPolicy() *infrav1.TaskPolicy {
+	return m.ClusterGetter.TaskPolicy()
 }
 
+// GetProxmoxVersion returns the version of proxmox detected by the ProxmoxCluster. empty until detected
+func (m *MachineScope) GetProxmoxVersion() string {
+	return m.ClusterGetter.ProxmoxVersion()
+}
+
+// GetProvisioningTimeouts returns the timeouts of the machine completed by the defaults of the cluster
+func (m *MachineScope) GetProvisioningTimeouts() infrav1.ProvisioningTimeouts {
+	timeouts := infrav1.ProvisioningTimeouts{}
+	if defaults := m.ClusterGetter.MachineTimeouts(); defaults != nil {
+		timeouts = *defaults
+	}
+	if t := m.ProxmoxMachine.Spec.Timeouts; t != nil {
+		if t.ImageImport != nil {
+			timeouts.ImageImport = t.ImageImport
+		}
+		if t.Create != nil {
+			timeouts.Create = t.Create
+		}
+		if t.GuestReady != nil {
+			timeouts.GuestReady = t.GuestReady
+		}
+		if t.Bootstrap != nil {
+			timeouts.Bootstrap = t.Bootstrap
+		}
+	}
+	return timeouts
+}
+
+func (m *MachineScope) ProvisioningPhase() *infrav1.ProvisioningPhase {
+	return m.ProxmoxMachine.Status.ProvisioningPhase
+}
+
+// SetProvisioningPhase sets the phase of provisioning. nil clears it
+func (m *MachineScope) SetProvisioningPhase(phase *infrav1.ProvisioningPhase) {
+	m.ProxmoxMachine.Status.ProvisioningPhase = phase
+}
+
+// Bootstrapped returns true once the node of the Machine has joined the cluster
+func (m *MachineScope) Bootstrapped() bool {
+	return m.Machine.Status.NodeRef != nil
+}
+
+// FailedNodes returns nodes creating the instance has failed on for node-specific reasons
+func (m *MachineScope) FailedNodes() []string {
+	return m.ProxmoxMachine.Status.FailedNodes
+}
+
+func (m *MachineScope) AddFailedNode(node string) {
+	if !slices.Contains(m.ProxmoxMachine.Status.FailedNodes, node) {
+		m.ProxmoxMachine.Status.FailedNodes = append(m.ProxmoxMachine.Status.FailedNodes, node)
+	}
+}
+
+func (m *MachineScope) ClearFailedNodes() {
+	m.ProxmoxMachine.Status.FailedNodes = nil
+}
+
+// GetRegistries returns the container registries configured by the cluster
+func (m *MachineScope) GetRegistries() []infrav1.Registry {
+	return m.ClusterGetter.ProxmoxCluster.Spec.Registries
+}
+
+// GetHardware returns the hardware whose network device is connected to the cluster network
 func (m *MachineScope) GetHardware() infrav1.Hardware {
-	return m.ProxmoxMachine.Spec.Hardware
+	hardware := m.ProxmoxMachine.Spec.Hardware
+	if n, err := m.ClusterNetwork(); err == nil && n != nil {
+		hardware.NetworkDevice = n.Device(hardware.NetworkDevice)
+	}
+	return hardware
 }
 
+// ClusterNetwork returns the network of the cluster the machine refers to, or nil if it refers to none
+func (m *MachineScope) ClusterNetwork() (*infrav1.ClusterNetwork, error) {
+	name := m.ProxmoxMachine.Spec.Network.Name
+	if name == "" {
+		return nil, nil
+	}
+	n := m.ClusterGetter.Network(name)
+	if n == nil {
+		return nil, errors.Errorf("network %s is not defined in ProxmoxCluster %s", name, m.ClusterGetter.ProxmoxCluster.Name)
+	}
+	return n, nil
+}
+
+// NodeGroup returns the node group of the ProxmoxCluster the machine is placed in, or nil if it has none
+func (m *MachineScope) NodeGroup() (*infrav1.NodeGroup, error) {
+	name := m.ProxmoxMachine.Spec.NodeGroup
+	if name == "" {
+		return nil, nil
+	}
+	g := m.ClusterGetter.NodeGroup(name)
+	if g == nil {
+		return nil, errors.Errorf("node group %s is not defined in ProxmoxCluster %s", name, m.ClusterGetter.ProxmoxCluster.Name)
+	}
+	return g, nil
+}
+
+// GetOptions returns the options of the machine whose tags default to the ones of the cluster
 func (m *MachineScope) GetOptions() infrav1.Options {
-	return m.ProxmoxMachine.Spec.Options
+	options := m.ProxmoxMachine.Spec.Options
+	if defaults := m.ClusterGetter.MachineDefaults(); defaults != nil && len(options.Tags) == 0 {
+		options.Tags = defaults.Tags
+	}
+	return options
+}
+
+func (m *MachineScope) GetSnapshotPolicy() *infrav1.SnapshotPolicy {
+	return m.ProxmoxMachine.Spec.SnapshotPolicy
 }
 
-// SetProviderID sets the ProxmoxMachine providerID in spec.
-func (m *MachineScope) SetProviderID(uuid string) error {
-	providerid, err := providerid.New(uuid)
+func (m *MachineScope) GetHA() *infrav1.HighAvailability {
+	return m.ProxmoxMachine.Spec.HA
+}
+
+func (m *MachineScope) GetReplication() *infrav1.Replication {
+	return m.ProxmoxMachine.Spec.Replication
+}
+
+func (m *MachineScope) GetFirewall() *infrav1.Firewall {
+	return m.ProxmoxMachine.Spec.Firewall
+}
+
+func (m *MachineScope) GetAddressFilter() *infrav1.AddressFilter {
+	return m.ProxmoxMachine.Spec.AddressFilter
+}
+
+// GetReadiness returns the readiness check of the machine
+func (m *MachineScope) GetReadiness() *infrav1.Readiness {
+	return m.ProxmoxMachine.Spec.Readiness
+}
+
+// GetType returns qemu unless the type is specified
+func (m *MachineScope) GetType() infrav1.InstanceType {
+	if m.ProxmoxMachine.Spec.Type == "" {
+		return infrav1.InstanceTypeQEMU
+	}
+	return m.ProxmoxMachine.Spec.Type
+}
+
+func (m *MachineScope) GetContainer() *infrav1.Container {
+	return m.ProxmoxMachine.Spec.Container
+}
+
+// ClusterName returns the name of the CAPI Cluster this machine belongs to
+func (m *MachineScope) ClusterName() string {
+	return m.Machine.Spec.ClusterName
+}
+
+// MachineName returns the name of the owner Machine
+func (m *MachineScope) MachineName() string {
+	return m.Machine.Name
+}
+
+// IsControlPlane returns true if the owner Machine is a control-plane machine
+func (m *MachineScope) IsControlPlane() bool {
+	return util.IsControlPlaneMachine(m.Machine)
+}
+
+// FailureDomain returns the failure domain of the Machine, or the one of the ProxmoxMachine if the Machine has none
+func (m *MachineScope) FailureDomain() *string {
+	if m.Machine.Spec.FailureDomain != nil {
+		return m.Machine.Spec.FailureDomain
+	}
+	return m.ProxmoxMachine.Spec.FailureDomain
+}
+
+// MachineOwner returns kind/name of the object managing the owner Machine.
+// e.g. MachineDeployment/md-0, KubeadmControlPlane/cp. empty if none
+func (m *MachineScope) MachineOwner() string {
+	if name, ok := m.Machine.Labels[clusterv1.MachineDeploymentNameLabel]; ok {
+		return "MachineDeployment/" + name
+	}
+	if ref := metav1.GetControllerOf(m.Machine); ref != nil {
+		return ref.Kind + "/" + ref.Name
+	}
+	return ""
+}
+
+// MachineDeploymentName returns the name of the MachineDeployment of the owner Machine. empty if none
+func (m *MachineScope) MachineDeploymentName() string {
+	return m.Machine.Labels[clusterv1.MachineDeploymentNameLabel]
+}
+
+// CreationTimestamp returns when the ProxmoxMachine was created
+func (m *MachineScope) CreationTimestamp() metav1.Time {
+	return m.ProxmoxMachine.CreationTimestamp
+}
+
+// GetMachineUID returns the UID of the owner Machine
+func (m *MachineScope) GetMachineUID() string {
+	return string(m.Machine.UID)
+}
+
+// SetProviderID sets the provider id of the guest in the format of the cluster. the provider id is
+// never changed once set, since it may have been adopted from the node of the machine
+func (m *MachineScope) SetProviderID(uuid string, vmid int) error {
+	if m.GetProviderID() != "" {
+		return nil
+	}
+	var id providerid.ProviderID
+	var err error
+	switch policy := m.ClusterGetter.ProviderIDPolicy(); policy.Format {
+	case infrav1.ProviderIDFormatRegionVMID:
+		id, err = providerid.NewRegionVMID(policy.Region, vmid)
+	default:
+		id, err = providerid.New(uuid)
+	}
 	if err != nil {
 		return err
 	}
-	m.ProxmoxMachine.Spec.ProviderID = ptr.To(providerid.String())
+	m.ProxmoxMachine.Spec.ProviderID = ptr.To(id.String())
 	return nil
 }
 
+// AdoptProviderID replaces the provider id of the machine by the one a cloud-controller-manager has set
+// on its node, so that Cluster API can bind the node to the machine
+func (m *MachineScope) AdoptProviderID(id string) {
+	m.ProxmoxMachine.Spec.ProviderID = ptr.To(id)
+}
+
 func (m *MachineScope) SetVMID(vmid int) {
 	m.ProxmoxMachine.Spec.VMID = &vmid
 }
@@ -220,6 +584,10 @@ func (m *MachineScope) SetConfigStatus(config api.VirtualMachineConfig) {
 	m.ProxmoxMachine.Status.Config = config
 }
 
+func (m *MachineScope) IsReady() bool {
+	return m.ProxmoxMachine.Status.Ready
+}
+
 func (m *MachineScope) SetReady() {
 	m.ProxmoxMachine.Status.Ready = true
 }
@@ -232,6 +600,115 @@ func (m *MachineScope) SetFailureReason(v capierrors.MachineStatusError) {
 	m.ProxmoxMachine.Status.FailureReason = &v
 }
 
+// GetConfigHash returns the hash of the qemu config last applied by cappx
+func (m *MachineScope) GetConfigHash() string {
+	return m.ProxmoxMachine.Annotations[infrav1.ConfigHashAnnotation]
+}
+
+func (m *MachineScope) SetConfigHash(hash string) {
+	if m.ProxmoxMachine.Annotations == nil {
+		m.ProxmoxMachine.Annotations = map[string]string{}
+	}
+	m.ProxmoxMachine.Annotations[infrav1.ConfigHashAnnotation] = hash
+}
+
+// GetBootstrapDataHash returns the hash of the bootstrap data last written to the snippet
+func (m *MachineScope) GetBootstrapDataHash() string {
+	return m.ProxmoxMachine.Annotations[infrav1.BootstrapDataHashAnnotation]
+}
+
+func (m *MachineScope) SetBootstrapDataHash(hash string) {
+	if m.ProxmoxMachine.Annotations == nil {
+		m.ProxmoxMachine.Annotations = map[string]string{}
+	}
+	m.ProxmoxMachine.Annotations[infrav1.BootstrapDataHashAnnotation] = hash
+}
+
+// GetSMBiosUUID returns the smbios uuid generated for the qemu
+func (m *MachineScope) GetSMBiosUUID() string {
+	return m.ProxmoxMachine.Annotations[infrav1.SMBiosUUIDAnnotation]
+}
+
+func (m *MachineScope) SetSMBiosUUID(uuid string) {
+	if m.ProxmoxMachine.Annotations == nil {
+		m.ProxmoxMachine.Annotations = map[string]string{}
+	}
+	m.ProxmoxMachine.Annotations[infrav1.SMBiosUUIDAnnotation] = uuid
+}
+
+// ConfigDrifted returns true if the qemu config has been found changed out of band
+func (m *MachineScope) ConfigDrifted() bool {
+	return conditions.IsFalse(m.ProxmoxMachine, infrav1.ConfigInSyncCondition)
+}
+
+func (m *MachineScope) SetConfigInSync() {
+	conditions.MarkTrue(m.ProxmoxMachine, infrav1.ConfigInSyncCondition)
+}
+
+func (m *MachineScope) SetConfigDrifted(message string) {
+	conditions.MarkFalse(m.ProxmoxMachine, infrav1.ConfigInSyncCondition, infrav1.ConfigDriftedReason, clusterv1.ConditionSeverityWarning, "%s", message)
+}
+
+// DeletionStepDone returns true if the deletion step of the condition has been completed
+func (m *MachineScope) DeletionStepDone(step clusterv1.ConditionType) bool {
+	return conditions.IsTrue(m.ProxmoxMachine, step)
+}
+
+func (m *MachineScope) SetDeletionStepDone(step clusterv1.ConditionType) {
+	conditions.MarkTrue(m.ProxmoxMachine, step)
+}
+
+func (m *MachineScope) SetDeletionStepFailed(step clusterv1.ConditionType, err error) {
+	conditions.MarkFalse(m.ProxmoxMachine, step, infrav1.DeletionFailedReason, clusterv1.ConditionSeverityWarning, "%s", err.Error())
+}
+
+func (m *MachineScope) SetOwnershipVerified() {
+	conditions.MarkTrue(m.ProxmoxMachine, infrav1.InstanceOwnershipVerifiedCondition)
+}
+
+func (m *MachineScope) SetOwnershipMismatch(message string) {
+	conditions.MarkFalse(m.ProxmoxMachine, infrav1.InstanceOwnershipVerifiedCondition, infrav1.OwnershipMismatchReason, clusterv1.ConditionSeverityError, "%s", message)
+}
+
+// TemplateChangedMessage returns how the template of the machine has changed. empty if it has not
+func (m *MachineScope) TemplateChangedMessage() string {
+	if !conditions.IsFalse(m.ProxmoxMachine, infrav1.TemplateInSyncCondition) {
+		return ""
+	}
+	return conditions.GetMessage(m.ProxmoxMachine, infrav1.TemplateInSyncCondition)
+}
+
+func (m *MachineScope) SetTemplateInSync() {
+	conditions.MarkTrue(m.ProxmoxMachine, infrav1.TemplateInSyncCondition)
+}
+
+func (m *MachineScope) SetTemplateChanged(message string) {
+	conditions.MarkFalse(m.ProxmoxMachine, infrav1.TemplateInSyncCondition, infrav1.TemplateChangedReason, clusterv1.ConditionSeverityInfo, "%s", message)
+}
+
+// ClearTemplateSync removes the condition of machines not created from a template
+func (m *MachineScope) ClearTemplateSync() {
+	conditions.Delete(m.ProxmoxMachine, infrav1.TemplateInSyncCondition)
+}
+
+func (m *MachineScope) SetWithinQuota() {
+	conditions.MarkTrue(m.ProxmoxMachine, infrav1.WithinQuotaCondition)
+}
+
+func (m *MachineScope) SetQuotaExceeded(message string) {
+	conditions.MarkFalse(m.ProxmoxMachine, infrav1.WithinQuotaCondition, infrav1.QuotaExceededReason, clusterv1.ConditionSeverityWarning, "%s", message)
+}
+
+// Eventf records a normal event on the ProxmoxMachine
+func (m *MachineScope) Eventf(reason, format string, args ...interface{}) {
+	record.Eventf(m.ProxmoxMachine, reason, format, args...)
+}
+
+// Warnf records a warning event on the ProxmoxMachine
+func (m *MachineScope) Warnf(reason, format string, args ...interface{}) {
+	record.Warnf(m.ProxmoxMachine, reason, format, args...)
+}
+
 // PatchObject persists the cluster configuration and status.
 func (s *MachineScope) PatchObject() error {
 	return s.patchHelper.Patch(context.TODO(), s.ProxmoxMachine)
@@ -0,0 +1,72 @@
+package scope
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+var _ = Describe("MachineDefaults", Label("unit", "scope"), func() {
+	var machine *infrav1.ProxmoxMachine
+	var cluster *infrav1.ProxmoxCluster
+	var s *MachineScope
+
+	BeforeEach(func() {
+		machine = &infrav1.ProxmoxMachine{}
+		cluster = &infrav1.ProxmoxCluster{}
+		cluster.Spec.Storage = infrav1.Storage{Name: "local-dir-test", Path: "/var/lib/vz/test"}
+		cluster.Spec.MachineDefaults = &infrav1.MachineDefaults{
+			Image: &infrav1.Image{URL: "https://example.com/default.img"},
+			Tags:  infrav1.Tags{"default"},
+		}
+		s = &MachineScope{ProxmoxMachine: machine, ClusterGetter: &ClusterScope{ProxmoxCluster: cluster}}
+	})
+
+	It("should default the image of qemu machines without image and restore", func() {
+		Expect(s.GetImage().URL).To(Equal("https://example.com/default.img"))
+
+		machine.Spec.Image = &infrav1.Image{URL: "https://example.com/gpu.img"}
+		Expect(s.GetImage().URL).To(Equal("https://example.com/gpu.img"))
+
+		machine.Spec.Image = nil
+		machine.Spec.Restore = &infrav1.Restore{Archive: "local:backup/vzdump-qemu-100.vma.zst"}
+		Expect(s.GetImage()).To(Equal(infrav1.Image{}))
+
+		machine.Spec.Restore = nil
+		machine.Spec.Type = infrav1.InstanceTypeLXC
+		Expect(s.GetImage()).To(Equal(infrav1.Image{}))
+	})
+
+	It("should replace the default tags by the tags of the machine", func() {
+		Expect(s.GetOptions().Tags).To(Equal(infrav1.Tags{"default"}))
+
+		machine.Spec.Options.Tags = infrav1.Tags{"gpu"}
+		Expect(s.GetOptions().Tags).To(Equal(infrav1.Tags{"gpu"}))
+	})
+
+	It("should override the default name template", func() {
+		Expect(s.GetNameTemplate()).To(BeEmpty())
+
+		cluster.Spec.MachineDefaults.NameTemplate = "k8s-{{ .VMID }}"
+		Expect(s.GetNameTemplate()).To(Equal("k8s-{{ .VMID }}"))
+
+		machine.Spec.NameTemplate = "{{ .MachineName }}"
+		Expect(s.GetNameTemplate()).To(Equal("{{ .MachineName }}"))
+	})
+
+	It("should override the snippet storage of the cluster", func() {
+		Expect(s.GetSnippetStorage()).To(Equal(cluster.Spec.Storage))
+
+		machine.Spec.SnippetStorage = &infrav1.Storage{Name: "nfs", Path: "/mnt/pve/nfs"}
+		Expect(s.GetSnippetStorage()).To(Equal(infrav1.Storage{Name: "nfs", Path: "/mnt/pve/nfs"}))
+	})
+
+	It("should override the dns of the cluster per field", func() {
+		cluster.Spec.DNS = &infrav1.DNS{NameServer: "10.0.0.2", SearchDomain: "example.com"}
+		machine.Spec.Network.NameServer = "1.1.1.1"
+		network := s.GetNetwork()
+		Expect(network.NameServer).To(Equal("1.1.1.1"))
+		Expect(network.SearchDomain).To(Equal("example.com"))
+	})
+})
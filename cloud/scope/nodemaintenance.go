@@ -0,0 +1,126 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"context"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/pkg/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+type NodeMaintenanceScopeParams struct {
+	Client                 client.Client
+	ProxmoxNodeMaintenance *infrav1.ProxmoxNodeMaintenance
+	ClusterGetter          *ClusterScope
+	// machines of the cluster having vmid
+	Machines []infrav1.ProxmoxMachine
+	// nodes under maintenance for the cluster
+	CordonedNodes []string
+}
+
+func NewNodeMaintenanceScope(params NodeMaintenanceScopeParams) (*NodeMaintenanceScope, error) {
+	if params.Client == nil {
+		return nil, errors.New("client is required when creating a NodeMaintenanceScope")
+	}
+	if params.ProxmoxNodeMaintenance == nil {
+		return nil, errors.New("failed to generate new scope from nil ProxmoxNodeMaintenance")
+	}
+	if params.ClusterGetter == nil {
+		return nil, errors.New("failed to generate new scope form nil ClusterScope")
+	}
+
+	helper, err := patch.NewHelper(params.ProxmoxNodeMaintenance, params.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init patch helper")
+	}
+
+	return &NodeMaintenanceScope{
+		client:                 params.Client,
+		patchHelper:            helper,
+		ProxmoxNodeMaintenance: params.ProxmoxNodeMaintenance,
+		ClusterGetter:          params.ClusterGetter,
+		machines:               params.Machines,
+		cordonedNodes:          params.CordonedNodes,
+	}, nil
+}
+
+type NodeMaintenanceScope struct {
+	client                 client.Client
+	patchHelper            *patch.Helper
+	ProxmoxNodeMaintenance *infrav1.ProxmoxNodeMaintenance
+	ClusterGetter          *ClusterScope
+	machines               []infrav1.ProxmoxMachine
+	cordonedNodes          []string
+}
+
+func (s *NodeMaintenanceScope) CloudClient() *proxmox.Service {
+	return s.ClusterGetter.CloudClient()
+}
+
+func (s *NodeMaintenanceScope) Name() string {
+	return s.ProxmoxNodeMaintenance.Name
+}
+
+func (s *NodeMaintenanceScope) Namespace() string {
+	return s.ProxmoxNodeMaintenance.Namespace
+}
+
+func (s *NodeMaintenanceScope) GetSpec() infrav1.ProxmoxNodeMaintenanceSpec {
+	return s.ProxmoxNodeMaintenance.Spec
+}
+
+// Machines returns machines of the cluster having vmid
+func (s *NodeMaintenanceScope) Machines() []infrav1.ProxmoxMachine {
+	return s.machines
+}
+
+// CordonedNodes returns nodes under maintenance for the cluster
+func (s *NodeMaintenanceScope) CordonedNodes() []string {
+	return s.cordonedNodes
+}
+
+// NodeGroup returns the node group of the cluster, or nil if it is not defined
+func (s *NodeMaintenanceScope) NodeGroup(name string) *infrav1.NodeGroup {
+	return s.ClusterGetter.NodeGroup(name)
+}
+
+func (s *NodeMaintenanceScope) SetMachines(names []string) {
+	s.ProxmoxNodeMaintenance.Status.Machines = names
+}
+
+func (s *NodeMaintenanceScope) SetReady(v bool) {
+	s.ProxmoxNodeMaintenance.Status.Ready = v
+}
+
+func (s *NodeMaintenanceScope) SetFailureMessage(v error) {
+	s.ProxmoxNodeMaintenance.Status.FailureMessage = ptr.To(v.Error())
+}
+
+func (s *NodeMaintenanceScope) Close() error {
+	return s.PatchObject()
+}
+
+// PatchObject persists the node maintenance configuration and status.
+func (s *NodeMaintenanceScope) PatchObject() error {
+	return s.patchHelper.Patch(context.TODO(), s.ProxmoxNodeMaintenance)
+}
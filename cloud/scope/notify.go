@@ -0,0 +1,64 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/notify"
+)
+
+// key of the notification hook secrets holding the url of the webhook
+const notificationURLKey = "url"
+
+// Notify sends the problem of the object to the notification hooks of the cluster.
+// hooks whose secret can not be read are skipped and logged
+func (s *ClusterScope) Notify(ctx context.Context, event infrav1.NotificationEvent, object, message string) {
+	if len(s.ProxmoxCluster.Spec.Notifications) == 0 {
+		return
+	}
+	n := notify.Notification{Event: event, Namespace: s.Namespace(), Cluster: s.Name(), Object: object, Message: message, Time: time.Now()}
+	if !notify.Default.Due(n) {
+		return
+	}
+	notify.Default.Send(ctx, s.notificationHooks(ctx), n)
+}
+
+// ResolveNotification forgets the problem of the object once it is over, so that it is sent again when it recurs
+func (s *ClusterScope) ResolveNotification(event infrav1.NotificationEvent, object string) {
+	notify.Default.Resolve(event, s.Namespace(), s.Name(), object)
+}
+
+func (s *ClusterScope) notificationHooks(ctx context.Context) []notify.Hook {
+	hooks := []notify.Hook{}
+	for _, h := range s.ProxmoxCluster.Spec.Notifications {
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: s.Namespace(), Name: h.SecretName}
+		if err := s.client.Get(ctx, key, secret); err != nil {
+			log.FromContext(ctx).Error(err, "failed to get secret of notification hook", "hook", h.Name, "secret", key.Name)
+			continue
+		}
+		url := string(secret.Data[notificationURLKey])
+		if url == "" {
+			log.FromContext(ctx).Error(fmt.Errorf("secret %s has no %s", key.Name, notificationURLKey), "invalid notification hook", "hook", h.Name)
+			continue
+		}
+		hooks = append(hooks, notify.Hook{NotificationHook: h, URL: url})
+	}
+	return hooks
+}
+
+// Notify sends the problem of the ProxmoxMachine to the notification hooks of its cluster
+func (m *MachineScope) Notify(ctx context.Context, event infrav1.NotificationEvent, message string) {
+	m.ClusterGetter.Notify(ctx, event, "ProxmoxMachine/"+m.Name(), message)
+}
+
+// ResolveNotification forgets the problem of the ProxmoxMachine once it is over
+func (m *MachineScope) ResolveNotification(event infrav1.NotificationEvent) {
+	m.ClusterGetter.ResolveNotification(event, "ProxmoxMachine/"+m.Name())
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"context"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+type SnapshotScopeParams struct {
+	Client          client.Client
+	ProxmoxSnapshot *infrav1.ProxmoxSnapshot
+	ProxmoxMachine  *infrav1.ProxmoxMachine
+	ClusterGetter   *ClusterScope
+}
+
+func NewSnapshotScope(params SnapshotScopeParams) (*SnapshotScope, error) {
+	if params.Client == nil {
+		return nil, errors.New("client is required when creating a SnapshotScope")
+	}
+	if params.ProxmoxSnapshot == nil {
+		return nil, errors.New("failed to generate new scope from nil ProxmoxSnapshot")
+	}
+	if params.ProxmoxMachine == nil {
+		return nil, errors.New("failed to generate new scope from nil ProxmoxMachine")
+	}
+	if params.ClusterGetter == nil {
+		return nil, errors.New("failed to generate new scope form nil ClusterScope")
+	}
+
+	helper, err := patch.NewHelper(params.ProxmoxSnapshot, params.Client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init patch helper")
+	}
+
+	return &SnapshotScope{
+		client:          params.Client,
+		patchHelper:     helper,
+		ProxmoxSnapshot: params.ProxmoxSnapshot,
+		ProxmoxMachine:  params.ProxmoxMachine,
+		ClusterGetter:   params.ClusterGetter,
+	}, nil
+}
+
+type SnapshotScope struct {
+	client          client.Client
+	patchHelper     *patch.Helper
+	ProxmoxSnapshot *infrav1.ProxmoxSnapshot
+	ProxmoxMachine  *infrav1.ProxmoxMachine
+	ClusterGetter   *ClusterScope
+}
+
+func (s *SnapshotScope) CloudClient() *proxmox.Service {
+	return s.ClusterGetter.CloudClient()
+}
+
+func (s *SnapshotScope) Name() string {
+	return s.ProxmoxSnapshot.Name
+}
+
+func (s *SnapshotScope) Namespace() string {
+	return s.ProxmoxSnapshot.Namespace
+}
+
+// MachineName returns the name of the target machine
+func (s *SnapshotScope) MachineName() string {
+	return s.ProxmoxMachine.Name
+}
+
+func (s *SnapshotScope) UID() string {
+	return string(s.ProxmoxSnapshot.UID)
+}
+
+func (s *SnapshotScope) Description() string {
+	return s.ProxmoxSnapshot.Spec.Description
+}
+
+func (s *SnapshotScope) RollbackRequested() bool {
+	return s.ProxmoxSnapshot.Spec.Rollback
+}
+
+// GetVMID returns vmid of the target machine. nil if the vm is not created yet
+func (s *SnapshotScope) GetVMID() *int {
+	return s.ProxmoxMachine.Spec.VMID
+}
+
+func (s *SnapshotScope) GetSnapshotName() string {
+	return s.ProxmoxSnapshot.Status.SnapshotName
+}
+
+func (s *SnapshotScope) SetSnapshotName(name string) {
+	s.ProxmoxSnapshot.Status.SnapshotName = name
+}
+
+// GetSnapshotVMID returns vmid of the vm the snapshot belongs to. nil if no snapshot is taken yet
+func (s *SnapshotScope) GetSnapshotVMID() *int {
+	return s.ProxmoxSnapshot.Status.VMID
+}
+
+func (s *SnapshotScope) SetVMID(vmid int) {
+	s.ProxmoxSnapshot.Status.VMID = &vmid
+}
+
+func (s *SnapshotScope) GetCreationTime() *metav1.Time {
+	return s.ProxmoxSnapshot.Status.CreationTime
+}
+
+func (s *SnapshotScope) SetCreationTime(t metav1.Time) {
+	s.ProxmoxSnapshot.Status.CreationTime = &t
+}
+
+func (s *SnapshotScope) IsRolledBack() bool {
+	return s.ProxmoxSnapshot.Status.RolledBack
+}
+
+func (s *SnapshotScope) SetRolledBack(v bool) {
+	s.ProxmoxSnapshot.Status.RolledBack = v
+}
+
+func (s *SnapshotScope) SetReady(v bool) {
+	s.ProxmoxSnapshot.Status.Ready = v
+}
+
+func (s *SnapshotScope) SetFailureMessage(v error) {
+	s.ProxmoxSnapshot.Status.FailureMessage = ptr.To(v.Error())
+}
+
+func (s *SnapshotScope) Close() error {
+	return s.PatchObject()
+}
+
+// PatchObject persists the snapshot configuration and status.
+func (s *SnapshotScope) PatchObject() error {
+	return s.patchHelper.Patch(context.TODO(), s.ProxmoxSnapshot)
+}
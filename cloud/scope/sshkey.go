@@ -0,0 +1,123 @@
+package scope
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// keys of the secret holding the ssh key pair of a cluster
+const (
+	sshPrivateKeyKey = corev1.SSHAuthPrivateKey
+	sshPublicKeyKey  = "ssh-publickey"
+)
+
+// SSHKeyPairSecretName returns the name of the secret holding the ssh key pair of the cluster
+func (s *ClusterScope) SSHKeyPairSecretName() string {
+	if spec := s.ProxmoxCluster.Spec.SSHKeyPair; spec != nil && spec.SecretName != "" {
+		return spec.SecretName
+	}
+	return fmt.Sprintf("%s-ssh", s.Name())
+}
+
+// SSHPublicKey returns the public key authorized on the machines of the cluster
+func (s *ClusterScope) SSHPublicKey() string {
+	return s.ProxmoxCluster.Status.SSHPublicKey
+}
+
+// ReconcileSSHKeyPair generates the ssh key pair of the cluster unless its secret exists,
+// and sets its public key to the status so that machines authorize it
+func (s *ClusterScope) ReconcileSSHKeyPair(ctx context.Context) error {
+	if s.ProxmoxCluster.Spec.SSHKeyPair == nil {
+		s.ProxmoxCluster.Status.SSHPublicKey = ""
+		return nil
+	}
+	log := log.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: s.Namespace(), Name: s.SSHKeyPairSecretName()}
+	err := s.client.Get(ctx, key, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get ssh key pair secret %s: %w", key.Name, err)
+	}
+	if apierrors.IsNotFound(err) {
+		log.Info("generating ssh key pair", "secret", key.Name)
+		if secret, err = sshKeyPairSecret(key); err != nil {
+			return err
+		}
+		// the generated key pair is deleted with the ProxmoxCluster
+		secret.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: infrav1.GroupVersion.String(),
+			Kind:       "ProxmoxCluster",
+			Name:       s.ProxmoxCluster.Name,
+			UID:        s.ProxmoxCluster.UID,
+		}})
+		if err := s.client.Create(ctx, secret); err != nil {
+			return fmt.Errorf("failed to create ssh key pair secret %s: %w", key.Name, err)
+		}
+	}
+
+	publicKey, err := sshPublicKey(secret)
+	if err != nil {
+		return fmt.Errorf("invalid ssh key pair secret %s: %w", key.Name, err)
+	}
+	s.ProxmoxCluster.Status.SSHPublicKey = publicKey
+	return nil
+}
+
+// returns a secret of a new ed25519 key pair in openssh format
+func sshKeyPairSecret(key client.ObjectKey) (*corev1.Secret, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ssh key pair: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, key.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ssh private key: %w", err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ssh public key: %w", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Type:       corev1.SecretTypeSSHAuth,
+		Data: map[string][]byte{
+			sshPrivateKeyKey: pem.EncodeToMemory(block),
+			sshPublicKeyKey:  ssh.MarshalAuthorizedKey(sshPublic),
+		},
+	}, nil
+}
+
+// returns the public key of the secret in authorized_keys format.
+// it is derived from the private key if the secret brought by the user has none
+func sshPublicKey(secret *corev1.Secret) (string, error) {
+	if public, ok := secret.Data[sshPublicKeyKey]; ok {
+		key, _, _, _, err := ssh.ParseAuthorizedKey(public)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", sshPublicKeyKey, err)
+		}
+		return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))), nil
+	}
+	private, ok := secret.Data[sshPrivateKeyKey]
+	if !ok {
+		return "", fmt.Errorf("neither %s nor %s is set", sshPublicKeyKey, sshPrivateKeyKey)
+	}
+	signer, err := ssh.ParsePrivateKey(private)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", sshPrivateKeyKey, err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))), nil
+}
@@ -0,0 +1,25 @@
+package backup
+
+import (
+	"time"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+func JobID(uid string) string {
+	return jobID(uid)
+}
+
+func JobRequest(spec infrav1.ProxmoxBackupPolicySpec, vmids []int, comment string) map[string]interface{} {
+	return jobRequest(spec, vmids, comment)
+}
+
+func UpdateRequest(request map[string]interface{}) map[string]interface{} {
+	return updateRequest(request)
+}
+
+type Volume = volume
+
+func ExpiredBackups(volumes []Volume, vmids []int, maxAge time.Duration, now time.Time) []Volume {
+	return expiredBackups(volumes, vmids, maxAge, now)
+}
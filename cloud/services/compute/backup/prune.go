@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+// backup volume in a storage
+type volume struct {
+	VolID string `json:"volid"`
+	VMID  int    `json:"vmid"`
+	// unix time the backup was created at
+	CTime     int64 `json:"ctime"`
+	Protected int   `json:"protected,omitempty"`
+}
+
+// deletes backups of the vms older than maxAge from the storage of the policy.
+// the storage is looked up on every online node since local storages differ per node
+func (s *Service) pruneBackups(ctx context.Context, vmids []int, maxAge time.Duration) error {
+	log := log.FromContext(ctx)
+	storage := s.scope.GetSpec().Storage
+	nodes, err := s.client.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+	deleted := map[string]bool{}
+	for _, node := range nodes {
+		if node.Status != "online" {
+			continue
+		}
+		var volumes []volume
+		if err := s.client.RESTClient().Get(ctx, contentPath(node.Node, storage)+"?content=backup", &volumes); err != nil {
+			// the storage may not be available on this node
+			log.V(3).Info("failed to list backups", "node", node.Node, "error", err.Error())
+			continue
+		}
+		for _, v := range expiredBackups(volumes, vmids, maxAge, time.Now()) {
+			// shared storages show the same volume on every node
+			if deleted[v.VolID] {
+				continue
+			}
+			log.Info("deleting expired backup", "node", node.Node, "volid", v.VolID)
+			var upid string
+			if err := audit.REST(&s.client).Delete(ctx, fmt.Sprintf("%s/%s", contentPath(node.Node, storage), url.PathEscape(v.VolID)), nil, &upid); err != nil {
+				return fmt.Errorf("failed to delete backup %s: %w", v.VolID, err)
+			}
+			if upid != "" {
+				if err := s.client.EnsureTaskDone(ctx, node.Node, upid); err != nil {
+					return fmt.Errorf("failed to delete backup %s: %w", v.VolID, err)
+				}
+			}
+			deleted[v.VolID] = true
+		}
+	}
+	return nil
+}
+
+func contentPath(node, storage string) string {
+	return fmt.Sprintf("/nodes/%s/storage/%s/content", node, url.PathEscape(storage))
+}
+
+// returns unprotected backups of the vms created more than maxAge before now
+func expiredBackups(volumes []volume, vmids []int, maxAge time.Duration, now time.Time) []volume {
+	expired := []volume{}
+	for _, v := range volumes {
+		if v.Protected != 0 || !slices.Contains(vmids, v.VMID) {
+			continue
+		}
+		if now.Sub(time.Unix(v.CTime, 0)) > maxAge {
+			expired = append(expired, v)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/names"
+)
+
+const (
+	// prefix of backup jobs managed by ProxmoxBackupPolicy
+	jobPrefix = "cappx-"
+	jobsPath  = "/cluster/backup"
+)
+
+// backup job of proxmox
+type job struct {
+	ID string `json:"id"`
+}
+
+func (s *Service) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling backup job")
+
+	id := s.scope.GetJobID()
+	if id == "" {
+		id = jobID(s.scope.UID())
+		s.scope.SetJobID(id)
+	}
+	exists, err := s.jobExists(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	vmids := s.scope.VMIDs()
+	s.scope.SetVMIDs(vmids)
+	if len(vmids) == 0 {
+		// proxmox does not accept a job without vms
+		log.Info("no vm is selected by the policy")
+		s.scope.SetReady(false)
+		if exists {
+			return s.deleteJob(ctx, id)
+		}
+		return nil
+	}
+
+	request := jobRequest(s.scope.GetSpec(), vmids, fmt.Sprintf("managed by cappx: %s/%s", s.scope.Namespace(), s.scope.Name()))
+	if exists {
+		request = updateRequest(request)
+		if err := audit.REST(&s.client).Put(ctx, jobPath(id), request, nil); err != nil {
+			return fmt.Errorf("failed to update backup job %s: %w", id, err)
+		}
+	} else {
+		request["id"] = id
+		if err := audit.REST(&s.client).Post(ctx, jobsPath, request, nil); err != nil {
+			return fmt.Errorf("failed to create backup job %s: %w", id, err)
+		}
+	}
+
+	log.Info("Reconciled backup job")
+	s.scope.SetReady(true)
+
+	if retention := s.scope.GetSpec().Retention; retention != nil && retention.MaxAge != nil {
+		return s.pruneBackups(ctx, vmids, retention.MaxAge.Duration)
+	}
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Deleting backup job")
+
+	id := s.scope.GetJobID()
+	if id == "" {
+		return nil
+	}
+	exists, err := s.jobExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Info("backup job is not found or already deleted")
+		return nil
+	}
+	return s.deleteJob(ctx, id)
+}
+
+func (s *Service) jobExists(ctx context.Context, id string) (bool, error) {
+	var jobs []job
+	if err := s.client.RESTClient().Get(ctx, jobsPath, &jobs); err != nil {
+		return false, err
+	}
+	for _, j := range jobs {
+		if j.ID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) deleteJob(ctx context.Context, id string) error {
+	if err := audit.REST(&s.client).Delete(ctx, jobPath(id), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete backup job %s: %w", id, err)
+	}
+	return nil
+}
+
+func jobPath(id string) string {
+	return fmt.Sprintf("%s/%s", jobsPath, url.PathEscape(id))
+}
+
+// backup job id must start with a letter
+func jobID(uid string) string {
+	return names.FromUID(jobPrefix, uid)
+}
+
+func jobRequest(spec infrav1.ProxmoxBackupPolicySpec, vmids []int, comment string) map[string]interface{} {
+	ids := []string{}
+	for _, id := range vmids {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	mode := spec.Mode
+	if mode == "" {
+		mode = "snapshot"
+	}
+	enabled := 1
+	if spec.Suspend {
+		enabled = 0
+	}
+	request := map[string]interface{}{
+		"schedule": spec.Schedule,
+		"storage":  spec.Storage,
+		"mode":     string(mode),
+		"vmid":     strings.Join(ids, ","),
+		"enabled":  enabled,
+		"comment":  comment,
+	}
+	if spec.Compress != "" {
+		request["compress"] = string(spec.Compress)
+	}
+	if spec.Retention != nil && spec.Retention.KeepLast > 0 {
+		request["prune-backups"] = fmt.Sprintf("keep-last=%d", spec.Retention.KeepLast)
+	}
+	return request
+}
+
+// returns the request updating an existing job. options which are not set are deleted from the job,
+// since proxmox keeps the ones left out of the request
+func updateRequest(request map[string]interface{}) map[string]interface{} {
+	deletes := []string{}
+	// compress falls back to the default of the node, prune-backups to the retention of the storage
+	for _, key := range []string{"compress", "prune-backups"} {
+		if _, ok := request[key]; !ok {
+			deletes = append(deletes, key)
+		}
+	}
+	if len(deletes) > 0 {
+		request["delete"] = strings.Join(deletes, ",")
+	}
+	return request
+}
@@ -0,0 +1,75 @@
+package backup_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/backup"
+)
+
+var _ = Describe("jobID", Label("unit", "backup"), func() {
+	It("should derive job id from uid", func() {
+		Expect(backup.JobID("5c1e5f6e-0bd4-4d3c-9f1b-0a2f7f0e1a11")).To(Equal("cappx-5c1e5f6e0bd4"))
+	})
+})
+
+var _ = Describe("jobRequest", Label("unit", "backup"), func() {
+	It("should render backup job", func() {
+		spec := infrav1.ProxmoxBackupPolicySpec{Schedule: "daily", Storage: "pbs", Compress: "zstd"}
+		request := backup.JobRequest(spec, []int{100, 101}, "comment")
+		Expect(request).To(HaveKeyWithValue("vmid", "100,101"))
+		Expect(request).To(HaveKeyWithValue("mode", "snapshot"))
+		Expect(request).To(HaveKeyWithValue("enabled", 1))
+		Expect(request).To(HaveKeyWithValue("compress", "zstd"))
+	})
+
+	It("should disable suspended job", func() {
+		spec := infrav1.ProxmoxBackupPolicySpec{Schedule: "daily", Storage: "local", Mode: "stop", Suspend: true}
+		request := backup.JobRequest(spec, []int{100}, "comment")
+		Expect(request).To(HaveKeyWithValue("enabled", 0))
+		Expect(request).To(HaveKeyWithValue("mode", "stop"))
+		Expect(request).NotTo(HaveKey("compress"))
+		Expect(request).NotTo(HaveKey("prune-backups"))
+	})
+
+	It("should render keep-last retention", func() {
+		spec := infrav1.ProxmoxBackupPolicySpec{Schedule: "daily", Storage: "pbs", Retention: &infrav1.BackupRetention{KeepLast: 7}}
+		request := backup.JobRequest(spec, []int{100}, "comment")
+		Expect(request).To(HaveKeyWithValue("prune-backups", "keep-last=7"))
+	})
+})
+
+var _ = Describe("updateRequest", Label("unit", "backup"), func() {
+	It("should delete options which are not set", func() {
+		spec := infrav1.ProxmoxBackupPolicySpec{Schedule: "daily", Storage: "pbs"}
+		request := backup.UpdateRequest(backup.JobRequest(spec, []int{100}, "comment"))
+		Expect(request).To(HaveKeyWithValue("delete", "compress,prune-backups"))
+	})
+
+	It("should not delete options which are set", func() {
+		spec := infrav1.ProxmoxBackupPolicySpec{Schedule: "daily", Storage: "pbs", Compress: "zstd", Retention: &infrav1.BackupRetention{KeepLast: 7}}
+		request := backup.UpdateRequest(backup.JobRequest(spec, []int{100}, "comment"))
+		Expect(request).NotTo(HaveKey("delete"))
+		spec.Retention = nil
+		request = backup.UpdateRequest(backup.JobRequest(spec, []int{100}, "comment"))
+		Expect(request).To(HaveKeyWithValue("delete", "prune-backups"))
+	})
+})
+
+var _ = Describe("expiredBackups", Label("unit", "backup"), func() {
+	volumes := []backup.Volume{
+		{VolID: "pbs:backup/vm/100/old", VMID: 100, CTime: 100},
+		{VolID: "pbs:backup/vm/100/new", VMID: 100, CTime: 3600},
+		{VolID: "pbs:backup/vm/101/old", VMID: 101, CTime: 100},
+		{VolID: "pbs:backup/vm/100/protected", VMID: 100, CTime: 100, Protected: 1},
+	}
+
+	It("should return unprotected old backups of the vms", func() {
+		Expect(backup.ExpiredBackups(volumes, []int{100}, time.Hour, time.Unix(4000, 0))).To(Equal([]backup.Volume{
+			{VolID: "pbs:backup/vm/100/old", VMID: 100, CTime: 100},
+		}))
+	})
+})
@@ -0,0 +1,28 @@
+package firewall
+
+import (
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+type Rule = rule
+type Entry = entry
+
+func ManagedComment(namespace, clusterName string) string {
+	return managedComment(namespace, clusterName)
+}
+
+func Rules(spec infrav1.ClusterFirewall, ipset string) []Rule {
+	return rules(spec, ipset)
+}
+
+func ManagedRules(rules []Rule) []Rule {
+	return managedRules(rules)
+}
+
+func RulesUpToDate(managed, desired []Rule) bool {
+	return rulesUpToDate(managed, desired)
+}
+
+func DiffEntries(entries []Entry, cidrs []string) ([]string, []string) {
+	return diffEntries(entries, cidrs)
+}
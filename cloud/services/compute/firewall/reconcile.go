@@ -0,0 +1,361 @@
+package firewall
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+// prefix of the comments of the rules added by cappx. the purpose of the rule follows it
+const ruleComment = "managed by cappx"
+
+// security groups and ipsets of the datacenter firewall
+type collection struct {
+	path string
+	// key of the name in requests and responses
+	key  string
+	kind string
+}
+
+var (
+	groups = collection{path: "/cluster/firewall/groups", key: "group", kind: "security group"}
+	ipsets = collection{path: "/cluster/firewall/ipset", key: "name", kind: "ipset"}
+)
+
+// rule of POST /cluster/firewall/groups/{group}. subset of GET /cluster/firewall/groups/{group}
+type rule struct {
+	Pos     int    `json:"pos,omitempty"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Proto   string `json:"proto,omitempty"`
+	Dport   string `json:"dport,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Enable  int    `json:"enable"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// subset of GET /cluster/firewall/groups and GET /cluster/firewall/ipset
+type object struct {
+	Group   string `json:"group,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// subset of GET /cluster/firewall/ipset/{name}
+type entry struct {
+	CIDR string `json:"cidr"`
+}
+
+// Reconcile keeps the security group opening the ports of kubernetes and the IPSet of the addresses
+// of the machines of the cluster. the group is attached to the machines by the machine reconciler
+func (s *Service) Reconcile(ctx context.Context) error {
+	spec := s.scope.FirewallSpec()
+	if spec == nil {
+		return nil
+	}
+	log := log.FromContext(ctx)
+	log.Info("Reconciling cluster firewall")
+
+	name := GroupName(*spec, s.scope.Namespace(), s.scope.Name())
+	comment := managedComment(s.scope.Namespace(), s.scope.Name())
+	addresses, err := s.scope.MachineAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	// the ipset is filled before the group refers to it
+	if err := s.ensure(ctx, ipsets, name, comment); err != nil {
+		return err
+	}
+	if err := s.reconcileIPSet(ctx, name, append(addresses, spec.Sources...)); err != nil {
+		return err
+	}
+	if err := s.ensure(ctx, groups, name, comment); err != nil {
+		return err
+	}
+	return s.reconcileRules(ctx, name, rules(*spec, name))
+}
+
+// the group and the ipset are left unless cappx created them for the cluster.
+// vms are gone by now since Machines are deleted before the ProxmoxCluster
+func (s *Service) Delete(ctx context.Context) error {
+	spec := s.scope.FirewallSpec()
+	if spec == nil {
+		return nil
+	}
+	log := log.FromContext(ctx)
+	name := GroupName(*spec, s.scope.Namespace(), s.scope.Name())
+	comment := managedComment(s.scope.Namespace(), s.scope.Name())
+
+	// proxmox refuses to delete groups with rules and ipsets with entries
+	if owned, err := s.owned(ctx, groups, name, comment); err != nil {
+		return err
+	} else if owned {
+		if err := s.reconcileRules(ctx, name, nil); err != nil {
+			return err
+		}
+		log.Info("deleting security group", "group", name)
+		if err := audit.REST(&s.client).Delete(ctx, groups.path+"/"+name, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete security group %s: %w", name, err)
+		}
+	}
+	if owned, err := s.owned(ctx, ipsets, name, comment); err != nil {
+		return err
+	} else if owned {
+		if err := s.reconcileIPSet(ctx, name, nil); err != nil {
+			return err
+		}
+		log.Info("deleting ipset", "ipset", name)
+		if err := audit.REST(&s.client).Delete(ctx, ipsets.path+"/"+name, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete ipset %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// creates the group or the ipset of the datacenter firewall unless it exists.
+// existing ones not created for the cluster are refused, so that rules of others are never taken over
+func (s *Service) ensure(ctx context.Context, c collection, name, comment string) error {
+	o, err := s.get(ctx, c, name)
+	if err != nil {
+		return err
+	}
+	if o != nil {
+		if o.Comment != comment {
+			return fmt.Errorf("%s %s of the datacenter firewall is not managed by cappx for the cluster", c.kind, name)
+		}
+		return nil
+	}
+	log.FromContext(ctx).Info("creating "+c.kind, c.key, name)
+	request := map[string]interface{}{c.key: name, "comment": comment}
+	if err := audit.REST(&s.client).Post(ctx, c.path, request, nil); err != nil {
+		return fmt.Errorf("failed to create %s %s: %w", c.kind, name, err)
+	}
+	return nil
+}
+
+// returns true if the group or the ipset exists and is created for the cluster
+func (s *Service) owned(ctx context.Context, c collection, name, comment string) (bool, error) {
+	o, err := s.get(ctx, c, name)
+	if err != nil {
+		return false, err
+	}
+	if o == nil {
+		log.FromContext(ctx).Info(c.kind+" not found or already deleted", c.key, name)
+		return false, nil
+	}
+	if o.Comment != comment {
+		log.FromContext(ctx).Info(c.kind+" is not created by cappx, skipping deletion", c.key, name)
+		return false, nil
+	}
+	return true, nil
+}
+
+// returns nil if the group or the ipset does not exist
+func (s *Service) get(ctx context.Context, c collection, name string) (*object, error) {
+	var objects []object
+	if err := s.client.RESTClient().Get(ctx, c.path, &objects); err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(objects, func(o object) bool { return o.Group+o.Name == name })
+	if i < 0 {
+		return nil, nil
+	}
+	return &objects[i], nil
+}
+
+// adds the missing cidrs to the ipset and removes the others
+func (s *Service) reconcileIPSet(ctx context.Context, name string, cidrs []string) error {
+	path := ipsets.path + "/" + name
+	var entries []entry
+	if err := s.client.RESTClient().Get(ctx, path, &entries); err != nil {
+		return err
+	}
+	add, remove := diffEntries(entries, cidrs)
+	for _, cidr := range add {
+		log.FromContext(ctx).Info("adding address to ipset", "ipset", name, "cidr", cidr)
+		if err := audit.REST(&s.client).Post(ctx, path, map[string]interface{}{"cidr": cidr}, nil); err != nil {
+			return fmt.Errorf("failed to add %s to ipset %s: %w", cidr, name, err)
+		}
+	}
+	for _, cidr := range remove {
+		log.FromContext(ctx).Info("removing address from ipset", "ipset", name, "cidr", cidr)
+		if err := audit.REST(&s.client).Delete(ctx, path+"/"+url.PathEscape(cidr), nil, nil); err != nil {
+			return fmt.Errorf("failed to remove %s from ipset %s: %w", cidr, name, err)
+		}
+	}
+	return nil
+}
+
+// replaces the rules added by cappx to the group with the desired ones unless they are up to date.
+// rules added by others are left below them
+func (s *Service) reconcileRules(ctx context.Context, group string, desired []rule) error {
+	path := groups.path + "/" + group
+	var current []rule
+	if err := s.client.RESTClient().Get(ctx, path, &current); err != nil {
+		return err
+	}
+	managed := managedRules(current)
+	if rulesUpToDate(managed, desired) {
+		return nil
+	}
+	log.FromContext(ctx).Info("updating rules of security group", "group", group, "rules", len(desired))
+	// delete from the bottom so that positions of the remaining rules are kept
+	for i := len(managed) - 1; i >= 0; i-- {
+		p := fmt.Sprintf("%s/%d", path, managed[i].Pos)
+		if err := audit.REST(&s.client).Delete(ctx, p, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete rule %d of security group %s: %w", managed[i].Pos, group, err)
+		}
+	}
+	// proxmox inserts new rules on top
+	for i := len(desired) - 1; i >= 0; i-- {
+		if err := audit.REST(&s.client).Post(ctx, path, desired[i], nil); err != nil {
+			return fmt.Errorf("failed to add rule %q to security group %s: %w", desired[i].Comment, group, err)
+		}
+	}
+	return nil
+}
+
+// GroupName returns the name of the security group and the ipset of the cluster.
+// names are limited to 18 characters by proxmox, so the default is derived from a hash of the cluster
+func GroupName(spec infrav1.ClusterFirewall, namespace, clusterName string) string {
+	if spec.GroupName != "" {
+		return spec.GroupName
+	}
+	sum := sha256.Sum256([]byte(namespace + "/" + clusterName))
+	return "k8s-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// marks groups and ipsets created by cappx so that the ones of others are never deleted
+func managedComment(namespace, clusterName string) string {
+	return fmt.Sprintf("managed by cappx for cluster %s/%s", namespace, clusterName)
+}
+
+// returns the rules opening the ports of kubernetes. ports between the machines are only opened
+// to the ipset of the cluster, while the api server and node ports are reached from outside
+func rules(spec infrav1.ClusterFirewall, ipset string) []rule {
+	machines := "+" + ipset
+	rules := []rule{
+		accept("kube-apiserver", "tcp", "6443", strings.Join(spec.APIServerSources, ",")),
+		accept("etcd", "tcp", "2379:2380", machines),
+		accept("kubelet", "tcp", "10250", machines),
+	}
+	if spec.NodePorts {
+		rules = append(rules,
+			accept("nodeports", "tcp", "30000:32767", ""),
+			accept("nodeports", "udp", "30000:32767", ""),
+		)
+	}
+	switch spec.Overlay {
+	case infrav1.FirewallOverlayVXLAN, "":
+		// flannel and cilium use the linux default port, calico the iana one
+		rules = append(rules,
+			accept("vxlan", "udp", "8472", machines),
+			accept("vxlan", "udp", "4789", machines),
+		)
+	case infrav1.FirewallOverlayGeneve:
+		rules = append(rules, accept("geneve", "udp", "6081", machines))
+	case infrav1.FirewallOverlayBGP:
+		rules = append(rules,
+			accept("bgp", "tcp", "179", machines),
+			accept("ipip", "ipencap", "", machines),
+		)
+	case infrav1.FirewallOverlayWireGuard:
+		// calico and cilium
+		rules = append(rules,
+			accept("wireguard", "udp", "51820", machines),
+			accept("wireguard", "udp", "51871", machines),
+		)
+	}
+	return rules
+}
+
+func accept(purpose, proto, dport, source string) rule {
+	return rule{
+		Type:    "in",
+		Action:  "ACCEPT",
+		Proto:   proto,
+		Dport:   dport,
+		Source:  source,
+		Enable:  1,
+		Comment: ruleComment + ": " + purpose,
+	}
+}
+
+// returns the rules added by cappx ordered by position
+func managedRules(rules []rule) []rule {
+	var managed []rule
+	for _, r := range rules {
+		if strings.HasPrefix(r.Comment, ruleComment) {
+			managed = append(managed, r)
+		}
+	}
+	sort.Slice(managed, func(i, j int) bool { return managed[i].Pos < managed[j].Pos })
+	return managed
+}
+
+// managed rules must be the desired ones in order on top of the other rules
+func rulesUpToDate(managed, desired []rule) bool {
+	if len(managed) != len(desired) {
+		return false
+	}
+	for i, r := range managed {
+		d := desired[i]
+		d.Pos = i
+		if r != d {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the cidrs missing from the entries and the entries not desired.
+// proxmox reports single addresses without prefix length, so cidrs are compared as prefixes
+func diffEntries(entries []entry, cidrs []string) ([]string, []string) {
+	current := map[string]string{}
+	for _, e := range entries {
+		current[canonicalCIDR(e.CIDR)] = e.CIDR
+	}
+	desired := map[string]bool{}
+	add := []string{}
+	for _, cidr := range cidrs {
+		key := canonicalCIDR(cidr)
+		if desired[key] {
+			continue
+		}
+		desired[key] = true
+		if _, ok := current[key]; !ok {
+			add = append(add, cidr)
+		}
+	}
+	remove := []string{}
+	for key, cidr := range current {
+		if !desired[key] {
+			remove = append(remove, cidr)
+		}
+	}
+	sort.Strings(remove)
+	return add, remove
+}
+
+// e.g. 10.0.0.1 -> 10.0.0.1/32. invalid cidrs are kept as they are for proxmox to refuse them
+func canonicalCIDR(cidr string) string {
+	if addr, err := netip.ParseAddr(cidr); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()).String()
+	}
+	if prefix, err := netip.ParsePrefix(cidr); err == nil {
+		return prefix.Masked().String()
+	}
+	return cidr
+}
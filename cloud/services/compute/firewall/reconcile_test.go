@@ -0,0 +1,117 @@
+package firewall_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/firewall"
+)
+
+var _ = Describe("GroupName", Label("unit", "firewall"), func() {
+	It("should default to a name unique per cluster within the limit of proxmox", func() {
+		name := firewall.GroupName(infrav1.ClusterFirewall{}, "default", "test")
+		Expect(name).To(MatchRegexp(`^k8s-[0-9a-f]{8}$`))
+		Expect(firewall.GroupName(infrav1.ClusterFirewall{}, "other", "test")).NotTo(Equal(name))
+		Expect(firewall.GroupName(infrav1.ClusterFirewall{GroupName: "prod"}, "default", "test")).To(Equal("prod"))
+	})
+})
+
+var _ = Describe("managedComment", Label("unit", "firewall"), func() {
+	It("should be unique per cluster", func() {
+		Expect(firewall.ManagedComment("default", "test")).To(Equal("managed by cappx for cluster default/test"))
+	})
+})
+
+var _ = Describe("rules", Label("unit", "firewall"), func() {
+	ports := func(rules []firewall.Rule) []string {
+		ports := []string{}
+		for _, r := range rules {
+			ports = append(ports, r.Proto+"/"+r.Dport+" from "+r.Source)
+		}
+		return ports
+	}
+
+	It("should open the api server to anywhere and the other ports to the machines", func() {
+		rules := firewall.Rules(infrav1.ClusterFirewall{Overlay: infrav1.FirewallOverlayVXLAN}, "k8s")
+		Expect(ports(rules)).To(Equal([]string{
+			"tcp/6443 from ",
+			"tcp/2379:2380 from +k8s",
+			"tcp/10250 from +k8s",
+			"udp/8472 from +k8s",
+			"udp/4789 from +k8s",
+		}))
+		for _, r := range rules {
+			Expect(r.Type).To(Equal("in"))
+			Expect(r.Action).To(Equal("ACCEPT"))
+			Expect(r.Enable).To(Equal(1))
+			Expect(r.Comment).To(HavePrefix("managed by cappx: "))
+		}
+	})
+
+	It("should restrict the api server to its sources", func() {
+		rules := firewall.Rules(infrav1.ClusterFirewall{APIServerSources: []string{"10.0.0.0/8", "192.168.1.10"}}, "k8s")
+		Expect(rules[0].Source).To(Equal("10.0.0.0/8,192.168.1.10"))
+	})
+
+	It("should open node ports and the ports of the overlay", func() {
+		rules := firewall.Rules(infrav1.ClusterFirewall{NodePorts: true, Overlay: infrav1.FirewallOverlayBGP}, "k8s")
+		Expect(ports(rules)[3:]).To(Equal([]string{
+			"tcp/30000:32767 from ",
+			"udp/30000:32767 from ",
+			"tcp/179 from +k8s",
+			"ipencap/ from +k8s",
+		}))
+		Expect(firewall.Rules(infrav1.ClusterFirewall{Overlay: infrav1.FirewallOverlayNone}, "k8s")).To(HaveLen(3))
+		Expect(ports(firewall.Rules(infrav1.ClusterFirewall{Overlay: infrav1.FirewallOverlayGeneve}, "k8s"))[3:]).To(Equal([]string{"udp/6081 from +k8s"}))
+	})
+})
+
+var _ = Describe("managed rules", Label("unit", "firewall"), func() {
+	desired := firewall.Rules(infrav1.ClusterFirewall{Overlay: infrav1.FirewallOverlayNone}, "k8s")
+	onTop := func() []firewall.Rule {
+		rules := []firewall.Rule{}
+		for i, r := range desired {
+			r.Pos = i
+			rules = append(rules, r)
+		}
+		return rules
+	}
+
+	It("should only return rules added by cappx ordered by position", func() {
+		rules := append(onTop(), firewall.Rule{Pos: 3, Type: "in", Action: "ACCEPT", Proto: "tcp", Dport: "22"})
+		rules[0], rules[3] = rules[3], rules[0]
+		Expect(firewall.ManagedRules(rules)).To(Equal(onTop()))
+	})
+
+	It("should be up to date with the desired rules in order on top", func() {
+		Expect(firewall.RulesUpToDate(onTop(), desired)).To(BeTrue())
+		Expect(firewall.RulesUpToDate(onTop()[1:], desired)).To(BeFalse())
+		Expect(firewall.RulesUpToDate(nil, nil)).To(BeTrue())
+
+		moved := onTop()
+		for i := range moved {
+			moved[i].Pos++
+		}
+		Expect(firewall.RulesUpToDate(moved, desired)).To(BeFalse())
+
+		changed := onTop()
+		changed[0].Source = "10.0.0.0/8"
+		Expect(firewall.RulesUpToDate(changed, desired)).To(BeFalse())
+	})
+})
+
+var _ = Describe("diffEntries", Label("unit", "firewall"), func() {
+	It("should add missing cidrs and remove the others", func() {
+		entries := []firewall.Entry{{CIDR: "10.0.0.1"}, {CIDR: "10.0.0.2"}, {CIDR: "fd00::/64"}}
+		add, remove := firewall.DiffEntries(entries, []string{"10.0.0.1/32", "10.0.0.3", "10.0.0.3", "fd00::1/64"})
+		Expect(add).To(Equal([]string{"10.0.0.3"}))
+		Expect(remove).To(Equal([]string{"10.0.0.2"}))
+	})
+
+	It("should remove all entries for no cidrs", func() {
+		add, remove := firewall.DiffEntries([]firewall.Entry{{CIDR: "10.0.0.1"}}, nil)
+		Expect(add).To(BeEmpty())
+		Expect(remove).To(Equal([]string{"10.0.0.1"}))
+	})
+})
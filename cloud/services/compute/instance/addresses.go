@@ -0,0 +1,264 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// interfaces created in the guest by container runtimes, CNIs and kube-proxy.
+// their addresses are not reachable from outside of the guest, so they are skipped unless included explicitly
+var virtualInterfacePrefixes = []string{
+	"lo", "docker", "cni", "flannel", "cali", "cilium", "vxlan", "veth", "tunl",
+	"kube-", "weave", "virbr", "br-", "nodelocaldns", "lxc", "genev", "ovn", "antrea",
+}
+
+// subset of GET /nodes/{node}/lxc/{vmid}/interfaces
+type lxcInterface struct {
+	Name   string `json:"name"`
+	HWAddr string `json:"hwaddr"`
+	Inet   string `json:"inet"`
+	Inet6  string `json:"inet6"`
+}
+
+// records the addresses of all network interfaces of the running guest.
+// the previous addresses are kept while the guest can not report them
+func (s *Service) reconcileAddresses(ctx context.Context, backend Backend, guest Guest) error {
+	if guest.Status() != infrav1.InstanceStatusRunning {
+		return nil
+	}
+	filter, err := newAddressFilter(s.scope.GetAddressFilter())
+	if err != nil {
+		return err
+	}
+	interfaces, err := backend.Interfaces(ctx, guest)
+	if err != nil {
+		log.FromContext(ctx).Info("failed to get network interfaces of the guest", "error", err.Error())
+		return nil
+	}
+	interfaces = filter.interfaces(interfaces)
+	s.scope.SetAddresses(machineAddresses(s.scope.Name(), s.scope.GetNetwork().IPConfig, interfaces), interfaces)
+	return nil
+}
+
+// qemu guests report their interfaces via the qemu guest agent.
+// the primary interface has the mac address of net0
+func (b *qemuBackend) Interfaces(ctx context.Context, guest Guest) ([]infrav1.NetworkInterface, error) {
+	if !b.scope.GetOptions().Agent.IsEnabled() {
+		return nil, nil
+	}
+	config, err := guest.(*qemuGuest).vm.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result agentInterfaces
+	path := fmt.Sprintf("/nodes/%s/qemu/%d/agent/network-get-interfaces", guest.Node(), guest.VMID())
+	if err := b.client.RESTClient().Get(ctx, path, &result); err != nil {
+		return nil, fmt.Errorf("qemu guest agent: %w", err)
+	}
+	return agentNetworkInterfaces(result, macAddress(config.Net.Net0)), nil
+}
+
+// containers report their interfaces via the proxmox api. net0 is named eth0
+func (b *lxcBackend) Interfaces(ctx context.Context, guest Guest) ([]infrav1.NetworkInterface, error) {
+	var result []lxcInterface
+	path := fmt.Sprintf("/nodes/%s/lxc/%d/interfaces", guest.Node(), guest.VMID())
+	if err := b.client.RESTClient().Get(ctx, path, &result); err != nil {
+		return nil, err
+	}
+	return lxcNetworkInterfaces(result), nil
+}
+
+func agentNetworkInterfaces(result agentInterfaces, primaryMAC string) []infrav1.NetworkInterface {
+	var interfaces []infrav1.NetworkInterface
+	for _, iface := range result.Result {
+		var ips []string
+		for _, address := range iface.IPAddresses {
+			ips = append(ips, address.IPAddress)
+		}
+		interfaces = append(interfaces, infrav1.NetworkInterface{
+			Name:        iface.Name,
+			MACAddress:  strings.ToLower(iface.HardwareAddress),
+			Primary:     primaryMAC != "" && strings.EqualFold(iface.HardwareAddress, primaryMAC),
+			IPAddresses: ips,
+		})
+	}
+	return interfaces
+}
+
+func lxcNetworkInterfaces(result []lxcInterface) []infrav1.NetworkInterface {
+	var interfaces []infrav1.NetworkInterface
+	for _, iface := range result {
+		var ips []string
+		for _, cidr := range []string{iface.Inet, iface.Inet6} {
+			ip, _, _ := strings.Cut(cidr, "/")
+			ips = append(ips, ip)
+		}
+		interfaces = append(interfaces, infrav1.NetworkInterface{
+			Name:        iface.Name,
+			MACAddress:  strings.ToLower(iface.HWAddr),
+			Primary:     iface.Name == "eth0",
+			IPAddresses: ips,
+		})
+	}
+	return interfaces
+}
+
+// addressFilter is the compiled AddressFilter of the machine
+type addressFilter struct {
+	include        []*regexp.Regexp
+	exclude        []*regexp.Regexp
+	includeSubnets []*net.IPNet
+	excludeSubnets []*net.IPNet
+}
+
+func newAddressFilter(spec *infrav1.AddressFilter) (*addressFilter, error) {
+	f := &addressFilter{}
+	if spec == nil {
+		return f, nil
+	}
+	var err error
+	if f.include, err = compileRegexps(spec.IncludeInterfaces); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compileRegexps(spec.ExcludeInterfaces); err != nil {
+		return nil, err
+	}
+	if f.includeSubnets, err = parseCIDRs(spec.IncludeSubnets); err != nil {
+		return nil, err
+	}
+	if f.excludeSubnets, err = parseCIDRs(spec.ExcludeSubnets); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// returns the allowed interfaces with their allowed addresses. the primary interface comes first
+func (f *addressFilter) interfaces(interfaces []infrav1.NetworkInterface) []infrav1.NetworkInterface {
+	var filtered []infrav1.NetworkInterface
+	for _, iface := range interfaces {
+		if !f.allowsInterface(iface) {
+			continue
+		}
+		var ips []string
+		for _, address := range iface.IPAddresses {
+			if ip := net.ParseIP(address); ip != nil && f.allowsIP(ip) {
+				ips = append(ips, ip.String())
+			}
+		}
+		iface.IPAddresses = ips
+		filtered = append(filtered, iface)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Primary && !filtered[j].Primary
+	})
+	return filtered
+}
+
+func (f *addressFilter) allowsInterface(iface infrav1.NetworkInterface) bool {
+	if matchesAny(f.exclude, iface.Name) {
+		return false
+	}
+	if len(f.include) != 0 {
+		return matchesAny(f.include, iface.Name)
+	}
+	return iface.Primary || !isVirtualInterface(iface.Name)
+}
+
+func (f *addressFilter) allowsIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() || containsIP(f.excludeSubnets, ip) {
+		return false
+	}
+	return len(f.includeSubnets) == 0 || containsIP(f.includeSubnets, ip)
+}
+
+func isVirtualInterface(name string) bool {
+	for _, prefix := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid addressFilter: %w", err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var subnets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid addressFilter: %w", err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(subnets []*net.IPNet, ip net.IP) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the hostname and the addresses of the interfaces. addresses of the primary interface
+// and private addresses of the other interfaces are internal, public ones of the other interfaces are external.
+// the static ips of net0 are used while the guest reports no primary interface
+func machineAddresses(name string, config infrav1.IPConfig, interfaces []infrav1.NetworkInterface) []clusterv1.MachineAddress {
+	addresses := []clusterv1.MachineAddress{{Type: clusterv1.MachineHostName, Address: name}}
+	seen := map[string]bool{}
+	add := func(t clusterv1.MachineAddressType, ip string) {
+		if seen[ip] {
+			return
+		}
+		seen[ip] = true
+		addresses = append(addresses, clusterv1.MachineAddress{Type: t, Address: ip})
+	}
+	if len(interfaces) == 0 || !interfaces[0].Primary {
+		for _, cidr := range []string{config.IP, config.IP6} {
+			if ip, _, err := net.ParseCIDR(cidr); err == nil {
+				add(clusterv1.MachineInternalIP, ip.String())
+			}
+		}
+	}
+	for _, iface := range interfaces {
+		for _, address := range iface.IPAddresses {
+			t := clusterv1.MachineInternalIP
+			if ip := net.ParseIP(address); !iface.Primary && ip != nil && !ip.IsPrivate() {
+				t = clusterv1.MachineExternalIP
+			}
+			add(t, address)
+		}
+	}
+	return addresses
+}
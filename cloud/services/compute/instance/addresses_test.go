@@ -0,0 +1,84 @@
+package instance_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("addressFilter", Label("unit", "instance"), func() {
+	var result instance.AgentInterfaces
+
+	BeforeEach(func() {
+		result = instance.AgentInterfaces{}
+		Expect(json.Unmarshal([]byte(`{"result":[
+			{"name":"lo","hardware-address":"00:00:00:00:00:00","ip-addresses":[{"ip-address":"127.0.0.1"}]},
+			{"name":"ens19","hardware-address":"bc:24:11:00:00:02","ip-addresses":[{"ip-address":"10.1.0.10"},{"ip-address":"fe80::2"}]},
+			{"name":"eth0","hardware-address":"bc:24:11:00:00:01","ip-addresses":[{"ip-address":"192.168.0.10"}]},
+			{"name":"cni0","hardware-address":"bc:24:11:00:00:03","ip-addresses":[{"ip-address":"10.244.0.1"}]},
+			{"name":"kube-ipvs0","hardware-address":"bc:24:11:00:00:04","ip-addresses":[{"ip-address":"10.96.0.1"}]}
+		]}`), &result)).To(Succeed())
+	})
+
+	It("should skip virtual interfaces and put the primary one first", func() {
+		interfaces, err := instance.FilterInterfaces(instance.AgentNetworkInterfaces(result, "BC:24:11:00:00:01"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interfaces).To(Equal([]infrav1.NetworkInterface{
+			{Name: "eth0", MACAddress: "bc:24:11:00:00:01", Primary: true, IPAddresses: []string{"192.168.0.10"}},
+			{Name: "ens19", MACAddress: "bc:24:11:00:00:02", IPAddresses: []string{"10.1.0.10"}},
+		}))
+	})
+
+	It("should use included interfaces and subnets only", func() {
+		filter := &infrav1.AddressFilter{
+			IncludeInterfaces: []string{"^eth", "^ens"},
+			ExcludeSubnets:    []string{"10.1.0.0/16"},
+		}
+		interfaces, err := instance.FilterInterfaces(instance.AgentNetworkInterfaces(result, ""), filter)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interfaces).To(Equal([]infrav1.NetworkInterface{
+			{Name: "ens19", MACAddress: "bc:24:11:00:00:02"},
+			{Name: "eth0", MACAddress: "bc:24:11:00:00:01", IPAddresses: []string{"192.168.0.10"}},
+		}))
+	})
+
+	It("should exclude even the primary interface", func() {
+		filter := &infrav1.AddressFilter{ExcludeInterfaces: []string{"^eth0$"}}
+		interfaces, err := instance.FilterInterfaces(instance.AgentNetworkInterfaces(result, "bc:24:11:00:00:01"), filter)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interfaces).To(HaveLen(1))
+		Expect(interfaces[0].Name).To(Equal("ens19"))
+	})
+
+	It("should fail with invalid regular expressions", func() {
+		_, err := instance.FilterInterfaces(nil, &infrav1.AddressFilter{IncludeInterfaces: []string{"("}})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("machineAddresses", Label("unit", "instance"), func() {
+	It("should type addresses of the interfaces", func() {
+		interfaces := []infrav1.NetworkInterface{
+			{Name: "eth0", Primary: true, IPAddresses: []string{"192.168.0.10"}},
+			{Name: "ens19", IPAddresses: []string{"10.1.0.10", "203.0.113.10"}},
+		}
+		Expect(instance.MachineAddresses("test-vm", infrav1.IPConfig{IP: "192.168.0.10/24"}, interfaces)).To(Equal([]clusterv1.MachineAddress{
+			{Type: clusterv1.MachineHostName, Address: "test-vm"},
+			{Type: clusterv1.MachineInternalIP, Address: "192.168.0.10"},
+			{Type: clusterv1.MachineInternalIP, Address: "10.1.0.10"},
+			{Type: clusterv1.MachineExternalIP, Address: "203.0.113.10"},
+		}))
+	})
+
+	It("should use the static ip without interfaces", func() {
+		Expect(instance.MachineAddresses("test-vm", infrav1.IPConfig{IP: "192.168.0.10/24"}, nil)).To(Equal([]clusterv1.MachineAddress{
+			{Type: clusterv1.MachineHostName, Address: "test-vm"},
+			{Type: clusterv1.MachineInternalIP, Address: "192.168.0.10"},
+		}))
+	})
+})
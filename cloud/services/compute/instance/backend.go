@@ -0,0 +1,205 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/qemuconfig"
+)
+
+// Backend provisions the proxmox guest of a machine.
+// Service drives the common lifecycle and a backend implements it per guest type.
+type Backend interface {
+	// Get returns the guest identified by the provider id of the machine.
+	// returns rest.NotFoundErr if the machine has no guest yet
+	Get(ctx context.Context) (Guest, error)
+
+	// GetByVMID returns the guest having the vmid of the machine.
+	// returns rest.NotFoundErr if there is no such guest
+	GetByVMID(ctx context.Context) (Guest, error)
+
+	// Create creates the guest without starting it.
+	// a guest partially created by previous reconciles is reused
+	Create(ctx context.Context) (Guest, error)
+
+	// Plan returns where and how Create would create the guest without creating it
+	Plan(ctx context.Context) (infrav1.Plan, error)
+
+	// DeliverBootstrap makes the bootstrap data available to the guest before it starts
+	DeliverBootstrap(ctx context.Context, guest Guest) error
+
+	// Start starts the guest if it is not running
+	Start(ctx context.Context, guest Guest) error
+
+	// Update reconciles the existing guest with the machine spec and records its config
+	Update(ctx context.Context, guest Guest) error
+
+	// Delete stops and deletes the guest and the resources created for it in steps tracked by
+	// conditions of the machine. guest is nil if it is already deleted
+	Delete(ctx context.Context, guest Guest) error
+
+	// Interfaces returns the network interfaces reported by the running guest.
+	// returns nil if the guest can not report them
+	Interfaces(ctx context.Context, guest Guest) ([]infrav1.NetworkInterface, error)
+}
+
+// Guest is a qemu or a container backing a machine
+type Guest interface {
+	Node() string
+	VMID() int
+	Status() infrav1.InstanceStatus
+	// UUID is used as provider id of the machine
+	UUID(ctx context.Context) (string, error)
+	Name() string
+	// Tags are semicolon separated
+	Tags(ctx context.Context) (string, error)
+}
+
+// returns the backend for the type of the machine
+func (s *Service) backend() (Backend, error) {
+	switch t := s.scope.GetType(); t {
+	case infrav1.InstanceTypeQEMU:
+		return &qemuBackend{s}, nil
+	case infrav1.InstanceTypeLXC:
+		return &lxcBackend{s}, nil
+	default:
+		return nil, fmt.Errorf("unknown instance type %s", t)
+	}
+}
+
+type qemuBackend struct {
+	*Service
+}
+
+type qemuGuest struct {
+	vm *proxmox.VirtualMachine
+}
+
+var _ Backend = &qemuBackend{}
+var _ Guest = &qemuGuest{}
+
+func (g *qemuGuest) Node() string {
+	return g.vm.Node
+}
+
+func (g *qemuGuest) VMID() int {
+	return g.vm.VM.VMID
+}
+
+func (g *qemuGuest) Status() infrav1.InstanceStatus {
+	return infrav1.InstanceStatus(g.vm.VM.Status)
+}
+
+// smbios uuid of the qemu
+func (g *qemuGuest) UUID(ctx context.Context) (string, error) {
+	uuid, err := getBiosUUIDFromVM(ctx, g.vm)
+	if err != nil {
+		return "", err
+	}
+	return *uuid, nil
+}
+
+func (g *qemuGuest) Name() string {
+	return g.vm.VM.Name
+}
+
+func (g *qemuGuest) Tags(ctx context.Context) (string, error) {
+	config, err := g.vm.GetConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return config.Tags, nil
+}
+
+func (b *qemuBackend) Get(ctx context.Context) (Guest, error) {
+	vm, err := b.getInstance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &qemuGuest{vm}, nil
+}
+
+func (b *qemuBackend) GetByVMID(ctx context.Context) (Guest, error) {
+	vm, err := b.getQEMU(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &qemuGuest{vm}, nil
+}
+
+// reconciles qemu, os image and storage
+func (b *qemuBackend) Create(ctx context.Context) (Guest, error) {
+	vm, err := b.reconcileQEMU(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// set cloud image to hard disk and then resize.
+	// disks of a restored qemu are kept as they are in the backup
+	if b.scope.GetRestore() == nil {
+		if err := b.reconcileBootDevice(ctx, vm); err != nil {
+			return nil, err
+		}
+	}
+	return &qemuGuest{vm}, nil
+}
+
+// the cloud-config and network-config snippets are referred by cicustom of the qemu
+func (b *qemuBackend) DeliverBootstrap(ctx context.Context, guest Guest) error {
+	return b.reconcileCloudInit(ctx, guest.(*qemuGuest).vm)
+}
+
+func (b *qemuBackend) Start(ctx context.Context, guest Guest) error {
+	return b.ensureRunning(ctx, guest.(*qemuGuest).vm)
+}
+
+func (b *qemuBackend) Update(ctx context.Context, guest Guest) error {
+	vm := guest.(*qemuGuest).vm
+	config, err := vm.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := b.reconcileConfigDrift(ctx, vm.VM.VMID, *config); err != nil {
+		return err
+	}
+	before, err := configHash(*config)
+	if err != nil {
+		return err
+	}
+	if err := b.reconcileSnapshots(ctx, vm, config); err != nil {
+		return err
+	}
+	if err := b.reconcileHotplug(ctx, vm, config); err != nil {
+		return err
+	}
+	if err := b.reconcileMemory(ctx, vm, config); err != nil {
+		return err
+	}
+	if err := b.reconcileTags(ctx, vm, config); err != nil {
+		return err
+	}
+	if err := b.reconcileHA(ctx, vm.VM.VMID); err != nil {
+		return err
+	}
+	if err := b.reconcileReplication(ctx, vm); err != nil {
+		return err
+	}
+	if err := b.reconcileFirewall(ctx, qemuPath(vm)); err != nil {
+		return err
+	}
+	if err := b.reconcileBootstrapDataRotation(ctx); err != nil {
+		return err
+	}
+	if err := b.recordAppliedConfig(ctx, vm, before, config); err != nil {
+		return err
+	}
+	normalized, err := qemuconfig.Normalize(*config)
+	if err != nil {
+		return err
+	}
+	b.scope.SetConfigStatus(normalized)
+	return nil
+}
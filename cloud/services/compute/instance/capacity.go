@@ -0,0 +1,39 @@
+package instance
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// Capacity returns the resources of a node of a machine of the spec, as reported in the status of
+// ProxmoxMachineTemplates. extra disks are not ephemeral storage of the node and are not counted
+func Capacity(spec infrav1.ProxmoxMachineSpec) (corev1.ResourceList, error) {
+	demand, err := Demand(infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{
+		CPU:      spec.Hardware.CPU,
+		Sockets:  spec.Hardware.Sockets,
+		Memory:   spec.Hardware.Memory,
+		RootDisk: spec.Hardware.RootDisk,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewQuantity(int64(demand.CPU), resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(int64(demand.Memory)<<20, resource.BinarySI),
+	}
+	if demand.Disk > 0 {
+		capacity[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(int64(demand.Disk)*gib, resource.BinarySI)
+	}
+	for _, device := range spec.Hardware.PCIDevices {
+		if device.ResourceName == "" {
+			continue
+		}
+		name := corev1.ResourceName(device.ResourceName)
+		count := capacity[name]
+		count.Add(*resource.NewQuantity(1, resource.DecimalSI))
+		capacity[name] = count
+	}
+	return capacity, nil
+}
@@ -0,0 +1,46 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("Capacity", Label("unit", "instance"), func() {
+	It("should report vcpus, memory and the root disk", func() {
+		spec := infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{
+			CPU: 2, Sockets: 2, Memory: 4096, RootDisk: "50G",
+			ExtraDisks: []infrav1.ExtraDisk{{Size: resource.MustParse("100Gi")}},
+		}}
+		capacity, err := instance.Capacity(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capacity.Cpu().Value()).To(Equal(int64(4)))
+		Expect(capacity.Memory().Value()).To(Equal(int64(4096) << 20))
+		Expect(capacity.StorageEphemeral().Value()).To(Equal(int64(50) << 30))
+		Expect(capacity).To(HaveLen(3))
+	})
+
+	It("should count pci devices by their resource", func() {
+		spec := infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{
+			CPU: 8, Memory: 16384,
+			PCIDevices: []infrav1.PCIDevice{
+				{Mapping: "gpu", ResourceName: "nvidia.com/gpu"},
+				{Mapping: "gpu", ResourceName: "nvidia.com/gpu"},
+				{Mapping: "nvme"},
+			},
+		}}
+		capacity, err := instance.Capacity(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capacity.Name("nvidia.com/gpu", resource.DecimalSI).Value()).To(Equal(int64(2)))
+		Expect(capacity).NotTo(HaveKey(corev1.ResourceEphemeralStorage))
+	})
+
+	It("should reject invalid root disks", func() {
+		_, err := instance.Capacity(infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{CPU: 1, Memory: 1024, RootDisk: "large"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -2,21 +2,39 @@ package instance
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
 	"github.com/pkg/errors"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/logging"
 )
 
 const (
-	userSnippetPathFormat = "snippets/%s-user.yml"
+	userSnippetPathFormat    = "snippets/%s-user.yml"
+	networkSnippetPathFormat = "snippets/%s-network.yml"
+
+	// encrypted bootstrap data is written here and decrypted to a file in tmpfs
+	encryptedBootstrapPath = "/etc/cappx/bootstrap.sh.age"
+	decryptedBootstrapPath = "/run/cappx-bootstrap.sh"
+	defaultAgeIdentityPath = "/etc/cappx/age.key"
+
+	reasonBootstrapDataRotated = "BootstrapDataRotated"
 )
 
 // reconcileCloudInit
-func (s *Service) reconcileCloudInit(ctx context.Context) error {
+func (s *Service) reconcileCloudInit(ctx context.Context, vm *proxmox.VirtualMachine) error {
+	ctx = logging.IntoContext(ctx, logging.CloudInit)
 	log := log.FromContext(ctx)
 	log.Info("Reconciling cloud init")
 
@@ -25,18 +43,25 @@ func (s *Service) reconcileCloudInit(ctx context.Context) error {
 		return err
 	}
 
+	// network-config
+	if hasNetworkSnippet(s.scope.GetNetwork()) {
+		if err := s.reconcileCloudInitNetwork(ctx, vm); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// delete CloudConfig
+// deletes the cloud-config and network-config snippets of the machine.
+// snippets already deleted, or never written, are skipped
 func (s *Service) deleteCloudConfig(ctx context.Context) error {
 	log := log.FromContext(ctx)
+	if s.scope.NodeName() == "" {
+		return nil
+	}
 	log.Info("deleting cloud config file")
 
-	storageName := s.scope.GetClusterStorage().Name
-	path := userSnippetPath(s.scope.Name())
-	volumeID := fmt.Sprintf("%s:%s", storageName, path)
-
+	storageName := s.scope.GetSnippetStorage().Name
 	node, err := s.client.GetNode(ctx, s.scope.NodeName())
 	if err != nil {
 		return err
@@ -46,47 +71,254 @@ func (s *Service) deleteCloudConfig(ctx context.Context) error {
 		return err
 	}
 	storage.Node = node.Node
-	return storage.DeleteVolume(ctx, volumeID)
+	contents, err := storage.GetContents(ctx)
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{userSnippetPath(s.scope.Name()), networkSnippetPath(s.scope.Name())} {
+		volumeID := fmt.Sprintf("%s:%s", storageName, path)
+		if !slices.ContainsFunc(contents, func(c *api.StorageContent) bool { return c.VolID == volumeID }) {
+			continue
+		}
+		if err := s.deleteVolume(ctx, storage, volumeID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // get cloud-config user datas from Secret and ProxmoxMachine
 // then merge them and set merged user data file to Proxmox Storage
 func (s *Service) reconcileCloudInitUser(ctx context.Context) error {
-	log := log.FromContext(ctx)
-
-	// cloud init from bootstrap provider
-	bootstrap, err := s.scope.GetBootstrapData()
+	configYaml, err := s.userDataYaml(ctx)
 	if err != nil {
-		log.Error(err, "Error getting bootstrap data for machine")
-		return errors.Wrap(err, "failed to retrieve bootstrap data")
+		return err
+	}
+
+	if err := s.writeSnippet(ctx, configYaml, userSnippetPath(s.scope.Name())); err != nil {
+		return err
 	}
-	bootstrapConfig, err := cloudinit.ParseUserData(bootstrap)
+	return s.recordBootstrapData()
+}
+
+// records the hash of the bootstrap data written to the snippet
+func (s *Service) recordBootstrapData() error {
+	bootstrap, _, err := s.scope.GetBootstrapData()
 	if err != nil {
 		return err
 	}
+	s.scope.SetBootstrapDataHash(bootstrapDataHash(bootstrap))
+	return nil
+}
 
-	vmName := s.scope.Name()
-	cloudConfig, err := mergeUserDatas(bootstrapConfig, baseUserData(vmName), s.scope.GetCloudInit().UserData)
+// rewrites the user-data snippet when the bootstrap data has changed since it was written, e.g. by token renewal,
+// so that a machine booting late does not fail to join with stale data. proxmox regenerates the config drive
+// when the vm starts. snippets of joined machines are left, since cloud-init must not run again.
+// snippets written before the hash was recorded are assumed to be up to date
+func (s *Service) reconcileBootstrapDataRotation(ctx context.Context) error {
+	if s.scope.Bootstrapped() {
+		return nil
+	}
+	bootstrap, _, err := s.scope.GetBootstrapData()
 	if err != nil {
 		return err
 	}
+	hash := bootstrapDataHash(bootstrap)
+	switch s.scope.GetBootstrapDataHash() {
+	case hash:
+		return nil
+	case "":
+		s.scope.SetBootstrapDataHash(hash)
+		return nil
+	}
+	ctx = logging.IntoContext(ctx, logging.CloudInit)
+	log.FromContext(ctx).Info("bootstrap data has changed, rewriting user-data snippet")
+	if err := s.reconcileCloudInitUser(ctx); err != nil {
+		return err
+	}
+	s.scope.Eventf(reasonBootstrapDataRotated, "Rewrote the user-data snippet with the changed bootstrap data")
+	return nil
+}
 
-	configYaml, err := cloudinit.GenerateUserDataYaml(*cloudConfig)
+func bootstrapDataHash(bootstrap string) string {
+	sum := sha256.Sum256([]byte(bootstrap))
+	return hex.EncodeToString(sum[:])
+}
+
+// renders the network of net0 into network-config, since ipconfig0 can not carry routes.
+// the interface is matched by the mac address proxmox generated for net0
+func (s *Service) reconcileCloudInitNetwork(ctx context.Context, vm *proxmox.VirtualMachine) error {
+	config, err := vm.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	mac := macAddress(config.Net.Net0)
+	if mac == "" {
+		return errors.Errorf("failed to find mac address of net0: %s", config.Net.Net0)
+	}
+	networkYaml, err := cloudinit.GenerateNetworkConfigYaml("eth0", mac, s.scope.GetNetwork())
 	if err != nil {
 		return err
 	}
+	return s.writeSnippet(ctx, networkYaml, networkSnippetPath(s.scope.Name()))
+}
 
+func (s *Service) writeSnippet(ctx context.Context, content, path string) error {
 	// to do: should be set via API
-	vnc, err := s.vncClient(s.scope.NodeName())
+	vnc, err := s.vncClient(ctx, s.scope.NodeName())
 	if err != nil {
 		return err
 	}
 	defer vnc.Close()
-	filePath := fmt.Sprintf("%s/%s", s.scope.GetClusterStorage().Path, userSnippetPath(vmName))
-	if err := vnc.WriteFile(context.TODO(), configYaml, filePath); err != nil {
+	filePath := fmt.Sprintf("%s/%s", s.scope.GetSnippetStorage().Path, path)
+	if !s.scope.GetCloudInit().SnippetMode.Restricted() {
+		if err := vnc.WriteFile(ctx, content, filePath); err != nil {
+			return errors.Errorf("failed to write file error : %v", err)
+		}
+		return nil
+	}
+	return writePrivateFile(ctx, vnc, content, filePath)
+}
+
+// writes a file owned and readable by root only. the umask is kept by the shell of the
+// vnc session, so the content is never readable by others, not even while being written
+func writePrivateFile(ctx context.Context, vnc *audit.VNCClient, content, path string) error {
+	if out, _, err := vnc.Exec(ctx, "umask 077"); err != nil {
+		return errors.Wrap(err, out)
+	}
+	if err := vnc.WriteFile(ctx, content, path); err != nil {
 		return errors.Errorf("failed to write file error : %v", err)
 	}
+	out, code, err := vnc.Exec(ctx, restrictFileCommand(path))
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return errors.Errorf("failed to restrict permission of %s: %s", path, out)
+	}
+	return nil
+}
+
+// files written before are restricted as well, since WriteFile keeps nothing but the content
+func restrictFileCommand(path string) string {
+	return fmt.Sprintf("chown root:root %[1]s && chmod 600 %[1]s", path)
+}
+
+// returns cloud-config merging bootstrap data and user data of the ProxmoxMachine
+func (s *Service) userDataYaml(ctx context.Context) (string, error) {
+	log := log.FromContext(ctx)
+
+	// cloud init from bootstrap provider
+	bootstrap, declared, err := s.scope.GetBootstrapData()
+	if err != nil {
+		log.Error(err, "Error getting bootstrap data for machine")
+		return "", errors.Wrap(err, "failed to retrieve bootstrap data")
+	}
+	format, err := cloudinit.ResolveFormat(declared, bootstrap)
+	if err != nil {
+		return "", err
+	}
+
+	qemu := s.scope.GetType() == infrav1.InstanceTypeQEMU
+	if format == cloudinit.FormatIgnition {
+		// ignition reads the user-data of the config drive as it is. nothing can be merged into it
+		if err := ignitionSupported(s.scope.GetType(), qemu && s.scope.GetOptions().OSType.IsWindows(), s.scope.GetCloudInit()); err != nil {
+			return "", err
+		}
+		log.Info("delivering bootstrap data as it is", "format", format)
+		return bootstrap, nil
+	}
+	log.Info("merging bootstrap data", "format", format)
+
+	base := baseUserData(s.scope.Name(), qemu && s.scope.GetOptions().Agent.IsEnabled())
+	if qemu && s.scope.GetOptions().OSType.IsWindows() {
+		base = windowsUserData(s.scope.Name())
+		bootstrap = cloudinit.StripJinjaHeader(bootstrap)
+	} else if base, err = containerdUserData(base, s.scope.GetProxy(), s.scope.GetRegistries()); err != nil {
+		return "", err
+	}
+	userData, err := mergeUserDatas(&infrav1.UserData{}, base, s.scope.GetCloudInit().UserData)
+	if err != nil {
+		return "", err
+	}
+	if key := s.scope.GetSSHPublicKey(); key != "" && !slices.Contains(userData.SSHAuthorizedKeys, key) {
+		userData.SSHAuthorizedKeys = append(userData.SSHAuthorizedKeys, key)
+	}
+	user, password, err := s.scope.GetCloudInitCredentials()
+	if err != nil {
+		return "", err
+	}
+	credentials := &infrav1.UserData{}
+	if password != "" {
+		credentials = credentialsUserData(user, password)
+	}
+	if encryption := s.scope.GetCloudInit().Encryption; encryption != nil {
+		if !qemu || s.scope.GetOptions().OSType.IsWindows() {
+			return "", errors.New("cloudInit.encryption is supported by linux vms only")
+		}
+		log.Info("encrypting bootstrap data", "recipients", len(encryption.Recipients))
+		return encryptedUserData(bootstrap, *userData, *credentials, s.scope.Name(), *encryption)
+	}
+	if password != "" {
+		if userData, err = cloudinit.MergeUserDatas(credentials, userData); err != nil {
+			return "", err
+		}
+	}
+	// bootstrap data is merged without parsing into UserData so that
+	// keys and formats of k3s and rke2 bootstrap providers survive
+	return cloudinit.MergeBootstrapData(bootstrap, *userData)
+}
 
+// returns cloud-config of the user data, carrying the bootstrap data and the credentials
+// encrypted with age. they are rendered as a shell script, which runcmd decrypts and runs
+// with the identity of the image. nothing of them is left unencrypted on the snippet storage
+func encryptedUserData(bootstrap string, userData, credentials infrav1.UserData, hostname string, encryption infrav1.SnippetEncryption) (string, error) {
+	config, err := cloudinit.MergeBootstrapData(bootstrap, credentials)
+	if err != nil {
+		return "", err
+	}
+	script, err := cloudinit.ShellScript(config, hostname)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := cloudinit.EncryptAge([]byte(script), encryption.Recipients)
+	if err != nil {
+		return "", err
+	}
+	identity := encryption.IdentityPath
+	if identity == "" {
+		identity = defaultAgeIdentityPath
+	}
+	userData.WriteFiles = append(userData.WriteFiles, infrav1.WriteFiles{
+		Path:        encryptedBootstrapPath,
+		Permissions: "0600",
+		Encoding:    "b64",
+		Content:     base64.StdEncoding.EncodeToString(encrypted),
+	})
+	userData.RunCmd = append(userData.RunCmd, decryptBootstrapCommand(identity))
+	return cloudinit.MergeBootstrapData("", userData)
+}
+
+// decrypts the bootstrap script readable by root only, runs it and removes it
+func decryptBootstrapCommand(identity string) string {
+	return fmt.Sprintf("(umask 077 && age -d -i %[1]s -o %[2]s %[3]s) && sh %[2]s; rc=$?; rm -f %[2]s; exit $rc",
+		identity, decryptedBootstrapPath, encryptedBootstrapPath)
+}
+
+// returns an error for settings of the machine which ignition bootstrap data can not be combined with
+func ignitionSupported(instanceType infrav1.InstanceType, windows bool, cloudInit infrav1.CloudInit) error {
+	switch {
+	case instanceType == infrav1.InstanceTypeLXC:
+		return errors.New("ignition bootstrap data is not supported by containers")
+	case windows:
+		return errors.New("ignition bootstrap data is not supported by windows machines")
+	case cloudInit.UserData != nil:
+		return errors.New("cloudInit.user can not be merged into ignition bootstrap data")
+	case cloudInit.CredentialsSecretRef != nil:
+		return errors.New("cloudInit.credentialsSecretRef can not be merged into ignition bootstrap data")
+	case cloudInit.Encryption != nil:
+		return errors.New("cloudInit.encryption is not supported for ignition bootstrap data")
+	}
 	return nil
 }
 
@@ -118,7 +350,55 @@ func userSnippetPath(vmName string) string {
 	return fmt.Sprintf(userSnippetPathFormat, vmName)
 }
 
-func baseUserData(vmName string) *infrav1.UserData {
+func networkSnippetPath(vmName string) string {
+	return fmt.Sprintf(networkSnippetPathFormat, vmName)
+}
+
+// the network-config snippet is only used for what ipconfig0 can not carry
+func hasNetworkSnippet(network infrav1.Network) bool {
+	return len(network.IPConfig.Routes) != 0
+}
+
+// returns the mac address of a network device option. e.g. "virtio=BC:24:11:00:00:01,bridge=vmbr0"
+func macAddress(net string) string {
+	model, _, _ := strings.Cut(net, ",")
+	_, mac, _ := strings.Cut(model, "=")
+	if strings.Count(mac, ":") != 5 {
+		return ""
+	}
+	return mac
+}
+
+// merges the proxy and the registries of the cluster into the base user data
+func containerdUserData(base *infrav1.UserData, proxy *infrav1.Proxy, registries []infrav1.Registry) (*infrav1.UserData, error) {
+	var err error
+	if proxy != nil {
+		if base, err = cloudinit.MergeUserDatas(base, cloudinit.ProxyUserData(*proxy)); err != nil {
+			return nil, err
+		}
+	}
+	if len(registries) != 0 {
+		if base, err = cloudinit.MergeUserDatas(base, cloudinit.RegistryUserData(registries)); err != nil {
+			return nil, err
+		}
+	}
+	return base, nil
+}
+
+// sets the password of the default user like ciuser and cipassword of proxmox do.
+// the user of the user data is kept unless the secret sets one
+func credentialsUserData(user, password string) *infrav1.UserData {
+	return &infrav1.UserData{
+		User:     user,
+		Password: password,
+		ChPasswd: infrav1.ChPasswd{Expire: "false"},
+	}
+}
+
+func baseUserData(vmName string, agent bool) *infrav1.UserData {
+	if !agent {
+		return &infrav1.UserData{HostName: vmName}
+	}
 	return &infrav1.UserData{
 		HostName: vmName,
 		Packages: []string{"qemu-guest-agent"},
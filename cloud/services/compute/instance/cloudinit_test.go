@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
 )
 
@@ -63,3 +64,96 @@ var _ = Describe("mergeUserDatas", Label("unit", "cloudinit"), func() {
 		})
 	})
 })
+
+var _ = Describe("macAddress", Label("unit", "cloudinit"), func() {
+	It("should return the mac address of the model", func() {
+		Expect(instance.MacAddress("virtio=BC:24:11:00:00:01,bridge=vmbr0,firewall=1")).To(Equal("BC:24:11:00:00:01"))
+	})
+
+	It("should return empty without mac address", func() {
+		Expect(instance.MacAddress("bridge=vmbr0")).To(BeEmpty())
+		Expect(instance.MacAddress("")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("restrictFileCommand", Label("unit", "cloudinit"), func() {
+	It("should make the file readable by root only", func() {
+		Expect(instance.RestrictFileCommand("/var/lib/vz/snippets/test-user.yml")).To(Equal("chown root:root /var/lib/vz/snippets/test-user.yml && chmod 600 /var/lib/vz/snippets/test-user.yml"))
+	})
+})
+
+var _ = Describe("SnippetMode", Label("unit", "cloudinit"), func() {
+	It("should be restricted by default", func() {
+		Expect(infrav1.SnippetMode("").Restricted()).To(BeTrue())
+		Expect(infrav1.SnippetModeRestricted.Restricted()).To(BeTrue())
+		Expect(infrav1.SnippetModeShared.Restricted()).To(BeFalse())
+	})
+})
+
+var _ = Describe("credentialsUserData", Label("unit", "cloudinit"), func() {
+	It("should override the password of the user data", func() {
+		userData := &infrav1.UserData{User: "ubuntu", Password: "inline", RunCmd: []string{"command A"}}
+		merged, err := cloudinit.MergeUserDatas(instance.CredentialsUserData("", "secret"), userData)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged.User).To(Equal("ubuntu"))
+		Expect(merged.Password).To(Equal("secret"))
+		Expect(merged.ChPasswd.Expire).To(Equal("false"))
+		Expect(merged.RunCmd).To(Equal([]string{"command A"}))
+	})
+
+	It("should override the user if set", func() {
+		merged, err := cloudinit.MergeUserDatas(instance.CredentialsUserData("admin", "secret"), &infrav1.UserData{User: "ubuntu"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged.User).To(Equal("admin"))
+	})
+})
+
+var _ = Describe("ignitionSupported", Label("unit", "cloudinit"), func() {
+	It("should accept plain qemu machines", func() {
+		Expect(instance.IgnitionSupported(infrav1.InstanceTypeQEMU, false, infrav1.CloudInit{SnippetMode: infrav1.SnippetModeShared})).To(Succeed())
+	})
+
+	It("should reject what can not be combined with ignition", func() {
+		Expect(instance.IgnitionSupported(infrav1.InstanceTypeLXC, false, infrav1.CloudInit{})).To(MatchError(ContainSubstring("containers")))
+		Expect(instance.IgnitionSupported(infrav1.InstanceTypeQEMU, true, infrav1.CloudInit{})).To(MatchError(ContainSubstring("windows")))
+		Expect(instance.IgnitionSupported(infrav1.InstanceTypeQEMU, false, infrav1.CloudInit{UserData: &infrav1.UserData{}})).To(MatchError(ContainSubstring("cloudInit.user")))
+	})
+})
+
+var _ = Describe("encryptedUserData", Label("unit", "cloudinit"), func() {
+	encryption := infrav1.SnippetEncryption{Recipients: []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"}}
+	bootstrap := "#cloud-config\nwrite_files:\n  - path: /run/kubeadm/kubeadm-join-config.yaml\n    content: token-abcdef\nruncmd:\n  - kubeadm join --config /run/kubeadm/kubeadm-join-config.yaml\n"
+
+	It("should leave no bootstrap data and credentials in plain text", func() {
+		config, err := instance.EncryptedUserData(bootstrap, infrav1.UserData{HostName: "test"}, *instance.CredentialsUserData("admin", "password-xyz"), "test", encryption)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(HavePrefix("#cloud-config\n"))
+		Expect(config).To(ContainSubstring("hostname: test"))
+		Expect(config).To(ContainSubstring("path: /etc/cappx/bootstrap.sh.age"))
+		Expect(config).To(ContainSubstring("age -d -i /etc/cappx/age.key -o /run/cappx-bootstrap.sh /etc/cappx/bootstrap.sh.age"))
+		Expect(config).NotTo(ContainSubstring("token-abcdef"))
+		Expect(config).NotTo(ContainSubstring("kubeadm join"))
+		Expect(config).NotTo(ContainSubstring("password-xyz"))
+	})
+
+	It("should decrypt with the identity of the image", func() {
+		encryption := encryption
+		encryption.IdentityPath = "/var/lib/age/key.txt"
+		config, err := instance.EncryptedUserData(bootstrap, infrav1.UserData{}, infrav1.UserData{}, "test", encryption)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(ContainSubstring("age -d -i /var/lib/age/key.txt "))
+	})
+
+	It("should fail for malformed recipients", func() {
+		_, err := instance.EncryptedUserData(bootstrap, infrav1.UserData{}, infrav1.UserData{}, "test", infrav1.SnippetEncryption{Recipients: []string{"age1invalid"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("bootstrapDataHash", Label("unit", "cloudinit"), func() {
+	It("should change with the bootstrap data", func() {
+		Expect(instance.BootstrapDataHash("#cloud-config\n")).To(HaveLen(64))
+		Expect(instance.BootstrapDataHash("token: a")).To(Equal(instance.BootstrapDataHash("token: a")))
+		Expect(instance.BootstrapDataHash("token: a")).NotTo(Equal(instance.BootstrapDataHash("token: b")))
+	})
+})
@@ -0,0 +1,51 @@
+package instance
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+const (
+	viewerXtermJS = "xtermjs"
+	viewerNoVNC   = "novnc"
+)
+
+// returns the console of the guest. the url is omitted if the endpoint can not be parsed
+func console(endpoint, name, node string, vmid int, instanceType infrav1.InstanceType, options infrav1.Options) infrav1.Console {
+	c := infrav1.Console{Node: node, VMID: vmid, Viewer: consoleViewer(instanceType, options)}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return c
+	}
+	kind := "kvm"
+	if instanceType == infrav1.InstanceTypeLXC {
+		kind = "lxc"
+	}
+	query := url.Values{}
+	query.Set("console", kind)
+	query.Set(c.Viewer, "1")
+	query.Set("vmid", strconv.Itoa(vmid))
+	query.Set("vmname", name)
+	query.Set("node", node)
+	c.URL = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/", RawQuery: query.Encode()}).String()
+	return c
+}
+
+// qemus use serial0 as display unless vga is specified, windows uses a graphical display
+func consoleViewer(instanceType infrav1.InstanceType, options infrav1.Options) string {
+	switch {
+	case instanceType == infrav1.InstanceTypeLXC:
+		return viewerXtermJS
+	case options.VGA != nil:
+		if strings.HasPrefix(options.VGA.Type, "serial") {
+			return viewerXtermJS
+		}
+		return viewerNoVNC
+	case options.OSType.IsWindows():
+		return viewerNoVNC
+	}
+	return viewerXtermJS
+}
@@ -0,0 +1,43 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("console", Label("unit", "instance"), func() {
+	endpoint := "https://192.168.0.101:8006/api2/json"
+
+	It("should link serial console of qemu", func() {
+		console := instance.Console(endpoint, "cappx-test", "pve1", 100, infrav1.InstanceTypeQEMU, infrav1.Options{})
+		Expect(console).To(Equal(infrav1.Console{
+			URL:    "https://192.168.0.101:8006/?console=kvm&node=pve1&vmid=100&vmname=cappx-test&xtermjs=1",
+			Node:   "pve1",
+			VMID:   100,
+			Viewer: "xtermjs",
+		}))
+	})
+
+	It("should link lxc console", func() {
+		console := instance.Console(endpoint, "ct", "pve2", 101, infrav1.InstanceTypeLXC, infrav1.Options{})
+		Expect(console.URL).To(Equal("https://192.168.0.101:8006/?console=lxc&node=pve2&vmid=101&vmname=ct&xtermjs=1"))
+	})
+
+	It("should use novnc for graphical displays", func() {
+		console := instance.Console(endpoint, "win", "pve1", 100, infrav1.InstanceTypeQEMU, infrav1.Options{OSType: "win11"})
+		Expect(console.Viewer).To(Equal("novnc"))
+		console = instance.Console(endpoint, "vm", "pve1", 100, infrav1.InstanceTypeQEMU, infrav1.Options{VGA: &infrav1.VGA{Type: "std"}})
+		Expect(console.Viewer).To(Equal("novnc"))
+		console = instance.Console(endpoint, "win", "pve1", 100, infrav1.InstanceTypeQEMU, infrav1.Options{OSType: "win11", VGA: &infrav1.VGA{Type: "serial0"}})
+		Expect(console.Viewer).To(Equal("xtermjs"))
+	})
+
+	It("should omit url for invalid endpoint", func() {
+		console := instance.Console("192.168.0.101", "vm", "pve1", 100, infrav1.InstanceTypeQEMU, infrav1.Options{})
+		Expect(console.URL).To(BeEmpty())
+		Expect(console.VMID).To(Equal(100))
+	})
+})
@@ -0,0 +1,350 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/retry"
+)
+
+// deletionStep is a step deleting the instance tracked by a condition of the machine
+type deletionStep struct {
+	condition clusterv1.ConditionType
+	run       func() error
+}
+
+// runs the steps in order skipping the completed ones. the first failure stops the deletion
+// and is recorded on the condition of its step, so that it is retried by the next reconcile
+func (s *Service) runDeletionSteps(ctx context.Context, steps []deletionStep) error {
+	log := log.FromContext(ctx)
+	for _, step := range steps {
+		if s.scope.DeletionStepDone(step.condition) {
+			continue
+		}
+		if err := step.run(); err != nil {
+			s.scope.SetDeletionStepFailed(step.condition, err)
+			return fmt.Errorf("deletion step %s failed: %w", step.condition, err)
+		}
+		log.Info("deletion step completed", "step", step.condition)
+		s.scope.SetDeletionStepDone(step.condition)
+	}
+	return nil
+}
+
+// guest is nil if the qemu is already deleted. the remaining steps clean up around it
+func (b *qemuBackend) Delete(ctx context.Context, guest Guest) error {
+	var vm *proxmox.VirtualMachine
+	if guest != nil {
+		vm = guest.(*qemuGuest).vm
+	}
+	vmid := b.deletionVMID(guest)
+	return b.runDeletionSteps(ctx, []deletionStep{
+		// stop requests of ha-managed vm are handed over to the ha manager.
+		// deregister it so that the vm can be stopped right away
+		{infrav1.HAResourceDeletedCondition, func() error {
+			if vmid == nil {
+				return nil
+			}
+			return b.deleteHA(ctx, *vmid)
+		}},
+		{infrav1.ReplicationDeletedCondition, func() error {
+			if vmid == nil {
+				return nil
+			}
+			return b.deleteReplication(ctx, *vmid)
+		}},
+		// must stop or pause instance before deletion
+		// otherwise deletion will be fail
+		{infrav1.InstanceStoppedCondition, func() error {
+			if vm == nil {
+				return nil
+			}
+			return b.ensureStoppedOrPaused(ctx, vm)
+		}},
+		{infrav1.DisksWipedCondition, func() error {
+			return b.wipeVolumes(ctx, vmid)
+		}},
+		{infrav1.CloudInitDeletedCondition, func() error {
+			return b.deleteCloudConfig(ctx)
+		}},
+		{infrav1.InstanceDeletedCondition, func() error {
+			if vm == nil {
+				return nil
+			}
+			return audit.Do(ctx, &b.client, http.MethodDelete, qemuPath(vm), func() error {
+				return vm.Delete(ctx)
+			})
+		}},
+		{infrav1.VolumesDeletedCondition, func() error {
+			return b.deleteVolumes(ctx, vmid)
+		}},
+	})
+}
+
+// guest is nil if the container is already deleted
+func (b *lxcBackend) Delete(ctx context.Context, guest Guest) error {
+	vmid := b.deletionVMID(guest)
+	return b.runDeletionSteps(ctx, []deletionStep{
+		{infrav1.InstanceStoppedCondition, func() error {
+			if guest == nil || guest.Status() != infrav1.InstanceStatusRunning {
+				return nil
+			}
+			return b.lxcTask(ctx, guest, "POST", "status/stop")
+		}},
+		{infrav1.DisksWipedCondition, func() error {
+			return b.wipeVolumes(ctx, vmid)
+		}},
+		{infrav1.InstanceDeletedCondition, func() error {
+			if guest == nil {
+				return nil
+			}
+			return b.lxcTask(ctx, guest, "DELETE", "")
+		}},
+		{infrav1.VolumesDeletedCondition, func() error {
+			return b.deleteVolumes(ctx, vmid)
+		}},
+	})
+}
+
+// refuses to stop and delete the guest found by the vmid of the machine unless it belongs to the machine,
+// so that a stale vmid never destroys an unrelated guest, e.g. after the vm was recreated by hand.
+// guests referred by provider ids without uuid are verified before they are updated too
+func (s *Service) verifyOwnership(ctx context.Context, instance Guest) error {
+	tags, err := instance.Tags(ctx)
+	if err != nil {
+		return err
+	}
+	owner := guest.Guest{Name: instance.Name(), Tags: tags}
+	if !owner.OwnedBy(guest.MachineTag(s.scope.Namespace(), s.scope.Name()), s.ownedNames(instance.VMID())...) {
+		err := fmt.Errorf("vmid %d is used by %q not tagged with the machine, refusing to manage it", instance.VMID(), instance.Name())
+		s.scope.SetOwnershipMismatch(err.Error())
+		return err
+	}
+	s.scope.SetOwnershipVerified()
+	return nil
+}
+
+// names the guest of the machine may have if it has no machine tag
+func (s *Service) ownedNames(vmid int) []string {
+	names := []string{s.scope.Name()}
+	if name, err := renderName(s.scope.GetNameTemplate(), s.nameData(vmid)); err == nil {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *Service) deletionVMID(guest Guest) *int {
+	if guest != nil {
+		vmid := guest.VMID()
+		return &vmid
+	}
+	return s.scope.GetVMID()
+}
+
+// deletes disks of the vmid left on the storage of the machine after the instance is deleted,
+// e.g. unreferenced disks of failed moves or imports
+func (s *Service) deleteVolumes(ctx context.Context, vmid *int) error {
+	log := log.FromContext(ctx)
+	if vmid == nil || s.scope.NodeName() == "" || s.scope.GetStorage() == "" {
+		return nil
+	}
+	// the vmid may have been taken by another guest since the instance was deleted. the disks are its own
+	guests, err := guest.List(ctx, &s.client)
+	if err != nil {
+		return err
+	}
+	if g := foreignGuest(guests, *vmid, guest.MachineTag(s.scope.Namespace(), s.scope.Name()), s.ownedNames(*vmid)...); g != nil {
+		log.Info("vmid is used by another guest, leaving its volumes alone", "vmid", *vmid, "guest", g.Name)
+		return nil
+	}
+	storage, err := s.client.Storage(ctx, s.scope.GetStorage())
+	if err != nil {
+		return err
+	}
+	storage.Node = s.scope.NodeName()
+	contents, err := storage.GetContents(ctx)
+	if err != nil {
+		return err
+	}
+	for _, volume := range leftoverVolumes(contents, *vmid) {
+		log.Info("deleting volume left by the instance", "volume", volume)
+		if err := s.deleteVolume(ctx, storage, volume); err != nil {
+			return fmt.Errorf("failed to delete volume %s: %w", volume, err)
+		}
+	}
+	return nil
+}
+
+// overwrites the disks of the vmid with zeros on the node of the machine if the machine asks for it.
+// disks on the storage of extra disks are wiped too
+func (s *Service) wipeVolumes(ctx context.Context, vmid *int) error {
+	log := log.FromContext(ctx)
+	if !s.scope.WipeDisksOnDelete() || vmid == nil || s.scope.NodeName() == "" || s.scope.GetStorage() == "" {
+		return nil
+	}
+	var volumes []string
+	for _, name := range s.diskStorages(s.scope.GetStorage()) {
+		storage, err := s.client.Storage(ctx, name)
+		if err != nil {
+			return err
+		}
+		storage.Node = s.scope.NodeName()
+		contents, err := storage.GetContents(ctx)
+		if err != nil {
+			return err
+		}
+		volumes = append(volumes, leftoverVolumes(contents, *vmid)...)
+	}
+	if len(volumes) == 0 {
+		return nil
+	}
+	vnc, err := s.vncClient(ctx, s.scope.NodeName())
+	if err != nil {
+		return err
+	}
+	defer vnc.Close()
+	for _, volume := range volumes {
+		log.Info("wiping volume", "volume", volume)
+		out, code, err := vnc.Exec(ctx, wipeVolumeCommand(volume))
+		if err != nil {
+			return fmt.Errorf("failed to wipe volume %s: %w: %s", volume, err, out)
+		}
+		if code != 0 {
+			return fmt.Errorf("failed to wipe volume %s: %s", volume, out)
+		}
+	}
+	return nil
+}
+
+// returns the storage of the machine and the storages of its extra disks
+func (s *Service) diskStorages(storage string) []string {
+	storages := []string{storage}
+	for _, disk := range s.scope.GetHardware().ExtraDisks {
+		if disk.Storage != "" && !slices.Contains(storages, disk.Storage) {
+			storages = append(storages, disk.Storage)
+		}
+	}
+	return storages
+}
+
+// fails if the machine asks for wiping its disks on deletion but the volumes on one of its storages
+// could not be wiped, so that the machine is not created only to get stuck in the DisksWiped step
+func (s *Service) checkWipeable(ctx context.Context, storage string, container bool) error {
+	if !s.scope.WipeDisksOnDelete() {
+		return nil
+	}
+	for _, name := range s.diskStorages(storage) {
+		var config struct {
+			Type string `json:"type"`
+			KRBD int    `json:"krbd"`
+		}
+		if err := s.client.RESTClient().Get(ctx, "/storage/"+url.PathEscape(name), &config); err != nil {
+			return err
+		}
+		if !wipeable(config.Type, config.KRBD == 1, container) {
+			return fmt.Errorf("wipeDisksOnDelete is not supported for %s storage %s", config.Type, name)
+		}
+	}
+	return nil
+}
+
+// returns true if pvesm path resolves the volumes of the storage type to block devices or files,
+// which are the only ones wipeVolumeCommand zeroes
+func wipeable(storageType string, krbd, container bool) bool {
+	switch storageType {
+	case "lvm", "lvmthin", "iscsi", "dir", "nfs", "cifs":
+		return true
+	case "zfspool", "btrfs":
+		// containers get subvolumes instead of zvols or image files
+		return !container
+	case "rbd":
+		// qemu accesses rbd images by librbd unless krbd is on. containers are always mapped by krbd
+		return krbd || container
+	default:
+		// e.g. ZFS over iSCSI and glusterfs are accessed by qemu over the network
+		return false
+	}
+}
+
+// zeroes the volume in place. block devices, e.g. of lvm or zfs, are zeroed by blkdiscard if the
+// device supports it, files, e.g. of directory or nfs storage, by shred. other volumes, e.g. of ceph
+// or zfs subvolumes of containers, are refused so that the deletion does not remove them unwiped.
+// checkWipeable keeps machines on such storages from being created in the first place
+func wipeVolumeCommand(volume string) string {
+	return fmt.Sprintf(`p=$(pvesm path '%s') && if [ -b "$p" ]; then blkdiscard -z "$p" 2>/dev/null || shred -n 0 -z "$p"; elif [ -f "$p" ]; then shred -n 0 -z -x "$p"; else echo "can not wipe $p"; false; fi`, volume)
+}
+
+// returns the guest having the vmid unless it belongs to the machine
+func foreignGuest(guests []guest.Guest, vmid int, machineTag string, names ...string) *guest.Guest {
+	g, err := guest.Find(guests, vmid)
+	if err != nil || g.OwnedBy(machineTag, names...) {
+		return nil
+	}
+	return g
+}
+
+// returns disks and container volumes owned by the vmid
+func leftoverVolumes(contents []*api.StorageContent, vmid int) []string {
+	var volumes []string
+	for _, c := range contents {
+		if c.VMID == vmid && (c.Content == "images" || c.Content == "rootdir") {
+			volumes = append(volumes, c.VolID)
+		}
+	}
+	return volumes
+}
+
+// server errors, e.g. timeouts of requests proxmox may have carried out, leave the guest to be reused
+// by the next attempt. other failures, e.g. failed or stuck tasks, leave a guest which can not become
+// the one of the machine
+func discardsPartialGuest(err error) bool {
+	return !retry.IsServerError(err)
+}
+
+// deletes the guest and the volumes left by a failed creation. only the guest whose creation has been
+// started by this reconcile is deleted, so that a guest of someone else having the vmid is never touched
+func (s *Service) deletePartialGuest(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	vmid := s.created
+	if vmid == nil {
+		return nil
+	}
+	guests, err := guest.List(ctx, &s.client)
+	if err != nil {
+		return err
+	}
+	if g, err := guest.Find(guests, *vmid); err == nil {
+		if !partialGuestOwned(*g, guest.MachineTag(s.scope.Namespace(), s.scope.Name())) {
+			return nil
+		}
+		log.Info("deleting partially created guest", "node", g.Node, "vmid", g.VMID)
+		var upid string
+		if err := audit.REST(&s.client).Delete(ctx, fmt.Sprintf("/nodes/%s/%s/%d?purge=1&destroy-unreferenced-disks=1", g.Node, g.Type, g.VMID), nil, &upid); err != nil {
+			return fmt.Errorf("failed to delete guest %d: %w", g.VMID, err)
+		}
+		if err := s.waitTask(ctx, g.Node, upid); err != nil {
+			return fmt.Errorf("failed to delete guest %d: %w", g.VMID, err)
+		}
+	}
+	return s.deleteVolumes(ctx, vmid)
+}
+
+// the guest created by this reconcile is untagged until it is configured. it is not the partial one
+// if it is tagged with another machine, e.g. another manager has won the vmid
+func partialGuestOwned(g guest.Guest, machineTag string) bool {
+	if _, tagged := g.MachineNamespace(); tagged {
+		return g.HasTag(machineTag)
+	}
+	return true
+}
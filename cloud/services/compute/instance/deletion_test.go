@@ -0,0 +1,135 @@
+package instance_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("leftoverVolumes", Label("unit", "instance"), func() {
+	It("should return disks of the vmid only", func() {
+		contents := []*api.StorageContent{
+			{VolID: "local-lvm:vm-100-disk-0", VMID: 100, Content: "images"},
+			{VolID: "local-lvm:vm-100-disk-1", VMID: 100, Content: "images"},
+			{VolID: "local-lvm:subvol-100-disk-0", VMID: 100, Content: "rootdir"},
+			{VolID: "local-lvm:vm-101-disk-0", VMID: 101, Content: "images"},
+			{VolID: "local:backup/vzdump-qemu-100.vma.zst", VMID: 100, Content: "backup"},
+		}
+		Expect(instance.LeftoverVolumes(contents, 100)).To(Equal([]string{
+			"local-lvm:vm-100-disk-0", "local-lvm:vm-100-disk-1", "local-lvm:subvol-100-disk-0",
+		}))
+	})
+})
+
+var _ = Describe("foreignGuest", Label("unit", "instance"), func() {
+	guests := []guest.Guest{
+		{VMID: 100, Name: "other", Tags: "cappx;machine.default.other"},
+		{VMID: 101, Name: "mycluster-cp-abc", Tags: "cappx;machine.default.mycluster-cp-abc"},
+		{VMID: 102, Name: "mycluster-cp-abc"},
+	}
+	tag := guest.MachineTag("default", "mycluster-cp-abc")
+
+	It("should return the guest which has taken the vmid", func() {
+		Expect(instance.ForeignGuest(guests, 100, tag, "mycluster-cp-abc")).To(Equal(&guests[0]))
+	})
+
+	It("should return nil for the guest of the machine or a free vmid", func() {
+		Expect(instance.ForeignGuest(guests, 101, tag, "mycluster-cp-abc")).To(BeNil())
+		Expect(instance.ForeignGuest(guests, 102, tag, "mycluster-cp-abc")).To(BeNil())
+		Expect(instance.ForeignGuest(guests, 103, tag, "mycluster-cp-abc")).To(BeNil())
+	})
+})
+
+var _ = Describe("wipeVolumeCommand", Label("unit", "instance"), func() {
+	// runs the command with a pvesm resolving every volume to path
+	run := func(volume, path string) (string, error) {
+		bin := GinkgoT().TempDir()
+		pvesm := fmt.Sprintf("#!/bin/sh\n[ \"$1 $2\" = \"path %s\" ] && echo '%s'\n", volume, path)
+		Expect(os.WriteFile(filepath.Join(bin, "pvesm"), []byte(pvesm), 0o755)).To(Succeed())
+		cmd := exec.Command("sh", "-c", instance.WipeVolumeCommand(volume))
+		cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	It("should zero the file of the volume", func() {
+		disk := filepath.Join(GinkgoT().TempDir(), "vm-100-disk-0.raw")
+		Expect(os.WriteFile(disk, []byte("secret data"), 0o600)).To(Succeed())
+
+		out, err := run("local:100/vm-100-disk-0.raw", disk)
+		Expect(err).NotTo(HaveOccurred(), out)
+		Expect(os.ReadFile(disk)).To(Equal(make([]byte, len("secret data"))))
+	})
+
+	It("should fail for volumes being neither a file nor a block device", func() {
+		out, err := run("local-zfs:subvol-100-disk-0", GinkgoT().TempDir())
+		Expect(err).To(HaveOccurred())
+		Expect(out).To(ContainSubstring("can not wipe"))
+
+		out, err = run("ceph:vm-100-disk-0", "rbd:rbd/vm-100-disk-0:conf=/etc/pve/ceph.conf")
+		Expect(err).To(HaveOccurred())
+		Expect(out).To(ContainSubstring("can not wipe"))
+	})
+
+	It("should fail if pvesm does not know the volume", func() {
+		_, err := run("local-lvm:vm-100-disk-0", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("wipeable", Label("unit", "instance"), func() {
+	It("should accept storages resolving to block devices or files", func() {
+		for _, storageType := range []string{"lvm", "lvmthin", "iscsi", "dir", "nfs", "cifs"} {
+			Expect(instance.Wipeable(storageType, false, false)).To(BeTrue(), storageType)
+			Expect(instance.Wipeable(storageType, false, true)).To(BeTrue(), storageType)
+		}
+		Expect(instance.Wipeable("zfspool", false, false)).To(BeTrue())
+		Expect(instance.Wipeable("rbd", true, false)).To(BeTrue())
+		Expect(instance.Wipeable("rbd", false, true)).To(BeTrue())
+	})
+
+	It("should reject storages accessed over the network or by subvolumes", func() {
+		Expect(instance.Wipeable("rbd", false, false)).To(BeFalse())
+		Expect(instance.Wipeable("zfspool", false, true)).To(BeFalse())
+		Expect(instance.Wipeable("btrfs", false, true)).To(BeFalse())
+		Expect(instance.Wipeable("zfs", false, false)).To(BeFalse())
+		Expect(instance.Wipeable("glusterfs", false, false)).To(BeFalse())
+		Expect(instance.Wipeable("cephfs", false, false)).To(BeFalse())
+	})
+})
+
+var _ = Describe("discardsPartialGuest", Label("unit", "instance"), func() {
+	It("should discard the guest of failed and stuck tasks", func() {
+		Expect(instance.DiscardsPartialGuest(errors.New("unable to restore backup"))).To(BeTrue())
+		Expect(instance.DiscardsPartialGuest(fmt.Errorf("failed to restore backup: %w", instance.ErrTaskStuck))).To(BeTrue())
+	})
+
+	It("should keep the guest on server errors for the next attempt", func() {
+		timeout := fmt.Errorf("failed to get status of task: %w", rest.NewError(http.StatusGatewayTimeout, "Gateway Timeout", nil))
+		Expect(instance.DiscardsPartialGuest(timeout)).To(BeFalse())
+	})
+})
+
+var _ = Describe("partialGuestOwned", Label("unit", "instance"), func() {
+	tag := guest.MachineTag("default", "test")
+
+	It("should own the guest created untagged", func() {
+		Expect(instance.PartialGuestOwned(guest.Guest{VMID: 100}, tag)).To(BeTrue())
+		Expect(instance.PartialGuestOwned(guest.Guest{VMID: 100, Tags: "cappx;" + tag}, tag)).To(BeTrue())
+	})
+
+	It("should not own the guest of another machine", func() {
+		Expect(instance.PartialGuestOwned(guest.Guest{VMID: 100, Tags: guest.MachineTag("default", "other")}, tag)).To(BeFalse())
+	})
+})
@@ -0,0 +1,95 @@
+package instance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/qemuconfig"
+)
+
+const configDriftedMessage = "config of the qemu has changed out of band. remove the " +
+	infrav1.ConfigHashAnnotation + " annotation to accept it"
+
+// returns the hash of the normalized qemu config, so that values proxmox reports differently from
+// the ones cappx has applied do not flag drift. lock is excluded since proxmox sets it while running
+// tasks like backups
+func configHash(config api.VirtualMachineConfig) (string, error) {
+	normalized, err := qemuconfig.Normalize(config)
+	if err != nil {
+		return "", err
+	}
+	return legacyConfigHash(normalized)
+}
+
+// returns the hash of the qemu config as it is, recorded by older versions of cappx
+func legacyConfigHash(config api.VirtualMachineConfig) (string, error) {
+	config.Lock = ""
+	b, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// flags the qemu config changed since cappx last applied it, e.g. in the Proxmox web UI.
+// the hash is recorded on the first reconcile and after the annotation is removed
+func (s *Service) reconcileConfigDrift(ctx context.Context, vmid int, config api.VirtualMachineConfig) error {
+	log := log.FromContext(ctx)
+	hash, err := configHash(config)
+	if err != nil {
+		return err
+	}
+	legacy, err := legacyConfigHash(config)
+	if err != nil {
+		return err
+	}
+	switch applied := s.scope.GetConfigHash(); applied {
+	case "", legacy:
+		s.scope.SetConfigHash(hash)
+		s.scope.SetConfigInSync()
+	case hash:
+		s.scope.SetConfigInSync()
+	default:
+		if !s.scope.ConfigDrifted() {
+			log.Info("qemu config has changed out of band", "applied", applied, "current", hash)
+			s.scope.Warnf(infrav1.ConfigDriftedReason, "config of qemu %d has changed out of band", vmid)
+		}
+		s.scope.SetConfigDrifted(configDriftedMessage)
+	}
+	return nil
+}
+
+// records the hash of the config changed by cappx during the reconcile. the config is read again
+// since proxmox may store it differently from the values cappx has applied. drifted configs are
+// not recorded so that the drift is kept flagged until the annotation is removed
+func (s *Service) recordAppliedConfig(ctx context.Context, vm *proxmox.VirtualMachine, before string, config *api.VirtualMachineConfig) error {
+	after, err := configHash(*config)
+	if err != nil {
+		return err
+	}
+	if after == before {
+		return nil
+	}
+	latest, err := vm.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	*config = *latest
+	if s.scope.ConfigDrifted() {
+		return nil
+	}
+	hash, err := configHash(*latest)
+	if err != nil {
+		return err
+	}
+	s.scope.SetConfigHash(hash)
+	return nil
+}
@@ -0,0 +1,44 @@
+package instance_test
+
+import (
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("configHash", Label("unit", "instance"), func() {
+	config := api.VirtualMachineConfig{Name: "cappx-test", Cores: 2, Memory: 4096, Tags: "cappx"}
+
+	It("should change with the config", func() {
+		hash, err := instance.ConfigHash(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hash).To(HaveLen(64))
+
+		edited := config
+		edited.Cores = 4
+		Expect(instance.ConfigHash(edited)).NotTo(Equal(hash))
+	})
+
+	It("should ignore lock", func() {
+		locked := config
+		locked.Lock = "backup"
+		hash, err := instance.ConfigHash(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.ConfigHash(locked)).To(Equal(hash))
+	})
+
+	It("should not change with values proxmox reports differently", func() {
+		hash, err := instance.ConfigHash(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		reported := config
+		reported.Tags = "cappx;cappx"
+		Expect(instance.ConfigHash(reported)).To(Equal(hash))
+
+		hotplug := config
+		hotplug.HotPlug = "network,disk,usb"
+		Expect(instance.ConfigHash(hotplug)).To(Equal(hash))
+	})
+})
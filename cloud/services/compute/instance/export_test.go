@@ -1,9 +1,277 @@
 package instance
 
 import (
+	"context"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/snapshot"
 )
 
 func MergeUserDatas(a, b, c *infrav1.UserData) (*infrav1.UserData, error) {
 	return mergeUserDatas(a, b, c)
 }
+
+func ValidateExtraDisks(disks []infrav1.ExtraDisk) error {
+	return validateExtraDisks(disks)
+}
+
+func ExtraDiskOption(disk infrav1.ExtraDisk, storage string) string {
+	return extraDiskOption(disk, storage)
+}
+
+func HotplugOption(hardware infrav1.Hardware, options infrav1.Options) string {
+	return hotplugOption(hardware, options)
+}
+
+func HotplugMemory(hardware infrav1.Hardware, config api.VirtualMachineConfig) (int, bool) {
+	return hotplugMemory(hardware, config)
+}
+
+func ValidateMaxMemory(hardware infrav1.Hardware) error {
+	return validateMaxMemory(hardware)
+}
+
+func PendingUpdate(hardware infrav1.Hardware, options infrav1.Options, config *api.VirtualMachineConfig) string {
+	return pendingUpdate(hardware, options, config)
+}
+
+func BeforeUpdateDescription(update string) string {
+	return beforeUpdateDescription(update)
+}
+
+func SnapshotTaken(snapshots []snapshot.Snapshot, description string) bool {
+	return snapshotTaken(snapshots, description)
+}
+
+func BootOption(options infrav1.Options) string {
+	return bootOption(options)
+}
+
+type DescriptionData = descriptionData
+
+func RenderDescription(description string, data DescriptionData) (string, error) {
+	return renderDescription(description, data)
+}
+
+func RenderName(nameTemplate string, data DescriptionData, vmid int, failureDomain string) (string, error) {
+	return renderName(nameTemplate, nameData{descriptionData: data, VMID: vmid, FailureDomain: failureDomain})
+}
+
+func VirtualMachine(ctx context.Context, client *proxmox.Service, vmid int) (*proxmox.VirtualMachine, error) {
+	return virtualMachine(ctx, client, vmid)
+}
+
+func DiscardsPartialGuest(err error) bool {
+	return discardsPartialGuest(err)
+}
+
+func PartialGuestOwned(g guest.Guest, machineTag string) bool {
+	return partialGuestOwned(g, machineTag)
+}
+
+func Wipeable(storageType string, krbd, container bool) bool {
+	return wipeable(storageType, krbd, container)
+}
+
+func WipeVolumeCommand(volume string) string {
+	return wipeVolumeCommand(volume)
+}
+
+func MetadataTags(clusterName, namespace, name string) infrav1.Tags {
+	return metadataTags(clusterName, namespace, name)
+}
+
+func MappedTags(mappings []infrav1.TagMapping, labels, annotations map[string]string) infrav1.Tags {
+	return mappedTags(mappings, labels, annotations)
+}
+
+func SyncTags(current string, mappings []infrav1.TagMapping, mapped infrav1.Tags) (string, bool) {
+	return syncTags(current, mappings, mapped)
+}
+
+func RestoredConfig(vmoption api.VirtualMachineCreateOptions, drive string) api.VirtualMachineConfig {
+	return restoredConfig(vmoption, drive)
+}
+
+func SetCloudInitDrive(vmoption *api.VirtualMachineCreateOptions, storage string) {
+	setCloudInitDrive(vmoption, storage)
+}
+
+func Aarch64Defaults(vmoption *api.VirtualMachineCreateOptions, hardware infrav1.Hardware) {
+	aarch64Defaults(vmoption, hardware)
+}
+
+func ValidateArch(arch infrav1.Arch, hardware infrav1.Hardware) error {
+	return validateArch(arch, hardware)
+}
+
+type HAResource = haResource
+
+func HARequest(ha infrav1.HighAvailability) map[string]interface{} {
+	return haRequest(ha)
+}
+
+func HAUpToDate(current HAResource, ha infrav1.HighAvailability) bool {
+	return haUpToDate(current, ha)
+}
+
+type ReplicationJob = replicationJob
+
+func ReplicationRequest(replication infrav1.Replication) map[string]interface{} {
+	return replicationRequest(replication)
+}
+
+func ReplicationUpToDate(current ReplicationJob, replication infrav1.Replication) bool {
+	return replicationUpToDate(current, replication)
+}
+
+func ReplicationRetargeted(current ReplicationJob, replication infrav1.Replication, node string) bool {
+	return replicationRetargeted(current, replication, node)
+}
+
+func LXCRequest(name string, vmid int, storage string, container infrav1.Container, hardware infrav1.Hardware, network infrav1.Network, arch infrav1.Arch, tags, pool string) (map[string]interface{}, error) {
+	return lxcRequest(name, vmid, storage, container, hardware, network, arch, tags, pool)
+}
+
+func DiskSizeGiB(size string) (int, error) {
+	return diskSizeGiB(size)
+}
+
+func WindowsHostName(name string) string {
+	return windowsHostName(name)
+}
+
+func WindowsUserData(vmName string) *infrav1.UserData {
+	return windowsUserData(vmName)
+}
+
+func ScsiController(hardware infrav1.Hardware, options infrav1.Options) api.ScsiHw {
+	return scsiController(hardware, options)
+}
+
+type AgentInterfaces = agentInterfaces
+
+func AgentAddress(interfaces AgentInterfaces) string {
+	filter, _ := newAddressFilter(nil)
+	return interfaceAddress(filter.interfaces(agentNetworkInterfaces(interfaces, "")))
+}
+
+func StaticIP(config infrav1.IPConfig) string {
+	return staticIP(config)
+}
+
+func InstallBootstrapCommand(vmid int, script string) string {
+	return installBootstrapCommand(vmid, script)
+}
+
+func BootstrapDataHash(bootstrap string) string {
+	return bootstrapDataHash(bootstrap)
+}
+
+func EncryptedUserData(bootstrap string, userData, credentials infrav1.UserData, hostname string, encryption infrav1.SnippetEncryption) (string, error) {
+	return encryptedUserData(bootstrap, userData, credentials, hostname, encryption)
+}
+
+func IgnitionSupported(instanceType infrav1.InstanceType, windows bool, cloudInit infrav1.CloudInit) error {
+	return ignitionSupported(instanceType, windows, cloudInit)
+}
+
+func CredentialsUserData(user, password string) *infrav1.UserData {
+	return credentialsUserData(user, password)
+}
+
+func RestrictFileCommand(path string) string {
+	return restrictFileCommand(path)
+}
+
+func RunBootstrapCommand(vmid int) string {
+	return runBootstrapCommand(vmid)
+}
+
+func Console(endpoint, name, node string, vmid int, instanceType infrav1.InstanceType, options infrav1.Options) infrav1.Console {
+	return console(endpoint, name, node, vmid, instanceType, options)
+}
+
+func PlacementMessage(result framework.SchedulerResult) string {
+	return placementMessage(result)
+}
+
+func ConfigHash(config api.VirtualMachineConfig) (string, error) {
+	return configHash(config)
+}
+
+func MacAddress(net string) string {
+	return macAddress(net)
+}
+
+func AgentNetworkInterfaces(result AgentInterfaces, primaryMAC string) []infrav1.NetworkInterface {
+	return agentNetworkInterfaces(result, primaryMAC)
+}
+
+func FilterInterfaces(interfaces []infrav1.NetworkInterface, spec *infrav1.AddressFilter) ([]infrav1.NetworkInterface, error) {
+	filter, err := newAddressFilter(spec)
+	if err != nil {
+		return nil, err
+	}
+	return filter.interfaces(interfaces), nil
+}
+
+func MachineAddresses(name string, config infrav1.IPConfig, interfaces []infrav1.NetworkInterface) []clusterv1.MachineAddress {
+	return machineAddresses(name, config, interfaces)
+}
+
+type FirewallRule = firewallRule
+
+func GroupRulesUpToDate(rules []FirewallRule, groups []string) bool {
+	return groupRulesUpToDate(managedGroupRules(rules), groups)
+}
+
+func SecurityGroups(clusterGroup string, firewall *infrav1.Firewall) []string {
+	return securityGroups(clusterGroup, firewall)
+}
+
+func ForeignGuest(guests []guest.Guest, vmid int, machineTag string, names ...string) *guest.Guest {
+	return foreignGuest(guests, vmid, machineTag, names...)
+}
+
+func LeftoverVolumes(contents []*api.StorageContent, vmid int) []string {
+	return leftoverVolumes(contents, vmid)
+}
+
+type TaskLogLine = taskLogLine
+
+// returns whether the task is stuck after reading the logs one by one at the times
+func TaskStuck(start time.Time, logs [][]TaskLogLine, times []time.Time, now time.Time, timeout time.Duration) bool {
+	progress := taskProgress{since: start}
+	for i := range logs {
+		progress.observe(logs[i], times[i])
+	}
+	return progress.stuck(now, timeout)
+}
+
+func IsNodeSpecific(err error) bool {
+	return isNodeSpecific(err)
+}
+
+func ImportSource(image infrav1.Image) string {
+	return importSource(image)
+}
+
+func ImageImportAllowed(path string, dirs []string) error {
+	return imageImportAllowed(path, dirs)
+}
+
+func ImageExistsCommand(path string) string {
+	return imageExistsCommand(path)
+}
+
+func ChecksumCommand(checksum, path, cscmd string) string {
+	return checksumCommand(checksum, path, cscmd)
+}
@@ -0,0 +1,145 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+const (
+	firewallGroupsPath  = "/cluster/firewall/groups"
+	firewallRuleComment = "managed by cappx"
+)
+
+// subset of GET /nodes/{node}/{type}/{vmid}/firewall/rules
+type firewallRule struct {
+	Pos     int    `json:"pos"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// subset of GET /cluster/firewall/groups
+type firewallGroup struct {
+	Group string `json:"group"`
+}
+
+// subset of GET /nodes/{node}/{type}/{vmid}/firewall/options
+type firewallOptions struct {
+	Enable int `json:"enable"`
+}
+
+// attaches the security group of the cluster and the ones of the machine to the guest on top of its rules
+// and enables the firewall of the guest. rules added by others are left as they are.
+// path is the api path of the guest. e.g. /nodes/{node}/qemu/{vmid}
+func (s *Service) reconcileFirewall(ctx context.Context, path string) error {
+	log := log.FromContext(ctx)
+	groups := securityGroups(s.scope.GetClusterFirewallGroup(), s.scope.GetFirewall())
+	var rules []firewallRule
+	if err := s.client.RESTClient().Get(ctx, path+"/firewall/rules", &rules); err != nil {
+		return err
+	}
+	managed := managedGroupRules(rules)
+	if !groupRulesUpToDate(managed, groups) {
+		if err := s.validateSecurityGroups(ctx, groups); err != nil {
+			return err
+		}
+		log.Info("updating security groups", "desired", groups)
+		// delete from the bottom so that positions of the remaining rules are kept
+		for i := len(managed) - 1; i >= 0; i-- {
+			p := fmt.Sprintf("%s/firewall/rules/%d", path, managed[i].Pos)
+			if err := audit.REST(&s.client).Delete(ctx, p, nil, nil); err != nil {
+				return fmt.Errorf("failed to delete firewall rule %d: %w", managed[i].Pos, err)
+			}
+		}
+		// proxmox inserts new rules on top
+		for i := len(groups) - 1; i >= 0; i-- {
+			request := map[string]interface{}{
+				"type":    "group",
+				"action":  groups[i],
+				"enable":  1,
+				"comment": firewallRuleComment,
+			}
+			if err := audit.REST(&s.client).Post(ctx, path+"/firewall/rules", request, nil); err != nil {
+				return fmt.Errorf("failed to attach security group %s: %w", groups[i], err)
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	var options firewallOptions
+	if err := s.client.RESTClient().Get(ctx, path+"/firewall/options", &options); err != nil {
+		return err
+	}
+	if options.Enable == 1 {
+		return nil
+	}
+	log.Info("enabling firewall")
+	return audit.REST(&s.client).Put(ctx, path+"/firewall/options", map[string]interface{}{"enable": 1}, nil)
+}
+
+// the group of the cluster opening the ports of kubernetes comes first
+func securityGroups(clusterGroup string, firewall *infrav1.Firewall) []string {
+	var groups []string
+	if clusterGroup != "" {
+		groups = append(groups, clusterGroup)
+	}
+	if firewall != nil {
+		for _, g := range firewall.SecurityGroups {
+			if g != clusterGroup {
+				groups = append(groups, g)
+			}
+		}
+	}
+	return groups
+}
+
+// security groups are defined by the proxmox admin. attaching an undefined one fails
+func (s *Service) validateSecurityGroups(ctx context.Context, groups []string) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	var defined []firewallGroup
+	if err := s.client.RESTClient().Get(ctx, firewallGroupsPath, &defined); err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if !slices.ContainsFunc(defined, func(g firewallGroup) bool { return g.Group == group }) {
+			return fmt.Errorf("security group %s is not defined in the datacenter firewall", group)
+		}
+	}
+	return nil
+}
+
+// returns the group rules added by cappx ordered by position
+func managedGroupRules(rules []firewallRule) []firewallRule {
+	var managed []firewallRule
+	for _, r := range rules {
+		if r.Type == "group" && r.Comment == firewallRuleComment {
+			managed = append(managed, r)
+		}
+	}
+	sort.Slice(managed, func(i, j int) bool { return managed[i].Pos < managed[j].Pos })
+	return managed
+}
+
+// managed rules must be the groups in order on top of the other rules
+func groupRulesUpToDate(managed []firewallRule, groups []string) bool {
+	if len(managed) != len(groups) {
+		return false
+	}
+	for i, r := range managed {
+		if r.Pos != i || r.Action != groups[i] {
+			return false
+		}
+	}
+	return true
+}
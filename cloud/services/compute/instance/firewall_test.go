@@ -0,0 +1,42 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("groupRulesUpToDate", Label("unit", "instance"), func() {
+	rules := []instance.FirewallRule{
+		{Pos: 2, Type: "in", Action: "ACCEPT"},
+		{Pos: 1, Type: "group", Action: "k8s-nodes", Comment: "managed by cappx"},
+		{Pos: 0, Type: "group", Action: "base", Comment: "managed by cappx"},
+		{Pos: 3, Type: "group", Action: "admin"},
+	}
+
+	It("should be up to date with the groups in order on top", func() {
+		Expect(instance.GroupRulesUpToDate(rules, []string{"base", "k8s-nodes"})).To(BeTrue())
+	})
+
+	It("should not be up to date with other groups or order", func() {
+		Expect(instance.GroupRulesUpToDate(rules, []string{"k8s-nodes", "base"})).To(BeFalse())
+		Expect(instance.GroupRulesUpToDate(rules, []string{"base"})).To(BeFalse())
+		Expect(instance.GroupRulesUpToDate(rules, nil)).To(BeFalse())
+	})
+
+	It("should ignore group rules not added by cappx", func() {
+		Expect(instance.GroupRulesUpToDate(rules[3:], nil)).To(BeTrue())
+	})
+})
+
+var _ = Describe("securityGroups", Label("unit", "instance"), func() {
+	It("should put the group of the cluster first", func() {
+		firewall := &infrav1.Firewall{SecurityGroups: []string{"base", "k8s-prod"}}
+		Expect(instance.SecurityGroups("k8s-prod", firewall)).To(Equal([]string{"k8s-prod", "base"}))
+		Expect(instance.SecurityGroups("k8s-prod", nil)).To(Equal([]string{"k8s-prod"}))
+		Expect(instance.SecurityGroups("", firewall)).To(Equal([]string{"base", "k8s-prod"}))
+		Expect(instance.SecurityGroups("", nil)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,136 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+const (
+	haResourcesPath = "/cluster/ha/resources"
+	haComment       = "managed by cappx"
+)
+
+// resource of proxmox ha manager
+type haResource struct {
+	SID         string `json:"sid"`
+	Group       string `json:"group,omitempty"`
+	State       string `json:"state,omitempty"`
+	MaxRestart  *int   `json:"max_restart,omitempty"`
+	MaxRelocate *int   `json:"max_relocate,omitempty"`
+}
+
+// registers the vm with the ha manager or updates its registration
+func (s *Service) reconcileHA(ctx context.Context, vmid int) error {
+	log := log.FromContext(ctx)
+	ha := s.scope.GetHA()
+	if ha == nil {
+		return nil
+	}
+	sid := haSID(vmid)
+	current, err := s.getHAResource(ctx, sid)
+	if err != nil {
+		return err
+	}
+	request := haRequest(*ha)
+	if current == nil {
+		log.Info("registering qemu with ha manager", "sid", sid, "group", ha.Group)
+		request["sid"] = sid
+		if err := audit.REST(&s.client).Post(ctx, haResourcesPath, request, nil); err != nil {
+			return fmt.Errorf("failed to register %s with ha manager: %w", sid, err)
+		}
+		return nil
+	}
+	if haUpToDate(*current, *ha) {
+		return nil
+	}
+	log.Info("updating ha resource", "sid", sid, "group", ha.Group)
+	if ha.Group == "" {
+		request["delete"] = "group"
+	}
+	if err := audit.REST(&s.client).Put(ctx, haResourcePath(sid), request, nil); err != nil {
+		return fmt.Errorf("failed to update ha resource %s: %w", sid, err)
+	}
+	return nil
+}
+
+// deregisters the vm from the ha manager if registered
+func (s *Service) deleteHA(ctx context.Context, vmid int) error {
+	log := log.FromContext(ctx)
+	sid := haSID(vmid)
+	current, err := s.getHAResource(ctx, sid)
+	if err != nil || current == nil {
+		return err
+	}
+	log.Info("deregistering qemu from ha manager", "sid", sid)
+	if err := audit.REST(&s.client).Delete(ctx, haResourcePath(sid), nil, nil); err != nil {
+		return fmt.Errorf("failed to deregister %s from ha manager: %w", sid, err)
+	}
+	return nil
+}
+
+// returns nil if the resource is not registered
+func (s *Service) getHAResource(ctx context.Context, sid string) (*haResource, error) {
+	var resources []haResource
+	if err := s.client.RESTClient().Get(ctx, haResourcesPath, &resources); err != nil {
+		return nil, err
+	}
+	for _, r := range resources {
+		if r.SID == sid {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+func haSID(vmid int) string {
+	return fmt.Sprintf("vm:%d", vmid)
+}
+
+func haResourcePath(sid string) string {
+	return fmt.Sprintf("%s/%s", haResourcesPath, url.PathEscape(sid))
+}
+
+func haState(ha infrav1.HighAvailability) string {
+	if ha.State == "" {
+		return "started"
+	}
+	return string(ha.State)
+}
+
+func haRequest(ha infrav1.HighAvailability) map[string]interface{} {
+	request := map[string]interface{}{
+		"state":   haState(ha),
+		"comment": haComment,
+	}
+	if ha.Group != "" {
+		request["group"] = ha.Group
+	}
+	if ha.MaxRestart != nil {
+		request["max_restart"] = *ha.MaxRestart
+	}
+	if ha.MaxRelocate != nil {
+		request["max_relocate"] = *ha.MaxRelocate
+	}
+	return request
+}
+
+// unspecified max restart/relocate are left as they are
+func haUpToDate(current haResource, ha infrav1.HighAvailability) bool {
+	if current.Group != ha.Group || current.State != haState(ha) {
+		return false
+	}
+	if ha.MaxRestart != nil && (current.MaxRestart == nil || *current.MaxRestart != *ha.MaxRestart) {
+		return false
+	}
+	if ha.MaxRelocate != nil && (current.MaxRelocate == nil || *current.MaxRelocate != *ha.MaxRelocate) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("haRequest", Label("unit", "instance"), func() {
+	It("should default state to started", func() {
+		request := instance.HARequest(infrav1.HighAvailability{})
+		Expect(request).To(HaveKeyWithValue("state", "started"))
+		Expect(request).NotTo(HaveKey("group"))
+		Expect(request).NotTo(HaveKey("max_restart"))
+	})
+
+	It("should render group and retries", func() {
+		request := instance.HARequest(infrav1.HighAvailability{Group: "cp", State: "stopped", MaxRestart: ptr.To(0), MaxRelocate: ptr.To(2)})
+		Expect(request).To(HaveKeyWithValue("group", "cp"))
+		Expect(request).To(HaveKeyWithValue("state", "stopped"))
+		Expect(request).To(HaveKeyWithValue("max_restart", 0))
+		Expect(request).To(HaveKeyWithValue("max_relocate", 2))
+	})
+})
+
+var _ = Describe("haUpToDate", Label("unit", "instance"), func() {
+	current := instance.HAResource{SID: "vm:100", Group: "cp", State: "started", MaxRestart: ptr.To(1), MaxRelocate: ptr.To(1)}
+
+	It("should ignore unspecified retries", func() {
+		Expect(instance.HAUpToDate(current, infrav1.HighAvailability{Group: "cp"})).To(BeTrue())
+	})
+
+	It("should detect changes", func() {
+		Expect(instance.HAUpToDate(current, infrav1.HighAvailability{Group: "other"})).To(BeFalse())
+		Expect(instance.HAUpToDate(current, infrav1.HighAvailability{Group: "cp", State: "stopped"})).To(BeFalse())
+		Expect(instance.HAUpToDate(current, infrav1.HighAvailability{Group: "cp", MaxRestart: ptr.To(3)})).To(BeFalse())
+	})
+})
@@ -3,6 +3,7 @@ package instance
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"path"
 	"strings"
 
@@ -11,12 +12,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
 )
 
 const (
 	rawImageDirPath = etcCAPPX + "/images"
 )
 
+// ImageImportDirs are the directories of the nodes images of sourceType NodeLocalPath
+// may be imported from. Set by --image-import-dirs of the controller.
+// Guest disks must never be below them, since any file there can be imported into a new machine.
+var ImageImportDirs = []string{"/var/lib/vz/import"}
+
 // reconcileBootDevice
 func (s *Service) reconcileBootDevice(ctx context.Context, vm *proxmox.VirtualMachine) error {
 	log := log.FromContext(ctx)
@@ -24,47 +32,98 @@ func (s *Service) reconcileBootDevice(ctx context.Context, vm *proxmox.VirtualMa
 
 	// boot disk
 	log.Info("resizing boot disk")
-	if err := vm.ResizeVolume(ctx, bootDvice, s.scope.GetHardware().RootDisk); err != nil {
+	if err := audit.Do(ctx, &s.client, http.MethodPut, qemuPath(vm)+"/resize", func() error {
+		return vm.ResizeVolume(ctx, bootDvice, s.scope.GetHardware().RootDisk)
+	}); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// setCloudImage downloads OS image into Proxmox node
+// setCloudImage makes the OS image available on the Proxmox node
 // so that proxmox can import image to the storage from there
 func (s *Service) setCloudImage(ctx context.Context) error {
 	log := log.FromContext(ctx)
 	log.Info("setting cloud image")
 
 	image := s.scope.GetImage()
+	switch image.SourceType {
+	case infrav1.ImageSourceStorage:
+		// proxmox checks the volume on importing it
+		return nil
+	case infrav1.ImageSourceNodeLocalPath:
+		return s.checkNodeLocalImage(ctx, image)
+	}
 	rawImageFilePath := rawImageFilePath(image)
 
-	vnc, err := s.vncClient(s.scope.NodeName())
+	vnc, err := s.vncClient(ctx, s.scope.NodeName())
 	if err != nil {
 		return errors.Errorf("failed to create vnc client: %v", err)
 	}
 	defer vnc.Close()
 
 	// download image
-	ok, _ := isChecksumOK(vnc, image, rawImageFilePath)
+	ok, _ := isChecksumOK(ctx, vnc, image, rawImageFilePath)
 	if !ok { // if checksum is ok, it means the image is already there. skip installing
 		out, _, err := vnc.Exec(ctx, fmt.Sprintf("mkdir -p %s && mkdir -p %s", etcCAPPX, rawImageDirPath))
 		if err != nil {
 			return errors.Errorf("failed to create dir %s: %s : %v", rawImageDirPath, out, err)
 		}
 		log.Info("downloading node image. this will take few mins.")
-		out, _, err = vnc.Exec(ctx, fmt.Sprintf("wget %s -O %s", image.URL, rawImageFilePath))
+		out, _, err = vnc.Exec(ctx, fmt.Sprintf("wget %s -O %s", cloudinit.ShellQuote(image.URL), cloudinit.ShellQuote(rawImageFilePath)))
 		if err != nil {
 			return errors.Errorf("failed to download image: %s : %v", out, err)
 		}
-		if _, err = isChecksumOK(vnc, image, rawImageFilePath); err != nil {
+		if _, err = isChecksumOK(ctx, vnc, image, rawImageFilePath); err != nil {
 			return errors.Errorf("failed to confirm checksum: %v", err)
 		}
 	}
 	return nil
 }
 
+// checks that the image exists on the node, and matches its checksum if any
+func (s *Service) checkNodeLocalImage(ctx context.Context, image infrav1.Image) error {
+	if err := imageImportAllowed(image.Path, ImageImportDirs); err != nil {
+		return err
+	}
+	vnc, err := s.vncClient(ctx, s.scope.NodeName())
+	if err != nil {
+		return errors.Errorf("failed to create vnc client: %v", err)
+	}
+	defer vnc.Close()
+
+	if out, _, err := vnc.Exec(ctx, imageExistsCommand(image.Path)); err != nil {
+		return errors.Errorf("image %s does not exist on node %s: %s : %v", image.Path, s.scope.NodeName(), out, err)
+	}
+	if _, err := isChecksumOK(ctx, vnc, image, image.Path); err != nil {
+		return errors.Errorf("failed to confirm checksum of %s: %v", image.Path, err)
+	}
+	return nil
+}
+
+// returns an error unless path is a valid image path under one of the dirs.
+// the path is checked again here, since it ends up in a shell of the node run by root
+func imageImportAllowed(p string, dirs []string) error {
+	if !infrav1.ValidImagePath(p) {
+		return errors.Errorf("image path %q is invalid", p)
+	}
+	for _, dir := range dirs {
+		if dir = path.Clean(dir); path.IsAbs(dir) && strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/") {
+			return nil
+		}
+	}
+	return errors.Errorf("image path %s is not under the import directories %v allowed by --image-import-dirs", p, dirs)
+}
+
+func imageExistsCommand(path string) string {
+	return "test -f " + cloudinit.ShellQuote(path)
+}
+
+func checksumCommand(checksum, path, cscmd string) string {
+	return fmt.Sprintf("echo -n %s | %s --check -", cloudinit.ShellQuote(checksum+" "+path), cscmd)
+}
+
 func findValidChecksumCommand(csType string) (string, error) {
 	csType = strings.ToLower(csType)
 	switch csType {
@@ -77,14 +136,13 @@ func findValidChecksumCommand(csType string) (string, error) {
 	}
 }
 
-func isChecksumOK(client *proxmox.VNCWebSocketClient, image infrav1.Image, path string) (bool, error) {
+func isChecksumOK(ctx context.Context, client *audit.VNCClient, image infrav1.Image, path string) (bool, error) {
 	if image.Checksum != "" {
 		cscmd, err := findValidChecksumCommand(*image.ChecksumType)
 		if err != nil {
 			return false, err
 		}
-		cmd := fmt.Sprintf("echo -n '%s %s' | %s --check -", image.Checksum, path, cscmd)
-		out, _, err := client.Exec(context.TODO(), cmd)
+		out, _, err := client.Exec(ctx, checksumCommand(image.Checksum, path, cscmd))
 		if err != nil {
 			return false, errors.Errorf("failed to confirm checksum: %s : %v", out, err)
 		}
@@ -93,6 +151,14 @@ func isChecksumOK(client *proxmox.VNCWebSocketClient, image infrav1.Image, path
 	return false, nil
 }
 
+// returns what proxmox imports the root disk from: the downloaded file, the path on the node or the volume
+func importSource(image infrav1.Image) string {
+	if image.SourceType == infrav1.ImageSourceNodeLocalPath || image.SourceType == infrav1.ImageSourceStorage {
+		return image.Source()
+	}
+	return rawImageFilePath(image)
+}
+
 func rawImageFilePath(image infrav1.Image) string {
 	fileName := path.Base(image.URL)
 	if image.Checksum != "" {
@@ -0,0 +1,61 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("importSource", Label("unit", "instance"), func() {
+	It("should import downloaded images from the cache of the node", func() {
+		image := infrav1.Image{URL: "https://example.com/ubuntu.img", Checksum: "abc"}
+		Expect(instance.ImportSource(image)).To(Equal("/etc/cappx/images/abc.ubuntu.img"))
+		image.SourceType = infrav1.ImageSourceURL
+		Expect(instance.ImportSource(image)).To(Equal("/etc/cappx/images/abc.ubuntu.img"))
+	})
+
+	It("should import node local paths directly", func() {
+		image := infrav1.Image{SourceType: infrav1.ImageSourceNodeLocalPath, Path: "/mnt/pve/nfs/images/ubuntu.qcow2"}
+		Expect(instance.ImportSource(image)).To(Equal("/mnt/pve/nfs/images/ubuntu.qcow2"))
+	})
+
+	It("should import storage volumes directly", func() {
+		image := infrav1.Image{SourceType: infrav1.ImageSourceStorage, Volume: "nfs:import/ubuntu.qcow2"}
+		Expect(instance.ImportSource(image)).To(Equal("nfs:import/ubuntu.qcow2"))
+	})
+})
+
+var _ = Describe("imageImportAllowed", Label("unit", "instance"), func() {
+	dirs := []string{"/var/lib/vz/import", "/mnt/pve/nfs/import/"}
+
+	It("should allow images under the import directories", func() {
+		Expect(instance.ImageImportAllowed("/var/lib/vz/import/ubuntu.qcow2", dirs)).To(Succeed())
+		Expect(instance.ImageImportAllowed("/mnt/pve/nfs/import/k8s/ubuntu.img", dirs)).To(Succeed())
+	})
+
+	It("should reject images outside the import directories", func() {
+		Expect(instance.ImageImportAllowed("/var/lib/vz/images/100/vm-100-disk-0.qcow2", dirs)).NotTo(Succeed())
+		Expect(instance.ImageImportAllowed("/var/lib/vz/import-other/ubuntu.qcow2", dirs)).NotTo(Succeed())
+		Expect(instance.ImageImportAllowed("/var/lib/vz/import/ubuntu.qcow2", nil)).NotTo(Succeed())
+	})
+
+	It("should reject invalid paths even under the import directories", func() {
+		Expect(instance.ImageImportAllowed("/var/lib/vz/import/../images/100/vm-100-disk-0.qcow2", dirs)).NotTo(Succeed())
+		Expect(instance.ImageImportAllowed("/var/lib/vz/import/$(reboot).qcow2", dirs)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("image commands", Label("unit", "instance"), func() {
+	It("should quote the path of the image", func() {
+		Expect(instance.ImageExistsCommand("/var/lib/vz/import/ubuntu.qcow2")).To(Equal("test -f '/var/lib/vz/import/ubuntu.qcow2'"))
+	})
+
+	It("should quote the checksum and the path", func() {
+		Expect(instance.ChecksumCommand("abc", "/etc/cappx/images/abc.ubuntu.img", "sha256sum")).
+			To(Equal("echo -n 'abc /etc/cappx/images/abc.ubuntu.img' | sha256sum --check -"))
+		Expect(instance.ChecksumCommand("a'; reboot; '", "/var/lib/vz/import/ubuntu.img", "md5sum")).
+			To(Equal(`echo -n 'a'\''; reboot; '\'' /var/lib/vz/import/ubuntu.img' | md5sum --check -`))
+	})
+})
@@ -0,0 +1,375 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/cloudinit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/logging"
+)
+
+// cloud-config of lxc containers is rendered as this script
+const lxcBootstrapScriptPath = etcCAPPX + "/cloud-config.sh"
+
+// lxcBackend provisions lxc containers. containers have no smbios uuid,
+// so the uid of the owner Machine is used as provider id
+type lxcBackend struct {
+	*Service
+}
+
+type lxcGuest struct {
+	node   string
+	vmid   int
+	status string
+	uuid   string
+	name   string
+	tags   string
+}
+
+var _ Backend = &lxcBackend{}
+var _ Guest = &lxcGuest{}
+
+func (g *lxcGuest) Node() string {
+	return g.node
+}
+
+func (g *lxcGuest) VMID() int {
+	return g.vmid
+}
+
+func (g *lxcGuest) Status() infrav1.InstanceStatus {
+	return infrav1.InstanceStatus(g.status)
+}
+
+func (g *lxcGuest) UUID(_ context.Context) (string, error) {
+	return g.uuid, nil
+}
+
+func (g *lxcGuest) Name() string {
+	return g.name
+}
+
+func (g *lxcGuest) Tags(_ context.Context) (string, error) {
+	return g.tags, nil
+}
+
+// the provider id is derived from the machine, so the container is looked up by vmid.
+// ErrGuestUnreachable is returned for containers on unreachable nodes like for qemus
+func (b *lxcBackend) Get(ctx context.Context) (Guest, error) {
+	if b.scope.GetProviderID() == "" {
+		return nil, rest.NotFoundErr
+	}
+	g, err := b.GetByVMID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if g.Status() == infrav1.InstanceStatus(guest.StatusUnknown) {
+		return nil, fmt.Errorf("%w: vmid %d on node %s", ErrGuestUnreachable, g.VMID(), g.Node())
+	}
+	return g, nil
+}
+
+func (b *lxcBackend) GetByVMID(ctx context.Context) (Guest, error) {
+	vmid := b.scope.GetVMID()
+	if vmid == nil {
+		return nil, rest.NotFoundErr
+	}
+	guests, err := guest.List(ctx, &b.client)
+	if err != nil {
+		return nil, err
+	}
+	g, err := guest.Find(guests, *vmid)
+	if err != nil {
+		return nil, err
+	}
+	if g.Type != guest.TypeLXC || g.Name != b.scope.Name() {
+		return nil, fmt.Errorf("vmid %d is used by %s %s", g.VMID, g.Type, g.Name)
+	}
+	return &lxcGuest{node: g.Node, vmid: g.VMID, status: string(g.Status), uuid: b.scope.GetMachineUID(), name: g.Name, tags: g.Tags}, nil
+}
+
+func (b *lxcBackend) Create(ctx context.Context) (Guest, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling LXC")
+
+	existing, err := b.GetByVMID(ctx)
+	if err == nil || !rest.IsNotFound(err) {
+		return existing, err
+	}
+	container, vmoption, err := b.lxcOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("creating lxc")
+	result, err := b.schedule(ctx, &vmoption)
+	if err != nil {
+		log.Error(err, "failed to schedule lxc instance")
+		return nil, err
+	}
+	node, vmid, storage := result.Node(), result.VMID(), result.Storage()
+	if err := b.checkWipeable(ctx, storage, true); err != nil {
+		return nil, err
+	}
+	b.scope.SetNodeName(node)
+	b.scope.SetVMID(vmid)
+	b.scope.SetStorage(storage)
+
+	request, err := lxcRequest(b.scope.Name(), vmid, storage, *container, b.scope.GetHardware(), b.scope.GetNetwork(), b.scope.GetOptions().Arch, vmoption.Tags, b.scope.GetPool())
+	if err != nil {
+		return nil, err
+	}
+	if err := b.runPhase(ctx, infrav1.ProvisioningPhaseCreate, func(ctx context.Context) error {
+		var upid string
+		if err := audit.REST(&b.client).Post(ctx, fmt.Sprintf("/nodes/%s/lxc", node), request, &upid); err != nil {
+			return err
+		}
+		return b.waitCreation(ctx, node, vmid, upid)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create lxc: %w", err)
+	}
+	if err := b.scope.PatchObject(); err != nil {
+		return nil, err
+	}
+	return &lxcGuest{node: node, vmid: vmid, status: string(api.ProcessStatusStopped), uuid: b.scope.GetMachineUID(), name: b.scope.Name(), tags: vmoption.Tags}, nil
+}
+
+// validates the machine spec and returns the container and the spec the scheduler places it by.
+// the scheduler only looks into name, arch and resources of the spec
+func (b *lxcBackend) lxcOptions() (*infrav1.Container, api.VirtualMachineCreateOptions, error) {
+	container := b.scope.GetContainer()
+	if container == nil {
+		return nil, api.VirtualMachineCreateOptions{}, fmt.Errorf("container must be specified for instance type %s", infrav1.InstanceTypeLXC)
+	}
+	if err := b.scope.GetOptions().Tags.Validate(); err != nil {
+		return nil, api.VirtualMachineCreateOptions{}, fmt.Errorf("invalid options: %w", err)
+	}
+	if _, err := b.scope.ClusterNetwork(); err != nil {
+		return nil, api.VirtualMachineCreateOptions{}, err
+	}
+	if _, err := b.scope.NodeGroup(); err != nil {
+		return nil, api.VirtualMachineCreateOptions{}, err
+	}
+	if hasNetworkSnippet(b.scope.GetNetwork()) {
+		return nil, api.VirtualMachineCreateOptions{}, fmt.Errorf("network.ipConfig.routes are not supported for instance type %s", infrav1.InstanceTypeLXC)
+	}
+	hardware := b.scope.GetHardware()
+	tags := b.guestTags()
+	return container, api.VirtualMachineCreateOptions{
+		Name:   b.scope.Name(),
+		Arch:   api.Arch(b.scope.GetOptions().Arch),
+		Cores:  hardware.CPU,
+		Memory: hardware.Memory,
+		Tags:   tags.String(),
+	}, nil
+}
+
+// containers have no cloud-init datasource. cloud-config is rendered as a shell script
+// and installed into the rootfs of the stopped container, then run by Update
+func (b *lxcBackend) DeliverBootstrap(ctx context.Context, guest Guest) error {
+	ctx = logging.IntoContext(ctx, logging.CloudInit)
+	log := log.FromContext(ctx)
+	if guest.Status() == infrav1.InstanceStatusRunning {
+		// the script is installed before the first start
+		return nil
+	}
+	log.Info("delivering bootstrap data to lxc")
+	configYaml, err := b.userDataYaml(ctx)
+	if err != nil {
+		return err
+	}
+	script, err := cloudinit.ShellScript(configYaml, b.scope.Name())
+	if err != nil {
+		return err
+	}
+	vnc, err := b.vncClient(ctx, guest.Node())
+	if err != nil {
+		return err
+	}
+	defer vnc.Close()
+	tmp := fmt.Sprintf("/tmp/cappx-%d-bootstrap.sh", guest.VMID())
+	if err := writePrivateFile(ctx, vnc, script, tmp); err != nil {
+		return err
+	}
+	out, code, err := vnc.Exec(ctx, installBootstrapCommand(guest.VMID(), tmp))
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("failed to install bootstrap script into lxc %d: %s", guest.VMID(), out)
+	}
+	return nil
+}
+
+func (b *lxcBackend) Start(ctx context.Context, guest Guest) error {
+	if guest.Status() == infrav1.InstanceStatusRunning {
+		return nil
+	}
+	if err := b.lxcTask(ctx, guest, "POST", "status/start"); err != nil {
+		return err
+	}
+	guest.(*lxcGuest).status = string(api.ProcessStatusRunning)
+	return nil
+}
+
+// updates tags and runs the bootstrap script until the machine gets ready. the script runs
+// in background only once, so that reconciles are not blocked by kubeadm or k3s
+func (b *lxcBackend) Update(ctx context.Context, guest Guest) error {
+	if err := b.reconcileTags(ctx, guest.(*lxcGuest)); err != nil {
+		return err
+	}
+	if err := b.reconcileFirewall(ctx, fmt.Sprintf("/nodes/%s/lxc/%d", guest.Node(), guest.VMID())); err != nil {
+		return err
+	}
+	if guest.Status() != infrav1.InstanceStatusRunning || b.scope.IsReady() {
+		return nil
+	}
+	vnc, err := b.vncClient(ctx, guest.Node())
+	if err != nil {
+		return err
+	}
+	defer vnc.Close()
+	out, code, err := vnc.Exec(ctx, runBootstrapCommand(guest.VMID()))
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("failed to run bootstrap script in lxc %d: %s", guest.VMID(), out)
+	}
+	return nil
+}
+
+// update tags of existing container following labels and annotations of the machine.
+// machine tags missing on containers created by older versions are added too
+func (b *lxcBackend) reconcileTags(ctx context.Context, guest *lxcGuest) error {
+	log := log.FromContext(ctx)
+	tags, changed := b.syncedTags(guest.tags)
+	if !changed {
+		return nil
+	}
+	log.Info("updating tags", "current", guest.tags, "desired", tags)
+	p := fmt.Sprintf("/nodes/%s/lxc/%d/config", guest.Node(), guest.VMID())
+	if err := audit.REST(&b.client).Put(ctx, p, map[string]interface{}{"tags": tags}, nil); err != nil {
+		return fmt.Errorf("failed to update tags of lxc %d: %w", guest.VMID(), err)
+	}
+	guest.tags = tags
+	return nil
+}
+
+// calls the api of the container and waits for the task
+func (b *lxcBackend) lxcTask(ctx context.Context, guest Guest, method, path string) error {
+	p := fmt.Sprintf("/nodes/%s/lxc/%d", guest.Node(), guest.VMID())
+	if path != "" {
+		p = fmt.Sprintf("%s/%s", p, path)
+	}
+	var upid string
+	var err error
+	switch method {
+	case "POST":
+		err = audit.REST(&b.client).Post(ctx, p, nil, &upid)
+	case "DELETE":
+		err = audit.REST(&b.client).Delete(ctx, p, nil, &upid)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", method, p, err)
+	}
+	return b.client.EnsureTaskDone(ctx, guest.Node(), upid)
+}
+
+// returns request of POST /nodes/{node}/lxc
+func lxcRequest(name string, vmid int, storage string, container infrav1.Container, hardware infrav1.Hardware, network infrav1.Network, arch infrav1.Arch, tags, pool string) (map[string]interface{}, error) {
+	size, err := diskSizeGiB(hardware.RootDisk)
+	if err != nil {
+		return nil, err
+	}
+	net0 := []string{"name=eth0"}
+	if hardware.NetworkDevice.Bridge != "" {
+		net0 = append(net0, fmt.Sprintf("bridge=%s", hardware.NetworkDevice.Bridge))
+	}
+	if hardware.NetworkDevice.Firewall {
+		net0 = append(net0, "firewall=1")
+	}
+	if hardware.NetworkDevice.Tag != 0 {
+		net0 = append(net0, fmt.Sprintf("tag=%d", hardware.NetworkDevice.Tag))
+	}
+	if hardware.NetworkDevice.MTU != 0 {
+		net0 = append(net0, fmt.Sprintf("mtu=%d", hardware.NetworkDevice.MTU))
+	}
+	net0 = append(net0, network.IPConfig.String())
+	request := map[string]interface{}{
+		"vmid":         vmid,
+		"hostname":     name,
+		"ostemplate":   container.OSTemplate,
+		"storage":      storage,
+		"rootfs":       fmt.Sprintf("%s:%d", storage, size),
+		"cores":        hardware.CPU,
+		"memory":       hardware.Memory,
+		"net0":         strings.Join(net0, ","),
+		"unprivileged": boolToInt8(!container.Privileged),
+		"tags":         tags,
+	}
+	if pool != "" {
+		request["pool"] = pool
+	}
+	if container.Features != "" {
+		request["features"] = container.Features
+	}
+	// lxc names architectures after debian
+	switch arch {
+	case infrav1.ArchX86_64:
+		request["arch"] = "amd64"
+	case infrav1.ArchAarch64:
+		request["arch"] = "arm64"
+	}
+	if network.NameServer != "" {
+		request["nameserver"] = network.NameServer
+	}
+	if network.SearchDomain != "" {
+		request["searchdomain"] = network.SearchDomain
+	}
+	return request, nil
+}
+
+// converts disk size like "50G" into GiB rounding up. sizes without unit are in bytes
+func diskSizeGiB(size string) (int, error) {
+	size = strings.TrimPrefix(size, "+")
+	units := map[string]float64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+	unit := float64(1)
+	if n := len(size); n > 0 {
+		if u, ok := units[size[n-1:]]; ok {
+			unit = u
+			size = size[:n-1]
+		}
+	}
+	value, err := strconv.ParseFloat(size, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid disk size %s", size)
+	}
+	return int(math.Ceil(value * unit / gib)), nil
+}
+
+// mounts the rootfs of the stopped container and moves the script into it
+func installBootstrapCommand(vmid int, script string) string {
+	dir := fmt.Sprintf("/var/lib/lxc/%d/rootfs%s", vmid, path.Dir(lxcBootstrapScriptPath))
+	return fmt.Sprintf("pct mount %[1]d >/dev/null && mkdir -p %[2]s && mv %[3]s %[2]s/%[4]s && chmod 700 %[2]s/%[4]s; rc=$?; pct unmount %[1]d; exit $rc",
+		vmid, dir, script, path.Base(lxcBootstrapScriptPath))
+}
+
+// starts the bootstrap script in background unless it has been started.
+// its output is left in /var/log/cappx-bootstrap.log of the container
+func runBootstrapCommand(vmid int) string {
+	started := lxcBootstrapScriptPath + ".started"
+	return fmt.Sprintf("pct exec %d -- sh -c 'test -e %[2]s && exit 0; touch %[2]s && nohup sh %[3]s >/var/log/cappx-bootstrap.log 2>&1 &'",
+		vmid, started, lxcBootstrapScriptPath)
+}
@@ -0,0 +1,82 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("lxcRequest", Label("unit", "instance"), func() {
+	container := infrav1.Container{OSTemplate: "local:vztmpl/ubuntu.tar.zst", Features: "nesting=1,keyctl=1"}
+	hardware := infrav1.Hardware{CPU: 2, Memory: 4096, RootDisk: "50G", NetworkDevice: infrav1.NetworkDevice{Bridge: "vmbr0", Firewall: true}}
+
+	It("should render container", func() {
+		request, err := instance.LXCRequest("ct", 100, "local-lvm", container, hardware, infrav1.Network{}, "", "cappx", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request).To(HaveKeyWithValue("rootfs", "local-lvm:50"))
+		Expect(request).To(HaveKeyWithValue("net0", "name=eth0,bridge=vmbr0,firewall=1,ip=dhcp"))
+		Expect(request).To(HaveKeyWithValue("unprivileged", int8(1)))
+		Expect(request).To(HaveKeyWithValue("features", "nesting=1,keyctl=1"))
+		Expect(request).NotTo(HaveKey("nameserver"))
+		Expect(request).NotTo(HaveKey("arch"))
+		Expect(request).NotTo(HaveKey("pool"))
+	})
+
+	It("should render arch in lxc naming", func() {
+		request, err := instance.LXCRequest("ct", 100, "local-lvm", container, hardware, infrav1.Network{}, infrav1.ArchAarch64, "cappx", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request).To(HaveKeyWithValue("arch", "arm64"))
+	})
+
+	It("should render pool", func() {
+		request, err := instance.LXCRequest("ct", 100, "local-lvm", container, hardware, infrav1.Network{}, "", "cappx", "test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request).To(HaveKeyWithValue("pool", "test"))
+	})
+
+	It("should render static ip", func() {
+		network := infrav1.Network{IPConfig: infrav1.IPConfig{IP: "10.0.0.10/24", Gateway: "10.0.0.1"}, NameServer: "10.0.0.1"}
+		request, err := instance.LXCRequest("ct", 100, "local-lvm", container, hardware, network, "", "cappx", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request).To(HaveKeyWithValue("net0", "name=eth0,bridge=vmbr0,firewall=1,ip=10.0.0.10/24,gw=10.0.0.1"))
+		Expect(request).To(HaveKeyWithValue("nameserver", "10.0.0.1"))
+	})
+
+	It("should render vlan and mtu", func() {
+		hardware := hardware
+		hardware.NetworkDevice.Tag, hardware.NetworkDevice.MTU = 100, 9000
+		request, err := instance.LXCRequest("ct", 100, "local-lvm", container, hardware, infrav1.Network{}, "", "cappx", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request).To(HaveKeyWithValue("net0", "name=eth0,bridge=vmbr0,firewall=1,tag=100,mtu=9000,ip=dhcp"))
+	})
+})
+
+var _ = Describe("diskSizeGiB", Label("unit", "instance"), func() {
+	It("should round up to GiB", func() {
+		for size, expected := range map[string]int{"50G": 50, "+1T": 1024, "1536M": 2, "0.5G": 1, "1073741824": 1} {
+			Expect(instance.DiskSizeGiB(size)).To(Equal(expected), size)
+		}
+	})
+
+	It("should reject invalid size", func() {
+		_, err := instance.DiskSizeGiB("G")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("bootstrap commands", Label("unit", "instance"), func() {
+	It("should install the script into the rootfs", func() {
+		cmd := instance.InstallBootstrapCommand(100, "/tmp/cappx-100-bootstrap.sh")
+		Expect(cmd).To(HavePrefix("pct mount 100 "))
+		Expect(cmd).To(ContainSubstring("mv /tmp/cappx-100-bootstrap.sh /var/lib/lxc/100/rootfs/etc/cappx/cloud-config.sh"))
+		Expect(cmd).To(HaveSuffix("pct unmount 100; exit $rc"))
+	})
+
+	It("should run the script only once", func() {
+		cmd := instance.RunBootstrapCommand(100)
+		Expect(cmd).To(HavePrefix("pct exec 100 -- sh -c 'test -e /etc/cappx/cloud-config.sh.started && exit 0;"))
+		Expect(cmd).To(ContainSubstring("nohup sh /etc/cappx/cloud-config.sh >/var/log/cappx-bootstrap.log 2>&1 &"))
+	})
+})
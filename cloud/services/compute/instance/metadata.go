@@ -0,0 +1,205 @@
+package instance
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/version"
+)
+
+const (
+	// used if options.description is empty
+	defaultDescriptionTemplate = `Managed by cluster-api-provider-proxmox {{ .ProviderVersion }}
+
+- cluster: {{ .Namespace }}/{{ .ClusterName }}
+- machine: {{ .MachineName }}
+{{- if .Owner }}
+- owner: {{ .Owner }}
+{{- end }}`
+
+	// proxmox names vms by dns names. longer names are not valid hostnames
+	maxNameLength = 63
+)
+
+var (
+	// characters proxmox rejects in tags
+	invalidTagChars = regexp.MustCompile(`[^a-z0-9_+.-]`)
+
+	// names proxmox accepts for vms
+	validName = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+)
+
+// values available in options.description template
+type descriptionData struct {
+	ClusterName        string
+	Namespace          string
+	MachineName        string
+	ProxmoxMachineName string
+	Owner              string
+	ProviderVersion    string
+}
+
+func (s *Service) descriptionData() descriptionData {
+	return descriptionData{
+		ClusterName:        s.scope.ClusterName(),
+		Namespace:          s.scope.Namespace(),
+		MachineName:        s.scope.MachineName(),
+		ProxmoxMachineName: s.scope.Name(),
+		Owner:              s.scope.MachineOwner(),
+		ProviderVersion:    version.Get(),
+	}
+}
+
+// render options.description as go template. e.g. "{{ .ClusterName }}/{{ .MachineName }}"
+func renderDescription(description string, data descriptionData) (string, error) {
+	if description == "" {
+		description = defaultDescriptionTemplate
+	}
+	tmpl, err := template.New("description").Option("missingkey=error").Parse(description)
+	if err != nil {
+		return "", fmt.Errorf("description: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("description: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// values available in nameTemplate
+type nameData struct {
+	descriptionData
+	VMID          int
+	FailureDomain string
+}
+
+func (s *Service) nameData(vmid int) nameData {
+	data := nameData{descriptionData: s.descriptionData(), VMID: vmid}
+	if failureDomain := s.scope.FailureDomain(); failureDomain != nil {
+		data.FailureDomain = *failureDomain
+	}
+	return data
+}
+
+// render nameTemplate as go template. e.g. "k8s-{{ .ClusterName }}-{{ .VMID }}"
+// the name of the ProxmoxMachine is used if the template is empty
+func renderName(nameTemplate string, data nameData) (string, error) {
+	if nameTemplate == "" {
+		return data.ProxmoxMachineName, nil
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Funcs(template.FuncMap{
+		// trunc n s returns the first n characters of s. e.g. {{ trunc 20 .MachineName }}
+		"trunc": func(n int, s string) string {
+			if len(s) > n {
+				return s[:n]
+			}
+			return s
+		},
+	}).Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("nameTemplate: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("nameTemplate: %w", err)
+	}
+	name := buf.String()
+	if len(name) > maxNameLength {
+		return "", fmt.Errorf("nameTemplate: name %q is longer than %d characters", name, maxNameLength)
+	}
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("nameTemplate: name %q is not a valid dns name", name)
+	}
+	return name, nil
+}
+
+// returns tags tracing the guest back to its cluster and ProxmoxMachine
+func metadataTags(clusterName, namespace, name string) infrav1.Tags {
+	tags := infrav1.Tags{guest.ManagedTag}
+	if clusterName != "" {
+		tags = append(tags, infrav1.Tag(guest.ClusterTag(clusterName)))
+	}
+	return append(tags, infrav1.Tag(guest.MachineTag(namespace, name)))
+}
+
+func (s *Service) metadataTags() infrav1.Tags {
+	return metadataTags(s.scope.ClusterName(), s.scope.Namespace(), s.scope.Name())
+}
+
+// returns tags of the guest: cappx, cluster and machine tags, vmTags of the cluster, options.tags
+// and tags mapped from labels and annotations
+func (s *Service) guestTags() infrav1.Tags {
+	tags := append(s.metadataTags(), s.scope.GetVMTags()...)
+	tags = append(tags, s.scope.GetOptions().Tags...)
+	return append(tags, mappedTags(s.scope.GetTagMappings(), s.scope.GetLabels(), s.scope.GetAnnotations())...)
+}
+
+// returns the current tags with the mapped tags updated. cappx, cluster and machine tags and the tags
+// of the cluster for all vms are always kept even if a mapping owns their prefix
+func (s *Service) syncedTags(current string) (string, bool) {
+	kept := append(s.metadataTags(), s.scope.GetVMTags()...)
+	mapped := mappedTags(s.scope.GetTagMappings(), s.scope.GetLabels(), s.scope.GetAnnotations())
+	return syncTags(current, s.scope.GetTagMappings(), append(kept, mapped...))
+}
+
+// returns tags of the mappings whose label or annotation is set. values are lowercased
+// and characters proxmox rejects are replaced by "_"
+func mappedTags(mappings []infrav1.TagMapping, labels, annotations map[string]string) infrav1.Tags {
+	tags := infrav1.Tags{}
+	for _, m := range mappings {
+		value := labels[m.Label]
+		if m.Annotation != "" {
+			value = annotations[m.Annotation]
+		}
+		if value == "" {
+			continue
+		}
+		tag := invalidTagChars.ReplaceAllString(strings.ToLower(tagPrefix(m)+value), "_")
+		tags = append(tags, infrav1.Tag(guest.TruncateTag(tag)))
+	}
+	return tags
+}
+
+// e.g. "deployment-name." for cluster.x-k8s.io/deployment-name
+func tagPrefix(m infrav1.TagMapping) string {
+	if m.Prefix != "" {
+		return strings.ToLower(m.Prefix)
+	}
+	key := m.Label
+	if m.Annotation != "" {
+		key = m.Annotation
+	}
+	return strings.ToLower(key[strings.LastIndex(key, "/")+1:]) + "."
+}
+
+// returns the current tags with the tags owned by the mappings replaced by the mapped ones.
+// false is returned if the tags are already up to date. the order of tags is ignored
+// since proxmox may sort them
+func syncTags(current string, mappings []infrav1.TagMapping, mapped infrav1.Tags) (string, bool) {
+	currentTags := splitTags(current)
+	desired := slices.DeleteFunc(slices.Clone(currentTags), func(tag infrav1.Tag) bool {
+		return slices.ContainsFunc(mappings, func(m infrav1.TagMapping) bool {
+			return strings.HasPrefix(string(tag), tagPrefix(m))
+		})
+	})
+	desired = append(desired, mapped...).Normalize()
+	sorted := slices.Clone(desired)
+	slices.Sort(sorted)
+	slices.Sort(currentTags)
+	return desired.String(), !slices.Equal(sorted, currentTags)
+}
+
+// splits tags of proxmox api into normalized tags
+func splitTags(tags string) infrav1.Tags {
+	result := infrav1.Tags{}
+	for _, tag := range strings.Split(tags, ";") {
+		result = append(result, infrav1.Tag(tag))
+	}
+	return result.Normalize()
+}
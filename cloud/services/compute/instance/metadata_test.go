@@ -0,0 +1,128 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("renderDescription", Label("unit", "instance"), func() {
+	data := instance.DescriptionData{
+		ClusterName:     "cappx-test",
+		Namespace:       "default",
+		MachineName:     "cappx-test-md-0-abcde",
+		Owner:           "MachineDeployment/cappx-test-md-0",
+		ProviderVersion: "v0.5.0",
+	}
+
+	It("should render default description", func() {
+		description, err := instance.RenderDescription("", data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(description).To(ContainSubstring("cluster-api-provider-proxmox v0.5.0"))
+		Expect(description).To(ContainSubstring("- cluster: default/cappx-test"))
+		Expect(description).To(ContainSubstring("- owner: MachineDeployment/cappx-test-md-0"))
+	})
+
+	It("should render custom template", func() {
+		description, err := instance.RenderDescription("{{ .ClusterName }}/{{ .MachineName }}", data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(description).To(Equal("cappx-test/cappx-test-md-0-abcde"))
+	})
+
+	It("should keep plain description", func() {
+		description, err := instance.RenderDescription("my vm", data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(description).To(Equal("my vm"))
+	})
+
+	It("should fail with unknown field", func() {
+		_, err := instance.RenderDescription("{{ .Unknown }}", data)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("renderName", Label("unit", "instance"), func() {
+	data := instance.DescriptionData{
+		ClusterName:        "cappx-test",
+		Namespace:          "default",
+		MachineName:        "cappx-test-md-0-abcde",
+		ProxmoxMachineName: "cappx-test-md-0-fghij",
+	}
+
+	It("should use the name of the ProxmoxMachine by default", func() {
+		Expect(instance.RenderName("", data, 100, "")).To(Equal("cappx-test-md-0-fghij"))
+	})
+
+	It("should render vmid and failure domain", func() {
+		Expect(instance.RenderName("k8s-{{ .FailureDomain }}-{{ .VMID }}", data, 100, "rack1")).To(Equal("k8s-rack1-100"))
+	})
+
+	It("should truncate values", func() {
+		Expect(instance.RenderName("{{ trunc 10 .ClusterName }}.{{ trunc 20 .ClusterName }}", data, 100, "")).To(Equal("cappx-test.cappx-test"))
+		Expect(instance.RenderName("{{ trunc 5 .MachineName }}-{{ .VMID }}", data, 100, "")).To(Equal("cappx-100"))
+	})
+
+	It("should reject invalid names", func() {
+		for _, tmpl := range []string{"{{ .Unknown }}", "{{ .MachineName }}_{{ .VMID }}", "-{{ .VMID }}", "{{ .MachineName }}-{{ .MachineName }}-{{ .MachineName }}"} {
+			_, err := instance.RenderName(tmpl, data, 100, "")
+			Expect(err).To(HaveOccurred(), tmpl)
+		}
+	})
+})
+
+var _ = Describe("metadataTags", Label("unit", "instance"), func() {
+	It("should tag cluster and machine names", func() {
+		Expect(instance.MetadataTags("cappx-test", "default", "cappx-test-md-0-abcde")).To(Equal(infrav1.Tags{"cappx", "cluster.cappx-test", "machine.default.cappx-test-md-0-abcde"}))
+	})
+})
+
+var _ = Describe("mappedTags", Label("unit", "instance"), func() {
+	mappings := []infrav1.TagMapping{
+		{Label: "cluster.x-k8s.io/deployment-name"},
+		{Label: "team", Prefix: "Team-"},
+		{Annotation: "example.com/env"},
+	}
+
+	It("should map labels and annotations", func() {
+		labels := map[string]string{"cluster.x-k8s.io/deployment-name": "cappx-test-md-0", "team": "Payments"}
+		annotations := map[string]string{"example.com/env": "prod eu/1"}
+		Expect(instance.MappedTags(mappings, labels, annotations)).To(Equal(infrav1.Tags{
+			"deployment-name.cappx-test-md-0", "team-payments", "env.prod_eu_1",
+		}))
+	})
+
+	It("should skip missing values", func() {
+		Expect(instance.MappedTags(mappings, map[string]string{"team": ""}, nil)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("syncTags", Label("unit", "instance"), func() {
+	mappings := []infrav1.TagMapping{{Label: "team"}}
+
+	It("should replace owned tags only", func() {
+		tags, changed := instance.SyncTags("cappx;cluster.test;team.a;web", mappings, infrav1.Tags{"team.b"})
+		Expect(changed).To(BeTrue())
+		Expect(tags).To(Equal("cappx;cluster.test;web;team.b"))
+	})
+
+	It("should remove tags whose label is gone", func() {
+		tags, changed := instance.SyncTags("cappx;team.a", mappings, infrav1.Tags{})
+		Expect(changed).To(BeTrue())
+		Expect(tags).To(Equal("cappx"))
+	})
+
+	It("should add missing tags of the cluster and keep removed ones", func() {
+		tags, changed := instance.SyncTags("cappx;env-old", mappings, infrav1.Tags{"cappx", "cost-center.42"})
+		Expect(changed).To(BeTrue())
+		Expect(tags).To(Equal("cappx;env-old;cost-center.42"))
+	})
+
+	It("should ignore the order of proxmox", func() {
+		_, changed := instance.SyncTags("cappx;team.a;web", mappings, infrav1.Tags{"team.a"})
+		Expect(changed).To(BeFalse())
+		_, changed = instance.SyncTags("team.a;cappx", mappings, infrav1.Tags{"cappx", "team.a"})
+		Expect(changed).To(BeFalse())
+	})
+})
@@ -0,0 +1,55 @@
+package instance
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// provisioning takes from seconds for lxc up to tens of minutes for imported images
+	lifecycleBuckets = []float64{5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+	lifecycleLabels  = []string{"namespace", "cluster", "machine_deployment"}
+
+	scheduledDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "cappx",
+			Subsystem: "machine",
+			Name:      "scheduled_duration_seconds",
+			Help:      "Time from the creation of a ProxmoxMachine until its instance is scheduled",
+			Buckets:   lifecycleBuckets,
+		},
+		lifecycleLabels,
+	)
+	createdDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "cappx",
+			Subsystem: "machine",
+			Name:      "created_duration_seconds",
+			Help:      "Time from the creation of a ProxmoxMachine until its instance is created",
+			Buckets:   lifecycleBuckets,
+		},
+		lifecycleLabels,
+	)
+	readyDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "cappx",
+			Subsystem: "machine",
+			Name:      "ready_duration_seconds",
+			Help:      "Time from the creation of a ProxmoxMachine until its instance is running and bootstrapped",
+			Buckets:   lifecycleBuckets,
+		},
+		lifecycleLabels,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(scheduledDuration, createdDuration, readyDuration)
+}
+
+// observes the time elapsed since the creation of the machine
+func (s *Service) observeLifecycle(histogram *prometheus.HistogramVec) {
+	elapsed := time.Since(s.scope.CreationTimestamp().Time)
+	histogram.WithLabelValues(s.scope.Namespace(), s.scope.ClusterName(), s.scope.MachineDeploymentName()).Observe(elapsed.Seconds())
+}
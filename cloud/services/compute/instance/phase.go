@@ -0,0 +1,96 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// ErrPhaseTimedOut is returned when a phase of provisioning has not completed within its timeout
+var ErrPhaseTimedOut = errors.New("provisioning phase timed out")
+
+// returns the timeout of the phase. nil if the phase is not limited
+func phaseTimeout(timeouts infrav1.ProvisioningTimeouts, name infrav1.ProvisioningPhaseName) *metav1.Duration {
+	switch name {
+	case infrav1.ProvisioningPhaseImageImport:
+		return timeouts.ImageImport
+	case infrav1.ProvisioningPhaseCreate:
+		return timeouts.Create
+	case infrav1.ProvisioningPhaseGuestReady:
+		return timeouts.GuestReady
+	case infrav1.ProvisioningPhaseBootstrap:
+		return timeouts.Bootstrap
+	}
+	return nil
+}
+
+// PhaseRemaining returns how long the phase may still take. false if the phase is not limited
+func PhaseRemaining(phase *infrav1.ProvisioningPhase, timeouts infrav1.ProvisioningTimeouts, now time.Time) (time.Duration, bool) {
+	if phase == nil {
+		return 0, false
+	}
+	timeout := phaseTimeout(timeouts, phase.Name)
+	if timeout == nil {
+		return 0, false
+	}
+	return phase.StartTime.Add(timeout.Duration).Sub(now), true
+}
+
+// PhaseExpired returns ErrPhaseTimedOut if the phase has taken longer than its timeout
+func PhaseExpired(phase *infrav1.ProvisioningPhase, timeouts infrav1.ProvisioningTimeouts, now time.Time) error {
+	if remaining, ok := PhaseRemaining(phase, timeouts, now); ok && remaining <= 0 {
+		return phaseTimedOut(phase.Name, *phaseTimeout(timeouts, phase.Name))
+	}
+	return nil
+}
+
+func phaseTimedOut(name infrav1.ProvisioningPhaseName, timeout metav1.Duration) error {
+	return fmt.Errorf("%w: %s has not completed within %s", ErrPhaseTimedOut, name, timeout.Duration)
+}
+
+// starts the phase unless the machine is already in it
+func (s *Service) enterPhase(name infrav1.ProvisioningPhaseName) {
+	if phase := s.scope.ProvisioningPhase(); phase != nil && phase.Name == name {
+		return
+	}
+	s.scope.SetProvisioningPhase(&infrav1.ProvisioningPhase{Name: name, StartTime: metav1.Now()})
+}
+
+func (s *Service) inPhase(name infrav1.ProvisioningPhaseName) bool {
+	phase := s.scope.ProvisioningPhase()
+	return phase != nil && phase.Name == name
+}
+
+// enters the phase and runs f with a context cancelled once the phase has timed out
+func (s *Service) runPhase(ctx context.Context, name infrav1.ProvisioningPhaseName, f func(context.Context) error) error {
+	s.enterPhase(name)
+	remaining, ok := PhaseRemaining(s.scope.ProvisioningPhase(), s.scope.GetProvisioningTimeouts(), time.Now())
+	if !ok {
+		return f(ctx)
+	}
+	phaseCtx, cancel := context.WithTimeout(ctx, remaining)
+	defer cancel()
+	err := f(phaseCtx)
+	if err != nil && errors.Is(phaseCtx.Err(), context.DeadlineExceeded) {
+		return phaseTimedOut(name, *phaseTimeout(s.scope.GetProvisioningTimeouts(), name))
+	}
+	return err
+}
+
+// the ready instance is bootstrapping until the node of the machine has joined
+func (s *Service) reconcilePhase(guest Guest) {
+	if guest.Status() != infrav1.InstanceStatusRunning {
+		return
+	}
+	if s.inPhase(infrav1.ProvisioningPhaseGuestReady) {
+		s.enterPhase(infrav1.ProvisioningPhaseBootstrap)
+	}
+	if s.inPhase(infrav1.ProvisioningPhaseBootstrap) && s.scope.Bootstrapped() {
+		s.scope.SetProvisioningPhase(nil)
+	}
+}
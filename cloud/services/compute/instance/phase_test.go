@@ -0,0 +1,43 @@
+package instance_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("PhaseExpired", Label("unit", "instance"), func() {
+	now := time.Now()
+	timeouts := infrav1.ProvisioningTimeouts{
+		Create:    &metav1.Duration{Duration: 10 * time.Minute},
+		Bootstrap: &metav1.Duration{Duration: 20 * time.Minute},
+	}
+	phase := func(name infrav1.ProvisioningPhaseName, started time.Duration) *infrav1.ProvisioningPhase {
+		return &infrav1.ProvisioningPhase{Name: name, StartTime: metav1.NewTime(now.Add(-started))}
+	}
+
+	It("should not expire without phase or timeout", func() {
+		Expect(instance.PhaseExpired(nil, timeouts, now)).To(Succeed())
+		Expect(instance.PhaseExpired(phase(infrav1.ProvisioningPhaseGuestReady, time.Hour), timeouts, now)).To(Succeed())
+		_, ok := instance.PhaseRemaining(phase(infrav1.ProvisioningPhaseGuestReady, time.Hour), timeouts, now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should not expire within the timeout of the phase", func() {
+		Expect(instance.PhaseExpired(phase(infrav1.ProvisioningPhaseBootstrap, 15*time.Minute), timeouts, now)).To(Succeed())
+		remaining, ok := instance.PhaseRemaining(phase(infrav1.ProvisioningPhaseBootstrap, 15*time.Minute), timeouts, now)
+		Expect(ok).To(BeTrue())
+		Expect(remaining).To(Equal(5 * time.Minute))
+	})
+
+	It("should expire once the phase has taken longer than its timeout", func() {
+		err := instance.PhaseExpired(phase(infrav1.ProvisioningPhaseCreate, 15*time.Minute), timeouts, now)
+		Expect(err).To(MatchError(instance.ErrPhaseTimedOut))
+		Expect(err.Error()).To(ContainSubstring("Create has not completed within 10m0s"))
+	})
+})
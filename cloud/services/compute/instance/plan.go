@@ -0,0 +1,120 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/logging"
+)
+
+// keys of the create options holding disks
+var diskKeys = regexp.MustCompile(`^((ide|sata|scsi|virtio|mp)\d+|efidisk0|tpmstate|rootfs)$`)
+
+// Plan returns what Reconcile would do without calling mutating proxmox apis.
+// only the creation is planned. existing instances are left as they are
+func (s *Service) Plan(ctx context.Context) (infrav1.Plan, error) {
+	ctx = logging.IntoContext(ctx, logging.Instance)
+	backend, err := s.backend()
+	if err != nil {
+		return infrav1.Plan{}, err
+	}
+	instance, err := backend.Get(ctx)
+	if err == nil {
+		return existingPlan(infrav1.PlanActionNone, s.scope.GetType(), instance), nil
+	}
+	if !rest.IsNotFound(err) {
+		return infrav1.Plan{}, err
+	}
+	return backend.Plan(ctx)
+}
+
+// PlanDelete returns what Delete would do without calling mutating proxmox apis
+func (s *Service) PlanDelete(ctx context.Context) (infrav1.Plan, error) {
+	ctx = logging.IntoContext(ctx, logging.Instance)
+	backend, err := s.backend()
+	if err != nil {
+		return infrav1.Plan{}, err
+	}
+	instance, err := backend.GetByVMID(ctx)
+	if err != nil {
+		if rest.IsNotFound(err) {
+			return infrav1.Plan{Action: infrav1.PlanActionNone, Type: s.scope.GetType()}, nil
+		}
+		return infrav1.Plan{}, err
+	}
+	return existingPlan(infrav1.PlanActionDelete, s.scope.GetType(), instance), nil
+}
+
+func existingPlan(action infrav1.PlanAction, instanceType infrav1.InstanceType, instance Guest) infrav1.Plan {
+	return infrav1.Plan{Action: action, Type: instanceType, Node: instance.Node(), VMID: instance.VMID()}
+}
+
+func (b *qemuBackend) Plan(ctx context.Context) (infrav1.Plan, error) {
+	plan := infrav1.Plan{Action: infrav1.PlanActionCreate, Type: infrav1.InstanceTypeQEMU, Source: b.scope.GetImage().Source()}
+	if restore := b.scope.GetRestore(); restore != nil {
+		plan.Action, plan.Source = infrav1.PlanActionRestore, restore.Archive
+	}
+	vmoption, err := b.createOptions()
+	if err != nil {
+		return plan, err
+	}
+	result, err := b.scheduler.Plan(b.schedulingContext(ctx), vmoption)
+	if err != nil {
+		return plan, err
+	}
+	plan.Node, plan.VMID, plan.Storage = result.Node(), result.VMID(), result.Storage()
+	b.injectVMOption(&vmoption, result.Storage())
+	if plan.Action == infrav1.PlanActionRestore {
+		// disks come from the backup. only the cloud-init drive is added
+		vmoption.Scsi = api.Scsi{Scsi30: vmoption.Scsi.Scsi30}
+	}
+	plan.Disks, err = planDisks(vmoption)
+	return plan, err
+}
+
+func (b *lxcBackend) Plan(ctx context.Context) (infrav1.Plan, error) {
+	plan := infrav1.Plan{Action: infrav1.PlanActionCreate, Type: infrav1.InstanceTypeLXC}
+	container, vmoption, err := b.lxcOptions()
+	if err != nil {
+		return plan, err
+	}
+	plan.Source = container.OSTemplate
+	result, err := b.scheduler.Plan(b.schedulingContext(ctx), vmoption)
+	if err != nil {
+		return plan, err
+	}
+	plan.Node, plan.VMID, plan.Storage = result.Node(), result.VMID(), result.Storage()
+	request, err := lxcRequest(b.scope.Name(), result.VMID(), result.Storage(), *container, b.scope.GetHardware(), b.scope.GetNetwork(), b.scope.GetOptions().Arch, vmoption.Tags, b.scope.GetPool())
+	if err != nil {
+		return plan, err
+	}
+	plan.Disks, err = planDisks(request)
+	return plan, err
+}
+
+// returns sorted disks of the create options or request. e.g. scsi0=local-lvm:0,import-from=...
+func planDisks(options interface{}) ([]string, error) {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	disks := []string{}
+	for key, value := range fields {
+		if diskKeys.MatchString(key) {
+			disks = append(disks, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+	slices.Sort(disks)
+	return disks, nil
+}
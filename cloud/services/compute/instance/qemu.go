@@ -4,16 +4,27 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/qemuconfig"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/cordon"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/plugins/nodegroup"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/feature"
 	"github.com/k8s-proxmox/proxmox-go/api"
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
 	"github.com/k8s-proxmox/proxmox-go/rest"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
 	bootDvice = "scsi0"
+	gib       = 1 << 30
 )
 
 // reconciles QEMU instance
@@ -30,14 +41,6 @@ func (s *Service) reconcileQEMU(ctx context.Context) (*proxmox.VirtualMachine, e
 
 		// no qemu found, try to create new one
 		log.V(3).Info("qemu wasn't found. new qemu will be created")
-		if exist, err := s.client.VirtualMachineExistsWithName(ctx, s.scope.Name()); exist || err != nil {
-			if exist {
-				// there should no qemu with same name. occuring an error
-				err = fmt.Errorf("qemu %s already exists", s.scope.Name())
-			}
-			log.Error(err, "stop creating new qemu to avoid replicating same qemu")
-			return nil, err
-		}
 		qemu, err = s.createQEMU(ctx)
 		if err != nil {
 			log.Error(err, "failed to create qemu")
@@ -59,7 +62,7 @@ func (s *Service) getQEMU(ctx context.Context) (*proxmox.VirtualMachine, error)
 	log.Info("getting qemu from vmid")
 	vmid := s.scope.GetVMID()
 	if vmid != nil {
-		return s.client.VirtualMachine(ctx, *vmid)
+		return virtualMachine(ctx, &s.client, *vmid)
 	}
 	return nil, rest.NotFoundErr
 }
@@ -70,15 +73,33 @@ func (s *Service) createQEMU(ctx context.Context) (*proxmox.VirtualMachine, erro
 
 	// create qemu
 	log.Info("making qemu spec")
-	vmoption := s.generateVMOptions()
-	// bind annotation key-values to context
-	schedCtx := framework.ContextWithMap(ctx, s.scope.Annotations())
-	result, err := s.scheduler.CreateQEMU(schedCtx, &vmoption)
+	vmoption, err := s.createOptions()
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.schedule(ctx, &vmoption)
 	if err != nil {
 		log.Error(err, "failed to schedule qemu instance")
 		return nil, err
 	}
 	node, vmid, storage := result.Node(), result.VMID(), result.Storage()
+	if err := s.checkWipeable(ctx, storage, false); err != nil {
+		return nil, err
+	}
+
+	// the name may embed the vmid, so it is rendered once the vmid is selected
+	vmoption.Name, err = renderName(s.scope.GetNameTemplate(), s.nameData(vmid))
+	if err != nil {
+		return nil, err
+	}
+	if exist, err := s.client.VirtualMachineExistsWithName(ctx, vmoption.Name); exist || err != nil {
+		if exist {
+			// there should no qemu with same name. occuring an error
+			err = fmt.Errorf("qemu %s already exists", vmoption.Name)
+		}
+		log.Error(err, "stop creating new qemu to avoid replicating same qemu")
+		return nil, err
+	}
 	s.scope.SetNodeName(node)
 	s.scope.SetVMID(vmid)
 
@@ -86,102 +107,148 @@ func (s *Service) createQEMU(ctx context.Context) (*proxmox.VirtualMachine, erro
 	s.injectVMOption(&vmoption, storage)
 	s.scope.SetStorage(storage)
 
+	if restore := s.scope.GetRestore(); restore != nil {
+		var vm *proxmox.VirtualMachine
+		err := s.runPhase(ctx, infrav1.ProvisioningPhaseCreate, func(ctx context.Context) error {
+			vm, err = s.restoreQEMU(ctx, node, vmid, *restore, vmoption)
+			return err
+		})
+		return vm, err
+	}
+
 	// os image
-	if err := s.setCloudImage(ctx); err != nil {
+	if err := s.runPhase(ctx, infrav1.ProvisioningPhaseImageImport, s.setCloudImage); err != nil {
 		return nil, err
 	}
 
 	// actually create qemu
-	vm, err := s.client.CreateVirtualMachine(ctx, node, vmid, vmoption)
-	if err != nil {
+	if err := s.runPhase(ctx, infrav1.ProvisioningPhaseCreate, func(ctx context.Context) error {
+		upid, err := audit.REST(&s.client).CreateVirtualMachine(ctx, node, vmid, vmoption)
+		if err != nil {
+			return err
+		}
+		return s.waitCreation(ctx, node, vmid, *upid)
+	}); err != nil {
 		return nil, err
 	}
+	return virtualMachine(ctx, &s.client, vmid)
+}
 
-	// Resize disks immediately after creation
-	if err := s.resizeExtraDisks(ctx, vm); err != nil {
-		log.Error(err, "Failed to resize extra disks")
+// validates the machine spec and returns the options of the qemu before scheduling
+func (s *Service) createOptions() (api.VirtualMachineCreateOptions, error) {
+	if _, err := s.scope.ClusterNetwork(); err != nil {
+		return api.VirtualMachineCreateOptions{}, err
+	}
+	if _, err := s.scope.NodeGroup(); err != nil {
+		return api.VirtualMachineCreateOptions{}, err
+	}
+	if err := validateExtraDisks(s.scope.GetHardware().ExtraDisks); err != nil {
+		return api.VirtualMachineCreateOptions{}, err
+	}
+	if err := validateArch(s.scope.GetOptions().Arch, s.scope.GetHardware()); err != nil {
+		return api.VirtualMachineCreateOptions{}, err
+	}
+	if err := s.scope.GetOptions().Tags.Validate(); err != nil {
+		return api.VirtualMachineCreateOptions{}, fmt.Errorf("invalid options: %w", err)
+	}
+	if s.scope.GetRestore() == nil {
+		if s.scope.GetImage().Source() == "" {
+			return api.VirtualMachineCreateOptions{}, fmt.Errorf("image must be specified unless the ProxmoxCluster has machineDefaults.image")
+		}
+		if err := s.checkImportFrom(); err != nil {
+			return api.VirtualMachineCreateOptions{}, err
+		}
+	}
+	if s.scope.GetOptions().Args != "" && !feature.Gates.Enabled(feature.QEMUArgs) {
+		return api.VirtualMachineCreateOptions{}, fmt.Errorf("options.args requires the %s feature gate to be enabled", feature.QEMUArgs)
 	}
 
-	return vm, nil
+	description, err := renderDescription(s.scope.GetOptions().Description, s.descriptionData())
+	if err != nil {
+		return api.VirtualMachineCreateOptions{}, fmt.Errorf("invalid options: %w", err)
+	}
+	// the vmid is not selected yet. checked again once it is
+	if _, err := renderName(s.scope.GetNameTemplate(), s.nameData(0)); err != nil {
+		return api.VirtualMachineCreateOptions{}, err
+	}
+	vmoption := s.generateVMOptions()
+	vmoption.Description = description
+	return vmoption, nil
 }
 
-func (s *Service) resizeExtraDisks(ctx context.Context, vm *proxmox.VirtualMachine) error {
-	log := log.FromContext(ctx)
-	log.Info("Resizing additional disks for VM", "vmid", vm.VM.VMID)
-
-	extraDisks := s.scope.GetHardware().ExtraDisks
-	if len(extraDisks) == 0 {
-		return nil // No extra disks, nothing to do
+// binds annotation key-values, nodes the instance must or must not be placed on and reserved vmids to context
+func (s *Service) schedulingContext(ctx context.Context) context.Context {
+	schedCtx := framework.ContextWithMap(ctx, s.scope.Annotations())
+	nodes := append([]string{}, s.scope.CordonedNodes()...)
+	// retried on other nodes unless none of them fits
+	nodes = append(nodes, s.scope.FailedNodes()...)
+	if replication := s.scope.GetReplication(); replication != nil {
+		// disks can not be replicated to the node itself
+		nodes = append(nodes, replication.Target)
 	}
-
-	for i, disk := range extraDisks {
-		diskName := fmt.Sprintf("scsi%d", i+1) // scsi1, scsi2, scsi3...
-		log.Info("Resizing disk", "vmid", vm.VM.VMID, "disk", diskName, "size", disk.Size)
-
-		// Use `ResizeVolume` to resize the disk
-		err := vm.ResizeVolume(ctx, diskName, disk.Size)
-		if err != nil {
-			log.Error(err, "Failed to resize disk", "disk", diskName)
-			return err
+	if len(nodes) > 0 {
+		schedCtx = context.WithValue(schedCtx, framework.CtxKey(cordon.CordonedNodesKey), strings.Join(nodes, ","))
+	}
+	if pinned := s.scope.PinnedNode(); pinned != "" {
+		schedCtx = context.WithValue(schedCtx, framework.CtxKey(framework.PinnedNodeKey), pinned)
+	}
+	if group, err := s.scope.NodeGroup(); err == nil && group != nil {
+		if len(group.Nodes) > 0 {
+			schedCtx = context.WithValue(schedCtx, framework.CtxKey(nodegroup.NodeGroupNodesKey), strings.Join(group.Nodes, ","))
+		}
+		if group.NodeRegex != "" {
+			schedCtx = context.WithValue(schedCtx, framework.CtxKey(nodegroup.NodeGroupRegexKey), group.NodeRegex)
 		}
 	}
-
-	log.Info("Successfully resized all extra disks", "vmid", vm.VM.VMID)
-	return nil
+	if reserved := s.scope.ReservedVMIDs(); reserved != "" {
+		// added to the vmids reserved by the annotation, if any
+		if value, ok := s.scope.Annotations()[framework.ReservedVMIDsKey]; ok {
+			reserved = value + "," + reserved
+		}
+		schedCtx = context.WithValue(schedCtx, framework.CtxKey(framework.ReservedVMIDsKey), reserved)
+	}
+	return schedCtx
 }
 
 func (s *Service) generateVMOptions() api.VirtualMachineCreateOptions {
 	vmName := s.scope.Name()
-	snippetStorageName := s.scope.GetClusterStorage().Name
+	snippetStorageName := s.scope.GetSnippetStorage().Name
 	imageStorageName := s.scope.GetStorage()
 	network := s.scope.GetNetwork()
 	hardware := s.scope.GetHardware()
 	options := s.scope.GetOptions()
 	cicustom := fmt.Sprintf("user=%s:%s", snippetStorageName, userSnippetPath(vmName))
-	ide2 := fmt.Sprintf("file=%s:cloudinit,media=cdrom", imageStorageName)
+	if hasNetworkSnippet(network) {
+		cicustom = fmt.Sprintf("%s,network=%s:%s", cicustom, snippetStorageName, networkSnippetPath(vmName))
+	}
 	net0 := hardware.NetworkDevice.String()
 	// Assign primary SCSI disk
 	scsiDisks := api.Scsi{}
-	scsiDisks.Scsi0 = fmt.Sprintf("%s:0,import-from=%s", imageStorageName, rawImageFilePath(s.scope.GetImage()))
-	// Assign additional disks manually
-	extraDisks := s.scope.GetHardware().ExtraDisks
-	if len(extraDisks) > 5 {
-		log.FromContext(context.TODO()).Error(fmt.Errorf("too many extra disks"), "Only 6 extra disks are supported, ignoring extra disks")
-		extraDisks = extraDisks[:5] // Trim to max 5 extra disks
-	}
-
-	// Assign extra disks
-	scsiStruct := reflect.ValueOf(&scsiDisks).Elem()
-	for i, disk := range extraDisks {
-		fieldName := fmt.Sprintf("Scsi%d", i+1) // Scsi1, Scsi2, ...
-		field := scsiStruct.FieldByName(fieldName)
-		if field.IsValid() && field.CanSet() {
-			field.SetString(fmt.Sprintf("%s:%d,format=%s,size=%s", disk.Storage, i+1, disk.Format, disk.Size))
-			// field.SetString(fmt.Sprintf("%s:%d,size=%s", disk.Storage, i+1, disk.Size))
-		} else {
-			log.FromContext(context.TODO()).Error(fmt.Errorf("invalid SCSI field"), "Failed to set extra disk", "field", fieldName)
-		}
-	}
+	scsiDisks.Scsi0 = fmt.Sprintf("%s:0,import-from=%s", imageStorageName, importSource(s.scope.GetImage()))
+	tags := s.guestTags()
 
 	vmoptions := api.VirtualMachineCreateOptions{
 		ACPI:          boolToInt8(options.ACPI),
-		Agent:         "enabled=1",
+		Affinity:      options.Affinity,
+		Agent:         options.Agent.String(),
 		Arch:          api.Arch(options.Arch),
+		Args:          options.Args,
 		Balloon:       options.Balloon,
 		BIOS:          string(hardware.BIOS),
-		Boot:          fmt.Sprintf("order=%s", bootDvice),
+		Boot:          bootOption(options),
 		CiCustom:      cicustom,
 		Cores:         hardware.CPU,
-		Cpu:           hardware.CPUType,
+		Cpu:           hardware.CPUOption(),
 		CpuLimit:      hardware.CPULimit,
-		Description:   options.Description,
+		CpuUnits:      hardware.CPUUnits,
+		HotPlug:       hotplugOption(hardware, options),
 		HugePages:     options.HugePages.String(),
-		Ide:           api.Ide{Ide2: ide2},
 		IPConfig:      api.IPConfig{IPConfig0: network.IPConfig.String()},
 		KeepHugePages: boolToInt8(options.KeepHugePages),
 		KVM:           boolToInt8(options.KVM),
 		LocalTime:     boolToInt8(options.LocalTime),
 		Lock:          string(options.Lock),
+		Machine:       hardware.Machine,
 		Memory:        hardware.Memory,
 		Name:          vmName,
 		NameServer:    network.NameServer,
@@ -190,16 +257,17 @@ func (s *Service) generateVMOptions() api.VirtualMachineCreateOptions {
 		Node:          s.scope.NodeName(),
 		OnBoot:        boolToInt8(options.OnBoot),
 		OSType:        api.OSType(options.OSType),
+		Pool:          s.scope.GetPool(),
 		Protection:    boolToInt8(options.Protection),
 		Reboot:        int(boolToInt8(options.Reboot)),
 		Scsi:          scsiDisks,
-		ScsiHw:        api.VirtioScsiPci,
+		ScsiHw:        scsiController(hardware, options),
 		SearchDomain:  network.SearchDomain,
 		Serial:        api.Serial{Serial0: "socket"},
 		Shares:        options.Shares,
 		Sockets:       hardware.Sockets,
 		Tablet:        boolToInt8(options.Tablet),
-		Tags:          options.Tags.String(),
+		Tags:          tags.String(),
 		TDF:           boolToInt8(options.TimeDriftFix),
 		Template:      boolToInt8(options.Template),
 		VCPUs:         options.VCPUs,
@@ -207,9 +275,169 @@ func (s *Service) generateVMOptions() api.VirtualMachineCreateOptions {
 		VMID:          s.scope.GetVMID(),
 		VGA:           "serial0",
 	}
+
+	if hardware.RNG != nil {
+		vmoptions.RNG0 = hardware.RNG.String()
+	}
+	if options.WatchDog != nil {
+		vmoptions.WatchDog = options.WatchDog.String()
+	}
+	if options.VGA != nil {
+		vmoptions.VGA = options.VGA.String()
+	}
+	if options.SMBios != nil {
+		vmoptions.SMBios1 = s.smbiosOption(*options.SMBios)
+	}
+	if options.StartUp != nil {
+		vmoptions.StartUp = options.StartUp.String()
+	}
+	if options.Arch == infrav1.ArchAarch64 {
+		aarch64Defaults(&vmoptions, hardware)
+	}
+	if options.OSType.IsWindows() {
+		windowsDefaults(&vmoptions, options)
+	}
+	setCloudInitDrive(&vmoptions, imageStorageName)
+
+	// Assign NUMA nodes (numa0 ~ numa7)
+	numa := reflect.ValueOf(&vmoptions.NumaS).Elem()
+	for i, node := range options.NUMANodes {
+		numa.FieldByName(fmt.Sprintf("Numa%d", i)).SetString(node.String())
+	}
+
+	// Assign host PCI devices (hostpci0 ~ hostpci3)
+	hostpci := reflect.ValueOf(&vmoptions.HostPci).Elem()
+	for i, device := range hardware.PCIDevices {
+		hostpci.FieldByName(fmt.Sprintf("HostPci%d", i)).SetString(device.String())
+	}
+	for i, nic := range hardware.SRIOVNICs {
+		hostpci.FieldByName(fmt.Sprintf("HostPci%d", len(hardware.PCIDevices)+i)).SetString(nic.String())
+	}
 	return vmoptions
 }
 
+// returns smbios1 option. uuid is generated if empty since proxmox
+// does not generate it when smbios1 is specified.
+func (s *Service) smbiosOption(smbios infrav1.SMBios) string {
+	if smbios.UUID == "" {
+		smbios.UUID = s.smbiosUUID()
+	}
+	if smbios.SerialFromMachineUID {
+		smbios.Serial = s.scope.GetMachineUID()
+	}
+	return smbios.String()
+}
+
+// returns the uuid generated for the qemu. it is kept in an annotation of the machine,
+// so that the options are the same whenever they are generated
+func (s *Service) smbiosUUID() string {
+	if id := s.scope.GetSMBiosUUID(); id != "" {
+		return id
+	}
+	id := string(uuid.NewUUID())
+	s.scope.SetSMBiosUUID(id)
+	return id
+}
+
+// returns boot option from options.bootOrder. defaults to root disk
+func bootOption(options infrav1.Options) string {
+	if len(options.BootOrder) == 0 {
+		return fmt.Sprintf("order=%s", bootDvice)
+	}
+	order := []string{}
+	for _, d := range options.BootOrder {
+		order = append(order, string(d))
+	}
+	return fmt.Sprintf("order=%s", strings.Join(order, ";"))
+}
+
+// returns hotplug option from options.hotPlug. memory is appended if memory hotplug is enabled.
+// empty string means proxmox's default (network,disk,usb)
+func hotplugOption(hardware infrav1.Hardware, options infrav1.Options) string {
+	devices := options.HotPlug
+	if len(devices) == 0 {
+		if !hardware.MemoryHotplug {
+			return ""
+		}
+		devices = []infrav1.HotPlugDevice{infrav1.HotPlugNetwork, infrav1.HotPlugDisk, infrav1.HotPlugUSB}
+	}
+	hotplug := []string{}
+	for _, d := range devices {
+		hotplug = append(hotplug, string(d))
+	}
+	if hardware.MemoryHotplug && !slices.Contains(devices, infrav1.HotPlugMemory) {
+		hotplug = append(hotplug, string(infrav1.HotPlugMemory))
+	}
+	return strings.Join(hotplug, ",")
+}
+
+// update hotplug option of existing qemu so that devices can be hot-added afterwards
+func (s *Service) reconcileHotplug(ctx context.Context, vm *proxmox.VirtualMachine, config *api.VirtualMachineConfig) error {
+	log := log.FromContext(ctx)
+	hotplug := hotplugOption(s.scope.GetHardware(), s.scope.GetOptions())
+	if hotplug == "" || qemuconfig.HotPlug(hotplug) == qemuconfig.HotPlug(config.HotPlug) {
+		return nil
+	}
+	log.Info("updating hotplug", "current", config.HotPlug, "desired", hotplug)
+	if err := s.setConfig(ctx, vm, api.VirtualMachineConfig{HotPlug: hotplug}); err != nil {
+		return err
+	}
+	config.HotPlug = hotplug
+	return nil
+}
+
+// increase memory in place if memory hotplug is enabled.
+// decreasing memory is not supported since unplugging dimms may fail.
+func (s *Service) reconcileMemory(ctx context.Context, vm *proxmox.VirtualMachine, config *api.VirtualMachineConfig) error {
+	log := log.FromContext(ctx)
+	if err := validateMaxMemory(s.scope.GetHardware()); err != nil {
+		return err
+	}
+	memory, ok := hotplugMemory(s.scope.GetHardware(), *config)
+	if !ok {
+		return nil
+	}
+	log.Info("hot-plugging memory", "current", int(config.Memory), "desired", memory)
+	if err := s.setConfig(ctx, vm, api.VirtualMachineConfig{Memory: api.StringOrInt(memory)}); err != nil {
+		return err
+	}
+	config.Memory = api.StringOrInt(memory)
+	return nil
+}
+
+// returns the memory to hot-plug into the qemu. false if the memory is not to be changed in place
+func hotplugMemory(hardware infrav1.Hardware, config api.VirtualMachineConfig) (int, bool) {
+	if validateMaxMemory(hardware) != nil {
+		return 0, false
+	}
+	return hardware.Memory, hardware.MemoryHotplug && int(config.Memory) < hardware.Memory
+}
+
+// maxMemory is a limit of cappx, proxmox has no such option. the api server checks it
+// as well, this only guards against specs written before the validation existed
+func validateMaxMemory(hardware infrav1.Hardware) error {
+	if hardware.MaxMemory != 0 && hardware.Memory > hardware.MaxMemory {
+		return fmt.Errorf("memory %dMiB exceeds maxMemory %dMiB", hardware.Memory, hardware.MaxMemory)
+	}
+	return nil
+}
+
+// update tags of existing qemu following labels and annotations of the machine.
+// machine tags missing on qemus created by older versions are added too
+func (s *Service) reconcileTags(ctx context.Context, vm *proxmox.VirtualMachine, config *api.VirtualMachineConfig) error {
+	log := log.FromContext(ctx)
+	tags, changed := s.syncedTags(config.Tags)
+	if !changed {
+		return nil
+	}
+	log.Info("updating tags", "current", config.Tags, "desired", tags)
+	if err := s.setConfig(ctx, vm, api.VirtualMachineConfig{Tags: tags}); err != nil {
+		return err
+	}
+	config.Tags = tags
+	return nil
+}
+
 func boolToInt8(b bool) int8 {
 	if b {
 		return 1
@@ -219,34 +447,91 @@ func boolToInt8(b bool) int8 {
 
 func (s *Service) injectVMOption(vmOption *api.VirtualMachineCreateOptions, storage string) *api.VirtualMachineCreateOptions {
 	// storage is finalized after node scheduling so we need to inject storage name here
-	ide2 := fmt.Sprintf("file=%s:cloudinit,media=cdrom", storage)
-	vmOption.Ide.Ide2 = ide2
+	setCloudInitDrive(vmOption, storage)
 	vmOption.Storage = storage
 	// Assign primary root disk
-	vmOption.Scsi.Scsi0 = fmt.Sprintf("%s:0,import-from=%s", storage, rawImageFilePath(s.scope.GetImage()))
+	vmOption.Scsi.Scsi0 = fmt.Sprintf("%s:0,import-from=%s", storage, importSource(s.scope.GetImage()))
 
-	// Assign Extra Disks (Scsi1, Scsi2, ... up to Scsi5)
-	extraDisks := s.scope.GetHardware().ExtraDisks
-	if len(extraDisks) > 5 {
-		log.FromContext(context.TODO()).Error(fmt.Errorf("too many extra disks"), "Only 5 extra disks are supported, ignoring excess")
-		extraDisks = extraDisks[:5] // Limit to 5 extra disks
+	// Assign extra disks (scsi1 ~ scsi30)
+	scsi := reflect.ValueOf(&vmOption.Scsi).Elem()
+	for i, disk := range s.scope.GetHardware().ExtraDisks {
+		scsi.FieldByName(fmt.Sprintf("Scsi%d", i+1)).SetString(extraDiskOption(disk, storage))
 	}
+	return vmOption
+}
 
-	// Set each disk explicitly
-	if len(extraDisks) > 0 {
-		vmOption.Scsi.Scsi1 = fmt.Sprintf("%s:1,size=%s", extraDisks[0].Storage, extraDisks[0].Size)
+// the virt machine of aarch64 has no ide controller, so the cloud-init drive
+// is attached to the last scsi slot instead of ide2
+func setCloudInitDrive(vmOption *api.VirtualMachineCreateOptions, storage string) {
+	drive := fmt.Sprintf("file=%s:cloudinit,media=cdrom", storage)
+	if vmOption.Arch == api.Aarch64 {
+		vmOption.Scsi.Scsi30 = drive
+		return
 	}
-	if len(extraDisks) > 1 {
-		vmOption.Scsi.Scsi2 = fmt.Sprintf("%s:2,size=%s", extraDisks[1].Storage, extraDisks[1].Size)
+	vmOption.Ide.Ide2 = drive
+}
+
+// fills the options aarch64 guests can not boot without.
+// they boot only via uefi on the virt machine and the default kvm64 cpu is x86 only
+func aarch64Defaults(vmOption *api.VirtualMachineCreateOptions, hardware infrav1.Hardware) {
+	if vmOption.BIOS == "" {
+		vmOption.BIOS = string(infrav1.BIOSOVMF)
 	}
-	if len(extraDisks) > 2 {
-		vmOption.Scsi.Scsi3 = fmt.Sprintf("%s:3,size=%s", extraDisks[2].Storage, extraDisks[2].Size)
+	if vmOption.Machine == "" {
+		vmOption.Machine = "virt"
 	}
-	if len(extraDisks) > 3 {
-		vmOption.Scsi.Scsi4 = fmt.Sprintf("%s:4,size=%s", extraDisks[3].Storage, extraDisks[3].Size)
+	if hardware.CPUType == "" && !hardware.NestedVirtualization {
+		vmOption.Cpu = strings.TrimSuffix("host,"+vmOption.Cpu, ",")
 	}
-	if len(extraDisks) > 4 {
-		vmOption.Scsi.Scsi5 = fmt.Sprintf("%s:5,size=%s", extraDisks[4].Storage, extraDisks[4].Size)
+}
+
+// also catches machines created before the cel rules of aarch64 were added
+func validateArch(arch infrav1.Arch, hardware infrav1.Hardware) error {
+	if arch != infrav1.ArchAarch64 {
+		return nil
 	}
-	return vmOption
+	if hardware.BIOS != "" && hardware.BIOS != infrav1.BIOSOVMF {
+		return fmt.Errorf("hardware.bios: %s can not boot aarch64, use %s", hardware.BIOS, infrav1.BIOSOVMF)
+	}
+	if hardware.Machine != "" && !strings.HasPrefix(hardware.Machine, "virt") {
+		return fmt.Errorf("hardware.machine: %s is not available on aarch64, use virt", hardware.Machine)
+	}
+	if len(hardware.ExtraDisks) >= infrav1.MaxExtraDisks {
+		return fmt.Errorf("hardware.extraDisks: at most %d extra disks are supported on aarch64", infrav1.MaxExtraDisks-1)
+	}
+	return nil
+}
+
+// validate extra disks before creating qemu so that the error names the offending disk
+func validateExtraDisks(disks []infrav1.ExtraDisk) error {
+	if len(disks) > infrav1.MaxExtraDisks {
+		return fmt.Errorf("hardware.extraDisks[%d]: too many extra disks, at most %d are supported", infrav1.MaxExtraDisks, infrav1.MaxExtraDisks)
+	}
+	for i, disk := range disks {
+		if _, err := extraDiskSizeGiB(disk.Size); err != nil {
+			return fmt.Errorf("hardware.extraDisks[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// converts the size of an extra disk into GiB rounding up. like rootDisk, K, M, G and T
+// are binary units, so 100G is 100GiB as well as 100Gi
+func extraDiskSizeGiB(size resource.Quantity) (int, error) {
+	return diskSizeGiB(strings.TrimSuffix(strings.Replace(size.String(), "k", "K", 1), "i"))
+}
+
+// extra disk option for scsiN. proxmox allocates new volume in GiB.
+// storage is used if the disk doesn't specify its own storage.
+// the size is validated by validateExtraDisks.
+func extraDiskOption(disk infrav1.ExtraDisk, storage string) string {
+	if disk.Storage != "" {
+		storage = disk.Storage
+	}
+	size, _ := extraDiskSizeGiB(disk.Size)
+	option := fmt.Sprintf("%s:%d", storage, size)
+	if disk.Format != "" {
+		option += fmt.Sprintf(",format=%s", disk.Format)
+	}
+	return option
 }
@@ -0,0 +1,233 @@
+package instance_test
+
+import (
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("validateExtraDisks", Label("unit", "instance"), func() {
+	It("should accept valid disks", func() {
+		disks := []infrav1.ExtraDisk{
+			{Size: resource.MustParse("10Gi"), Storage: "local-lvm"},
+			{Size: resource.MustParse("100G")},
+		}
+		Expect(instance.ValidateExtraDisks(disks)).To(Succeed())
+	})
+
+	It("should name the disk with invalid size", func() {
+		disks := []infrav1.ExtraDisk{
+			{Size: resource.MustParse("10Gi")},
+			{Size: resource.MustParse("0")},
+		}
+		err := instance.ValidateExtraDisks(disks)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("hardware.extraDisks[1]"))
+	})
+
+	It("should reject negative and fractional byte sizes", func() {
+		Expect(instance.ValidateExtraDisks([]infrav1.ExtraDisk{{Size: resource.MustParse("-10Gi")}})).NotTo(Succeed())
+		Expect(instance.ValidateExtraDisks([]infrav1.ExtraDisk{{Size: resource.MustParse("500m")}})).NotTo(Succeed())
+	})
+
+	It("should reject too many disks", func() {
+		disks := make([]infrav1.ExtraDisk, infrav1.MaxExtraDisks+1)
+		for i := range disks {
+			disks[i].Size = resource.MustParse("1Gi")
+		}
+		err := instance.ValidateExtraDisks(disks)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("hardware.extraDisks[30]"))
+	})
+})
+
+var _ = Describe("extraDiskOption", Label("unit", "instance"), func() {
+	It("should round up size to GiB", func() {
+		disk := infrav1.ExtraDisk{Size: resource.MustParse("100G"), Storage: "ceph", Format: "raw"}
+		Expect(instance.ExtraDiskOption(disk, "local-lvm")).To(Equal("ceph:100,format=raw"))
+		disk = infrav1.ExtraDisk{Size: resource.MustParse("1536Mi")}
+		Expect(instance.ExtraDiskOption(disk, "local-lvm")).To(Equal("local-lvm:2"))
+	})
+
+	It("should size G like Gi as rootDisk does", func() {
+		g := infrav1.ExtraDisk{Size: resource.MustParse("100G")}
+		gi := infrav1.ExtraDisk{Size: resource.MustParse("100Gi")}
+		Expect(instance.ExtraDiskOption(g, "local-lvm")).To(Equal(instance.ExtraDiskOption(gi, "local-lvm")))
+	})
+
+	It("should fall back to the given storage", func() {
+		disk := infrav1.ExtraDisk{Size: resource.MustParse("32Gi")}
+		Expect(instance.ExtraDiskOption(disk, "local-lvm")).To(Equal("local-lvm:32"))
+	})
+})
+
+var _ = Describe("hotplugOption", Label("unit", "instance"), func() {
+	It("should leave proxmox's default if nothing is specified", func() {
+		Expect(instance.HotplugOption(infrav1.Hardware{}, infrav1.Options{})).To(BeEmpty())
+	})
+
+	It("should join specified devices", func() {
+		options := infrav1.Options{HotPlug: []infrav1.HotPlugDevice{infrav1.HotPlugDisk, infrav1.HotPlugCPU}}
+		Expect(instance.HotplugOption(infrav1.Hardware{}, options)).To(Equal("disk,cpu"))
+	})
+
+	It("should append memory if memory hotplug is enabled", func() {
+		hardware := infrav1.Hardware{MemoryHotplug: true}
+		Expect(instance.HotplugOption(hardware, infrav1.Options{})).To(Equal("network,disk,usb,memory"))
+		options := infrav1.Options{HotPlug: []infrav1.HotPlugDevice{infrav1.HotPlugMemory, infrav1.HotPlugCPU}}
+		Expect(instance.HotplugOption(hardware, options)).To(Equal("memory,cpu"))
+	})
+})
+
+var _ = Describe("hotplugMemory", Label("unit", "instance"), func() {
+	It("should hot-plug increased memory", func() {
+		hardware := infrav1.Hardware{Memory: 8192, MemoryHotplug: true}
+		memory, ok := instance.HotplugMemory(hardware, api.VirtualMachineConfig{Memory: 4096})
+		Expect(ok).To(BeTrue())
+		Expect(memory).To(Equal(8192))
+	})
+
+	It("should not change memory without memory hotplug", func() {
+		hardware := infrav1.Hardware{Memory: 8192}
+		_, ok := instance.HotplugMemory(hardware, api.VirtualMachineConfig{Memory: 4096})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should not decrease memory", func() {
+		hardware := infrav1.Hardware{Memory: 4096, MemoryHotplug: true}
+		_, ok := instance.HotplugMemory(hardware, api.VirtualMachineConfig{Memory: 8192})
+		Expect(ok).To(BeFalse())
+		_, ok = instance.HotplugMemory(hardware, api.VirtualMachineConfig{Memory: 4096})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should hot-plug memory up to maxMemory only", func() {
+		hardware := infrav1.Hardware{Memory: 8192, MaxMemory: 8192, MemoryHotplug: true}
+		_, ok := instance.HotplugMemory(hardware, api.VirtualMachineConfig{Memory: 4096})
+		Expect(ok).To(BeTrue())
+		hardware.Memory = 16384
+		_, ok = instance.HotplugMemory(hardware, api.VirtualMachineConfig{Memory: 4096})
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("validateMaxMemory", Label("unit", "instance"), func() {
+	It("should refuse memory exceeding maxMemory", func() {
+		Expect(instance.ValidateMaxMemory(infrav1.Hardware{Memory: 8192})).To(Succeed())
+		Expect(instance.ValidateMaxMemory(infrav1.Hardware{Memory: 8192, MaxMemory: 8192})).To(Succeed())
+		Expect(instance.ValidateMaxMemory(infrav1.Hardware{Memory: 16384, MaxMemory: 8192})).To(MatchError("memory 16384MiB exceeds maxMemory 8192MiB"))
+	})
+})
+
+var _ = Describe("bootOption", Label("unit", "instance"), func() {
+	It("should boot from root disk by default", func() {
+		Expect(instance.BootOption(infrav1.Options{})).To(Equal("order=scsi0"))
+	})
+
+	It("should join boot devices in order", func() {
+		options := infrav1.Options{BootOrder: []infrav1.BootDevice{"net0", "scsi0", "ide2"}}
+		Expect(instance.BootOption(options)).To(Equal("order=net0;scsi0;ide2"))
+	})
+})
+
+var _ = Describe("restoredConfig", Label("unit", "instance"), func() {
+	vmoption := api.VirtualMachineCreateOptions{
+		Name:     "restored",
+		CiCustom: "user=local:snippets/restored-user.yml",
+		Ide:      api.Ide{Ide2: "file=local-lvm:cloudinit,media=cdrom"},
+		IPConfig: api.IPConfig{IPConfig0: "ip=dhcp"},
+	}
+
+	It("should regenerate smbios uuid and attach cloud-init drive", func() {
+		config := instance.RestoredConfig(vmoption, "")
+		Expect(config.Name).To(Equal("restored"))
+		Expect(config.CiCustom).To(Equal(vmoption.CiCustom))
+		Expect(config.IPConfig.IPConfig0).To(Equal("ip=dhcp"))
+		Expect(config.SMBios1).To(HavePrefix("uuid="))
+		Expect(config.Ide.Ide2).To(Equal(vmoption.Ide.Ide2))
+	})
+
+	It("should keep cloud-init drive of the backup", func() {
+		config := instance.RestoredConfig(vmoption, "file=local:vm-100-cloudinit,media=cdrom")
+		Expect(config.Ide.Ide2).To(BeEmpty())
+	})
+
+	It("should attach cloud-init drive to scsi30 for aarch64", func() {
+		option := vmoption
+		option.Arch = api.Aarch64
+		option.Ide = api.Ide{}
+		option.Scsi = api.Scsi{Scsi30: "file=local-lvm:cloudinit,media=cdrom"}
+		config := instance.RestoredConfig(option, "")
+		Expect(config.Scsi.Scsi30).To(Equal(option.Scsi.Scsi30))
+		Expect(config.Ide.Ide2).To(BeEmpty())
+	})
+
+	It("should use smbios option of the machine", func() {
+		option := vmoption
+		option.SMBios1 = "uuid=00000000-0000-0000-0000-000000000001"
+		Expect(instance.RestoredConfig(option, "").SMBios1).To(Equal(option.SMBios1))
+	})
+})
+
+var _ = Describe("setCloudInitDrive", Label("unit", "instance"), func() {
+	It("should attach cloud-init drive to ide2", func() {
+		option := api.VirtualMachineCreateOptions{}
+		instance.SetCloudInitDrive(&option, "local-lvm")
+		Expect(option.Ide.Ide2).To(Equal("file=local-lvm:cloudinit,media=cdrom"))
+		Expect(option.Scsi.Scsi30).To(BeEmpty())
+	})
+
+	It("should attach cloud-init drive to scsi30 for aarch64", func() {
+		option := api.VirtualMachineCreateOptions{Arch: api.Aarch64}
+		instance.SetCloudInitDrive(&option, "local-lvm")
+		Expect(option.Scsi.Scsi30).To(Equal("file=local-lvm:cloudinit,media=cdrom"))
+		Expect(option.Ide.Ide2).To(BeEmpty())
+	})
+})
+
+var _ = Describe("aarch64Defaults", Label("unit", "instance"), func() {
+	It("should default to uefi on virt machine with host cpu", func() {
+		option := api.VirtualMachineCreateOptions{}
+		instance.Aarch64Defaults(&option, infrav1.Hardware{})
+		Expect(option.BIOS).To(Equal("ovmf"))
+		Expect(option.Machine).To(Equal("virt"))
+		Expect(option.Cpu).To(Equal("host"))
+	})
+
+	It("should keep cpu flags", func() {
+		hardware := infrav1.Hardware{CPUFlags: []infrav1.CPUFlag{"+aes"}}
+		option := api.VirtualMachineCreateOptions{Cpu: hardware.CPUOption()}
+		instance.Aarch64Defaults(&option, hardware)
+		Expect(option.Cpu).To(Equal("host," + hardware.CPUOption()))
+	})
+
+	It("should keep options of the machine", func() {
+		hardware := infrav1.Hardware{CPUType: "cortex-a72", Machine: "virt-8.1"}
+		option := api.VirtualMachineCreateOptions{Cpu: hardware.CPUOption(), Machine: hardware.Machine}
+		instance.Aarch64Defaults(&option, hardware)
+		Expect(option.Cpu).To(Equal(hardware.CPUOption()))
+		Expect(option.Machine).To(Equal("virt-8.1"))
+	})
+})
+
+var _ = Describe("validateArch", Label("unit", "instance"), func() {
+	It("should not validate x86_64", func() {
+		Expect(instance.ValidateArch(infrav1.ArchX86_64, infrav1.Hardware{BIOS: infrav1.BIOSSeaBIOS, Machine: "q35"})).To(Succeed())
+	})
+
+	It("should reject seabios and non virt machine on aarch64", func() {
+		Expect(instance.ValidateArch(infrav1.ArchAarch64, infrav1.Hardware{BIOS: infrav1.BIOSSeaBIOS})).NotTo(Succeed())
+		Expect(instance.ValidateArch(infrav1.ArchAarch64, infrav1.Hardware{Machine: "q35"})).NotTo(Succeed())
+		Expect(instance.ValidateArch(infrav1.ArchAarch64, infrav1.Hardware{BIOS: infrav1.BIOSOVMF, Machine: "virt"})).To(Succeed())
+	})
+
+	It("should reserve scsi30 on aarch64", func() {
+		disks := make([]infrav1.ExtraDisk, infrav1.MaxExtraDisks)
+		Expect(instance.ValidateArch(infrav1.ArchAarch64, infrav1.Hardware{ExtraDisks: disks})).NotTo(Succeed())
+		Expect(instance.ValidateArch(infrav1.ArchAarch64, infrav1.Hardware{ExtraDisks: disks[1:]})).To(Succeed())
+	})
+})
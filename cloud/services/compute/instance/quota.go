@@ -0,0 +1,98 @@
+package instance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// Resources are what a machine takes from the quota of its cluster
+type Resources struct {
+	VMs    int
+	CPU    int
+	Memory int
+	Disk   int
+}
+
+// Demand returns the resources of the machine counted against the quota
+func Demand(spec infrav1.ProxmoxMachineSpec) (Resources, error) {
+	hardware := spec.Hardware
+	sockets := hardware.Sockets
+	if sockets == 0 {
+		sockets = 1
+	}
+	disk := 0
+	if hardware.RootDisk != "" {
+		size, err := diskSizeGiB(hardware.RootDisk)
+		if err != nil {
+			return Resources{}, err
+		}
+		disk = size
+	}
+	for _, d := range hardware.ExtraDisks {
+		size, err := extraDiskSizeGiB(d.Size)
+		if err != nil {
+			return Resources{}, err
+		}
+		disk += size
+	}
+	return Resources{VMs: 1, CPU: hardware.CPU * sockets, Memory: hardware.Memory, Disk: disk}, nil
+}
+
+func (r Resources) add(o Resources) Resources {
+	return Resources{VMs: r.VMs + o.VMs, CPU: r.CPU + o.CPU, Memory: r.Memory + o.Memory, Disk: r.Disk + o.Disk}
+}
+
+// returns the limits of the quota the resources exceed. e.g. "cpu 34/32"
+func (r Resources) exceeded(quota infrav1.ResourceQuota) []string {
+	var exceeded []string
+	check := func(name string, used, limit int) {
+		if limit != 0 && used > limit {
+			exceeded = append(exceeded, fmt.Sprintf("%s %d/%d", name, used, limit))
+		}
+	}
+	check("vms", r.VMs, quota.MaxVMs)
+	check("cpu", r.CPU, quota.CPU)
+	check("memory", r.Memory, quota.Memory)
+	check("disk", r.Disk, quota.Disk)
+	return exceeded
+}
+
+// Admit returns whether the machine of the name fits in the quota together with the other machines
+// of the cluster, and the exceeded limits otherwise. machines having instances are counted first,
+// then the others in creation order, so that machines beyond the quota are admitted first come first served
+func Admit(quota infrav1.ResourceQuota, machines []infrav1.ProxmoxMachine, name string) (bool, string, error) {
+	ordered := append([]infrav1.ProxmoxMachine{}, machines...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if hasInstance(a) != hasInstance(b) {
+			return hasInstance(a)
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Name < b.Name
+	})
+	used := Resources{}
+	for _, m := range ordered {
+		demand, err := Demand(m.Spec)
+		if err != nil {
+			return false, "", err
+		}
+		used = used.add(demand)
+		if m.Name != name {
+			continue
+		}
+		if exceeded := used.exceeded(quota); len(exceeded) != 0 {
+			return false, fmt.Sprintf("quota of the cluster exceeded: %s", strings.Join(exceeded, ", ")), nil
+		}
+		return true, "", nil
+	}
+	return false, "", fmt.Errorf("machine %s is not found", name)
+}
+
+func hasInstance(m infrav1.ProxmoxMachine) bool {
+	return m.Spec.ProviderID != nil || m.Status.InstanceStatus != nil
+}
@@ -0,0 +1,63 @@
+package instance_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("Demand", Label("unit", "instance"), func() {
+	It("should count vcpus, memory and disks", func() {
+		spec := infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{
+			CPU: 2, Sockets: 2, Memory: 4096, RootDisk: "50G",
+			ExtraDisks: []infrav1.ExtraDisk{{Size: resource.MustParse("100Gi")}},
+		}}
+		Expect(instance.Demand(spec)).To(Equal(instance.Resources{VMs: 1, CPU: 4, Memory: 4096, Disk: 150}))
+	})
+})
+
+var _ = Describe("Admit", Label("unit", "instance"), func() {
+	now := time.Now()
+	machine := func(name string, created time.Duration, running bool) infrav1.ProxmoxMachine {
+		m := infrav1.ProxmoxMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(created))},
+			Spec:       infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{CPU: 4, Memory: 8192, RootDisk: "50G"}},
+		}
+		if running {
+			status := infrav1.InstanceStatusRunning
+			m.Status.InstanceStatus = &status
+		}
+		return m
+	}
+	// a machine created later but having an instance counts first
+	machines := []infrav1.ProxmoxMachine{
+		machine("pending-1", time.Minute, false),
+		machine("pending-2", 2*time.Minute, false),
+		machine("running", 3*time.Minute, true),
+	}
+
+	It("should admit machines within the quota in creation order", func() {
+		quota := infrav1.ResourceQuota{CPU: 8}
+		admitted, _, err := instance.Admit(quota, machines, "pending-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(admitted).To(BeTrue())
+
+		admitted, message, err := instance.Admit(quota, machines, "pending-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(admitted).To(BeFalse())
+		Expect(message).To(Equal("quota of the cluster exceeded: cpu 12/8"))
+	})
+
+	It("should report every exceeded limit", func() {
+		quota := infrav1.ResourceQuota{MaxVMs: 1, Memory: 8192, Disk: 1000}
+		_, message, err := instance.Admit(quota, machines, "pending-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message).To(Equal("quota of the cluster exceeded: vms 2/1, memory 16384/8192"))
+	})
+})
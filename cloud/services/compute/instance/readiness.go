@@ -0,0 +1,123 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+const readinessTimeout = 3 * time.Second
+
+// ErrGuestNotReady is returned while the readiness check of the machine fails
+var ErrGuestNotReady = errors.New("guest is not ready")
+
+// subset of GET /nodes/{node}/qemu/{vmid}/agent/network-get-interfaces
+type agentInterfaces struct {
+	Result []struct {
+		Name            string `json:"name"`
+		HardwareAddress string `json:"hardware-address"`
+		IPAddresses     []struct {
+			IPAddress string `json:"ip-address"`
+		} `json:"ip-addresses"`
+	} `json:"result"`
+}
+
+// a running guest is not necessarily ready. e.g. windows reboots after
+// cloudbase-init renamed it, so the machine may wait for a port of the guest.
+// ready machines are not checked anymore
+func (s *Service) reconcileReadiness(ctx context.Context, guest Guest) error {
+	if guest.Status() != infrav1.InstanceStatusRunning || s.scope.IsReady() {
+		return nil
+	}
+	if s.waitsForAgent() {
+		if err := s.pingAgent(ctx, guest); err != nil {
+			return fmt.Errorf("%w: qemu guest agent does not answer: %v", ErrGuestNotReady, err)
+		}
+	}
+	readiness := s.scope.GetReadiness()
+	if readiness == nil {
+		return nil
+	}
+	address, err := s.guestAddress(ctx, guest)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGuestNotReady, err)
+	}
+	endpoint := net.JoinHostPort(address, strconv.Itoa(readiness.Port))
+	dialer := net.Dialer{Timeout: readinessTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGuestNotReady, err)
+	}
+	log.FromContext(ctx).Info("guest is ready", "endpoint", endpoint)
+	return conn.Close()
+}
+
+// the guest agent is waited for only while the guest ready phase is limited
+func (s *Service) waitsForAgent() bool {
+	return s.inPhase(infrav1.ProvisioningPhaseGuestReady) &&
+		s.scope.GetProvisioningTimeouts().GuestReady != nil &&
+		s.scope.GetType() == infrav1.InstanceTypeQEMU &&
+		s.scope.GetOptions().Agent.IsEnabled()
+}
+
+func (s *Service) pingAgent(ctx context.Context, guest Guest) error {
+	return s.client.RESTClient().Post(ctx, fmt.Sprintf("/nodes/%s/qemu/%d/agent/ping", guest.Node(), guest.VMID()), nil, nil)
+}
+
+// returns the static ip of the machine or the one reported by the qemu guest agent
+func (s *Service) guestAddress(ctx context.Context, guest Guest) (string, error) {
+	if ip := staticIP(s.scope.GetNetwork().IPConfig); ip != "" {
+		return ip, nil
+	}
+	if s.scope.GetType() != infrav1.InstanceTypeQEMU || !s.scope.GetOptions().Agent.IsEnabled() {
+		return "", errors.New("address is unknown without static ip or qemu guest agent")
+	}
+	filter, err := newAddressFilter(s.scope.GetAddressFilter())
+	if err != nil {
+		return "", err
+	}
+	interfaces, err := (&qemuBackend{s}).Interfaces(ctx, guest)
+	if err != nil {
+		return "", err
+	}
+	if ip := interfaceAddress(filter.interfaces(interfaces)); ip != "" {
+		return ip, nil
+	}
+	return "", errors.New("qemu guest agent reports no address")
+}
+
+func staticIP(config infrav1.IPConfig) string {
+	for _, cidr := range []string{config.IP, config.IP6} {
+		if ip, _, err := net.ParseCIDR(cidr); err == nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+// returns the first address preferring ipv4. the interfaces are filtered and the primary one comes first
+func interfaceAddress(interfaces []infrav1.NetworkInterface) string {
+	var ip6 string
+	for _, iface := range interfaces {
+		for _, address := range iface.IPAddresses {
+			ip := net.ParseIP(address)
+			if ip == nil {
+				continue
+			}
+			if ip.To4() != nil {
+				return ip.String()
+			}
+			if ip6 == "" {
+				ip6 = ip.String()
+			}
+		}
+	}
+	return ip6
+}
@@ -0,0 +1,48 @@
+package instance_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("staticIP", Label("unit", "instance"), func() {
+	It("should strip prefix length", func() {
+		Expect(instance.StaticIP(infrav1.IPConfig{IP: "10.0.0.10/24"})).To(Equal("10.0.0.10"))
+		Expect(instance.StaticIP(infrav1.IPConfig{IP: "dhcp", IP6: "fd00::10/64"})).To(Equal("fd00::10"))
+	})
+
+	It("should be empty for dhcp", func() {
+		Expect(instance.StaticIP(infrav1.IPConfig{})).To(BeEmpty())
+		Expect(instance.StaticIP(infrav1.IPConfig{IP: "dhcp"})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("agentAddress", Label("unit", "instance"), func() {
+	parse := func(s string) instance.AgentInterfaces {
+		var interfaces instance.AgentInterfaces
+		Expect(json.Unmarshal([]byte(s), &interfaces)).To(Succeed())
+		return interfaces
+	}
+
+	It("should prefer global ipv4", func() {
+		interfaces := parse(`{"result":[
+			{"name":"Loopback Pseudo-Interface 1","ip-addresses":[{"ip-address":"127.0.0.1"},{"ip-address":"::1"}]},
+			{"name":"Ethernet","ip-addresses":[{"ip-address":"fe80::1"},{"ip-address":"fd00::10"},{"ip-address":"169.254.1.1"},{"ip-address":"10.0.0.10"}]}
+		]}`)
+		Expect(instance.AgentAddress(interfaces)).To(Equal("10.0.0.10"))
+	})
+
+	It("should fall back to global ipv6", func() {
+		interfaces := parse(`{"result":[{"name":"Ethernet","ip-addresses":[{"ip-address":"fe80::1"},{"ip-address":"fd00::10"}]}]}`)
+		Expect(instance.AgentAddress(interfaces)).To(Equal("fd00::10"))
+	})
+
+	It("should be empty without addresses", func() {
+		Expect(instance.AgentAddress(parse(`{"result":[]}`))).To(BeEmpty())
+	})
+})
@@ -3,6 +3,8 @@ package instance
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/k8s-proxmox/proxmox-go/api"
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
@@ -12,82 +14,154 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/providerid"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/retry"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/logging"
 )
 
 const (
 	etcCAPPX = "/etc/cappx"
 )
 
+// ErrNodeDown is returned when the vm is left on a proxmox node which is down permanently
+var ErrNodeDown = errors.New("proxmox node is down")
+
+// ErrGuestUnreachable is returned when the guest is on a proxmox node which can not be reached
+var ErrGuestUnreachable = guest.ErrUnreachable
+
 // reconcile normal
 func (s *Service) Reconcile(ctx context.Context) error {
+	ctx = logging.IntoContext(ctx, logging.Instance)
 	log := log.FromContext(ctx)
 	log.Info("Reconciling instance")
-	instance, err := s.createOrGetInstance(ctx)
+	backend, err := s.backend()
+	if err != nil {
+		return err
+	}
+	instance, err := s.createOrGetInstance(ctx, backend)
 	if err != nil {
 		log.Error(err, "failed to create/get instance")
 		return err
 	}
 
-	uuid, err := getBiosUUIDFromVM(ctx, instance)
+	uuid, err := instance.UUID(ctx)
 	if err != nil {
 		return err
 	}
 
 	log.Info("updating instance status")
-	if err := s.scope.SetProviderID(*uuid); err != nil {
+	if err := s.scope.SetProviderID(uuid, instance.VMID()); err != nil {
 		return err
 	}
-	s.scope.SetInstanceStatus(infrav1.InstanceStatus(instance.VM.Status))
-	s.scope.SetNodeName(instance.Node)
-	s.scope.SetVMID(instance.VM.VMID)
+	s.scope.SetInstanceStatus(instance.Status())
+	s.scope.SetNodeName(instance.Node())
+	s.scope.SetVMID(instance.VMID())
+	s.scope.SetConsole(console(s.scope.GetServerEndpoint(), s.scope.Name(), instance.Node(), instance.VMID(), s.scope.GetType(), s.scope.GetOptions()))
 
 	log.Info("updating instance config status")
-	config, err := instance.GetConfig(ctx)
-	if err != nil {
+	if err := backend.Update(ctx, instance); err != nil {
 		return err
 	}
-	s.scope.SetConfigStatus(*config)
+	if err := s.reconcileAddresses(ctx, backend, instance); err != nil {
+		return err
+	}
+	if err := s.reconcileReadiness(ctx, instance); err != nil {
+		return err
+	}
+	s.reconcilePhase(instance)
+	// the machine becomes ready once its instance is running
+	if !s.scope.IsReady() && instance.Status() == infrav1.InstanceStatusRunning {
+		s.observeLifecycle(readyDuration)
+	}
 	return nil
 }
 
 // reconcile delete
 func (s *Service) Delete(ctx context.Context) error {
+	ctx = logging.IntoContext(ctx, logging.Instance)
 	log := log.FromContext(ctx)
 	log.Info("Deleting instance resources")
+	// released first, so that nothing is left for the name even if the deletion fails
+	s.scheduler.Release(s.scope.Name())
+	backend, err := s.backend()
+	if err != nil {
+		return err
+	}
+
+	if s.scope.NodeDown() {
+		return s.deleteOnDownNode(ctx, backend)
+	}
 
-	log.Info("trying to get qemu")
-	instance, err := s.getQEMU(ctx)
+	// the vmid may already be used by another guest. the remaining steps do not look for the instance
+	if s.scope.DeletionStepDone(infrav1.InstanceDeletedCondition) {
+		log.Info("instance is already deleted")
+		return backend.Delete(ctx, nil)
+	}
+
+	log.Info("trying to get instance from vmid")
+	var instance Guest
+	err = retry.OnServerError(ctx, func() (err error) {
+		instance, err = backend.GetByVMID(ctx)
+		return err
+	})
 	if err != nil {
 		if !rest.IsNotFound(err) {
 			return err
 		}
-		log.Info("qemu is not found or already deleted")
-		return nil
+		// the remaining steps clean up what the instance has left
+		log.Info("instance is not found or already deleted")
+		return backend.Delete(ctx, nil)
 	}
-
-	// must stop or pause instance before deletion
-	// otherwise deletion will be fail
-	if err := ensureStoppedOrPaused(ctx, *instance); err != nil {
+	if err := s.verifyOwnership(ctx, instance); err != nil {
 		return err
 	}
+	return backend.Delete(ctx, instance)
+}
 
-	// delete cloud-config file
-	if err := s.deleteCloudConfig(ctx); err != nil {
+// the down node can not be reached. the instance is deleted only if the ha manager
+// has recovered it on another node, otherwise it is left on the down node
+func (s *Service) deleteOnDownNode(ctx context.Context, backend Backend) error {
+	log := log.FromContext(ctx)
+	instance, err := backend.Get(ctx)
+	if err == nil {
+		if err := s.verifyOwnership(ctx, instance); err != nil {
+			return err
+		}
+		return backend.Delete(ctx, instance)
+	}
+	if !rest.IsNotFound(err) && !errors.Is(err, ErrGuestUnreachable) {
 		return err
 	}
-
-	// delete qemu
-	return instance.Delete(ctx)
+	log.Info("instance is left on the down node, skipping its deletion", "node", s.scope.NodeName())
+	vmid := s.scope.GetVMID()
+	if vmid == nil {
+		return nil
+	}
+	// cluster-wide resources of the vmid are released since the instance will not come back
+	return s.runDeletionSteps(ctx, []deletionStep{
+		{infrav1.HAResourceDeletedCondition, func() error { return s.deleteHA(ctx, *vmid) }},
+		{infrav1.ReplicationDeletedCondition, func() error { return s.deleteReplication(ctx, *vmid) }},
+	})
 }
 
-func (s *Service) createOrGetInstance(ctx context.Context) (*proxmox.VirtualMachine, error) {
+func (s *Service) createOrGetInstance(ctx context.Context, backend Backend) (Guest, error) {
 	log := log.FromContext(ctx)
 
-	instance, err := s.getInstance(ctx)
+	var instance Guest
+	err := retry.OnServerError(ctx, func() (err error) {
+		instance, err = backend.Get(ctx)
+		return err
+	})
 	if err != nil {
+		// the ha manager did not recover the vm on another node
+		if s.scope.NodeDown() && (rest.IsNotFound(err) || errors.Is(err, ErrGuestUnreachable)) {
+			return nil, ErrNodeDown
+		}
 		if rest.IsNotFound(err) {
 			log.Info("instance wasn't found. new instance will be created")
-			return s.createInstance(ctx)
+			return s.createInstance(ctx, backend)
 		}
 		log.Error(err, "failed to get instance")
 		return nil, err
@@ -96,17 +170,88 @@ func (s *Service) createOrGetInstance(ctx context.Context) (*proxmox.VirtualMach
 	return instance, nil
 }
 
-// getInstance() gets proxmoxm vm from providerID
+// creates the instance, delivers bootstrap data to it and then starts it
+func (s *Service) createInstance(ctx context.Context, backend Backend) (Guest, error) {
+	log := log.FromContext(ctx)
+
+	// unsupported versions would fail with obscure parameter errors
+	if _, err := s.proxmoxVersion(); err != nil {
+		return nil, err
+	}
+	instance, err := backend.Create(ctx)
+	if err != nil {
+		s.handleCreateFailure(err)
+		// the next attempt starts over instead of adopting the broken guest
+		if discardsPartialGuest(err) {
+			if err := s.deletePartialGuest(ctx); err != nil {
+				log.Error(err, "failed to delete partially created instance")
+			}
+		}
+		return nil, err
+	}
+	s.scope.ClearFailedNodes()
+	log.Info(fmt.Sprintf("reconciled instance: type=%s,node=%s,vmid=%d", s.scope.GetType(), instance.Node(), instance.VMID()))
+	s.observeLifecycle(createdDuration)
+
+	if err := backend.DeliverBootstrap(ctx, instance); err != nil {
+		return nil, err
+	}
+
+	if err := backend.Start(ctx, instance); err != nil {
+		return nil, err
+	}
+	s.enterPhase(infrav1.ProvisioningPhaseGuestReady)
+	return instance, nil
+}
+
+// getInstance() gets proxmoxm vm from providerID.
+// the vm is looked up by its vmid if known, since looking it up by uuid reads the config of every vm
 func (s *Service) getInstance(ctx context.Context) (*proxmox.VirtualMachine, error) {
 	log := log.FromContext(ctx)
 
-	biosUUID := s.scope.GetBiosUUID()
-	if biosUUID == nil {
+	if s.scope.GetProviderID() == "" {
 		log.Info("instance does not have providerID yet")
 		return nil, rest.NotFoundErr
 	}
+	id, err := providerid.Parse(s.scope.GetProviderID())
+	if err != nil {
+		return nil, err
+	}
 
-	vm, err := s.client.VirtualMachineFromUUID(ctx, *biosUUID)
+	vmid := s.scope.GetVMID()
+	if id.VMID() != 0 {
+		vmid = ptr.To(id.VMID())
+	}
+	if vmid != nil {
+		vm, err := virtualMachine(ctx, &s.client, *vmid)
+		if err != nil {
+			if rest.IsNotFound(err) {
+				log.Info("instance wasn't found")
+				return nil, rest.NotFoundErr
+			}
+			log.Error(err, "failed to get instance from vmid")
+			return nil, err
+		}
+		// ids of the region/vmid format pin no uuid, so the guest having the vmid must be tagged with
+		// the machine. another one is never taken for it, since the provider id would keep pointing to it
+		if id.UUID() == "" {
+			if err := s.verifyOwnership(ctx, &qemuGuest{vm}); err != nil {
+				return nil, err
+			}
+			return vm, nil
+		}
+		uuid, err := getBiosUUIDFromVM(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(*uuid, id.UUID()) {
+			log.Info("vmid is used by another instance", "vmid", *vmid)
+			return nil, rest.NotFoundErr
+		}
+		return vm, nil
+	}
+
+	vm, err := s.client.VirtualMachineFromUUID(ctx, id.UUID())
 	if err != nil {
 		if rest.IsNotFound(err) {
 			log.Info("instance wasn't found")
@@ -119,64 +264,52 @@ func (s *Service) getInstance(ctx context.Context) (*proxmox.VirtualMachine, err
 	return vm, nil
 }
 
-func getBiosUUIDFromVM(ctx context.Context, vm *proxmox.VirtualMachine) (*string, error) {
-	log := log.FromContext(ctx)
-	config, err := vm.GetConfig(ctx)
+// virtualMachine returns the qemu having the vmid. rest.NotFoundErr is returned if no qemu has the vmid
+func virtualMachine(ctx context.Context, client *proxmox.Service, vmid int) (*proxmox.VirtualMachine, error) {
+	guests, err := guest.List(ctx, client)
 	if err != nil {
-		log.Error(err, "failed to get vm config")
 		return nil, err
 	}
-	smbios := config.SMBios1
-	uuid, err := proxmox.ConvertSMBiosToUUID(smbios)
+	g, err := guest.Find(guests, vmid)
 	if err != nil {
-		log.Error(err, "failed to convert SMBios to UUID")
 		return nil, err
 	}
-	return ptr.To(uuid), nil
+	return g.QEMU(ctx, client)
 }
 
-// reconciles qemu, cloud-config, os image and storage
-func (s *Service) createInstance(ctx context.Context) (*proxmox.VirtualMachine, error) {
+func getBiosUUIDFromVM(ctx context.Context, vm *proxmox.VirtualMachine) (*string, error) {
 	log := log.FromContext(ctx)
-
-	// qemu
-	instance, err := s.reconcileQEMU(ctx)
+	config, err := vm.GetConfig(ctx)
 	if err != nil {
+		log.Error(err, "failed to get vm config")
 		return nil, err
 	}
-	vmid := instance.VM.VMID
-	log.Info(fmt.Sprintf("reconciled qemu: node=%s,vmid=%d", instance.Node, vmid))
-
-	// cloud init
-	if err := s.reconcileCloudInit(ctx); err != nil {
-		return nil, err
-	}
-
-	// set cloud image to hard disk and then resize
-	if err := s.reconcileBootDevice(ctx, instance); err != nil {
-		return nil, err
-	}
-
-	// vm status
-	if err := ensureRunning(ctx, *instance); err != nil {
+	smbios := config.SMBios1
+	uuid, err := proxmox.ConvertSMBiosToUUID(smbios)
+	if err != nil {
+		log.Error(err, "failed to convert SMBios to UUID")
 		return nil, err
 	}
-	return instance, nil
+	return ptr.To(uuid), nil
 }
 
-func ensureRunning(ctx context.Context, instance proxmox.VirtualMachine) error {
+func (s *Service) ensureRunning(ctx context.Context, instance *proxmox.VirtualMachine) error {
 	log := log.FromContext(ctx)
 	log.Info("ensuring qemu is running")
 	switch instance.VM.Status {
 	case api.ProcessStatusRunning:
 		return nil
 	case api.ProcessStatusStopped:
-		if err := instance.Start(ctx, api.VirtualMachineStartOption{}); err != nil {
+		if err := audit.Do(ctx, &s.client, http.MethodPost, qemuPath(instance)+"/status/start", func() error {
+			return instance.Start(ctx, api.VirtualMachineStartOption{})
+		}); err != nil {
 			log.Error(err, "failed to start instance process")
 			return err
 		}
 	case api.ProcessStatusPaused:
-		if err := instance.Resume(ctx, api.VirtualMachineResumeOption{}); err != nil {
+		if err := audit.Do(ctx, &s.client, http.MethodPost, qemuPath(instance)+"/status/resume", func() error {
+			return instance.Resume(ctx, api.VirtualMachineResumeOption{})
+		}); err != nil {
 			log.Error(err, "failed to resume instance process")
 			return err
 		}
@@ -186,12 +319,14 @@ func ensureRunning(ctx context.Context, instance proxmox.VirtualMachine) error {
 	return nil
 }
 
-func ensureStoppedOrPaused(ctx context.Context, instance proxmox.VirtualMachine) error {
+func (s *Service) ensureStoppedOrPaused(ctx context.Context, instance *proxmox.VirtualMachine) error {
 	log := log.FromContext(ctx)
 	log.Info("ensuring qemus is stopped or paused")
 	switch instance.VM.Status {
 	case api.ProcessStatusRunning:
-		if err := instance.Stop(ctx, api.VirtualMachineStopOption{}); err != nil {
+		if err := audit.Do(ctx, &s.client, http.MethodPost, qemuPath(instance)+"/status/stop", func() error {
+			return instance.Stop(ctx, api.VirtualMachineStopOption{})
+		}); err != nil {
 			log.Error(err, "failed to stop instance process")
 			return err
 		}
@@ -0,0 +1,77 @@
+package instance_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("virtualMachine", Label("unit", "instance"), func() {
+	var (
+		server *httptest.Server
+		client *proxmox.Service
+	)
+
+	// pve1 is down and answers 595 like the proxy of the api does for unreachable nodes
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api2/json/cluster/resources":
+				_, _ = w.Write([]byte(`{"data":[
+					{"type":"qemu","node":"pve1","vmid":100,"name":"left","status":"unknown"},
+					{"type":"qemu","node":"pve2","vmid":101,"name":"recovered","status":"running"},
+					{"type":"lxc","node":"pve2","vmid":102,"name":"container","status":"running"}
+				]}`))
+			case "/api2/json/nodes":
+				_, _ = w.Write([]byte(`{"data":[{"node":"pve1","status":"offline"},{"node":"pve2","status":"online"}]}`))
+			case "/api2/json/nodes/pve2/qemu":
+				_, _ = w.Write([]byte(`{"data":[{"vmid":101,"name":"recovered","status":"running"}]}`))
+			case "/api2/json/nodes/pve2/qemu/101/config":
+				_, _ = w.Write([]byte(`{"data":{"smbios1":"uuid=0a1b2c3d-0000-4000-8000-000000000101"}}`))
+			case "/api2/json/nodes/pve1/qemu":
+				w.WriteHeader(595)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		params := proxmox.NewParams(server.URL+"/api2/json", proxmox.AuthConfig{TokenID: "cappx@pve!token", Secret: "secret"}, proxmox.ClientConfig{})
+		var err error
+		client, err = proxmox.GetOrCreateService(params)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should fail with 595 when every node is asked", func() {
+		_, err := client.VirtualMachine(context.TODO(), 101)
+		Expect(err).To(MatchError(ContainSubstring("595")))
+	})
+
+	It("should find the qemu on a reachable node despite the unreachable one", func() {
+		vm, err := instance.VirtualMachine(context.TODO(), client, 101)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vm.Node).To(Equal("pve2"))
+		Expect(vm.VM.VMID).To(Equal(101))
+	})
+
+	It("should tell the qemu on the unreachable node", func() {
+		_, err := instance.VirtualMachine(context.TODO(), client, 100)
+		Expect(err).To(MatchError(instance.ErrGuestUnreachable))
+	})
+
+	It("should not find vmids of other guests or no guest", func() {
+		_, err := instance.VirtualMachine(context.TODO(), client, 102)
+		Expect(rest.IsNotFound(err)).To(BeTrue())
+		_, err = instance.VirtualMachine(context.TODO(), client, 103)
+		Expect(rest.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,152 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+const (
+	replicationJobsPath        = "/cluster/replication"
+	defaultReplicationSchedule = "*/15"
+)
+
+// storage replication job of proxmox
+type replicationJob struct {
+	ID       string   `json:"id"`
+	Target   string   `json:"target"`
+	Schedule string   `json:"schedule,omitempty"`
+	Rate     *float64 `json:"rate,omitempty"`
+	// set while the job is being removed
+	RemoveJob string `json:"remove_job,omitempty"`
+}
+
+// creates or updates the replication job of the vm
+func (s *Service) reconcileReplication(ctx context.Context, vm *proxmox.VirtualMachine) error {
+	log := log.FromContext(ctx)
+	replication := s.scope.GetReplication()
+	if replication == nil {
+		return nil
+	}
+	id := replicationJobID(vm.VM.VMID)
+	current, err := s.getReplicationJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	request := replicationRequest(*replication)
+	if current == nil {
+		if vm.Node == replication.Target {
+			log.Info("qemu is running on the replication target. replication job is not created", "target", replication.Target)
+			return nil
+		}
+		log.Info("creating replication job", "id", id, "target", replication.Target)
+		request["id"] = id
+		request["type"] = "local"
+		request["target"] = replication.Target
+		if err := audit.REST(&s.client).Post(ctx, replicationJobsPath, request, nil); err != nil {
+			return fmt.Errorf("failed to create replication job %s: %w", id, err)
+		}
+		return nil
+	}
+	if current.RemoveJob != "" {
+		log.Info("replication job is being removed", "id", id)
+		return nil
+	}
+	// proxmox swaps source and target of the job when the vm is migrated to the target,
+	// e.g. after recovery by ha manager. the target is left as it is in that case.
+	// otherwise the target is immutable, but can change when replication is removed and added again.
+	// proxmox can not move a job to another target, so the job is removed and created again
+	if replicationRetargeted(*current, *replication, vm.Node) {
+		log.Info("removing replication job of another target", "id", id, "target", current.Target)
+		return s.deleteReplication(ctx, vm.VM.VMID)
+	}
+	if replicationUpToDate(*current, *replication) {
+		return nil
+	}
+	log.Info("updating replication job", "id", id)
+	if replication.Rate == nil {
+		request["delete"] = "rate"
+	}
+	if err := audit.REST(&s.client).Put(ctx, replicationJobPath(id), request, nil); err != nil {
+		return fmt.Errorf("failed to update replication job %s: %w", id, err)
+	}
+	return nil
+}
+
+// deletes the replication job of the vm together with the replicated disks on the target
+func (s *Service) deleteReplication(ctx context.Context, vmid int) error {
+	log := log.FromContext(ctx)
+	id := replicationJobID(vmid)
+	current, err := s.getReplicationJob(ctx, id)
+	if err != nil || current == nil {
+		return err
+	}
+	log.Info("deleting replication job", "id", id)
+	if err := audit.REST(&s.client).Delete(ctx, replicationJobPath(id), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete replication job %s: %w", id, err)
+	}
+	return nil
+}
+
+// returns nil if the job does not exist
+func (s *Service) getReplicationJob(ctx context.Context, id string) (*replicationJob, error) {
+	var jobs []replicationJob
+	if err := s.client.RESTClient().Get(ctx, replicationJobsPath, &jobs); err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		if j.ID == id {
+			return &j, nil
+		}
+	}
+	return nil, nil
+}
+
+// cappx manages the first job of the vm
+func replicationJobID(vmid int) string {
+	return fmt.Sprintf("%d-0", vmid)
+}
+
+func replicationJobPath(id string) string {
+	return fmt.Sprintf("%s/%s", replicationJobsPath, url.PathEscape(id))
+}
+
+func replicationSchedule(replication infrav1.Replication) string {
+	if replication.Schedule == "" {
+		return defaultReplicationSchedule
+	}
+	return replication.Schedule
+}
+
+func replicationRequest(replication infrav1.Replication) map[string]interface{} {
+	request := map[string]interface{}{
+		"schedule": replicationSchedule(replication),
+		"comment":  "managed by cappx",
+	}
+	if replication.Rate != nil {
+		request["rate"] = *replication.Rate
+	}
+	return request
+}
+
+func replicationUpToDate(current replicationJob, replication infrav1.Replication) bool {
+	if current.Schedule != replicationSchedule(replication) {
+		return false
+	}
+	if replication.Rate == nil {
+		return current.Rate == nil
+	}
+	return current.Rate != nil && *current.Rate == float64(*replication.Rate)
+}
+
+// returns true if the job replicates to another node than the target, unless the vm runs on the target
+func replicationRetargeted(current replicationJob, replication infrav1.Replication, node string) bool {
+	return current.Target != replication.Target && node != replication.Target
+}
@@ -0,0 +1,55 @@
+package instance_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("replicationRequest", Label("unit", "instance"), func() {
+	It("should default schedule", func() {
+		request := instance.ReplicationRequest(infrav1.Replication{Target: "node2"})
+		Expect(request).To(HaveKeyWithValue("schedule", "*/15"))
+		Expect(request).NotTo(HaveKey("rate"))
+	})
+
+	It("should render rate", func() {
+		request := instance.ReplicationRequest(infrav1.Replication{Target: "node2", Schedule: "*/5", Rate: ptr.To(100)})
+		Expect(request).To(HaveKeyWithValue("schedule", "*/5"))
+		Expect(request).To(HaveKeyWithValue("rate", 100))
+	})
+})
+
+var _ = Describe("replicationUpToDate", Label("unit", "instance"), func() {
+	It("should ignore target swapped by migration", func() {
+		current := instance.ReplicationJob{ID: "100-0", Target: "node1", Schedule: "*/15"}
+		Expect(instance.ReplicationUpToDate(current, infrav1.Replication{Target: "node2"})).To(BeTrue())
+	})
+
+	It("should detect changes of schedule and rate", func() {
+		current := instance.ReplicationJob{ID: "100-0", Target: "node2", Schedule: "*/15", Rate: ptr.To(100.0)}
+		Expect(instance.ReplicationUpToDate(current, infrav1.Replication{Target: "node2", Rate: ptr.To(100)})).To(BeTrue())
+		Expect(instance.ReplicationUpToDate(current, infrav1.Replication{Target: "node2"})).To(BeFalse())
+		Expect(instance.ReplicationUpToDate(current, infrav1.Replication{Target: "node2", Schedule: "*/5", Rate: ptr.To(100)})).To(BeFalse())
+	})
+})
+
+var _ = Describe("replicationRetargeted", Label("unit", "instance"), func() {
+	It("should keep the job of the target", func() {
+		current := instance.ReplicationJob{ID: "100-0", Target: "node2"}
+		Expect(instance.ReplicationRetargeted(current, infrav1.Replication{Target: "node2"}, "node1")).To(BeFalse())
+	})
+
+	It("should keep the job swapped by a migration to the target", func() {
+		current := instance.ReplicationJob{ID: "100-0", Target: "node1"}
+		Expect(instance.ReplicationRetargeted(current, infrav1.Replication{Target: "node2"}, "node2")).To(BeFalse())
+	})
+
+	It("should replace the job of another target", func() {
+		current := instance.ReplicationJob{ID: "100-0", Target: "node3"}
+		Expect(instance.ReplicationRetargeted(current, infrav1.Replication{Target: "node2"}, "node1")).To(BeTrue())
+	})
+})
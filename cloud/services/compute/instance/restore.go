@@ -0,0 +1,87 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+// request of POST /nodes/{node}/qemu restoring a backup
+type restoreRequest struct {
+	VMID    int    `json:"vmid"`
+	Archive string `json:"archive"`
+	Storage string `json:"storage,omitempty"`
+	Pool    string `json:"pool,omitempty"`
+	// regenerate mac addresses so that the restored vm can coexist with the original one
+	Unique int8 `json:"unique"`
+}
+
+// restoreQEMU restores a backup into a new qemu and then overrides the
+// identity of the backup (name, smbios uuid, cloud-init) with the machine's one
+func (s *Service) restoreQEMU(ctx context.Context, node string, vmid int, restore infrav1.Restore, vmoption api.VirtualMachineCreateOptions) (*proxmox.VirtualMachine, error) {
+	log := log.FromContext(ctx)
+	log.Info("restoring qemu from backup", "archive", restore.Archive)
+
+	req := restoreRequest{VMID: vmid, Archive: restore.Archive, Storage: vmoption.Storage, Pool: vmoption.Pool, Unique: 1}
+	var upid string
+	if err := audit.REST(&s.client).Post(ctx, fmt.Sprintf("/nodes/%s/qemu", node), req, &upid); err != nil {
+		return nil, fmt.Errorf("failed to restore backup %s: %w", restore.Archive, err)
+	}
+	if err := s.waitCreation(ctx, node, vmid, upid); err != nil {
+		return nil, fmt.Errorf("failed to restore backup %s: %w", restore.Archive, err)
+	}
+
+	vm, err := virtualMachine(ctx, &s.client, vmid)
+	if err != nil {
+		return nil, err
+	}
+	config, err := vm.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	drive := config.Ide2
+	if vmoption.Arch == api.Aarch64 {
+		drive = config.Scsi30
+	}
+	if err := s.setConfig(ctx, vm, restoredConfig(vmoption, drive)); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// returns config applied to the restored qemu. smbios uuid is always regenerated
+// since the one in the backup is the provider id of the original machine.
+// cloud-init drive is attached unless the backup already has one in its slot.
+func restoredConfig(vmoption api.VirtualMachineCreateOptions, drive string) api.VirtualMachineConfig {
+	smbios := vmoption.SMBios1
+	if smbios == "" {
+		smbios = fmt.Sprintf("uuid=%s", uuid.NewUUID())
+	}
+	config := api.VirtualMachineConfig{
+		Name:         vmoption.Name,
+		Description:  vmoption.Description,
+		Tags:         vmoption.Tags,
+		CiCustom:     vmoption.CiCustom,
+		IPConfig:     vmoption.IPConfig,
+		NameServer:   vmoption.NameServer,
+		SearchDomain: vmoption.SearchDomain,
+		SMBios1:      smbios,
+	}
+	if !strings.Contains(drive, "cloudinit") {
+		if vmoption.Arch == api.Aarch64 {
+			config.Scsi.Scsi30 = vmoption.Scsi.Scsi30
+		} else {
+			config.Ide.Ide2 = vmoption.Ide.Ide2
+		}
+	}
+	return config
+}
@@ -0,0 +1,87 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/pkg/errors"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/retry"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+)
+
+const (
+	// reasons of events recorded like kube-scheduler does for pods
+	reasonScheduled        = "Scheduled"
+	reasonFailedScheduling = "FailedScheduling"
+	reasonFailedCreate     = "FailedCreate"
+
+	// number of alternative nodes listed in the Scheduled event
+	maxAlternatives = 3
+)
+
+// schedules the guest and records as events where it is placed or why no node fits
+func (s *Service) schedule(ctx context.Context, vmoption *api.VirtualMachineCreateOptions) (framework.SchedulerResult, error) {
+	result, err := s.scheduler.CreateQEMU(s.schedulingContext(ctx), vmoption)
+	if err != nil {
+		var fitErr *scheduler.FitError
+		if errors.As(err, &fitErr) {
+			s.scope.Warnf(reasonFailedScheduling, "%s", fitErr.Error())
+			// sent once no node has fit several times in a row
+			s.scope.Notify(ctx, infrav1.NotificationEventSchedulingFailed, fitErr.Error())
+			// no other node fits. the failed nodes are tried again by the next reconcile
+			s.scope.ClearFailedNodes()
+		}
+		return result, err
+	}
+	s.scope.ResolveNotification(infrav1.NotificationEventSchedulingFailed)
+	s.scope.Eventf(reasonScheduled, "%s", placementMessage(result))
+	s.observeLifecycle(scheduledDuration)
+	return result, nil
+}
+
+// e.g. "Placed on node pve1 with storage local-lvm and vmid 100. alternatives: pve2 (score 80), pve3 (score 60)"
+func placementMessage(result framework.SchedulerResult) string {
+	msg := fmt.Sprintf("Placed on node %s with storage %s and vmid %d", result.Node(), result.Storage(), result.VMID())
+	alternatives := []string{}
+	for _, score := range result.Scores() {
+		if score.Name == result.Node() {
+			msg = fmt.Sprintf("%s (score %d)", msg, score.Score)
+			continue
+		}
+		if len(alternatives) < maxAlternatives {
+			alternatives = append(alternatives, fmt.Sprintf("%s (score %d)", score.Name, score.Score))
+		}
+	}
+	if len(alternatives) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s. alternatives: %s", msg, strings.Join(alternatives, ", "))
+}
+
+// returns true if creating the instance may succeed on another node: the task creating it on the node
+// has failed or is stuck, e.g. a storage is full, or the node has failed the request, e.g. 500 of a storage
+// not online on the node or 595 of the node being unreachable by the proxy of the api.
+// requests rejected for the spec (4xx) and an unavailable api endpoint are not specific to the node
+func isNodeSpecific(err error) bool {
+	if errors.Is(err, ErrTaskStuck) || errors.Is(err, ErrTaskFailed) {
+		return true
+	}
+	code, ok := retry.StatusCode(err)
+	return ok && code >= 500 && !retry.IsUnavailable(err)
+}
+
+// records the node of the failed creation so that the next scheduling excludes it
+func (s *Service) handleCreateFailure(err error) {
+	node := s.scope.NodeName()
+	// a pinned instance has no other node to go to
+	if node == "" || s.scope.PinnedNode() != "" || !isNodeSpecific(err) {
+		return
+	}
+	s.scope.AddFailedNode(node)
+	s.scope.Warnf(reasonFailedCreate, "Failed to create instance on node %s, scheduling it to another node: %v", node, err)
+}
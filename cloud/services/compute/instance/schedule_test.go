@@ -0,0 +1,49 @@
+package instance_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler/framework"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("placementMessage", Label("unit", "instance"), func() {
+	It("should summarize placement and top alternatives", func() {
+		result := framework.NewSchedulerResult(100, "pve1", "local-lvm")
+		result.SetScores([]framework.NodeScore{
+			{Name: "pve1", Score: 90}, {Name: "pve2", Score: 80}, {Name: "pve3", Score: 60}, {Name: "pve4", Score: 50}, {Name: "pve5", Score: 10},
+		})
+		Expect(instance.PlacementMessage(result)).To(Equal(
+			"Placed on node pve1 with storage local-lvm and vmid 100 (score 90). alternatives: pve2 (score 80), pve3 (score 60), pve4 (score 50)"))
+	})
+
+	It("should omit scores if the node is the only candidate", func() {
+		result := framework.NewSchedulerResult(100, "pve1", "local-lvm")
+		Expect(instance.PlacementMessage(result)).To(Equal("Placed on node pve1 with storage local-lvm and vmid 100"))
+	})
+})
+
+var _ = Describe("isNodeSpecific", Label("unit", "instance"), func() {
+	It("should reschedule on errors of the node", func() {
+		Expect(instance.IsNodeSpecific(fmt.Errorf("%w: lvcreate 'pve/vm-100-disk-0' error:   Volume group \"pve\" has insufficient free space (100 extents): 2560 required.", instance.ErrTaskFailed))).To(BeTrue())
+		Expect(instance.IsNodeSpecific(fmt.Errorf("failed to create qemu: %w", rest.NewError(595, "Connection refused", nil)))).To(BeTrue())
+		Expect(instance.IsNodeSpecific(rest.NewError(500, "storage 'nfs' is not online", nil))).To(BeTrue())
+		Expect(instance.IsNodeSpecific(fmt.Errorf("failed to create lxc: %w", instance.ErrTaskStuck))).To(BeTrue())
+	})
+
+	It("should not reschedule on errors of the spec", func() {
+		Expect(instance.IsNodeSpecific(rest.NewError(400, "Parameter verification failed.", []byte(`{"errors":{"memory":"value must be at least 16"}}`)))).To(BeFalse())
+		Expect(instance.IsNodeSpecific(scheduler.ErrNoVMIDAvailable)).To(BeFalse())
+		Expect(instance.IsNodeSpecific(errors.New("storage 'nfs' is not online"))).To(BeFalse())
+	})
+
+	It("should not reschedule while the api endpoint is unavailable", func() {
+		Expect(instance.IsNodeSpecific(rest.NewError(503, "Service Unavailable", nil))).To(BeFalse())
+	})
+})
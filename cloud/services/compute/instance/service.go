@@ -2,10 +2,14 @@ package instance
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 
+	"github.com/k8s-proxmox/proxmox-go/api"
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
 
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler"
 )
 
@@ -17,6 +21,8 @@ type Service struct {
 	scope     Scope
 	client    proxmox.Service
 	scheduler *scheduler.Scheduler
+	// vmid of the guest whose creation has been started by this reconcile
+	created *int
 }
 
 func NewService(s Scope) *Service {
@@ -27,10 +33,27 @@ func NewService(s Scope) *Service {
 	}
 }
 
-func (s *Service) vncClient(nodeName string) (*proxmox.VNCWebSocketClient, error) {
-	client, err := s.client.NewNodeVNCWebSocketConnection(context.TODO(), nodeName)
-	if err != nil {
-		return nil, err
-	}
-	return client, nil
+// path of the qemu in the proxmox api, e.g. /nodes/pve1/qemu/100
+func qemuPath(vm *proxmox.VirtualMachine) string {
+	return fmt.Sprintf("/nodes/%s/qemu/%d", vm.Node, vm.VM.VMID)
+}
+
+// updates the config of the qemu and records it to the audit log
+func (s *Service) setConfig(ctx context.Context, vm *proxmox.VirtualMachine, config api.VirtualMachineConfig) error {
+	return audit.Do(ctx, &s.client, http.MethodPost, qemuPath(vm)+"/config", func() error {
+		return vm.SetConfigAsync(ctx, config)
+	})
+}
+
+// deletes the volume from the storage and records it to the audit log
+func (s *Service) deleteVolume(ctx context.Context, storage *proxmox.Storage, volume string) error {
+	path := fmt.Sprintf("/nodes/%s/storage/%s/content/%s", storage.Node, storage.Storage.Storage, volume)
+	return audit.Do(ctx, &s.client, http.MethodDelete, path, func() error {
+		return storage.DeleteVolume(ctx, volume)
+	})
+}
+
+// commands run and files written through the vnc shell are recorded to the audit log
+func (s *Service) vncClient(ctx context.Context, nodeName string) (*audit.VNCClient, error) {
+	return audit.NewVNCClient(ctx, &s.client, nodeName)
 }
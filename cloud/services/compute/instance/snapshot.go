@@ -0,0 +1,103 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/qemuconfig"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/snapshot"
+)
+
+const (
+	// prefix of snapshots taken by snapshot policy
+	policySnapshotPrefix = "cappx-"
+	// must fit into 40 characters of proxmox snapshot name
+	policySnapshotTimeFormat = "20060102-150405"
+	defaultSnapshotRetain    = 3
+)
+
+// take a snapshot if in-place updates are going to be applied and the policy requires it,
+// and then prune snapshots exceeding the retention of the policy
+func (s *Service) reconcileSnapshots(ctx context.Context, vm *proxmox.VirtualMachine, config *api.VirtualMachineConfig) error {
+	policy := s.scope.GetSnapshotPolicy()
+	if policy == nil {
+		return nil
+	}
+	var maxAge time.Duration
+	if policy.MaxAge != nil {
+		maxAge = policy.MaxAge.Duration
+	}
+	if update := pendingUpdate(s.scope.GetHardware(), s.scope.GetOptions(), config); policy.BeforeUpdate && update != "" {
+		if err := s.snapshotBeforeUpdate(ctx, vm, update); err != nil {
+			return err
+		}
+	} else if maxAge == 0 {
+		// retain can be exceeded only by taking a new snapshot
+		return nil
+	}
+	retain := policy.Retain
+	if retain < 1 {
+		retain = defaultSnapshotRetain
+	}
+	return snapshot.Prune(ctx, &s.client, vm, policySnapshotPrefix, retain, maxAge)
+}
+
+// takes a snapshot before the update unless one has been taken for it already,
+// so that an update failing over and over is not snapshotted on every reconcile
+func (s *Service) snapshotBeforeUpdate(ctx context.Context, vm *proxmox.VirtualMachine, update string) error {
+	log := log.FromContext(ctx)
+	snapshots, err := snapshot.List(ctx, &s.client, vm)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	description := beforeUpdateDescription(update)
+	if snapshotTaken(snapshots, description) {
+		log.V(1).Info("snapshot before update has been taken already", "update", update)
+		return nil
+	}
+	name := policySnapshotName(time.Now())
+	log.Info("taking snapshot before update", "snapshot", name, "update", update)
+	if err := snapshot.Create(ctx, &s.client, vm, name, description); err != nil {
+		return fmt.Errorf("failed to take snapshot before update: %w", err)
+	}
+	return nil
+}
+
+// returns the changes reconcileHotplug and reconcileMemory will apply to the config, empty if none
+func pendingUpdate(hardware infrav1.Hardware, options infrav1.Options, config *api.VirtualMachineConfig) string {
+	changes := []string{}
+	hotplug := hotplugOption(hardware, options)
+	if hotplug != "" && qemuconfig.HotPlug(hotplug) != qemuconfig.HotPlug(config.HotPlug) {
+		changes = append(changes, "hotplug="+hotplug)
+	}
+	if memory, ok := hotplugMemory(hardware, *config); ok {
+		changes = append(changes, fmt.Sprintf("memory=%d", memory))
+	}
+	return strings.Join(changes, " ")
+}
+
+// the pending update is recorded in the description of the snapshot taken before it
+func beforeUpdateDescription(update string) string {
+	return "taken by cappx before in-place update: " + update
+}
+
+// returns true if a snapshot taken by the policy has the description
+func snapshotTaken(snapshots []snapshot.Snapshot, description string) bool {
+	for _, snap := range snapshots {
+		if strings.HasPrefix(snap.Name, policySnapshotPrefix) && strings.TrimSpace(snap.Description) == description {
+			return true
+		}
+	}
+	return false
+}
+
+func policySnapshotName(t time.Time) string {
+	return policySnapshotPrefix + t.UTC().Format(policySnapshotTimeFormat)
+}
@@ -0,0 +1,45 @@
+package instance_test
+
+import (
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/snapshot"
+)
+
+var _ = Describe("pendingUpdate", Label("unit", "instance"), func() {
+	It("should be empty if nothing is updated in place", func() {
+		hardware := infrav1.Hardware{Memory: 4096}
+		Expect(instance.PendingUpdate(hardware, infrav1.Options{}, &api.VirtualMachineConfig{Memory: 4096})).To(BeEmpty())
+	})
+
+	It("should describe hotplug and memory changes", func() {
+		hardware := infrav1.Hardware{Memory: 8192, MemoryHotplug: true}
+		config := &api.VirtualMachineConfig{Memory: 4096, HotPlug: "network,disk,usb"}
+		Expect(instance.PendingUpdate(hardware, infrav1.Options{}, config)).To(Equal("hotplug=network,disk,usb,memory memory=8192"))
+		config.HotPlug = "network,disk,usb,memory"
+		Expect(instance.PendingUpdate(hardware, infrav1.Options{}, config)).To(Equal("memory=8192"))
+	})
+})
+
+var _ = Describe("snapshotTaken", Label("unit", "instance"), func() {
+	description := instance.BeforeUpdateDescription("memory=8192")
+
+	It("should find the snapshot taken before the same update", func() {
+		snapshots := []snapshot.Snapshot{
+			{Name: "cappx-20240101-000000", Description: description + "\n"},
+		}
+		Expect(instance.SnapshotTaken(snapshots, description)).To(BeTrue())
+	})
+
+	It("should not find snapshots of other updates or taken by users", func() {
+		snapshots := []snapshot.Snapshot{
+			{Name: "cappx-20240101-000000", Description: instance.BeforeUpdateDescription("memory=6144")},
+			{Name: "manual", Description: description},
+		}
+		Expect(instance.SnapshotTaken(snapshots, description)).To(BeFalse())
+	})
+})
@@ -0,0 +1,163 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/retry"
+)
+
+const (
+	defaultTaskTimeout = 15 * time.Minute
+
+	// reason of the event recorded when a stuck task is cancelled
+	reasonTaskStuck = "TaskStuck"
+
+	// "no content" is returned as the only line of an empty range of the task log
+	taskLogNoContent  = "no content"
+	taskStatusStopped = "stopped"
+)
+
+// ErrTaskStuck is returned when a task has made no progress within the task timeout and has been cancelled
+var ErrTaskStuck = errors.New("proxmox task is stuck")
+
+// ErrTaskFailed is returned with the exit status of a task which has stopped with an error
+var ErrTaskFailed = errors.New("proxmox task failed")
+
+// interval between polls of running tasks. var for testing
+var taskPollInterval = 5 * time.Second
+
+// response of GET /nodes/{node}/tasks/{upid}/status
+type taskStatus struct {
+	Status     string `json:"status"`
+	ExitStatus string `json:"exitstatus"`
+}
+
+// line of GET /nodes/{node}/tasks/{upid}/log
+type taskLogLine struct {
+	N int    `json:"n"`
+	T string `json:"t"`
+}
+
+// taskProgress tracks when the log of a task has grown last
+type taskProgress struct {
+	lines int
+	since time.Time
+}
+
+// observe records lines read from the log starting after the lines already seen
+func (p *taskProgress) observe(lines []taskLogLine, now time.Time) {
+	grown := false
+	for _, line := range lines {
+		if line.T == taskLogNoContent || line.N <= p.lines {
+			continue
+		}
+		p.lines = line.N
+		grown = true
+	}
+	if grown {
+		p.since = now
+	}
+}
+
+// stuck returns true if the log has not grown for the timeout
+func (p *taskProgress) stuck(now time.Time, timeout time.Duration) bool {
+	return now.Sub(p.since) >= timeout
+}
+
+func (s *Service) taskTimeout() time.Duration {
+	if policy := s.scope.GetTaskPolicy(); policy != nil && policy.Timeout.Duration > 0 {
+		return policy.Timeout.Duration
+	}
+	return defaultTaskTimeout
+}
+
+// waits for the task to be done. the task is cancelled once its log has not grown
+// for the task timeout, and ErrTaskStuck is returned
+func (s *Service) waitTask(ctx context.Context, node, upid string) error {
+	log := log.FromContext(ctx)
+	timeout := s.taskTimeout()
+	progress := taskProgress{since: time.Now()}
+	for {
+		// the task keeps running while the api fails. a long wait must not be lost to a transient error
+		var status taskStatus
+		if err := retry.OnServerError(ctx, func() error {
+			return s.client.RESTClient().Get(ctx, fmt.Sprintf("/nodes/%s/tasks/%s/status", node, upid), &status)
+		}); err != nil {
+			return fmt.Errorf("failed to get status of task %s: %w", upid, err)
+		}
+		if status.Status == taskStatusStopped {
+			if status.ExitStatus != proxmox.TaskStatusOK {
+				return fmt.Errorf("%w: %s", ErrTaskFailed, status.ExitStatus)
+			}
+			return nil
+		}
+
+		var lines []taskLogLine
+		if err := retry.OnServerError(ctx, func() error {
+			return s.client.RESTClient().Get(ctx, fmt.Sprintf("/nodes/%s/tasks/%s/log?start=%d&limit=500", node, upid, progress.lines), &lines)
+		}); err != nil {
+			return fmt.Errorf("failed to get log of task %s: %w", upid, err)
+		}
+		progress.observe(lines, time.Now())
+		if progress.stuck(time.Now(), timeout) {
+			log.Info("cancelling stuck task", "node", node, "upid", upid, "timeout", timeout)
+			if err := audit.REST(&s.client).Delete(ctx, fmt.Sprintf("/nodes/%s/tasks/%s", node, upid), nil, nil); err != nil {
+				return fmt.Errorf("failed to cancel stuck task %s: %w", upid, err)
+			}
+			return fmt.Errorf("%w: no progress of task %s on node %s for %s", ErrTaskStuck, upid, node, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(taskPollInterval):
+		}
+	}
+}
+
+// waits for the cancelled task to release the guest, so that it can be deleted
+func (s *Service) recoverStuckTask(ctx context.Context, node, upid string, taskErr error) error {
+	s.scope.Warnf(reasonTaskStuck, "%s", taskErr.Error())
+	if err := s.waitTaskStopped(ctx, node, upid); err != nil {
+		return err
+	}
+	return taskErr
+}
+
+// polls the cancelled task until it has stopped
+func (s *Service) waitTaskStopped(ctx context.Context, node, upid string) error {
+	for i := 0; i < 12; i++ {
+		var status taskStatus
+		if err := s.client.RESTClient().Get(ctx, fmt.Sprintf("/nodes/%s/tasks/%s/status", node, upid), &status); err != nil {
+			return fmt.Errorf("failed to get status of task %s: %w", upid, err)
+		}
+		if status.Status == taskStatusStopped {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(taskPollInterval):
+		}
+	}
+	return fmt.Errorf("cancelled task %s has not stopped", upid)
+}
+
+// waits for the task creating the guest of the vmid. the stuck task is cancelled.
+// the vmid is recorded first, so that the guest is cleaned up if its creation fails
+// even though it is not tagged yet, e.g. qemus are tagged only once restored
+func (s *Service) waitCreation(ctx context.Context, node string, vmid int, upid string) error {
+	s.created = &vmid
+	err := s.waitTask(ctx, node, upid)
+	if errors.Is(err, ErrTaskStuck) {
+		return s.recoverStuckTask(ctx, node, upid, err)
+	}
+	return err
+}
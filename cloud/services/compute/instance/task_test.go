@@ -0,0 +1,42 @@
+package instance_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("TaskStuck", Label("unit", "instance"), func() {
+	start := time.Now()
+	timeout := 10 * time.Minute
+	lines := func(from, to int) []instance.TaskLogLine {
+		var l []instance.TaskLogLine
+		for n := from; n <= to; n++ {
+			l = append(l, instance.TaskLogLine{N: n, T: "transferred 1.0 GiB"})
+		}
+		return l
+	}
+	noContent := []instance.TaskLogLine{{N: 1, T: "no content"}}
+
+	It("should not be stuck while the log grows", func() {
+		logs := [][]instance.TaskLogLine{lines(1, 3), lines(4, 5)}
+		times := []time.Time{start.Add(5 * time.Minute), start.Add(12 * time.Minute)}
+		Expect(instance.TaskStuck(start, logs, times, start.Add(20*time.Minute), timeout)).To(BeFalse())
+	})
+
+	It("should be stuck once the log has not grown for the timeout", func() {
+		logs := [][]instance.TaskLogLine{lines(1, 3), lines(3, 3)}
+		times := []time.Time{start.Add(5 * time.Minute), start.Add(12 * time.Minute)}
+		Expect(instance.TaskStuck(start, logs, times, start.Add(15*time.Minute), timeout)).To(BeTrue())
+	})
+
+	It("should be stuck if the task has never written to its log", func() {
+		logs := [][]instance.TaskLogLine{noContent}
+		times := []time.Time{start.Add(5 * time.Minute)}
+		Expect(instance.TaskStuck(start, logs, times, start.Add(9*time.Minute), timeout)).To(BeFalse())
+		Expect(instance.TaskStuck(start, logs, times, start.Add(10*time.Minute), timeout)).To(BeTrue())
+	})
+})
@@ -0,0 +1,33 @@
+package instance
+
+import (
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/version"
+)
+
+// returns the version of proxmox detected by the cluster. nil until detected
+func (s *Service) proxmoxVersion() (*version.Version, error) {
+	detected := s.scope.GetProxmoxVersion()
+	if detected == "" {
+		return nil, nil
+	}
+	v, err := version.Parse(detected)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Supported(); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// returns an error if the image can not be imported by the version of proxmox
+func (s *Service) checkImportFrom() error {
+	v, err := s.proxmoxVersion()
+	if err != nil || v == nil {
+		return err
+	}
+	if !v.Features().ImportFrom {
+		return version.ImportFromRequired(*v)
+	}
+	return nil
+}
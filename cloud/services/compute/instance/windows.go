@@ -0,0 +1,53 @@
+package instance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// windows limits host names to the 15 characters of netbios
+const netBIOSNameLength = 15
+
+// cloudbase-init only knows set_hostname of cloud-config. the qemu guest agent
+// comes with virtio-win, so it is not installed via cloud-config
+func windowsUserData(vmName string) *infrav1.UserData {
+	return &infrav1.UserData{SetHostName: windowsHostName(vmName)}
+}
+
+// cloudbase-init truncates longer host names, which makes the names of machines
+// in a MachineDeployment collide. they are shortened keeping a hash of the name instead
+func windowsHostName(name string) string {
+	name = strings.ReplaceAll(name, ".", "-")
+	if len(name) <= netBIOSNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:5]
+	prefix := strings.TrimRight(name[:netBIOSNameLength-len(suffix)-1], "-")
+	return prefix + "-" + suffix
+}
+
+// windows has no serial console, so a graphical display is used unless specified
+func windowsDefaults(vmOption *api.VirtualMachineCreateOptions, options infrav1.Options) {
+	if options.VGA == nil {
+		vmOption.VGA = "std"
+	}
+}
+
+// returns the scsi controller. virtio-scsi-single is what the proxmox wizard
+// picks for windows, and its vioscsi driver is part of virtio-win
+func scsiController(hardware infrav1.Hardware, options infrav1.Options) api.ScsiHw {
+	switch {
+	case hardware.SCSIController != "":
+		return api.ScsiHw(hardware.SCSIController)
+	case options.OSType.IsWindows():
+		return api.ScsiHw(infrav1.SCSIControllerVirtioSingle)
+	default:
+		return api.ScsiHw(infrav1.SCSIControllerVirtio)
+	}
+}
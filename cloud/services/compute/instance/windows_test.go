@@ -0,0 +1,55 @@
+package instance_test
+
+import (
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+var _ = Describe("windowsHostName", Label("unit", "instance"), func() {
+	It("should keep short names", func() {
+		Expect(instance.WindowsHostName("win-cp-abcde")).To(Equal("win-cp-abcde"))
+	})
+
+	It("should shorten long names keeping them unique", func() {
+		a := instance.WindowsHostName("cappx-test-md-0-7d9f8b5c4-x2v7q")
+		b := instance.WindowsHostName("cappx-test-md-0-7d9f8b5c4-k8l2m")
+		Expect(a).To(HaveLen(15))
+		Expect(a).To(HavePrefix("cappx-tes-"))
+		Expect(a).NotTo(Equal(b))
+		Expect(instance.WindowsHostName("cappx-test-md-0-7d9f8b5c4-x2v7q")).To(Equal(a))
+	})
+
+	It("should not end the prefix with a hyphen", func() {
+		Expect(instance.WindowsHostName("abcdefghi-jklmnopq")).To(MatchRegexp(`^abcdefghi-[0-9a-f]{5}$`))
+		Expect(instance.WindowsHostName("abcdefgh-ijklmnopq")).To(MatchRegexp(`^abcdefgh-[0-9a-f]{5}$`))
+	})
+
+	It("should replace dots", func() {
+		Expect(instance.WindowsHostName("win.example")).To(Equal("win-example"))
+	})
+})
+
+var _ = Describe("windowsUserData", Label("unit", "instance"), func() {
+	It("should set hostname the cloudbase-init way", func() {
+		userData := instance.WindowsUserData("win-0")
+		Expect(userData.SetHostName).To(Equal("win-0"))
+		Expect(userData.HostName).To(BeEmpty())
+		Expect(userData.Packages).To(BeEmpty())
+	})
+})
+
+var _ = Describe("scsiController", Label("unit", "instance"), func() {
+	It("should default per os type", func() {
+		Expect(instance.ScsiController(infrav1.Hardware{}, infrav1.Options{})).To(Equal(api.ScsiHw("virtio-scsi-pci")))
+		Expect(instance.ScsiController(infrav1.Hardware{}, infrav1.Options{OSType: "win11"})).To(Equal(api.ScsiHw("virtio-scsi-single")))
+	})
+
+	It("should use the controller of the machine", func() {
+		hardware := infrav1.Hardware{SCSIController: "lsi"}
+		Expect(instance.ScsiController(hardware, infrav1.Options{OSType: "win11"})).To(Equal(api.ScsiHw("lsi")))
+	})
+})
@@ -0,0 +1,77 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/migration"
+)
+
+// Reconcile migrates vms of the machines off the node under maintenance.
+// with recreate strategy, machines on the node are only recorded so that they are replaced by the controller.
+// machines which are not movable are always recorded
+func (s *Service) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling node maintenance")
+
+	spec := s.scope.GetSpec()
+	guests, err := guest.List(ctx, &s.client)
+	if err != nil {
+		return err
+	}
+	left := []string{}
+	// machines of low priority are moved first
+	machines := slices.Clone(s.scope.Machines())
+	slices.SortStableFunc(machines, func(a, b infrav1.ProxmoxMachine) int {
+		return a.Spec.Priority.Rank() - b.Spec.Priority.Rank()
+	})
+	for _, m := range machines {
+		vm, err := guest.Find(guests, *m.Spec.VMID)
+		if err != nil {
+			continue
+		}
+		if vm.Node != spec.NodeName {
+			continue
+		}
+		// pinned and high priority machines are not moved automatically. they are left for the admin to handle
+		if spec.Strategy == infrav1.MaintenanceStrategyRecreate || !m.Spec.Movable() {
+			left = append(left, m.Name)
+			continue
+		}
+		target, err := s.targetNode(ctx, m.Spec.NodeGroup)
+		if err != nil {
+			return err
+		}
+		log.Info("migrating guest", "machine", m.Name, "type", vm.Type, "vmid", vm.VMID, "target", target)
+		if err := migration.Migrate(ctx, &s.client, *vm, target); err != nil {
+			return fmt.Errorf("failed to migrate machine %s to node %s: %w", m.Name, target, err)
+		}
+	}
+	s.scope.SetMachines(left)
+	s.scope.SetReady(len(left) == 0)
+	log.Info("Reconciled node maintenance")
+	return nil
+}
+
+// returns the node to migrate vms to. nodes under maintenance and out of the node group, if any, are excluded
+func (s *Service) targetNode(ctx context.Context, nodeGroup string) (string, error) {
+	nodes, err := s.client.GetNodes(ctx)
+	if err != nil {
+		return "", err
+	}
+	excluded := append([]string{s.scope.GetSpec().NodeName}, s.scope.CordonedNodes()...)
+	if nodeGroup != "" {
+		group := s.scope.NodeGroup(nodeGroup)
+		for _, n := range nodes {
+			if group == nil || !group.Contains(n.Node) {
+				excluded = append(excluded, n.Node)
+			}
+		}
+	}
+	return migration.SelectTarget(nodes, excluded)
+}
@@ -0,0 +1,18 @@
+package nodehealth
+
+import (
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+func OfflineNodes(current []infrav1.OfflineNode, nodes []*api.Node, now metav1.Time) []infrav1.OfflineNode {
+	return offlineNodes(current, nodes, now)
+}
+
+func DownNodes(offline []infrav1.OfflineNode, policy infrav1.NodeFailurePolicy, confirmed []string, now time.Time) []string {
+	return downNodes(offline, policy, confirmed, now)
+}
@@ -0,0 +1,78 @@
+package nodehealth
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// tracks offline nodes and decides which of them are down permanently
+func (s *Service) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	policy := s.scope.NodeFailurePolicy()
+	if policy == nil {
+		s.scope.SetOfflineNodes(nil)
+		s.scope.SetDownNodes(nil)
+		return nil
+	}
+
+	nodes, err := s.client.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+	now := metav1.Now()
+	offline := offlineNodes(s.scope.OfflineNodes(), nodes, now)
+	down := downNodes(offline, *policy, s.scope.ConfirmedDownNodes(), now.Time)
+	for _, node := range down {
+		if !slices.Contains(s.scope.DownNodes(), node) {
+			log.Info("proxmox node is considered down, machines on it will be recreated", "node", node)
+		}
+	}
+	s.scope.SetOfflineNodes(offline)
+	s.scope.SetDownNodes(down)
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context) error {
+	return nil
+}
+
+// returns nodes not online, keeping the time they were first observed offline
+func offlineNodes(current []infrav1.OfflineNode, nodes []*api.Node, now metav1.Time) []infrav1.OfflineNode {
+	offline := []infrav1.OfflineNode{}
+	for _, node := range nodes {
+		if node.Status == "online" {
+			continue
+		}
+		since := now
+		for _, c := range current {
+			if c.Name == node.Node {
+				since = c.Since
+			}
+		}
+		offline = append(offline, infrav1.OfflineNode{Name: node.Node, Since: since})
+	}
+	return offline
+}
+
+// returns sorted nodes offline longer than the timeout and confirmed unless confirmation is skipped
+func downNodes(offline []infrav1.OfflineNode, policy infrav1.NodeFailurePolicy, confirmed []string, now time.Time) []string {
+	down := []string{}
+	for _, node := range offline {
+		if now.Sub(node.Since.Time) < policy.Timeout.Duration {
+			continue
+		}
+		if !policy.SkipConfirmation && !slices.Contains(confirmed, node.Name) {
+			continue
+		}
+		down = append(down, node.Name)
+	}
+	slices.Sort(down)
+	return down
+}
@@ -0,0 +1,51 @@
+package nodehealth_test
+
+import (
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/nodehealth"
+)
+
+var _ = Describe("offlineNodes", Label("unit", "nodehealth"), func() {
+	earlier := metav1.NewTime(time.Unix(100, 0))
+	now := metav1.NewTime(time.Unix(1000, 0))
+
+	It("should keep the time nodes were first observed offline", func() {
+		current := []infrav1.OfflineNode{{Name: "pve2", Since: earlier}, {Name: "pve3", Since: earlier}}
+		nodes := []*api.Node{
+			{Node: "pve1", Status: "online"},
+			{Node: "pve2", Status: "offline"},
+			{Node: "pve3", Status: "online"},
+			{Node: "pve4", Status: "unknown"},
+		}
+		Expect(nodehealth.OfflineNodes(current, nodes, now)).To(Equal([]infrav1.OfflineNode{
+			{Name: "pve2", Since: earlier},
+			{Name: "pve4", Since: now},
+		}))
+	})
+})
+
+var _ = Describe("downNodes", Label("unit", "nodehealth"), func() {
+	offline := []infrav1.OfflineNode{
+		{Name: "pve3", Since: metav1.NewTime(time.Unix(0, 0))},
+		{Name: "pve2", Since: metav1.NewTime(time.Unix(0, 0))},
+		{Name: "pve4", Since: metav1.NewTime(time.Unix(500, 0))},
+	}
+	now := time.Unix(700, 0)
+
+	It("should require confirmation", func() {
+		policy := infrav1.NodeFailurePolicy{Timeout: metav1.Duration{Duration: 5 * time.Minute}}
+		Expect(nodehealth.DownNodes(offline, policy, []string{"pve3", "pve4"}, now)).To(Equal([]string{"pve3"}))
+	})
+
+	It("should consider nodes down after the timeout when confirmation is skipped", func() {
+		policy := infrav1.NodeFailurePolicy{Timeout: metav1.Duration{Duration: 5 * time.Minute}, SkipConfirmation: true}
+		Expect(nodehealth.DownNodes(offline, policy, nil, now)).To(Equal([]string{"pve2", "pve3"}))
+	})
+})
@@ -0,0 +1,13 @@
+package nodehealth_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNodeHealth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Node Health Service Suite")
+}
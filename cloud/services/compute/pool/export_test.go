@@ -0,0 +1,28 @@
+package pool
+
+import (
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+type Member = member
+
+func PoolName(spec infrav1.ResourcePool, clusterName string) string {
+	return poolName(spec, clusterName)
+}
+
+func ManagedComment(namespace, clusterName string) string {
+	return managedComment(namespace, clusterName)
+}
+
+func VMsToAdd(guests []guest.Guest, namespace, clusterName string) []string {
+	return vmsToAdd(guests, namespace, clusterName)
+}
+
+func HasStorage(members []Member, storage string) bool {
+	return hasStorage(members, storage)
+}
+
+func IsEmpty(members []Member, storage string) bool {
+	return isEmpty(members, storage)
+}
@@ -0,0 +1,172 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+// member of GET /pools/{poolid}
+type member struct {
+	Type    string `json:"type"`
+	VMID    int    `json:"vmid,omitempty"`
+	Storage string `json:"storage,omitempty"`
+}
+
+type poolConfig struct {
+	Comment string   `json:"comment"`
+	Members []member `json:"members"`
+}
+
+func (s *Service) Reconcile(ctx context.Context) error {
+	spec := s.scope.PoolSpec()
+	if spec == nil {
+		return nil
+	}
+	log := log.FromContext(ctx)
+	log.Info("Reconciling resource pool")
+
+	name := poolName(*spec, s.scope.Name())
+	config, err := s.getPool(ctx, name)
+	if err != nil {
+		if !rest.IsNotFound(err) {
+			return err
+		}
+		log.Info("creating resource pool", "pool", name)
+		pool := api.ResourcePool{PoolID: name, Comment: managedComment(s.scope.Namespace(), s.scope.Name())}
+		if err := s.client.RESTClient().CreateResourcePool(ctx, pool); err != nil {
+			return fmt.Errorf("failed to create resource pool %s: %w", name, err)
+		}
+		config = &poolConfig{Comment: pool.Comment}
+	}
+	s.scope.SetPool(name)
+
+	// guests created before the pool was set are added too. guests in another pool are left
+	// since proxmox refuses to move them between pools
+	guests, err := guest.List(ctx, &s.client)
+	if err != nil {
+		return err
+	}
+	update := map[string]interface{}{}
+	if vms := vmsToAdd(guests, s.scope.Namespace(), s.scope.Name()); len(vms) > 0 {
+		update["vms"] = strings.Join(vms, ",")
+	}
+	if storage := s.scope.Storage().Name; !hasStorage(config.Members, storage) {
+		update["storage"] = storage
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	log.Info("adding members to resource pool", "pool", name, "members", update)
+	if err := audit.REST(&s.client).Put(ctx, poolPath(name), update, nil); err != nil {
+		return fmt.Errorf("failed to add members to resource pool %s: %w", name, err)
+	}
+	return nil
+}
+
+// the pool is left unless cappx created it for the cluster and only the snippet storage is left in it.
+// vms are gone by now since Machines are deleted before the ProxmoxCluster
+func (s *Service) Delete(ctx context.Context) error {
+	spec := s.scope.PoolSpec()
+	if spec == nil {
+		return nil
+	}
+	log := log.FromContext(ctx)
+	name := poolName(*spec, s.scope.Name())
+	config, err := s.getPool(ctx, name)
+	if err != nil {
+		if rest.IsNotFound(err) {
+			log.Info("resource pool not found or already deleted", "pool", name)
+			return nil
+		}
+		return err
+	}
+	if config.Comment != managedComment(s.scope.Namespace(), s.scope.Name()) {
+		log.Info("resource pool is not created by cappx, skipping deletion", "pool", name)
+		return nil
+	}
+	storage := s.scope.Storage().Name
+	if !isEmpty(config.Members, storage) {
+		log.Info("resource pool not empty, skipping deletion", "pool", name)
+		return nil
+	}
+	if hasStorage(config.Members, storage) {
+		remove := map[string]interface{}{"storage": storage, "delete": 1}
+		if err := audit.REST(&s.client).Put(ctx, poolPath(name), remove, nil); err != nil {
+			return fmt.Errorf("failed to remove storage from resource pool %s: %w", name, err)
+		}
+	}
+	log.Info("deleting resource pool", "pool", name)
+	return s.client.RESTClient().DeleteResourcePool(ctx, name)
+}
+
+func (s *Service) getPool(ctx context.Context, name string) (*poolConfig, error) {
+	pools, err := s.client.RESTClient().GetResourcePools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(pools, func(p *api.ResourcePool) bool { return p.PoolID == name }) {
+		return nil, rest.NotFoundErr
+	}
+	var config poolConfig
+	if err := s.client.RESTClient().Get(ctx, poolPath(name), &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func poolPath(name string) string {
+	return fmt.Sprintf("/pools/%s", name)
+}
+
+func poolName(spec infrav1.ResourcePool, clusterName string) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	return clusterName
+}
+
+// marks pools created by cappx so that pools reused from the user are never deleted
+func managedComment(namespace, clusterName string) string {
+	return fmt.Sprintf("managed by cappx for cluster %s/%s", namespace, clusterName)
+}
+
+// returns vmids of the guests of the cluster not in any pool.
+// guests of a cluster of the same name in another namespace are skipped
+func vmsToAdd(guests []guest.Guest, namespace, clusterName string) []string {
+	vms := []string{}
+	for _, g := range guests {
+		if g.Pool != "" || !g.HasTag(guest.ClusterTag(clusterName)) {
+			continue
+		}
+		if ns, ok := g.MachineNamespace(); ok && ns != namespace {
+			continue
+		}
+		vms = append(vms, strconv.Itoa(g.VMID))
+	}
+	return vms
+}
+
+func hasStorage(members []member, storage string) bool {
+	return slices.ContainsFunc(members, func(m member) bool { return m.Type == "storage" && m.Storage == storage })
+}
+
+// the pool is empty if nothing but the snippet storage is in it
+func isEmpty(members []member, storage string) bool {
+	for _, m := range members {
+		if m.Type != "storage" || m.Storage != storage {
+			return false
+		}
+	}
+	return true
+}
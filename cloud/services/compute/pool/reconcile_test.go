@@ -0,0 +1,65 @@
+package pool_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/pool"
+)
+
+var _ = Describe("poolName", Label("unit", "pool"), func() {
+	It("should default to the cluster name", func() {
+		Expect(pool.PoolName(infrav1.ResourcePool{}, "test")).To(Equal("test"))
+		Expect(pool.PoolName(infrav1.ResourcePool{Name: "k8s"}, "test")).To(Equal("k8s"))
+	})
+})
+
+var _ = Describe("managedComment", Label("unit", "pool"), func() {
+	It("should be unique per cluster", func() {
+		Expect(pool.ManagedComment("default", "test")).To(Equal("managed by cappx for cluster default/test"))
+		Expect(pool.ManagedComment("other", "test")).NotTo(Equal(pool.ManagedComment("default", "test")))
+	})
+})
+
+var _ = Describe("vmsToAdd", Label("unit", "pool"), func() {
+	It("should only return guests of the cluster not in a pool", func() {
+		guests := []guest.Guest{
+			{VMID: 100, Tags: "cluster.test"},
+			{VMID: 101, Tags: "foo;Cluster.Test", Pool: ""},
+			{VMID: 102, Tags: "cluster.test", Pool: "other"},
+			{VMID: 103, Tags: "cluster.other"},
+			{VMID: 104},
+		}
+		Expect(pool.VMsToAdd(guests, "default", "test")).To(Equal([]string{"100", "101"}))
+	})
+
+	It("should skip guests of a cluster of the same name in another namespace", func() {
+		guests := []guest.Guest{
+			{VMID: 100, Tags: "cluster.test;machine.default.cp-0"},
+			{VMID: 101, Tags: "cluster.test;machine.other.cp-0"},
+		}
+		Expect(pool.VMsToAdd(guests, "default", "test")).To(Equal([]string{"100"}))
+	})
+})
+
+var _ = Describe("pool members", Label("unit", "pool"), func() {
+	members := []pool.Member{
+		{Type: "storage", Storage: "local"},
+		{Type: "storage", Storage: "local-test-snippets"},
+	}
+
+	It("should find the storage", func() {
+		Expect(pool.HasStorage(members, "local-test-snippets")).To(BeTrue())
+		Expect(pool.HasStorage(members, "local-lvm")).To(BeFalse())
+		Expect(pool.HasStorage([]pool.Member{{Type: "qemu", VMID: 100}}, "local")).To(BeFalse())
+	})
+
+	It("should be empty with only the snippet storage left", func() {
+		Expect(pool.IsEmpty(nil, "local-test-snippets")).To(BeTrue())
+		Expect(pool.IsEmpty(members[1:], "local-test-snippets")).To(BeTrue())
+		Expect(pool.IsEmpty(members, "local-test-snippets")).To(BeFalse())
+		Expect(pool.IsEmpty([]pool.Member{{Type: "lxc", VMID: 100}}, "local-test-snippets")).To(BeFalse())
+	})
+})
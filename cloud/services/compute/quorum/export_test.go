@@ -0,0 +1,11 @@
+package quorum
+
+import (
+	"github.com/k8s-proxmox/proxmox-go/api"
+)
+
+type ClusterStatus = clusterStatus
+
+func Check(status []ClusterStatus, nodes []*api.Node) (string, string) {
+	return check(status, nodes)
+}
@@ -0,0 +1,72 @@
+package quorum
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// entry of GET /cluster/status. quorate is only set on the entry of type cluster
+type clusterStatus struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Quorate int    `json:"quorate"`
+}
+
+// checks the quorum of the proxmox cluster and the state of its nodes
+func (s *Service) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	var status []clusterStatus
+	if err := s.client.RESTClient().Get(ctx, "/cluster/status", &status); err != nil {
+		return fmt.Errorf("failed to get cluster status: %w", err)
+	}
+	nodes, err := s.client.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	reason, message := check(status, nodes)
+	if reason == "" {
+		if !s.scope.Quorate() {
+			log.Info("proxmox cluster is quorate again, resuming operations")
+		}
+		s.scope.SetQuorate()
+		return nil
+	}
+	if s.scope.Quorate() {
+		log.Info("pausing operations on proxmox cluster", "reason", reason, "message", message)
+	}
+	s.scope.SetQuorumLost(reason, message)
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context) error {
+	return nil
+}
+
+// returns the reason mutating operations must be paused. empty if none
+func check(status []clusterStatus, nodes []*api.Node) (string, string) {
+	for _, entry := range status {
+		// standalone nodes have no entry of type cluster and are always quorate
+		if entry.Type == "cluster" && entry.Quorate == 0 {
+			return infrav1.QuorumLostReason, fmt.Sprintf("proxmox cluster %s has lost quorum", entry.Name)
+		}
+	}
+	unknown := []string{}
+	for _, node := range nodes {
+		if node.Status == "unknown" {
+			unknown = append(unknown, node.Node)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return infrav1.NodesUnknownReason, fmt.Sprintf("proxmox nodes in unknown state: %s", strings.Join(unknown, ","))
+	}
+	return "", ""
+}
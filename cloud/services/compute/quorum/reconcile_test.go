@@ -0,0 +1,43 @@
+package quorum_test
+
+import (
+	"github.com/k8s-proxmox/proxmox-go/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/quorum"
+)
+
+var _ = Describe("check", Label("unit", "quorum"), func() {
+	online := []*api.Node{
+		{Node: "pve1", Status: "online"},
+		{Node: "pve2", Status: "offline"},
+	}
+
+	It("should pass quorate clusters", func() {
+		status := []quorum.ClusterStatus{{Type: "cluster", Name: "pve", Quorate: 1}, {Type: "node", Name: "pve1"}}
+		reason, _ := quorum.Check(status, online)
+		Expect(reason).To(BeEmpty())
+	})
+
+	It("should pass standalone nodes", func() {
+		reason, _ := quorum.Check([]quorum.ClusterStatus{{Type: "node", Name: "pve1"}}, online[:1])
+		Expect(reason).To(BeEmpty())
+	})
+
+	It("should detect the loss of quorum", func() {
+		status := []quorum.ClusterStatus{{Type: "cluster", Name: "pve", Quorate: 0}}
+		reason, message := quorum.Check(status, online)
+		Expect(reason).To(Equal(infrav1.QuorumLostReason))
+		Expect(message).To(ContainSubstring("pve has lost quorum"))
+	})
+
+	It("should detect nodes in unknown state", func() {
+		status := []quorum.ClusterStatus{{Type: "cluster", Name: "pve", Quorate: 1}}
+		nodes := append([]*api.Node{{Node: "pve4", Status: "unknown"}, {Node: "pve3", Status: "unknown"}}, online...)
+		reason, message := quorum.Check(status, nodes)
+		Expect(reason).To(Equal(infrav1.NodesUnknownReason))
+		Expect(message).To(HaveSuffix("pve3,pve4"))
+	})
+})
@@ -0,0 +1,13 @@
+package quorum_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuorum(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Quorum Service Suite")
+}
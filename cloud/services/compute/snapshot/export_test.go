@@ -0,0 +1,5 @@
+package snapshot
+
+func SnapshotName(uid string) string {
+	return snapshotName(uid)
+}
@@ -0,0 +1,171 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/k8s-proxmox/proxmox-go/rest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/names"
+	qemusnapshot "github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/snapshot"
+)
+
+const (
+	// prefix of snapshots managed by ProxmoxSnapshot
+	snapshotPrefix = "k8s-"
+)
+
+var (
+	// ErrVMNotFound is returned when the target machine has no vm yet
+	ErrVMNotFound = errors.New("target machine has no vm yet")
+	// ErrVMNotOwned is returned when the vmid of the target machine has been taken by another guest
+	ErrVMNotOwned = errors.New("vm does not belong to the target machine")
+	// ErrSnapshotLost is returned when the snapshot taken before is no longer found in proxmox
+	ErrSnapshotLost = errors.New("snapshot is lost")
+	// ErrVMChanged is returned when the target machine has another vm than the snapshot was taken of
+	ErrVMChanged = errors.New("vm of the target machine has changed since the snapshot was taken")
+)
+
+func (s *Service) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling snapshot")
+
+	vm, err := s.getVM(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.createOrGetSnapshot(ctx, vm); err != nil {
+		return err
+	}
+	if err := s.reconcileRollback(ctx, vm); err != nil {
+		return err
+	}
+
+	log.Info("Reconciled snapshot")
+	s.scope.SetReady(true)
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Deleting snapshot")
+
+	name := s.scope.GetSnapshotName()
+	if name == "" {
+		return nil
+	}
+	vm, err := s.getVM(ctx)
+	if err != nil {
+		if errors.Is(err, ErrVMNotFound) || errors.Is(err, ErrVMNotOwned) || rest.IsNotFound(err) {
+			// snapshots are deleted together with the vm
+			log.Info("vm is not found or already deleted", "reason", err.Error())
+			return nil
+		}
+		return err
+	}
+	snapshots, err := qemusnapshot.List(ctx, &s.client, vm)
+	if err != nil {
+		return err
+	}
+	if !contains(snapshots, name) {
+		log.Info("snapshot is not found or already deleted")
+		return nil
+	}
+	return qemusnapshot.Delete(ctx, &s.client, vm, name)
+}
+
+// returns the vm of the target machine. the guest having the vmid must carry the machine tag of
+// the target, or its name if it has no machine tag, so that a vmid taken by another guest after
+// the vm was deleted is never snapshotted or rolled back
+func (s *Service) getVM(ctx context.Context) (*proxmox.VirtualMachine, error) {
+	vmid := s.scope.GetVMID()
+	if vmid == nil {
+		return nil, ErrVMNotFound
+	}
+	guests, err := guest.List(ctx, &s.client)
+	if err != nil {
+		return nil, err
+	}
+	g, err := guest.Find(guests, *vmid)
+	if err != nil {
+		return nil, err
+	}
+	if !g.OwnedBy(guest.MachineTag(s.scope.Namespace(), s.scope.MachineName()), s.scope.MachineName()) {
+		return nil, fmt.Errorf("%w: vmid %d is taken by %s", ErrVMNotOwned, *vmid, g.Name)
+	}
+	return g.QEMU(ctx, &s.client)
+}
+
+// takes the snapshot once. a snapshot taken before is never taken again, even if it is lost
+// or the machine has got another vm, since the new one would not hold the state it was taken for
+func (s *Service) createOrGetSnapshot(ctx context.Context, vm *proxmox.VirtualMachine) error {
+	log := log.FromContext(ctx)
+	name := s.scope.GetSnapshotName()
+	if name == "" {
+		name = snapshotName(s.scope.UID())
+		s.scope.SetSnapshotName(name)
+		s.scope.SetVMID(vm.VM.VMID)
+	}
+	if vmid := s.scope.GetSnapshotVMID(); vmid != nil && *vmid != vm.VM.VMID {
+		s.scope.SetReady(false)
+		return fmt.Errorf("%w: snapshot %s belongs to vmid %d, but the machine has vmid %d", ErrVMChanged, name, *vmid, vm.VM.VMID)
+	}
+
+	snapshots, err := qemusnapshot.List(ctx, &s.client, vm)
+	if err != nil {
+		return err
+	}
+	if contains(snapshots, name) {
+		return nil
+	}
+	// the name is recorded before taking the snapshot, so that only the creation time tells it has been taken
+	if s.scope.GetCreationTime() != nil {
+		s.scope.SetReady(false)
+		return fmt.Errorf("%w: snapshot %s is not found in vmid %d", ErrSnapshotLost, name, vm.VM.VMID)
+	}
+
+	log.Info("taking snapshot", "snapshot", name)
+	if err := qemusnapshot.Create(ctx, &s.client, vm, name, s.scope.Description()); err != nil {
+		return fmt.Errorf("failed to take snapshot %s: %w", name, err)
+	}
+	s.scope.SetCreationTime(metav1.Now())
+	return nil
+}
+
+// roll back once for each time spec.rollback is set to true
+func (s *Service) reconcileRollback(ctx context.Context, vm *proxmox.VirtualMachine) error {
+	log := log.FromContext(ctx)
+	if !s.scope.RollbackRequested() {
+		s.scope.SetRolledBack(false)
+		return nil
+	}
+	if s.scope.IsRolledBack() {
+		return nil
+	}
+	log.Info("rolling back vm", "snapshot", s.scope.GetSnapshotName())
+	if err := qemusnapshot.Rollback(ctx, &s.client, vm, s.scope.GetSnapshotName(), true); err != nil {
+		return fmt.Errorf("failed to roll back to snapshot %s: %w", s.scope.GetSnapshotName(), err)
+	}
+	s.scope.SetRolledBack(true)
+	return nil
+}
+
+// proxmox snapshot name must start with a letter and be at most 40 characters
+func snapshotName(uid string) string {
+	return names.FromUID(snapshotPrefix, uid)
+}
+
+func contains(snapshots []qemusnapshot.Snapshot, name string) bool {
+	for _, s := range snapshots {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
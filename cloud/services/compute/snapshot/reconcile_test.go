@@ -0,0 +1,141 @@
+package snapshot_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/snapshot"
+)
+
+var _ = Describe("snapshotName", Label("unit", "snapshot"), func() {
+	It("should derive a valid proxmox snapshot name from uid", func() {
+		name := snapshot.SnapshotName("5c1e5f6e-0bd4-4d3c-9f1b-0a2f7f0e1a11")
+		Expect(name).To(Equal("k8s-5c1e5f6e0bd4"))
+		Expect(len(name)).To(BeNumerically("<=", 40))
+	})
+})
+
+type fakeScope struct {
+	client       *proxmox.Service
+	vmid         int
+	snapshotName string
+	snapshotVMID *int
+	creationTime *metav1.Time
+	ready        bool
+}
+
+func (f *fakeScope) CloudClient() *proxmox.Service { return f.client }
+func (f *fakeScope) Name() string                  { return "snap" }
+func (f *fakeScope) Namespace() string             { return "default" }
+func (f *fakeScope) MachineName() string           { return "md-0" }
+func (f *fakeScope) UID() string                   { return "5c1e5f6e-0bd4-4d3c-9f1b-0a2f7f0e1a11" }
+func (f *fakeScope) Description() string           { return "" }
+func (f *fakeScope) RollbackRequested() bool       { return false }
+func (f *fakeScope) GetVMID() *int                 { return &f.vmid }
+func (f *fakeScope) GetSnapshotName() string       { return f.snapshotName }
+func (f *fakeScope) SetSnapshotName(name string)   { f.snapshotName = name }
+func (f *fakeScope) GetSnapshotVMID() *int         { return f.snapshotVMID }
+func (f *fakeScope) SetVMID(vmid int)              { f.snapshotVMID = &vmid }
+func (f *fakeScope) GetCreationTime() *metav1.Time { return f.creationTime }
+func (f *fakeScope) SetCreationTime(t metav1.Time) { f.creationTime = &t }
+func (f *fakeScope) IsRolledBack() bool            { return false }
+func (f *fakeScope) SetRolledBack(_ bool)          {}
+func (f *fakeScope) SetReady(v bool)               { f.ready = v }
+
+func newClient(url string) *proxmox.Service {
+	params := proxmox.NewParams(url+"/api2/json", proxmox.AuthConfig{TokenID: "cappx@pve!token", Secret: "secret"}, proxmox.ClientConfig{})
+	client, err := proxmox.NewService(params)
+	Expect(err).NotTo(HaveOccurred())
+	return client
+}
+
+var _ = Describe("getVM", Label("unit", "snapshot"), func() {
+	var (
+		server   *httptest.Server
+		client   *proxmox.Service
+		requests []string
+	)
+
+	// vmid 100 has been taken by a guest of another machine
+	BeforeEach(func() {
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			switch r.URL.Path {
+			case "/api2/json/cluster/resources":
+				_, _ = w.Write([]byte(`{"data":[
+					{"type":"qemu","node":"pve1","vmid":100,"name":"other","status":"running","tags":"cappx;machine.default.other"}
+				]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(server.Close)
+		client = newClient(server.URL)
+	})
+
+	It("should not snapshot a vm of another machine", func() {
+		err := snapshot.NewService(&fakeScope{client: client, vmid: 100}).Reconcile(context.TODO())
+		Expect(err).To(MatchError(snapshot.ErrVMNotOwned))
+		Expect(requests).To(ConsistOf("GET /api2/json/cluster/resources"))
+	})
+
+	It("should leave snapshots of another machine alone on deletion", func() {
+		Expect(snapshot.NewService(&fakeScope{client: client, vmid: 100, snapshotName: "k8s-5c1e5f6e0bd4"}).Delete(context.TODO())).To(Succeed())
+		Expect(requests).To(ConsistOf("GET /api2/json/cluster/resources"))
+	})
+})
+
+var _ = Describe("createOrGetSnapshot", Label("unit", "snapshot"), func() {
+	var (
+		client   *proxmox.Service
+		requests []string
+	)
+
+	// vmid 100 of the machine has no snapshot
+	BeforeEach(func() {
+		requests = nil
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			switch r.URL.Path {
+			case "/api2/json/cluster/resources":
+				_, _ = w.Write([]byte(`{"data":[
+					{"type":"qemu","node":"pve1","vmid":100,"name":"md-0","status":"running","tags":"cappx;machine.default.md-0"}
+				]}`))
+			case "/api2/json/nodes":
+				_, _ = w.Write([]byte(`{"data":[{"node":"pve1","status":"online"}]}`))
+			case "/api2/json/nodes/pve1/qemu":
+				_, _ = w.Write([]byte(`{"data":[{"vmid":100,"name":"md-0","status":"running"}]}`))
+			case "/api2/json/nodes/pve1/qemu/100/snapshot":
+				_, _ = w.Write([]byte(`{"data":[{"name":"current","description":"You are here!"}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		DeferCleanup(server.Close)
+		client = newClient(server.URL)
+	})
+
+	It("should report a lost snapshot instead of taking it again", func() {
+		scope := &fakeScope{client: client, vmid: 100, snapshotName: "k8s-5c1e5f6e0bd4", snapshotVMID: ptr.To(100), creationTime: &metav1.Time{}, ready: true}
+		err := snapshot.NewService(scope).Reconcile(context.TODO())
+		Expect(err).To(MatchError(snapshot.ErrSnapshotLost))
+		Expect(scope.ready).To(BeFalse())
+		Expect(requests).NotTo(ContainElement(HavePrefix("POST")))
+	})
+
+	It("should refuse a snapshot of another vm of the machine", func() {
+		scope := &fakeScope{client: client, vmid: 100, snapshotName: "k8s-5c1e5f6e0bd4", snapshotVMID: ptr.To(101), creationTime: &metav1.Time{}, ready: true}
+		err := snapshot.NewService(scope).Reconcile(context.TODO())
+		Expect(err).To(MatchError(snapshot.ErrVMChanged))
+		Expect(scope.ready).To(BeFalse())
+		Expect(requests).NotTo(ContainElement(HavePrefix("POST")))
+	})
+})
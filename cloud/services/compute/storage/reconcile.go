@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/k8s-proxmox/proxmox-go/api"
 	"github.com/k8s-proxmox/proxmox-go/proxmox"
@@ -9,6 +10,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
 )
 
 const (
@@ -57,10 +59,10 @@ func (s *Service) getStorage(ctx context.Context, name string) error {
 }
 
 func (s *Service) createStorage(ctx context.Context, options api.StorageCreateOptions) error {
-	if _, err := s.client.CreateStorage(ctx, options.Storage, options.StorageType, options); err != nil {
+	return audit.Do(ctx, &s.client, http.MethodPost, "/storage", func() error {
+		_, err := s.client.CreateStorage(ctx, options.Storage, options.StorageType, options)
 		return err
-	}
-	return nil
+	})
 }
 
 func (s *Service) deleteStorage(ctx context.Context) error {
@@ -95,10 +97,9 @@ func (s *Service) deleteStorage(ctx context.Context) error {
 	}
 
 	// delete
-	if err := storage.Delete(ctx); err != nil {
-		return err
-	}
-	return nil
+	return audit.Do(ctx, &s.client, http.MethodDelete, "/storage/"+storage.Storage.Storage, func() error {
+		return storage.Delete(ctx)
+	})
 }
 
 func generateVMStorageOptions(scope Scope) api.StorageCreateOptions {
@@ -0,0 +1,37 @@
+package version
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// detects the version of Proxmox VE and reports whether it is supported
+func (s *Service) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	release, err := s.client.RESTClient().GetVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get proxmox version: %w", err)
+	}
+	if release.Version != s.scope.ProxmoxVersion() {
+		log.Info("detected proxmox version", "version", release.Version)
+	}
+	s.scope.SetProxmoxVersion(release.Version)
+
+	v, err := Parse(release.Version)
+	if err != nil {
+		s.scope.SetVersionUnsupported(err.Error())
+		return err
+	}
+	if err := v.Supported(); err != nil {
+		s.scope.SetVersionUnsupported(err.Error())
+		return err
+	}
+	s.scope.SetVersionSupported()
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version of Proxmox VE
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+var (
+	// MinimumSupported is the oldest version of Proxmox VE supported by cappx
+	MinimumSupported = Version{Major: 7}
+
+	// importing disks with import-from on creating qemus has been added in 7.2
+	importFromSince = Version{Major: 7, Minor: 2}
+)
+
+// Features of the Proxmox VE API available depending on its version
+type Features struct {
+	// ImportFrom is true if disks can be imported from images when creating qemus
+	ImportFrom bool
+}
+
+// Parse parses the version returned by GET /version. e.g. "8.1.4" or "7.4-3"
+func Parse(s string) (Version, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '-' })
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid proxmox version %q", s)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid proxmox version %q", s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less returns true if v is older than other
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// Supported returns an error if the version is older than the minimum supported one
+func (v Version) Supported() error {
+	if v.Less(MinimumSupported) {
+		return fmt.Errorf("proxmox version %s is not supported, %d.%d or later is required", v, MinimumSupported.Major, MinimumSupported.Minor)
+	}
+	return nil
+}
+
+// Features returns the features of the API available in the version
+func (v Version) Features() Features {
+	return Features{
+		ImportFrom: !v.Less(importFromSince),
+	}
+}
+
+// ImportFromRequired returns the error of creating a qemu from an image on a version without import-from
+func ImportFromRequired(v Version) error {
+	return fmt.Errorf("spec.image requires proxmox version %d.%d or later to import the image, found %s. use spec.restore instead", importFromSince.Major, importFromSince.Minor, v)
+}
@@ -0,0 +1,42 @@
+package version_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/version"
+)
+
+var _ = Describe("Parse", Label("unit", "version"), func() {
+	It("should parse versions of 8.x", func() {
+		Expect(version.Parse("8.1.4")).To(Equal(version.Version{Major: 8, Minor: 1, Patch: 4}))
+	})
+
+	It("should parse versions of 7.x", func() {
+		Expect(version.Parse("7.4-3")).To(Equal(version.Version{Major: 7, Minor: 4, Patch: 3}))
+		Expect(version.Parse("7.1")).To(Equal(version.Version{Major: 7, Minor: 1}))
+	})
+
+	It("should reject invalid versions", func() {
+		for _, s := range []string{"", "8", "8.x.1", "8.1.4.2", "v8.1"} {
+			_, err := version.Parse(s)
+			Expect(err).To(HaveOccurred(), s)
+		}
+	})
+})
+
+var _ = Describe("Supported", Label("unit", "version"), func() {
+	It("should require the minimum version", func() {
+		Expect(version.Version{Major: 6, Minor: 4, Patch: 13}.Supported()).To(MatchError(ContainSubstring("not supported")))
+		Expect(version.Version{Major: 7}.Supported()).To(Succeed())
+		Expect(version.Version{Major: 8, Minor: 2}.Supported()).To(Succeed())
+	})
+})
+
+var _ = Describe("Features", Label("unit", "version"), func() {
+	It("should enable import-from since 7.2", func() {
+		Expect(version.Version{Major: 7, Minor: 1, Patch: 10}.Features().ImportFrom).To(BeFalse())
+		Expect(version.Version{Major: 7, Minor: 2}.Features().ImportFrom).To(BeTrue())
+		Expect(version.Version{Major: 8, Minor: 1, Patch: 4}.Features().ImportFrom).To(BeTrue())
+	})
+})
@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/audit"
+)
+
+const (
+	// pseudo snapshot representing the current state of the vm
+	current = "current"
+)
+
+// Snapshot is a snapshot of a qemu
+type Snapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parent      string `json:"parent,omitempty"`
+	// unix time the snapshot was taken at
+	SnapTime int64 `json:"snaptime,omitempty"`
+	// 1 if ram is included
+	VMState int `json:"vmstate,omitempty"`
+}
+
+func path(vm *proxmox.VirtualMachine) string {
+	return fmt.Sprintf("/nodes/%s/qemu/%d/snapshot", vm.Node, vm.VM.VMID)
+}
+
+// List returns snapshots of the vm sorted by snaptime (oldest first)
+func List(ctx context.Context, client *proxmox.Service, vm *proxmox.VirtualMachine) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	if err := client.RESTClient().Get(ctx, path(vm), &snapshots); err != nil {
+		return nil, err
+	}
+	result := []Snapshot{}
+	for _, s := range snapshots {
+		if s.Name != current {
+			result = append(result, s)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].SnapTime < result[j].SnapTime })
+	return result, nil
+}
+
+// Create takes a disk snapshot of the vm and waits for the task
+func Create(ctx context.Context, client *proxmox.Service, vm *proxmox.VirtualMachine, name, description string) error {
+	request := map[string]interface{}{"snapname": name}
+	if description != "" {
+		request["description"] = description
+	}
+	var upid string
+	if err := audit.REST(client).Post(ctx, path(vm), request, &upid); err != nil {
+		return err
+	}
+	return client.EnsureTaskDone(ctx, vm.Node, upid)
+}
+
+// Delete deletes the snapshot of the vm and waits for the task
+func Delete(ctx context.Context, client *proxmox.Service, vm *proxmox.VirtualMachine, name string) error {
+	var upid string
+	if err := audit.REST(client).Delete(ctx, fmt.Sprintf("%s/%s", path(vm), url.PathEscape(name)), nil, &upid); err != nil {
+		return err
+	}
+	return client.EnsureTaskDone(ctx, vm.Node, upid)
+}
+
+// Rollback rolls the vm back to the snapshot and waits for the task.
+// the vm is stopped after rollback of a disk-only snapshot unless start is true
+func Rollback(ctx context.Context, client *proxmox.Service, vm *proxmox.VirtualMachine, name string, start bool) error {
+	request := map[string]interface{}{}
+	if start {
+		request["start"] = 1
+	}
+	var upid string
+	if err := audit.REST(client).Post(ctx, fmt.Sprintf("%s/%s/rollback", path(vm), url.PathEscape(name)), request, &upid); err != nil {
+		return err
+	}
+	return client.EnsureTaskDone(ctx, vm.Node, upid)
+}
+
+// Prune deletes the oldest snapshots having the prefix so that at most retain of them are left.
+// snapshots older than maxAge are deleted as well unless maxAge is 0
+func Prune(ctx context.Context, client *proxmox.Service, vm *proxmox.VirtualMachine, prefix string, retain int, maxAge time.Duration) error {
+	snapshots, err := List(ctx, client, vm)
+	if err != nil {
+		return err
+	}
+	deleted := map[string]bool{}
+	for _, s := range append(Outdated(snapshots, prefix, retain), Expired(snapshots, prefix, maxAge, time.Now())...) {
+		if deleted[s.Name] {
+			continue
+		}
+		if err := Delete(ctx, client, vm, s.Name); err != nil {
+			return err
+		}
+		deleted[s.Name] = true
+	}
+	return nil
+}
+
+// Outdated returns the oldest snapshots having the prefix exceeding retain.
+// snapshots must be sorted by snaptime
+func Outdated(snapshots []Snapshot, prefix string, retain int) []Snapshot {
+	matched := []Snapshot{}
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Name, prefix) {
+			matched = append(matched, s)
+		}
+	}
+	if len(matched) <= retain {
+		return nil
+	}
+	return matched[:len(matched)-retain]
+}
+
+// Expired returns snapshots having the prefix taken more than maxAge before now.
+// nothing is expired if maxAge is 0
+func Expired(snapshots []Snapshot, prefix string, maxAge time.Duration, now time.Time) []Snapshot {
+	if maxAge <= 0 {
+		return nil
+	}
+	expired := []Snapshot{}
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Name, prefix) && now.Sub(time.Unix(s.SnapTime, 0)) > maxAge {
+			expired = append(expired, s)
+		}
+	}
+	return expired
+}
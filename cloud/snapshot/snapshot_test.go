@@ -0,0 +1,55 @@
+package snapshot_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/snapshot"
+)
+
+func TestSnapshot(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Snapshot Suite")
+}
+
+var _ = Describe("Outdated", Label("unit", "snapshot"), func() {
+	snapshots := []snapshot.Snapshot{
+		{Name: "cappx-1", SnapTime: 1},
+		{Name: "manual", SnapTime: 2},
+		{Name: "cappx-2", SnapTime: 3},
+		{Name: "cappx-3", SnapTime: 4},
+	}
+
+	It("should return oldest snapshots having the prefix", func() {
+		Expect(snapshot.Outdated(snapshots, "cappx-", 1)).To(Equal([]snapshot.Snapshot{
+			{Name: "cappx-1", SnapTime: 1},
+			{Name: "cappx-2", SnapTime: 3},
+		}))
+	})
+
+	It("should return nothing within retention", func() {
+		Expect(snapshot.Outdated(snapshots, "cappx-", 3)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Expired", Label("unit", "snapshot"), func() {
+	snapshots := []snapshot.Snapshot{
+		{Name: "cappx-1", SnapTime: 100},
+		{Name: "manual", SnapTime: 100},
+		{Name: "cappx-2", SnapTime: 3600},
+	}
+	now := time.Unix(4000, 0)
+
+	It("should return snapshots having the prefix older than max age", func() {
+		Expect(snapshot.Expired(snapshots, "cappx-", time.Hour, now)).To(Equal([]snapshot.Snapshot{
+			{Name: "cappx-1", SnapTime: 100},
+		}))
+	})
+
+	It("should return nothing without max age", func() {
+		Expect(snapshot.Expired(snapshots, "cappx-", 0, now)).To(BeEmpty())
+	})
+})
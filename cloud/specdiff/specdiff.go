@@ -0,0 +1,115 @@
+// Package specdiff summarizes the differences of two specs field by field, e.g. to tell users
+// how a ProxmoxMachineTemplate has changed since a machine was created from it.
+package specdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// values longer than this are truncated in changes
+	maxValueLength = 40
+
+	unset = "<unset>"
+)
+
+// Diff returns the changes of the fields from old to new, sorted by path.
+// e.g. "hardware.memory: 4096 -> 8192"
+func Diff(old, new any) ([]string, error) {
+	o, err := normalize(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := normalize(new)
+	if err != nil {
+		return nil, err
+	}
+	changes := []string{}
+	walk("", o, n, &changes)
+	sort.Strings(changes)
+	return changes, nil
+}
+
+// Summary joins at most max changes. the number of the others is appended
+func Summary(changes []string, max int) string {
+	if len(changes) <= max {
+		return strings.Join(changes, "; ")
+	}
+	return fmt.Sprintf("%s; and %d more", strings.Join(changes[:max], "; "), len(changes)-max)
+}
+
+// returns the json representation of v, so that fields are compared by their json names
+func normalize(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func walk(path string, old, new any, changes *[]string) {
+	if reflect.DeepEqual(old, new) {
+		return
+	}
+	switch o := old.(type) {
+	case map[string]any:
+		if n, ok := new.(map[string]any); ok {
+			keys := map[string]struct{}{}
+			for k := range o {
+				keys[k] = struct{}{}
+			}
+			for k := range n {
+				keys[k] = struct{}{}
+			}
+			for k := range keys {
+				walk(join(path, k), o[k], n[k], changes)
+			}
+			return
+		}
+	case []any:
+		if n, ok := new.([]any); ok {
+			for i := 0; i < max(len(o), len(n)); i++ {
+				var oi, ni any
+				if i < len(o) {
+					oi = o[i]
+				}
+				if i < len(n) {
+					ni = n[i]
+				}
+				walk(fmt.Sprintf("%s[%d]", path, i), oi, ni, changes)
+			}
+			return
+		}
+	}
+	*changes = append(*changes, fmt.Sprintf("%s: %s -> %s", path, format(old), format(new)))
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func format(v any) string {
+	if v == nil {
+		return unset
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(b)
+	if len(s) > maxValueLength {
+		s = s[:maxValueLength-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package specdiff_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/specdiff"
+)
+
+func TestSpecDiff(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Spec Diff Suite")
+}
+
+var _ = Describe("Diff", Label("unit", "specdiff"), func() {
+	It("should report no changes of equal specs", func() {
+		spec := infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{CPU: 2, Memory: 4096}}
+		Expect(specdiff.Diff(spec, spec)).To(BeEmpty())
+	})
+
+	It("should report changed, added and removed fields by their json path", func() {
+		old := infrav1.ProxmoxMachineSpec{
+			Image:    &infrav1.Image{URL: "https://example.com/a.img"},
+			Hardware: infrav1.Hardware{CPU: 2, Memory: 4096, PCIDevices: []infrav1.PCIDevice{{Mapping: "gpu"}}},
+		}
+		new := infrav1.ProxmoxMachineSpec{
+			Image:    &infrav1.Image{URL: "https://example.com/b.img"},
+			Hardware: infrav1.Hardware{CPU: 2, Memory: 8192, RootDisk: "50G"},
+		}
+		Expect(specdiff.Diff(old, new)).To(Equal([]string{
+			`hardware.memory: 4096 -> 8192`,
+			`hardware.pciDevices: [{"mapping":"gpu"}] -> <unset>`,
+			`hardware.rootDisk: <unset> -> "50G"`,
+			`image.url: "https://example.com/a.img" -> "https://example.com/b.img"`,
+		}))
+	})
+
+	It("should compare list items by index", func() {
+		old := infrav1.Hardware{PCIDevices: []infrav1.PCIDevice{{Mapping: "gpu"}}}
+		new := infrav1.Hardware{PCIDevices: []infrav1.PCIDevice{{Mapping: "gpu", PCIe: true}, {Mapping: "nvme"}}}
+		Expect(specdiff.Diff(old, new)).To(Equal([]string{
+			`pciDevices[0].pcie: <unset> -> true`,
+			`pciDevices[1]: <unset> -> {"mapping":"nvme"}`,
+		}))
+	})
+})
+
+var _ = Describe("format", Label("unit", "specdiff"), func() {
+	It("should truncate long values", func() {
+		old := infrav1.Image{URL: "https://example.com/releases/noble/ubuntu-24.04-server-cloudimg-amd64.img"}
+		Expect(specdiff.Diff(old, infrav1.Image{})).To(Equal([]string{`url: "https://example.com/releases/noble/u... -> <unset>`}))
+	})
+})
+
+var _ = Describe("Summary", Label("unit", "specdiff"), func() {
+	It("should limit the number of changes", func() {
+		changes := []string{"a: 1 -> 2", "b: 1 -> 2", "c: 1 -> 2"}
+		Expect(specdiff.Summary(changes, 3)).To(Equal("a: 1 -> 2; b: 1 -> 2; c: 1 -> 2"))
+		Expect(specdiff.Summary(changes, 2)).To(Equal("a: 1 -> 2; b: 1 -> 2; and 1 more"))
+	})
+})
@@ -0,0 +1,50 @@
+package usage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	labels = []string{"namespace", "cluster"}
+
+	guestsGauge    = newGauge("guests", "Number of VMs and containers of a cluster")
+	cpuGauge       = newGauge("cpu_usage_cores", "CPU cores used by the guests of a cluster")
+	memoryGauge    = newGauge("memory_usage_bytes", "Memory used by the guests of a cluster")
+	diskGauge      = newGauge("disk_allocated_bytes", "Size of the disks of the guests of a cluster")
+	diskReadGauge  = newGauge("disk_read_bytes_per_second", "Disk read rate of the guests of a cluster")
+	diskWriteGauge = newGauge("disk_write_bytes_per_second", "Disk write rate of the guests of a cluster")
+	netInGauge     = newGauge("network_receive_bytes_per_second", "Network receive rate of the guests of a cluster")
+	netOutGauge    = newGauge("network_transmit_bytes_per_second", "Network transmit rate of the guests of a cluster")
+
+	gauges = []*prometheus.GaugeVec{guestsGauge, cpuGauge, memoryGauge, diskGauge, diskReadGauge, diskWriteGauge, netInGauge, netOutGauge}
+)
+
+func newGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: "cappx", Subsystem: "cluster", Name: name, Help: help}, labels)
+}
+
+func init() {
+	for _, g := range gauges {
+		metrics.Registry.MustRegister(g)
+	}
+}
+
+// Record sets the usage of the cluster to the gauges
+func Record(namespace, clusterName string, u Usage) {
+	guestsGauge.WithLabelValues(namespace, clusterName).Set(float64(u.Guests))
+	cpuGauge.WithLabelValues(namespace, clusterName).Set(u.CPU)
+	memoryGauge.WithLabelValues(namespace, clusterName).Set(u.Memory)
+	diskGauge.WithLabelValues(namespace, clusterName).Set(u.Disk)
+	diskReadGauge.WithLabelValues(namespace, clusterName).Set(u.DiskRead)
+	diskWriteGauge.WithLabelValues(namespace, clusterName).Set(u.DiskWrite)
+	netInGauge.WithLabelValues(namespace, clusterName).Set(u.NetIn)
+	netOutGauge.WithLabelValues(namespace, clusterName).Set(u.NetOut)
+}
+
+// Forget removes the cluster from the gauges
+func Forget(namespace, clusterName string) {
+	for _, g := range gauges {
+		g.DeleteLabelValues(namespace, clusterName)
+	}
+}
@@ -0,0 +1,100 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+)
+
+// Usage is the resource consumption of the guests of a cluster
+type Usage struct {
+	Guests int
+	// cores
+	CPU float64
+	// bytes
+	Memory float64
+	// bytes allocated to the disks. proxmox does not know how much of a qemu disk is used
+	Disk float64
+	// bytes per second
+	DiskRead  float64
+	DiskWrite float64
+	NetIn     float64
+	NetOut    float64
+}
+
+// entry of the rrd data of a guest. fields of minutes the guest was not running are null
+type rrdEntry struct {
+	CPU       *float64 `json:"cpu"`
+	MaxCPU    *float64 `json:"maxcpu"`
+	Mem       *float64 `json:"mem"`
+	DiskRead  *float64 `json:"diskread"`
+	DiskWrite *float64 `json:"diskwrite"`
+	NetIn     *float64 `json:"netin"`
+	NetOut    *float64 `json:"netout"`
+}
+
+// Guests returns the guests tagged with the cluster.
+// guests of a cluster of the same name in another namespace are skipped
+func Guests(guests []guest.Guest, namespace, clusterName string) []guest.Guest {
+	result := []guest.Guest{}
+	for _, g := range guests {
+		if !g.HasTag(guest.ManagedTag) || !g.HasTag(guest.ClusterTag(clusterName)) {
+			continue
+		}
+		if ns, ok := g.MachineNamespace(); ok && ns != namespace {
+			continue
+		}
+		result = append(result, g)
+	}
+	return result
+}
+
+// Collect sums the usage of the guests. the cpu, memory and io of running guests are
+// the averages of the last minute of their rrd data, so one request is sent per running guest
+func Collect(ctx context.Context, client *proxmox.Service, guests []guest.Guest) (Usage, error) {
+	usage := Usage{Guests: len(guests)}
+	for _, g := range guests {
+		usage.Disk += float64(g.MaxDisk)
+		if g.Status != api.ProcessStatusRunning {
+			continue
+		}
+		var entries []rrdEntry
+		path := fmt.Sprintf("/nodes/%s/%s/%d/rrddata?timeframe=hour&cf=AVERAGE", g.Node, g.Type, g.VMID)
+		if err := client.RESTClient().Get(ctx, path, &entries); err != nil {
+			return usage, fmt.Errorf("failed to get rrd data of %s %d: %w", g.Type, g.VMID, err)
+		}
+		usage.add(latest(entries))
+	}
+	return usage, nil
+}
+
+// returns the last entry having values. the last minute may not be consolidated yet
+func latest(entries []rrdEntry) rrdEntry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].CPU != nil {
+			return entries[i]
+		}
+	}
+	return rrdEntry{}
+}
+
+func (u *Usage) add(e rrdEntry) {
+	// cpu is the fraction of maxcpu in use
+	u.CPU += value(e.CPU) * value(e.MaxCPU)
+	u.Memory += value(e.Mem)
+	u.DiskRead += value(e.DiskRead)
+	u.DiskWrite += value(e.DiskWrite)
+	u.NetIn += value(e.NetIn)
+	u.NetOut += value(e.NetOut)
+}
+
+func value(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,81 @@
+package usage_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k8s-proxmox/proxmox-go/api"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/usage"
+)
+
+func TestUsage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Usage Suite")
+}
+
+var _ = Describe("Guests", Label("unit", "usage"), func() {
+	It("should return the guests of the cluster in the namespace", func() {
+		guests := []guest.Guest{
+			{VMID: 100, Tags: "cappx;cluster.foo;machine.default.foo-1"},
+			{VMID: 101, Tags: "cappx;cluster.foo"},
+			{VMID: 102, Tags: "cappx;cluster.foo;machine.other.foo-1"},
+			{VMID: 103, Tags: "cappx;cluster.bar;machine.default.bar-1"},
+			{VMID: 104, Tags: "cluster.foo"},
+		}
+		vmids := []int{}
+		for _, g := range usage.Guests(guests, "default", "foo") {
+			vmids = append(vmids, g.VMID)
+		}
+		Expect(vmids).To(Equal([]int{100, 101}))
+	})
+})
+
+var _ = Describe("Collect", Label("unit", "usage"), func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api2/json/nodes/pve1/qemu/100/rrddata":
+				// the last minute is not consolidated yet
+				_, _ = w.Write([]byte(`{"data":[
+					{"time":60,"cpu":0.1,"maxcpu":2,"mem":1000,"diskread":10,"diskwrite":20,"netin":30,"netout":40},
+					{"time":120,"cpu":0.5,"maxcpu":2,"mem":2000,"diskread":1,"diskwrite":2,"netin":3,"netout":4},
+					{"time":180}
+				]}`))
+			case "/api2/json/nodes/pve2/lxc/101/rrddata":
+				_, _ = w.Write([]byte(`{"data":[{"time":120,"cpu":0.25,"maxcpu":4,"mem":500,"diskread":1,"diskwrite":1,"netin":1,"netout":1}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should sum the last consolidated usage of running guests", func() {
+		params := proxmox.NewParams(server.URL+"/api2/json", proxmox.AuthConfig{TokenID: "cappx@pve!token", Secret: "secret"}, proxmox.ClientConfig{})
+		client, err := proxmox.GetOrCreateService(params)
+		Expect(err).NotTo(HaveOccurred())
+		guests := []guest.Guest{
+			{Type: guest.TypeQEMU, Node: "pve1", VMID: 100, Status: api.ProcessStatusRunning, MaxDisk: 10},
+			{Type: guest.TypeLXC, Node: "pve2", VMID: 101, Status: api.ProcessStatusRunning, MaxDisk: 20},
+			{Type: guest.TypeQEMU, Node: "pve1", VMID: 102, Status: api.ProcessStatusStopped, MaxDisk: 30},
+		}
+		u, err := usage.Collect(context.TODO(), client, guests)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u).To(Equal(usage.Usage{
+			Guests: 3, CPU: 2, Memory: 2500, Disk: 60,
+			DiskRead: 2, DiskWrite: 3, NetIn: 4, NetOut: 5,
+		}))
+	})
+})
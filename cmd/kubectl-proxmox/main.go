@@ -0,0 +1,391 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-proxmox is a kubectl plugin correlating ProxmoxMachines with the live state of their guests.
+//
+//	kubectl proxmox machines [-n namespace | -A] [--cluster name]
+//	kubectl proxmox tasks <proxmoxmachine> [-n namespace] [--limit 20]
+//	kubectl proxmox explain <proxmoxmachine> [-n namespace] [--plugin-config file]
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"github.com/go-logr/logr"
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/inspect"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+const usage = `kubectl proxmox inspects ProxmoxMachines together with the live state of their Proxmox guests.
+
+Usage:
+  kubectl proxmox machines [-n namespace | -A] [--cluster name] [-o wide]
+  kubectl proxmox tasks <proxmoxmachine> [-n namespace] [--limit 20]
+  kubectl proxmox explain <proxmoxmachine> [-n namespace] [--plugin-config file]
+
+Flags:
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(infrav1.AddToScheme(scheme))
+	utilruntime.Must(clusterv1.AddToScheme(scheme))
+}
+
+type options struct {
+	kubeconfig    string
+	context       string
+	namespace     string
+	allNamespaces bool
+	cluster       string
+	output        string
+	limit         int
+	pluginConfig  string
+}
+
+func main() {
+	opts := options{}
+	flags := pflag.NewFlagSet("kubectl-proxmox", pflag.ContinueOnError)
+	flags.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	flags.StringVar(&opts.context, "context", "", "Name of the kubeconfig context to use")
+	flags.StringVarP(&opts.namespace, "namespace", "n", "", "Namespace of the ProxmoxMachines. Defaults to the namespace of the context")
+	flags.BoolVarP(&opts.allNamespaces, "all-namespaces", "A", false, "List ProxmoxMachines of all namespaces")
+	flags.StringVar(&opts.cluster, "cluster", "", "Only list ProxmoxMachines of the Cluster")
+	flags.StringVarP(&opts.output, "output", "o", "", "Output format. wide adds the issues and console hints")
+	flags.IntVar(&opts.limit, "limit", 20, "Number of tasks to list")
+	flags.StringVar(&opts.pluginConfig, "plugin-config", "", "Path to the scheduler plugin config the controller runs with")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), opts, flags.Args(), os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("a command is required: machines, tasks or explain")
+	}
+	c, namespace, err := newClient(opts)
+	if err != nil {
+		return err
+	}
+	if opts.namespace == "" {
+		opts.namespace = namespace
+	}
+
+	switch command, args := args[0], args[1:]; command {
+	case "machines", "machine", "ms":
+		return machines(ctx, c, opts, out)
+	case "tasks":
+		if len(args) != 1 {
+			return errors.New("tasks requires the name of a ProxmoxMachine")
+		}
+		return tasks(ctx, c, opts, args[0], out)
+	case "explain":
+		if len(args) != 1 {
+			return errors.New("explain requires the name of a ProxmoxMachine")
+		}
+		return explain(ctx, c, opts, args[0], out)
+	default:
+		return fmt.Errorf("unknown command %q: use machines, tasks or explain", command)
+	}
+}
+
+// returns the client and the namespace of the kubeconfig context
+func newClient(opts options) (client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.kubeconfig
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: opts.context})
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	namespace, _, err := config.Namespace()
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	return c, namespace, err
+}
+
+// lists the ProxmoxMachines with the guests found for them. proxmox is asked once per cluster
+func machines(ctx context.Context, c client.Client, opts options, out io.Writer) error {
+	list := &infrav1.ProxmoxMachineList{}
+	listOpts := []client.ListOption{}
+	if !opts.allNamespaces {
+		listOpts = append(listOpts, client.InNamespace(opts.namespace))
+	}
+	if opts.cluster != "" {
+		listOpts = append(listOpts, client.MatchingLabels{clusterv1.ClusterNameLabel: opts.cluster})
+	}
+	if err := c.List(ctx, list, listOpts...); err != nil {
+		return err
+	}
+
+	// machines grouped by namespace/cluster
+	groups := map[client.ObjectKey][]infrav1.ProxmoxMachine{}
+	for _, m := range list.Items {
+		key := client.ObjectKey{Namespace: m.Namespace, Name: m.Labels[clusterv1.ClusterNameLabel]}
+		groups[key] = append(groups[key], m)
+	}
+	keys := make([]client.ObjectKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	header := "NAMESPACE\tNAME\tCLUSTER\tVMID\tNODE\tSTATUS\tVM NAME"
+	if opts.output == "wide" {
+		header += "\tCONSOLE\tISSUES"
+	}
+	fmt.Fprintln(w, header)
+	for _, key := range keys {
+		var guests []guest.Guest
+		clusterScope, err := newClusterScope(ctx, c, key)
+		if err == nil {
+			guests, err = guest.List(ctx, clusterScope.CloudClient())
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to list vms of cluster %s: %v\n", key, err)
+		}
+		for _, state := range inspect.Correlate(groups[key], guests) {
+			name := "<none>"
+			if state.Guest != nil {
+				name = state.Guest.Name
+			}
+			vmid := "<none>"
+			if state.VMID() != 0 {
+				vmid = fmt.Sprint(state.VMID())
+			}
+			row := []string{state.Machine.Namespace, state.Machine.Name, state.Cluster(), vmid, state.Node(), state.Status(), name}
+			if opts.output == "wide" {
+				issues := "<none>"
+				if len(state.Issues) != 0 {
+					issues = strings.Join(state.Issues, "; ")
+				}
+				row = append(row, state.ConsoleHint(), issues)
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+	}
+	return w.Flush()
+}
+
+// lists the latest tasks of the guest of the ProxmoxMachine, newest first
+func tasks(ctx context.Context, c client.Client, opts options, name string, out io.Writer) error {
+	proxmoxMachine, clusterScope, err := getMachine(ctx, c, opts.namespace, name)
+	if err != nil {
+		return err
+	}
+	state, err := machineState(ctx, clusterScope.CloudClient(), *proxmoxMachine)
+	if err != nil {
+		return err
+	}
+	if state.Guest == nil {
+		return fmt.Errorf("no vm is found for ProxmoxMachine %s", name)
+	}
+	list, err := inspect.Tasks(ctx, clusterScope.CloudClient(), state.Guest.Node, state.Guest.VMID, opts.limit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "VM %s (%d) on node %s is %s\n", state.Guest.Name, state.Guest.VMID, state.Guest.Node, state.Guest.Status)
+	fmt.Fprintf(out, "Console: %s\n\n", state.ConsoleHint())
+	now := time.Now()
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tTYPE\tUSER\tDURATION\tRESULT")
+	for _, t := range list {
+		started := time.Unix(t.StartTime, 0).Format(time.DateTime)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", started, t.Type, t.User, t.Duration(now), t.Result())
+	}
+	return w.Flush()
+}
+
+// runs the scheduler for the ProxmoxMachine the way dry-run mode does, without creating anything
+func explain(ctx context.Context, c client.Client, opts options, name string, out io.Writer) error {
+	proxmoxMachine, clusterScope, err := getMachine(ctx, c, opts.namespace, name)
+	if err != nil {
+		return err
+	}
+	machine, err := util.GetOwnerMachine(ctx, c, proxmoxMachine.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	if machine == nil {
+		return fmt.Errorf("ProxmoxMachine %s has no owner Machine yet", name)
+	}
+	manager, err := scheduler.NewManager(scheduler.SchedulerParams{Logger: logr.Discard(), PluginConfigFile: opts.pluginConfig})
+	if err != nil {
+		return err
+	}
+	cordoned, err := cordonedNodes(ctx, c, clusterScope.Cluster)
+	if err != nil {
+		return err
+	}
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:           c,
+		Machine:          machine,
+		ProxmoxMachine:   proxmoxMachine,
+		ClusterGetter:    clusterScope,
+		SchedulerManager: manager,
+		CordonedNodes:    cordoned,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the scope is never closed, so that nothing is written back to the ProxmoxMachine
+	plan, err := instance.NewService(machineScope).Plan(ctx)
+	if err != nil {
+		if plan.Action == "" {
+			return err
+		}
+		plan.Error = err.Error()
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Action:\t%s\n", plan.Action)
+	fmt.Fprintf(w, "Type:\t%s\n", plan.Type)
+	if plan.Action == infrav1.PlanActionNone {
+		fmt.Fprintf(w, "Node:\t%s\n", plan.Node)
+		fmt.Fprintf(w, "VMID:\t%d\n", plan.VMID)
+		fmt.Fprintln(w, "Note:\tthe instance exists and is not scheduled again")
+		return w.Flush()
+	}
+	if len(cordoned) != 0 {
+		fmt.Fprintf(w, "Cordoned nodes:\t%s\n", strings.Join(cordoned, ", "))
+	}
+	if plan.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", plan.Error)
+		return w.Flush()
+	}
+	fmt.Fprintf(w, "Node:\t%s\n", plan.Node)
+	fmt.Fprintf(w, "VMID:\t%d\n", plan.VMID)
+	fmt.Fprintf(w, "Storage:\t%s\n", plan.Storage)
+	fmt.Fprintf(w, "Source:\t%s\n", plan.Source)
+	for i, disk := range plan.Disks {
+		label := ""
+		if i == 0 {
+			label = "Disks:"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", label, disk)
+	}
+	return w.Flush()
+}
+
+// returns the ProxmoxMachine and the scope of its cluster
+func getMachine(ctx context.Context, c client.Client, namespace, name string) (*infrav1.ProxmoxMachine, *scope.ClusterScope, error) {
+	proxmoxMachine := &infrav1.ProxmoxMachine{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, proxmoxMachine); err != nil {
+		return nil, nil, err
+	}
+	clusterName, ok := proxmoxMachine.Labels[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil, nil, fmt.Errorf("ProxmoxMachine %s has no %s label", name, clusterv1.ClusterNameLabel)
+	}
+	clusterScope, err := newClusterScope(ctx, c, client.ObjectKey{Namespace: namespace, Name: clusterName})
+	if err != nil {
+		return nil, nil, err
+	}
+	return proxmoxMachine, clusterScope, nil
+}
+
+// returns the scope of the cluster, reading the proxmox credentials the way the controller does
+// but leaving the credentials secret as it is
+func newClusterScope(ctx context.Context, c client.Client, key client.ObjectKey) (*scope.ClusterScope, error) {
+	cluster := &clusterv1.Cluster{}
+	if err := c.Get(ctx, key, cluster); err != nil {
+		return nil, err
+	}
+	if cluster.Spec.InfrastructureRef == nil {
+		return nil, fmt.Errorf("Cluster %s has no infrastructureRef", key)
+	}
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: key.Namespace, Name: cluster.Spec.InfrastructureRef.Name}, proxmoxCluster); err != nil {
+		return nil, err
+	}
+	compute, err := scope.NewComputeService(ctx, proxmoxCluster, c)
+	if err != nil {
+		return nil, err
+	}
+	return scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		ProxmoxServices: scope.ProxmoxServices{Compute: compute},
+		Client:          c,
+		Cluster:         cluster,
+		ProxmoxCluster:  proxmoxCluster,
+	})
+}
+
+func machineState(ctx context.Context, proxmoxClient *proxmox.Service, proxmoxMachine infrav1.ProxmoxMachine) (inspect.MachineState, error) {
+	guests, err := guest.List(ctx, proxmoxClient)
+	if err != nil {
+		return inspect.MachineState{}, err
+	}
+	return inspect.Correlate([]infrav1.ProxmoxMachine{proxmoxMachine}, guests)[0], nil
+}
+
+// nodes under maintenance for the cluster, which the scheduler of the controller skips
+func cordonedNodes(ctx context.Context, c client.Client, cluster *clusterv1.Cluster) ([]string, error) {
+	list := &infrav1.ProxmoxNodeMaintenanceList{}
+	if err := c.List(ctx, list, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, err
+	}
+	nodes := []string{}
+	for _, m := range list.Items {
+		if m.Spec.ClusterName == cluster.Name && m.DeletionTimestamp.IsZero() {
+			nodes = append(nodes, m.Spec.NodeName)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
@@ -25,6 +25,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -33,13 +34,20 @@ import (
 	logsv1 "k8s.io/component-base/logs/api/v1"
 	"k8s.io/klog/v2"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/exp/runtime/server"
 	"sigs.k8s.io/cluster-api/util/flags"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
 	infrastructurev1beta1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
 	controller "github.com/k8s-proxmox/cluster-api-provider-proxmox/controllers"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/extension"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/feature"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/logging"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -52,6 +60,8 @@ var (
 	enableLeaderElection bool
 	probeAddr            string
 	pluginConfig         string
+	extensionPort        int
+	extensionCertDir     string
 	logOptions           = logs.NewOptions()
 )
 
@@ -88,6 +98,12 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "36404136.cluster.x-k8s.io",
+		// secrets are read from the api server, so that the manager does not cache every secret of the cluster
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Secret{}},
+			},
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -106,7 +122,7 @@ func main() {
 	}
 	schedManager, err := scheduler.NewManager(
 		scheduler.SchedulerParams{
-			Logger:           klog.Background(),
+			Logger:           logging.Logger(klog.Background(), logging.Scheduler),
 			PluginConfigFile: pluginConfig,
 		},
 	)
@@ -123,6 +139,13 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxMachine")
 		os.Exit(1)
 	}
+	if err = (&controller.ProxmoxMachineTemplateReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxMachineTemplate")
+		os.Exit(1)
+	}
 	if err = (&controller.ProxmoxClusterReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -130,8 +153,74 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxCluster")
 		os.Exit(1)
 	}
+	if err = (&controller.ProxmoxSnapshotReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxSnapshot")
+		os.Exit(1)
+	}
+	if err = (&controller.ProxmoxBackupPolicyReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxBackupPolicy")
+		os.Exit(1)
+	}
+	if err = (&controller.ProxmoxNodeMaintenanceReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxNodeMaintenance")
+		os.Exit(1)
+	}
+	if err = (&controller.ProxmoxClusterOrphanReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxClusterOrphan")
+		os.Exit(1)
+	}
+	if err = (&controller.ProxmoxClusterUsageReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxClusterUsage")
+		os.Exit(1)
+	}
+	if err = (&controller.ProxmoxClusterAdoptionReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxmoxClusterAdoption")
+		os.Exit(1)
+	}
+	if feature.Gates.Enabled(feature.ClusterRebalancer) {
+		if err = (&controller.ProxmoxClusterRebalanceReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ProxmoxClusterRebalance")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
+	if feature.Gates.Enabled(feature.RuntimeExtension) {
+		extensionServer, err := extension.NewServer(mgr.GetScheme(), server.Options{
+			Port:    extensionPort,
+			CertDir: extensionCertDir,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create runtime extension server")
+			os.Exit(1)
+		}
+		if err := mgr.Add(extensionServer); err != nil {
+			setupLog.Error(err, "unable to set up runtime extension server")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -140,6 +229,19 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	proxmoxChecker := scope.NewProxmoxChecker(mgr.GetAPIReader())
+	if err := mgr.Add(proxmoxChecker); err != nil {
+		setupLog.Error(err, "unable to set up proxmox connectivity check")
+		os.Exit(1)
+	}
+	if err := mgr.AddHealthzCheck("proxmox", proxmoxChecker.Check); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("proxmox", proxmoxChecker.Check); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -150,12 +252,21 @@ func main() {
 
 func InitFlags(fs *pflag.FlagSet) {
 	logsv1.AddFlags(logOptions, fs)
+	logging.AddFlags(fs)
 
 	fs.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	fs.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	fs.StringVar(&pluginConfig, "scheduler-plugin-config", "", "The config file path for qemu-scheduler plugins")
+	fs.IntVar(&extensionPort, "runtime-extension-port", 9443,
+		"The port the Runtime Extension server binds to. Requires the RuntimeExtension feature gate.")
+	fs.StringVar(&extensionCertDir, "runtime-extension-cert-dir", "",
+		"The directory of tls.crt and tls.key of the Runtime Extension server. Defaults to {TempDir}/k8s-webhook-server/serving-certs.")
+	fs.StringSliceVar(&instance.ImageImportDirs, "image-import-dirs", instance.ImageImportDirs,
+		"Comma-separated directories of the Proxmox nodes images of sourceType NodeLocalPath may be imported from.")
+
+	feature.MutableGates.AddFlag(fs)
 
 	flags.AddManagerOptions(fs, &managerOptions)
 }
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// reason of events describing what cappx would do in dry-run mode
+const reasonDryRun = "DryRun"
+
+// how often an object left alone in dry-run mode is checked again. removing the annotation from
+// the ProxmoxCluster does not trigger the reconcile of its snapshots, backup policies and maintenances
+const dryRunRequeueInterval = time.Minute
+
+// leaves the object alone while it or its ProxmoxCluster is in dry-run mode. its finalizer is kept
+// so that the resources on proxmox are cleaned up once the annotation is removed
+func skipDryRun(ctx context.Context, o client.Object, kind string) ctrl.Result {
+	verb := "reconcile"
+	if !o.GetDeletionTimestamp().IsZero() {
+		verb = "delete"
+	}
+	log.FromContext(ctx).Info(fmt.Sprintf("%s or linked ProxmoxCluster is in dry-run mode. Won't %s", kind, verb))
+	record.Eventf(o, reasonDryRun, "Would %s %s %s", verb, kind, o.GetName())
+	return ctrl.Result{RequeueAfter: dryRunRequeueInterval}
+}
+
+// returns true if any of the objects has the dry-run annotation
+func isDryRun(objects ...metav1.Object) bool {
+	for _, o := range objects {
+		if _, ok := o.GetAnnotations()[infrav1.DryRunAnnotation]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// e.g. "Would create qemu on node pve1 with vmid 100 and storage local-lvm from https://.../jammy.img. disks: ide2=..., scsi0=..."
+func planMessage(plan infrav1.Plan) string {
+	if plan.Error != "" {
+		return fmt.Sprintf("Would fail to %s %s: %s", strings.ToLower(string(plan.Action)), plan.Type, plan.Error)
+	}
+	switch plan.Action {
+	case infrav1.PlanActionCreate, infrav1.PlanActionRestore:
+		msg := fmt.Sprintf("Would %s %s on node %s with vmid %d and storage %s from %s",
+			strings.ToLower(string(plan.Action)), plan.Type, plan.Node, plan.VMID, plan.Storage, plan.Source)
+		if len(plan.Disks) == 0 {
+			return msg
+		}
+		return fmt.Sprintf("%s. disks: %s", msg, strings.Join(plan.Disks, ", "))
+	case infrav1.PlanActionDelete:
+		return fmt.Sprintf("Would delete %s %d on node %s", plan.Type, plan.VMID, plan.Node)
+	default:
+		if plan.VMID == 0 {
+			return fmt.Sprintf("No %s exists, nothing would be done", plan.Type)
+		}
+		return fmt.Sprintf("%s %d exists on node %s, nothing would be done", plan.Type, plan.VMID, plan.Node)
+	}
+}
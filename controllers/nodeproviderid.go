@@ -0,0 +1,135 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/providerid"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+)
+
+// interval of looking up the node of a running machine which Cluster API has not bound yet
+const nodeMatchInterval = 30 * time.Second
+
+// the kubelet taints nodes with it until a cloud-controller-manager has initialized them
+const uninitializedTaint = "node.cloudprovider.kubernetes.io/uninitialized"
+
+// reconcileNodeProviderID helps Cluster API bind the node of a running machine, which it looks up by provider id.
+// a cloud-controller-manager may set a provider id of another format on the node. it is adopted if it refers to
+// the guest of the machine. nodes the kubelet has left without provider id get the one of the machine.
+// returns true while the node is not bound yet
+func (r *ProxmoxMachineReconciler) reconcileNodeProviderID(ctx context.Context, machineScope *scope.MachineScope) (bool, error) {
+	log := log.FromContext(ctx)
+	cluster := machineScope.ClusterGetter.Cluster
+	if machineScope.Bootstrapped() || machineScope.GetProviderID() == "" {
+		return false, nil
+	}
+	// the control plane of adopted machines and external control planes are running already, but
+	// Cluster API only tells it initialized once the node of a control plane machine is bound
+	_, adopted := machineScope.ProxmoxMachine.Annotations[infrav1.AdoptedAnnotation]
+	external := machineScope.ClusterGetter.ExternalControlPlane()
+	if !adopted && !external && !conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
+		return true, nil
+	}
+	workload, err := remote.NewClusterClient(ctx, "cappx", r.Client, util.ObjectKey(cluster))
+	if err != nil {
+		return true, err
+	}
+	nodes := &corev1.NodeList{}
+	if err := workload.List(ctx, nodes); err != nil {
+		return true, err
+	}
+	node := matchNode(nodes.Items, machineScope.GetBiosUUID(), machineScope.Name(), machineScope.MachineName())
+	if node == nil || node.Spec.ProviderID == machineScope.GetProviderID() {
+		return true, nil
+	}
+
+	if node.Spec.ProviderID == "" {
+		if hasTaint(node, uninitializedTaint) {
+			log.V(3).Info("waiting for the cloud-controller-manager to initialize the node", "node", node.Name)
+			return true, nil
+		}
+		log.Info("setting the provider id of the node", "node", node.Name, "providerID", machineScope.GetProviderID())
+		patch := client.MergeFrom(node.DeepCopy())
+		node.Spec.ProviderID = machineScope.GetProviderID()
+		return true, workload.Patch(ctx, node, patch)
+	}
+
+	theirs, err := providerid.Parse(node.Spec.ProviderID)
+	if err != nil {
+		record.Warnf(machineScope.ProxmoxMachine, "ProviderIDMismatch", "Node %s has provider id %s which is not the one of a Proxmox guest: %v", node.Name, node.Spec.ProviderID, err)
+		return true, nil
+	}
+	ours, err := providerid.Parse(machineScope.GetProviderID())
+	if err != nil {
+		return true, err
+	}
+	vmid := 0
+	if machineScope.GetVMID() != nil {
+		vmid = *machineScope.GetVMID()
+	}
+	if !providerid.Matches(ours, theirs, vmid) {
+		record.Warnf(machineScope.ProxmoxMachine, "ProviderIDMismatch", "Node %s has provider id %s of another guest than %s", node.Name, node.Spec.ProviderID, machineScope.GetProviderID())
+		return true, nil
+	}
+	log.Info("adopting the provider id of the node", "node", node.Name, "providerID", node.Spec.ProviderID)
+	record.Eventf(machineScope.ProxmoxMachine, "ProviderIDAdopted", "Adopted provider id %s of node %s", node.Spec.ProviderID, node.Name)
+	machineScope.AdoptProviderID(node.Spec.ProviderID)
+	return true, nil
+}
+
+// returns the node of the guest. nodes are matched by the system uuid the kubelet reports,
+// which is the smbios uuid of qemus, or else by name
+func matchNode(nodes []corev1.Node, uuid *string, names ...string) *corev1.Node {
+	if uuid != nil {
+		for i := range nodes {
+			if strings.EqualFold(nodes[i].Status.NodeInfo.SystemUUID, *uuid) {
+				return &nodes[i]
+			}
+		}
+	}
+	for i := range nodes {
+		for _, name := range names {
+			if nodes[i].Name == name {
+				return &nodes[i]
+			}
+		}
+	}
+	return nil
+}
+
+func hasTaint(node *corev1.Node, key string) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == key {
+			return true
+		}
+	}
+	return false
+}
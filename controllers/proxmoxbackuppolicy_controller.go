@@ -0,0 +1,249 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/backup"
+)
+
+// interval to check expired backups of a policy with retention.maxAge
+const backupPruneInterval = time.Hour
+
+// ProxmoxBackupPolicyReconciler reconciles a ProxmoxBackupPolicy object
+type ProxmoxBackupPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxbackuppolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxbackuppolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxbackuppolicies/finalizers,verbs=update
+
+func (r *ProxmoxBackupPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+
+	policy := &infrav1.ProxmoxBackupPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, policy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	cluster := &clusterv1.Cluster{}
+	clusterKey := client.ObjectKey{Namespace: policy.Namespace, Name: policy.Spec.ClusterName}
+	if err := r.Get(ctx, clusterKey, cluster); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if !policy.DeletionTimestamp.IsZero() {
+			// no way to reach proxmox without the cluster
+			log.Info("Cluster is already deleted. backup job is left as is")
+			return r.removeFinalizer(ctx, policy)
+		}
+		log.Info("Cluster is not found", "cluster", clusterKey.Name)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	if annotations.IsPaused(cluster, policy) {
+		log.Info("ProxmoxBackupPolicy or linked Cluster is marked as paused. Won't reconcile")
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name)
+	if cluster.Spec.InfrastructureRef == nil {
+		log.Info("Cluster does not have infrastructureRef yet")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	proxmoxClusterKey := client.ObjectKey{
+		Namespace: policy.Namespace,
+		Name:      cluster.Spec.InfrastructureRef.Name,
+	}
+	if err := r.Client.Get(ctx, proxmoxClusterKey, proxmoxCluster); err != nil {
+		log.Info("ProxmoxCluster is not available yet")
+		return ctrl.Result{}, nil
+	}
+	if isDryRun(proxmoxCluster, policy) {
+		return skipDryRun(ctx, policy, "ProxmoxBackupPolicy"), nil
+	}
+
+	// Create the cluster scope
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		Client:         r.Client,
+		Cluster:        cluster,
+		ProxmoxCluster: proxmoxCluster,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	vmids, err := r.selectVMIDs(ctx, policy)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Create the backup policy scope
+	policyScope, err := scope.NewBackupPolicyScope(scope.BackupPolicyScopeParams{
+		Client:              r.Client,
+		ProxmoxBackupPolicy: policy,
+		ClusterGetter:       clusterScope,
+		VMIDs:               vmids,
+	})
+	if err != nil {
+		return ctrl.Result{}, errors.Errorf("failed to create scope: %+v", err)
+	}
+
+	// Always close the scope when exiting this function so we can persist any ProxmoxBackupPolicy changes.
+	defer func() {
+		if err := policyScope.Close(); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	if !policy.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, policyScope)
+	}
+
+	return r.reconcile(ctx, policyScope)
+}
+
+func (r *ProxmoxBackupPolicyReconciler) reconcile(ctx context.Context, policyScope *scope.BackupPolicyScope) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling ProxmoxBackupPolicy")
+
+	if ok := controllerutil.AddFinalizer(policyScope.ProxmoxBackupPolicy, infrav1.BackupPolicyFinalizer); ok {
+		log.Info("update finalizer to ProxmoxBackupPolicy")
+	}
+	if err := policyScope.PatchObject(); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := backup.NewService(policyScope).Reconcile(ctx); err != nil {
+		log.Error(err, "Reconcile error")
+		policyScope.SetFailureMessage(err)
+		record.Warnf(policyScope.ProxmoxBackupPolicy, "ProxmoxBackupPolicyReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+
+	log.Info("Reconciled ProxmoxBackupPolicy")
+	record.Event(policyScope.ProxmoxBackupPolicy, "ProxmoxBackupPolicyReconcile", "Reconciled")
+	if retention := policyScope.ProxmoxBackupPolicy.Spec.Retention; retention != nil && retention.MaxAge != nil {
+		// backups expire without any change of the cluster
+		return ctrl.Result{RequeueAfter: backupPruneInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ProxmoxBackupPolicyReconciler) reconcileDelete(ctx context.Context, policyScope *scope.BackupPolicyScope) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling Delete ProxmoxBackupPolicy")
+
+	if err := backup.NewService(policyScope).Delete(ctx); err != nil {
+		log.Error(err, "Reconcile error")
+		record.Warnf(policyScope.ProxmoxBackupPolicy, "ProxmoxBackupPolicyReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+
+	log.Info("Reconciled ProxmoxBackupPolicy")
+	controllerutil.RemoveFinalizer(policyScope.ProxmoxBackupPolicy, infrav1.BackupPolicyFinalizer)
+	record.Event(policyScope.ProxmoxBackupPolicy, "ProxmoxBackupPolicyReconcile", "Reconciled")
+	return ctrl.Result{}, nil
+}
+
+func (r *ProxmoxBackupPolicyReconciler) removeFinalizer(ctx context.Context, policy *infrav1.ProxmoxBackupPolicy) (ctrl.Result, error) {
+	patch := client.MergeFrom(policy.DeepCopy())
+	controllerutil.RemoveFinalizer(policy, infrav1.BackupPolicyFinalizer)
+	return ctrl.Result{}, r.Patch(ctx, policy, patch)
+}
+
+// returns sorted vmids of the ProxmoxMachines selected by the policy
+func (r *ProxmoxBackupPolicyReconciler) selectVMIDs(ctx context.Context, policy *infrav1.ProxmoxBackupPolicy) ([]int, error) {
+	selector := labels.Everything()
+	if policy.Spec.Selector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(policy.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	machines := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, machines,
+		client.InNamespace(policy.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: policy.Spec.ClusterName},
+	); err != nil {
+		return nil, err
+	}
+	vmids := []int{}
+	for _, m := range machines.Items {
+		if m.Spec.VMID == nil || !m.DeletionTimestamp.IsZero() || !selector.Matches(labels.Set(m.Labels)) {
+			continue
+		}
+		vmids = append(vmids, *m.Spec.VMID)
+	}
+	sort.Ints(vmids)
+	return vmids, nil
+}
+
+// returns requests for the policies of the cluster the ProxmoxMachine belongs to
+func (r *ProxmoxBackupPolicyReconciler) proxmoxMachineToPolicies(ctx context.Context, o client.Object) []reconcile.Request {
+	clusterName, ok := o.GetLabels()[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil
+	}
+	policies := &infrav1.ProxmoxBackupPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, p := range policies.Items {
+		if p.Spec.ClusterName == clusterName {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: p.Namespace, Name: p.Name}})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProxmoxBackupPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1.ProxmoxBackupPolicy{}).
+		Watches(&infrav1.ProxmoxMachine{}, handler.EnqueueRequestsFromMapFunc(r.proxmoxMachineToPolicies)).
+		Complete(r)
+}
@@ -22,20 +22,32 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/cluster-api/util"
-	capiannotations "sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/firewall"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/nodehealth"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/pool"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/quorum"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/storage"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/version"
 )
 
 // ProxmoxClusterReconciler reconciles a ProxmoxCluster object
@@ -44,11 +56,22 @@ type ProxmoxClusterReconciler struct {
 	Scheme *runtime.Scheme
 }
 
+const (
+	// interval to check node status and quorum of the proxmox cluster
+	nodeHealthInterval = time.Minute
+
+	// interval to check quorum again while operations are paused
+	quorumCheckInterval = 30 * time.Second
+
+	// reason of the event recorded while operations are paused by lost quorum
+	reasonQuorumLost = "QuorumLost"
+)
+
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters/finalizers,verbs=update
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch
 
 func (r *ProxmoxClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	log := log.FromContext(ctx)
@@ -75,7 +98,11 @@ func (r *ProxmoxClusterReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, nil
 	}
 
-	if capiannotations.IsPaused(cluster, proxmoxCluster) {
+	paused, err := scope.EnsurePausedCondition(ctx, r.Client, cluster, proxmoxCluster, "ProxmoxCluster")
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if paused {
 		log.Info("ProxmoxCluster or linked Cluster is marked as paused. Won't reconcile")
 		return ctrl.Result{}, nil
 	}
@@ -114,8 +141,26 @@ func (r *ProxmoxClusterReconciler) reconcile(ctx context.Context, clusterScope *
 		return ctrl.Result{}, err
 	}
 
+	if result, paused, err := r.checkQuorum(ctx, clusterScope); paused || err != nil {
+		return result, err
+	}
+
 	reconcilers := []cloud.Reconciler{
+		// the version is checked first so that an unsupported one is reported instead of parameter errors
+		version.NewService(clusterScope),
 		storage.NewService(clusterScope),
+		pool.NewService(clusterScope),
+		firewall.NewService(clusterScope),
+		nodehealth.NewService(clusterScope),
+	}
+	if isDryRun(clusterScope.ProxmoxCluster) {
+		// the cluster becomes ready without the snippet storage, the pool and the firewall
+		// so that cluster api creates the machines to be planned
+		record.Event(clusterScope.ProxmoxCluster, reasonDryRun, clusterPlanMessage("reconcile", clusterScope))
+		reconcilers = []cloud.Reconciler{
+			version.NewService(clusterScope),
+			nodehealth.NewService(clusterScope),
+		}
 	}
 
 	for _, r := range reconcilers {
@@ -126,10 +171,28 @@ func (r *ProxmoxClusterReconciler) reconcile(ctx context.Context, clusterScope *
 		}
 	}
 
+	// the key pair is set before the cluster gets ready, so that every machine authorizes it
+	if err := clusterScope.ReconcileSSHKeyPair(ctx); err != nil {
+		log.Error(err, "Reconcile error")
+		record.Warnf(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+
+	// nothing provisions the endpoint of an external control plane, it is read from its kubeconfig
+	if err := clusterScope.ReconcileExternalControlPlaneEndpoint(ctx); err != nil {
+		log.Error(err, "Reconcile error")
+		record.Warnf(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
 	controlPlaneEndpoint := clusterScope.ControlPlaneEndpoint()
 	if controlPlaneEndpoint.Host == "" {
 		log.Info("ProxmoxCluster does not have control-plane endpoint yet. Reconciling")
-		record.Event(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Waiting for control-plane endpoint")
+		if clusterScope.ExternalControlPlane() {
+			record.Event(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Waiting for the kubeconfig Secret of the external control plane")
+		} else {
+			record.Event(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Waiting for control-plane endpoint")
+		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
@@ -137,14 +200,42 @@ func (r *ProxmoxClusterReconciler) reconcile(ctx context.Context, clusterScope *
 	record.Eventf(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Got control-plane endpoint - %s", controlPlaneEndpoint.Host)
 	clusterScope.SetReady()
 	record.Event(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Reconciled")
-	return ctrl.Result{}, nil
+	// keep watching node status and quorum
+	return ctrl.Result{RequeueAfter: nodeHealthInterval}, nil
+}
+
+// pauses the reconcile while the proxmox cluster is not quorate, so that no storm of
+// failing tasks is generated. it resumes once quorum has returned
+func (r *ProxmoxClusterReconciler) checkQuorum(ctx context.Context, clusterScope *scope.ClusterScope) (ctrl.Result, bool, error) {
+	if err := quorum.NewService(clusterScope).Reconcile(ctx); err != nil {
+		log.FromContext(ctx).Error(err, "Reconcile error")
+		record.Warnf(clusterScope.ProxmoxCluster, "ProxmoxClusterReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, false, err
+	}
+	if clusterScope.Quorate() {
+		return ctrl.Result{}, false, nil
+	}
+	record.Warnf(clusterScope.ProxmoxCluster, reasonQuorumLost, "Pausing operations - %s", clusterScope.QuorumMessage())
+	return ctrl.Result{RequeueAfter: quorumCheckInterval}, true, nil
 }
 
 func (r *ProxmoxClusterReconciler) reconcileDelete(ctx context.Context, clusterScope *scope.ClusterScope) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.Info("Reconciling Delete ProxmoxCluster")
 
+	if result, paused, err := r.checkQuorum(ctx, clusterScope); paused || err != nil {
+		return result, err
+	}
+
+	if isDryRun(clusterScope.ProxmoxCluster) {
+		// the finalizer is kept until the annotation is removed so that nothing is left behind on proxmox
+		record.Event(clusterScope.ProxmoxCluster, reasonDryRun, clusterPlanMessage("delete", clusterScope))
+		return ctrl.Result{RequeueAfter: dryRunRequeueInterval}, nil
+	}
+
 	reconcilers := []cloud.Reconciler{
+		firewall.NewService(clusterScope),
+		pool.NewService(clusterScope),
 		storage.NewService(clusterScope),
 	}
 
@@ -162,9 +253,53 @@ func (r *ProxmoxClusterReconciler) reconcileDelete(ctx context.Context, clusterS
 	return ctrl.Result{}, nil
 }
 
+// e.g. "Would reconcile snippet storage local-dir-cappx-test and resource pool cappx-test"
+func clusterPlanMessage(verb string, clusterScope *scope.ClusterScope) string {
+	msg := fmt.Sprintf("Would %s snippet storage %s", verb, clusterScope.Storage().Name)
+	if spec := clusterScope.PoolSpec(); spec != nil {
+		name := spec.Name
+		if name == "" {
+			name = clusterScope.Name()
+		}
+		msg = fmt.Sprintf("%s and resource pool %s", msg, name)
+	}
+	return msg
+}
+
+// enqueues the ProxmoxCluster of the machine if it manages a firewall, so that the ipset of the
+// cluster follows the addresses of the machines
+func (r *ProxmoxClusterReconciler) proxmoxMachineToProxmoxCluster(ctx context.Context, o client.Object) []reconcile.Request {
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, metav1.ObjectMeta{Namespace: o.GetNamespace(), Labels: o.GetLabels()})
+	if err != nil || cluster.Spec.InfrastructureRef == nil || cluster.Spec.InfrastructureRef.Kind != "ProxmoxCluster" {
+		return nil
+	}
+	key := types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Spec.InfrastructureRef.Name}
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	if err := r.Get(ctx, key, proxmoxCluster); err != nil || proxmoxCluster.Spec.Firewall == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: key}}
+}
+
+// machines are created without addresses, so only changes of the addresses and deletions matter
+var machineAddressesChanged = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		old, ok := e.ObjectOld.(*infrav1.ProxmoxMachine)
+		if !ok {
+			return false
+		}
+		updated, ok := e.ObjectNew.(*infrav1.ProxmoxMachine)
+		return ok && !equality.Semantic.DeepEqual(old.Status.Addresses, updated.Status.Addresses)
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return true },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ProxmoxClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&infrav1.ProxmoxCluster{}).
+		Watches(&infrav1.ProxmoxMachine{}, handler.EnqueueRequestsFromMapFunc(r.proxmoxMachineToProxmoxCluster), builder.WithPredicates(machineAddressesChanged)).
 		Complete(r)
 }
@@ -6,8 +6,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
 )
@@ -53,3 +55,24 @@ var _ = Describe("ProxmoxClusterReconciler", Label("unit", "controllers"), func(
 		})
 	})
 })
+
+var _ = Describe("machineAddressesChanged", Label("unit", "controllers"), func() {
+	machine := func(addresses ...string) *infrav1.ProxmoxMachine {
+		m := &infrav1.ProxmoxMachine{}
+		for _, a := range addresses {
+			m.Status.Addresses = append(m.Status.Addresses, clusterv1.MachineAddress{Type: clusterv1.MachineInternalIP, Address: a})
+		}
+		return m
+	}
+
+	It("should pass changes of the addresses", func() {
+		Expect(machineAddressesChanged.Update(event.UpdateEvent{ObjectOld: machine(), ObjectNew: machine("10.0.0.1")})).To(BeTrue())
+		Expect(machineAddressesChanged.Update(event.UpdateEvent{ObjectOld: machine("10.0.0.1"), ObjectNew: machine("10.0.0.2")})).To(BeTrue())
+		Expect(machineAddressesChanged.Delete(event.DeleteEvent{Object: machine("10.0.0.1")})).To(BeTrue())
+	})
+
+	It("should drop other updates and creations", func() {
+		Expect(machineAddressesChanged.Update(event.UpdateEvent{ObjectOld: machine("10.0.0.1"), ObjectNew: machine("10.0.0.1")})).To(BeFalse())
+		Expect(machineAddressesChanged.Create(event.CreateEvent{Object: machine()})).To(BeFalse())
+	})
+})
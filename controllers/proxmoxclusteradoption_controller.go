@@ -0,0 +1,201 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/adoption"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+)
+
+const adoptionRetryInterval = time.Minute
+
+// ProxmoxClusterAdoptionReconciler generates Machines and ProxmoxMachines adopting the existing
+// qemus listed in spec.adoption of a ProxmoxCluster
+type ProxmoxClusterAdoptionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachines,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create
+
+func (r *ProxmoxClusterAdoptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	if err := r.Get(ctx, req.NamespacedName, proxmoxCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !proxmoxCluster.DeletionTimestamp.IsZero() || !proxmoxCluster.Status.Ready || len(proxmoxCluster.Spec.Adoption) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, proxmoxCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster == nil {
+		log.Info("Cluster Controller has not yet set OwnerRef")
+		return ctrl.Result{}, nil
+	}
+	if annotations.IsPaused(cluster, proxmoxCluster) {
+		log.Info("ProxmoxCluster or linked Cluster is marked as paused. Won't adopt VMs")
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name)
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		Client:         r.Client,
+		Cluster:        cluster,
+		ProxmoxCluster: proxmoxCluster,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Always close the scope when exiting this function so we can persist the adoption condition.
+	defer func() {
+		if err := clusterScope.Close(); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	if err := r.reconcileAdoption(ctx, clusterScope); err != nil {
+		log.Error(err, "Adoption error")
+		conditions.MarkFalse(proxmoxCluster, infrav1.VMsAdoptedCondition, infrav1.AdoptionFailedReason, clusterv1.ConditionSeverityWarning, "%v", err)
+		return ctrl.Result{RequeueAfter: adoptionRetryInterval}, nil
+	}
+	conditions.MarkTrue(proxmoxCluster, infrav1.VMsAdoptedCondition)
+	return ctrl.Result{}, nil
+}
+
+// generates the objects of every vm of spec.adoption. vms failing to be adopted do not block the others
+func (r *ProxmoxClusterAdoptionReconciler) reconcileAdoption(ctx context.Context, clusterScope *scope.ClusterScope) error {
+	proxmoxClient := clusterScope.CloudClient()
+	guests, err := guest.List(ctx, proxmoxClient)
+	if err != nil {
+		return err
+	}
+	machines := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, machines, client.InNamespace(clusterScope.Namespace())); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, vm := range clusterScope.ProxmoxCluster.Spec.Adoption {
+		if err := r.adopt(ctx, clusterScope, proxmoxClient, vm, guests, machines.Items); err != nil {
+			errs = append(errs, fmt.Errorf("vm %d: %w", vm.VMID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ProxmoxClusterAdoptionReconciler) adopt(ctx context.Context, clusterScope *scope.ClusterScope, proxmoxClient *proxmox.Service,
+	vm infrav1.AdoptedVM, guests []guest.Guest, machines []infrav1.ProxmoxMachine) error {
+	log := log.FromContext(ctx)
+	g, err := guest.Find(guests, vm.VMID)
+	if err != nil {
+		return fmt.Errorf("vm is not found: %w", err)
+	}
+	name := adoption.MachineName(vm, *g, clusterScope.Name())
+	if err := adoption.Validate(*g, machines, clusterScope.Namespace(), name); err != nil {
+		return err
+	}
+
+	qemu, err := proxmoxClient.VirtualMachine(ctx, vm.VMID)
+	if err != nil {
+		return err
+	}
+	config, err := qemu.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	uuid, err := proxmox.ConvertSMBiosToUUID(config.SMBios1)
+	if err != nil {
+		return fmt.Errorf("vm has no smbios uuid: %w", err)
+	}
+
+	proxmoxMachine, machine, secret, err := adoption.Objects(clusterScope.Cluster, vm, *g, name, uuid)
+	if err != nil {
+		return err
+	}
+	// the ProxmoxMachine exists before the Machine, so that it is never taken for a new one
+	if created, err := createIfNotFound(ctx, r.Client, proxmoxMachine); err != nil {
+		return err
+	} else if created {
+		log.Info("adopting vm", "vmid", vm.VMID, "name", g.Name, "node", g.Node, "machine", name)
+		record.Eventf(clusterScope.ProxmoxCluster, "VMAdopted", "Adopting VM %s (%d) on node %s by Machine %s", g.Name, vm.VMID, g.Node, name)
+	}
+	if _, err := createIfNotFound(ctx, r.Client, machine); err != nil {
+		return err
+	}
+	if err := controllerutil.SetOwnerReference(machine, secret, r.Scheme); err != nil {
+		return err
+	}
+	_, err = createIfNotFound(ctx, r.Client, secret)
+	return err
+}
+
+// creates the object unless it exists. the existing object is read into obj.
+// returns true if the object has been created
+func createIfNotFound(ctx context.Context, c client.Client, obj client.Object) (bool, error) {
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if err == nil {
+		return false, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	if err := c.Create(ctx, obj); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProxmoxClusterAdoptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("proxmoxclusteradoption").
+		For(&infrav1.ProxmoxCluster{}).
+		Complete(r)
+}
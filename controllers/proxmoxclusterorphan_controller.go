@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/orphan"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+)
+
+const defaultOrphanInterval = 10 * time.Minute
+
+// ProxmoxClusterOrphanReconciler reports and deletes VMs of a ProxmoxCluster
+// belonging to no ProxmoxMachine
+type ProxmoxClusterOrphanReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachines,verbs=get;list;watch
+
+func (r *ProxmoxClusterOrphanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	if err := r.Get(ctx, req.NamespacedName, proxmoxCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !proxmoxCluster.DeletionTimestamp.IsZero() || !proxmoxCluster.Status.Ready {
+		return ctrl.Result{}, nil
+	}
+
+	policy := orphanPolicy(proxmoxCluster.Spec.Orphans)
+	if isDryRun(proxmoxCluster) {
+		policy.Action = infrav1.OrphanActionReport
+	}
+	if last := proxmoxCluster.Status.LastOrphanCheckTime; last != nil {
+		if elapsed := time.Since(last.Time); elapsed < policy.Interval.Duration {
+			return ctrl.Result{RequeueAfter: policy.Interval.Duration - elapsed}, nil
+		}
+	}
+
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, proxmoxCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster == nil {
+		log.Info("Cluster Controller has not yet set OwnerRef")
+		return ctrl.Result{}, nil
+	}
+	if annotations.IsPaused(cluster, proxmoxCluster) {
+		log.Info("ProxmoxCluster or linked Cluster is marked as paused. Won't check orphaned VMs")
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name)
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		Client:         r.Client,
+		Cluster:        cluster,
+		ProxmoxCluster: proxmoxCluster,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Always close the scope when exiting this function so we can persist the orphaned VMs.
+	defer func() {
+		if err := clusterScope.Close(); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	if err := r.reconcileOrphans(ctx, clusterScope, policy); err != nil {
+		log.Error(err, "Orphan check error")
+		record.Warnf(proxmoxCluster, "ProxmoxClusterOrphans", "Orphan check error - %v", err)
+	}
+	clusterScope.SetLastOrphanCheckTime(metav1.Now())
+	return ctrl.Result{RequeueAfter: policy.Interval.Duration}, nil
+}
+
+func (r *ProxmoxClusterOrphanReconciler) reconcileOrphans(ctx context.Context, clusterScope *scope.ClusterScope, policy infrav1.OrphanPolicy) error {
+	log := log.FromContext(ctx)
+	proxmoxClient := clusterScope.CloudClient()
+
+	guests, err := guest.List(ctx, proxmoxClient)
+	if err != nil {
+		return err
+	}
+	// machines of other clusters are listed too. they own no vm of the cluster, but a machine
+	// not labeled with its cluster yet must never have its vm taken for an orphan
+	machines := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, machines, client.InNamespace(clusterScope.Namespace())); err != nil {
+		return err
+	}
+
+	current := clusterScope.OrphanedVMs()
+	now := metav1.Now()
+	orphans := orphan.Find(guests, machines.Items, clusterScope.Namespace(), clusterScope.Name())
+	tracked := orphan.Track(current, orphans, now)
+	for _, vm := range tracked {
+		if !slices.ContainsFunc(current, func(c infrav1.OrphanedVM) bool { return c.VMID == vm.VMID }) {
+			log.Info("found orphaned vm", "vmid", vm.VMID, "name", vm.Name, "node", vm.Node)
+			record.Warnf(clusterScope.ProxmoxCluster, "OrphanedVM", "VM %s (%d) on node %s belongs to no ProxmoxMachine", vm.Name, vm.VMID, vm.Node)
+		}
+	}
+	clusterScope.SetOrphanedVMs(tracked)
+	if policy.Action != infrav1.OrphanActionDelete {
+		return nil
+	}
+
+	remaining := []infrav1.OrphanedVM{}
+	for i, g := range orphans {
+		if !orphan.Deletable(g, tracked[i], clusterScope.Namespace(), policy.Interval.Duration, now.Time) {
+			remaining = append(remaining, tracked[i])
+			continue
+		}
+		log.Info("deleting orphaned vm", "type", g.Type, "vmid", g.VMID, "name", g.Name, "node", g.Node)
+		if err := orphan.Delete(ctx, proxmoxClient, g); err != nil {
+			clusterScope.SetOrphanedVMs(append(remaining, tracked[i:]...))
+			return fmt.Errorf("failed to delete orphaned vm %d: %w", g.VMID, err)
+		}
+		record.Eventf(clusterScope.ProxmoxCluster, "OrphanedVMDeleted", "Deleted VM %s (%d) on node %s", g.Name, g.VMID, g.Node)
+	}
+	clusterScope.SetOrphanedVMs(remaining)
+	return nil
+}
+
+// the vms are reported every 10m unless the policy is set
+func orphanPolicy(policy *infrav1.OrphanPolicy) infrav1.OrphanPolicy {
+	result := infrav1.OrphanPolicy{Action: infrav1.OrphanActionReport}
+	if policy != nil {
+		result = *policy
+	}
+	if result.Interval.Duration == 0 {
+		result.Interval.Duration = defaultOrphanInterval
+	}
+	return result
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProxmoxClusterOrphanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("proxmoxclusterorphan").
+		For(&infrav1.ProxmoxCluster{}).
+		Complete(r)
+}
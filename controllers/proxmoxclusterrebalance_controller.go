@@ -0,0 +1,317 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/k8s-proxmox/proxmox-go/proxmox"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/migration"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/rebalance"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+)
+
+const defaultRebalanceInterval = 10 * time.Minute
+
+// ProxmoxClusterRebalanceReconciler moves VMs of a ProxmoxCluster between Proxmox nodes
+// to even out their memory usage
+type ProxmoxClusterRebalanceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachines,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch;delete
+
+func (r *ProxmoxClusterRebalanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	if err := r.Get(ctx, req.NamespacedName, proxmoxCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	policy := proxmoxCluster.Spec.Rebalance
+	if policy == nil || !proxmoxCluster.DeletionTimestamp.IsZero() || !proxmoxCluster.Status.Ready || isDryRun(proxmoxCluster) {
+		return ctrl.Result{}, nil
+	}
+
+	interval := policy.Interval.Duration
+	if interval == 0 {
+		interval = defaultRebalanceInterval
+	}
+	if last := proxmoxCluster.Status.LastRebalanceTime; last != nil {
+		if elapsed := time.Since(last.Time); elapsed < interval {
+			return ctrl.Result{RequeueAfter: interval - elapsed}, nil
+		}
+	}
+
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, proxmoxCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster == nil {
+		log.Info("Cluster Controller has not yet set OwnerRef")
+		return ctrl.Result{}, nil
+	}
+	if annotations.IsPaused(cluster, proxmoxCluster) {
+		log.Info("ProxmoxCluster or linked Cluster is marked as paused. Won't rebalance")
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name)
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		Client:         r.Client,
+		Cluster:        cluster,
+		ProxmoxCluster: proxmoxCluster,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Always close the scope when exiting this function so we can persist the rebalance time.
+	defer func() {
+		if err := clusterScope.Close(); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	if err := r.rebalance(ctx, clusterScope, *policy); err != nil {
+		log.Error(err, "Rebalance error")
+		record.Warnf(proxmoxCluster, "ProxmoxClusterRebalance", "Rebalance error - %v", err)
+	}
+	clusterScope.SetLastRebalanceTime(metav1.Now())
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+func (r *ProxmoxClusterRebalanceReconciler) rebalance(ctx context.Context, clusterScope *scope.ClusterScope, policy infrav1.RebalancePolicy) error {
+	log := log.FromContext(ctx)
+	proxmoxClient := clusterScope.CloudClient()
+
+	cordoned, err := cordonedNodes(ctx, r.Client, clusterScope.Cluster)
+	if err != nil {
+		return err
+	}
+	nodes, guests, err := rebalanceNodes(ctx, proxmoxClient, cordoned)
+	if err != nil {
+		return err
+	}
+	machines := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, machines,
+		client.InNamespace(clusterScope.Namespace()),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: clusterScope.Name()},
+	); err != nil {
+		return err
+	}
+	recreate := policy.Strategy == infrav1.RebalanceStrategyRecreate
+	budget := policy.MaxMoves
+	controlPlaneRecreatable := false
+	if recreate {
+		if name := recreatingMachine(machines.Items); name != "" {
+			log.Info("waiting for ProxmoxMachine to be recreated before rebalancing", "proxmoxmachine", name)
+			return nil
+		}
+		// machines are recreated one by one so that the cluster does not lose capacity at once
+		budget = min(budget, 1)
+		controlPlane := &clusterv1.MachineList{}
+		if err := r.List(ctx, controlPlane,
+			client.InNamespace(clusterScope.Namespace()),
+			client.MatchingLabels{clusterv1.ClusterNameLabel: clusterScope.Name()},
+			client.HasLabels{clusterv1.MachineControlPlaneLabel},
+		); err != nil {
+			return err
+		}
+		controlPlaneRecreatable = canRecreateControlPlane(controlPlane.Items)
+	}
+	vms := []rebalance.VM{}
+	for _, m := range activeMachines(machines.Items) {
+		g, ok := guests[*m.Spec.VMID]
+		if !ok {
+			continue
+		}
+		vms = append(vms, rebalance.VM{
+			Name:     m.Name,
+			VMID:     *m.Spec.VMID,
+			Node:     g.Node,
+			Memory:   int64(m.Spec.Hardware.Memory) << 20,
+			Group:    rebalanceGroup(m),
+			Pinned:   !rebalanceMovable(m, recreate, controlPlaneRecreatable),
+			Nodes:    groupNodes(clusterScope, m, nodes),
+			Priority: m.Spec.Priority.Rank(),
+		})
+	}
+
+	skew := rebalance.Skew(nodes)
+	moves := rebalance.Plan(nodes, vms, float64(policy.Threshold), budget)
+	log.Info("evaluated node load", "skew", fmt.Sprintf("%.1f%%", skew), "moves", len(moves))
+	for _, move := range moves {
+		if recreate {
+			if err := r.deleteOwnerMachine(ctx, clusterScope, machines.Items, move.VM.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		g := guests[move.VM.VMID]
+		log.Info("migrating guest", "machine", move.VM.Name, "type", g.Type, "vmid", move.VM.VMID, "source", g.Node, "target", move.Target)
+		if err := migration.Migrate(ctx, proxmoxClient, g, move.Target); err != nil {
+			return fmt.Errorf("failed to migrate machine %s to node %s: %w", move.VM.Name, move.Target, err)
+		}
+		record.Eventf(clusterScope.ProxmoxCluster, "ProxmoxClusterRebalance", "Migrated %s from %s to %s", move.VM.Name, g.Node, move.Target)
+	}
+	return nil
+}
+
+func (r *ProxmoxClusterRebalanceReconciler) deleteOwnerMachine(ctx context.Context, clusterScope *scope.ClusterScope, machines []infrav1.ProxmoxMachine, name string) error {
+	for _, m := range machines {
+		if m.Name != name {
+			continue
+		}
+		machine, err := util.GetOwnerMachine(ctx, r.Client, m.ObjectMeta)
+		if err != nil || machine == nil {
+			return err
+		}
+		log.FromContext(ctx).Info("deleting Machine to recreate it on another node", "machine", machine.Name)
+		record.Eventf(clusterScope.ProxmoxCluster, "ProxmoxClusterRebalance", "Deleting Machine %s", machine.Name)
+		if err := r.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns true if the rebalancer may move the machine. passthrough devices cannot be live-migrated,
+// and control-plane machines are recreated only if the control plane stays healthy without them
+func rebalanceMovable(m infrav1.ProxmoxMachine, recreate, controlPlaneRecreatable bool) bool {
+	if !m.Spec.Movable() {
+		return false
+	}
+	if recreate {
+		_, controlPlane := m.Labels[clusterv1.MachineControlPlaneLabel]
+		return !controlPlane || controlPlaneRecreatable
+	}
+	return len(m.Spec.Hardware.PCIDevices) == 0 && len(m.Spec.Hardware.SRIOVNICs) == 0
+}
+
+// returns true if the control plane has more than one replica and all of them are healthy,
+// so that deleting one of them keeps the etcd quorum
+func canRecreateControlPlane(machines []clusterv1.Machine) bool {
+	if len(machines) < 2 {
+		return false
+	}
+	for _, m := range machines {
+		if !m.DeletionTimestamp.IsZero() || m.Status.NodeRef == nil || !conditions.IsTrue(&m, clusterv1.ReadyCondition) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the name of a ProxmoxMachine being deleted or not yet ready, i.e. a previous recreation has not settled
+func recreatingMachine(machines []infrav1.ProxmoxMachine) string {
+	for _, m := range machines {
+		if !m.DeletionTimestamp.IsZero() || !m.Status.Ready {
+			return m.Name
+		}
+	}
+	return ""
+}
+
+// returns online nodes and map[vmid]guest of all qemus and containers on them
+func rebalanceNodes(ctx context.Context, proxmoxClient *proxmox.Service, cordoned []string) ([]rebalance.Node, map[int]guest.Guest, error) {
+	list, err := proxmoxClient.GetNodes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := guest.List(ctx, proxmoxClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes := []rebalance.Node{}
+	guests := map[int]guest.Guest{}
+	for _, n := range list {
+		if n.Status != "online" {
+			continue
+		}
+		for _, g := range all {
+			if g.Node == n.Node {
+				guests[g.VMID] = g
+			}
+		}
+		nodes = append(nodes, rebalance.Node{
+			Name:     n.Node,
+			Used:     int64(n.Mem),
+			Total:    int64(n.MaxMem),
+			Cordoned: slices.Contains(cordoned, n.Node),
+		})
+	}
+	return nodes, guests, nil
+}
+
+// returns the nodes of the node group of the machine. nil if it has no node group
+func groupNodes(clusterScope *scope.ClusterScope, m infrav1.ProxmoxMachine, nodes []rebalance.Node) []string {
+	if m.Spec.NodeGroup == "" {
+		return nil
+	}
+	group := clusterScope.NodeGroup(m.Spec.NodeGroup)
+	names := []string{}
+	for _, n := range nodes {
+		if group != nil && group.Contains(n.Name) {
+			names = append(names, n.Name)
+		}
+	}
+	return names
+}
+
+// machines of the same control plane or MachineDeployment are kept on different nodes
+func rebalanceGroup(m infrav1.ProxmoxMachine) string {
+	if _, ok := m.Labels[clusterv1.MachineControlPlaneLabel]; ok {
+		return "controlplane"
+	}
+	if name, ok := m.Labels[clusterv1.MachineDeploymentNameLabel]; ok {
+		return "machinedeployment/" + name
+	}
+	return ""
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProxmoxClusterRebalanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("proxmoxclusterrebalance").
+		For(&infrav1.ProxmoxCluster{}).
+		Complete(r)
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+var _ = Describe("rebalanceMovable", Label("unit", "controllers"), func() {
+	controlPlane := infrav1.ProxmoxMachine{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{clusterv1.MachineControlPlaneLabel: ""}}}
+	passthrough := infrav1.ProxmoxMachine{Spec: infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{PCIDevices: []infrav1.PCIDevice{{}}}}}
+	sriov := infrav1.ProxmoxMachine{Spec: infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{SRIOVNICs: []infrav1.SRIOVNIC{{}}}}}
+
+	It("should not migrate machines having passthrough devices", func() {
+		Expect(rebalanceMovable(passthrough, false, false)).To(BeFalse())
+		Expect(rebalanceMovable(sriov, false, false)).To(BeFalse())
+		Expect(rebalanceMovable(passthrough, true, false)).To(BeTrue())
+		Expect(rebalanceMovable(controlPlane, false, false)).To(BeTrue())
+	})
+
+	It("should recreate control-plane machines only if the control plane allows it", func() {
+		Expect(rebalanceMovable(controlPlane, true, false)).To(BeFalse())
+		Expect(rebalanceMovable(controlPlane, true, true)).To(BeTrue())
+	})
+
+	It("should not move pinned machines", func() {
+		pinned := infrav1.ProxmoxMachine{Spec: infrav1.ProxmoxMachineSpec{NodeName: "pve1"}}
+		Expect(rebalanceMovable(pinned, false, false)).To(BeFalse())
+		Expect(rebalanceMovable(pinned, true, true)).To(BeFalse())
+	})
+})
+
+var _ = Describe("canRecreateControlPlane", Label("unit", "controllers"), func() {
+	healthy := func() clusterv1.Machine {
+		return clusterv1.Machine{Status: clusterv1.MachineStatus{
+			NodeRef:    &corev1.ObjectReference{Name: "node"},
+			Conditions: clusterv1.Conditions{{Type: clusterv1.ReadyCondition, Status: corev1.ConditionTrue}},
+		}}
+	}
+
+	It("should require more than one replica", func() {
+		Expect(canRecreateControlPlane(nil)).To(BeFalse())
+		Expect(canRecreateControlPlane([]clusterv1.Machine{healthy()})).To(BeFalse())
+		Expect(canRecreateControlPlane([]clusterv1.Machine{healthy(), healthy(), healthy()})).To(BeTrue())
+	})
+
+	It("should require all replicas to be healthy", func() {
+		unready := healthy()
+		unready.Status.Conditions[0].Status = corev1.ConditionFalse
+		Expect(canRecreateControlPlane([]clusterv1.Machine{healthy(), healthy(), unready})).To(BeFalse())
+
+		now := metav1.Now()
+		deleting := healthy()
+		deleting.DeletionTimestamp = &now
+		Expect(canRecreateControlPlane([]clusterv1.Machine{healthy(), healthy(), deleting})).To(BeFalse())
+	})
+})
+
+var _ = Describe("recreatingMachine", Label("unit", "controllers"), func() {
+	It("should return a machine being deleted or not ready", func() {
+		ready := infrav1.ProxmoxMachine{ObjectMeta: metav1.ObjectMeta{Name: "ready"}, Status: infrav1.ProxmoxMachineStatus{Ready: true}}
+		Expect(recreatingMachine([]infrav1.ProxmoxMachine{ready})).To(BeEmpty())
+
+		replacement := infrav1.ProxmoxMachine{ObjectMeta: metav1.ObjectMeta{Name: "replacement"}}
+		Expect(recreatingMachine([]infrav1.ProxmoxMachine{ready, replacement})).To(Equal("replacement"))
+
+		now := metav1.Now()
+		deleting := ready
+		deleting.Name = "deleting"
+		deleting.DeletionTimestamp = &now
+		Expect(recreatingMachine([]infrav1.ProxmoxMachine{deleting, ready})).To(Equal("deleting"))
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/guest"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/usage"
+)
+
+// rrd data of proxmox is consolidated every minute
+const usageInterval = time.Minute
+
+// ProxmoxClusterUsageReconciler exports the resource consumption of the VMs of a ProxmoxCluster as metrics
+type ProxmoxClusterUsageReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxclusters,verbs=get;list;watch
+
+func (r *ProxmoxClusterUsageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	if err := r.Get(ctx, req.NamespacedName, proxmoxCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, proxmoxCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster == nil {
+		log.Info("Cluster Controller has not yet set OwnerRef")
+		return ctrl.Result{}, nil
+	}
+	if !proxmoxCluster.DeletionTimestamp.IsZero() {
+		usage.Forget(cluster.Namespace, cluster.Name)
+		return ctrl.Result{}, nil
+	}
+	// status updates are not watched, so the cluster is checked again later
+	if !proxmoxCluster.Status.Ready {
+		return ctrl.Result{RequeueAfter: usageInterval}, nil
+	}
+	if annotations.IsPaused(cluster, proxmoxCluster) {
+		log.Info("ProxmoxCluster or linked Cluster is marked as paused. Won't collect resource usage")
+		return ctrl.Result{RequeueAfter: usageInterval}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name)
+	// the status is not updated, so the scope is never closed
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		Client:         r.Client,
+		Cluster:        cluster,
+		ProxmoxCluster: proxmoxCluster,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := reconcileUsage(ctx, clusterScope); err != nil {
+		// the last usage is kept rather than reporting a partial one
+		log.Error(err, "Resource usage collection error")
+	}
+	return ctrl.Result{RequeueAfter: usageInterval}, nil
+}
+
+func reconcileUsage(ctx context.Context, clusterScope *scope.ClusterScope) error {
+	proxmoxClient := clusterScope.CloudClient()
+	guests, err := guest.List(ctx, proxmoxClient)
+	if err != nil {
+		return err
+	}
+	u, err := usage.Collect(ctx, proxmoxClient, usage.Guests(guests, clusterScope.Namespace(), clusterScope.Name()))
+	if err != nil {
+		return err
+	}
+	usage.Record(clusterScope.Namespace(), clusterScope.Name(), u)
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// status updates of the ProxmoxCluster are ignored. the usage is collected every minute
+func (r *ProxmoxClusterUsageReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("proxmoxclusterusage").
+		For(&infrav1.ProxmoxCluster{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Complete(r)
+}
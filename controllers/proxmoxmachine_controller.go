@@ -18,27 +18,42 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util"
-	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/retry"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scheduler"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
 	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
 )
 
+// requeue interval of machines whose requests the proxmox api has rejected
+const clientErrorRequeueAfter = 5 * time.Minute
+
+var errControlPlaneMachine = errors.New("control-plane machines are not provisioned for clusters with an external control plane")
+
 // ProxmoxMachineReconciler reconciles a ProxmoxMachine object
 type ProxmoxMachineReconciler struct {
 	client.Client
@@ -50,6 +65,9 @@ type ProxmoxMachineReconciler struct {
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachines/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachines/finalizers,verbs=update
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachinetemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=delete;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxnodemaintenances,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
 // +kubebuilder:rbac:groups="",resources=secrets;,verbs=get;list;watch
 
@@ -85,7 +103,11 @@ func (r *ProxmoxMachineReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, nil
 	}
 
-	if annotations.IsPaused(cluster, proxmoxMachine) {
+	paused, err := scope.EnsurePausedCondition(ctx, r.Client, cluster, proxmoxMachine, "ProxmoxMachine")
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if paused {
 		log.Info("ProxmoxMachine or linked Cluster is marked as paused. Won't reconcile")
 		return ctrl.Result{}, nil
 	}
@@ -111,6 +133,11 @@ func (r *ProxmoxMachineReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, err
 	}
 
+	cordonedNodes, err := cordonedNodes(ctx, r.Client, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Create the machine scope
 	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
 		Client:           r.Client,
@@ -118,25 +145,64 @@ func (r *ProxmoxMachineReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		ProxmoxMachine:   proxmoxMachine,
 		ClusterGetter:    clusterScope,
 		SchedulerManager: r.SchedulerManager,
+		CordonedNodes:    cordonedNodes,
 	})
 	if err != nil {
 		return ctrl.Result{}, errors.Errorf("failed to create scope: %+v", err)
 	}
 
 	// Always close the scope when exiting this function so we can persist any ProxmoxMachine changes.
+	failed := proxmoxMachine.Status.FailureReason != nil
 	defer func() {
+		// the hooks of the cluster hear of machines failing for good once
+		if !failed && proxmoxMachine.Status.FailureMessage != nil {
+			machineScope.Notify(ctx, infrav1.NotificationEventMachineFailed, *proxmoxMachine.Status.FailureMessage)
+		}
 		if err := machineScope.Close(); err != nil && reterr == nil {
 			reterr = err
 		}
 	}()
 
+	dryRun := isDryRun(proxmoxCluster, proxmoxMachine)
+
+	// reconciles are paused while the proxmox api is clearly down instead of piling up failures
+	endpoint := clusterScope.ServerEndpoint()
+	breaker := retry.BreakerFor(endpoint)
+	if paused := breaker.Allow(); paused > 0 {
+		log.Info("Proxmox API is unavailable, pausing reconcile", "endpoint", endpoint, "requeueAfter", paused)
+		clusterScope.Notify(ctx, infrav1.NotificationEventEndpointUnavailable, endpoint, fmt.Sprintf("Proxmox API %s is unavailable, reconciles are paused", endpoint))
+		return ctrl.Result{RequeueAfter: paused}, nil
+	}
+	done := func(err error) {
+		breaker.Done(err)
+		if !retry.IsUnavailable(err) {
+			clusterScope.ResolveNotification(infrav1.NotificationEventEndpointUnavailable, endpoint)
+		}
+	}
+
+	// guests are neither created nor deleted until the proxmox cluster is quorate again
+	if !clusterScope.Quorate() && !dryRun {
+		log.Info("Proxmox cluster is not quorate, pausing reconcile", "reason", clusterScope.QuorumMessage())
+		return ctrl.Result{RequeueAfter: quorumCheckInterval}, nil
+	}
+
 	// Handle deleted machines
 	if !proxmoxMachine.ObjectMeta.DeletionTimestamp.IsZero() {
-		return r.reconcileDelete(ctx, machineScope)
+		if dryRun {
+			return r.reconcileDeleteDryRun(ctx, machineScope)
+		}
+		result, err := r.reconcileDelete(ctx, machineScope)
+		done(err)
+		return result, err
 	}
 
 	// Handle non-deleted machines
-	return r.reconcile(ctx, machineScope)
+	if dryRun {
+		return r.reconcileDryRun(ctx, machineScope)
+	}
+	result, err := r.reconcile(ctx, machineScope)
+	done(err)
+	return result, err
 }
 
 func (r *ProxmoxMachineReconciler) reconcile(ctx context.Context, machineScope *scope.MachineScope) (ctrl.Result, error) {
@@ -147,37 +213,98 @@ func (r *ProxmoxMachineReconciler) reconcile(ctx context.Context, machineScope *
 		log.Info("update finalizer to ProxmoxMachine")
 	}
 
+	machineScope.SetPlan(nil)
 	if err := machineScope.PatchObject(); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if err := instance.PhaseExpired(machineScope.ProvisioningPhase(), machineScope.GetProvisioningTimeouts(), time.Now()); err != nil {
+		return r.failProvisioning(machineScope, err)
+	}
+
+	if machineScope.ClusterGetter.ExternalControlPlane() && machineScope.IsControlPlane() && machineScope.GetProviderID() == "" {
+		// only workers joining the external control plane are provisioned
+		if machineScope.ProxmoxMachine.Status.FailureReason == nil {
+			record.Warnf(machineScope.ProxmoxMachine, "ExternalControlPlane", "%v", errControlPlaneMachine)
+		}
+		machineScope.SetFailureReason(capierrors.InvalidConfigurationMachineError)
+		machineScope.SetFailureMessage(errControlPlaneMachine)
+		return ctrl.Result{}, nil
+	}
+
+	if admitted, err := r.reconcileQuota(ctx, machineScope); err != nil || !admitted {
+		// parked until other machines are deleted or the quota is raised
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
 	reconcilers := []cloud.Reconciler{
 		instance.NewService(machineScope),
 	}
 
-	for _, r := range reconcilers {
-		if err := r.Reconcile(ctx); err != nil {
+	nodeDown := false
+	for _, reconciler := range reconcilers {
+		if err := reconciler.Reconcile(ctx); err != nil {
+			if errors.Is(err, instance.ErrNodeDown) {
+				nodeDown = true
+				break
+			}
+			if errors.Is(err, instance.ErrPhaseTimedOut) {
+				return r.failProvisioning(machineScope, err)
+			}
+			if errors.Is(err, instance.ErrGuestNotReady) {
+				log.Info("waiting for the guest to be ready", "reason", err.Error())
+				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			}
 			log.Error(err, "Reconcile error")
 			record.Warnf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "Reconcile error - %v", err)
+			if retry.IsClientError(err) {
+				// the api has rejected the request. retried slowly unless the machine changes
+				return ctrl.Result{RequeueAfter: clientErrorRequeueAfter}, nil
+			}
 			return ctrl.Result{RequeueAfter: 5 * time.Second}, err
 		}
 	}
+	if nodeDown {
+		return ctrl.Result{}, r.recreateMachineOnDownNode(ctx, machineScope)
+	}
+
+	if err := r.reconcilePlacementLabels(ctx, machineScope); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileTemplateDrift(ctx, machineScope); err != nil {
+		// only informational, the machine is reconciled anyway
+		log.Error(err, "failed to check ProxmoxMachineTemplate for changes")
+	}
 
 	instanceState := *machineScope.GetInstanceStatus()
 	switch instanceState {
 	case infrav1.InstanceStatusRunning:
-		log.Info("ProxmoxMachine instance is running", "bios-uuid", *machineScope.GetBiosUUID())
-		record.Eventf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "ProxmoxMachine instance is running - bios-uuid: %s", *machineScope.GetBiosUUID())
+		log.Info("ProxmoxMachine instance is running", "provider-id", machineScope.GetProviderID())
+		record.Eventf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "ProxmoxMachine instance is running - provider-id: %s", machineScope.GetProviderID())
 		record.Event(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "Reconciled")
 		machineScope.SetReady()
-		return ctrl.Result{}, nil
+		result := ctrl.Result{}
+		if unbound, err := r.reconcileNodeProviderID(ctx, machineScope); err != nil {
+			// only a help for Cluster API, which binds nodes with the provider id of the machine anyway
+			log.Error(err, "failed to match the node of the machine")
+			result.RequeueAfter = nodeMatchInterval
+		} else if unbound {
+			result.RequeueAfter = nodeMatchInterval
+		}
+		// checked again once the bootstrap phase times out
+		if remaining, ok := instance.PhaseRemaining(machineScope.ProvisioningPhase(), machineScope.GetProvisioningTimeouts(), time.Now()); ok {
+			if result.RequeueAfter == 0 || remaining < result.RequeueAfter {
+				result.RequeueAfter = remaining
+			}
+		}
+		return result, nil
 	case infrav1.InstanceStatusStopped:
-		log.Info("ProxmoxMachine instance is stopped", "instance-id", *machineScope.GetBiosUUID())
-		record.Eventf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "ProxmoxMachine instance is stopped - bios-uuid: %s", *machineScope.GetBiosUUID())
+		log.Info("ProxmoxMachine instance is stopped", "provider-id", machineScope.GetProviderID())
+		record.Eventf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "ProxmoxMachine instance is stopped - provider-id: %s", machineScope.GetProviderID())
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	case infrav1.InstanceStatusPaused:
-		log.Info("ProxmoxMachine instance is paused", "instance-id", *machineScope.GetBiosUUID())
-		record.Eventf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "ProxmoxMachine instance is paused - bios-uuid: %s", *machineScope.GetBiosUUID())
+		log.Info("ProxmoxMachine instance is paused", "provider-id", machineScope.GetProviderID())
+		record.Eventf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "ProxmoxMachine instance is paused - provider-id: %s", machineScope.GetProviderID())
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	default:
 		machineScope.SetFailureReason(capierrors.UpdateMachineError)
@@ -186,6 +313,45 @@ func (r *ProxmoxMachineReconciler) reconcile(ctx context.Context, machineScope *
 	}
 }
 
+// marks the machine failed so that a MachineHealthCheck or the owner of the Machine replaces it
+func (r *ProxmoxMachineReconciler) failProvisioning(machineScope *scope.MachineScope, err error) (ctrl.Result, error) {
+	if machineScope.ProxmoxMachine.Status.FailureReason == nil {
+		record.Warnf(machineScope.ProxmoxMachine, "ProvisioningTimeout", "%v", err)
+	}
+	machineScope.SetFailureReason(capierrors.CreateMachineError)
+	machineScope.SetFailureMessage(err)
+	return ctrl.Result{}, nil
+}
+
+// checks that a machine without instance fits in the quota of the cluster before it is scheduled
+func (r *ProxmoxMachineReconciler) reconcileQuota(ctx context.Context, machineScope *scope.MachineScope) (bool, error) {
+	quota := machineScope.ClusterGetter.ProxmoxCluster.Spec.Quota
+	if quota == nil || machineScope.GetProviderID() != "" || machineScope.GetInstanceStatus() != nil {
+		return true, nil
+	}
+	machines := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, machines,
+		client.InNamespace(machineScope.Namespace()),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: machineScope.ClusterName()},
+	); err != nil {
+		return false, err
+	}
+	admitted, message, err := instance.Admit(*quota, machines.Items, machineScope.Name())
+	if err != nil {
+		return false, err
+	}
+	if !admitted {
+		if !conditions.IsFalse(machineScope.ProxmoxMachine, infrav1.WithinQuotaCondition) {
+			record.Warnf(machineScope.ProxmoxMachine, infrav1.QuotaExceededReason, "%s", message)
+		}
+		log.FromContext(ctx).Info("machine is not scheduled", "reason", message)
+		machineScope.SetQuotaExceeded(message)
+		return false, nil
+	}
+	machineScope.SetWithinQuota()
+	return true, nil
+}
+
 func (r *ProxmoxMachineReconciler) reconcileDelete(ctx context.Context, machineScope *scope.MachineScope) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.Info("Reconciling Delete ProxmoxMachine")
@@ -202,15 +368,207 @@ func (r *ProxmoxMachineReconciler) reconcileDelete(ctx context.Context, machineS
 		}
 	}
 
+	// forget the problems of the machine, so that the notifier does not keep them forever
+	machineScope.ResolveNotification(infrav1.NotificationEventMachineFailed)
+	machineScope.ResolveNotification(infrav1.NotificationEventSchedulingFailed)
 	controllerutil.RemoveFinalizer(machineScope.ProxmoxMachine, infrav1.MachineFinalizer)
 	record.Event(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "Reconciled")
 	log.Info("Reconciled ProxmoxMachine")
 	return ctrl.Result{}, nil
 }
 
+// publishes what reconcile would do without calling mutating proxmox apis.
+// no finalizer is added since nothing is created
+func (r *ProxmoxMachineReconciler) reconcileDryRun(ctx context.Context, machineScope *scope.MachineScope) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling ProxmoxMachine in dry-run mode")
+
+	plan, err := instance.NewService(machineScope).Plan(ctx)
+	if err != nil {
+		if plan.Action == "" {
+			log.Error(err, "Reconcile error")
+			record.Warnf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "Reconcile error - %v", err)
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+		}
+		plan.Error = err.Error()
+	}
+	r.publishPlan(ctx, machineScope, plan)
+	if plan.Error != "" {
+		// e.g. a node may become available
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// the instance is deleted only after the dry-run annotation is removed.
+// the finalizer is removed right away if there is no instance
+func (r *ProxmoxMachineReconciler) reconcileDeleteDryRun(ctx context.Context, machineScope *scope.MachineScope) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling Delete ProxmoxMachine in dry-run mode")
+
+	plan, err := instance.NewService(machineScope).PlanDelete(ctx)
+	if err != nil {
+		log.Error(err, "Reconcile error")
+		record.Warnf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+	r.publishPlan(ctx, machineScope, plan)
+	if plan.Action == infrav1.PlanActionNone {
+		controllerutil.RemoveFinalizer(machineScope.ProxmoxMachine, infrav1.MachineFinalizer)
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ProxmoxMachineReconciler) publishPlan(ctx context.Context, machineScope *scope.MachineScope, plan infrav1.Plan) {
+	msg := planMessage(plan)
+	log.FromContext(ctx).Info("planned ProxmoxMachine", "plan", msg)
+	machineScope.SetPlan(&plan)
+	if plan.Error != "" {
+		record.Warn(machineScope.ProxmoxMachine, reasonDryRun, msg)
+		return
+	}
+	record.Event(machineScope.ProxmoxMachine, reasonDryRun, msg)
+}
+
+// the vm can not be recovered from the down node. mark the machine failed
+// and delete its Machine so that the owner recreates it on a healthy node
+func (r *ProxmoxMachineReconciler) recreateMachineOnDownNode(ctx context.Context, machineScope *scope.MachineScope) error {
+	log := log.FromContext(ctx)
+	node := machineScope.NodeName()
+	machineScope.SetFailureReason(capierrors.UpdateMachineError)
+	machineScope.SetFailureMessage(errors.Errorf("proxmox node %s is down", node))
+	machine := machineScope.Machine
+	if !machine.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	log.Info("deleting Machine to recreate it on a healthy node", "node", node, "machine", machine.Name)
+	record.Warnf(machineScope.ProxmoxMachine, "ProxmoxMachineReconcile", "Proxmox node %s is down, deleting Machine %s", node, machine.Name)
+	if err := r.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// labels the Machine with the proxmox node, storage and failure domain of its vm.
+// the labels follow the vm when it is migrated to another node
+func (r *ProxmoxMachineReconciler) reconcilePlacementLabels(ctx context.Context, machineScope *scope.MachineScope) error {
+	log := log.FromContext(ctx)
+	machine := machineScope.Machine
+	failureDomain := machine.Spec.FailureDomain
+	if failureDomain == nil {
+		failureDomain = machineScope.ProxmoxMachine.Spec.FailureDomain
+	}
+	placement := placementLabels(machineScope.NodeName(), machineScope.GetStorage(), failureDomain)
+	before := machine.DeepCopy()
+	if machine.Labels == nil {
+		machine.Labels = map[string]string{}
+	}
+	if !syncLabels(machine.Labels, placement) {
+		return nil
+	}
+	log.Info("updating placement labels of Machine", "labels", placement)
+	return r.Patch(ctx, machine, client.MergeFrom(before))
+}
+
+// returns labels describing the placement. empty values are removed from the Machine
+func placementLabels(node, storage string, failureDomain *string) map[string]string {
+	labels := map[string]string{
+		infrav1.ProxmoxNodeLabel:          node,
+		infrav1.ProxmoxStorageLabel:       storage,
+		infrav1.ProxmoxFailureDomainLabel: "",
+	}
+	if failureDomain != nil && len(validation.IsValidLabelValue(*failureDomain)) == 0 {
+		labels[infrav1.ProxmoxFailureDomainLabel] = *failureDomain
+	}
+	return labels
+}
+
+// sets or removes the desired labels. returns true if labels are changed
+func syncLabels(labels, desired map[string]string) bool {
+	changed := false
+	for key, value := range desired {
+		current, ok := labels[key]
+		switch {
+		case value == "" && ok:
+			delete(labels, key)
+		case value != "" && current != value:
+			labels[key] = value
+		default:
+			continue
+		}
+		changed = true
+	}
+	return changed
+}
+
+// returns requests for the ProxmoxMachines of the cluster so that
+// they notice proxmox nodes going down
+func (r *ProxmoxMachineReconciler) proxmoxClusterToProxmoxMachines(ctx context.Context, o client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+	clusterName, ok := o.GetLabels()[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil
+	}
+	list := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, list, client.InNamespace(o.GetNamespace()), client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName}); err != nil {
+		log.Error(err, "failed to list ProxmoxMachines")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, m := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.Namespace, Name: m.Name}})
+	}
+	return requests
+}
+
+// enqueues the ProxmoxMachines whose Machine has not joined yet and refers to the bootstrap data secret,
+// so that rotated bootstrap data is written to their snippets before they boot
+func (r *ProxmoxMachineReconciler) bootstrapSecretToProxmoxMachines(ctx context.Context, o client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+	clusterName, ok := o.GetLabels()[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil
+	}
+	list := &clusterv1.MachineList{}
+	if err := r.List(ctx, list, client.InNamespace(o.GetNamespace()), client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName}); err != nil {
+		log.Error(err, "failed to list Machines")
+		return nil
+	}
+	return unjoinedMachinesOfBootstrapSecret(list.Items, o.GetName())
+}
+
+func unjoinedMachinesOfBootstrapSecret(machines []clusterv1.Machine, secretName string) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, m := range machines {
+		if m.Status.NodeRef != nil || m.Spec.InfrastructureRef.Kind != "ProxmoxMachine" {
+			continue
+		}
+		if m.Spec.Bootstrap.DataSecretName == nil || *m.Spec.Bootstrap.DataSecretName != secretName {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.Namespace, Name: m.Spec.InfrastructureRef.Name}})
+	}
+	return requests
+}
+
+// bootstrap secrets are labeled with the name of their cluster
+var hasClusterNameLabel = predicate.NewPredicateFuncs(func(o client.Object) bool {
+	_, ok := o.GetLabels()[clusterv1.ClusterNameLabel]
+	return ok
+})
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ProxmoxMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&infrav1.ProxmoxMachine{}).
+		Watches(&infrav1.ProxmoxCluster{}, handler.EnqueueRequestsFromMapFunc(r.proxmoxClusterToProxmoxMachines)).
+		// edits of the template are reported on the machines cloned from it
+		Watches(&infrav1.ProxmoxMachineTemplate{}, handler.EnqueueRequestsFromMapFunc(r.proxmoxMachineTemplateToProxmoxMachines), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// labels of the Machine are mapped to tags of the vm
+		Watches(&clusterv1.Machine{}, handler.EnqueueRequestsFromMapFunc(util.MachineToInfrastructureMapFunc(infrav1.GroupVersion.WithKind("ProxmoxMachine")))).
+		// rotated bootstrap data is written to the snippets of machines which have not joined yet.
+		// only the metadata of secrets of clusters is watched, so that not every secret is cached
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.bootstrapSecretToProxmoxMachines),
+			builder.OnlyMetadata, builder.WithPredicates(hasClusterNameLabel)).
 		Complete(r)
 }
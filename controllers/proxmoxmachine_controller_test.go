@@ -5,9 +5,14 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
 )
@@ -36,3 +41,135 @@ var _ = Describe("ProxmoxMachineReconciler", Label("unit", "controllers"), func(
 		})
 	})
 })
+
+var _ = Describe("placementLabels", Label("unit", "controllers"), func() {
+	It("should label node, storage and failure domain", func() {
+		Expect(placementLabels("pve1", "local-lvm", ptr.To("rack-1"))).To(Equal(map[string]string{
+			infrav1.ProxmoxNodeLabel:          "pve1",
+			infrav1.ProxmoxStorageLabel:       "local-lvm",
+			infrav1.ProxmoxFailureDomainLabel: "rack-1",
+		}))
+	})
+
+	It("should skip failure domains not valid as label value", func() {
+		Expect(placementLabels("pve1", "local-lvm", ptr.To("rack 1"))).To(HaveKeyWithValue(infrav1.ProxmoxFailureDomainLabel, ""))
+		Expect(placementLabels("pve1", "local-lvm", nil)).To(HaveKeyWithValue(infrav1.ProxmoxFailureDomainLabel, ""))
+	})
+})
+
+var _ = Describe("syncLabels", Label("unit", "controllers"), func() {
+	It("should set and remove labels", func() {
+		labels := map[string]string{"app": "web", infrav1.ProxmoxNodeLabel: "pve1", infrav1.ProxmoxFailureDomainLabel: "rack-1"}
+		desired := map[string]string{infrav1.ProxmoxNodeLabel: "pve2", infrav1.ProxmoxStorageLabel: "local-lvm", infrav1.ProxmoxFailureDomainLabel: ""}
+		Expect(syncLabels(labels, desired)).To(BeTrue())
+		Expect(labels).To(Equal(map[string]string{"app": "web", infrav1.ProxmoxNodeLabel: "pve2", infrav1.ProxmoxStorageLabel: "local-lvm"}))
+		Expect(syncLabels(labels, desired)).To(BeFalse())
+	})
+})
+
+var _ = Describe("unjoinedMachinesOfBootstrapSecret", Label("unit", "controllers"), func() {
+	machine := func(name, secret string, joined bool) clusterv1.Machine {
+		m := clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		m.Spec.InfrastructureRef = corev1.ObjectReference{Kind: "ProxmoxMachine", Name: name + "-infra"}
+		m.Spec.Bootstrap.DataSecretName = ptr.To(secret)
+		if joined {
+			m.Status.NodeRef = &corev1.ObjectReference{Name: name}
+		}
+		return m
+	}
+
+	It("should enqueue unjoined machines of the secret only", func() {
+		machines := []clusterv1.Machine{machine("a", "a-bootstrap", false), machine("b", "a-bootstrap", true), machine("c", "c-bootstrap", false)}
+		Expect(unjoinedMachinesOfBootstrapSecret(machines, "a-bootstrap")).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "default", Name: "a-infra"}},
+		}))
+	})
+})
+
+var _ = Describe("isDryRun", Label("unit", "controllers"), func() {
+	It("should be true if any object has the annotation", func() {
+		annotated := &infrav1.ProxmoxMachine{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{infrav1.DryRunAnnotation: ""}}}
+		Expect(isDryRun(&infrav1.ProxmoxCluster{}, annotated)).To(BeTrue())
+		Expect(isDryRun(&infrav1.ProxmoxCluster{}, &infrav1.ProxmoxMachine{})).To(BeFalse())
+	})
+})
+
+var _ = Describe("planMessage", Label("unit", "controllers"), func() {
+	It("should describe the planned creation", func() {
+		plan := infrav1.Plan{
+			Action: infrav1.PlanActionCreate, Type: infrav1.InstanceTypeQEMU, Node: "pve1", VMID: 100,
+			Storage: "local-lvm", Source: "https://example.com/jammy.img", Disks: []string{"scsi0=local-lvm:0"},
+		}
+		Expect(planMessage(plan)).To(Equal("Would create qemu on node pve1 with vmid 100 and storage local-lvm from https://example.com/jammy.img. disks: scsi0=local-lvm:0"))
+	})
+
+	It("should describe the failure", func() {
+		plan := infrav1.Plan{Action: infrav1.PlanActionCreate, Type: infrav1.InstanceTypeLXC, Error: "no node is available"}
+		Expect(planMessage(plan)).To(Equal("Would fail to create lxc: no node is available"))
+	})
+
+	It("should describe the deletion and no-ops", func() {
+		Expect(planMessage(infrav1.Plan{Action: infrav1.PlanActionDelete, Type: infrav1.InstanceTypeQEMU, Node: "pve1", VMID: 100})).To(Equal("Would delete qemu 100 on node pve1"))
+		Expect(planMessage(infrav1.Plan{Action: infrav1.PlanActionNone, Type: infrav1.InstanceTypeQEMU, Node: "pve1", VMID: 100})).To(Equal("qemu 100 exists on node pve1, nothing would be done"))
+		Expect(planMessage(infrav1.Plan{Action: infrav1.PlanActionNone, Type: infrav1.InstanceTypeQEMU})).To(Equal("No qemu exists, nothing would be done"))
+	})
+})
+
+var _ = Describe("templateChanges", Label("unit", "controllers"), func() {
+	template := infrav1.ProxmoxMachineSpec{Hardware: infrav1.Hardware{CPU: 2, Memory: 4096}}
+
+	It("should ignore fields filled in on the machine", func() {
+		machine := *template.DeepCopy()
+		machine.ProviderID = ptr.To("proxmox://uuid")
+		machine.Node = "pve1"
+		machine.VMID = ptr.To(100)
+		machine.Storage = "local-lvm"
+		machine.FailureDomain = ptr.To("rack-1")
+		Expect(templateChanges(machine, template)).To(BeEmpty())
+	})
+
+	It("should report changes of the template", func() {
+		changed := *template.DeepCopy()
+		changed.Hardware.Memory = 8192
+		Expect(templateChanges(template, changed)).To(Equal([]string{"hardware.memory: 4096 -> 8192"}))
+	})
+})
+
+var _ = Describe("clonedFromTemplate", Label("unit", "controllers"), func() {
+	It("should only return ProxmoxMachineTemplates", func() {
+		m := &infrav1.ProxmoxMachine{}
+		m.Annotations = map[string]string{
+			clusterv1.TemplateClonedFromNameAnnotation:      "workers",
+			clusterv1.TemplateClonedFromGroupKindAnnotation: "ProxmoxMachineTemplate.infrastructure.cluster.x-k8s.io",
+		}
+		Expect(clonedFromTemplate(m)).To(Equal("workers"))
+
+		m.Annotations[clusterv1.TemplateClonedFromGroupKindAnnotation] = "OtherMachineTemplate.infrastructure.cluster.x-k8s.io"
+		Expect(clonedFromTemplate(m)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("matchNode", Label("unit", "controllers"), func() {
+	node := func(name, uuid string) corev1.Node {
+		n := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		n.Status.NodeInfo.SystemUUID = uuid
+		return n
+	}
+	nodes := []corev1.Node{
+		node("worker-a", "0D9E6A86-2C1A-4B8E-9F57-6B1C0A3B2F11"),
+		node("worker-b", "5f0c2a7e-9d3b-4c44-8a1e-2b7d6c9e0f22"),
+	}
+
+	It("should match the system uuid case-insensitively", func() {
+		Expect(matchNode(nodes, ptr.To("0d9e6a86-2c1a-4b8e-9f57-6b1c0a3b2f11"), "worker-b")).To(HaveField("Name", "worker-a"))
+	})
+
+	It("should fall back to the name", func() {
+		Expect(matchNode(nodes, ptr.To("7a1b2c3d-0000-4000-8000-000000000000"), "other", "worker-b")).To(HaveField("Name", "worker-b"))
+		Expect(matchNode(nodes, nil, "worker-b")).To(HaveField("Name", "worker-b"))
+	})
+
+	It("should return nil without match", func() {
+		Expect(matchNode(nodes, nil, "other")).To(BeNil())
+	})
+})
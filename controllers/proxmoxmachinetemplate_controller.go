@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/instance"
+)
+
+// ProxmoxMachineTemplateReconciler reports the capacity of the machines of a ProxmoxMachineTemplate
+type ProxmoxMachineTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachinetemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxmachinetemplates/status,verbs=get;update;patch
+
+func (r *ProxmoxMachineTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	template := &infrav1.ProxmoxMachineTemplate{}
+	if err := r.Get(ctx, req.NamespacedName, template); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !template.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// the capacity is derived from the spec only, so the template is not reconciled again until it changes
+	capacity, err := instance.Capacity(template.Spec.Template.Spec)
+	if err != nil {
+		log.Error(err, "failed to compute capacity of ProxmoxMachineTemplate")
+		return ctrl.Result{}, nil
+	}
+	if apiequality.Semantic.DeepEqual(template.Status.Capacity, capacity) {
+		return ctrl.Result{}, nil
+	}
+	patch := client.MergeFrom(template.DeepCopy())
+	template.Status.Capacity = capacity
+	if err := r.Status().Patch(ctx, template, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("Updated capacity of ProxmoxMachineTemplate")
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProxmoxMachineTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1.ProxmoxMachineTemplate{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
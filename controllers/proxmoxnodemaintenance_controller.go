@@ -0,0 +1,271 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/maintenance"
+)
+
+// ProxmoxNodeMaintenanceReconciler reconciles a ProxmoxNodeMaintenance object
+type ProxmoxNodeMaintenanceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxnodemaintenances,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxnodemaintenances/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=delete
+
+func (r *ProxmoxNodeMaintenanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+
+	nodeMaintenance := &infrav1.ProxmoxNodeMaintenance{}
+	if err := r.Get(ctx, req.NamespacedName, nodeMaintenance); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !nodeMaintenance.DeletionTimestamp.IsZero() {
+		// nothing to clean up. the node is uncordoned once this is gone
+		return ctrl.Result{}, nil
+	}
+
+	cluster := &clusterv1.Cluster{}
+	clusterKey := client.ObjectKey{Namespace: nodeMaintenance.Namespace, Name: nodeMaintenance.Spec.ClusterName}
+	if err := r.Get(ctx, clusterKey, cluster); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		log.Info("Cluster is not found", "cluster", clusterKey.Name)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	if annotations.IsPaused(cluster, nodeMaintenance) {
+		log.Info("ProxmoxNodeMaintenance or linked Cluster is marked as paused. Won't reconcile")
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name, "node", nodeMaintenance.Spec.NodeName)
+	if cluster.Spec.InfrastructureRef == nil {
+		log.Info("Cluster does not have infrastructureRef yet")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	proxmoxClusterKey := client.ObjectKey{
+		Namespace: nodeMaintenance.Namespace,
+		Name:      cluster.Spec.InfrastructureRef.Name,
+	}
+	if err := r.Client.Get(ctx, proxmoxClusterKey, proxmoxCluster); err != nil {
+		log.Info("ProxmoxCluster is not available yet")
+		return ctrl.Result{}, nil
+	}
+	if isDryRun(proxmoxCluster, nodeMaintenance) {
+		return skipDryRun(ctx, nodeMaintenance, "ProxmoxNodeMaintenance"), nil
+	}
+
+	// Create the cluster scope
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		Client:         r.Client,
+		Cluster:        cluster,
+		ProxmoxCluster: proxmoxCluster,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	machines := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, machines,
+		client.InNamespace(nodeMaintenance.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: cluster.Name},
+	); err != nil {
+		return ctrl.Result{}, err
+	}
+	cordonedNodes, err := cordonedNodes(ctx, r.Client, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Create the node maintenance scope
+	maintenanceScope, err := scope.NewNodeMaintenanceScope(scope.NodeMaintenanceScopeParams{
+		Client:                 r.Client,
+		ProxmoxNodeMaintenance: nodeMaintenance,
+		ClusterGetter:          clusterScope,
+		Machines:               activeMachines(machines.Items),
+		CordonedNodes:          cordonedNodes,
+	})
+	if err != nil {
+		return ctrl.Result{}, errors.Errorf("failed to create scope: %+v", err)
+	}
+
+	// Always close the scope when exiting this function so we can persist any ProxmoxNodeMaintenance changes.
+	defer func() {
+		if err := maintenanceScope.Close(); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	return r.reconcile(ctx, maintenanceScope, machines.Items)
+}
+
+func (r *ProxmoxNodeMaintenanceReconciler) reconcile(ctx context.Context, maintenanceScope *scope.NodeMaintenanceScope, machines []infrav1.ProxmoxMachine) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling ProxmoxNodeMaintenance")
+
+	if err := maintenance.NewService(maintenanceScope).Reconcile(ctx); err != nil {
+		log.Error(err, "Reconcile error")
+		maintenanceScope.SetFailureMessage(err)
+		record.Warnf(maintenanceScope.ProxmoxNodeMaintenance, "ProxmoxNodeMaintenanceReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+
+	left := maintenanceScope.ProxmoxNodeMaintenance.Status.Machines
+	if len(left) > 0 {
+		if err := r.recreateMachine(ctx, maintenanceScope, machines); err != nil {
+			log.Error(err, "Reconcile error")
+			maintenanceScope.SetFailureMessage(err)
+			record.Warnf(maintenanceScope.ProxmoxNodeMaintenance, "ProxmoxNodeMaintenanceReconcile", "Reconcile error - %v", err)
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	log.Info("Reconciled ProxmoxNodeMaintenance")
+	record.Event(maintenanceScope.ProxmoxNodeMaintenance, "ProxmoxNodeMaintenanceReconcile", "Node is evacuated")
+	return ctrl.Result{}, nil
+}
+
+// deletes the Machine owning the first movable ProxmoxMachine left on the node.
+// machines are recreated one by one so that the cluster does not lose capacity at once
+func (r *ProxmoxNodeMaintenanceReconciler) recreateMachine(ctx context.Context, maintenanceScope *scope.NodeMaintenanceScope, machines []infrav1.ProxmoxMachine) error {
+	log := log.FromContext(ctx)
+	nodeName := maintenanceScope.GetSpec().NodeName
+	for _, m := range machines {
+		if !m.DeletionTimestamp.IsZero() && m.Spec.Node == nodeName {
+			log.Info("waiting for ProxmoxMachine to be deleted", "proxmoxmachine", m.Name)
+			return nil
+		}
+	}
+	name := firstMovable(maintenanceScope.ProxmoxNodeMaintenance.Status.Machines, machines)
+	for _, m := range machines {
+		if m.Name != name {
+			continue
+		}
+		machine, err := util.GetOwnerMachine(ctx, r.Client, m.ObjectMeta)
+		if err != nil || machine == nil {
+			return err
+		}
+		if !machine.DeletionTimestamp.IsZero() {
+			return nil
+		}
+		log.Info("deleting Machine to recreate it on another node", "machine", machine.Name)
+		record.Eventf(maintenanceScope.ProxmoxNodeMaintenance, "ProxmoxNodeMaintenanceReconcile", "Deleting Machine %s", machine.Name)
+		if err := r.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the first of the names whose ProxmoxMachine is movable. replacements of pinned machines would
+// come back to the node and high priority machines are not touched, so they are left for the admin to handle
+func firstMovable(names []string, machines []infrav1.ProxmoxMachine) string {
+	movable := map[string]bool{}
+	for _, m := range machines {
+		movable[m.Name] = m.Spec.Movable()
+	}
+	for _, name := range names {
+		if movable[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// returns machines having vmid and not being deleted
+func activeMachines(machines []infrav1.ProxmoxMachine) []infrav1.ProxmoxMachine {
+	active := []infrav1.ProxmoxMachine{}
+	for _, m := range machines {
+		if m.Spec.VMID != nil && m.DeletionTimestamp.IsZero() {
+			active = append(active, m)
+		}
+	}
+	return active
+}
+
+// returns sorted names of the nodes under maintenance for the cluster
+func cordonedNodes(ctx context.Context, c client.Client, cluster *clusterv1.Cluster) ([]string, error) {
+	list := &infrav1.ProxmoxNodeMaintenanceList{}
+	if err := c.List(ctx, list, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, err
+	}
+	nodes := []string{}
+	for _, m := range list.Items {
+		if m.Spec.ClusterName == cluster.Name && m.DeletionTimestamp.IsZero() {
+			nodes = append(nodes, m.Spec.NodeName)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// returns requests for the maintenances of the cluster the ProxmoxMachine belongs to
+func (r *ProxmoxNodeMaintenanceReconciler) proxmoxMachineToMaintenances(ctx context.Context, o client.Object) []reconcile.Request {
+	clusterName, ok := o.GetLabels()[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil
+	}
+	list := &infrav1.ProxmoxNodeMaintenanceList{}
+	if err := r.List(ctx, list, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, m := range list.Items {
+		if m.Spec.ClusterName == clusterName {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.Namespace, Name: m.Name}})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProxmoxNodeMaintenanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1.ProxmoxNodeMaintenance{}).
+		Watches(&infrav1.ProxmoxMachine{}, handler.EnqueueRequestsFromMapFunc(r.proxmoxMachineToMaintenances)).
+		Complete(r)
+}
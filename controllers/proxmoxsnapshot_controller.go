@@ -0,0 +1,245 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/services/compute/snapshot"
+)
+
+// ProxmoxSnapshotReconciler reconciles a ProxmoxSnapshot object
+type ProxmoxSnapshotReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxsnapshots,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxsnapshots/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=proxmoxsnapshots/finalizers,verbs=update
+
+func (r *ProxmoxSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+
+	proxmoxSnapshot := &infrav1.ProxmoxSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, proxmoxSnapshot); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	proxmoxMachine := &infrav1.ProxmoxMachine{}
+	proxmoxMachineKey := client.ObjectKey{
+		Namespace: proxmoxSnapshot.Namespace,
+		Name:      proxmoxSnapshot.Spec.MachineRef.Name,
+	}
+	if err := r.Get(ctx, proxmoxMachineKey, proxmoxMachine); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if !proxmoxSnapshot.DeletionTimestamp.IsZero() {
+			// snapshots are deleted together with the vm
+			log.Info("ProxmoxMachine is already deleted")
+			return r.removeFinalizer(ctx, proxmoxSnapshot)
+		}
+		log.Info("ProxmoxMachine is not found", "machine", proxmoxMachineKey.Name)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	log = log.WithValues("machine", proxmoxMachine.Name)
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, proxmoxMachine.ObjectMeta)
+	if err != nil {
+		log.Info("ProxmoxMachine is missing cluster label or cluster does not exist")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	if annotations.IsPaused(cluster, proxmoxSnapshot) {
+		log.Info("ProxmoxSnapshot or linked Cluster is marked as paused. Won't reconcile")
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name)
+	proxmoxCluster := &infrav1.ProxmoxCluster{}
+	proxmoxClusterKey := client.ObjectKey{
+		Namespace: proxmoxMachine.Namespace,
+		Name:      cluster.Spec.InfrastructureRef.Name,
+	}
+	if err := r.Client.Get(ctx, proxmoxClusterKey, proxmoxCluster); err != nil {
+		log.Info("ProxmoxCluster is not available yet")
+		return ctrl.Result{}, nil
+	}
+	if isDryRun(proxmoxCluster, proxmoxMachine, proxmoxSnapshot) {
+		return skipDryRun(ctx, proxmoxSnapshot, "ProxmoxSnapshot"), nil
+	}
+
+	// Create the cluster scope
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		Client:         r.Client,
+		Cluster:        cluster,
+		ProxmoxCluster: proxmoxCluster,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Create the snapshot scope
+	snapshotScope, err := scope.NewSnapshotScope(scope.SnapshotScopeParams{
+		Client:          r.Client,
+		ProxmoxSnapshot: proxmoxSnapshot,
+		ProxmoxMachine:  proxmoxMachine,
+		ClusterGetter:   clusterScope,
+	})
+	if err != nil {
+		return ctrl.Result{}, errors.Errorf("failed to create scope: %+v", err)
+	}
+
+	// Always close the scope when exiting this function so we can persist any ProxmoxSnapshot changes.
+	defer func() {
+		if err := snapshotScope.Close(); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	if !proxmoxSnapshot.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, snapshotScope)
+	}
+
+	return r.reconcile(ctx, snapshotScope)
+}
+
+func (r *ProxmoxSnapshotReconciler) reconcile(ctx context.Context, snapshotScope *scope.SnapshotScope) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling ProxmoxSnapshot")
+
+	if ok := controllerutil.AddFinalizer(snapshotScope.ProxmoxSnapshot, infrav1.SnapshotFinalizer); ok {
+		log.Info("update finalizer to ProxmoxSnapshot")
+	}
+	// garbage collect snapshots together with the machine
+	if err := controllerutil.SetOwnerReference(snapshotScope.ProxmoxMachine, snapshotScope.ProxmoxSnapshot, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := snapshotScope.PatchObject(); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := snapshot.NewService(snapshotScope).Reconcile(ctx); err != nil {
+		if errors.Is(err, snapshot.ErrVMNotFound) {
+			log.Info("ProxmoxMachine does not have vm yet. Waiting")
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+		if errors.Is(err, snapshot.ErrSnapshotLost) || errors.Is(err, snapshot.ErrVMChanged) {
+			// taking the snapshot again would not restore the state it was taken for
+			log.Error(err, "Snapshot can not be used anymore")
+			snapshotScope.SetFailureMessage(err)
+			record.Warnf(snapshotScope.ProxmoxSnapshot, "ProxmoxSnapshotLost", "%v", err)
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Reconcile error")
+		snapshotScope.SetFailureMessage(err)
+		record.Warnf(snapshotScope.ProxmoxSnapshot, "ProxmoxSnapshotReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+
+	if err := r.pruneSnapshots(ctx, snapshotScope.ProxmoxSnapshot); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Reconciled ProxmoxSnapshot")
+	record.Event(snapshotScope.ProxmoxSnapshot, "ProxmoxSnapshotReconcile", "Reconciled")
+	return ctrl.Result{}, nil
+}
+
+func (r *ProxmoxSnapshotReconciler) reconcileDelete(ctx context.Context, snapshotScope *scope.SnapshotScope) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling Delete ProxmoxSnapshot")
+
+	if err := snapshot.NewService(snapshotScope).Delete(ctx); err != nil {
+		log.Error(err, "Reconcile error")
+		record.Warnf(snapshotScope.ProxmoxSnapshot, "ProxmoxSnapshotReconcile", "Reconcile error - %v", err)
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	}
+
+	log.Info("Reconciled ProxmoxSnapshot")
+	controllerutil.RemoveFinalizer(snapshotScope.ProxmoxSnapshot, infrav1.SnapshotFinalizer)
+	record.Event(snapshotScope.ProxmoxSnapshot, "ProxmoxSnapshotReconcile", "Reconciled")
+	return ctrl.Result{}, nil
+}
+
+func (r *ProxmoxSnapshotReconciler) removeFinalizer(ctx context.Context, proxmoxSnapshot *infrav1.ProxmoxSnapshot) (ctrl.Result, error) {
+	patch := client.MergeFrom(proxmoxSnapshot.DeepCopy())
+	controllerutil.RemoveFinalizer(proxmoxSnapshot, infrav1.SnapshotFinalizer)
+	return ctrl.Result{}, r.Patch(ctx, proxmoxSnapshot, patch)
+}
+
+// delete the oldest ProxmoxSnapshots of the same machine exceeding spec.retain
+func (r *ProxmoxSnapshotReconciler) pruneSnapshots(ctx context.Context, proxmoxSnapshot *infrav1.ProxmoxSnapshot) error {
+	log := log.FromContext(ctx)
+	if proxmoxSnapshot.Spec.Retain == nil {
+		return nil
+	}
+	list := &infrav1.ProxmoxSnapshotList{}
+	if err := r.List(ctx, list, client.InNamespace(proxmoxSnapshot.Namespace)); err != nil {
+		return err
+	}
+	for _, s := range outdatedSnapshots(list.Items, proxmoxSnapshot.Spec.MachineRef.Name, *proxmoxSnapshot.Spec.Retain) {
+		log.Info("deleting outdated ProxmoxSnapshot", "snapshot", s.Name)
+		if err := r.Delete(ctx, &s); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the oldest snapshots of the machine exceeding retain
+func outdatedSnapshots(snapshots []infrav1.ProxmoxSnapshot, machine string, retain int) []infrav1.ProxmoxSnapshot {
+	matched := []infrav1.ProxmoxSnapshot{}
+	for _, s := range snapshots {
+		if s.Spec.MachineRef.Name == machine && s.DeletionTimestamp.IsZero() {
+			matched = append(matched, s)
+		}
+	}
+	if len(matched) <= retain {
+		return nil
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].CreationTimestamp.Before(&matched[j].CreationTimestamp)
+	})
+	return matched[:len(matched)-retain]
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProxmoxSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1.ProxmoxSnapshot{}).
+		Complete(r)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/scope"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/cloud/specdiff"
+)
+
+// max number of changed fields in the message of the condition
+const maxTemplateChanges = 5
+
+var proxmoxMachineTemplateGroupKind = infrav1.GroupVersion.WithKind("ProxmoxMachineTemplate").GroupKind().String()
+
+// returns the name of the ProxmoxMachineTemplate the machine was cloned from. empty if none
+func clonedFromTemplate(proxmoxMachine *infrav1.ProxmoxMachine) string {
+	annotations := proxmoxMachine.GetAnnotations()
+	if annotations[clusterv1.TemplateClonedFromGroupKindAnnotation] != proxmoxMachineTemplateGroupKind {
+		return ""
+	}
+	return annotations[clusterv1.TemplateClonedFromNameAnnotation]
+}
+
+// returns the changes of the template since the machine was cloned from it.
+// fields cappx or cluster api fill in on the machine are not changes
+func templateChanges(machine, template infrav1.ProxmoxMachineSpec) ([]string, error) {
+	template.ProviderID = machine.ProviderID
+	template.Node = machine.Node
+	template.VMID = machine.VMID
+	template.FailureDomain = machine.FailureDomain
+	if template.Storage == "" {
+		template.Storage = machine.Storage
+	}
+	return specdiff.Diff(machine, template)
+}
+
+// flags machines whose template has been edited in place. cluster api rolls out machines only when the
+// MachineDeployment references another template, so the machines keep running the old spec
+func (r *ProxmoxMachineReconciler) reconcileTemplateDrift(ctx context.Context, machineScope *scope.MachineScope) error {
+	name := clonedFromTemplate(machineScope.ProxmoxMachine)
+	if name == "" {
+		machineScope.ClearTemplateSync()
+		return nil
+	}
+	template := &infrav1.ProxmoxMachineTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: machineScope.Namespace(), Name: name}, template); err != nil {
+		if apierrors.IsNotFound(err) {
+			machineScope.ClearTemplateSync()
+			return nil
+		}
+		return err
+	}
+	changes, err := templateChanges(machineScope.ProxmoxMachine.Spec, template.Spec.Template.Spec)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		machineScope.SetTemplateInSync()
+		return nil
+	}
+	message := fmt.Sprintf("ProxmoxMachineTemplate %s has changed since the machine was created: %s. "+
+		"machines are rolled out only when the MachineDeployment references a new template",
+		name, specdiff.Summary(changes, maxTemplateChanges))
+	if machineScope.TemplateChangedMessage() != message {
+		log.FromContext(ctx).Info("ProxmoxMachineTemplate has changed", "template", name, "changes", changes)
+		record.Eventf(machineScope.ProxmoxMachine, infrav1.TemplateChangedReason, "%s", message)
+	}
+	machineScope.SetTemplateChanged(message)
+	return nil
+}
+
+// returns the ProxmoxMachines cloned from the template
+func (r *ProxmoxMachineReconciler) proxmoxMachineTemplateToProxmoxMachines(ctx context.Context, o client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+	list := &infrav1.ProxmoxMachineList{}
+	if err := r.List(ctx, list, client.InNamespace(o.GetNamespace())); err != nil {
+		log.Error(err, "failed to list ProxmoxMachines")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, m := range list.Items {
+		if clonedFromTemplate(&m) == o.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.Namespace, Name: m.Name}})
+		}
+	}
+	return requests
+}
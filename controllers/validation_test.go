@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+// the CEL rules of the CRDs are evaluated by the api server of envtest.
+// machines are created in dry-run mode so that nothing is left behind
+var _ = Describe("ProxmoxMachine validation", Label("unit", "controllers"), func() {
+	create := func(spec infrav1.ProxmoxMachineSpec) error {
+		machine := &infrav1.ProxmoxMachine{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "cel-", Namespace: "default"},
+			Spec:       spec,
+		}
+		return k8sClient.Create(context.Background(), machine, client.DryRunAll)
+	}
+	expectRejected := func(spec infrav1.ProxmoxMachineSpec, message string) {
+		err := create(spec)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+		Expect(err).To(MatchError(ContainSubstring(message)))
+	}
+
+	It("should accept a minimal spec", func() {
+		Expect(create(infrav1.ProxmoxMachineSpec{})).To(Succeed())
+	})
+
+	It("should accept static and dhcp addresses", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Network.IPConfig = infrav1.IPConfig{IP: "10.0.0.10/24", Gateway: "10.0.0.1", IP6: "fd00::10/64", Gateway6: "fd00::1"}
+		Expect(create(spec)).To(Succeed())
+		spec.Network.IPConfig = infrav1.IPConfig{IP: "dhcp", IP6: "dhcp"}
+		Expect(create(spec)).To(Succeed())
+	})
+
+	It("should reject malformed addresses", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Network.IPConfig = infrav1.IPConfig{IP: "10.0.0.10"}
+		expectRejected(spec, "ip must be 'dhcp' or an IPv4 address with CIDR")
+		spec.Network.IPConfig = infrav1.IPConfig{IP6: "fd00::10"}
+		expectRejected(spec, "ip6 must be 'dhcp', 'auto' or an IPv6 address with CIDR")
+	})
+
+	It("should reject gateways without a static address", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Network.IPConfig = infrav1.IPConfig{IP: "dhcp", Gateway: "10.0.0.1"}
+		expectRejected(spec, "gateway requires a static ip")
+		spec.Network.IPConfig = infrav1.IPConfig{IP6: "dhcp", Gateway6: "fd00::1"}
+		expectRejected(spec, "gateway6 requires a static ip6")
+	})
+
+	It("should accept root disk sizes and reject malformed ones", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Hardware.RootDisk = "100G"
+		Expect(create(spec)).To(Succeed())
+		spec.Hardware.RootDisk = "100GB"
+		expectRejected(spec, "spec.hardware.rootDisk")
+	})
+
+	It("should reject template machines", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Options.Template = true
+		expectRejected(spec, "options.template can not be enabled")
+	})
+
+	It("should require memory to be a multiple of hugepages", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Hardware.Memory = 2048
+		spec.Options.HugePages = ptr.To(infrav1.HugePages(1024))
+		Expect(create(spec)).To(Succeed())
+		spec.Hardware.Memory = 1500
+		expectRejected(spec, "hardware.memory must be a multiple of options.hugePages")
+	})
+
+	It("should keep memory within maxMemory", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Options.NUMA = true
+		spec.Hardware.MemoryHotplug = true
+		spec.Hardware.Memory, spec.Hardware.MaxMemory = 8192, 16384
+		Expect(create(spec)).To(Succeed())
+		spec.Hardware.Memory = 32768
+		expectRejected(spec, "memory must not exceed maxMemory")
+		spec.Hardware.MemoryHotplug = false
+		spec.Hardware.Memory = 8192
+		expectRejected(spec, "maxMemory requires memoryHotplug")
+	})
+
+	It("should limit vcpus to the cores of all sockets", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Hardware.CPU, spec.Hardware.Sockets = 2, 2
+		spec.Options.VCPUs = 4
+		Expect(create(spec)).To(Succeed())
+		spec.Options.VCPUs = 5
+		expectRejected(spec, "options.vcpus must not exceed hardware.cpu * hardware.sockets")
+	})
+
+	It("should reject relative snippet storage paths", func() {
+		spec := infrav1.ProxmoxMachineSpec{SnippetStorage: &infrav1.Storage{Name: "local", Path: "/var/lib/vz"}}
+		Expect(create(spec)).To(Succeed())
+		spec.SnippetStorage.Path = "var/lib/vz"
+		expectRejected(spec, "path must be absolute")
+	})
+
+	It("should reject node local image paths with parent references or shell characters", func() {
+		spec := infrav1.ProxmoxMachineSpec{}
+		spec.Image = &infrav1.Image{SourceType: infrav1.ImageSourceNodeLocalPath, Path: "/var/lib/vz/import/ubuntu.qcow2"}
+		Expect(create(spec)).To(Succeed())
+		spec.Image.Path = "/var/lib/vz/import/../images/100/vm-100-disk-0.qcow2"
+		expectRejected(spec, "path must not contain '..'")
+		spec.Image.Path = "/var/lib/vz/import/$(reboot).qcow2"
+		expectRejected(spec, "spec.image.path")
+	})
+})
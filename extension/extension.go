@@ -0,0 +1,127 @@
+// Package extension implements the Runtime Extension of cappx which patches
+// ProxmoxMachineTemplates of ClusterClass topologies.
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/exp/runtime/topologymutation"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+)
+
+const (
+	// ImagesVariable maps Kubernetes versions, e.g. v1.30.4 or v1.30, to the image of machines running them
+	ImagesVariable = "proxmoxImages"
+
+	// MachineSizeVariable overrides the cpu, memory and root disk of machines
+	MachineSizeVariable = "proxmoxMachineSize"
+)
+
+// MachineSize is the value of the proxmoxMachineSize variable
+type MachineSize struct {
+	// number of CPU cores
+	CPU int `json:"cpu,omitempty"`
+	// amount of RAM in MiB
+	Memory int `json:"memory,omitempty"`
+	// root disk size. e.g. 50G
+	RootDisk string `json:"rootDisk,omitempty"`
+}
+
+// Handler handles the topology mutation hooks
+type Handler struct {
+	decoder runtime.Decoder
+}
+
+// NewHandler returns a Handler decoding the templates with the scheme, which must know infrav1
+func NewHandler(scheme *runtime.Scheme) *Handler {
+	return &Handler{decoder: serializer.NewCodecFactory(scheme).UniversalDecoder(infrav1.GroupVersion)}
+}
+
+// GeneratePatches patches the ProxmoxMachineTemplates of the topology with the proxmox variables.
+// templates of other kinds are left as they are
+func (h *Handler) GeneratePatches(ctx context.Context, req *runtimehooksv1.GeneratePatchesRequest, resp *runtimehooksv1.GeneratePatchesResponse) {
+	log := ctrl.LoggerFrom(ctx)
+	log.V(3).Info("GeneratePatches is called")
+	topologymutation.WalkTemplates(ctx, h.decoder, req, resp, func(_ context.Context, obj runtime.Object, variables map[string]apiextensionsv1.JSON, _ runtimehooksv1.HolderReference) error {
+		template, ok := obj.(*infrav1.ProxmoxMachineTemplate)
+		if !ok {
+			return nil
+		}
+		return patchMachineTemplate(template, variables)
+	})
+}
+
+func patchMachineTemplate(template *infrav1.ProxmoxMachineTemplate, variables map[string]apiextensionsv1.JSON) error {
+	spec := &template.Spec.Template.Spec
+	images := map[string]infrav1.Image{}
+	if err := topologymutation.GetObjectVariableInto(variables, ImagesVariable, &images); err == nil {
+		// restored machines and containers do not boot from an image
+		if spec.Restore == nil && spec.Type != infrav1.InstanceTypeLXC {
+			version, err := kubernetesVersion(variables)
+			if err != nil {
+				return err
+			}
+			image, err := imageOf(images, version)
+			if err != nil {
+				return err
+			}
+			spec.Image = &image
+		}
+	} else if !topologymutation.IsNotFoundError(err) {
+		return err
+	}
+
+	size := MachineSize{}
+	if err := topologymutation.GetObjectVariableInto(variables, MachineSizeVariable, &size); err == nil {
+		if size.CPU != 0 {
+			spec.Hardware.CPU = size.CPU
+		}
+		if size.Memory != 0 {
+			spec.Hardware.Memory = size.Memory
+		}
+		if size.RootDisk != "" {
+			spec.Hardware.RootDisk = size.RootDisk
+		}
+	} else if !topologymutation.IsNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+// returns the Kubernetes version of the machine deployment, machine pool or control plane the template belongs to
+func kubernetesVersion(variables map[string]apiextensionsv1.JSON) (string, error) {
+	builtins := runtimehooksv1.Builtins{}
+	if err := topologymutation.GetObjectVariableInto(variables, runtimehooksv1.BuiltinsName, &builtins); err != nil {
+		return "", err
+	}
+	switch {
+	case builtins.MachineDeployment != nil && builtins.MachineDeployment.Version != "":
+		return builtins.MachineDeployment.Version, nil
+	case builtins.MachinePool != nil && builtins.MachinePool.Version != "":
+		return builtins.MachinePool.Version, nil
+	case builtins.ControlPlane != nil && builtins.ControlPlane.Version != "":
+		return builtins.ControlPlane.Version, nil
+	}
+	return "", fmt.Errorf("no Kubernetes version in the builtin variables")
+}
+
+// returns the image of the exact version, or else of its minor version
+func imageOf(images map[string]infrav1.Image, version string) (infrav1.Image, error) {
+	if image, ok := images[version]; ok {
+		return image, nil
+	}
+	if parts := strings.SplitN(version, ".", 3); len(parts) == 3 {
+		if image, ok := images[parts[0]+"."+parts[1]]; ok {
+			return image, nil
+		}
+	}
+	return infrav1.Image{}, fmt.Errorf("%s has no image for Kubernetes %s", ImagesVariable, version)
+}
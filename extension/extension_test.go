@@ -0,0 +1,108 @@
+package extension_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+
+	infrav1 "github.com/k8s-proxmox/cluster-api-provider-proxmox/api/v1beta1"
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/extension"
+)
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+var _ = Describe("GeneratePatches", Label("unit", "extension"), func() {
+	var handler *extension.Handler
+	var template *infrav1.ProxmoxMachineTemplate
+
+	variable := func(name, value string) runtimehooksv1.Variable {
+		return runtimehooksv1.Variable{Name: name, Value: apiextensionsv1.JSON{Raw: []byte(value)}}
+	}
+	generate := func(variables ...runtimehooksv1.Variable) *runtimehooksv1.GeneratePatchesResponse {
+		raw, err := json.Marshal(template)
+		Expect(err).NotTo(HaveOccurred())
+		req := &runtimehooksv1.GeneratePatchesRequest{
+			Variables: []runtimehooksv1.Variable{variable(extension.ImagesVariable, `{"v1.30": {"url": "https://example.com/k8s-1.30.qcow2"}, "v1.31.1": {"url": "https://example.com/k8s-1.31.1.qcow2", "checksum": "abc"}}`)},
+			Items: []runtimehooksv1.GeneratePatchesRequestItem{{
+				UID: "1",
+				HolderReference: runtimehooksv1.HolderReference{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "MachineDeployment",
+					Name:       "workers",
+					FieldPath:  "spec.template.spec.infrastructureRef",
+				},
+				Object:    runtime.RawExtension{Raw: raw},
+				Variables: variables,
+			}},
+		}
+		resp := &runtimehooksv1.GeneratePatchesResponse{}
+		handler.GeneratePatches(context.Background(), req, resp)
+		return resp
+	}
+	operations := func(resp *runtimehooksv1.GeneratePatchesResponse) []patchOperation {
+		Expect(resp.Status).To(Equal(runtimehooksv1.ResponseStatusSuccess), resp.Message)
+		Expect(resp.Items).To(HaveLen(1))
+		ops := []patchOperation{}
+		Expect(json.Unmarshal(resp.Items[0].Patch, &ops)).To(Succeed())
+		return ops
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(infrav1.AddToScheme(scheme)).To(Succeed())
+		handler = extension.NewHandler(scheme)
+		template = &infrav1.ProxmoxMachineTemplate{}
+		template.APIVersion = infrav1.GroupVersion.String()
+		template.Kind = "ProxmoxMachineTemplate"
+		template.Spec.Template.Spec.Hardware = infrav1.Hardware{CPU: 2, Memory: 4096, RootDisk: "50G"}
+	})
+
+	It("should pick the image of the Kubernetes version", func() {
+		ops := operations(generate(variable(runtimehooksv1.BuiltinsName, `{"machineDeployment": {"version": "v1.31.1"}}`)))
+		Expect(ops).To(ConsistOf(patchOperation{
+			Op:    "add",
+			Path:  "/spec/template/spec/image",
+			Value: map[string]interface{}{"url": "https://example.com/k8s-1.31.1.qcow2", "checksum": "abc"},
+		}))
+
+		ops = operations(generate(variable(runtimehooksv1.BuiltinsName, `{"machineDeployment": {"version": "v1.30.4"}}`)))
+		Expect(ops).To(ConsistOf(patchOperation{
+			Op:    "add",
+			Path:  "/spec/template/spec/image",
+			Value: map[string]interface{}{"url": "https://example.com/k8s-1.30.qcow2"},
+		}))
+	})
+
+	It("should fail for versions without image", func() {
+		resp := generate(variable(runtimehooksv1.BuiltinsName, `{"machineDeployment": {"version": "v1.29.0"}}`))
+		Expect(resp.Status).To(Equal(runtimehooksv1.ResponseStatusFailure))
+		Expect(resp.Message).To(ContainSubstring("proxmoxImages has no image for Kubernetes v1.29.0"))
+	})
+
+	It("should not set the image of restored machines", func() {
+		template.Spec.Template.Spec.Restore = &infrav1.Restore{Archive: "local:backup/vzdump-qemu-100.vma.zst"}
+		Expect(operations(generate(variable(runtimehooksv1.BuiltinsName, `{"machineDeployment": {"version": "v1.29.0"}}`)))).To(BeEmpty())
+	})
+
+	It("should size the machine", func() {
+		ops := operations(generate(
+			variable(runtimehooksv1.BuiltinsName, `{"controlPlane": {"version": "v1.30.2"}}`),
+			variable(extension.MachineSizeVariable, `{"cpu": 8, "rootDisk": "100G"}`),
+		))
+		Expect(ops).To(ContainElements(
+			patchOperation{Op: "replace", Path: "/spec/template/spec/hardware/cpu", Value: float64(8)},
+			patchOperation{Op: "replace", Path: "/spec/template/spec/hardware/rootDisk", Value: "100G"},
+		))
+		Expect(ops).NotTo(ContainElement(HaveField("Path", "/spec/template/spec/hardware/memory")))
+	})
+})
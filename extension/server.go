@@ -0,0 +1,34 @@
+package extension
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimecatalog "sigs.k8s.io/cluster-api/exp/runtime/catalog"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/exp/runtime/server"
+)
+
+// GeneratePatchesHandlerName is the name of the GeneratePatches handler referred to by external patches of ClusterClasses
+const GeneratePatchesHandlerName = "generate-patches"
+
+// NewServer returns the Runtime Extension server serving the topology mutation hooks. it is started by the manager.
+// the port and the cert dir of options default to 9443 and {TempDir}/k8s-webhook-server/serving-certs
+func NewServer(scheme *runtime.Scheme, options server.Options) (*server.Server, error) {
+	catalog := runtimecatalog.New()
+	if err := runtimehooksv1.AddToCatalog(catalog); err != nil {
+		return nil, err
+	}
+	options.Catalog = catalog
+	s, err := server.New(options)
+	if err != nil {
+		return nil, err
+	}
+	handler := NewHandler(scheme)
+	if err := s.AddExtensionHandler(server.ExtensionHandler{
+		Hook:        runtimehooksv1.GeneratePatches,
+		Name:        GeneratePatchesHandlerName,
+		HandlerFunc: handler.GeneratePatches,
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
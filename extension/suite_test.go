@@ -0,0 +1,13 @@
+package extension_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestExtension(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Extension Suite")
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package feature implements feature gates of cappx.
+package feature
+
+import (
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+)
+
+const (
+	// QEMUArgs allows passing arbitrary arguments to QEMU via options.args.
+	QEMUArgs featuregate.Feature = "QEMUArgs"
+
+	// ClusterRebalancer enables the controller moving VMs between Proxmox nodes per ProxmoxCluster.spec.rebalance.
+	ClusterRebalancer featuregate.Feature = "ClusterRebalancer"
+
+	// RuntimeExtension serves the Runtime Extension patching ProxmoxMachineTemplates of ClusterClass topologies.
+	RuntimeExtension featuregate.Feature = "RuntimeExtension"
+)
+
+var (
+	// MutableGates is a mutable version of Gates.
+	// Only top-level commands/options setup should make use of this.
+	MutableGates featuregate.MutableFeatureGate = featuregate.NewFeatureGate()
+
+	// Gates is a shared global FeatureGate.
+	Gates featuregate.FeatureGate = MutableGates
+)
+
+// defaultFeatureGates consists of all known cappx feature keys.
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	QEMUArgs:          {Default: false, PreRelease: featuregate.Alpha},
+	ClusterRebalancer: {Default: false, PreRelease: featuregate.Alpha},
+	RuntimeExtension:  {Default: false, PreRelease: featuregate.Alpha},
+}
+
+func init() {
+	runtime.Must(MutableGates.Add(defaultFeatureGates))
+}
@@ -0,0 +1,8 @@
+package logging
+
+// ResetLevels restores the default verbosity of the subsystems
+func ResetLevels() {
+	mu.Lock()
+	defer mu.Unlock()
+	levels = map[Subsystem]int{Client: 0}
+}
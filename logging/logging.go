@@ -0,0 +1,189 @@
+/*
+Copyright 2023 Teppei Sudo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging implements verbosity per subsystem of cappx.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+)
+
+// Subsystem is a part of cappx having its own verbosity
+type Subsystem string
+
+const (
+	Scheduler Subsystem = "scheduler"
+	Instance  Subsystem = "instance"
+	CloudInit Subsystem = "cloudinit"
+	// Client logs requests and responses of the Proxmox api at level 1.
+	// request bodies may contain credentials
+	Client Subsystem = "client"
+)
+
+// Subsystems are all known subsystems
+var Subsystems = []Subsystem{Scheduler, Instance, CloudInit, Client}
+
+var (
+	mu sync.RWMutex
+	// subsystems not listed follow -v. the client is silent unless configured
+	// since its logs are verbose and may contain credentials
+	levels = map[Subsystem]int{Client: 0}
+)
+
+// SetLevels sets verbosity of the subsystems. other subsystems are kept as is
+func SetLevels(l map[Subsystem]int) {
+	mu.Lock()
+	defer mu.Unlock()
+	for subsystem, level := range l {
+		levels[subsystem] = level
+	}
+}
+
+func levelOf(subsystem Subsystem) (int, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	level, ok := levels[subsystem]
+	return level, ok
+}
+
+// ParseLevels parses verbosity of subsystems like "scheduler=4,client=1"
+func ParseLevels(value string) (map[Subsystem]int, error) {
+	l := map[Subsystem]int{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("missing level of %q, expected <subsystem>=<level>", pair)
+		}
+		subsystem := Subsystem(strings.TrimSpace(key))
+		if !slices.Contains(Subsystems, subsystem) {
+			return nil, fmt.Errorf("unknown subsystem %q, must be one of %v", subsystem, Subsystems)
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || level < 0 {
+			return nil, fmt.Errorf("invalid level %q of %s", v, subsystem)
+		}
+		l[subsystem] = level
+	}
+	return l, nil
+}
+
+// AddFlags adds --log-levels to the flag set
+func AddFlags(fs *pflag.FlagSet) {
+	fs.Var(&levelsFlag{}, "log-levels",
+		fmt.Sprintf("Comma-separated verbosity per subsystem overriding -v. e.g. scheduler=4,client=1. Subsystems: %v", Subsystems))
+}
+
+type levelsFlag struct {
+	value map[Subsystem]int
+}
+
+func (f *levelsFlag) Set(value string) error {
+	l, err := ParseLevels(value)
+	if err != nil {
+		return err
+	}
+	f.value = l
+	SetLevels(l)
+	return nil
+}
+
+func (f *levelsFlag) String() string {
+	pairs := []string{}
+	for subsystem, level := range f.value {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", subsystem, level))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f *levelsFlag) Type() string {
+	return "mapStringInt"
+}
+
+// Logger returns the logger of the subsystem. the verbosity of the subsystem
+// replaces the one of the logger if it is configured
+func Logger(logger logr.Logger, subsystem Subsystem) logr.Logger {
+	base := logger.GetSink()
+	if base == nil {
+		return logger
+	}
+	if s, ok := base.(*sink); ok {
+		// the innermost subsystem wins. e.g. cloudinit called from instance
+		return logr.New(&sink{LogSink: s.LogSink, subsystem: subsystem})
+	}
+	// sink.Info adds a frame between the logger and the base sink
+	if cd, ok := base.(logr.CallDepthLogSink); ok {
+		base = cd.WithCallDepth(1)
+	}
+	return logr.New(&sink{LogSink: base, subsystem: subsystem})
+}
+
+// IntoContext returns a context whose logger is the one of the subsystem
+func IntoContext(ctx context.Context, subsystem Subsystem) context.Context {
+	return logr.NewContext(ctx, Logger(logr.FromContextOrDiscard(ctx), subsystem))
+}
+
+// sink filters log lines by the verbosity of the subsystem
+type sink struct {
+	logr.LogSink
+	subsystem Subsystem
+}
+
+// the base sink is already initialized by its logger
+func (s *sink) Init(_ logr.RuntimeInfo) {}
+
+func (s *sink) Enabled(level int) bool {
+	if v, ok := levelOf(s.subsystem); ok {
+		return level <= v
+	}
+	return s.LogSink.Enabled(level)
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if _, ok := levelOf(s.subsystem); ok {
+		// already enabled by the subsystem. the base sink would drop it above -v
+		level = 0
+	}
+	s.LogSink.Info(level, msg, keysAndValues...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithValues(keysAndValues...), subsystem: s.subsystem}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithName(name), subsystem: s.subsystem}
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	if cd, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return &sink{LogSink: cd.WithCallDepth(depth), subsystem: s.subsystem}
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package logging_test
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8s-proxmox/cluster-api-provider-proxmox/logging"
+)
+
+var _ = Describe("ParseLevels", Label("unit", "logging"), func() {
+	It("should parse levels per subsystem", func() {
+		levels, err := logging.ParseLevels("scheduler=4, client=1,")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(levels).To(Equal(map[logging.Subsystem]int{logging.Scheduler: 4, logging.Client: 1}))
+	})
+
+	It("should reject unknown subsystems and invalid levels", func() {
+		for _, value := range []string{"foo=1", "scheduler", "scheduler=high", "scheduler=-1"} {
+			_, err := logging.ParseLevels(value)
+			Expect(err).To(HaveOccurred(), value)
+		}
+	})
+})
+
+var _ = Describe("Logger", Label("unit", "logging"), func() {
+	var lines []string
+	var base logr.Logger
+
+	BeforeEach(func() {
+		lines = []string{}
+		base = funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{Verbosity: 1})
+	})
+
+	AfterEach(func() {
+		logging.ResetLevels()
+	})
+
+	It("should raise and lower verbosity of the subsystem", func() {
+		logging.SetLevels(map[logging.Subsystem]int{logging.Scheduler: 4, logging.Instance: 0})
+		logging.Logger(base, logging.Scheduler).V(4).Info("scheduler")
+		logging.Logger(base, logging.Scheduler).V(5).Info("dropped")
+		logging.Logger(base, logging.Instance).V(1).Info("dropped")
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"msg"="scheduler"`))
+	})
+
+	It("should use the innermost subsystem", func() {
+		logging.SetLevels(map[logging.Subsystem]int{logging.Instance: 0, logging.CloudInit: 2})
+		logger := logging.Logger(logging.Logger(base, logging.Instance).WithValues("machine", "m"), logging.CloudInit)
+		logger.V(2).Info("cloudinit")
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"machine"="m"`))
+	})
+
+	It("should follow the logger unless configured", func() {
+		logging.Logger(base, logging.Scheduler).V(1).Info("scheduler")
+		logging.Logger(base, logging.Scheduler).V(2).Info("dropped")
+		Expect(lines).To(HaveLen(1))
+	})
+
+	It("should keep the client silent unless configured", func() {
+		logging.Logger(base, logging.Client).V(1).Info("request body")
+		Expect(lines).To(BeEmpty())
+	})
+})